@@ -0,0 +1,93 @@
+/*  Copyright 2022  Kosmas Valianos (kosmas.valianos@gmail.com)
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    https://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License. */
+
+package gojsonrpc
+
+import (
+	"context"
+	"strings"
+)
+
+// AMQPMessage is a message published to or delivered from an AMQP broker
+type AMQPMessage struct {
+	Body          []byte
+	ContentType   string
+	ReplyTo       string
+	CorrelationID string
+}
+
+// AMQPChannel is the channel to the AMQP broker, e.g. RabbitMQ, of the AMQP transport. A small wrapper of the channel
+// of an AMQP 0-9-1 client such as amqp091-go copies the fields of the AMQPMessage from and to its Publishing and
+// Delivery. The queues are declared by the application
+type AMQPChannel interface {
+	// Publish publishes the message to the queue named by the routing key through the default exchange
+	Publish(ctx context.Context, routingKey string, msg AMQPMessage) error
+	// Consume passes the messages of the queue to the handler, in their order, acknowledging them, until cancelled
+	Consume(queue string, handler func(msg AMQPMessage)) (cancel func() error, err error)
+}
+
+// amqpContentType is the content type of the messages
+const amqpContentType = "application/json"
+
+// ServeAMQP serves the requests and notifications of the queue with the mux until the ctx is done, publishing
+// the responses to the reply-to queues of the requests with their correlation IDs. Many servers may consume
+// the same queue, each message being served by one of them.
+// Returns the error of the consumption or of its cancellation
+func ServeAMQP(ctx context.Context, channel AMQPChannel, queue string, mux *Mux) error {
+	cancel, err := channel.Consume(queue, func(msg AMQPMessage) {
+		serveBrokerMessage(ctx, mux, msg.Body, func(responseRaw []byte) {
+			if msg.ReplyTo != "" {
+				_ = channel.Publish(ctx, msg.ReplyTo, AMQPMessage{
+					Body:          responseRaw,
+					ContentType:   amqpContentType,
+					CorrelationID: msg.CorrelationID,
+				})
+			}
+		})
+	})
+	if err != nil {
+		return err
+	}
+	<-ctx.Done()
+	return cancel()
+}
+
+// DialAMQP creates a Conn publishing the requests and notifications to the queue and consuming the responses
+// of the reply queue, e.g. an exclusive queue of the client or "amq.rabbitmq.reply-to" for the direct reply-to
+// of RabbitMQ. The requests carry the reply queue and their JSON-RPC IDs, comma separated for a batch, as
+// the correlation ID. The notifications published by the server to the reply queue are served with the mux,
+// which may be nil. Closing the Conn cancels the consumption of the reply queue.
+// Returns a *Conn object or an error
+func DialAMQP(channel AMQPChannel, queue string, replyQueue string, mux *Mux, options ...ClientOption) (*Conn, error) {
+	amqpConn := newBrokerConn(func(ctx context.Context, messageRaw []byte) error {
+		ids, err := messageIDs(messageRaw)
+		if err != nil {
+			return err
+		}
+		msg := AMQPMessage{Body: messageRaw, ContentType: amqpContentType}
+		if len(ids) > 0 {
+			msg.ReplyTo = replyQueue
+			msg.CorrelationID = strings.Join(ids, ",")
+		}
+		return channel.Publish(ctx, queue, msg)
+	})
+	var err error
+	amqpConn.unsubscribe, err = channel.Consume(replyQueue, func(msg AMQPMessage) {
+		amqpConn.deliver(msg.Body)
+	})
+	if err != nil {
+		return nil, err
+	}
+	return NewConn(amqpConn, mux, options...), nil
+}
@@ -0,0 +1,120 @@
+/*  Copyright 2022  Kosmas Valianos (kosmas.valianos@gmail.com)
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    https://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License. */
+
+package gojsonrpc
+
+import (
+	"context"
+	"reflect"
+	"sync"
+	"testing"
+)
+
+// memAMQP is an in-memory AMQP broker with queues consumed by their consumers in turn
+type memAMQP struct {
+	mu        sync.Mutex
+	queues    map[string]chan AMQPMessage
+	published []AMQPMessage
+}
+
+func newMemAMQP() *memAMQP {
+	return &memAMQP{queues: make(map[string]chan AMQPMessage)}
+}
+
+func (a *memAMQP) queue(name string) chan AMQPMessage {
+	a.mu.Lock()
+	defer a.mu.Unlock()
+	if a.queues[name] == nil {
+		a.queues[name] = make(chan AMQPMessage, 64)
+	}
+	return a.queues[name]
+}
+
+func (a *memAMQP) Publish(ctx context.Context, routingKey string, msg AMQPMessage) error {
+	a.mu.Lock()
+	a.published = append(a.published, msg)
+	a.mu.Unlock()
+	a.queue(routingKey) <- msg
+	return nil
+}
+
+func (a *memAMQP) Consume(queue string, handler func(msg AMQPMessage)) (func() error, error) {
+	messages := a.queue(queue)
+	cancelled := make(chan struct{})
+	stopped := make(chan struct{})
+	go func() {
+		defer close(stopped)
+		for {
+			select {
+			case msg := <-messages:
+				handler(msg)
+			case <-cancelled:
+				return
+			}
+		}
+	}()
+	return func() error {
+		close(cancelled)
+		<-stopped
+		return nil
+	}, nil
+}
+
+func TestDialAMQP(t *testing.T) {
+	broker := newMemAMQP()
+	ctx, cancel := context.WithCancel(context.Background())
+	served := make(chan error, 1)
+	go func() {
+		served <- ServeAMQP(ctx, broker, "math", newTestMux(t))
+	}()
+
+	conn, err := DialAMQP(broker, "math", "amq.rabbitmq.reply-to", nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	var result int
+	err = conn.Call(context.Background(), "subtract", []int{42, 23}, &result)
+	if err != nil || result != 19 {
+		t.Errorf("Call() = %v, %v, want %v", result, err, 19)
+	}
+	err = conn.Notify(context.Background(), "subtract", []int{42, 23})
+	if err != nil {
+		t.Errorf("Notify() = %v, want nil", err)
+	}
+	err = conn.Close()
+	if err != nil {
+		t.Errorf("Close() = %v, want nil", err)
+	}
+	cancel()
+	if err := <-served; err != nil {
+		t.Errorf("ServeAMQP() = %v, want nil", err)
+	}
+
+	broker.mu.Lock()
+	defer broker.mu.Unlock()
+	want := []AMQPMessage{
+		{ContentType: amqpContentType, ReplyTo: "amq.rabbitmq.reply-to", CorrelationID: "1"},
+		{ContentType: amqpContentType, CorrelationID: "1"},
+		{ContentType: amqpContentType},
+	}
+	if len(broker.published) != len(want) {
+		t.Fatalf("published %v messages, want %v", len(broker.published), len(want))
+	}
+	for i, msg := range broker.published {
+		msg.Body = nil
+		if !reflect.DeepEqual(msg, want[i]) {
+			t.Errorf("published[%v] = %+v, want %+v", i, msg, want[i])
+		}
+	}
+}
@@ -0,0 +1,63 @@
+/*  Copyright 2022  Kosmas Valianos (kosmas.valianos@gmail.com)
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    https://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License. */
+
+package gojsonrpc
+
+// AppendRequest appends the request using the method, the params and the id to dst, as NewRequest creates it.
+// Returns the extended buffer, or dst unchanged and an error
+func AppendRequest[I idInterface](dst []byte, method string, params any, id I, options ...MarshalOption) ([]byte, error) {
+	config := newMarshalConfig(options)
+	request, err := buildRequest(method, params, id, &config)
+	if err != nil {
+		return dst, err
+	}
+	return appendMessage(dst, request, &config)
+}
+
+// AppendNotification appends the notification using the method and the params to dst, as NewNotification creates it.
+// Returns the extended buffer, or dst unchanged and an error
+func AppendNotification(dst []byte, method string, params any, options ...MarshalOption) ([]byte, error) {
+	config := newMarshalConfig(options)
+	notification, err := buildNotification(method, params, &config)
+	if err != nil {
+		return dst, err
+	}
+	return appendMessage(dst, notification, &config)
+}
+
+// AppendResultResponse appends the response from a result object using the id to dst, as NewResultResponse creates it.
+// Returns the extended buffer, or dst unchanged and an error
+func AppendResultResponse[I idInterface](dst []byte, id I, result any, options ...MarshalOption) ([]byte, error) {
+	config := newMarshalConfig(options)
+	resultRaw, err := config.marshalValue(result)
+	if err != nil {
+		return dst, err
+	}
+	return appendMessage(dst, &response{
+		JsonRPC: jsonRPCProtocol,
+		Result:  resultRaw,
+		ID:      id,
+	}, &config)
+}
+
+// AppendErrorResponse appends the response from a *jsonRPCError object using the id to dst, as NewErrorResponse creates it.
+// Returns the extended buffer, or dst unchanged and an error
+func AppendErrorResponse(dst []byte, id any, jsonError *jsonRPCError, options ...MarshalOption) ([]byte, error) {
+	response, err := buildErrorResponse(id, jsonError)
+	if err != nil {
+		return dst, err
+	}
+	config := newMarshalConfig(options)
+	return appendMessage(dst, response, &config)
+}
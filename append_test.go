@@ -0,0 +1,120 @@
+/*  Copyright 2022  Kosmas Valianos (kosmas.valianos@gmail.com)
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    https://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License. */
+
+package gojsonrpc
+
+import (
+	"bytes"
+	"testing"
+)
+
+func TestAppend(t *testing.T) {
+	prefix := []byte("prefix\n")
+	tests := []struct {
+		name   string
+		append func(dst []byte) ([]byte, error)
+		new    func() ([]byte, error)
+	}{
+		{
+			name:   "AppendRequest",
+			append: func(dst []byte) ([]byte, error) { return AppendRequest(dst, "subtract", []int{42, 23}, 1) },
+			new:    func() ([]byte, error) { return NewRequest("subtract", []int{42, 23}, 1) },
+		},
+		{
+			name:   "AppendNotification",
+			append: func(dst []byte) ([]byte, error) { return AppendNotification(dst, "update", nil) },
+			new:    func() ([]byte, error) { return NewNotification("update", nil) },
+		},
+		{
+			name:   "AppendResultResponse",
+			append: func(dst []byte) ([]byte, error) { return AppendResultResponse(dst, "abc", 19) },
+			new:    func() ([]byte, error) { return NewResultResponse("abc", 19) },
+		},
+		{
+			name:   "AppendErrorResponse",
+			append: func(dst []byte) ([]byte, error) { return AppendErrorResponse(dst, nil, &JsonParseError) },
+			new:    func() ([]byte, error) { return NewErrorResponse(nil, &JsonParseError) },
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			want, err := tt.new()
+			if err != nil {
+				t.Fatal(err)
+			}
+			want = append(append([]byte{}, prefix...), want...)
+			got, err := tt.append(append([]byte{}, prefix...))
+			if err != nil {
+				t.Fatal(err)
+			}
+			if !bytes.Equal(got, want) {
+				t.Errorf("%v() = %v, want %v", tt.name, string(got), string(want))
+			}
+		})
+	}
+}
+
+func TestAppend_error(t *testing.T) {
+	tests := []struct {
+		name   string
+		append func(dst []byte) ([]byte, error)
+	}{
+		{
+			name:   "AppendRequest",
+			append: func(dst []byte) ([]byte, error) { return AppendRequest(dst, "subtract", make(chan int), 1) },
+		},
+		{
+			name:   "AppendNotification",
+			append: func(dst []byte) ([]byte, error) { return AppendNotification(dst, "update", make(chan int)) },
+		},
+		{
+			name:   "AppendResultResponse",
+			append: func(dst []byte) ([]byte, error) { return AppendResultResponse(dst, 1, make(chan int)) },
+		},
+		{
+			name:   "AppendErrorResponse",
+			append: func(dst []byte) ([]byte, error) { return AppendErrorResponse(dst, nil, &JsonMethodNotFound) },
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			dst := []byte("prefix\n")
+			got, err := tt.append(dst)
+			if err == nil {
+				t.Fatalf("%v() error = nil, want an error", tt.name)
+			}
+			if !bytes.Equal(got, dst) {
+				t.Errorf("%v() = %v, want %v", tt.name, string(got), string(dst))
+			}
+		})
+	}
+}
+
+func BenchmarkAppend(b *testing.B) {
+	id := "2b6f1a8e-8d0a-4c4b-9d8e-3f1b2c7a9e10"
+	buffer := make([]byte, 0, 512)
+
+	b.Run("AppendRequest", func(b *testing.B) {
+		b.ReportAllocs()
+		for i := 0; i < b.N; i++ {
+			buffer, _ = AppendRequest(buffer[:0], "subtract", nil, id)
+		}
+	})
+	b.Run("AppendErrorResponse", func(b *testing.B) {
+		b.ReportAllocs()
+		for i := 0; i < b.N; i++ {
+			buffer, _ = AppendErrorResponse(buffer[:0], id, &JsonMethodNotFound)
+		}
+	})
+}
@@ -0,0 +1,100 @@
+/*  Copyright 2022  Kosmas Valianos (kosmas.valianos@gmail.com)
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    https://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License. */
+
+package gojsonrpc
+
+import (
+	"context"
+	"errors"
+	"sync/atomic"
+)
+
+// BalancingStrategy selects the endpoint of each message of a BalancedTransport
+type BalancingStrategy int
+
+const (
+	// RoundRobin uses the endpoints in turn
+	RoundRobin BalancingStrategy = iota
+	// LeastPending uses the endpoint with the fewest calls in flight, the first one in case of a tie
+	LeastPending
+)
+
+// BalancedTransport is a Transport spreading the messages over several endpoints according to a BalancingStrategy
+type BalancedTransport struct {
+	transports []Transport
+	strategy   BalancingStrategy
+	next       atomic.Uint64
+	pending    []atomic.Int64
+}
+
+// NewBalancedTransport creates a BalancedTransport over the transports of the endpoints using the strategy.
+// Returns a *BalancedTransport object or an error if there are no transports or the strategy is unknown
+func NewBalancedTransport(transports []Transport, strategy BalancingStrategy) (*BalancedTransport, error) {
+	if len(transports) == 0 {
+		return nil, errors.New("no transports passed as parameter")
+	}
+	for _, transport := range transports {
+		if transport == nil {
+			return nil, errors.New("transport must not be nil")
+		}
+	}
+	if strategy != RoundRobin && strategy != LeastPending {
+		return nil, errors.New("unknown balancing strategy")
+	}
+	return &BalancedTransport{
+		transports: transports,
+		strategy:   strategy,
+		pending:    make([]atomic.Int64, len(transports)),
+	}, nil
+}
+
+// RoundTrip sends a request or a batch through the endpoint selected by the strategy.
+// Returns the raw bytes of the response or an error
+func (t *BalancedTransport) RoundTrip(ctx context.Context, requestRaw []byte) ([]byte, error) {
+	i := t.pick()
+	t.pending[i].Add(1)
+	defer t.pending[i].Add(-1)
+	return t.transports[i].RoundTrip(ctx, requestRaw)
+}
+
+// Send sends a notification through the endpoint selected by the strategy
+func (t *BalancedTransport) Send(ctx context.Context, notificationRaw []byte) error {
+	i := t.pick()
+	t.pending[i].Add(1)
+	defer t.pending[i].Add(-1)
+	return t.transports[i].Send(ctx, notificationRaw)
+}
+
+// Pending returns the number of calls in flight through each endpoint
+func (t *BalancedTransport) Pending() []int64 {
+	pending := make([]int64, len(t.pending))
+	for i := range t.pending {
+		pending[i] = t.pending[i].Load()
+	}
+	return pending
+}
+
+// pick returns the index of the endpoint of the next message
+func (t *BalancedTransport) pick() int {
+	if t.strategy == RoundRobin {
+		return int((t.next.Add(1) - 1) % uint64(len(t.transports)))
+	}
+	least := 0
+	for i := 1; i < len(t.pending); i++ {
+		if t.pending[i].Load() < t.pending[least].Load() {
+			least = i
+		}
+	}
+	return least
+}
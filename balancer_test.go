@@ -0,0 +1,96 @@
+/*  Copyright 2022  Kosmas Valianos (kosmas.valianos@gmail.com)
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    https://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License. */
+
+package gojsonrpc
+
+import (
+	"context"
+	"reflect"
+	"sync/atomic"
+	"testing"
+	"time"
+)
+
+// countingTransport counts the round trips served with a mux
+type countingTransport struct {
+	muxTransport
+	roundTrips atomic.Int64
+}
+
+func (t *countingTransport) RoundTrip(ctx context.Context, requestRaw []byte) ([]byte, error) {
+	t.roundTrips.Add(1)
+	return t.muxTransport.RoundTrip(ctx, requestRaw)
+}
+
+func TestBalancedTransport(t *testing.T) {
+	t.Run("Round robin", func(t *testing.T) {
+		endpoints := []*countingTransport{
+			{muxTransport: muxTransport{mux: newTestMux(t)}},
+			{muxTransport: muxTransport{mux: newTestMux(t)}},
+			{muxTransport: muxTransport{mux: newTestMux(t)}},
+		}
+		transport, err := NewBalancedTransport([]Transport{endpoints[0], endpoints[1], endpoints[2]}, RoundRobin)
+		if err != nil {
+			t.Fatal(err)
+		}
+		client := NewClient(transport)
+		for i := 0; i < 9; i++ {
+			if result, err := Call[int](context.Background(), client, "subtract", []int{42, 23}); err != nil || result != 19 {
+				t.Fatalf("Call() = %v, %v, want 19", result, err)
+			}
+		}
+		for i, endpoint := range endpoints {
+			if got := endpoint.roundTrips.Load(); got != 3 {
+				t.Errorf("round trips of endpoint %v = %v, want 3", i, got)
+			}
+		}
+	})
+
+	t.Run("Least pending", func(t *testing.T) {
+		unblock := make(chan struct{})
+		slow := &countingTransport{muxTransport: muxTransport{mux: newSlowTestMux(t, unblock)}}
+		fast := &countingTransport{muxTransport: muxTransport{mux: newTestMux(t)}}
+		transport, err := NewBalancedTransport([]Transport{slow, fast}, LeastPending)
+		if err != nil {
+			t.Fatal(err)
+		}
+		client := NewClient(transport)
+
+		// The slow call keeps the first endpoint busy
+		call := client.Go(context.Background(), "slow", nil)
+		for slow.roundTrips.Load() == 0 {
+			time.Sleep(time.Millisecond)
+		}
+		for i := 0; i < 3; i++ {
+			if result, err := Call[int](context.Background(), client, "subtract", []int{42, 23}); err != nil || result != 19 {
+				t.Fatalf("Call() = %v, %v, want 19", result, err)
+			}
+		}
+		if got, want := transport.Pending(), []int64{1, 0}; !reflect.DeepEqual(got, want) {
+			t.Errorf("Pending() = %v, want %v", got, want)
+		}
+		close(unblock)
+		<-call.Done
+		if got := fast.roundTrips.Load(); got != 3 {
+			t.Errorf("round trips of the idle endpoint = %v, want 3", got)
+		}
+	})
+
+	if _, err := NewBalancedTransport(nil, RoundRobin); err == nil {
+		t.Error("NewBalancedTransport() without transports succeeded")
+	}
+	if _, err := NewBalancedTransport([]Transport{&muxTransport{mux: newTestMux(t)}}, BalancingStrategy(42)); err == nil {
+		t.Error("NewBalancedTransport() with an unknown strategy succeeded")
+	}
+}
@@ -0,0 +1,217 @@
+/*  Copyright 2022  Kosmas Valianos (kosmas.valianos@gmail.com)
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    https://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License. */
+
+package gojsonrpc
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"sync"
+	"time"
+)
+
+// serveBatch processes the requests and notifications of a batch concurrently.
+// Returns the raw bytes of the array of the responses in the order of the requests or nil if there are only notifications
+func (m *Mux) serveBatch(ctx context.Context, batchRaw []byte) []byte {
+	if m.maxBatchSize > 0 && exceedsBatchSize(batchRaw, m.maxBatchSize) {
+		return newNullIDErrorResponse(&JsonInvalidRequest)
+	}
+
+	var messagesRaw []json.RawMessage
+	err := json.Unmarshal(batchRaw, &messagesRaw)
+	if err != nil || len(messagesRaw) == 0 {
+		return newNullIDErrorResponse(&JsonInvalidRequest)
+	}
+
+	responsesRaw := make([][]byte, len(messagesRaw))
+	var wg sync.WaitGroup
+	for i, messageRaw := range messagesRaw {
+		if jsonKind(messageRaw) != '{' {
+			responsesRaw[i] = newNullIDErrorResponse(&JsonInvalidRequest)
+			continue
+		}
+		wg.Add(1)
+		go func(i int, messageRaw json.RawMessage) {
+			defer wg.Done()
+			if m.metrics != nil || m.logger != nil {
+				responsesRaw[i] = m.serveObservedCall(ctx, messageRaw)
+				return
+			}
+			responsesRaw[i] = m.Serve(ctx, messageRaw)
+		}(i, messageRaw)
+	}
+	wg.Wait()
+
+	var batchResponseRaw []byte
+	for _, responseRaw := range responsesRaw {
+		if responseRaw == nil {
+			continue
+		}
+		if batchResponseRaw == nil {
+			batchResponseRaw = append(batchResponseRaw, '[')
+		} else {
+			batchResponseRaw = append(batchResponseRaw, ',')
+		}
+		batchResponseRaw = append(batchResponseRaw, bytes.TrimSuffix(responseRaw, []byte("\n"))...)
+	}
+	if batchResponseRaw == nil {
+		return nil
+	}
+	return append(batchResponseRaw, ']', '\n')
+}
+
+// exceedsBatchSize reports whether a valid JSON array has more than maxBatchSize elements, counting them
+// without unmarshaling any
+func exceedsBatchSize(batchRaw []byte, maxBatchSize int) bool {
+	i := skipWhitespace(batchRaw, skipWhitespace(batchRaw, 0)+1)
+	for count := 0; i < len(batchRaw) && batchRaw[i] != ']'; count++ {
+		if count == maxBatchSize {
+			return true
+		}
+		var ok bool
+		i, ok = skipValue(batchRaw, i)
+		if !ok {
+			return false
+		}
+		i = skipWhitespace(batchRaw, i)
+		if i < len(batchRaw) && batchRaw[i] == ',' {
+			i = skipWhitespace(batchRaw, i+1)
+		}
+	}
+	return false
+}
+
+// BatchItem is a request or notification of a batch sent by Client.CallBatch
+type BatchItem struct {
+	Method string
+	Params any
+	// Notification sends the item as a notification which is never answered
+	Notification bool
+}
+
+// BatchResult is the outcome of a request of a batch sent by Client.CallBatch
+type BatchResult struct {
+	// Result is the raw result of the response, use Unmarshal to decode it
+	Result json.RawMessage
+	// Error is the *jsonRPCError object of the response or an error if the request has no response
+	Error error
+
+	client *Client
+}
+
+// Unmarshal unmarshals the result into result.
+// Returns the error of the request or an error if the result cannot be unmarshaled
+func (r BatchResult) Unmarshal(result any) error {
+	if r.Error != nil {
+		return r.Error
+	}
+	if r.client == nil {
+		return errors.New("no result of a notification")
+	}
+	return r.client.unmarshalResult(r.Result, result)
+}
+
+// CallBatch sends the items as a batch in one round trip and correlates the responses by their IDs.
+// Returns a []BatchResult in the order of the items, with zero values for the notifications,
+// or an error if the batch could not be sent or its response could not be parsed
+func (c *Client) CallBatch(ctx context.Context, items []BatchItem) (results []BatchResult, err error) {
+	if len(items) == 0 {
+		return nil, errors.New("batch must not be empty")
+	}
+	if c.logger != nil {
+		start := time.Now()
+		defer func() {
+			c.logger.logBatch(ctx, len(items), err, time.Since(start))
+		}()
+	}
+
+	ids := make(map[string]int)
+	batchRaw := []byte{'['}
+	for i, item := range items {
+		paramsRaw, err := c.marshalParams(item.Params)
+		if err != nil {
+			return nil, err
+		}
+		var messageRaw []byte
+		if item.Notification {
+			messageRaw, err = NewNotification(item.Method, paramsRaw)
+		} else {
+			id := c.idGenerator.NextID()
+			ids[idKey(id)] = i
+			messageRaw, err = newRequestWithID(item.Method, paramsRaw, id)
+		}
+		if err != nil {
+			return nil, err
+		}
+		if i > 0 {
+			batchRaw = append(batchRaw, ',')
+		}
+		batchRaw = append(batchRaw, bytes.TrimSuffix(messageRaw, []byte("\n"))...)
+	}
+	batchRaw = append(batchRaw, ']', '\n')
+
+	results = make([]BatchResult, len(items))
+	if len(ids) == 0 {
+		return results, c.transport.Send(ctx, batchRaw)
+	}
+	responseRaw, err := c.roundTrip(ctx, batchRaw)
+	if err != nil {
+		return nil, err
+	}
+
+	if jsonKind(responseRaw) != '[' {
+		// The batch as a whole was rejected
+		response, err := ParseResponseZeroCopy(responseRaw)
+		if err != nil {
+			return nil, err
+		}
+		if response.Error == nil {
+			return nil, errors.New("response of a batch must be an array")
+		}
+		for _, i := range ids {
+			results[i].Error = response.Error
+		}
+		return results, nil
+	}
+
+	var responsesRaw []json.RawMessage
+	err = json.Unmarshal(responseRaw, &responsesRaw)
+	if err != nil {
+		return nil, err
+	}
+	for _, responseRaw := range responsesRaw {
+		response, err := ParseResponseZeroCopy(responseRaw)
+		if err != nil {
+			return nil, err
+		}
+		i, found := ids[idKey(response.ID)]
+		if response.ID == nil || !found || results[i].client != nil {
+			// Errors of requests the server could not parse are not correlated
+			continue
+		}
+		results[i] = BatchResult{Result: response.Result, client: c}
+		if response.Error != nil {
+			results[i].Error = response.Error
+		}
+	}
+	for id, i := range ids {
+		if results[i].client == nil {
+			results[i].Error = fmt.Errorf("no response for the request with ID %v", id)
+		}
+	}
+	return results, nil
+}
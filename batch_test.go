@@ -0,0 +1,121 @@
+/*  Copyright 2022  Kosmas Valianos (kosmas.valianos@gmail.com)
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    https://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License. */
+
+package gojsonrpc
+
+import (
+	"bytes"
+	"context"
+	"testing"
+)
+
+func TestMux_ServeBatch(t *testing.T) {
+	tests := []struct {
+		name     string
+		rawBytes []byte
+		want     []byte
+	}{
+		{
+			name:     "Batch",
+			rawBytes: []byte(`[{"jsonrpc": "2.0", "method": "subtract", "params": [42, 23], "id": 1}, {"jsonrpc": "2.0", "method": "subtract", "params": [42, 23]}, {"jsonrpc": "2.0", "method": "multiply", "id": "2"}, 1]`),
+			want:     []byte(`[{"jsonrpc":"2.0","result":19,"id":1},{"jsonrpc":"2.0","error":{"code":-32601,"message":"Method not found"},"id":"2"},{"jsonrpc":"2.0","error":{"code":-32600,"message":"Invalid Request"},"id":null}]` + "\n"),
+		},
+		{
+			name:     "Only notifications",
+			rawBytes: []byte(`[{"jsonrpc": "2.0", "method": "subtract", "params": [42, 23]}, {"jsonrpc": "2.0", "method": "update"}]`),
+			want:     nil,
+		},
+		{
+			name:     "Empty batch",
+			rawBytes: []byte(`[]`),
+			want:     []byte(`{"jsonrpc":"2.0","error":{"code":-32600,"message":"Invalid Request"},"id":null}` + "\n"),
+		},
+		{
+			name:     "Invalid JSON",
+			rawBytes: []byte(`[{"jsonrpc": "2.0", "method": "subtract", "params": [42, 23], "id": 1}, {"jsonrpc": "2.0", "method"]`),
+			want:     []byte(`{"jsonrpc":"2.0","error":{"code":-32700,"message":"Parse error"},"id":null}` + "\n"),
+		},
+	}
+
+	mux := newTestMux(t)
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			jsonRPCResponseRaw := mux.Serve(context.Background(), tt.rawBytes)
+			if !bytes.Equal(jsonRPCResponseRaw, tt.want) {
+				t.Errorf("Serve() = %v, want %v", string(jsonRPCResponseRaw), string(tt.want))
+			}
+		})
+	}
+}
+
+func TestClient_CallBatch(t *testing.T) {
+	client := NewClient(&muxTransport{mux: newTestMux(t)})
+	results, err := client.CallBatch(context.Background(), []BatchItem{
+		{Method: "subtract", Params: []int{42, 23}},
+		{Method: "subtract", Params: []int{42, 23}, Notification: true},
+		{Method: "multiply", Params: []int{42, 23}},
+		{Method: "subtract", Params: []int{23, 42}},
+	})
+	if err != nil {
+		t.Fatalf("CallBatch() error = %v", err)
+	}
+	if len(results) != 4 {
+		t.Fatalf("CallBatch() = %v, want 4 results", results)
+	}
+
+	for i, want := range map[int]int{0: 19, 3: -19} {
+		var result int
+		err := results[i].Unmarshal(&result)
+		if err != nil || result != want {
+			t.Errorf("Unmarshal() of result %v = %v, %v, want %v", i, result, err, want)
+		}
+	}
+	if err := results[1].Unmarshal(new(int)); err == nil {
+		t.Error("Unmarshal() of the result of a notification succeeded")
+	}
+	if jsonRPCError, ok := AsJsonRPCError(results[2].Error); !ok || jsonRPCError.Code != MethodNotFound {
+		t.Errorf("CallBatch() error of result 2 = %v, want %v", results[2].Error, JsonMethodNotFound)
+	}
+
+	results, err = client.CallBatch(context.Background(), []BatchItem{{Method: "update", Notification: true}})
+	if err != nil || len(results) != 1 {
+		t.Errorf("CallBatch() of notifications = %v, %v", results, err)
+	}
+	_, err = client.CallBatch(context.Background(), nil)
+	if err == nil {
+		t.Error("CallBatch() of an empty batch succeeded")
+	}
+	results, err = NewClient(staticTransport(`{"jsonrpc":"2.0","error":{"code":-32600,"message":"Invalid Request"},"id":null}`)).
+		CallBatch(context.Background(), []BatchItem{{Method: "subtract"}})
+	if err != nil || results[0].Error == nil {
+		t.Errorf("CallBatch() of a rejected batch = %v, %v", results, err)
+	}
+}
+
+func Test_exceedsBatchSize(t *testing.T) {
+	tests := []struct {
+		batchRaw string
+		want     bool
+	}{
+		{batchRaw: `[]`, want: false},
+		{batchRaw: ` [ 1 , "]" , {"a":[1,2]} ] `, want: false},
+		{batchRaw: `[1,2,3,4]`, want: true},
+		{batchRaw: `[{"a":"],"},[1,2],null,true]`, want: true},
+	}
+	for _, tt := range tests {
+		if got := exceedsBatchSize([]byte(tt.batchRaw), 3); got != tt.want {
+			t.Errorf("exceedsBatchSize(%v) = %v, want %v", tt.batchRaw, got, tt.want)
+		}
+	}
+}
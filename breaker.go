@@ -0,0 +1,93 @@
+/*  Copyright 2022  Kosmas Valianos (kosmas.valianos@gmail.com)
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    https://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License. */
+
+package gojsonrpc
+
+import (
+	"context"
+	"errors"
+	"sync"
+	"time"
+)
+
+// ErrCircuitOpen is returned without calling the server while the circuit breaker of a Client is open
+var ErrCircuitOpen = errors.New("circuit breaker open")
+
+// WithCircuitBreaker makes the calls fail fast with ErrCircuitOpen for the cooldown once threshold consecutive calls failed
+// in the transport or with an internal or server error. After the cooldown a single trial call is let through
+// which closes the circuit again if it succeeds. A zero or negative threshold means no circuit breaker
+func WithCircuitBreaker(threshold int, cooldown time.Duration) ClientOption {
+	return func(c *Client) {
+		c.breaker = nil
+		if threshold > 0 {
+			c.breaker = &circuitBreaker{threshold: threshold, cooldown: cooldown}
+		}
+	}
+}
+
+type circuitBreaker struct {
+	threshold int
+	cooldown  time.Duration
+
+	mu        sync.Mutex
+	failures  int
+	openUntil time.Time
+	trial     bool
+}
+
+// allow reports whether a call may be attempted, letting a single trial call through once the cooldown is over,
+// and whether the call is that trial call
+func (b *circuitBreaker) allow() (allowed bool, trial bool) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	if b.failures < b.threshold {
+		return true, false
+	}
+	if b.trial || time.Now().Before(b.openUntil) {
+		return false, false
+	}
+	b.trial = true
+	return true, true
+}
+
+// record records the outcome of an attempted call, opening the circuit after too many consecutive failures.
+// Only the trial call, as reported by allow, lets another trial call through, not the calls started before the circuit
+// opened. Calls cancelled by the caller do not count
+func (b *circuitBreaker) record(ctx context.Context, trial bool, responseRaw []byte, err error) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	if trial {
+		b.trial = false
+	}
+	if err != nil && errors.Is(ctx.Err(), context.Canceled) {
+		return
+	}
+	if !failedCall(responseRaw, err) {
+		b.failures = 0
+		return
+	}
+	b.failures++
+	if b.failures >= b.threshold {
+		b.openUntil = time.Now().Add(b.cooldown)
+	}
+}
+
+// failedCall reports whether the outcome of a call counts as a failure of the transport or of the server
+func failedCall(responseRaw []byte, err error) bool {
+	if err != nil {
+		return true
+	}
+	code, ok := responseErrorCode(responseRaw)
+	return ok && (code == InternalError || IsServerError(code))
+}
@@ -0,0 +1,113 @@
+/*  Copyright 2022  Kosmas Valianos (kosmas.valianos@gmail.com)
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    https://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License. */
+
+package gojsonrpc
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+)
+
+func TestWithCircuitBreaker(t *testing.T) {
+	transport := &flakyTransport{muxTransport: muxTransport{mux: newTestMux(t)}, failures: 3}
+	client := NewClient(transport, WithCircuitBreaker(2, 50*time.Millisecond))
+	ctx := context.Background()
+
+	for i := 0; i < 2; i++ {
+		if _, err := Call[int](ctx, client, "subtract", []int{42, 23}); err == nil || errors.Is(err, ErrCircuitOpen) {
+			t.Fatalf("Call() error = %v, want the error of the transport", err)
+		}
+	}
+	// The circuit is open
+	if _, err := Call[int](ctx, client, "subtract", []int{42, 23}); !errors.Is(err, ErrCircuitOpen) {
+		t.Fatalf("Call() error = %v, want %v", err, ErrCircuitOpen)
+	}
+	if got := transport.attempts.Load(); got != 2 {
+		t.Errorf("attempts = %v, want 2", got)
+	}
+
+	// The trial call after the cooldown fails and reopens the circuit
+	time.Sleep(60 * time.Millisecond)
+	if _, err := Call[int](ctx, client, "subtract", []int{42, 23}); err == nil || errors.Is(err, ErrCircuitOpen) {
+		t.Fatalf("Call() error = %v, want the error of the transport", err)
+	}
+	if _, err := Call[int](ctx, client, "subtract", []int{42, 23}); !errors.Is(err, ErrCircuitOpen) {
+		t.Fatalf("Call() error = %v, want %v", err, ErrCircuitOpen)
+	}
+
+	// The trial call after the cooldown succeeds and closes the circuit
+	time.Sleep(60 * time.Millisecond)
+	for i := 0; i < 3; i++ {
+		if result, err := Call[int](ctx, client, "subtract", []int{42, 23}); err != nil || result != 19 {
+			t.Fatalf("Call() = %v, %v, want 19", result, err)
+		}
+	}
+
+	// Errors of the client's own making do not count
+	for i := 0; i < 3; i++ {
+		if _, err := Call[int](ctx, client, "multiply", []int{42, 23}); errors.Is(err, ErrCircuitOpen) {
+			t.Fatalf("Call() error = %v after method not found errors", err)
+		}
+	}
+}
+
+func Test_circuitBreaker_trial(t *testing.T) {
+	breaker := &circuitBreaker{threshold: 1, cooldown: time.Millisecond}
+	ctx := context.Background()
+	_, staleTrial := breaker.allow()
+	breaker.record(ctx, false, nil, errors.New("connection refused"))
+	time.Sleep(2 * time.Millisecond)
+
+	allowed, trial := breaker.allow()
+	if !allowed || !trial {
+		t.Fatalf("allow() = %v, %v, want the trial call", allowed, trial)
+	}
+	// A call started before the circuit opened completes while the trial call is outstanding
+	breaker.record(ctx, staleTrial, nil, errors.New("connection refused"))
+	time.Sleep(2 * time.Millisecond)
+	if allowed, _ := breaker.allow(); allowed {
+		t.Errorf("allow() = %v, want a single trial call", allowed)
+	}
+
+	breaker.record(ctx, trial, nil, errors.New("connection refused"))
+	time.Sleep(2 * time.Millisecond)
+	if allowed, trial := breaker.allow(); !allowed || !trial {
+		t.Errorf("allow() = %v, %v, want the next trial call", allowed, trial)
+	}
+}
+
+func Test_failedCall(t *testing.T) {
+	tests := []struct {
+		name        string
+		responseRaw []byte
+		err         error
+		want        bool
+	}{
+		{name: "Transport failure", err: errors.New("connection reset"), want: true},
+		{name: "Result", responseRaw: []byte(`{"jsonrpc":"2.0","result":19,"id":1}`), want: false},
+		{name: "Internal error", responseRaw: []byte(`{"jsonrpc":"2.0","error":{"code":-32603,"message":"Internal error"},"id":1}`), want: true},
+		{name: "Server error", responseRaw: []byte(`{"jsonrpc":"2.0","error":{"code":-32003,"message":"Server busy"},"id":1}`), want: true},
+		{name: "Invalid params", responseRaw: []byte(`{"jsonrpc":"2.0","error":{"code":-32602,"message":"Invalid method parameters"},"id":1}`), want: false},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := failedCall(tt.responseRaw, tt.err); got != tt.want {
+				t.Errorf("failedCall() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
@@ -0,0 +1,129 @@
+/*  Copyright 2022  Kosmas Valianos (kosmas.valianos@gmail.com)
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    https://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License. */
+
+package gojsonrpc
+
+import (
+	"context"
+	"crypto/rand"
+	"fmt"
+	"sync"
+)
+
+// brokerConn is a MessageConn over a message broker. It publishes the messages written with a function and reads
+// the messages delivered to its subscription
+type brokerConn struct {
+	publish     func(ctx context.Context, messageRaw []byte) error
+	unsubscribe func() error
+	messages    chan []byte
+	closed      chan struct{}
+	closeOnce   sync.Once
+	closeErr    error
+}
+
+// newBrokerConn creates a brokerConn publishing the messages with the publish function. Its subscription must pass
+// the messages to deliver and set its unsubscribe function before it is used
+func newBrokerConn(publish func(ctx context.Context, messageRaw []byte) error) *brokerConn {
+	return &brokerConn{
+		publish:  publish,
+		messages: make(chan []byte, pipeBuffer),
+		closed:   make(chan struct{}),
+	}
+}
+
+// deliver queues a message of the subscription for ReadMessage, waiting while too many are queued
+func (c *brokerConn) deliver(messageRaw []byte) {
+	select {
+	case c.messages <- append([]byte(nil), messageRaw...):
+	case <-c.closed:
+	}
+}
+
+// WriteMessage publishes a message
+func (c *brokerConn) WriteMessage(ctx context.Context, messageRaw []byte) error {
+	select {
+	case <-c.closed:
+		return ErrTransportClosed
+	default:
+	}
+	return c.publish(ctx, messageRaw)
+}
+
+// ReadMessage returns the next message delivered to the subscription or ErrTransportClosed once closed
+func (c *brokerConn) ReadMessage() ([]byte, error) {
+	select {
+	case <-c.closed:
+		return nil, ErrTransportClosed
+	default:
+	}
+	select {
+	case messageRaw := <-c.messages:
+		return messageRaw, nil
+	case <-c.closed:
+		return nil, ErrTransportClosed
+	}
+}
+
+// Close ends the subscription
+func (c *brokerConn) Close() error {
+	c.closeOnce.Do(func() {
+		close(c.closed)
+		if c.unsubscribe != nil {
+			c.closeErr = c.unsubscribe()
+		}
+	})
+	return c.closeErr
+}
+
+// unsubscribeAll combines the unsubscribe functions of many subscriptions.
+// Returns the first error
+func unsubscribeAll(unsubscribes ...func() error) func() error {
+	return func() error {
+		var firstErr error
+		for _, unsubscribe := range unsubscribes {
+			if err := unsubscribe(); err != nil && firstErr == nil {
+				firstErr = err
+			}
+		}
+		return firstErr
+	}
+}
+
+// serveBrokerMessage serves a message received from a broker with the mux in its own goroutine and passes its
+// response, if any, to the reply function
+func serveBrokerMessage(ctx context.Context, mux *Mux, messageRaw []byte, reply func(responseRaw []byte)) {
+	messageRaw = append([]byte(nil), messageRaw...)
+	go func() {
+		responseRaw := mux.Serve(ctx, messageRaw)
+		if responseRaw != nil {
+			reply(responseRaw)
+		}
+	}()
+}
+
+// isNotificationMessage returns whether a message is a notification or a batch of notifications only
+func isNotificationMessage(messageRaw []byte) bool {
+	ids, err := messageIDs(messageRaw)
+	return err == nil && len(ids) == 0
+}
+
+// newBrokerClientID returns a random ID naming the reply address of a client
+func newBrokerClientID() (string, error) {
+	var idRaw [12]byte
+	_, err := rand.Read(idRaw[:])
+	if err != nil {
+		return "", err
+	}
+	return fmt.Sprintf("%x", idRaw), nil
+}
@@ -0,0 +1,40 @@
+/*  Copyright 2022  Kosmas Valianos (kosmas.valianos@gmail.com)
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    https://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License. */
+
+package gojsonrpc
+
+import (
+	"testing"
+)
+
+func Test_isNotificationMessage(t *testing.T) {
+	tests := []struct {
+		name       string
+		messageRaw string
+		want       bool
+	}{
+		{"notification", `{"jsonrpc":"2.0","method":"update","params":[1]}`, true},
+		{"request", `{"jsonrpc":"2.0","method":"subtract","params":[42,23],"id":1}`, false},
+		{"notifications", `[{"jsonrpc":"2.0","method":"update"},{"jsonrpc":"2.0","method":"update"}]`, true},
+		{"mixed batch", `[{"jsonrpc":"2.0","method":"update"},{"jsonrpc":"2.0","method":"sum","id":"1"}]`, false},
+		{"invalid", `{"jsonrpc"`, false},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := isNotificationMessage([]byte(tt.messageRaw)); got != tt.want {
+				t.Errorf("isNotificationMessage() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
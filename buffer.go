@@ -0,0 +1,95 @@
+/*  Copyright 2022  Kosmas Valianos (kosmas.valianos@gmail.com)
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    https://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License. */
+
+package gojsonrpc
+
+import (
+	"bytes"
+	"encoding/json"
+	"sync"
+)
+
+// maxPooledBufferSize is the capacity above which a buffer is not returned to the pool,
+// so that a single huge message does not pin its memory for good
+const maxPooledBufferSize = 64 << 10
+
+// messageBuffer is a pooled buffer with its encoder, so that neither is allocated per message
+type messageBuffer struct {
+	buffer  bytes.Buffer
+	encoder *json.Encoder
+}
+
+var messageBufferPool = sync.Pool{
+	New: func() any {
+		messageBuffer := &messageBuffer{}
+		messageBuffer.encoder = json.NewEncoder(&messageBuffer.buffer)
+		return messageBuffer
+	},
+}
+
+// encodeMessage encodes the message as the config sets, by default as json.Marshal does followed by a newline,
+// using a pooled buffer.
+// Returns the raw bytes, owned by the caller, or an error
+func encodeMessage(message any, config *marshalConfig) ([]byte, error) {
+	messageBuffer, err := encodePooled(message, config.escapeHTML)
+	if err != nil {
+		return nil, err
+	}
+	defer messageBuffer.release()
+
+	body := messageBuffer.body()
+	return config.appendFormatted(make([]byte, 0, len(body)+len(config.delimiter)), body)
+}
+
+// appendMessage appends the encoding of the message as the config sets, by default followed by a newline, to dst.
+// Returns the extended buffer, or dst unchanged and an error
+func appendMessage(dst []byte, message any, config *marshalConfig) ([]byte, error) {
+	messageBuffer, err := encodePooled(message, config.escapeHTML)
+	if err != nil {
+		return dst, err
+	}
+	defer messageBuffer.release()
+	messageRaw, err := config.appendFormatted(dst, messageBuffer.body())
+	if err != nil {
+		return dst, err
+	}
+	return messageRaw, nil
+}
+
+// encodePooled encodes the message followed by a newline into a pooled buffer, which the caller releases.
+// Returns a *messageBuffer object or an error
+func encodePooled(message any, escapeHTML bool) (*messageBuffer, error) {
+	messageBuffer := messageBufferPool.Get().(*messageBuffer)
+	messageBuffer.encoder.SetEscapeHTML(escapeHTML)
+	// Encode appends the newline which terminates every message
+	if err := messageBuffer.encoder.Encode(message); err != nil {
+		messageBuffer.release()
+		return nil, err
+	}
+	return messageBuffer, nil
+}
+
+// body returns the encoded message without the newline which terminates it
+func (m *messageBuffer) body() []byte {
+	encoded := m.buffer.Bytes()
+	return encoded[:len(encoded)-1]
+}
+
+// release returns the buffer to the pool unless it has grown too large
+func (m *messageBuffer) release() {
+	if m.buffer.Cap() <= maxPooledBufferSize {
+		m.buffer.Reset()
+		messageBufferPool.Put(m)
+	}
+}
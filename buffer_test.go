@@ -0,0 +1,124 @@
+/*  Copyright 2022  Kosmas Valianos (kosmas.valianos@gmail.com)
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    https://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License. */
+
+package gojsonrpc
+
+import (
+	"bytes"
+	"encoding/json"
+	"strings"
+	"testing"
+)
+
+func Test_encodeMessage(t *testing.T) {
+	config := newMarshalConfig(nil)
+	tests := []struct {
+		name    string
+		message any
+	}{
+		{name: "request", message: &request{JsonRPC: jsonRPCProtocol, Method: "subtract", Params: json.RawMessage(`[42, 23]`), ID: 1}},
+		{name: "notification", message: &notification{JsonRPC: jsonRPCProtocol, Method: "update"}},
+		{name: "error response", message: &response{JsonRPC: jsonRPCProtocol, Error: &JsonInvalidRequest}},
+		{name: "HTML characters", message: &notification{JsonRPC: jsonRPCProtocol, Method: "<b>&</b>"}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			want, err := json.Marshal(tt.message)
+			if err != nil {
+				t.Fatal(err)
+			}
+			want = append(want, '\n')
+			got, err := encodeMessage(tt.message, &config)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if !bytes.Equal(got, want) {
+				t.Errorf("encodeMessage() = %v, want %v", string(got), string(want))
+			}
+		})
+	}
+}
+
+func Test_encodeMessage_owned(t *testing.T) {
+	config := newMarshalConfig(nil)
+	first, err := encodeMessage(&notification{JsonRPC: jsonRPCProtocol, Method: "first"}, &config)
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := string(first)
+	if _, err := encodeMessage(&notification{JsonRPC: jsonRPCProtocol, Method: "second"}, &config); err != nil {
+		t.Fatal(err)
+	}
+	if string(first) != want {
+		t.Errorf("encodeMessage() = %v after reusing the buffer, want %v", string(first), want)
+	}
+	if cap(first) != len(first) {
+		t.Errorf("cap(encodeMessage()) = %v, want %v", cap(first), len(first))
+	}
+
+	// A failed encoding must not leak into the next message
+	if _, err := encodeMessage(&notification{JsonRPC: jsonRPCProtocol, Method: "bad", Params: json.RawMessage(`{`)}, &config); err == nil {
+		t.Error("encodeMessage() error = nil, want an error")
+	}
+	got, err := encodeMessage(&notification{JsonRPC: jsonRPCProtocol, Method: "first"}, &config)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if string(got) != want {
+		t.Errorf("encodeMessage() = %v, want %v", string(got), want)
+	}
+}
+
+func Test_encodeMessage_large(t *testing.T) {
+	config := newMarshalConfig(nil)
+	method := strings.Repeat("a", 2*maxPooledBufferSize)
+	got, err := encodeMessage(&notification{JsonRPC: jsonRPCProtocol, Method: method}, &config)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !bytes.Contains(got, []byte(method)) {
+		t.Errorf("encodeMessage() does not contain the method")
+	}
+}
+
+func BenchmarkConstructors(b *testing.B) {
+	params := []int{42, 23}
+	result := map[string]any{"balance": 42.5, "currency": "EUR"}
+	id := "2b6f1a8e-8d0a-4c4b-9d8e-3f1b2c7a9e10"
+
+	b.Run("NewRequest", func(b *testing.B) {
+		b.ReportAllocs()
+		for i := 0; i < b.N; i++ {
+			_, _ = NewRequest("subtract", params, id)
+		}
+	})
+	b.Run("NewNotification", func(b *testing.B) {
+		b.ReportAllocs()
+		for i := 0; i < b.N; i++ {
+			_, _ = NewNotification("update", params)
+		}
+	})
+	b.Run("NewResultResponse", func(b *testing.B) {
+		b.ReportAllocs()
+		for i := 0; i < b.N; i++ {
+			_, _ = NewResultResponse(id, result)
+		}
+	})
+	b.Run("NewErrorResponse", func(b *testing.B) {
+		b.ReportAllocs()
+		for i := 0; i < b.N; i++ {
+			_, _ = NewErrorResponse(id, &JsonMethodNotFound)
+		}
+	})
+}
@@ -0,0 +1,109 @@
+/*  Copyright 2022  Kosmas Valianos (kosmas.valianos@gmail.com)
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    https://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License. */
+
+package gojsonrpc
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+)
+
+// CancelRequestMethod is the method of the notifications cancelling a request, as in the Language Server Protocol
+const CancelRequestMethod = "$/cancelRequest"
+
+// errRequestCancelled is the cause of the context of a request cancelled by the remote peer
+var errRequestCancelled = errors.New("request cancelled by the peer")
+
+// CancelParams are the params of the notifications cancelling the request with the ID
+type CancelParams struct {
+	ID any `json:"id"`
+}
+
+// inflightRequest is a request being served which the remote peer can cancel
+type inflightRequest struct {
+	cancel context.CancelCauseFunc
+}
+
+// WithCancellation makes the notifications of the method, e.g. CancelRequestMethod, carrying CancelParams cancel the
+// context of the request with the ID being served. The handler of a cancelled request which returns an error is
+// answered with JsonRequestCancelled. Only the requests served within a Conn, which received the notification as well,
+// can be cancelled, e.g. over TCP or WebSocket but not over HTTP
+func WithCancellation(method string) MuxOption {
+	return func(m *Mux) {
+		m.cancelMethod = method
+	}
+}
+
+// WithCancelRequestMethod sets the method of the notifications sent by AsyncCall.CancelRemote.
+// The default one is CancelRequestMethod
+func WithCancelRequestMethod(method string) ClientOption {
+	return func(c *Client) {
+		c.cancelMethod = method
+	}
+}
+
+// CancelRemote asks the server to cancel the call with a notification carrying its ID. The call still completes
+// with the response of the server, JsonRequestCancelled if the handler gave up, or with its own context.
+// Returns an error if the notification could not be sent
+func (call *AsyncCall) CancelRemote(ctx context.Context) error {
+	return call.client.Notify(ctx, call.client.cancelMethod, CancelParams{ID: call.ID})
+}
+
+// trackRequest derives a context which the remote peer of the Conn serving the request, if any, can cancel.
+// Returns the context and a function to call once the request is served
+func trackRequest(ctx context.Context, id any) (context.Context, func()) {
+	conn, ok := ConnFromContext(ctx)
+	if !ok {
+		return ctx, func() {}
+	}
+
+	ctx, cancel := context.WithCancelCause(ctx)
+	request := &inflightRequest{cancel: cancel}
+	key := idKey(id)
+	conn.inflightMu.Lock()
+	if conn.inflight == nil {
+		conn.inflight = make(map[string]*inflightRequest)
+	}
+	conn.inflight[key] = request
+	conn.inflightMu.Unlock()
+
+	return ctx, func() {
+		conn.inflightMu.Lock()
+		if conn.inflight[key] == request {
+			delete(conn.inflight, key)
+		}
+		conn.inflightMu.Unlock()
+		cancel(nil)
+	}
+}
+
+// cancelRequest cancels the request being served by the Conn of ctx whose ID the params carry
+func cancelRequest(ctx context.Context, paramsRaw json.RawMessage) {
+	conn, ok := ConnFromContext(ctx)
+	if !ok {
+		return
+	}
+	var params CancelParams
+	if err := json.Unmarshal(paramsRaw, &params); err != nil || params.ID == nil {
+		return
+	}
+
+	conn.inflightMu.Lock()
+	request, ok := conn.inflight[idKey(params.ID)]
+	conn.inflightMu.Unlock()
+	if ok {
+		request.cancel(errRequestCancelled)
+	}
+}
@@ -0,0 +1,105 @@
+/*  Copyright 2022  Kosmas Valianos (kosmas.valianos@gmail.com)
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    https://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License. */
+
+package gojsonrpc
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"testing"
+	"time"
+)
+
+func TestCancellation(t *testing.T) {
+	tests := []struct {
+		name         string
+		muxOptions   []MuxOption
+		clientOption []ClientOption
+		wantErr      error
+	}{
+		{
+			name:       "Cancelled",
+			muxOptions: []MuxOption{WithCancellation(CancelRequestMethod)},
+			wantErr:    ErrRequestCancelled,
+		},
+		{
+			name:         "Cancelled with another method",
+			muxOptions:   []MuxOption{WithCancellation("cancel")},
+			clientOption: []ClientOption{WithCancelRequestMethod("cancel")},
+			wantErr:      ErrRequestCancelled,
+		},
+		{
+			name:    "Cancellation not enabled",
+			wantErr: ErrCallTimeout,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			started := make(chan struct{})
+			mux := NewMux(tt.muxOptions...)
+			err := mux.Handle("wait", HandlerFunc(func(ctx context.Context, params json.RawMessage) (any, error) {
+				close(started)
+				<-ctx.Done()
+				return nil, ctx.Err()
+			}))
+			if err != nil {
+				t.Fatal(err)
+			}
+			server, client := NewConnPipe(mux, nil, tt.clientOption...)
+			defer server.Close()
+			defer client.Close()
+
+			ctx, cancel := context.WithTimeout(context.Background(), 500*time.Millisecond)
+			defer cancel()
+			call := client.Client().Go(ctx, "wait", nil)
+			<-started
+			if err := call.CancelRemote(context.Background()); err != nil {
+				t.Fatal(err)
+			}
+			<-call.Done
+			if !errors.Is(call.Error, tt.wantErr) {
+				t.Errorf("call error = %v, want %v", call.Error, tt.wantErr)
+			}
+		})
+	}
+}
+
+func TestCancellation_unknownID(t *testing.T) {
+	mux := NewMux(WithCancellation(CancelRequestMethod))
+	err := mux.Handle("echo", HandlerFunc(func(ctx context.Context, params json.RawMessage) (any, error) {
+		return "ok", nil
+	}))
+	if err != nil {
+		t.Fatal(err)
+	}
+	server, client := NewConnPipe(mux, nil)
+	defer server.Close()
+	defer client.Close()
+
+	// Cancelling a request which is not being served, or malformed params, is ignored
+	if err := client.Notify(context.Background(), CancelRequestMethod, CancelParams{ID: 42}); err != nil {
+		t.Fatal(err)
+	}
+	if err := client.Notify(context.Background(), CancelRequestMethod, []int{42}); err != nil {
+		t.Fatal(err)
+	}
+	var result string
+	if err := client.Call(context.Background(), "echo", nil, &result); err != nil || result != "ok" {
+		t.Errorf("Call() = %v, %v, want ok", result, err)
+	}
+	if got := mux.Serve(context.Background(), []byte(`{"jsonrpc": "2.0", "method": "$/cancelRequest", "params": {"id": 1}}`)); got != nil {
+		t.Errorf("Serve() = %s outside a Conn, want nil", got)
+	}
+}
@@ -0,0 +1,405 @@
+/*  Copyright 2022  Kosmas Valianos (kosmas.valianos@gmail.com)
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    https://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License. */
+
+package gojsonrpc
+
+import (
+	"encoding/binary"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"math"
+	"math/big"
+	"strconv"
+)
+
+// CBORCodec encodes the messages in CBOR (RFC 8949), e.g. for the constrained devices already using it. The members
+// of the messages keep their JSON names and order. The integers are encoded as such and the other numbers as floats,
+// in 32 bits when they fit. The byte strings are decoded as base64 strings, the undefined value as null and the tags
+// as the values they tag
+var CBORCodec Codec = cborCodec{}
+
+type cborCodec struct{}
+
+// The major types of CBOR
+const (
+	cborUnsigned byte = iota
+	cborNegative
+	cborBytes
+	cborText
+	cborArray
+	cborMap
+	cborTag
+	cborSimple
+)
+
+const (
+	// cborIndefinite is the additional information of the indefinite lengths
+	cborIndefinite = 31
+	// cborBreak ends the items of an indefinite length
+	cborBreak = 0xff
+)
+
+var errCBORTruncated = errors.New("cbor: truncated data")
+
+// ContentType returns "application/cbor"
+func (cborCodec) ContentType() string {
+	return "application/cbor"
+}
+
+// Marshal encodes a message given in JSON in CBOR
+func (cborCodec) Marshal(messageRaw []byte) ([]byte, error) {
+	value, err := parseJSONValue(messageRaw)
+	if err != nil {
+		return nil, err
+	}
+	return appendCBOR(nil, value)
+}
+
+// Unmarshal decodes a message in CBOR to JSON
+func (cborCodec) Unmarshal(data []byte) ([]byte, error) {
+	decoder := cborDecoder{data: data}
+	value, err := decoder.decode(0)
+	if err != nil {
+		return nil, err
+	}
+	if decoder.offset != len(data) {
+		return nil, errors.New("cbor: data after the message")
+	}
+	return appendJSONValue(nil, value)
+}
+
+// appendCBORHead appends the head of an item of the major type with its argument in its shortest encoding
+func appendCBORHead(dst []byte, major byte, argument uint64) []byte {
+	major <<= 5
+	switch {
+	case argument < 24:
+		return append(dst, major|byte(argument))
+	case argument <= math.MaxUint8:
+		return append(dst, major|24, byte(argument))
+	case argument <= math.MaxUint16:
+		return binary.BigEndian.AppendUint16(append(dst, major|25), uint16(argument))
+	case argument <= math.MaxUint32:
+		return binary.BigEndian.AppendUint32(append(dst, major|26), uint32(argument))
+	default:
+		return binary.BigEndian.AppendUint64(append(dst, major|27), argument)
+	}
+}
+
+// appendCBOR appends the CBOR encoding of a JSON value
+func appendCBOR(dst []byte, value any) ([]byte, error) {
+	switch value := value.(type) {
+	case nil:
+		return append(dst, 0xf6), nil
+	case bool:
+		if value {
+			return append(dst, 0xf5), nil
+		}
+		return append(dst, 0xf4), nil
+	case json.Number:
+		return appendCBORNumber(dst, value)
+	case string:
+		dst = appendCBORHead(dst, cborText, uint64(len(value)))
+		return append(dst, value...), nil
+	case []any:
+		dst = appendCBORHead(dst, cborArray, uint64(len(value)))
+		for _, element := range value {
+			var err error
+			dst, err = appendCBOR(dst, element)
+			if err != nil {
+				return nil, err
+			}
+		}
+		return dst, nil
+	case jsonObject:
+		dst = appendCBORHead(dst, cborMap, uint64(len(value)))
+		for _, member := range value {
+			var err error
+			dst, err = appendCBOR(dst, member.key)
+			if err != nil {
+				return nil, err
+			}
+			dst, err = appendCBOR(dst, member.value)
+			if err != nil {
+				return nil, err
+			}
+		}
+		return dst, nil
+	default:
+		return nil, fmt.Errorf("cbor: unsupported value %T", value)
+	}
+}
+
+// appendCBORNumber appends an integer as such and any other number as a float, in 32 bits if it is exact
+func appendCBORNumber(dst []byte, number json.Number) ([]byte, error) {
+	if i, err := strconv.ParseInt(string(number), 10, 64); err == nil {
+		if i >= 0 {
+			return appendCBORHead(dst, cborUnsigned, uint64(i)), nil
+		}
+		return appendCBORHead(dst, cborNegative, uint64(-1-i)), nil
+	}
+	if u, err := strconv.ParseUint(string(number), 10, 64); err == nil {
+		return appendCBORHead(dst, cborUnsigned, u), nil
+	}
+	f, err := strconv.ParseFloat(string(number), 64)
+	if err != nil {
+		return nil, err
+	}
+	if f32 := float32(f); float64(f32) == f {
+		return binary.BigEndian.AppendUint32(append(dst, 0xfa), math.Float32bits(f32)), nil
+	}
+	return binary.BigEndian.AppendUint64(append(dst, 0xfb), math.Float64bits(f)), nil
+}
+
+// cborDecoder decodes CBOR to JSON values
+type cborDecoder struct {
+	data   []byte
+	offset int
+}
+
+// read returns the next n bytes
+func (d *cborDecoder) read(n uint64) ([]byte, error) {
+	if n > uint64(len(d.data)-d.offset) {
+		return nil, errCBORTruncated
+	}
+	b := d.data[d.offset : d.offset+int(n)]
+	d.offset += int(n)
+	return b, nil
+}
+
+// head reads the head of the next item.
+// Returns its major type, its additional information and its argument or an error
+func (d *cborDecoder) head() (byte, byte, uint64, error) {
+	b, err := d.read(1)
+	if err != nil {
+		return 0, 0, 0, err
+	}
+	major, additional := b[0]>>5, b[0]&0x1f
+	switch {
+	case additional < 24:
+		return major, additional, uint64(additional), nil
+	case additional <= 27:
+		b, err := d.read(1 << (additional - 24))
+		if err != nil {
+			return 0, 0, 0, err
+		}
+		var argument uint64
+		for _, c := range b {
+			argument = argument<<8 | uint64(c)
+		}
+		return major, additional, argument, nil
+	case additional == cborIndefinite:
+		return major, additional, 0, nil
+	default:
+		return 0, 0, 0, fmt.Errorf("cbor: reserved additional information %v", additional)
+	}
+}
+
+// length checks that the data left holds a length of items of a byte each at least
+func (d *cborDecoder) length(argument uint64) (int, error) {
+	if argument > uint64(len(d.data)-d.offset) {
+		return 0, errCBORTruncated
+	}
+	return int(argument), nil
+}
+
+// decode decodes the next item
+func (d *cborDecoder) decode(depth int) (any, error) {
+	major, additional, argument, err := d.head()
+	if err != nil {
+		return nil, err
+	}
+	if additional == cborIndefinite && (major < cborBytes || major == cborTag) {
+		return nil, errors.New("cbor: indefinite length of a number or a tag")
+	}
+	switch major {
+	case cborUnsigned:
+		return argument, nil
+	case cborNegative:
+		if argument <= math.MaxInt64 {
+			return -1 - int64(argument), nil
+		}
+		n := new(big.Int).SetUint64(argument)
+		return json.Number(n.Neg(n.Add(n, big.NewInt(1))).String()), nil
+	case cborBytes, cborText:
+		b, err := d.decodeString(major, additional, argument)
+		if err != nil {
+			return nil, err
+		}
+		if major == cborBytes {
+			return b, nil
+		}
+		return string(b), nil
+	case cborArray:
+		return d.decodeArray(additional, argument, depth)
+	case cborMap:
+		return d.decodeMap(additional, argument, depth)
+	case cborTag:
+		if depth >= maxValueDepth {
+			return nil, errValueTooDeep
+		}
+		return d.decode(depth + 1)
+	default:
+		return d.decodeSimple(additional, argument)
+	}
+}
+
+// decodeString decodes a byte or a text string, concatenating the chunks of an indefinite length
+func (d *cborDecoder) decodeString(major byte, additional byte, argument uint64) ([]byte, error) {
+	if additional != cborIndefinite {
+		b, err := d.read(argument)
+		return append([]byte(nil), b...), err
+	}
+	var concatenated []byte
+	for {
+		chunkMajor, chunkAdditional, chunkArgument, err := d.head()
+		if err != nil {
+			return nil, err
+		}
+		if chunkMajor == cborSimple && chunkAdditional == cborIndefinite {
+			return concatenated, nil
+		}
+		if chunkMajor != major || chunkAdditional == cborIndefinite {
+			return nil, errors.New("cbor: invalid chunk of an indefinite length string")
+		}
+		b, err := d.read(chunkArgument)
+		if err != nil {
+			return nil, err
+		}
+		concatenated = append(concatenated, b...)
+	}
+}
+
+// atBreak consumes the break ending the items of an indefinite length, if it is next
+func (d *cborDecoder) atBreak() bool {
+	if d.offset < len(d.data) && d.data[d.offset] == cborBreak {
+		d.offset++
+		return true
+	}
+	return false
+}
+
+// decodeArray decodes the items of an array
+func (d *cborDecoder) decodeArray(additional byte, argument uint64, depth int) (any, error) {
+	if depth >= maxValueDepth {
+		return nil, errValueTooDeep
+	}
+	if additional == cborIndefinite {
+		array := []any{}
+		for !d.atBreak() {
+			element, err := d.decode(depth + 1)
+			if err != nil {
+				return nil, err
+			}
+			array = append(array, element)
+		}
+		return array, nil
+	}
+	length, err := d.length(argument)
+	if err != nil {
+		return nil, err
+	}
+	array := make([]any, 0, length)
+	for i := 0; i < length; i++ {
+		element, err := d.decode(depth + 1)
+		if err != nil {
+			return nil, err
+		}
+		array = append(array, element)
+	}
+	return array, nil
+}
+
+// decodeMap decodes the pairs of a map, whose keys must be text strings or integers
+func (d *cborDecoder) decodeMap(additional byte, argument uint64, depth int) (any, error) {
+	if depth >= maxValueDepth {
+		return nil, errValueTooDeep
+	}
+	length := -1
+	if additional != cborIndefinite {
+		var err error
+		length, err = d.length(argument)
+		if err != nil {
+			return nil, err
+		}
+	}
+	object := jsonObject{}
+	for i := 0; (length < 0 && !d.atBreak()) || i < length; i++ {
+		key, err := d.decode(depth + 1)
+		if err != nil {
+			return nil, err
+		}
+		value, err := d.decode(depth + 1)
+		if err != nil {
+			return nil, err
+		}
+		member := jsonMember{value: value}
+		switch key := key.(type) {
+		case string:
+			member.key = key
+		case uint64:
+			member.key = strconv.FormatUint(key, 10)
+		case int64:
+			member.key = strconv.FormatInt(key, 10)
+		default:
+			return nil, fmt.Errorf("cbor: unsupported map key %T", key)
+		}
+		object = append(object, member)
+	}
+	return object, nil
+}
+
+// decodeSimple decodes a simple value, a float or the break
+func (d *cborDecoder) decodeSimple(additional byte, argument uint64) (any, error) {
+	switch additional {
+	case 20:
+		return false, nil
+	case 21:
+		return true, nil
+	case 22, 23:
+		// null and undefined
+		return nil, nil
+	case 25:
+		return float16ToFloat64(uint16(argument)), nil
+	case 26:
+		return float64(math.Float32frombits(uint32(argument))), nil
+	case 27:
+		return math.Float64frombits(argument), nil
+	case cborIndefinite:
+		return nil, errors.New("cbor: unexpected break")
+	default:
+		return nil, fmt.Errorf("cbor: unsupported simple value %v", argument)
+	}
+}
+
+// float16ToFloat64 converts an IEEE 754 half-precision float
+func float16ToFloat64(half uint16) float64 {
+	sign := 1.0
+	if half&0x8000 != 0 {
+		sign = -1
+	}
+	exponent := int(half >> 10 & 0x1f)
+	mantissa := float64(half & 0x3ff)
+	switch exponent {
+	case 0:
+		return sign * math.Ldexp(mantissa, -24)
+	case 0x1f:
+		if mantissa == 0 {
+			return math.Inf(int(sign))
+		}
+		return math.NaN()
+	default:
+		return sign * math.Ldexp(mantissa+1024, exponent-25)
+	}
+}
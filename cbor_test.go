@@ -0,0 +1,135 @@
+/*  Copyright 2022  Kosmas Valianos (kosmas.valianos@gmail.com)
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    https://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License. */
+
+package gojsonrpc
+
+import (
+	"context"
+	"encoding/hex"
+	"strings"
+	"testing"
+)
+
+func TestCBORCodec(t *testing.T) {
+	// The examples of RFC 8949 Appendix A
+	tests := []struct {
+		name        string
+		messageRaw  string
+		wantEncoded string
+	}{
+		{"0", `0`, "00"},
+		{"23", `23`, "17"},
+		{"24", `24`, "1818"},
+		{"1000", `1000`, "1903e8"},
+		{"1000000", `1000000`, "1a000f4240"},
+		{"max uint64", `18446744073709551615`, "1bffffffffffffffff"},
+		{"-1", `-1`, "20"},
+		{"-1000", `-1000`, "3903e7"},
+		{"float32", `1.5`, "fa3fc00000"},
+		{"float64", `1.1`, "fb3ff199999999999a"},
+		{"false", `false`, "f4"},
+		{"true", `true`, "f5"},
+		{"null", `null`, "f6"},
+		{"text", `"ü"`, "62c3bc"},
+		{"empty array", `[]`, "80"},
+		{"array", `[1,2,3]`, "83010203"},
+		{"empty map", `{}`, "a0"},
+		{"map", `{"a":1,"b":[2,3]}`, "a26161016162820203"},
+		{
+			"request",
+			`{"jsonrpc":"2.0","method":"subtract","params":[42,23],"id":1}`,
+			"a4676a736f6e72706363322e30666d6574686f6468737562747261637466706172616d7382182a1762696401",
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			encoded, err := CBORCodec.Marshal([]byte(tt.messageRaw))
+			if err != nil {
+				t.Fatalf("Marshal() error = %v", err)
+			}
+			if hex.EncodeToString(encoded) != tt.wantEncoded {
+				t.Errorf("Marshal() = %x, want %v", encoded, tt.wantEncoded)
+			}
+			decoded, err := CBORCodec.Unmarshal(encoded)
+			if err != nil || string(decoded) != tt.messageRaw {
+				t.Errorf("Unmarshal() = %s, %v, want %s", decoded, err, tt.messageRaw)
+			}
+		})
+	}
+}
+
+func TestCBORCodec_Unmarshal(t *testing.T) {
+	tests := []struct {
+		name    string
+		encoded string
+		want    string
+		wantErr bool
+	}{
+		{"min negative", "3bffffffffffffffff", `-18446744073709551616`, false},
+		{"float16", "f93800", `0.5`, false},
+		{"negative float16", "f9c400", `-4`, false},
+		{"subnormal float16", "f90001", `5.960464477539063e-8`, false},
+		{"undefined", "f7", `null`, false},
+		{"tag", "c11a514b67b0", `1363896240`, false},
+		{"bytes", "4401020304", `"AQIDBA=="`, false},
+		{"indefinite array", "9f018202039f0405ffff", `[1,[2,3],[4,5]]`, false},
+		{"indefinite map", "bf61610161629f0203ffff", `{"a":1,"b":[2,3]}`, false},
+		{"indefinite text", "7f657374726561646d696e67ff", `"streaming"`, false},
+		{"integer key", "a10102", `{"1":2}`, false},
+		{"truncated", "6261", "", true},
+		{"length beyond the data", "9bffffffffffffffff", "", true},
+		{"break", "ff", "", true},
+		{"break in a definite array", "9f81ffff", "", true},
+		{"reserved", "1c", "", true},
+		{"indefinite integer", "1f", "", true},
+		{"array key", "a18000", "", true},
+		{"NaN", "f97e00", "", true},
+		{"trailing data", "0000", "", true},
+		{"empty", "", "", true},
+		{"too deep", strings.Repeat("81", maxValueDepth+1) + "00", "", true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			encoded, err := hex.DecodeString(tt.encoded)
+			if err != nil {
+				t.Fatal(err)
+			}
+			decoded, err := CBORCodec.Unmarshal(encoded)
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("Unmarshal() error = %v, wantErr %v", err, tt.wantErr)
+			}
+			if string(decoded) != tt.want {
+				t.Errorf("Unmarshal() = %s, want %s", decoded, tt.want)
+			}
+		})
+	}
+}
+
+func TestCBORCodec_conn(t *testing.T) {
+	serverEnd, clientEnd := Pipe()
+	server := NewConn(NewCodecConn(serverEnd, CBORCodec), newTestMux(t))
+	defer server.Close()
+	client := NewConn(NewCodecConn(clientEnd, CBORCodec), nil)
+	defer client.Close()
+
+	var result int
+	err := client.Call(context.Background(), "subtract", []int{42, 23}, &result)
+	if err != nil || result != 19 {
+		t.Errorf("Call() = %v, %v, want %v", result, err, 19)
+	}
+	err = client.Call(context.Background(), "database", nil, &result)
+	if err == nil || err.Error() != JsonInvalidMethodParameters.Error() {
+		t.Errorf("Call() = %v, want %v", err, &JsonInvalidMethodParameters)
+	}
+}
@@ -0,0 +1,275 @@
+/*  Copyright 2022  Kosmas Valianos (kosmas.valianos@gmail.com)
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    https://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License. */
+
+package gojsonrpc
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"reflect"
+	"time"
+)
+
+// ErrCallTimeout is returned by the calls whose deadline expired before their response arrived
+var ErrCallTimeout = errors.New("call timeout")
+
+// Transport carries the raw bytes of the JSON-RPC messages of a Client to a server
+type Transport interface {
+	// RoundTrip sends a request and returns the raw bytes of its response, which the Client keeps: the results of
+	// the calls reference them without copying them
+	RoundTrip(ctx context.Context, requestRaw []byte) ([]byte, error)
+	// Send sends a notification which is never answered
+	Send(ctx context.Context, notificationRaw []byte) error
+}
+
+// Client calls the methods of a JSON-RPC 2.0 server through a Transport.
+// It is safe for concurrent use
+type Client struct {
+	transport   Transport
+	idGenerator IDGenerator
+	callTimeout time.Duration
+	retryPolicy RetryPolicy
+	breaker     *circuitBreaker
+	fieldNaming FieldNaming
+	// cancelMethod is the method of the notifications sent by AsyncCall.CancelRemote
+	cancelMethod string
+	logger       *logConfig
+}
+
+// ClientOption configures a Client
+type ClientOption func(*Client)
+
+// WithClientFieldNaming sets the FieldNaming of the params and of the results,
+// so that the struct fields without a json tag follow the convention of the server on the wire
+func WithClientFieldNaming(naming FieldNaming) ClientOption {
+	return func(c *Client) {
+		c.fieldNaming = naming
+	}
+}
+
+// WithCallTimeout sets the default timeout of the calls whose context has no deadline,
+// after which they fail with ErrCallTimeout. A zero or negative timeout means no timeout
+func WithCallTimeout(timeout time.Duration) ClientOption {
+	return func(c *Client) {
+		c.callTimeout = timeout
+	}
+}
+
+// NewClient creates a Client sending its messages through the transport configured by the options.
+// Returns a *Client object
+func NewClient(transport Transport, options ...ClientOption) *Client {
+	client := &Client{transport: transport, idGenerator: &IncrementingIDGenerator{}, cancelMethod: CancelRequestMethod}
+	for _, option := range options {
+		option(client)
+	}
+	return client
+}
+
+// Call calls the method with the params and unmarshals the result of the response into result unless it is nil.
+// Returns the *jsonRPCError object of the response or an error if the call failed
+func (c *Client) Call(ctx context.Context, method string, params any, result any) error {
+	if result != nil && reflect.TypeOf(result).Kind() != reflect.Pointer {
+		return errors.New("result must be a pointer")
+	}
+	resultRaw, err := c.call(ctx, method, params, c.idGenerator.NextID())
+	if err != nil {
+		return err
+	}
+	return c.unmarshalResult(resultRaw, result)
+}
+
+// AsyncCall is a call of a method started by Client.Go
+type AsyncCall struct {
+	Method string
+	Params any
+	// ID is the id of the request, which AsyncCall.CancelRemote refers to
+	ID any
+	// Result is the raw result of the response, use Unmarshal to decode it
+	Result json.RawMessage
+	// Error is the *jsonRPCError object of the response or an error if the call failed
+	Error error
+	// Done receives the AsyncCall when it is complete
+	Done chan *AsyncCall
+
+	client *Client
+}
+
+// Go calls the method with the params asynchronously, so that many calls can be in flight at the same time
+// over one Transport, which must then be safe for concurrent use.
+// Returns an *AsyncCall object whose Done channel receives it when it is complete
+func (c *Client) Go(ctx context.Context, method string, params any) *AsyncCall {
+	return c.goCall(ctx, method, params, c.idGenerator.NextID(), nil)
+}
+
+// goCall calls the method with the params and the id asynchronously, calling complete, unless nil,
+// once the call is complete before it is sent on its Done channel.
+// Returns an *AsyncCall object
+func (c *Client) goCall(ctx context.Context, method string, params any, id any, complete func()) *AsyncCall {
+	call := &AsyncCall{
+		Method: method,
+		Params: params,
+		ID:     id,
+		Done:   make(chan *AsyncCall, 1),
+		client: c,
+	}
+	go func() {
+		call.Result, call.Error = c.call(ctx, method, params, call.ID)
+		if complete != nil {
+			complete()
+		}
+		call.Done <- call
+	}()
+	return call
+}
+
+// Unmarshal unmarshals the result of the complete call into result.
+// Returns the error of the call or an error if the result cannot be unmarshaled
+func (call *AsyncCall) Unmarshal(result any) error {
+	if call.Error != nil {
+		return call.Error
+	}
+	if result == nil || reflect.TypeOf(result).Kind() != reflect.Pointer {
+		return errors.New("result must be a pointer")
+	}
+	return call.client.unmarshalResult(call.Result, result)
+}
+
+// call sends a request of the method with the params and the id and parses its response.
+// Returns the raw result or the *jsonRPCError object of the response or an error if the call failed
+func (c *Client) call(ctx context.Context, method string, params any, id any) (resultRaw json.RawMessage, err error) {
+	if c.logger != nil {
+		start := time.Now()
+		defer func() {
+			c.logger.logCall(ctx, method, id, params, err, time.Since(start))
+		}()
+	}
+	paramsRaw, err := c.marshalParams(params)
+	if err != nil {
+		return nil, err
+	}
+	requestRaw, err := newRequestWithID(method, paramsRaw, id)
+	if err != nil {
+		return nil, err
+	}
+
+	responseRaw, err := c.roundTrip(ctx, requestRaw)
+	if err != nil {
+		return nil, err
+	}
+	response, err := ParseResponseZeroCopy(responseRaw)
+	if err != nil {
+		return nil, err
+	}
+	if response.Error != nil {
+		return nil, response.Error
+	}
+	if idKey(response.ID) != idKey(id) {
+		return nil, fmt.Errorf("response's ID %v does not match request's ID %v", response.ID, id)
+	}
+	return response.Result, nil
+}
+
+// roundTrip sends a request or a batch through the transport within the timeout of the call,
+// retrying it according to the RetryPolicy and failing fast while the circuit breaker of the Client is open.
+// Returns the raw bytes of the response, ErrCallTimeout if the deadline of the call expired, ErrCircuitOpen or an error
+func (c *Client) roundTrip(ctx context.Context, requestRaw []byte) ([]byte, error) {
+	if _, ok := ctx.Deadline(); !ok && c.callTimeout > 0 {
+		var cancel context.CancelFunc
+		ctx, cancel = context.WithTimeout(ctx, c.callTimeout)
+		defer cancel()
+	}
+	for attempt := 1; ; attempt++ {
+		trial := false
+		if c.breaker != nil {
+			var allowed bool
+			allowed, trial = c.breaker.allow()
+			if !allowed {
+				return nil, ErrCircuitOpen
+			}
+		}
+		responseRaw, err := c.transport.RoundTrip(ctx, requestRaw)
+		if c.breaker != nil {
+			c.breaker.record(ctx, trial, responseRaw, err)
+		}
+		if err != nil && errors.Is(ctx.Err(), context.DeadlineExceeded) {
+			return nil, ErrCallTimeout
+		}
+		if !c.retryPolicy.retryable(ctx, attempt, responseRaw, err) || !sleep(ctx, c.retryPolicy.backoff(attempt)) {
+			return responseRaw, err
+		}
+	}
+}
+
+// unmarshalResult unmarshals the raw result into result unless it is nil,
+// translating its field names by the FieldNaming of the Client, if any
+func (c *Client) unmarshalResult(resultRaw json.RawMessage, result any) error {
+	if result == nil {
+		return nil
+	}
+	if c.fieldNaming != nil {
+		var err error
+		resultRaw, err = translateFieldNames(resultRaw, reflect.TypeOf(result).Elem(), c.fieldNaming, false)
+		if err != nil {
+			return err
+		}
+	}
+	return currentJSONEngine().Unmarshal(resultRaw, result)
+}
+
+// Call calls the method with the params through the client and decodes the result into R.
+// Returns the result or the *jsonRPCError object of the response, which AsJsonRPCError extracts, or an error if the call failed
+func Call[R any](ctx context.Context, client *Client, method string, params any) (R, error) {
+	var result R
+	err := client.Call(ctx, method, params, &result)
+	if err != nil {
+		var zero R
+		return zero, err
+	}
+	return result, nil
+}
+
+// Notify sends a notification of the method with the params.
+// Returns an error if it could not be sent
+func (c *Client) Notify(ctx context.Context, method string, params any) (err error) {
+	if c.logger != nil {
+		start := time.Now()
+		defer func() {
+			c.logger.logCall(ctx, method, nil, params, err, time.Since(start))
+		}()
+	}
+	paramsRaw, err := c.marshalParams(params)
+	if err != nil {
+		return err
+	}
+	notificationRaw, err := NewNotification(method, paramsRaw)
+	if err != nil {
+		return err
+	}
+	return c.transport.Send(ctx, notificationRaw)
+}
+
+// marshalParams translates the field names of the params by the FieldNaming of the Client, if any.
+// Returns the params to marshal or an error
+func (c *Client) marshalParams(params any) (any, error) {
+	if params == nil || c.fieldNaming == nil {
+		return params, nil
+	}
+	paramsRaw, err := currentJSONEngine().Marshal(params)
+	if err != nil {
+		return nil, err
+	}
+	return translateFieldNames(paramsRaw, reflect.TypeOf(params), c.fieldNaming, true)
+}
@@ -0,0 +1,248 @@
+/*  Copyright 2022  Kosmas Valianos (kosmas.valianos@gmail.com)
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    https://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License. */
+
+package gojsonrpc
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"fmt"
+	"reflect"
+	"testing"
+)
+
+// muxTransport is a Transport serving the messages with a Mux in process
+type muxTransport struct {
+	mux           *Mux
+	notifications chan []byte
+}
+
+func (t *muxTransport) RoundTrip(ctx context.Context, requestRaw []byte) ([]byte, error) {
+	return t.mux.Serve(ctx, requestRaw), nil
+}
+
+func (t *muxTransport) Send(ctx context.Context, notificationRaw []byte) error {
+	if t.notifications != nil {
+		t.notifications <- notificationRaw
+	}
+	t.mux.Serve(ctx, notificationRaw)
+	return nil
+}
+
+// staticTransport is a Transport answering every request with the same response
+type staticTransport []byte
+
+func (t staticTransport) RoundTrip(ctx context.Context, requestRaw []byte) ([]byte, error) {
+	return t, nil
+}
+
+func (t staticTransport) Send(ctx context.Context, notificationRaw []byte) error {
+	return errors.New("notifications not supported")
+}
+
+func TestClient_Call(t *testing.T) {
+	client := NewClient(&muxTransport{mux: newTestMux(t)})
+	tests := []struct {
+		name    string
+		client  *Client
+		method  string
+		params  any
+		result  any
+		want    any
+		wantErr *jsonRPCError
+	}{
+		{
+			name:   "Result",
+			client: client,
+			method: "subtract",
+			params: []int{42, 23},
+			result: new(int),
+			want:   19,
+		},
+		{
+			name:   "Ignored result",
+			client: client,
+			method: "subtract",
+			params: []int{42, 23},
+		},
+		{
+			name:   "Raw result",
+			client: client,
+			method: "raw",
+			params: map[string]string{"foo": "bar"},
+			result: new(map[string]string),
+			want:   map[string]string{"foo": "bar"},
+		},
+		{
+			name:    "Error response",
+			client:  client,
+			method:  "database",
+			result:  new(int),
+			wantErr: &JsonInvalidMethodParameters,
+		},
+		{
+			name:    "Method not found",
+			client:  client,
+			method:  "multiply",
+			params:  []int{42, 23},
+			result:  new(int),
+			wantErr: &JsonMethodNotFound,
+		},
+		{
+			name:    "Mismatched ID",
+			client:  NewClient(staticTransport(`{"jsonrpc":"2.0","result":19,"id":"other"}`)),
+			method:  "subtract",
+			result:  new(int),
+			wantErr: &jsonRPCError{},
+		},
+		{
+			name:    "Invalid response",
+			client:  NewClient(staticTransport(`{"jsonrpc":"1.0","result":19,"id":1}`)),
+			method:  "subtract",
+			result:  new(int),
+			wantErr: &jsonRPCError{},
+		},
+		{
+			name:    "Result not a pointer",
+			client:  client,
+			method:  "subtract",
+			params:  []int{42, 23},
+			result:  0,
+			wantErr: &jsonRPCError{},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			err := tt.client.Call(context.Background(), tt.method, tt.params, tt.result)
+			if tt.wantErr != nil {
+				if err == nil {
+					t.Fatalf("Call() error = nil, wantErr %v", tt.wantErr)
+				}
+				if tt.wantErr.Code != 0 {
+					jsonRPCError, ok := err.(*jsonRPCError)
+					if !ok || jsonRPCError.Code != tt.wantErr.Code {
+						t.Errorf("Call() error = %v, wantErr %v", err, tt.wantErr)
+					}
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("Call() error = %v", err)
+			}
+			if tt.result != nil {
+				if got := reflect.ValueOf(tt.result).Elem().Interface(); !reflect.DeepEqual(got, tt.want) {
+					t.Errorf("Call() result = %v, want %v", got, tt.want)
+				}
+			}
+		})
+	}
+}
+
+func TestClient_Notify(t *testing.T) {
+	transport := &muxTransport{mux: newTestMux(t), notifications: make(chan []byte, 2)}
+	client := NewClient(transport)
+	err := client.Notify(context.Background(), "subtract", []int{42, 23})
+	if err != nil {
+		t.Fatalf("Notify() error = %v", err)
+	}
+	err = client.Notify(context.Background(), "update", nil)
+	if err != nil {
+		t.Fatalf("Notify() error = %v", err)
+	}
+
+	want := [][]byte{
+		[]byte(`{"jsonrpc":"2.0","method":"subtract","params":[42,23]}` + "\n"),
+		[]byte(`{"jsonrpc":"2.0","method":"update"}` + "\n"),
+	}
+	for _, notificationRaw := range want {
+		if got := <-transport.notifications; !bytes.Equal(got, notificationRaw) {
+			t.Errorf("Notify() = %v, want %v", string(got), string(notificationRaw))
+		}
+	}
+
+	err = NewClient(staticTransport(nil)).Notify(context.Background(), "update", nil)
+	if err == nil {
+		t.Error("Notify() error = nil, want the error of the transport")
+	}
+}
+
+func TestWithClientFieldNaming(t *testing.T) {
+	mux := NewMux(WithFieldNaming(SnakeCase))
+	err := HandleFunc(mux, "order.copy", func(ctx context.Context, params testOrder) (testOrder, error) {
+		return params, nil
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	client := NewClient(&muxTransport{mux: mux}, WithClientFieldNaming(SnakeCase))
+	order := testOrder{OrderID: 1, CustomerID: "c1", Items: []testOrderItem{{ProductID: 7, UnitPrice: 2.5}}}
+	var result testOrder
+	err = client.Call(context.Background(), "order.copy", order, &result)
+	if err != nil {
+		t.Fatalf("Call() error = %v", err)
+	}
+	if !reflect.DeepEqual(result, order) {
+		t.Errorf("Call() result = %v, want %v", result, order)
+	}
+}
+
+func TestCall(t *testing.T) {
+	client := NewClient(&muxTransport{mux: newTestMux(t)})
+	result, err := Call[int](context.Background(), client, "subtract", []int{42, 23})
+	if err != nil || result != 19 {
+		t.Errorf("Call() = %v, %v, want 19", result, err)
+	}
+
+	_, err = Call[int](context.Background(), client, "multiply", []int{42, 23})
+	wrapped := fmt.Errorf("multiply: %w", err)
+	jsonRPCError, ok := AsJsonRPCError(wrapped)
+	if !ok || jsonRPCError.Code != MethodNotFound {
+		t.Errorf("AsJsonRPCError() = %v, %v, want %v", jsonRPCError, ok, JsonMethodNotFound)
+	}
+
+	_, err = Call[string](context.Background(), client, "subtract", []int{42, 23})
+	if _, ok := AsJsonRPCError(err); err == nil || ok {
+		t.Errorf("Call() error = %v, want an unmarshaling error", err)
+	}
+}
+
+func TestClient_Go(t *testing.T) {
+	client := NewClient(&muxTransport{mux: newTestMux(t)})
+	calls := make([]*AsyncCall, 0, 10)
+	for i := 0; i < 10; i++ {
+		calls = append(calls, client.Go(context.Background(), "subtract", []int{42, i}))
+	}
+	failed := client.Go(context.Background(), "multiply", []int{42, 23})
+
+	for i, call := range calls {
+		<-call.Done
+		var result int
+		err := call.Unmarshal(&result)
+		if err != nil || result != 42-i {
+			t.Errorf("Unmarshal() = %v, %v, want %v", result, err, 42-i)
+		}
+	}
+
+	call := <-failed.Done
+	if jsonRPCError, ok := AsJsonRPCError(call.Error); !ok || jsonRPCError.Code != MethodNotFound {
+		t.Errorf("Go() error = %v, want %v", call.Error, JsonMethodNotFound)
+	}
+	var result int
+	if err := call.Unmarshal(&result); err != call.Error {
+		t.Errorf("Unmarshal() error = %v, want %v", err, call.Error)
+	}
+}
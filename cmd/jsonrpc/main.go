@@ -0,0 +1,291 @@
+/*  Copyright 2022  Kosmas Valianos (kosmas.valianos@gmail.com)
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    https://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License. */
+
+// Command jsonrpc calls the methods of a JSON-RPC 2.0 server, e.g. to debug a server built with gojsonrpc.
+//
+// Usage:
+//
+//	jsonrpc [flags] endpoint method [params]
+//	jsonrpc [flags] -batch file endpoint
+//
+// The endpoint is an http://, https://, ws:// or wss:// url, a tcp://host:port address or stdio:command which
+// starts the command and talks to it over its standard input and output. The params are raw JSON.
+// The result of a request, or the error object of its response, is pretty-printed on the standard output.
+// The batch file holds a JSON-RPC batch whose responses are pretty-printed with the ids of the file
+package main
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"flag"
+	"fmt"
+	"io"
+	"net/url"
+	"os"
+	"os/exec"
+	"strings"
+	"time"
+
+	"github.com/kosmas-valianos/gojsonrpc"
+)
+
+// errRPC is returned when the server answered with an error object, which is already printed
+var errRPC = errors.New("error response")
+
+// headers are the repeated -header flags
+type headers []string
+
+func (h *headers) String() string {
+	return strings.Join(*h, ", ")
+}
+
+func (h *headers) Set(value string) error {
+	if !strings.Contains(value, ":") {
+		return fmt.Errorf("header %q is not key:value", value)
+	}
+	*h = append(*h, value)
+	return nil
+}
+
+func main() {
+	os.Exit(run(context.Background(), os.Args[1:], os.Stdout, os.Stderr))
+}
+
+// run runs the command with the arguments, without the name of the program.
+// Returns the exit code, 1 if the server answered with an error object and 2 if the call could not be made
+func run(ctx context.Context, args []string, stdout, stderr io.Writer) int {
+	flags := flag.NewFlagSet("jsonrpc", flag.ContinueOnError)
+	flags.SetOutput(stderr)
+	flags.Usage = func() {
+		fmt.Fprintln(stderr, "usage: jsonrpc [flags] endpoint method [params]")
+		fmt.Fprintln(stderr, "       jsonrpc [flags] -batch file endpoint")
+		flags.PrintDefaults()
+	}
+	notify := flags.Bool("notify", false, "send a notification which is never answered")
+	batch := flags.String("batch", "", "send the batch of the `file`, - for the standard input")
+	timeout := flags.Duration("timeout", 30*time.Second, "timeout of the call")
+	var header headers
+	flags.Var(&header, "header", "add a `key:value` header to the HTTP requests or the WebSocket handshake")
+	if err := flags.Parse(args); err != nil {
+		return 2
+	}
+
+	arguments := flags.Args()
+	if (*batch == "" && len(arguments) != 2 && len(arguments) != 3) || (*batch != "" && len(arguments) != 1) {
+		flags.Usage()
+		return 2
+	}
+
+	ctx, cancel := context.WithTimeout(ctx, *timeout)
+	defer cancel()
+	client, closer, err := dial(ctx, arguments[0], header)
+	if err != nil {
+		fmt.Fprintln(stderr, "jsonrpc:", err)
+		return 2
+	}
+	defer closer.Close()
+
+	if *batch != "" {
+		err = callBatch(ctx, client, *batch, stdout)
+	} else {
+		var params json.RawMessage
+		if len(arguments) == 3 {
+			params = json.RawMessage(arguments[2])
+			if !json.Valid(params) {
+				fmt.Fprintln(stderr, "jsonrpc: params are not valid JSON")
+				return 2
+			}
+		}
+		err = call(ctx, client, arguments[1], params, *notify, stdout)
+	}
+	switch {
+	case errors.Is(err, errRPC):
+		return 1
+	case err != nil:
+		fmt.Fprintln(stderr, "jsonrpc:", err)
+		return 2
+	}
+	return 0
+}
+
+// nopCloser closes nothing, for the endpoints without a connection
+type nopCloser struct{}
+
+func (nopCloser) Close() error { return nil }
+
+// dial creates a Client calling the endpoint.
+// Returns the *gojsonrpc.Client object and the connection to close once done, or an error
+func dial(ctx context.Context, endpoint string, header headers) (*gojsonrpc.Client, io.Closer, error) {
+	if command, ok := strings.CutPrefix(endpoint, "stdio:"); ok {
+		fields := strings.Fields(command)
+		if len(fields) == 0 {
+			return nil, nil, errors.New("stdio endpoint has no command")
+		}
+		cmd := exec.Command(fields[0], fields[1:]...)
+		cmd.Stderr = os.Stderr
+		conn, err := gojsonrpc.StartCommand(cmd, nil)
+		if err != nil {
+			return nil, nil, err
+		}
+		return conn.Client(), conn, nil
+	}
+
+	endpointURL, err := url.Parse(endpoint)
+	if err != nil {
+		return nil, nil, err
+	}
+	switch endpointURL.Scheme {
+	case "http", "https":
+		var options []gojsonrpc.HTTPTransportOption
+		for _, h := range header {
+			key, value, _ := strings.Cut(h, ":")
+			options = append(options, gojsonrpc.WithHTTPHeader(strings.TrimSpace(key), strings.TrimSpace(value)))
+		}
+		return gojsonrpc.NewClient(gojsonrpc.NewHTTPTransport(endpoint, options...)), nopCloser{}, nil
+	case "ws", "wss":
+		var options []gojsonrpc.WebSocketOption
+		for _, h := range header {
+			key, value, _ := strings.Cut(h, ":")
+			options = append(options, gojsonrpc.WithWebSocketHeader(strings.TrimSpace(key), strings.TrimSpace(value)))
+		}
+		conn, err := gojsonrpc.DialWebSocket(ctx, endpoint, nil, options...)
+		if err != nil {
+			return nil, nil, err
+		}
+		return conn.Client(), conn, nil
+	case "tcp":
+		conn, err := gojsonrpc.DialTCP(ctx, endpointURL.Host, nil)
+		if err != nil {
+			return nil, nil, err
+		}
+		return conn.Client(), conn, nil
+	default:
+		return nil, nil, fmt.Errorf("endpoint %q is neither http(s)://, ws(s)://, tcp:// nor stdio:", endpoint)
+	}
+}
+
+// call calls the method, or notifies it, and prints the result or the error object of the response
+func call(ctx context.Context, client *gojsonrpc.Client, method string, params json.RawMessage, notify bool, stdout io.Writer) error {
+	var callParams any
+	if params != nil {
+		callParams = params
+	}
+	if notify {
+		return client.Notify(ctx, method, callParams)
+	}
+
+	var result json.RawMessage
+	err := client.Call(ctx, method, callParams, &result)
+	if jsonRPCError, ok := gojsonrpc.AsJsonRPCError(err); ok {
+		if printErr := printJSON(stdout, jsonRPCError); printErr != nil {
+			return printErr
+		}
+		return errRPC
+	}
+	if err != nil {
+		return err
+	}
+	return printRaw(stdout, result)
+}
+
+// batchMessage is a request or a notification of a batch file
+type batchMessage struct {
+	Method string          `json:"method"`
+	Params json.RawMessage `json:"params,omitempty"`
+	ID     json.RawMessage `json:"id,omitempty"`
+}
+
+// callBatch sends the batch of the file and prints its responses with the ids of the file
+func callBatch(ctx context.Context, client *gojsonrpc.Client, path string, stdout io.Writer) error {
+	var batchRaw []byte
+	var err error
+	if path == "-" {
+		batchRaw, err = io.ReadAll(os.Stdin)
+	} else {
+		batchRaw, err = os.ReadFile(path)
+	}
+	if err != nil {
+		return err
+	}
+
+	var messages []batchMessage
+	if err := json.Unmarshal(batchRaw, &messages); err != nil {
+		return fmt.Errorf("batch file: %w", err)
+	}
+	items := make([]gojsonrpc.BatchItem, len(messages))
+	for i, message := range messages {
+		items[i] = gojsonrpc.BatchItem{Method: message.Method, Notification: message.ID == nil}
+		if message.Params != nil {
+			items[i].Params = message.Params
+		}
+	}
+	results, err := client.CallBatch(ctx, items)
+	if err != nil {
+		return err
+	}
+
+	type batchResponse struct {
+		JsonRPC string          `json:"jsonrpc"`
+		Result  json.RawMessage `json:"result,omitempty"`
+		Error   any             `json:"error,omitempty"`
+		ID      json.RawMessage `json:"id"`
+	}
+	responses := []batchResponse{}
+	failed := false
+	for i, result := range results {
+		if items[i].Notification {
+			continue
+		}
+		response := batchResponse{JsonRPC: "2.0", Result: result.Result, ID: messages[i].ID}
+		if result.Error != nil {
+			jsonRPCError, ok := gojsonrpc.AsJsonRPCError(result.Error)
+			if !ok {
+				return result.Error
+			}
+			response.Result, response.Error = nil, jsonRPCError
+			failed = true
+		}
+		responses = append(responses, response)
+	}
+	if len(responses) == 0 {
+		return nil
+	}
+	if err := printJSON(stdout, responses); err != nil {
+		return err
+	}
+	if failed {
+		return errRPC
+	}
+	return nil
+}
+
+func printJSON(stdout io.Writer, value any) error {
+	valueRaw, err := json.Marshal(value)
+	if err != nil {
+		return err
+	}
+	return printRaw(stdout, valueRaw)
+}
+
+func printRaw(stdout io.Writer, valueRaw json.RawMessage) error {
+	var indented bytes.Buffer
+	if err := json.Indent(&indented, valueRaw, "", "  "); err != nil {
+		return err
+	}
+	indented.WriteByte('\n')
+	_, err := indented.WriteTo(stdout)
+	return err
+}
@@ -0,0 +1,143 @@
+/*  Copyright 2022  Kosmas Valianos (kosmas.valianos@gmail.com)
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    https://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License. */
+
+package main
+
+import (
+	"bytes"
+	"context"
+	"net"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/kosmas-valianos/gojsonrpc"
+)
+
+func newTestMux(t *testing.T) *gojsonrpc.Mux {
+	mux := gojsonrpc.NewMux()
+	err := gojsonrpc.HandleFunc(mux, "subtract", func(ctx context.Context, params [2]int) (int, error) {
+		return params[0] - params[1], nil
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	err = gojsonrpc.HandleFunc(mux, "update", func(ctx context.Context, params []int) (any, error) {
+		return nil, nil
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	return mux
+}
+
+func TestRun(t *testing.T) {
+	server := httptest.NewServer(gojsonrpc.HTTPHandler(newTestMux(t)))
+	defer server.Close()
+
+	listener, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer listener.Close()
+	go gojsonrpc.ServeTCP(listener, newTestMux(t))
+
+	batchFile := filepath.Join(t.TempDir(), "batch.json")
+	err = os.WriteFile(batchFile, []byte(`[
+		{"jsonrpc": "2.0", "method": "subtract", "params": [42, 23], "id": "a"},
+		{"jsonrpc": "2.0", "method": "update", "params": [1, 2]},
+		{"jsonrpc": "2.0", "method": "foobar", "id": 7}
+	]`), 0o600)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	tests := []struct {
+		name     string
+		args     []string
+		wantCode int
+		wantOut  string
+	}{
+		{
+			name:    "HTTP request",
+			args:    []string{server.URL, "subtract", "[42, 23]"},
+			wantOut: "19\n",
+		},
+		{
+			name:    "TCP request",
+			args:    []string{"tcp://" + listener.Addr().String(), "subtract", "[23, 42]"},
+			wantOut: "-19\n",
+		},
+		{
+			name: "Notification",
+			args: []string{"-notify", server.URL, "update", "[1]"},
+		},
+		{
+			name:     "Error response",
+			args:     []string{server.URL, "foobar"},
+			wantCode: 1,
+			wantOut:  "{\n  \"code\": -32601,\n  \"message\": \"Method not found\"\n}\n",
+		},
+		{
+			name:     "Batch",
+			args:     []string{"-batch", batchFile, server.URL},
+			wantCode: 1,
+			wantOut: `[
+  {
+    "jsonrpc": "2.0",
+    "result": 19,
+    "id": "a"
+  },
+  {
+    "jsonrpc": "2.0",
+    "error": {
+      "code": -32601,
+      "message": "Method not found"
+    },
+    "id": 7
+  }
+]
+`,
+		},
+		{
+			name:     "Invalid params",
+			args:     []string{server.URL, "subtract", "[42,"},
+			wantCode: 2,
+		},
+		{
+			name:     "Unknown endpoint",
+			args:     []string{"udp://localhost:1", "subtract"},
+			wantCode: 2,
+		},
+		{
+			name:     "Missing method",
+			args:     []string{server.URL},
+			wantCode: 2,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var stdout, stderr bytes.Buffer
+			code := run(context.Background(), tt.args, &stdout, &stderr)
+			if code != tt.wantCode {
+				t.Errorf("run() = %v, want %v (%s)", code, tt.wantCode, strings.TrimSpace(stderr.String()))
+			}
+			if stdout.String() != tt.wantOut {
+				t.Errorf("run() stdout = %q, want %q", stdout.String(), tt.wantOut)
+			}
+		})
+	}
+}
@@ -0,0 +1,118 @@
+/*  Copyright 2022  Kosmas Valianos (kosmas.valianos@gmail.com)
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    https://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License. */
+
+package gojsonrpc
+
+import (
+	"context"
+	"mime"
+	"strings"
+)
+
+// Codec encodes the JSON-RPC messages on the wire in a format other than JSON, e.g. MessagePack or CBOR.
+// The messages keep being validated and routed in JSON: the Codec transcodes them at the edges of the transports,
+// with NewCodecConn, WithHTTPCodecs and WithHTTPCodec
+type Codec interface {
+	// ContentType returns the media type of the encoded messages, e.g. "application/msgpack"
+	ContentType() string
+	// Marshal encodes a message given in JSON
+	Marshal(messageRaw []byte) ([]byte, error)
+	// Unmarshal decodes an encoded message to JSON
+	Unmarshal(data []byte) ([]byte, error)
+}
+
+// JSONCodec is the default Codec, keeping the messages in JSON
+var JSONCodec Codec = jsonCodec{}
+
+type jsonCodec struct{}
+
+// ContentType returns "application/json"
+func (jsonCodec) ContentType() string {
+	return "application/json"
+}
+
+// Marshal returns the message as it is
+func (jsonCodec) Marshal(messageRaw []byte) ([]byte, error) {
+	return messageRaw, nil
+}
+
+// Unmarshal returns the message as it is, its syntax being checked when it is served
+func (jsonCodec) Unmarshal(data []byte) ([]byte, error) {
+	return data, nil
+}
+
+// codecConn is a MessageConn encoding the messages of another MessageConn with a Codec
+type codecConn struct {
+	conn  MessageConn
+	codec Codec
+}
+
+// NewCodecConn makes a MessageConn encoding the messages written to the connection with the codec and decoding
+// those read from it. A message which cannot be decoded fails the connection. The streams need a binary-safe Framing,
+// such as ContentLengthFraming, for the binary codecs.
+// Returns a MessageConn
+func NewCodecConn(conn MessageConn, codec Codec) MessageConn {
+	return &codecConn{conn: conn, codec: codec}
+}
+
+// WriteMessage encodes and writes a message
+func (c *codecConn) WriteMessage(ctx context.Context, messageRaw []byte) error {
+	data, err := c.codec.Marshal(messageRaw)
+	if err != nil {
+		return err
+	}
+	return c.conn.WriteMessage(ctx, data)
+}
+
+// ReadMessage reads and decodes the next message
+func (c *codecConn) ReadMessage() ([]byte, error) {
+	data, err := c.conn.ReadMessage()
+	if err != nil {
+		return nil, err
+	}
+	return c.codec.Unmarshal(data)
+}
+
+// Close closes the connection
+func (c *codecConn) Close() error {
+	return c.conn.Close()
+}
+
+// WithHTTPCodecs serves the bodies of the content types of the codecs besides application/json, answering them
+// in the same content type
+func WithHTTPCodecs(codecs ...Codec) HTTPHandlerOption {
+	return func(h *httpHandler) {
+		if h.codecs == nil {
+			h.codecs = make(map[string]Codec)
+		}
+		for _, codec := range codecs {
+			h.codecs[strings.ToLower(codec.ContentType())] = codec
+		}
+	}
+}
+
+// WithHTTPCodec posts the messages encoded with the codec and decodes the responses in its content type
+func WithHTTPCodec(codec Codec) HTTPTransportOption {
+	return func(t *HTTPTransport) {
+		if codec != nil {
+			t.codec = codec
+		}
+	}
+}
+
+// hasContentType reports whether the Content-Type of a header, if any, is the one of the codec
+func hasContentType(contentType string, codec Codec) bool {
+	mediaType, _, err := mime.ParseMediaType(contentType)
+	return err == nil && strings.EqualFold(mediaType, codec.ContentType())
+}
@@ -0,0 +1,133 @@
+/*  Copyright 2022  Kosmas Valianos (kosmas.valianos@gmail.com)
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    https://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License. */
+
+package gojsonrpc
+
+import (
+	"bytes"
+	"context"
+	"encoding/base64"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+)
+
+// base64Codec encodes the messages in base64, standing for a binary codec
+type base64Codec struct{}
+
+func (base64Codec) ContentType() string {
+	return "application/x-base64"
+}
+
+func (base64Codec) Marshal(messageRaw []byte) ([]byte, error) {
+	return []byte(base64.StdEncoding.EncodeToString(bytes.TrimSpace(messageRaw))), nil
+}
+
+func (base64Codec) Unmarshal(data []byte) ([]byte, error) {
+	return base64.StdEncoding.DecodeString(string(data))
+}
+
+func TestNewCodecConn(t *testing.T) {
+	serverEnd, clientEnd := Pipe()
+	server := NewConn(NewCodecConn(serverEnd, base64Codec{}), newTestMux(t))
+	defer server.Close()
+
+	// The client end is read raw
+	clientConn := NewCodecConn(clientEnd, base64Codec{})
+	err := clientConn.WriteMessage(context.Background(), []byte(`{"jsonrpc":"2.0","method":"subtract","params":[42,23],"id":1}`))
+	if err != nil {
+		t.Fatal(err)
+	}
+	data, err := clientEnd.ReadMessage()
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := base64.StdEncoding.EncodeToString([]byte(`{"jsonrpc":"2.0","result":19,"id":1}`))
+	if string(data) != want {
+		t.Errorf("ReadMessage() = %s, want %s", data, want)
+	}
+
+	client := NewConn(clientConn, nil)
+	defer client.Close()
+	var result int
+	err = client.Call(context.Background(), "subtract", []int{42, 23}, &result)
+	if err != nil || result != 19 {
+		t.Errorf("Call() = %v, %v, want %v", result, err, 19)
+	}
+}
+
+func TestWithHTTPCodecs(t *testing.T) {
+	server := httptest.NewServer(HTTPHandler(newTestMux(t), WithHTTPCodecs(base64Codec{})))
+	defer server.Close()
+
+	client := NewClient(NewHTTPTransport(server.URL, WithHTTPCodec(base64Codec{})))
+	var result int
+	err := client.Call(context.Background(), "subtract", []int{42, 23}, &result)
+	if err != nil || result != 19 {
+		t.Errorf("Call() = %v, %v, want %v", result, err, 19)
+	}
+	// JSON is still accepted
+	client = NewClient(NewHTTPTransport(server.URL))
+	err = client.Call(context.Background(), "subtract", []int{42, 23}, &result)
+	if err != nil || result != 19 {
+		t.Errorf("Call() = %v, %v, want %v", result, err, 19)
+	}
+
+	tests := []struct {
+		name            string
+		contentType     string
+		body            string
+		wantStatusCode  int
+		wantContentType string
+		wantBody        string
+	}{
+		{
+			"undecodable",
+			"application/x-base64",
+			"!",
+			http.StatusInternalServerError,
+			"application/x-base64",
+			base64.StdEncoding.EncodeToString([]byte(`{"jsonrpc":"2.0","error":{"code":-32700,"message":"Parse error"},"id":null}`)),
+		},
+		{
+			"unsupported",
+			"application/cbor",
+			"",
+			http.StatusUnsupportedMediaType,
+			"text/plain; charset=utf-8",
+			"Unsupported Media Type\n",
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			response, err := http.Post(server.URL, tt.contentType, strings.NewReader(tt.body))
+			if err != nil {
+				t.Fatal(err)
+			}
+			defer response.Body.Close()
+			body, _ := io.ReadAll(response.Body)
+			if response.StatusCode != tt.wantStatusCode {
+				t.Errorf("StatusCode = %v, want %v", response.StatusCode, tt.wantStatusCode)
+			}
+			if contentType := response.Header.Get("Content-Type"); contentType != tt.wantContentType {
+				t.Errorf("Content-Type = %q, want %q", contentType, tt.wantContentType)
+			}
+			if string(body) != tt.wantBody {
+				t.Errorf("body = %q, want %q", body, tt.wantBody)
+			}
+		})
+	}
+}
@@ -0,0 +1,68 @@
+/*  Copyright 2022  Kosmas Valianos (kosmas.valianos@gmail.com)
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    https://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License. */
+
+package gojsonrpc
+
+// Const error code ranges
+const (
+	ReservedErrorStart    = -32768
+	ReservedErrorEnd      = -32000
+	ServerErrorStart      = -32099
+	ServerErrorEnd        = -32000
+	LSPReservedErrorStart = -32899
+	LSPReservedErrorEnd   = -32800
+)
+
+// Const error codes of the Language Server Protocol and the Debug Adapter Protocol
+const (
+	ServerNotInitialized = -32002
+	UnknownErrorCode     = -32001
+	RequestFailed        = -32803
+	ServerCancelled      = -32802
+	ContentModified      = -32801
+	RequestCancelled     = -32800
+)
+
+// Common error objects of the Language Server Protocol and the Debug Adapter Protocol
+var (
+	JsonServerNotInitialized = jsonRPCError{Code: ServerNotInitialized, Message: "Server not initialized"}
+	JsonUnknownError         = jsonRPCError{Code: UnknownErrorCode, Message: "Unknown error"}
+	JsonRequestFailed        = jsonRPCError{Code: RequestFailed, Message: "Request failed"}
+	JsonServerCancelled      = jsonRPCError{Code: ServerCancelled, Message: "Server cancelled"}
+	JsonContentModified      = jsonRPCError{Code: ContentModified, Message: "Content modified"}
+	JsonRequestCancelled     = jsonRPCError{Code: RequestCancelled, Message: "Request cancelled"}
+)
+
+// IsReservedCode reports whether the code is reserved by the JSON-RPC 2.0 specification i.e. between -32768 and -32000
+func IsReservedCode(code int) bool {
+	return code >= ReservedErrorStart && code <= ReservedErrorEnd
+}
+
+// IsServerError reports whether the code is reserved for implementation-defined server errors
+// i.e. between -32099 and -32000
+func IsServerError(code int) bool {
+	return code >= ServerErrorStart && code <= ServerErrorEnd
+}
+
+// IsCustomCode reports whether the code may be defined by an application i.e. outside the range reserved by the
+// JSON-RPC 2.0 specification, e.g. 1001 or -40000, or reserved for implementation-defined server errors
+func IsCustomCode(code int) bool {
+	return !IsReservedCode(code) || IsServerError(code)
+}
+
+// IsLSPReservedCode reports whether the code is reserved by the Language Server Protocol
+// i.e. between -32899 and -32800
+func IsLSPReservedCode(code int) bool {
+	return code >= LSPReservedErrorStart && code <= LSPReservedErrorEnd
+}
@@ -0,0 +1,99 @@
+/*  Copyright 2022  Kosmas Valianos (kosmas.valianos@gmail.com)
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    https://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License. */
+
+package gojsonrpc
+
+import "testing"
+
+func TestErrorCodeClassification(t *testing.T) {
+	tests := []struct {
+		name                string
+		code                int
+		wantReservedCode    bool
+		wantServerError     bool
+		wantLSPReservedCode bool
+		wantCustomCode      bool
+	}{
+		{
+			name:             "Parse error",
+			code:             ParseError,
+			wantReservedCode: true,
+		},
+		{
+			name:             "Reserved range start",
+			code:             -32768,
+			wantReservedCode: true,
+		},
+		{
+			name:             "Server error range start",
+			code:             -32099,
+			wantReservedCode: true,
+			wantServerError:  true,
+			wantCustomCode:   true,
+		},
+		{
+			name:             "Server error range end",
+			code:             -32000,
+			wantReservedCode: true,
+			wantServerError:  true,
+			wantCustomCode:   true,
+		},
+		{
+			name:             "Server not initialized",
+			code:             ServerNotInitialized,
+			wantReservedCode: true,
+			wantServerError:  true,
+			wantCustomCode:   true,
+		},
+		{
+			name:                "Request cancelled",
+			code:                RequestCancelled,
+			wantLSPReservedCode: true,
+			wantCustomCode:      true,
+		},
+		{
+			name:                "LSP reserved range start",
+			code:                -32899,
+			wantLSPReservedCode: true,
+			wantCustomCode:      true,
+		},
+		{
+			name:           "Application code",
+			code:           -32769,
+			wantCustomCode: true,
+		},
+		{
+			name:           "Positive application code",
+			code:           1001,
+			wantCustomCode: true,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := IsReservedCode(tt.code); got != tt.wantReservedCode {
+				t.Errorf("IsReservedCode() = %v, want %v", got, tt.wantReservedCode)
+			}
+			if got := IsServerError(tt.code); got != tt.wantServerError {
+				t.Errorf("IsServerError() = %v, want %v", got, tt.wantServerError)
+			}
+			if got := IsLSPReservedCode(tt.code); got != tt.wantLSPReservedCode {
+				t.Errorf("IsLSPReservedCode() = %v, want %v", got, tt.wantLSPReservedCode)
+			}
+			if got := IsCustomCode(tt.code); got != tt.wantCustomCode {
+				t.Errorf("IsCustomCode() = %v, want %v", got, tt.wantCustomCode)
+			}
+		})
+	}
+}
@@ -0,0 +1,79 @@
+/*  Copyright 2022  Kosmas Valianos (kosmas.valianos@gmail.com)
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    https://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License. */
+
+package gojsonrpc
+
+import (
+	"bytes"
+	"compress/gzip"
+	"compress/zlib"
+	"errors"
+	"io"
+	"net/http"
+	"strconv"
+	"strings"
+)
+
+// compressMinSize is the size from which the messages are compressed, smaller ones barely shrinking
+const compressMinSize = 1024
+
+// errUnsupportedEncoding is returned for a request body with a Content-Encoding other than gzip and deflate
+var errUnsupportedEncoding = errors.New("unsupported content encoding")
+
+// decodedBody returns a reader decompressing the body according to its Content-Encoding
+func decodedBody(header http.Header, body io.Reader) (io.Reader, error) {
+	switch encoding := strings.ToLower(strings.TrimSpace(header.Get("Content-Encoding"))); encoding {
+	case "", "identity":
+		return body, nil
+	case "gzip", "x-gzip":
+		return gzip.NewReader(body)
+	case "deflate":
+		return zlib.NewReader(body)
+	default:
+		return nil, errUnsupportedEncoding
+	}
+}
+
+// acceptsEncoding reports whether the Accept-Encoding header allows the encoding
+func acceptsEncoding(header http.Header, encoding string) bool {
+	for _, accepted := range strings.Split(header.Get("Accept-Encoding"), ",") {
+		name, params, _ := strings.Cut(accepted, ";")
+		name, params = strings.TrimSpace(name), strings.TrimSpace(params)
+		if !strings.EqualFold(name, encoding) && name != "*" {
+			continue
+		}
+		if !strings.HasPrefix(params, "q=") {
+			return true
+		}
+		q, err := strconv.ParseFloat(strings.TrimPrefix(params, "q="), 64)
+		return err == nil && q > 0
+	}
+	return false
+}
+
+// gzipMessage compresses a message with gzip
+func gzipMessage(messageRaw []byte) []byte {
+	var compressed bytes.Buffer
+	writer := gzip.NewWriter(&compressed)
+	_, _ = writer.Write(messageRaw)
+	_ = writer.Close()
+	return compressed.Bytes()
+}
+
+// WithHTTPCompression compresses the posted messages of 1KB or more with gzip and asks for compressed responses
+func WithHTTPCompression() HTTPTransportOption {
+	return func(t *HTTPTransport) {
+		t.compression = true
+	}
+}
@@ -0,0 +1,179 @@
+/*  Copyright 2022  Kosmas Valianos (kosmas.valianos@gmail.com)
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    https://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License. */
+
+package gojsonrpc
+
+import (
+	"bytes"
+	"compress/gzip"
+	"compress/zlib"
+	"context"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync/atomic"
+	"testing"
+)
+
+func TestHTTPHandler_Compression(t *testing.T) {
+	handler := HTTPHandler(newTestMux(t), WithMaxBodySize(4096))
+	large := `{"jsonrpc":"2.0","method":"raw","params":["` + strings.Repeat("a", 2048) + `"],"id":1}`
+	small := `{"jsonrpc":"2.0","method":"subtract","params":[42,23],"id":1}`
+	deflate := func(message string) []byte {
+		var compressed bytes.Buffer
+		writer := zlib.NewWriter(&compressed)
+		writer.Write([]byte(message))
+		writer.Close()
+		return compressed.Bytes()
+	}
+
+	tests := []struct {
+		name                string
+		body                []byte
+		contentEncoding     string
+		acceptEncoding      string
+		wantStatusCode      int
+		wantContentEncoding string
+	}{
+		{
+			name:            "gzip request",
+			body:            gzipMessage([]byte(small)),
+			contentEncoding: "gzip",
+			wantStatusCode:  http.StatusOK,
+		},
+		{
+			name:            "deflate request",
+			body:            deflate(small),
+			contentEncoding: "deflate",
+			wantStatusCode:  http.StatusOK,
+		},
+		{
+			name:            "Unsupported encoding",
+			body:            []byte(small),
+			contentEncoding: "br",
+			wantStatusCode:  http.StatusUnsupportedMediaType,
+		},
+		{
+			name:            "Invalid gzip",
+			body:            []byte(small),
+			contentEncoding: "gzip",
+			wantStatusCode:  http.StatusBadRequest,
+		},
+		{
+			name:            "Too large once decompressed",
+			body:            gzipMessage([]byte(`{"jsonrpc":"2.0","method":"raw","params":["` + strings.Repeat("a", 8192) + `"],"id":1}`)),
+			contentEncoding: "gzip",
+			wantStatusCode:  http.StatusRequestEntityTooLarge,
+		},
+		{
+			name:                "Compressed response",
+			body:                []byte(large),
+			acceptEncoding:      "deflate, gzip;q=0.8",
+			wantStatusCode:      http.StatusOK,
+			wantContentEncoding: "gzip",
+		},
+		{
+			name:           "gzip not accepted",
+			body:           []byte(large),
+			acceptEncoding: "gzip;q=0",
+			wantStatusCode: http.StatusOK,
+		},
+		{
+			name:           "Small response",
+			body:           []byte(small),
+			acceptEncoding: "gzip",
+			wantStatusCode: http.StatusOK,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			request := httptest.NewRequest(http.MethodPost, "/rpc", bytes.NewReader(tt.body))
+			request.Header.Set("Content-Type", "application/json")
+			if tt.contentEncoding != "" {
+				request.Header.Set("Content-Encoding", tt.contentEncoding)
+			}
+			if tt.acceptEncoding != "" {
+				request.Header.Set("Accept-Encoding", tt.acceptEncoding)
+			}
+			recorder := httptest.NewRecorder()
+			handler.ServeHTTP(recorder, request)
+			if recorder.Code != tt.wantStatusCode {
+				t.Errorf("StatusCode = %v, want %v", recorder.Code, tt.wantStatusCode)
+			}
+			if got := recorder.Header().Get("Content-Encoding"); got != tt.wantContentEncoding {
+				t.Errorf("Content-Encoding = %q, want %q", got, tt.wantContentEncoding)
+			}
+			if tt.wantContentEncoding == "gzip" {
+				reader, err := gzip.NewReader(recorder.Body)
+				if err != nil {
+					t.Fatal(err)
+				}
+				responseRaw, _ := io.ReadAll(reader)
+				if !strings.Contains(string(responseRaw), strings.Repeat("a", 2048)) {
+					t.Errorf("decompressed response = %s", responseRaw)
+				}
+			}
+		})
+	}
+}
+
+func TestWithHTTPCompression(t *testing.T) {
+	var compressedRequests atomic.Int64
+	handler := HTTPHandler(newTestMux(t))
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.Header.Get("Content-Encoding") == "gzip" {
+			compressedRequests.Add(1)
+		}
+		handler.ServeHTTP(w, r)
+	}))
+	defer server.Close()
+	client := NewClient(NewHTTPTransport(server.URL, WithHTTPCompression()))
+
+	large := []string{strings.Repeat("a", 2048)}
+	result, err := Call[[]string](context.Background(), client, "raw", large)
+	if err != nil || len(result) != 1 || result[0] != large[0] {
+		t.Errorf("Call() = %v, %v, want %v", len(result), err, large)
+	}
+	difference, err := Call[int](context.Background(), client, "subtract", []int{42, 23})
+	if err != nil || difference != 19 {
+		t.Errorf("Call() = %v, %v, want 19", difference, err)
+	}
+	if got := compressedRequests.Load(); got != 1 {
+		t.Errorf("compressed requests = %v, want 1", got)
+	}
+}
+
+func Test_acceptsEncoding(t *testing.T) {
+	tests := []struct {
+		acceptEncoding string
+		want           bool
+	}{
+		{"gzip", true},
+		{"deflate, gzip", true},
+		{"GZIP;q=0.5", true},
+		{"gzip;q=0", false},
+		{"*", true},
+		{"deflate", false},
+		{"", false},
+	}
+	for _, tt := range tests {
+		t.Run(tt.acceptEncoding, func(t *testing.T) {
+			header := http.Header{"Accept-Encoding": []string{tt.acceptEncoding}}
+			if got := acceptsEncoding(header, "gzip"); got != tt.want {
+				t.Errorf("acceptsEncoding() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
@@ -0,0 +1,249 @@
+/*  Copyright 2022  Kosmas Valianos (kosmas.valianos@gmail.com)
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    https://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License. */
+
+package gojsonrpc
+
+import "encoding/json"
+
+// MessageKind is the classification of a JSON-RPC payload
+type MessageKind int
+
+// Kinds reported by Conformance
+const (
+	KindInvalid MessageKind = iota
+	KindRequest
+	KindNotification
+	KindResponse
+	KindBatch
+)
+
+var messageKindNames = map[MessageKind]string{
+	KindInvalid:      "invalid",
+	KindRequest:      "request",
+	KindNotification: "notification",
+	KindResponse:     "response",
+	KindBatch:        "batch",
+}
+
+// String implements String() of fmt.Stringer interface
+func (k MessageKind) String() string {
+	if name, ok := messageKindNames[k]; ok {
+		return name
+	}
+	return "unknown"
+}
+
+// ConformanceReport is the outcome of checking a payload against the JSON-RPC 2.0 specification.
+// Elements holds the report of every element of a batch in order
+type ConformanceReport struct {
+	Kind       MessageKind
+	Violations []Violation
+	Elements   []ConformanceReport
+}
+
+// Conforms reports whether neither the payload nor any of its batch elements violates the specification
+func (r ConformanceReport) Conforms() bool {
+	if len(r.Violations) > 0 {
+		return false
+	}
+	for _, element := range r.Elements {
+		if !element.Conforms() {
+			return false
+		}
+	}
+	return true
+}
+
+// Conformance classifies a raw JSON-RPC payload as a request, a notification, a response or a batch of them
+// and lists every violation of the specification found. Unlike the parsers it never stops at the first problem.
+// Returns a ConformanceReport object
+func Conformance(raw []byte) ConformanceReport {
+	if !json.Valid(raw) {
+		return ConformanceReport{Kind: KindInvalid, Violations: []Violation{ViolationParseError}}
+	}
+	if jsonKind(raw) != '[' {
+		return conformanceMessage(raw)
+	}
+
+	var elements []json.RawMessage
+	if err := json.Unmarshal(raw, &elements); err != nil {
+		return ConformanceReport{Kind: KindInvalid, Violations: []Violation{ViolationParseError}}
+	}
+	report := ConformanceReport{Kind: KindBatch}
+	if len(elements) == 0 {
+		report.Violations = []Violation{ViolationBatchEmpty}
+		return report
+	}
+	report.Elements = make([]ConformanceReport, 0, len(elements))
+	for _, element := range elements {
+		if jsonKind(element) == '[' {
+			// Batches do not nest
+			report.Elements = append(report.Elements, ConformanceReport{Kind: KindInvalid, Violations: []Violation{ViolationNotObject}})
+			continue
+		}
+		report.Elements = append(report.Elements, conformanceMessage(element))
+	}
+	return report
+}
+
+func conformanceMessage(raw []byte) ConformanceReport {
+	var members map[string]json.RawMessage
+	if jsonKind(raw) != '{' || json.Unmarshal(raw, &members) != nil {
+		return ConformanceReport{Kind: KindInvalid, Violations: []Violation{ViolationNotObject}}
+	}
+
+	_, hasMethod := members["method"]
+	_, hasResult := members["result"]
+	_, hasError := members["error"]
+	switch {
+	case hasMethod:
+		return conformanceCall(members)
+	case hasResult || hasError:
+		return conformanceResponse(members)
+	default:
+		violations := append(diagnoseJsonRPC(members), ViolationNotMessage)
+		return ConformanceReport{Kind: KindInvalid, Violations: violations}
+	}
+}
+
+func conformanceCall(members map[string]json.RawMessage) ConformanceReport {
+	var violations []Violation
+	violations = append(violations, diagnoseJsonRPC(members)...)
+	violations = append(violations, diagnoseMethod(members)...)
+	violations = append(violations, diagnoseParams(members)...)
+
+	id, ok := members["id"]
+	if !ok {
+		return ConformanceReport{Kind: KindNotification, Violations: violations}
+	}
+	switch jsonKind(id) {
+	case '"', '0':
+	case 'n':
+		violations = append(violations, ViolationIDNull)
+	default:
+		violations = append(violations, ViolationIDType)
+	}
+	return ConformanceReport{Kind: KindRequest, Violations: violations}
+}
+
+func conformanceResponse(members map[string]json.RawMessage) ConformanceReport {
+	violations := diagnoseJsonRPC(members)
+
+	errorRaw, hasError := members["error"]
+	if _, hasResult := members["result"]; hasResult && hasError {
+		violations = append(violations, ViolationResultAndError)
+	}
+	var code *int
+	if hasError {
+		var errorViolations []Violation
+		code, errorViolations = diagnoseError(errorRaw)
+		violations = append(violations, errorViolations...)
+	}
+
+	id, ok := members["id"]
+	switch {
+	case !ok:
+		violations = append(violations, ViolationIDMissing)
+	case jsonKind(id) == 'n':
+		// A null id is only allowed when the id of the request could not be determined
+		if code == nil || (*code != ParseError && *code != InvalidRequest) {
+			violations = append(violations, ViolationResponseIDNull)
+		}
+	case jsonKind(id) != '"' && jsonKind(id) != '0':
+		violations = append(violations, ViolationIDType)
+	}
+	return ConformanceReport{Kind: KindResponse, Violations: violations}
+}
+
+// diagnoseError checks the error member of a response.
+// Returns the error code, when it is a valid one, and the violations found
+func diagnoseError(errorRaw json.RawMessage) (*int, []Violation) {
+	var members map[string]json.RawMessage
+	if jsonKind(errorRaw) != '{' || json.Unmarshal(errorRaw, &members) != nil {
+		return nil, []Violation{ViolationErrorType}
+	}
+
+	var violations []Violation
+	var code *int
+	codeRaw, ok := members["code"]
+	if !ok {
+		violations = append(violations, ViolationErrorCodeMissing)
+	} else {
+		var value int
+		if jsonKind(codeRaw) != '0' || json.Unmarshal(codeRaw, &value) != nil {
+			violations = append(violations, ViolationErrorCodeType)
+		} else {
+			code = &value
+		}
+	}
+
+	messageRaw, ok := members["message"]
+	if !ok {
+		violations = append(violations, ViolationErrorMessageMissing)
+	} else if jsonKind(messageRaw) != '"' {
+		violations = append(violations, ViolationErrorMessageType)
+	}
+	return code, violations
+}
+
+// SpecExample is one of the examples of the JSON-RPC 2.0 specification along with its expected classification
+type SpecExample struct {
+	Name     string
+	Message  string
+	Kind     MessageKind
+	Conforms bool
+}
+
+// SpecExamples are the examples of section 7 of the JSON-RPC 2.0 specification, requests and responses alike.
+// They serve as a suite for checking a validator, or a gateway, against the specification
+var SpecExamples = []SpecExample{
+	{"positional parameters request", `{"jsonrpc": "2.0", "method": "subtract", "params": [42, 23], "id": 1}`, KindRequest, true},
+	{"positional parameters response", `{"jsonrpc": "2.0", "result": 19, "id": 1}`, KindResponse, true},
+	{"named parameters request", `{"jsonrpc": "2.0", "method": "subtract", "params": {"subtrahend": 23, "minuend": 42}, "id": 3}`, KindRequest, true},
+	{"named parameters response", `{"jsonrpc": "2.0", "result": 19, "id": 3}`, KindResponse, true},
+	{"notification with parameters", `{"jsonrpc": "2.0", "method": "update", "params": [1,2,3,4,5]}`, KindNotification, true},
+	{"notification without parameters", `{"jsonrpc": "2.0", "method": "foobar"}`, KindNotification, true},
+	{"non-existent method request", `{"jsonrpc": "2.0", "method": "foobar", "id": "1"}`, KindRequest, true},
+	{"non-existent method response", `{"jsonrpc": "2.0", "error": {"code": -32601, "message": "Method not found"}, "id": "1"}`, KindResponse, true},
+	{"invalid JSON request", `{"jsonrpc": "2.0", "method": "foobar, "params": "bar", "baz]`, KindInvalid, false},
+	{"invalid JSON response", `{"jsonrpc": "2.0", "error": {"code": -32700, "message": "Parse error"}, "id": null}`, KindResponse, true},
+	{"invalid request object", `{"jsonrpc": "2.0", "method": 1, "params": "bar"}`, KindNotification, false},
+	{"invalid request object response", `{"jsonrpc": "2.0", "error": {"code": -32600, "message": "Invalid Request"}, "id": null}`, KindResponse, true},
+	{"invalid JSON batch", `[
+  {"jsonrpc": "2.0", "method": "sum", "params": [1,2,4], "id": "1"},
+  {"jsonrpc": "2.0", "method"
+]`, KindInvalid, false},
+	{"empty batch", `[]`, KindBatch, false},
+	{"invalid non-empty batch", `[1]`, KindBatch, false},
+	{"invalid batch", `[1,2,3]`, KindBatch, false},
+	{"mixed batch request", `[
+  {"jsonrpc": "2.0", "method": "sum", "params": [1,2,4], "id": "1"},
+  {"jsonrpc": "2.0", "method": "notify_hello", "params": [7]},
+  {"jsonrpc": "2.0", "method": "subtract", "params": [42,23], "id": "2"},
+  {"foo": "boo"},
+  {"jsonrpc": "2.0", "method": "foo.get", "params": {"name": "myself"}, "id": "5"},
+  {"jsonrpc": "2.0", "method": "get_data", "id": "9"}
+]`, KindBatch, false},
+	{"mixed batch response", `[
+  {"jsonrpc": "2.0", "result": 7, "id": "1"},
+  {"jsonrpc": "2.0", "result": 19, "id": "2"},
+  {"jsonrpc": "2.0", "error": {"code": -32600, "message": "Invalid Request"}, "id": null},
+  {"jsonrpc": "2.0", "error": {"code": -32601, "message": "Method not found"}, "id": "5"},
+  {"jsonrpc": "2.0", "result": ["hello", 5], "id": "9"}
+]`, KindBatch, true},
+	{"notifications batch", `[
+  {"jsonrpc": "2.0", "method": "notify_sum", "params": [1,2,4]},
+  {"jsonrpc": "2.0", "method": "notify_hello", "params": [7]}
+]`, KindBatch, true},
+}
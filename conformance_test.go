@@ -0,0 +1,103 @@
+/*  Copyright 2022  Kosmas Valianos (kosmas.valianos@gmail.com)
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    https://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License. */
+
+package gojsonrpc
+
+import (
+	"reflect"
+	"testing"
+)
+
+func TestConformanceSpecExamples(t *testing.T) {
+	for _, example := range SpecExamples {
+		t.Run(example.Name, func(t *testing.T) {
+			report := Conformance([]byte(example.Message))
+			if report.Kind != example.Kind {
+				t.Errorf("Conformance().Kind = %v, want %v", report.Kind, example.Kind)
+			}
+			if report.Conforms() != example.Conforms {
+				t.Errorf("Conformance().Conforms() = %v, want %v (%+v)", report.Conforms(), example.Conforms, report)
+			}
+		})
+	}
+}
+
+func TestConformance(t *testing.T) {
+	tests := []struct {
+		name     string
+		rawBytes []byte
+		expected ConformanceReport
+	}{
+		{
+			name:     "Request - every violation",
+			rawBytes: []byte(`{"jsonrpc": "1.0", "method": "rpc.subtract", "params": 42, "id": true}`),
+			expected: ConformanceReport{
+				Kind:       KindRequest,
+				Violations: []Violation{ViolationJsonRPCValue, ViolationMethodReserved, ViolationParamsType, ViolationIDType},
+			},
+		},
+		{
+			name:     "Request - null id",
+			rawBytes: []byte(`{"jsonrpc": "2.0", "method": "subtract", "id": null}`),
+			expected: ConformanceReport{Kind: KindRequest, Violations: []Violation{ViolationIDNull}},
+		},
+		{
+			name:     "Response - result and error",
+			rawBytes: []byte(`{"jsonrpc": "2.0", "result": 19, "error": {"code": -32601, "message": "Method not found"}, "id": 1}`),
+			expected: ConformanceReport{Kind: KindResponse, Violations: []Violation{ViolationResultAndError}},
+		},
+		{
+			name:     "Response - malformed error",
+			rawBytes: []byte(`{"jsonrpc": "2.0", "error": {"code": 1.5}}`),
+			expected: ConformanceReport{
+				Kind:       KindResponse,
+				Violations: []Violation{ViolationErrorCodeType, ViolationErrorMessageMissing, ViolationIDMissing},
+			},
+		},
+		{
+			name:     "Response - error not an object",
+			rawBytes: []byte(`{"error": "failed", "id": 1}`),
+			expected: ConformanceReport{Kind: KindResponse, Violations: []Violation{ViolationJsonRPCMissing, ViolationErrorType}},
+		},
+		{
+			name:     "Response - null id of a result",
+			rawBytes: []byte(`{"jsonrpc": "2.0", "result": 19, "id": null}`),
+			expected: ConformanceReport{Kind: KindResponse, Violations: []Violation{ViolationResponseIDNull}},
+		},
+		{
+			name:     "Neither request nor response",
+			rawBytes: []byte(`{"jsonrpc": "2.0", "id": 1}`),
+			expected: ConformanceReport{Kind: KindInvalid, Violations: []Violation{ViolationNotMessage}},
+		},
+		{
+			name:     "Batch - nested batch",
+			rawBytes: []byte(`[[], {"jsonrpc": "2.0", "method": "update"}]`),
+			expected: ConformanceReport{
+				Kind: KindBatch,
+				Elements: []ConformanceReport{
+					{Kind: KindInvalid, Violations: []Violation{ViolationNotObject}},
+					{Kind: KindNotification},
+				},
+			},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			report := Conformance(tt.rawBytes)
+			if !reflect.DeepEqual(report, tt.expected) {
+				t.Errorf("Conformance() = %+v, want %+v", report, tt.expected)
+			}
+		})
+	}
+}
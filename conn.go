@@ -0,0 +1,201 @@
+/*  Copyright 2022  Kosmas Valianos (kosmas.valianos@gmail.com)
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    https://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License. */
+
+package gojsonrpc
+
+import (
+	"context"
+	"encoding/json"
+	"sync"
+)
+
+// Conn is a JSON-RPC peer over a persistent connection carrying both roles. It serves the requests and notifications
+// received from the remote peer with a Mux, while local code calls and notifies the remote peer through its Client.
+// The responses are correlated with the calls in both directions by their IDs
+type Conn struct {
+	id        string
+	conn      MessageConn
+	mux       *Mux
+	transport *MessageTransport
+	client    *Client
+	ctx       context.Context
+	cancel    context.CancelFunc
+	done      chan struct{}
+	writeMu   sync.Mutex
+	closeOnce sync.Once
+	// subscriptions receive their events in order, in the reading of the connection
+	subscriptions clientSubscriptions
+	// inflight are the requests being served which the remote peer can cancel, by the key of their id
+	inflightMu sync.Mutex
+	inflight   map[string]*inflightRequest
+	// progress are the channels of the progress of the calls made with GoWithProgress, by the key of their id
+	progressMu sync.Mutex
+	progress   map[string]chan ProgressNotification
+	err        error
+}
+
+// NewConn creates a Conn over the connection, serving the incoming requests and notifications with the mux and calling
+// the remote peer with a Client configured by the options, and starts reading the connection in the background.
+// The handlers can get the Conn from their context with ConnFromContext, e.g. to call back the remote peer.
+// A nil mux answers every request with JsonMethodNotFound.
+// Returns a *Conn object
+func NewConn(conn MessageConn, mux *Mux, options ...ClientOption) *Conn {
+	return newConn("", conn, mux, options...)
+}
+
+func newConn(id string, conn MessageConn, mux *Mux, options ...ClientOption) *Conn {
+	if mux == nil {
+		mux = NewMux()
+	}
+	ctx, cancel := context.WithCancel(context.Background())
+	if state, ok := connectionState(conn); ok {
+		ctx = withPeerCertificate(ctx, state)
+	}
+	c := &Conn{
+		id:     id,
+		conn:   conn,
+		mux:    mux,
+		ctx:    ctx,
+		cancel: cancel,
+		done:   make(chan struct{}),
+	}
+	c.ctx = context.WithValue(ctx, connContextKey, c)
+	c.transport = NewMessageTransport(c.write)
+	c.client = NewClient(c.transport, options...)
+	go c.run()
+	return c
+}
+
+// ConnFromContext returns the Conn which received the request or notification being served, if any
+func ConnFromContext(ctx context.Context) (*Conn, bool) {
+	conn, ok := ctx.Value(connContextKey).(*Conn)
+	return conn, ok
+}
+
+// ID returns the ID of the Conn in its ConnRegistry, empty if it is not registered
+func (c *Conn) ID() string {
+	return c.id
+}
+
+// Client returns the Client calling the remote peer
+func (c *Conn) Client() *Client {
+	return c.client
+}
+
+// Call calls a method of the remote peer and unmarshals its result into result. See Client.Call
+func (c *Conn) Call(ctx context.Context, method string, params any, result any) error {
+	return c.client.Call(ctx, method, params, result)
+}
+
+// Notify sends a notification to the remote peer. See Client.Notify
+func (c *Conn) Notify(ctx context.Context, method string, params any) error {
+	return c.client.Notify(ctx, method, params)
+}
+
+// Done returns a channel closed once the connection is closed or lost
+func (c *Conn) Done() <-chan struct{} {
+	return c.done
+}
+
+// Err returns the error which ended the connection once Done is closed, nil if it was closed by Close
+func (c *Conn) Err() error {
+	<-c.done
+	return c.err
+}
+
+// Close closes the connection, cancels the requests being served and fails the outstanding calls with ErrTransportClosed
+func (c *Conn) Close() error {
+	err := c.shutdown(nil)
+	<-c.done
+	return err
+}
+
+// write writes a message to the connection, one at a time
+func (c *Conn) write(ctx context.Context, messageRaw []byte) error {
+	c.writeMu.Lock()
+	defer c.writeMu.Unlock()
+	select {
+	case <-c.ctx.Done():
+		return ErrTransportClosed
+	default:
+	}
+	return c.conn.WriteMessage(ctx, messageRaw)
+}
+
+// run reads the connection, delivering the responses to the outstanding calls and serving the rest concurrently
+func (c *Conn) run() {
+	defer close(c.done)
+	for {
+		messageRaw, err := c.conn.ReadMessage()
+		if err != nil {
+			if c.client.logger != nil && c.ctx.Err() == nil {
+				c.client.logger.logConnLost(c.ctx, c.id, err)
+			}
+			c.shutdown(err)
+			return
+		}
+		if isResponseMessage(messageRaw) {
+			err := c.transport.Deliver(messageRaw)
+			if c.client.logger != nil && err != nil {
+				c.client.logger.logUndelivered(c.ctx, c.id, err)
+			}
+			continue
+		}
+		if c.deliverSubscription(messageRaw) || c.deliverProgress(messageRaw) {
+			continue
+		}
+		go c.serve(messageRaw)
+	}
+}
+
+// serve serves a request, a notification or a batch and writes back its response, if any
+func (c *Conn) serve(messageRaw []byte) {
+	responseRaw := c.mux.Serve(c.ctx, messageRaw)
+	if responseRaw != nil {
+		_ = c.write(c.ctx, responseRaw)
+	}
+}
+
+// shutdown ends the connection once, recording the error which ended it
+func (c *Conn) shutdown(err error) error {
+	var closeErr error
+	c.closeOnce.Do(func() {
+		c.err = err
+		c.cancel()
+		c.transport.Close()
+		c.subscriptions.close()
+		closeErr = c.conn.Close()
+	})
+	return closeErr
+}
+
+// isResponseMessage reports whether a message is a response or a batch of responses, rather than a request, a notification
+// or a batch of them. Messages which are not valid JSON are handed to the Mux which answers them with a parse error
+func isResponseMessage(messageRaw []byte) bool {
+	if jsonKind(messageRaw) == '[' {
+		var messagesRaw []json.RawMessage
+		if err := json.Unmarshal(messageRaw, &messagesRaw); err != nil || len(messagesRaw) == 0 {
+			return false
+		}
+		return jsonKind(messagesRaw[0]) == '{' && isResponseMessage(messagesRaw[0])
+	}
+	var members map[string]json.RawMessage
+	if err := json.Unmarshal(messageRaw, &members); err != nil {
+		return false
+	}
+	_, hasMethod := members["method"]
+	_, hasResult := members["result"]
+	_, hasError := members["error"]
+	return !hasMethod && (hasResult || hasError)
+}
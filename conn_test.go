@@ -0,0 +1,142 @@
+/*  Copyright 2022  Kosmas Valianos (kosmas.valianos@gmail.com)
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    https://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License. */
+
+package gojsonrpc
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+)
+
+func TestConn(t *testing.T) {
+	clientEnd, serverEnd := Pipe()
+
+	// The server calls back the client while serving its request
+	serverMux := newTestMux(t)
+	err := HandleFunc(serverMux, "greet", func(ctx context.Context, params []string) (string, error) {
+		conn, ok := ConnFromContext(ctx)
+		if !ok {
+			return "", errors.New("no connection")
+		}
+		var name string
+		err := conn.Call(ctx, "name", nil, &name)
+		if err != nil {
+			return "", err
+		}
+		return params[0] + " " + name, nil
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	clientMux := NewMux()
+	notified := make(chan string, 1)
+	err = HandleFunc(clientMux, "name", func(ctx context.Context, params any) (string, error) {
+		return "Kosmas", nil
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	err = HandleFunc(clientMux, "log", func(ctx context.Context, params []string) (any, error) {
+		notified <- params[0]
+		return nil, nil
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	client := NewConn(clientEnd, clientMux)
+	server := NewConn(serverEnd, serverMux)
+	defer server.Close()
+
+	t.Run("Call", func(t *testing.T) {
+		var result int
+		err := client.Call(context.Background(), "subtract", []int{42, 23}, &result)
+		if err != nil || result != 19 {
+			t.Errorf("Call() = %v, %v, want %v", result, err, 19)
+		}
+	})
+
+	t.Run("Call back", func(t *testing.T) {
+		result, err := Call[string](context.Background(), client.Client(), "greet", []string{"Hello"})
+		if err != nil || result != "Hello Kosmas" {
+			t.Errorf("Call() = %v, %v, want %v", result, err, "Hello Kosmas")
+		}
+	})
+
+	t.Run("Notify", func(t *testing.T) {
+		err := server.Notify(context.Background(), "log", []string{"started"})
+		if err != nil {
+			t.Fatalf("Notify() = %v, want nil", err)
+		}
+		select {
+		case message := <-notified:
+			if message != "started" {
+				t.Errorf("notification = %v, want %v", message, "started")
+			}
+		case <-time.After(5 * time.Second):
+			t.Error("notification not received")
+		}
+	})
+
+	t.Run("Error", func(t *testing.T) {
+		err := client.Call(context.Background(), "database", nil, nil)
+		if jsonRPCError, ok := AsJsonRPCError(err); !ok || jsonRPCError.Code != JsonInvalidMethodParameters.Code {
+			t.Errorf("Call() = %v, want %v", err, &JsonInvalidMethodParameters)
+		}
+	})
+
+	t.Run("Close", func(t *testing.T) {
+		err := client.Close()
+		if err != nil {
+			t.Errorf("Close() = %v, want nil", err)
+		}
+		if err := client.Err(); err != nil {
+			t.Errorf("Err() = %v, want nil", err)
+		}
+		// The server sees the connection lost
+		if err := server.Err(); err == nil {
+			t.Error("Err() = nil, want an error")
+		}
+		err = server.Call(context.Background(), "name", nil, nil)
+		if !errors.Is(err, ErrTransportClosed) {
+			t.Errorf("Call() = %v, want %v", err, ErrTransportClosed)
+		}
+	})
+}
+
+func Test_isResponseMessage(t *testing.T) {
+	tests := []struct {
+		name    string
+		message string
+		want    bool
+	}{
+		{"Result", `{"jsonrpc":"2.0","result":19,"id":1}`, true},
+		{"Error", `{"jsonrpc":"2.0","error":{"code":-32601,"message":"Method not found"},"id":"1"}`, true},
+		{"Request", `{"jsonrpc":"2.0","method":"subtract","params":[42,23],"id":1}`, false},
+		{"Notification", `{"jsonrpc":"2.0","method":"update"}`, false},
+		{"Batch of responses", `[{"jsonrpc":"2.0","result":19,"id":1}]`, true},
+		{"Batch of requests", `[{"jsonrpc":"2.0","method":"subtract","id":1}]`, false},
+		{"Empty batch", `[]`, false},
+		{"Invalid JSON", `{"jsonrpc":"2.0","result"`, false},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := isResponseMessage([]byte(tt.message)); got != tt.want {
+				t.Errorf("isResponseMessage() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
@@ -0,0 +1,96 @@
+/*  Copyright 2022  Kosmas Valianos (kosmas.valianos@gmail.com)
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    https://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License. */
+
+package gojsonrpc
+
+import (
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// CORSConfig configures the Cross-Origin Resource Sharing of the http.Handler returned by HTTPHandler,
+// so that browser-based clients of other origins can call it
+type CORSConfig struct {
+	// AllowedOrigins are the origins allowed to call, e.g. "https://example.com", or "*" for any origin
+	AllowedOrigins []string
+	// AllowedHeaders are the request headers allowed besides Content-Type, e.g. "Authorization"
+	AllowedHeaders []string
+	// AllowCredentials allows the requests with cookies or HTTP authentication
+	AllowCredentials bool
+	// MaxAge is how long the answer to a preflight request may be cached, not cached by the browser if zero
+	MaxAge time.Duration
+}
+
+// WithCORS enables the Cross-Origin Resource Sharing configured by config. The preflight requests are answered
+// with HTTP status 204 No Content
+func WithCORS(config CORSConfig) HTTPHandlerOption {
+	return func(h *httpHandler) {
+		h.cors = &config
+	}
+}
+
+// allowedOrigin returns the value of the Access-Control-Allow-Origin header for the origin
+func (c *CORSConfig) allowedOrigin(origin string) (string, bool) {
+	for _, allowed := range c.AllowedOrigins {
+		if allowed == "*" {
+			if c.AllowCredentials {
+				// The wildcard is not allowed with credentials
+				return origin, true
+			}
+			return "*", true
+		}
+		if strings.EqualFold(allowed, origin) {
+			return origin, true
+		}
+	}
+	return "", false
+}
+
+// handle adds the CORS headers of the request to the response.
+// Returns true if the request was a preflight request and was answered
+func (c *CORSConfig) handle(w http.ResponseWriter, r *http.Request, methods string) bool {
+	origin := r.Header.Get("Origin")
+	if origin == "" {
+		return false
+	}
+	header := w.Header()
+	header.Add("Vary", "Origin")
+	preflight := r.Method == http.MethodOptions && r.Header.Get("Access-Control-Request-Method") != ""
+	if preflight {
+		header.Add("Vary", "Access-Control-Request-Method")
+		header.Add("Vary", "Access-Control-Request-Headers")
+	}
+
+	allowedOrigin, ok := c.allowedOrigin(origin)
+	if ok {
+		header.Set("Access-Control-Allow-Origin", allowedOrigin)
+		if c.AllowCredentials {
+			header.Set("Access-Control-Allow-Credentials", "true")
+		}
+	}
+	if !preflight {
+		return false
+	}
+	if ok {
+		header.Set("Access-Control-Allow-Methods", methods)
+		header.Set("Access-Control-Allow-Headers", strings.Join(append([]string{"Content-Type"}, c.AllowedHeaders...), ", "))
+		if c.MaxAge > 0 {
+			header.Set("Access-Control-Max-Age", strconv.Itoa(int(c.MaxAge.Seconds())))
+		}
+	}
+	w.WriteHeader(http.StatusNoContent)
+	return true
+}
@@ -0,0 +1,116 @@
+/*  Copyright 2022  Kosmas Valianos (kosmas.valianos@gmail.com)
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    https://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License. */
+
+package gojsonrpc
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestWithCORS(t *testing.T) {
+	tests := []struct {
+		name           string
+		config         CORSConfig
+		method         string
+		header         map[string]string
+		wantStatusCode int
+		wantHeader     map[string]string
+	}{
+		{
+			name:           "Preflight",
+			config:         CORSConfig{AllowedOrigins: []string{"https://example.com"}, AllowedHeaders: []string{"Authorization"}, MaxAge: time.Hour},
+			method:         http.MethodOptions,
+			header:         map[string]string{"Origin": "https://example.com", "Access-Control-Request-Method": "POST"},
+			wantStatusCode: http.StatusNoContent,
+			wantHeader: map[string]string{
+				"Access-Control-Allow-Origin":  "https://example.com",
+				"Access-Control-Allow-Methods": "POST",
+				"Access-Control-Allow-Headers": "Content-Type, Authorization",
+				"Access-Control-Max-Age":       "3600",
+			},
+		},
+		{
+			name:           "Preflight of a disallowed origin",
+			config:         CORSConfig{AllowedOrigins: []string{"https://example.com"}},
+			method:         http.MethodOptions,
+			header:         map[string]string{"Origin": "https://evil.com", "Access-Control-Request-Method": "POST"},
+			wantStatusCode: http.StatusNoContent,
+			wantHeader:     map[string]string{"Access-Control-Allow-Origin": "", "Access-Control-Allow-Methods": ""},
+		},
+		{
+			name:           "Request of any origin",
+			config:         CORSConfig{AllowedOrigins: []string{"*"}},
+			method:         http.MethodPost,
+			header:         map[string]string{"Origin": "https://example.com"},
+			wantStatusCode: http.StatusOK,
+			wantHeader:     map[string]string{"Access-Control-Allow-Origin": "*", "Vary": "Origin"},
+		},
+		{
+			name:           "Request with credentials",
+			config:         CORSConfig{AllowedOrigins: []string{"*"}, AllowCredentials: true},
+			method:         http.MethodPost,
+			header:         map[string]string{"Origin": "https://example.com"},
+			wantStatusCode: http.StatusOK,
+			wantHeader: map[string]string{
+				"Access-Control-Allow-Origin":      "https://example.com",
+				"Access-Control-Allow-Credentials": "true",
+			},
+		},
+		{
+			name:           "Request of a disallowed origin",
+			config:         CORSConfig{AllowedOrigins: []string{"https://example.com"}},
+			method:         http.MethodPost,
+			header:         map[string]string{"Origin": "https://evil.com"},
+			wantStatusCode: http.StatusOK,
+			wantHeader:     map[string]string{"Access-Control-Allow-Origin": ""},
+		},
+		{
+			name:           "Same origin request",
+			config:         CORSConfig{AllowedOrigins: []string{"*"}},
+			method:         http.MethodPost,
+			wantStatusCode: http.StatusOK,
+			wantHeader:     map[string]string{"Access-Control-Allow-Origin": "", "Vary": "Accept-Encoding"},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			handler := HTTPHandler(newTestMux(t), WithCORS(tt.config))
+			var body *strings.Reader
+			if tt.method == http.MethodPost {
+				body = strings.NewReader(`{"jsonrpc":"2.0","method":"subtract","params":[42,23],"id":1}`)
+			} else {
+				body = strings.NewReader("")
+			}
+			request := httptest.NewRequest(tt.method, "/rpc", body)
+			request.Header.Set("Content-Type", "application/json")
+			for key, value := range tt.header {
+				request.Header.Set(key, value)
+			}
+			recorder := httptest.NewRecorder()
+			handler.ServeHTTP(recorder, request)
+			if recorder.Code != tt.wantStatusCode {
+				t.Errorf("StatusCode = %v, want %v", recorder.Code, tt.wantStatusCode)
+			}
+			for key, want := range tt.wantHeader {
+				if got := recorder.Header().Get(key); got != want {
+					t.Errorf("%v = %q, want %q", key, got, want)
+				}
+			}
+		})
+	}
+}
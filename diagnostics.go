@@ -0,0 +1,174 @@
+/*  Copyright 2022  Kosmas Valianos (kosmas.valianos@gmail.com)
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    https://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License. */
+
+package gojsonrpc
+
+import (
+	"bytes"
+	"encoding/json"
+	"strings"
+)
+
+// Violation is a specific violation of the JSON-RPC 2.0 specification
+type Violation int
+
+// Violations reported by DiagnoseRequest and Conformance
+const (
+	ViolationParseError Violation = iota + 1
+	ViolationNotObject
+	ViolationJsonRPCMissing
+	ViolationJsonRPCValue
+	ViolationMethodMissing
+	ViolationMethodType
+	ViolationMethodReserved
+	ViolationParamsType
+	ViolationIDMissing
+	ViolationIDNull
+	ViolationIDType
+	ViolationBatchEmpty
+	ViolationNotMessage
+	ViolationResultAndError
+	ViolationErrorType
+	ViolationErrorCodeMissing
+	ViolationErrorCodeType
+	ViolationErrorMessageMissing
+	ViolationErrorMessageType
+	ViolationResponseIDNull
+)
+
+var violationMessages = map[Violation]string{
+	ViolationParseError:          "invalid JSON",
+	ViolationNotObject:           "message is not an object",
+	ViolationJsonRPCMissing:      "\"jsonrpc\" is missing",
+	ViolationJsonRPCValue:        "\"jsonrpc\" is not exactly \"" + jsonRPCProtocol + "\"",
+	ViolationMethodMissing:       "\"method\" is missing",
+	ViolationMethodType:          "\"method\" is not a string",
+	ViolationMethodReserved:      "\"method\" has the reserved prefix \"rpc.\"",
+	ViolationParamsType:          "\"params\" is neither an array nor an object",
+	ViolationIDMissing:           "\"id\" is missing",
+	ViolationIDNull:              "\"id\" is null",
+	ViolationIDType:              "\"id\" is neither a string nor a number",
+	ViolationBatchEmpty:          "batch is empty",
+	ViolationNotMessage:          "message has neither \"method\" nor \"result\" nor \"error\"",
+	ViolationResultAndError:      "response has both \"result\" and \"error\"",
+	ViolationErrorType:           "\"error\" is not an object",
+	ViolationErrorCodeMissing:    "\"code\" of \"error\" is missing",
+	ViolationErrorCodeType:       "\"code\" of \"error\" is not an integer",
+	ViolationErrorMessageMissing: "\"message\" of \"error\" is missing",
+	ViolationErrorMessageType:    "\"message\" of \"error\" is not a string",
+	ViolationResponseIDNull:      "\"id\" is null although the error is neither a parse error nor an invalid request",
+}
+
+// String implements String() of fmt.Stringer interface
+func (v Violation) String() string {
+	if message, ok := violationMessages[v]; ok {
+		return message
+	}
+	return "unknown violation"
+}
+
+// DiagnoseRequest parses a JSON-RPC request from raw bytes reporting every violation of the specification found.
+// Returns a *request object or the list of violations
+func DiagnoseRequest(requestRaw []byte) (*request, []Violation) {
+	if !json.Valid(requestRaw) {
+		return nil, []Violation{ViolationParseError}
+	}
+
+	var members map[string]json.RawMessage
+	err := json.Unmarshal(requestRaw, &members)
+	if err != nil || members == nil {
+		return nil, []Violation{ViolationNotObject}
+	}
+
+	var violations []Violation
+	violations = append(violations, diagnoseJsonRPC(members)...)
+	violations = append(violations, diagnoseMethod(members)...)
+	violations = append(violations, diagnoseParams(members)...)
+
+	id, ok := members["id"]
+	switch {
+	case !ok:
+		violations = append(violations, ViolationIDMissing)
+	case jsonKind(id) == 'n':
+		violations = append(violations, ViolationIDNull)
+	case jsonKind(id) != '"' && jsonKind(id) != '0':
+		violations = append(violations, ViolationIDType)
+	}
+
+	if len(violations) > 0 {
+		return nil, violations
+	}
+
+	request, jsonRPCError := ParseRequest(requestRaw)
+	if jsonRPCError != nil {
+		// Not expected as the checks above are a superset of ParseRequest's ones
+		return nil, []Violation{ViolationNotObject}
+	}
+	return request, nil
+}
+
+func diagnoseJsonRPC(members map[string]json.RawMessage) []Violation {
+	jsonRPC, ok := members["jsonrpc"]
+	if !ok {
+		return []Violation{ViolationJsonRPCMissing}
+	}
+
+	var version string
+	if json.Unmarshal(jsonRPC, &version) != nil || version != jsonRPCProtocol {
+		return []Violation{ViolationJsonRPCValue}
+	}
+	return nil
+}
+
+func diagnoseMethod(members map[string]json.RawMessage) []Violation {
+	methodRaw, ok := members["method"]
+	if !ok {
+		return []Violation{ViolationMethodMissing}
+	}
+
+	var method string
+	if json.Unmarshal(methodRaw, &method) != nil {
+		return []Violation{ViolationMethodType}
+	}
+	if strings.HasPrefix(method, "rpc.") {
+		return []Violation{ViolationMethodReserved}
+	}
+	return nil
+}
+
+func diagnoseParams(members map[string]json.RawMessage) []Violation {
+	params, ok := members["params"]
+	if ok && jsonKind(params) != '[' && jsonKind(params) != '{' {
+		return []Violation{ViolationParamsType}
+	}
+	return nil
+}
+
+// jsonKind classifies a valid JSON value by its first byte.
+// Returns '{', '[', '"', 'n' for null, 't' for booleans or '0' for numbers
+func jsonKind(valueRaw json.RawMessage) byte {
+	valueRaw = bytes.TrimLeft(valueRaw, " \t\r\n")
+	if len(valueRaw) == 0 {
+		return 0
+	}
+
+	switch c := valueRaw[0]; c {
+	case '{', '[', '"', 'n':
+		return c
+	case 't', 'f':
+		return 't'
+	default:
+		return '0'
+	}
+}
@@ -0,0 +1,125 @@
+/*  Copyright 2022  Kosmas Valianos (kosmas.valianos@gmail.com)
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    https://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License. */
+
+package gojsonrpc
+
+import (
+	"reflect"
+	"testing"
+)
+
+func TestDiagnoseRequest(t *testing.T) {
+	tests := []struct {
+		name                   string
+		rawBytes               []byte
+		expectedJsonRPCRequest *request
+		expectedViolations     []Violation
+	}{
+		{
+			name:     "Valid request",
+			rawBytes: []byte(`{"jsonrpc": "2.0", "method": "subtract", "params": [42, 23], "id": 1}`),
+			expectedJsonRPCRequest: &request{
+				JsonRPC: jsonRPCProtocol,
+				Method:  "subtract",
+				Params:  []byte(`[42, 23]`),
+				ID:      float64(1),
+			},
+		},
+		{
+			name:     "Valid request - no params",
+			rawBytes: []byte(`{"jsonrpc": "2.0", "method": "subtract", "id": "abc"}`),
+			expectedJsonRPCRequest: &request{
+				JsonRPC: jsonRPCProtocol,
+				Method:  "subtract",
+				ID:      "abc",
+			},
+		},
+		{
+			name:               "Parse error",
+			rawBytes:           []byte(`{"jsonrpc": "2.0", "method": "subtract", "params": [42, 23], "id": 1`),
+			expectedViolations: []Violation{ViolationParseError},
+		},
+		{
+			name:               "Invalid request - not an object",
+			rawBytes:           []byte(`[1, 2]`),
+			expectedViolations: []Violation{ViolationNotObject},
+		},
+		{
+			name:               "Invalid request - null",
+			rawBytes:           []byte(`null`),
+			expectedViolations: []Violation{ViolationNotObject},
+		},
+		{
+			name:               "Invalid request - every member wrong",
+			rawBytes:           []byte(`{"jsonrpc": "1.0", "method": "rpc.subtract", "params": 42, "id": {"test": 1}}`),
+			expectedViolations: []Violation{ViolationJsonRPCValue, ViolationMethodReserved, ViolationParamsType, ViolationIDType},
+		},
+		{
+			name:               "Invalid request - every member missing",
+			rawBytes:           []byte(`{}`),
+			expectedViolations: []Violation{ViolationJsonRPCMissing, ViolationMethodMissing, ViolationIDMissing},
+		},
+		{
+			name:               "Invalid request - wrong types",
+			rawBytes:           []byte(`{"jsonrpc": 2.0, "method": 1, "params": "42", "id": true}`),
+			expectedViolations: []Violation{ViolationJsonRPCValue, ViolationMethodType, ViolationParamsType, ViolationIDType},
+		},
+		{
+			name:               "Invalid request - \"id\" null",
+			rawBytes:           []byte(`{"jsonrpc": "2.0", "method": "subtract", "params": {"minuend": 42}, "id": null}`),
+			expectedViolations: []Violation{ViolationIDNull},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			jsonRPCRequest, violations := DiagnoseRequest(tt.rawBytes)
+			if !reflect.DeepEqual(violations, tt.expectedViolations) {
+				t.Errorf("DiagnoseRequest() violations = %v, want %v", violations, tt.expectedViolations)
+				return
+			}
+
+			if !reflect.DeepEqual(jsonRPCRequest, tt.expectedJsonRPCRequest) {
+				t.Errorf("DiagnoseRequest() = %v, want %v", jsonRPCRequest, tt.expectedJsonRPCRequest)
+			}
+		})
+	}
+}
+
+func TestViolation_String(t *testing.T) {
+	tests := []struct {
+		name      string
+		violation Violation
+		want      string
+	}{
+		{
+			name:      "Known violation",
+			violation: ViolationMethodReserved,
+			want:      `"method" has the reserved prefix "rpc."`,
+		},
+		{
+			name:      "Unknown violation",
+			violation: Violation(0),
+			want:      "unknown violation",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := tt.violation.String(); got != tt.want {
+				t.Errorf("String() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
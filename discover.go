@@ -0,0 +1,120 @@
+/*  Copyright 2022  Kosmas Valianos (kosmas.valianos@gmail.com)
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    https://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License. */
+
+package gojsonrpc
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"sort"
+)
+
+// MethodInfo describes a method for introspection
+type MethodInfo struct {
+	Name        string `json:"name"`
+	Summary     string `json:"summary,omitempty"`
+	Description string `json:"description,omitempty"`
+	Deprecated  bool   `json:"deprecated,omitempty"`
+}
+
+// WithDiscovery enables the built-in methods "rpc.discover", returning the OpenRPC document of the registered
+// methods, and "rpc.listMethods", returning their names. Only the methods visible through the MethodFilter of the
+// request's context are listed
+func WithDiscovery() MuxOption {
+	return func(m *Mux) {
+		m.builtins["rpc.discover"] = HandlerFunc(func(ctx context.Context, params json.RawMessage) (any, error) {
+			return newOpenRPCDocument(m.openRPCInfo, m.fieldNaming, m.visibleRoutes(ctx)), nil
+		})
+		m.builtins["rpc.listMethods"] = HandlerFunc(func(ctx context.Context, params json.RawMessage) (any, error) {
+			routes := m.visibleRoutes(ctx)
+			names := make([]string, 0, len(routes))
+			for _, route := range routes {
+				names = append(names, route.info.Name)
+			}
+			return names, nil
+		})
+	}
+}
+
+// SetMethodInfo sets the info of a registered method which is returned by Methods and "rpc.discover".
+// The name of the info is always the method.
+// Returns an error if the method is not registered
+func (m *Mux) SetMethodInfo(method string, methodInfo MethodInfo) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	if _, ok := m.handlers[method]; !ok {
+		return fmt.Errorf("method \"%v\" is not registered", method)
+	}
+
+	methodInfo.Name = method
+	m.methodInfos[method] = methodInfo
+	return nil
+}
+
+// Methods returns the info of the registered methods, including the ones of the mounted muxes, sorted by name
+func (m *Mux) Methods() []MethodInfo {
+	routes := m.routes()
+	methodInfos := make([]MethodInfo, 0, len(routes))
+	for _, route := range routes {
+		methodInfos = append(methodInfos, route.info)
+	}
+	return methodInfos
+}
+
+// route is a registered method along with its info and its handler
+type route struct {
+	info    MethodInfo
+	handler Handler
+}
+
+// routes returns the registered methods, including the ones of the mounted muxes, sorted by name
+func (m *Mux) routes() []route {
+	m.mu.RLock()
+	routes := make([]route, 0, len(m.handlers))
+	for method, handler := range m.handlers {
+		methodInfo := m.methodInfos[method]
+		methodInfo.Name = method
+		routes = append(routes, route{info: methodInfo, handler: handler})
+	}
+	mounts := make(map[string]*Mux, len(m.mounts))
+	for prefix, mux := range m.mounts {
+		mounts[prefix] = mux
+	}
+	m.mu.RUnlock()
+
+	for prefix, mux := range mounts {
+		for _, route := range mux.routes() {
+			route.info.Name = prefix + "." + route.info.Name
+			routes = append(routes, route)
+		}
+	}
+
+	sort.Slice(routes, func(i, j int) bool {
+		return routes[i].info.Name < routes[j].info.Name
+	})
+	return routes
+}
+
+// visibleRoutes returns the registered methods visible through the MethodFilter carried by ctx
+func (m *Mux) visibleRoutes(ctx context.Context) []route {
+	routes := m.routes()
+	visible := routes[:0]
+	for _, route := range routes {
+		if methodVisible(ctx, route.info.Name) {
+			visible = append(visible, route)
+		}
+	}
+	return visible
+}
@@ -0,0 +1,181 @@
+/*  Copyright 2022  Kosmas Valianos (kosmas.valianos@gmail.com)
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    https://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License. */
+
+package gojsonrpc
+
+import (
+	"bytes"
+	"context"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+func newTestDiscoveryMux(t *testing.T) *Mux {
+	invoiceMux := NewMux()
+	err := HandleFunc(invoiceMux, "create", func(ctx context.Context, params any) (string, error) {
+		return "created", nil
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	err = invoiceMux.SetMethodInfo("create", MethodInfo{Summary: "Creates an invoice"})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	mux := NewMux(WithDiscovery())
+	err = HandleFunc(mux, "subtract", func(ctx context.Context, params [2]int) (int, error) {
+		return params[0] - params[1], nil
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	err = mux.SetMethodInfo("subtract", MethodInfo{Name: "ignored", Description: "Subtracts the second number from the first one", Deprecated: true})
+	if err != nil {
+		t.Fatal(err)
+	}
+	err = HandleFunc(mux, "add", func(ctx context.Context, params [2]int) (int, error) {
+		return params[0] + params[1], nil
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	err = mux.Mount("invoice", invoiceMux)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return mux
+}
+
+func TestMux_Methods(t *testing.T) {
+	mux := newTestDiscoveryMux(t)
+	want := []MethodInfo{
+		{Name: "add"},
+		{Name: "invoice.create", Summary: "Creates an invoice"},
+		{Name: "subtract", Description: "Subtracts the second number from the first one", Deprecated: true},
+	}
+	if got := mux.Methods(); !reflect.DeepEqual(got, want) {
+		t.Errorf("Methods() = %v, want %v", got, want)
+	}
+
+	err := mux.SetMethodInfo("multiply", MethodInfo{})
+	if err == nil {
+		t.Error("SetMethodInfo() of a method not registered succeeded")
+	}
+}
+
+func TestWithDiscovery(t *testing.T) {
+	tests := []struct {
+		name     string
+		mux      *Mux
+		rawBytes []byte
+		want     []byte
+	}{
+		{
+			name:     "rpc.discover",
+			mux:      newTestDiscoveryMux(t),
+			rawBytes: []byte(`{"jsonrpc": "2.0", "method": "rpc.discover", "id": 1}`),
+			want:     []byte(`{"jsonrpc":"2.0","result":{"openrpc":"1.2.6","info":{"title":"","version":""},"methods":[{"name":"add","paramStructure":"by-position","params":[{"name":"param1","required":true,"schema":{"type":"integer"}},{"name":"param2","required":true,"schema":{"type":"integer"}}],"result":{"name":"result","schema":{"type":"integer"}}},{"name":"invoice.create","summary":"Creates an invoice","params":[],"result":{"name":"result","schema":{"type":"string"}}},{"name":"subtract","description":"Subtracts the second number from the first one","deprecated":true,"paramStructure":"by-position","params":[{"name":"param1","required":true,"schema":{"type":"integer"}},{"name":"param2","required":true,"schema":{"type":"integer"}}],"result":{"name":"result","schema":{"type":"integer"}}}]},"id":1}` + "\n"),
+		},
+		{
+			name:     "rpc.listMethods",
+			mux:      newTestDiscoveryMux(t),
+			rawBytes: []byte(`{"jsonrpc": "2.0", "method": "rpc.listMethods", "id": 1}`),
+			want:     []byte(`{"jsonrpc":"2.0","result":["add","invoice.create","subtract"],"id":1}` + "\n"),
+		},
+		{
+			name:     "Unknown reserved method",
+			mux:      newTestDiscoveryMux(t),
+			rawBytes: []byte(`{"jsonrpc": "2.0", "method": "rpc.subtract", "id": 1}`),
+			want:     []byte(`{"jsonrpc":"2.0","error":{"code":-32600,"message":"Invalid Request"},"id":null}` + "\n"),
+		},
+		{
+			name:     "Discovery disabled",
+			mux:      newTestMux(t),
+			rawBytes: []byte(`{"jsonrpc": "2.0", "method": "rpc.discover", "id": 1}`),
+			want:     []byte(`{"jsonrpc":"2.0","error":{"code":-32600,"message":"Invalid Request"},"id":null}` + "\n"),
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			jsonRPCResponseRaw := tt.mux.Serve(context.Background(), tt.rawBytes)
+			if !bytes.Equal(jsonRPCResponseRaw, tt.want) {
+				t.Errorf("Serve() = %v, want %v", string(jsonRPCResponseRaw), string(tt.want))
+			}
+		})
+	}
+}
+
+func TestContextWithMethodFilter(t *testing.T) {
+	// A session whose role only allows the invoice methods
+	filter := func(method string) bool {
+		return strings.HasPrefix(method, "invoice.") || strings.HasPrefix(method, "rpc.")
+	}
+
+	tests := []struct {
+		name     string
+		rawBytes []byte
+		want     []byte
+	}{
+		{
+			name:     "Visible method",
+			rawBytes: []byte(`{"jsonrpc": "2.0", "method": "invoice.create", "id": 1}`),
+			want:     []byte(`{"jsonrpc":"2.0","result":"created","id":1}` + "\n"),
+		},
+		{
+			name:     "Hidden method",
+			rawBytes: []byte(`{"jsonrpc": "2.0", "method": "subtract", "params": [42, 23], "id": 1}`),
+			want:     []byte(`{"jsonrpc":"2.0","error":{"code":-32601,"message":"Method not found"},"id":1}` + "\n"),
+		},
+		{
+			name:     "rpc.discover",
+			rawBytes: []byte(`{"jsonrpc": "2.0", "method": "rpc.discover", "id": 1}`),
+			want:     []byte(`{"jsonrpc":"2.0","result":{"openrpc":"1.2.6","info":{"title":"","version":""},"methods":[{"name":"invoice.create","summary":"Creates an invoice","params":[],"result":{"name":"result","schema":{"type":"string"}}}]},"id":1}` + "\n"),
+		},
+		{
+			name:     "rpc.listMethods",
+			rawBytes: []byte(`{"jsonrpc": "2.0", "method": "rpc.listMethods", "id": 1}`),
+			want:     []byte(`{"jsonrpc":"2.0","result":["invoice.create"],"id":1}` + "\n"),
+		},
+	}
+
+	mux := newTestDiscoveryMux(t)
+	ctx := ContextWithMethodFilter(context.Background(), filter)
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			jsonRPCResponseRaw := mux.Serve(ctx, tt.rawBytes)
+			if !bytes.Equal(jsonRPCResponseRaw, tt.want) {
+				t.Errorf("Serve() = %v, want %v", string(jsonRPCResponseRaw), string(tt.want))
+			}
+		})
+	}
+
+	t.Run("Hidden notification", func(t *testing.T) {
+		handled := false
+		err := HandleFunc(mux, "notify", func(ctx context.Context, params any) (any, error) {
+			handled = true
+			return nil, nil
+		})
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		mux.Serve(ctx, []byte(`{"jsonrpc": "2.0", "method": "notify"}`))
+		if handled {
+			t.Error("Serve() handled a hidden notification")
+		}
+	})
+}
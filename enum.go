@@ -0,0 +1,113 @@
+/*  Copyright 2022  Kosmas Valianos (kosmas.valianos@gmail.com)
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    https://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License. */
+
+package gojsonrpc
+
+import (
+	"encoding/json"
+	"fmt"
+	"reflect"
+	"strings"
+)
+
+// EnumValues is implemented by the types whose values are restricted, e.g.
+//
+//	type Color string
+//
+//	func (Color) Values() []Color { return []Color{"red", "green", "blue"} }
+type EnumValues[T any] interface {
+	comparable
+	// Values returns the allowed values
+	Values() []T
+}
+
+// Enum is a params or result field restricted to the allowed values of T.
+// Unmarshaling a value which is not allowed fails, so handlers registered with HandleFunc
+// reply with JsonInvalidMethodParameters listing the allowed values, which the OpenRPC document lists as well
+type Enum[T EnumValues[T]] struct {
+	Value T
+}
+
+// NewEnum creates an Enum of the value.
+// Returns an Enum object or an error if the value is not allowed
+func NewEnum[T EnumValues[T]](value T) (Enum[T], error) {
+	enum := Enum[T]{Value: value}
+	if !enum.Valid() {
+		return Enum[T]{}, enum.invalidError()
+	}
+	return enum, nil
+}
+
+// Values returns the allowed values
+func (e Enum[T]) Values() []T {
+	return e.Value.Values()
+}
+
+// Valid reports whether the value is allowed
+func (e Enum[T]) Valid() bool {
+	for _, value := range e.Values() {
+		if value == e.Value {
+			return true
+		}
+	}
+	return false
+}
+
+// String returns the value formatted with fmt
+func (e Enum[T]) String() string {
+	return fmt.Sprint(e.Value)
+}
+
+// MarshalJSON implements json.Marshaler by marshaling the value.
+// Returns an error listing the allowed values if the value is not allowed, as UnmarshalJSON would refuse it
+func (e Enum[T]) MarshalJSON() ([]byte, error) {
+	if !e.Valid() {
+		return nil, e.invalidError()
+	}
+	return json.Marshal(e.Value)
+}
+
+// UnmarshalJSON implements json.Unmarshaler by unmarshaling the value.
+// Returns an error listing the allowed values if the value is not allowed
+func (e *Enum[T]) UnmarshalJSON(data []byte) error {
+	var enum Enum[T]
+	err := json.Unmarshal(data, &enum.Value)
+	if err != nil {
+		return err
+	}
+	if !enum.Valid() {
+		return enum.invalidError()
+	}
+	*e = enum
+	return nil
+}
+
+func (e Enum[T]) invalidError() error {
+	values := make([]string, 0, len(e.Values()))
+	for _, value := range e.Values() {
+		valueRaw, _ := json.Marshal(value)
+		values = append(values, string(valueRaw))
+	}
+	valueRaw, _ := json.Marshal(e.Value)
+	return fmt.Errorf("%s is not one of the allowed values %v", valueRaw, strings.Join(values, ", "))
+}
+
+// enumSchema returns the type of the values and the allowed values for the OpenRPC document
+func (e Enum[T]) enumSchema() (reflect.Type, []any) {
+	values := make([]any, 0, len(e.Values()))
+	for _, value := range e.Values() {
+		values = append(values, value)
+	}
+	return reflect.TypeOf(e.Value), values
+}
@@ -0,0 +1,139 @@
+/*  Copyright 2022  Kosmas Valianos (kosmas.valianos@gmail.com)
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    https://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License. */
+
+package gojsonrpc
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"reflect"
+	"testing"
+)
+
+type testColor string
+
+func (testColor) Values() []testColor {
+	return []testColor{"red", "green", "blue"}
+}
+
+type testPaint struct {
+	Color Enum[testColor] `json:"color"`
+}
+
+func TestEnum(t *testing.T) {
+	tests := []struct {
+		name    string
+		raw     []byte
+		want    Enum[testColor]
+		wantErr bool
+	}{
+		{
+			name: "Allowed value",
+			raw:  []byte(`"green"`),
+			want: Enum[testColor]{Value: "green"},
+		},
+		{
+			name:    "Not allowed value",
+			raw:     []byte(`"yellow"`),
+			wantErr: true,
+		},
+		{
+			name:    "Wrong type",
+			raw:     []byte(`42`),
+			wantErr: true,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var got Enum[testColor]
+			err := json.Unmarshal(tt.raw, &got)
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("UnmarshalJSON() error = %v, wantErr %v", err, tt.wantErr)
+			}
+			if got != tt.want {
+				t.Errorf("UnmarshalJSON() = %v, want %v", got, tt.want)
+			}
+			if !tt.wantErr {
+				raw, err := json.Marshal(got)
+				if err != nil || !bytes.Equal(raw, tt.raw) {
+					t.Errorf("MarshalJSON() = %v, %v, want %v", string(raw), err, string(tt.raw))
+				}
+			}
+		})
+	}
+
+	if _, err := NewEnum[testColor]("yellow"); err == nil {
+		t.Error("NewEnum() of a not allowed value succeeded")
+	}
+	enum, err := NewEnum[testColor]("red")
+	if err != nil || !enum.Valid() {
+		t.Errorf("NewEnum() = %v, %v, want a valid Enum", enum, err)
+	}
+	if got, want := enum.Values(), (testColor("")).Values(); !reflect.DeepEqual(got, want) {
+		t.Errorf("Values() = %v, want %v", got, want)
+	}
+	// The values which UnmarshalJSON refuses are not marshaled either
+	if raw, err := json.Marshal(Enum[testColor]{Value: "yellow"}); err == nil {
+		t.Errorf("MarshalJSON() = %v, want an error", string(raw))
+	}
+}
+
+func TestEnum_Serve(t *testing.T) {
+	mux := NewMux()
+	err := HandleFunc(mux, "paint", func(ctx context.Context, params testPaint) (Enum[testColor], error) {
+		return params.Color, nil
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	tests := []struct {
+		name     string
+		rawBytes []byte
+		want     []byte
+	}{
+		{
+			name:     "Allowed value",
+			rawBytes: []byte(`{"jsonrpc": "2.0", "method": "paint", "params": {"color": "blue"}, "id": 1}`),
+			want:     []byte(`{"jsonrpc":"2.0","result":"blue","id":1}` + "\n"),
+		},
+		{
+			name:     "Not allowed value",
+			rawBytes: []byte(`{"jsonrpc": "2.0", "method": "paint", "params": {"color": "yellow"}, "id": 1}`),
+			want:     []byte(`{"jsonrpc":"2.0","error":{"code":-32602,"message":"Invalid method parameters","data":"\"yellow\" is not one of the allowed values \"red\", \"green\", \"blue\""},"id":1}` + "\n"),
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			jsonRPCResponseRaw := mux.Serve(context.Background(), tt.rawBytes)
+			if !bytes.Equal(jsonRPCResponseRaw, tt.want) {
+				t.Errorf("Serve() = %v, want %v", string(jsonRPCResponseRaw), string(tt.want))
+			}
+		})
+	}
+
+	t.Run("OpenRPC", func(t *testing.T) {
+		want := `{"openrpc":"1.2.6","info":{"title":"","version":""},"methods":[{"name":"paint","paramStructure":"by-name","params":[{"name":"color","required":true,"schema":{"type":"string","enum":["red","green","blue"]}}],"result":{"name":"result","schema":{"type":"string","enum":["red","green","blue"]}}}]}`
+		got, err := json.Marshal(mux.OpenRPC())
+		if err != nil {
+			t.Fatal(err)
+		}
+		if string(got) != want {
+			t.Errorf("OpenRPC() = %v, want %v", string(got), want)
+		}
+	})
+}
@@ -0,0 +1,77 @@
+/*  Copyright 2022  Kosmas Valianos (kosmas.valianos@gmail.com)
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    https://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License. */
+
+package gojsonrpc
+
+import (
+	"encoding/json"
+)
+
+// responseEnvelope is the envelope of the response to a request, rendered once the request is parsed,
+// so that only the result or the error is encoded when the handler returns
+type responseEnvelope struct {
+	// suffix holds the id member and the end of the response
+	suffix []byte
+}
+
+var (
+	resultResponsePrefix = []byte(`{"jsonrpc":"` + jsonRPCProtocol + `","result":`)
+	errorResponsePrefix  = []byte(`{"jsonrpc":"` + jsonRPCProtocol + `","error":`)
+	nullIDResponseSuffix = []byte(`,"id":null}` + "\n")
+)
+
+// newResponseEnvelope renders the envelope of the response to the request with the id.
+// Returns a responseEnvelope object or an error if the id cannot be marshaled
+func newResponseEnvelope(id any) (responseEnvelope, error) {
+	idRaw, err := json.Marshal(id)
+	if err != nil {
+		return responseEnvelope{}, err
+	}
+	suffix := make([]byte, 0, len(`,"id":}`)+len(idRaw)+1)
+	suffix = append(suffix, `,"id":`...)
+	suffix = append(suffix, idRaw...)
+	suffix = append(suffix, '}', '\n')
+	return responseEnvelope{suffix: suffix}, nil
+}
+
+// resultResponse encodes the result into the envelope with the engine.
+// Returns the raw bytes of the response or an error
+func (e responseEnvelope) resultResponse(engine JSONEngine, result any) ([]byte, error) {
+	resultRaw, err := engine.Marshal(result)
+	if err != nil {
+		return nil, err
+	}
+	return e.render(resultResponsePrefix, resultRaw, e.suffix), nil
+}
+
+// errorResponse encodes the error into the envelope. The id is null if the error is ParseError or InvalidRequest.
+// Returns the raw bytes of the response
+func (e responseEnvelope) errorResponse(jsonRPCError *jsonRPCError) []byte {
+	jsonRPCErrorRaw, err := json.Marshal(jsonRPCError)
+	if err != nil {
+		jsonRPCErrorRaw, _ = json.Marshal(&JsonInternalError)
+	}
+	suffix := e.suffix
+	if jsonRPCError.Code == ParseError || jsonRPCError.Code == InvalidRequest {
+		suffix = nullIDResponseSuffix
+	}
+	return e.render(errorResponsePrefix, jsonRPCErrorRaw, suffix)
+}
+
+func (e responseEnvelope) render(prefix, member, suffix []byte) []byte {
+	responseRaw := make([]byte, 0, len(prefix)+len(member)+len(suffix))
+	responseRaw = append(responseRaw, prefix...)
+	responseRaw = append(responseRaw, member...)
+	return append(responseRaw, suffix...)
+}
@@ -0,0 +1,104 @@
+/*  Copyright 2022  Kosmas Valianos (kosmas.valianos@gmail.com)
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    https://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License. */
+
+package gojsonrpc
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"testing"
+)
+
+func Test_responseEnvelope(t *testing.T) {
+	tests := []struct {
+		name         string
+		id           any
+		result       any
+		jsonRPCError *jsonRPCError
+	}{
+		{name: "Integer id", id: float64(1), result: 19},
+		{name: "Fractional id", id: 2.5, result: []int{1, 2}},
+		{name: "String id", id: "abc<>", result: "<html>"},
+		{name: "Null result", id: float64(1)},
+		{name: "Raw result", id: "abc", result: json.RawMessage(`{ "foo" : [1, 2] }`)},
+		{name: "Error", id: float64(1), jsonRPCError: &JsonMethodNotFound},
+		{name: "Error with data", id: "abc", jsonRPCError: &jsonRPCError{Code: 42, Message: "Custom", Data: json.RawMessage(`{"foo":"bar"}`)}},
+		{name: "Invalid request", id: float64(1), jsonRPCError: &JsonInvalidRequest},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			envelope, err := newResponseEnvelope(tt.id)
+			if err != nil {
+				t.Fatalf("newResponseEnvelope() error = %v", err)
+			}
+			request := &request{JsonRPC: jsonRPCProtocol, ID: tt.id}
+
+			var got, want []byte
+			if tt.jsonRPCError != nil {
+				got = envelope.errorResponse(tt.jsonRPCError)
+				want, err = NewErrorResponse(tt.id, tt.jsonRPCError)
+			} else {
+				got, err = envelope.resultResponse(stdJSONEngine{}, tt.result)
+				if err != nil {
+					t.Fatalf("resultResponse() error = %v", err)
+				}
+				want, err = request.NewResultResponse(tt.result)
+			}
+			if err != nil {
+				t.Fatal(err)
+			}
+			if !bytes.Equal(got, want) {
+				t.Errorf("responseEnvelope = %v, want %v", string(got), string(want))
+			}
+		})
+	}
+}
+
+func BenchmarkMux_Serve(b *testing.B) {
+	mux := NewMux()
+	err := HandleFunc(mux, "subtract", func(ctx context.Context, params [2]int) (int, error) {
+		return params[0] - params[1], nil
+	})
+	if err != nil {
+		b.Fatal(err)
+	}
+	requestRaw := []byte(`{"jsonrpc": "2.0", "method": "subtract", "params": [42, 23], "id": "2b6f1a8e-8d0a-4c4b-9d8e-3f1b2c7a9e10"}`)
+
+	b.ReportAllocs()
+	for i := 0; i < b.N; i++ {
+		mux.Serve(context.Background(), requestRaw)
+	}
+}
+
+func BenchmarkResponse(b *testing.B) {
+	result := map[string]any{"balance": 42.5, "currency": "EUR", "history": []int{1, 2, 3, 4, 5, 6, 7, 8}}
+	id := "2b6f1a8e-8d0a-4c4b-9d8e-3f1b2c7a9e10"
+
+	b.Run("NewResultResponse", func(b *testing.B) {
+		request := &request{JsonRPC: jsonRPCProtocol, ID: id}
+		b.ReportAllocs()
+		for i := 0; i < b.N; i++ {
+			_, _ = request.NewResultResponse(result)
+		}
+	})
+	b.Run("responseEnvelope", func(b *testing.B) {
+		envelope, _ := newResponseEnvelope(id)
+		b.ReportAllocs()
+		for i := 0; i < b.N; i++ {
+			_, _ = envelope.resultResponse(stdJSONEngine{}, result)
+		}
+	})
+}
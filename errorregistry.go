@@ -0,0 +1,144 @@
+/*  Copyright 2022  Kosmas Valianos (kosmas.valianos@gmail.com)
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    https://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License. */
+
+package gojsonrpc
+
+import (
+	"errors"
+	"fmt"
+	"sort"
+	"sync"
+)
+
+// ErrorDefinition declares an error of an application once, to be referenced by its name
+type ErrorDefinition struct {
+	Name    string
+	Code    int
+	Message string
+	// DataSchema describes the data of the error, if any, e.g. for the documentation of the API
+	DataSchema *JSONSchema
+}
+
+// ErrorRegistry keeps the ErrorDefinitions of an application by name, so that a large codebase uses each
+// code consistently. It is safe for concurrent use
+type ErrorRegistry struct {
+	mu          sync.RWMutex
+	definitions map[string]ErrorDefinition
+	names       map[int]string
+}
+
+// Errors is the default ErrorRegistry
+var Errors = NewErrorRegistry()
+
+// predefinedErrors are the error objects of the package, whose codes cannot be defined again
+var predefinedErrors = []*jsonRPCError{
+	&JsonParseError, &JsonInvalidRequest, &JsonMethodNotFound, &JsonInvalidMethodParameters, &JsonInternalError,
+	&JsonRequestTimeout, &JsonServerBusy, &JsonUpstreamUnavailable,
+	&JsonServerNotInitialized, &JsonUnknownError, &JsonRequestFailed, &JsonServerCancelled, &JsonContentModified,
+	&JsonRequestCancelled,
+}
+
+// predefinedError returns the error object of the package with the code, if any
+func predefinedError(code int) (*jsonRPCError, bool) {
+	for _, predefined := range predefinedErrors {
+		if predefined.Code == code {
+			return predefined, true
+		}
+	}
+	return nil, false
+}
+
+// NewErrorRegistry creates an empty ErrorRegistry.
+// Returns a *ErrorRegistry object
+func NewErrorRegistry() *ErrorRegistry {
+	return &ErrorRegistry{definitions: make(map[string]ErrorDefinition), names: make(map[int]string)}
+}
+
+// Define adds the definition to the registry. Its code must be a custom one, see IsCustomCode, and neither the code
+// of an error object of the package, e.g. JsonRequestTimeout, nor of another definition.
+// Returns an error if the definition is invalid or collides with another one
+func (r *ErrorRegistry) Define(definition ErrorDefinition) error {
+	if definition.Name == "" {
+		return errors.New("error definition without a name")
+	}
+	if !IsCustomCode(definition.Code) {
+		return fmt.Errorf("code %v of error %q is reserved by the specification", definition.Code, definition.Name)
+	}
+	if predefined, ok := predefinedError(definition.Code); ok {
+		return fmt.Errorf("code %v of error %q is the code of %q", definition.Code, definition.Name, predefined.Message)
+	}
+
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	if _, ok := r.definitions[definition.Name]; ok {
+		return fmt.Errorf("error %q is already defined", definition.Name)
+	}
+	if name, ok := r.names[definition.Code]; ok {
+		return fmt.Errorf("code %v of error %q is the code of error %q", definition.Code, definition.Name, name)
+	}
+	r.definitions[definition.Name] = definition
+	r.names[definition.Code] = definition.Name
+	return nil
+}
+
+// Get creates the error object of the definition with the name.
+// Returns a new *jsonRPCError object and true or false if no error is defined with the name
+func (r *ErrorRegistry) Get(name string) (*jsonRPCError, bool) {
+	r.mu.RLock()
+	definition, ok := r.definitions[name]
+	r.mu.RUnlock()
+	if !ok {
+		return nil, false
+	}
+	return &jsonRPCError{Code: definition.Code, Message: definition.Message}, true
+}
+
+// New creates the error object of the definition with the name and the data, nil for omitting.
+// Returns a new *jsonRPCError object or an error if no error is defined with the name or the data cannot be marshaled
+func (r *ErrorRegistry) New(name string, data any) (*jsonRPCError, error) {
+	jsonRPCError, ok := r.Get(name)
+	if !ok {
+		return nil, fmt.Errorf("error %q is not defined", name)
+	}
+	if data == nil {
+		return jsonRPCError, nil
+	}
+	return jsonRPCError.AddData(data)
+}
+
+// Lookup finds the definition of a code, e.g. of the error object of a response.
+// Returns the ErrorDefinition and true or false if no error is defined with the code
+func (r *ErrorRegistry) Lookup(code int) (ErrorDefinition, bool) {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+	name, ok := r.names[code]
+	if !ok {
+		return ErrorDefinition{}, false
+	}
+	return r.definitions[name], true
+}
+
+// Definitions returns the definitions of the registry sorted by code
+func (r *ErrorRegistry) Definitions() []ErrorDefinition {
+	r.mu.RLock()
+	definitions := make([]ErrorDefinition, 0, len(r.definitions))
+	for _, definition := range r.definitions {
+		definitions = append(definitions, definition)
+	}
+	r.mu.RUnlock()
+	sort.Slice(definitions, func(i, j int) bool {
+		return definitions[i].Code < definitions[j].Code
+	})
+	return definitions
+}
@@ -0,0 +1,99 @@
+/*  Copyright 2022  Kosmas Valianos (kosmas.valianos@gmail.com)
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    https://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License. */
+
+package gojsonrpc
+
+import (
+	"errors"
+	"reflect"
+	"testing"
+)
+
+func TestErrorRegistry_Define(t *testing.T) {
+	registry := NewErrorRegistry()
+	err := registry.Define(ErrorDefinition{Name: "db_unavailable", Code: -32050, Message: "Database unavailable"})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	tests := []struct {
+		name       string
+		definition ErrorDefinition
+		wantErr    bool
+	}{
+		{name: "Application code", definition: ErrorDefinition{Name: "insufficient_funds", Code: 1001, Message: "Insufficient funds"}},
+		{name: "Negative application code", definition: ErrorDefinition{Name: "quota_exceeded", Code: -40000, Message: "Quota exceeded"}},
+		{name: "No name", definition: ErrorDefinition{Code: 1002, Message: "Unnamed"}, wantErr: true},
+		{name: "Duplicate name", definition: ErrorDefinition{Name: "db_unavailable", Code: -32051, Message: "Database unavailable"}, wantErr: true},
+		{name: "Duplicate code", definition: ErrorDefinition{Name: "db_down", Code: -32050, Message: "Database down"}, wantErr: true},
+		{name: "Reserved code", definition: ErrorDefinition{Name: "bad_params", Code: -32602, Message: "Bad params"}, wantErr: true},
+		{name: "Predefined server error", definition: ErrorDefinition{Name: "timeout", Code: RequestTimeout, Message: "Timeout"}, wantErr: true},
+		{name: "Predefined LSP error", definition: ErrorDefinition{Name: "cancelled", Code: RequestCancelled, Message: "Cancelled"}, wantErr: true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			err := registry.Define(tt.definition)
+			if (err != nil) != tt.wantErr {
+				t.Errorf("Define() error = %v, wantErr %v", err, tt.wantErr)
+			}
+		})
+	}
+
+	var codes []int
+	for _, definition := range registry.Definitions() {
+		codes = append(codes, definition.Code)
+	}
+	if want := []int{-40000, -32050, 1001}; !reflect.DeepEqual(codes, want) {
+		t.Errorf("Definitions() codes = %v, want %v", codes, want)
+	}
+}
+
+func TestErrorRegistry_Get(t *testing.T) {
+	registry := NewErrorRegistry()
+	definition := ErrorDefinition{Name: "db_unavailable", Code: -32050, Message: "Database unavailable", DataSchema: &JSONSchema{Type: "string"}}
+	if err := registry.Define(definition); err != nil {
+		t.Fatal(err)
+	}
+
+	want := &jsonRPCError{Code: -32050, Message: "Database unavailable"}
+	got, ok := registry.Get("db_unavailable")
+	if !ok || !reflect.DeepEqual(got, want) {
+		t.Errorf("Get() = %v, %v, want %v", got, ok, want)
+	}
+	// Each error object is a new one
+	got.Message = "Changed"
+	if again, _ := registry.Get("db_unavailable"); again.Message != "Database unavailable" {
+		t.Errorf("Get() = %v after changing a previous error object", again)
+	}
+	if _, ok := registry.Get("unknown"); ok {
+		t.Error("Get() = true for an unknown error")
+	}
+
+	withData, err := registry.New("db_unavailable", "primary")
+	if err != nil || string(withData.Data) != `"primary"` || !errors.Is(withData, want) {
+		t.Errorf("New() = %v, %v, want the data \"primary\"", withData, err)
+	}
+	withoutData, err := registry.New("db_unavailable", nil)
+	if err != nil || withoutData.Data != nil {
+		t.Errorf("New() = %v, %v, want no data", withoutData, err)
+	}
+	if _, err := registry.New("unknown", nil); err == nil {
+		t.Error("New() error = nil for an unknown error")
+	}
+
+	gotDefinition, ok := registry.Lookup(-32050)
+	if !ok || !reflect.DeepEqual(gotDefinition, definition) {
+		t.Errorf("Lookup() = %v, %v, want %v", gotDefinition, ok, definition)
+	}
+}
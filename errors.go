@@ -0,0 +1,172 @@
+/*  Copyright 2022  Kosmas Valianos (kosmas.valianos@gmail.com)
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    https://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License. */
+
+package gojsonrpc
+
+import (
+	"encoding/json"
+	"errors"
+)
+
+// MaxCauseDepth is the maximum number of nested causes kept by AddUpstreamCause
+const MaxCauseDepth = 8
+
+type errorCauseData struct {
+	Cause *jsonRPCError `json:"cause"`
+}
+
+// Sentinel errors matching a JSON-RPC error by its code with errors.Is, whatever its message and data,
+// e.g. errors.Is(err, ErrMethodNotFound) for the error object of a response returned by Call
+var (
+	ErrParseError              error = &JsonParseError
+	ErrInvalidRequest          error = &JsonInvalidRequest
+	ErrMethodNotFound          error = &JsonMethodNotFound
+	ErrInvalidMethodParameters error = &JsonInvalidMethodParameters
+	ErrInternalError           error = &JsonInternalError
+	ErrRequestTimeout          error = &JsonRequestTimeout
+	ErrServerBusy              error = &JsonServerBusy
+	ErrUpstreamUnavailable     error = &JsonUpstreamUnavailable
+	ErrServerNotInitialized    error = &JsonServerNotInitialized
+	ErrUnknownError            error = &JsonUnknownError
+	ErrRequestFailed           error = &JsonRequestFailed
+	ErrServerCancelled         error = &JsonServerCancelled
+	ErrContentModified         error = &JsonContentModified
+	ErrRequestCancelled        error = &JsonRequestCancelled
+)
+
+// Is reports whether the target is a JSON-RPC error with the same code, which errors.Is relies on
+func (j *jsonRPCError) Is(target error) bool {
+	targetJsonRPCError, ok := target.(*jsonRPCError)
+	return ok && targetJsonRPCError != nil && j != nil && targetJsonRPCError.Code == j.Code
+}
+
+// AsJsonRPCError finds the first JSON-RPC error in the chain of err, e.g. the error object of a response returned by Call.
+// Returns the *jsonRPCError object and true or false if there is none
+func AsJsonRPCError(err error) (*jsonRPCError, bool) {
+	var jsonRPCError *jsonRPCError
+	ok := errors.As(err, &jsonRPCError)
+	return jsonRPCError, ok
+}
+
+// ErrNoErrorData is returned when unmarshaling the data of a JSON-RPC error which has none
+var ErrNoErrorData = errors.New("no data in the JSON-RPC error")
+
+// UnmarshalData unmarshals the data of the error into v.
+// Returns an error, ErrNoErrorData if the error has no data
+func (j *jsonRPCError) UnmarshalData(v any) error {
+	if len(j.Data) == 0 {
+		return ErrNoErrorData
+	}
+	return json.Unmarshal(j.Data, v)
+}
+
+// ErrorData finds the first JSON-RPC error in the chain of err, e.g. the error object of a response returned
+// by Call, and unmarshals its data into T.
+// Returns the data or an error, ErrNoErrorData if the error has no data
+func ErrorData[T any](err error) (T, error) {
+	var data T
+	jsonRPCError, ok := AsJsonRPCError(err)
+	if !ok {
+		return data, errors.New("not a JSON-RPC error")
+	}
+	err = jsonRPCError.UnmarshalData(&data)
+	return data, err
+}
+
+// AddRawData adds a data object which is JSON already using an existing jsonRPCError object, without marshaling it.
+// Returns a new *jsonRPCError object or an error if the data is not valid JSON.
+// e.g. jsonRPCError, _ = jsonrpc.JsonInternalError.AddRawData(json.RawMessage(`{"retry":true}`))
+func (j jsonRPCError) AddRawData(data json.RawMessage) (*jsonRPCError, error) {
+	if !json.Valid(data) {
+		return nil, errors.New("data is not valid JSON")
+	}
+	return &jsonRPCError{Code: j.Code, Message: j.Message, Data: data, cause: j.cause}, nil
+}
+
+// WithCause attaches the Go error behind the error using an existing jsonRPCError object, e.g. the error of a database
+// call, which errors.Is, errors.As and errors.Unwrap reach. The cause stays on this side and is never sent to the peer.
+// Returns a new *jsonRPCError object.
+// e.g. return nil, jsonrpc.JsonInternalError.WithCause(err)
+func (j jsonRPCError) WithCause(cause error) *jsonRPCError {
+	j.cause = cause
+	return &j
+}
+
+// Unwrap returns the cause attached with WithCause, if any
+func (j *jsonRPCError) Unwrap() error {
+	return j.cause
+}
+
+// AddUpstreamCause adds the upstream error as the "cause" member of the data object using an existing jsonRPCError object.
+// Causes nested in the upstream error are kept up to MaxCauseDepth so that the origin of a multi-hop failure is visible.
+// Returns a new *jsonRPCError object or an error.
+// e.g. jsonRPCError, _ = jsonrpc.JsonInternalError.AddUpstreamCause(upstreamError)
+func (j jsonRPCError) AddUpstreamCause(upstream *jsonRPCError) (*jsonRPCError, error) {
+	if upstream == nil {
+		return nil, errors.New("no upstream JSON-RPC error passed as parameter")
+	}
+
+	cause := &jsonRPCError{
+		Code:    upstream.Code,
+		Message: upstream.Message,
+		Data:    trimCauses(upstream.Data, MaxCauseDepth-1),
+	}
+	return j.AddData(errorCauseData{Cause: cause})
+}
+
+// Causes returns the chain of the upstream errors added with AddUpstreamCause, starting from the closest one
+func (j *jsonRPCError) Causes() []*jsonRPCError {
+	var causes []*jsonRPCError
+	data := j.Data
+	for len(causes) < MaxCauseDepth {
+		var causeData errorCauseData
+		if json.Unmarshal(data, &causeData) != nil || causeData.Cause == nil {
+			break
+		}
+		causes = append(causes, causeData.Cause)
+		data = causeData.Cause.Data
+	}
+	return causes
+}
+
+// trimCauses drops the causes nested in the data beyond depth, keeping the other members of the data
+func trimCauses(data json.RawMessage, depth int) json.RawMessage {
+	var members map[string]json.RawMessage
+	if json.Unmarshal(data, &members) != nil {
+		return data
+	}
+	var cause *jsonRPCError
+	if json.Unmarshal(members["cause"], &cause) != nil || cause == nil {
+		return data
+	}
+
+	if depth <= 0 {
+		delete(members, "cause")
+		if len(members) == 0 {
+			return nil
+		}
+	} else {
+		cause.Data = trimCauses(cause.Data, depth-1)
+		causeRaw, err := json.Marshal(cause)
+		if err != nil {
+			return data
+		}
+		members["cause"] = causeRaw
+	}
+	trimmed, err := json.Marshal(members)
+	if err != nil {
+		return data
+	}
+	return trimmed
+}
@@ -0,0 +1,260 @@
+/*  Copyright 2022  Kosmas Valianos (kosmas.valianos@gmail.com)
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    https://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License. */
+
+package gojsonrpc
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"testing"
+)
+
+func TestJsonRPCError_AddUpstreamCause(t *testing.T) {
+	tests := []struct {
+		name     string
+		upstream *jsonRPCError
+		want     []byte
+		wantErr  bool
+	}{
+		{
+			name:     "Upstream error without data",
+			upstream: &JsonMethodNotFound,
+			want:     []byte(`{"cause":{"code":-32601,"message":"Method not found"}}`),
+		},
+		{
+			name:     "Upstream error with data",
+			upstream: &jsonRPCError{Code: -32000, Message: "Database error", Data: []byte(`{"table":"users"}`)},
+			want:     []byte(`{"cause":{"code":-32000,"message":"Database error","data":{"table":"users"}}}`),
+		},
+		{
+			name:     "Upstream error with cause",
+			upstream: &jsonRPCError{Code: InternalError, Message: "Internal error", Data: []byte(`{"cause":{"code":-32000,"message":"Database error"}}`)},
+			want:     []byte(`{"cause":{"code":-32603,"message":"Internal error","data":{"cause":{"code":-32000,"message":"Database error"}}}}`),
+		},
+		{
+			name:     "Upstream error with cause and other members",
+			upstream: &jsonRPCError{Code: InternalError, Message: "Internal error", Data: []byte(`{"cause":{"code":-32000,"message":"Database error"},"table":"users"}`)},
+			want:     []byte(`{"cause":{"code":-32603,"message":"Internal error","data":{"cause":{"code":-32000,"message":"Database error"},"table":"users"}}}`),
+		},
+		{
+			name:    "No upstream error",
+			wantErr: true,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			jsonRPCError, err := JsonInternalError.AddUpstreamCause(tt.upstream)
+			if (err != nil) != tt.wantErr {
+				t.Errorf("AddUpstreamCause() error = %v, wantErr %v", err, tt.wantErr)
+				return
+			}
+			if tt.wantErr {
+				return
+			}
+
+			if jsonRPCError.Code != InternalError || !bytes.Equal(jsonRPCError.Data, tt.want) {
+				t.Errorf("AddUpstreamCause() = %v, want data %v", jsonRPCError, string(tt.want))
+			}
+		})
+	}
+}
+
+func TestJsonRPCError_Causes(t *testing.T) {
+	// Chain more hops than MaxCauseDepth, the origin being "hop 0"
+	chained := &jsonRPCError{Code: -32000, Message: "hop 0"}
+	for hop := 1; hop <= MaxCauseDepth+2; hop++ {
+		var err error
+		chained, err = jsonRPCError{Code: -32000, Message: fmt.Sprintf("hop %d", hop)}.AddUpstreamCause(chained)
+		if err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	causes := chained.Causes()
+	if len(causes) != MaxCauseDepth {
+		t.Fatalf("Causes() = %v causes, want %v", len(causes), MaxCauseDepth)
+	}
+	for i, cause := range causes {
+		want := fmt.Sprintf("hop %d", MaxCauseDepth+1-i)
+		if cause.Message != want {
+			t.Errorf("Causes()[%d] = %v, want %v", i, cause.Message, want)
+		}
+	}
+
+	// The members of the data next to the cause beyond MaxCauseDepth are kept
+	chained = &jsonRPCError{Code: -32000, Message: "hop 0"}
+	for hop := 1; hop <= MaxCauseDepth+1; hop++ {
+		var err error
+		chained, err = jsonRPCError{Code: -32000, Message: fmt.Sprintf("hop %d", hop)}.AddData(map[string]any{"hop": hop, "cause": chained})
+		if err != nil {
+			t.Fatal(err)
+		}
+	}
+	chained, err := JsonInternalError.AddUpstreamCause(chained)
+	if err != nil {
+		t.Fatal(err)
+	}
+	causes = chained.Causes()
+	last := causes[len(causes)-1]
+	if want := `{"hop":2}`; string(last.Data) != want {
+		t.Errorf("Causes()[%d].Data = %s, want %v", len(causes)-1, last.Data, want)
+	}
+
+	if causes := JsonInternalError.Causes(); len(causes) != 0 {
+		t.Errorf("Causes() = %v, want none", causes)
+	}
+}
+
+func TestJsonRPCError_Is(t *testing.T) {
+	withData, err := JsonMethodNotFound.AddData("subtract")
+	if err != nil {
+		t.Fatal(err)
+	}
+	tests := []struct {
+		name   string
+		err    error
+		target error
+		want   bool
+	}{
+		{name: "Same object", err: &JsonMethodNotFound, target: ErrMethodNotFound, want: true},
+		{name: "With data", err: withData, target: ErrMethodNotFound, want: true},
+		{name: "Wrapped", err: fmt.Errorf("call failed: %w", withData), target: ErrMethodNotFound, want: true},
+		{name: "Other message", err: &jsonRPCError{Code: RequestCancelled, Message: "Cancelled"}, target: ErrRequestCancelled, want: true},
+		{name: "Other code", err: &JsonInvalidRequest, target: ErrMethodNotFound, want: false},
+		{name: "Not a JSON-RPC error", err: errors.New("Method not found"), target: ErrMethodNotFound, want: false},
+		{name: "Nil target", err: &JsonInternalError, target: (*jsonRPCError)(nil), want: false},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := errors.Is(tt.err, tt.target); got != tt.want {
+				t.Errorf("errors.Is() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+
+	jsonRPCError, ok := AsJsonRPCError(fmt.Errorf("call failed: %w", withData))
+	if !ok || jsonRPCError != withData {
+		t.Errorf("AsJsonRPCError() = %v, %v, want %v", jsonRPCError, ok, withData)
+	}
+}
+
+func TestErrorData(t *testing.T) {
+	type balanceData struct {
+		Balance  float64 `json:"balance"`
+		Currency string  `json:"currency"`
+	}
+	withData, err := JsonInvalidMethodParameters.AddData(balanceData{Balance: 42.5, Currency: "EUR"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	tests := []struct {
+		name    string
+		err     error
+		want    balanceData
+		wantErr error
+	}{
+		{name: "Data", err: withData, want: balanceData{Balance: 42.5, Currency: "EUR"}},
+		{name: "Wrapped", err: fmt.Errorf("call failed: %w", withData), want: balanceData{Balance: 42.5, Currency: "EUR"}},
+		{name: "No data", err: &JsonInvalidMethodParameters, wantErr: ErrNoErrorData},
+		{name: "Not a JSON-RPC error", err: errors.New("failed"), wantErr: errors.New("not a JSON-RPC error")},
+		{name: "Other type", err: &jsonRPCError{Code: InvalidMethodParameters, Data: []byte(`"insufficient"`)}, wantErr: errors.New("json: cannot unmarshal string into Go value of type gojsonrpc.balanceData")},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := ErrorData[balanceData](tt.err)
+			if (err == nil) != (tt.wantErr == nil) || (err != nil && err.Error() != tt.wantErr.Error()) {
+				t.Fatalf("ErrorData() error = %v, want %v", err, tt.wantErr)
+			}
+			if got != tt.want {
+				t.Errorf("ErrorData() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+
+	var data map[string]any
+	if err := withData.UnmarshalData(&data); err != nil || data["currency"] != "EUR" {
+		t.Errorf("UnmarshalData() = %v, %v, want the currency EUR", data, err)
+	}
+}
+
+func TestJsonRPCError_AddRawData(t *testing.T) {
+	tests := []struct {
+		name    string
+		data    json.RawMessage
+		want    string
+		wantErr bool
+	}{
+		{name: "Object", data: json.RawMessage(`{"retry": true}`), want: `{"code":-32603,"message":"Internal error","data":{"retry":true}}`},
+		{name: "String", data: json.RawMessage(`"busy"`), want: `{"code":-32603,"message":"Internal error","data":"busy"}`},
+		{name: "Invalid JSON", data: json.RawMessage(`{"retry"`), wantErr: true},
+		{name: "Empty", data: json.RawMessage(``), wantErr: true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			jsonRPCError, err := JsonInternalError.AddRawData(tt.data)
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("AddRawData() error = %v, wantErr %v", err, tt.wantErr)
+			}
+			if tt.wantErr {
+				return
+			}
+			got, err := json.Marshal(jsonRPCError)
+			if err != nil || string(got) != tt.want {
+				t.Errorf("AddRawData() = %s, %v, want %s", got, err, tt.want)
+			}
+		})
+	}
+}
+
+func TestJsonRPCError_WithCause(t *testing.T) {
+	errDatabase := errors.New("connection refused")
+	jsonRPCError := JsonInternalError.WithCause(fmt.Errorf("query failed: %w", errDatabase))
+	if !errors.Is(jsonRPCError, errDatabase) || !errors.Is(jsonRPCError, ErrInternalError) {
+		t.Errorf("errors.Is() = false, want the cause and the code matched")
+	}
+	if JsonInternalError.Unwrap() != nil {
+		t.Error("WithCause() changed the existing error object")
+	}
+
+	// The data keeps the cause while the cause is never sent
+	withData, err := jsonRPCError.AddData("primary")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !errors.Is(withData, errDatabase) {
+		t.Errorf("AddData() dropped the cause %v", errDatabase)
+	}
+	responseRaw, err := NewErrorResponse(1, withData)
+	want := `{"jsonrpc":"2.0","error":{"code":-32603,"message":"Internal error","data":"primary"},"id":1}` + "\n"
+	if err != nil || string(responseRaw) != want {
+		t.Errorf("NewErrorResponse() = %s, %v, want %s", responseRaw, err, want)
+	}
+
+	mux := NewMux()
+	err = HandleFunc(mux, "lookup", func(ctx context.Context, params any) (any, error) {
+		return nil, JsonInvalidMethodParameters.WithCause(errDatabase)
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	got := mux.Serve(context.Background(), []byte(`{"jsonrpc":"2.0","method":"lookup","id":1}`))
+	want = `{"jsonrpc":"2.0","error":{"code":-32602,"message":"Invalid method parameters"},"id":1}` + "\n"
+	if string(got) != want {
+		t.Errorf("Serve() = %s, want %s", got, want)
+	}
+}
@@ -0,0 +1,124 @@
+/*  Copyright 2022  Kosmas Valianos (kosmas.valianos@gmail.com)
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    https://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License. */
+
+package gojsonrpc
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"sync"
+)
+
+// FailoverTransport is a Transport over several endpoints, e.g. the replicas of a server, which uses one endpoint
+// at a time and fails over to the next one when a message cannot be carried. The calls which failed are replayed
+// on the next endpoint only if their methods are idempotent, since the failed endpoint may have served them
+type FailoverTransport struct {
+	transports []Transport
+	idempotent func(method string) bool
+
+	mu      sync.Mutex
+	current int
+}
+
+// NewFailoverTransport creates a FailoverTransport over the transports of the endpoints in the order to use them.
+// idempotent reports whether a method may be called more than once; nil means none is.
+// Returns a *FailoverTransport object or an error if there are no transports
+func NewFailoverTransport(transports []Transport, idempotent func(method string) bool) (*FailoverTransport, error) {
+	if len(transports) == 0 {
+		return nil, errors.New("no transports passed as parameter")
+	}
+	for _, transport := range transports {
+		if transport == nil {
+			return nil, errors.New("transport must not be nil")
+		}
+	}
+	return &FailoverTransport{transports: transports, idempotent: idempotent}, nil
+}
+
+// RoundTrip sends a request or a batch through the current endpoint, failing over to the next ones.
+// Returns the raw bytes of the response or the error of the last endpoint tried
+func (t *FailoverTransport) RoundTrip(ctx context.Context, requestRaw []byte) ([]byte, error) {
+	var responseRaw []byte
+	err := t.failover(ctx, requestRaw, func(transport Transport) error {
+		var err error
+		responseRaw, err = transport.RoundTrip(ctx, requestRaw)
+		return err
+	})
+	return responseRaw, err
+}
+
+// Send sends a notification through the current endpoint, failing over to the next ones
+func (t *FailoverTransport) Send(ctx context.Context, notificationRaw []byte) error {
+	return t.failover(ctx, notificationRaw, func(transport Transport) error {
+		return transport.Send(ctx, notificationRaw)
+	})
+}
+
+// Current returns the index of the endpoint in use
+func (t *FailoverTransport) Current() int {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	return t.current
+}
+
+// failover carries a message through the current endpoint and on failure moves on to the next one,
+// on which the message is replayed if it is idempotent, until every endpoint was tried once
+func (t *FailoverTransport) failover(ctx context.Context, messageRaw []byte, carry func(transport Transport) error) error {
+	current := t.Current()
+	var err error
+	for tried := 0; tried < len(t.transports); tried++ {
+		err = carry(t.transports[current])
+		if err == nil || ctx.Err() != nil {
+			return err
+		}
+		current = t.next(current)
+		if !t.replayable(messageRaw) {
+			return err
+		}
+	}
+	return err
+}
+
+// next moves on from the failed endpoint unless another call already did.
+// Returns the index of the endpoint in use
+func (t *FailoverTransport) next(failed int) int {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	if t.current == failed {
+		t.current = (failed + 1) % len(t.transports)
+	}
+	return t.current
+}
+
+// replayable reports whether every method of the message or of the batch is idempotent
+func (t *FailoverTransport) replayable(messageRaw []byte) bool {
+	if t.idempotent == nil {
+		return false
+	}
+	messagesRaw, err := splitBatch(messageRaw)
+	if err != nil {
+		return false
+	}
+	for _, messageRaw := range messagesRaw {
+		var envelope struct {
+			Method string `json:"method"`
+		}
+		err := json.Unmarshal(messageRaw, &envelope)
+		if err != nil || !t.idempotent(envelope.Method) {
+			return false
+		}
+	}
+	return true
+}
@@ -0,0 +1,101 @@
+/*  Copyright 2022  Kosmas Valianos (kosmas.valianos@gmail.com)
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    https://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License. */
+
+package gojsonrpc
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"sync/atomic"
+	"testing"
+)
+
+// downTransport is a Transport of an endpoint which is down
+type downTransport struct {
+	attempts atomic.Int64
+}
+
+func (t *downTransport) RoundTrip(ctx context.Context, requestRaw []byte) ([]byte, error) {
+	t.attempts.Add(1)
+	return nil, errors.New("connection refused")
+}
+
+func (t *downTransport) Send(ctx context.Context, notificationRaw []byte) error {
+	t.attempts.Add(1)
+	return errors.New("connection refused")
+}
+
+func TestFailoverTransport(t *testing.T) {
+	idempotent := func(method string) bool {
+		return method == "subtract"
+	}
+
+	t.Run("Replayed idempotent call", func(t *testing.T) {
+		down := &downTransport{}
+		transport, err := NewFailoverTransport([]Transport{down, &muxTransport{mux: newTestMux(t)}}, idempotent)
+		if err != nil {
+			t.Fatal(err)
+		}
+		client := NewClient(transport)
+		for i := 0; i < 2; i++ {
+			result, err := Call[int](context.Background(), client, "subtract", []int{42, 23})
+			if err != nil || result != 19 {
+				t.Errorf("Call() = %v, %v, want 19", result, err)
+			}
+		}
+		if got := down.attempts.Load(); got != 1 {
+			t.Errorf("attempts on the endpoint which is down = %v, want 1", got)
+		}
+		if got := transport.Current(); got != 1 {
+			t.Errorf("Current() = %v, want 1", got)
+		}
+	})
+
+	t.Run("Not idempotent call", func(t *testing.T) {
+		transport, err := NewFailoverTransport([]Transport{&downTransport{}, &muxTransport{mux: newTestMux(t)}}, idempotent)
+		if err != nil {
+			t.Fatal(err)
+		}
+		client := NewClient(transport)
+		_, err = Call[json.RawMessage](context.Background(), client, "raw", []int{1})
+		if err == nil {
+			t.Error("Call() of a not idempotent method was replayed")
+		}
+		// The next calls use the next endpoint
+		result, err := Call[json.RawMessage](context.Background(), client, "raw", []int{1})
+		if err != nil || string(result) != "[1]" {
+			t.Errorf("Call() = %v, %v, want [1]", string(result), err)
+		}
+	})
+
+	t.Run("Every endpoint down", func(t *testing.T) {
+		first, second := &downTransport{}, &downTransport{}
+		transport, err := NewFailoverTransport([]Transport{first, second}, idempotent)
+		if err != nil {
+			t.Fatal(err)
+		}
+		_, err = Call[int](context.Background(), NewClient(transport), "subtract", []int{42, 23})
+		if err == nil {
+			t.Error("Call() succeeded with every endpoint down")
+		}
+		if first.attempts.Load() != 1 || second.attempts.Load() != 1 {
+			t.Errorf("attempts = %v and %v, want 1 on each endpoint", first.attempts.Load(), second.attempts.Load())
+		}
+	})
+
+	if _, err := NewFailoverTransport(nil, idempotent); err == nil {
+		t.Error("NewFailoverTransport() without transports succeeded")
+	}
+}
@@ -0,0 +1,250 @@
+/*  Copyright 2022  Kosmas Valianos (kosmas.valianos@gmail.com)
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    https://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License. */
+
+package gojsonrpc
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"reflect"
+	"strings"
+	"unicode"
+)
+
+// FieldNaming translates the Go name of a struct field without a json tag to its name on the wire
+type FieldNaming func(name string) string
+
+// SnakeCase is the FieldNaming of e.g. Python peers, UserID becomes user_id
+func SnakeCase(name string) string {
+	return strings.Join(splitWords(name), "_")
+}
+
+// CamelCase is the FieldNaming of e.g. JavaScript peers, UserID becomes userId
+func CamelCase(name string) string {
+	words := splitWords(name)
+	for i := 1; i < len(words); i++ {
+		words[i] = strings.ToUpper(words[i][:1]) + words[i][1:]
+	}
+	return strings.Join(words, "")
+}
+
+// splitWords splits a Go identifier into lowercase words, keeping acronyms such as HTTP or ID together
+func splitWords(name string) []string {
+	var words []string
+	runes := []rune(name)
+	start := 0
+	for i := 1; i <= len(runes); i++ {
+		if i < len(runes) && !isWordBoundary(runes, i) {
+			continue
+		}
+		if word := strings.Trim(string(runes[start:i]), "_"); word != "" {
+			words = append(words, strings.ToLower(word))
+		}
+		start = i
+	}
+	return words
+}
+
+func isWordBoundary(runes []rune, i int) bool {
+	previous, current := runes[i-1], runes[i]
+	switch {
+	case current == '_' || previous == '_':
+		return true
+	case unicode.IsUpper(current) && (unicode.IsLower(previous) || unicode.IsDigit(previous)):
+		return true
+	case unicode.IsUpper(current) && unicode.IsUpper(previous):
+		// The last upper case letter of an acronym starts the next word, e.g. HTTPServer
+		return i+1 < len(runes) && unicode.IsLower(runes[i+1])
+	default:
+		return false
+	}
+}
+
+// WithFieldNaming sets the FieldNaming of the params and of the results of the handlers registered with HandleFunc,
+// so that the struct fields without a json tag follow the convention of the peers on the wire.
+// The names of the fields with a json tag are left as they are
+func WithFieldNaming(naming FieldNaming) MuxOption {
+	return func(m *Mux) {
+		m.fieldNaming = naming
+	}
+}
+
+func fieldNamingFromContext(ctx context.Context) FieldNaming {
+	naming, _ := ctx.Value(fieldNamingContextKey).(FieldNaming)
+	return naming
+}
+
+// jsonField is a member of the JSON object marshaled from a struct field
+type jsonField struct {
+	name    string // The name on the wire
+	key     string // The name encoding/json marshals
+	typ     reflect.Type
+	options string
+}
+
+// jsonFields returns the members of the JSON object marshaled from the struct type following the rules of encoding/json.
+// The names of the fields without a json tag are translated by naming, if any
+func jsonFields(t reflect.Type, naming FieldNaming) []jsonField {
+	var fields []jsonField
+	for i := 0; i < t.NumField(); i++ {
+		field := t.Field(i)
+		tag := field.Tag.Get("json")
+		if tag == "-" {
+			continue
+		}
+		name, options, _ := strings.Cut(tag, ",")
+
+		fieldType := indirectType(field.Type)
+		if field.Anonymous && name == "" && fieldType.Kind() == reflect.Struct {
+			// The fields of embedded structs are promoted
+			fields = append(fields, jsonFields(fieldType, naming)...)
+			continue
+		}
+		if !field.IsExported() {
+			continue
+		}
+
+		key := name
+		if key == "" {
+			key = field.Name
+			name = field.Name
+			if naming != nil {
+				name = naming(field.Name)
+			}
+		}
+		fields = append(fields, jsonField{name: name, key: key, typ: field.Type, options: options})
+	}
+	return fields
+}
+
+// translateFieldNames renames the members of the JSON objects in raw marshaled from or unmarshaled into t.
+// toWire translates the names encoding/json marshals to the names on the wire, otherwise the other way around
+func translateFieldNames(raw json.RawMessage, t reflect.Type, naming FieldNaming, toWire bool) (json.RawMessage, error) {
+	t = indirectType(t)
+	if t == timeType || hasCustomMarshaling(t) {
+		return raw, nil
+	}
+
+	switch kind := t.Kind(); {
+	case kind == reflect.Struct:
+		renames := make(map[string]jsonField)
+		for _, field := range jsonFields(t, naming) {
+			if toWire {
+				renames[field.key] = field
+			} else {
+				renames[field.name] = field
+			}
+		}
+		return translateObject(raw, func(key string) (string, reflect.Type) {
+			field, ok := renames[key]
+			if !ok {
+				return key, nil
+			}
+			if toWire {
+				return field.name, field.typ
+			}
+			return field.key, field.typ
+		}, naming, toWire)
+	case kind == reflect.Map:
+		return translateObject(raw, func(key string) (string, reflect.Type) {
+			return key, t.Elem()
+		}, naming, toWire)
+	case (kind == reflect.Slice || kind == reflect.Array) && t.Elem().Kind() != reflect.Uint8:
+		return translateArray(raw, t.Elem(), naming, toWire)
+	default:
+		return raw, nil
+	}
+}
+
+// translateObject renames the members of the JSON object in raw, preserving their order.
+// Anything else than an object is returned as it is
+func translateObject(raw json.RawMessage, rename func(key string) (string, reflect.Type), naming FieldNaming,
+	toWire bool) (json.RawMessage, error) {
+	decoder := json.NewDecoder(bytes.NewReader(raw))
+	token, err := decoder.Token()
+	if err != nil {
+		return nil, err
+	}
+	if token != json.Delim('{') {
+		return raw, nil
+	}
+
+	var buffer bytes.Buffer
+	buffer.WriteByte('{')
+	for decoder.More() {
+		token, err := decoder.Token()
+		if err != nil {
+			return nil, err
+		}
+		var value json.RawMessage
+		err = decoder.Decode(&value)
+		if err != nil {
+			return nil, err
+		}
+
+		key, valueType := rename(token.(string))
+		if valueType != nil {
+			value, err = translateFieldNames(value, valueType, naming, toWire)
+			if err != nil {
+				return nil, err
+			}
+		}
+		keyRaw, err := json.Marshal(key)
+		if err != nil {
+			return nil, err
+		}
+		if buffer.Len() > 1 {
+			buffer.WriteByte(',')
+		}
+		buffer.Write(keyRaw)
+		buffer.WriteByte(':')
+		buffer.Write(value)
+	}
+	buffer.WriteByte('}')
+	return buffer.Bytes(), nil
+}
+
+// translateArray renames the members of the JSON objects of the elements of the JSON array in raw.
+// Anything else than an array is returned as it is
+func translateArray(raw json.RawMessage, elemType reflect.Type, naming FieldNaming, toWire bool) (json.RawMessage, error) {
+	decoder := json.NewDecoder(bytes.NewReader(raw))
+	token, err := decoder.Token()
+	if err != nil {
+		return nil, err
+	}
+	if token != json.Delim('[') {
+		return raw, nil
+	}
+
+	var buffer bytes.Buffer
+	buffer.WriteByte('[')
+	for decoder.More() {
+		var value json.RawMessage
+		err = decoder.Decode(&value)
+		if err != nil {
+			return nil, err
+		}
+		value, err = translateFieldNames(value, elemType, naming, toWire)
+		if err != nil {
+			return nil, err
+		}
+		if buffer.Len() > 1 {
+			buffer.WriteByte(',')
+		}
+		buffer.Write(value)
+	}
+	buffer.WriteByte(']')
+	return buffer.Bytes(), nil
+}
@@ -0,0 +1,102 @@
+/*  Copyright 2022  Kosmas Valianos (kosmas.valianos@gmail.com)
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    https://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License. */
+
+package gojsonrpc
+
+import (
+	"bytes"
+	"context"
+	"testing"
+)
+
+func TestFieldNaming(t *testing.T) {
+	tests := []struct {
+		name      string
+		snakeCase string
+		camelCase string
+	}{
+		{name: "Name", snakeCase: "name", camelCase: "name"},
+		{name: "FirstName", snakeCase: "first_name", camelCase: "firstName"},
+		{name: "UserID", snakeCase: "user_id", camelCase: "userId"},
+		{name: "HTTPServer", snakeCase: "http_server", camelCase: "httpServer"},
+		{name: "Address2Line", snakeCase: "address2_line", camelCase: "address2Line"},
+		{name: "Already_Snake", snakeCase: "already_snake", camelCase: "alreadySnake"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := SnakeCase(tt.name); got != tt.snakeCase {
+				t.Errorf("SnakeCase() = %v, want %v", got, tt.snakeCase)
+			}
+			if got := CamelCase(tt.name); got != tt.camelCase {
+				t.Errorf("CamelCase() = %v, want %v", got, tt.camelCase)
+			}
+		})
+	}
+}
+
+type testOrderItem struct {
+	ProductID int
+	UnitPrice float64
+}
+
+type testOrder struct {
+	OrderID    int
+	CustomerID string `json:"customer"`
+	Items      []testOrderItem
+	Notes      map[string]testOrderItem
+}
+
+func TestWithFieldNaming(t *testing.T) {
+	tests := []struct {
+		name     string
+		naming   FieldNaming
+		rawBytes []byte
+		want     []byte
+	}{
+		{
+			name:     "Snake case",
+			naming:   SnakeCase,
+			rawBytes: []byte(`{"jsonrpc": "2.0", "method": "order.copy", "params": {"order_id": 1, "customer": "c1", "items": [{"product_id": 7, "unit_price": 2.5}], "notes": {"gift": {"product_id": 8, "unit_price": 0}}}, "id": 1}`),
+			want:     []byte(`{"jsonrpc":"2.0","result":{"order_id":1,"customer":"c1","items":[{"product_id":7,"unit_price":2.5}],"notes":{"gift":{"product_id":8,"unit_price":0}}},"id":1}` + "\n"),
+		},
+		{
+			name:     "Camel case",
+			naming:   CamelCase,
+			rawBytes: []byte(`{"jsonrpc": "2.0", "method": "order.copy", "params": {"orderId": 1, "customer": "c1", "items": [{"productId": 7, "unitPrice": 2.5}]}, "id": 1}`),
+			want:     []byte(`{"jsonrpc":"2.0","result":{"orderId":1,"customer":"c1","items":[{"productId":7,"unitPrice":2.5}],"notes":null},"id":1}` + "\n"),
+		},
+		{
+			name:     "No naming",
+			rawBytes: []byte(`{"jsonrpc": "2.0", "method": "order.copy", "params": {"OrderID": 1, "customer": "c1", "Items": null}, "id": 1}`),
+			want:     []byte(`{"jsonrpc":"2.0","result":{"OrderID":1,"customer":"c1","Items":null,"Notes":null},"id":1}` + "\n"),
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			mux := NewMux(WithFieldNaming(tt.naming))
+			err := HandleFunc(mux, "order.copy", func(ctx context.Context, params testOrder) (testOrder, error) {
+				return params, nil
+			})
+			if err != nil {
+				t.Fatal(err)
+			}
+			jsonRPCResponseRaw := mux.Serve(context.Background(), tt.rawBytes)
+			if !bytes.Equal(jsonRPCResponseRaw, tt.want) {
+				t.Errorf("Serve() = %v, want %v", string(jsonRPCResponseRaw), string(tt.want))
+			}
+		})
+	}
+}
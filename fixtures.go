@@ -0,0 +1,154 @@
+/*  Copyright 2022  Kosmas Valianos (kosmas.valianos@gmail.com)
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    https://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License. */
+
+package gojsonrpc
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+)
+
+// Example is an example call of a method to generate a Fixture from
+type Example struct {
+	// Name identifies the fixture and names its file
+	Name   string
+	Method string
+	Params any
+	// ID of the request of type int, float64 or string. A nil ID makes a notification
+	ID any
+}
+
+// Fixture is a request or notification and the exact response of a Mux to it,
+// so that clients in other languages can be tested against the bytes served
+type Fixture struct {
+	Name    string `json:"name"`
+	Request string `json:"request"`
+	// Response is empty for notifications
+	Response string `json:"response,omitempty"`
+}
+
+// GenerateFixtures serves the examples with the mux and records the requests and the responses.
+// Returns a []Fixture or an error if an example cannot be marshaled
+func GenerateFixtures(ctx context.Context, mux *Mux, examples []Example) ([]Fixture, error) {
+	fixtures := make([]Fixture, 0, len(examples))
+	for _, example := range examples {
+		if example.Name == "" {
+			return nil, fmt.Errorf("example of method %v must have a name", example.Method)
+		}
+		requestRaw, err := newExampleRequest(example)
+		if err != nil {
+			return nil, fmt.Errorf("example %v: %w", example.Name, err)
+		}
+		fixtures = append(fixtures, Fixture{
+			Name:     example.Name,
+			Request:  string(requestRaw),
+			Response: string(mux.Serve(ctx, requestRaw)),
+		})
+	}
+	return fixtures, nil
+}
+
+func newExampleRequest(example Example) ([]byte, error) {
+	if example.ID == nil {
+		return NewNotification(example.Method, example.Params)
+	}
+	return newRequestWithID(example.Method, example.Params, example.ID)
+}
+
+// WriteFixtures writes each fixture as an indented JSON file named after it in dir.
+// Returns an error if a file cannot be written or, before writing any, if the names of two fixtures map to the same
+// file name, case insensitively as on some file systems
+func WriteFixtures(dir string, fixtures []Fixture) error {
+	fileNames := make(map[string]string, len(fixtures))
+	for _, fixture := range fixtures {
+		fileName := strings.ToLower(fixtureFileName(fixture.Name))
+		if name, ok := fileNames[fileName]; ok {
+			return fmt.Errorf("fixtures %q and %q have the same file name %v", name, fixture.Name, fixtureFileName(fixture.Name))
+		}
+		fileNames[fileName] = fixture.Name
+	}
+
+	err := os.MkdirAll(dir, 0o755)
+	if err != nil {
+		return err
+	}
+	for _, fixture := range fixtures {
+		fixtureRaw, err := json.MarshalIndent(fixture, "", "  ")
+		if err != nil {
+			return err
+		}
+		err = os.WriteFile(filepath.Join(dir, fixtureFileName(fixture.Name)), append(fixtureRaw, '\n'), 0o644)
+		if err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// ReadFixtures reads the fixtures of the JSON files in dir.
+// Returns a []Fixture or an error if a file cannot be read or parsed
+func ReadFixtures(dir string) ([]Fixture, error) {
+	entries, err := os.ReadDir(dir)
+	if err != nil {
+		return nil, err
+	}
+	var fixtures []Fixture
+	for _, entry := range entries {
+		if entry.IsDir() || filepath.Ext(entry.Name()) != ".json" {
+			continue
+		}
+		fixtureRaw, err := os.ReadFile(filepath.Join(dir, entry.Name()))
+		if err != nil {
+			return nil, err
+		}
+		var fixture Fixture
+		err = json.Unmarshal(fixtureRaw, &fixture)
+		if err != nil {
+			return nil, fmt.Errorf("fixture %v: %w", entry.Name(), err)
+		}
+		fixtures = append(fixtures, fixture)
+	}
+	return fixtures, nil
+}
+
+// VerifyFixtures serves the requests of the fixtures with the mux and compares the responses byte for byte.
+// Returns an error listing the fixtures whose responses differ
+func VerifyFixtures(ctx context.Context, mux *Mux, fixtures []Fixture) error {
+	var mismatches []string
+	for _, fixture := range fixtures {
+		responseRaw := mux.Serve(ctx, []byte(fixture.Request))
+		if !bytes.Equal(responseRaw, []byte(fixture.Response)) {
+			mismatches = append(mismatches, fmt.Sprintf("%v: response %q, want %q", fixture.Name, responseRaw, fixture.Response))
+		}
+	}
+	if len(mismatches) > 0 {
+		return fmt.Errorf("fixtures do not match: %v", strings.Join(mismatches, "; "))
+	}
+	return nil
+}
+
+// fixtureFileName returns the name of the file of a fixture keeping only characters safe in file names
+func fixtureFileName(name string) string {
+	return strings.Map(func(r rune) rune {
+		if r >= 'a' && r <= 'z' || r >= 'A' && r <= 'Z' || r >= '0' && r <= '9' || r == '.' || r == '_' || r == '-' {
+			return r
+		}
+		return '_'
+	}, name) + ".json"
+}
@@ -0,0 +1,83 @@
+/*  Copyright 2022  Kosmas Valianos (kosmas.valianos@gmail.com)
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    https://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License. */
+
+package gojsonrpc
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"reflect"
+	"testing"
+)
+
+func TestFixtures(t *testing.T) {
+	examples := []Example{
+		{Name: "subtract", Method: "subtract", Params: []int{42, 23}, ID: 1},
+		{Name: "subtract/string id", Method: "subtract", Params: []int{23, 42}, ID: "abc"},
+		{Name: "database error", Method: "database", ID: 2.5},
+		{Name: "notification", Method: "subtract", Params: []int{42, 23}},
+	}
+	want := []Fixture{
+		{Name: "subtract", Request: `{"jsonrpc":"2.0","method":"subtract","params":[42,23],"id":1}` + "\n", Response: `{"jsonrpc":"2.0","result":19,"id":1}` + "\n"},
+		{Name: "subtract/string id", Request: `{"jsonrpc":"2.0","method":"subtract","params":[23,42],"id":"abc"}` + "\n", Response: `{"jsonrpc":"2.0","result":-19,"id":"abc"}` + "\n"},
+		{Name: "database error", Request: `{"jsonrpc":"2.0","method":"database","id":2.5}` + "\n", Response: `{"jsonrpc":"2.0","error":{"code":-32602,"message":"Invalid method parameters"},"id":2.5}` + "\n"},
+		{Name: "notification", Request: `{"jsonrpc":"2.0","method":"subtract","params":[42,23]}` + "\n"},
+	}
+
+	mux := newTestMux(t)
+	fixtures, err := GenerateFixtures(context.Background(), mux, examples)
+	if err != nil {
+		t.Fatalf("GenerateFixtures() error = %v", err)
+	}
+	if !reflect.DeepEqual(fixtures, want) {
+		t.Fatalf("GenerateFixtures() = %v, want %v", fixtures, want)
+	}
+
+	dir := filepath.Join(t.TempDir(), "fixtures")
+	err = WriteFixtures(dir, fixtures)
+	if err != nil {
+		t.Fatalf("WriteFixtures() error = %v", err)
+	}
+	if _, err := os.Stat(filepath.Join(dir, "subtract_string_id.json")); err != nil {
+		t.Errorf("WriteFixtures() did not write the file of a fixture: %v", err)
+	}
+	read, err := ReadFixtures(dir)
+	if err != nil {
+		t.Fatalf("ReadFixtures() error = %v", err)
+	}
+	if len(read) != len(fixtures) {
+		t.Fatalf("ReadFixtures() = %v, want %v", read, fixtures)
+	}
+
+	err = VerifyFixtures(context.Background(), mux, read)
+	if err != nil {
+		t.Errorf("VerifyFixtures() error = %v", err)
+	}
+	read[0].Response = `{"jsonrpc":"2.0","result":20,"id":1}` + "\n"
+	err = VerifyFixtures(context.Background(), mux, read)
+	if err == nil {
+		t.Error("VerifyFixtures() of a changed response succeeded")
+	}
+
+	err = WriteFixtures(t.TempDir(), []Fixture{{Name: "subtract string id"}, {Name: "subtract/string/id"}})
+	if err == nil {
+		t.Error("WriteFixtures() of fixtures with the same file name succeeded")
+	}
+
+	_, err = GenerateFixtures(context.Background(), mux, []Example{{Name: "bool id", Method: "subtract", ID: true}})
+	if err == nil {
+		t.Error("GenerateFixtures() of an example with an invalid ID succeeded")
+	}
+}
@@ -0,0 +1,150 @@
+/*  Copyright 2022  Kosmas Valianos (kosmas.valianos@gmail.com)
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    https://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License. */
+
+package gojsonrpc
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"log/slog"
+	"strconv"
+	"strings"
+	"sync/atomic"
+	"unicode/utf8"
+)
+
+// maxLoggedValueSize is the size beyond which the params, the results and the error data are truncated in the summaries
+const maxLoggedValueSize = 128
+
+var logRedaction atomic.Bool
+
+// SetLogRedaction makes the summaries of the messages, their String and LogValue, show the size of the params,
+// the results and the error data instead of their content, which may be confidential. The method, the id
+// and the code and message of the errors are always shown
+func SetLogRedaction(redact bool) {
+	logRedaction.Store(redact)
+}
+
+// String summarizes the request on one line, e.g. request method="subtract" id=1 params=[42,23]
+func (r *request) String() string {
+	var summary strings.Builder
+	fmt.Fprintf(&summary, "request method=%q id=%v", r.Method, loggedID(r.ID))
+	if len(r.Params) > 0 {
+		fmt.Fprintf(&summary, " params=%v", loggedValue(r.Params))
+	}
+	return summary.String()
+}
+
+// LogValue summarizes the request for log/slog
+func (r *request) LogValue() slog.Value {
+	attrs := []slog.Attr{slog.String("method", r.Method), slog.Any("id", r.ID)}
+	if len(r.Params) > 0 {
+		attrs = append(attrs, slog.String("params", loggedValue(r.Params)))
+	}
+	return slog.GroupValue(attrs...)
+}
+
+// String summarizes the notification on one line, e.g. notification method="update" params=[1,2,3]
+func (n *notification) String() string {
+	var summary strings.Builder
+	fmt.Fprintf(&summary, "notification method=%q", n.Method)
+	if len(n.Params) > 0 {
+		fmt.Fprintf(&summary, " params=%v", loggedValue(n.Params))
+	}
+	return summary.String()
+}
+
+// LogValue summarizes the notification for log/slog
+func (n *notification) LogValue() slog.Value {
+	attrs := []slog.Attr{slog.String("method", n.Method)}
+	if len(n.Params) > 0 {
+		attrs = append(attrs, slog.String("params", loggedValue(n.Params)))
+	}
+	return slog.GroupValue(attrs...)
+}
+
+// String summarizes the response on one line, e.g. response id=1 result=19
+func (r *response) String() string {
+	if r.Error != nil {
+		return fmt.Sprintf("response id=%v error={%v}", loggedID(r.ID), r.Error.String())
+	}
+	return fmt.Sprintf("response id=%v result=%v", loggedID(r.ID), loggedValue(r.Result))
+}
+
+// LogValue summarizes the response for log/slog
+func (r *response) LogValue() slog.Value {
+	if r.Error != nil {
+		return slog.GroupValue(slog.Any("id", r.ID), slog.Any("error", r.Error))
+	}
+	return slog.GroupValue(slog.Any("id", r.ID), slog.String("result", loggedValue(r.Result)))
+}
+
+// String summarizes the error on one line, e.g. code=-32601 message="Method not found".
+// Unlike Error it shows the data as the other summaries do
+func (j *jsonRPCError) String() string {
+	var summary strings.Builder
+	fmt.Fprintf(&summary, "code=%v message=%q", j.Code, j.Message)
+	if len(j.Data) > 0 {
+		fmt.Fprintf(&summary, " data=%v", loggedValue(j.Data))
+	}
+	if j.cause != nil {
+		fmt.Fprintf(&summary, " cause=%q", j.cause.Error())
+	}
+	return summary.String()
+}
+
+// LogValue summarizes the error for log/slog
+func (j *jsonRPCError) LogValue() slog.Value {
+	attrs := []slog.Attr{slog.Int("code", j.Code), slog.String("message", j.Message)}
+	if len(j.Data) > 0 {
+		attrs = append(attrs, slog.String("data", loggedValue(j.Data)))
+	}
+	if j.cause != nil {
+		attrs = append(attrs, slog.String("cause", j.cause.Error()))
+	}
+	return slog.GroupValue(attrs...)
+}
+
+// loggedID formats an id as in JSON
+func loggedID(id any) string {
+	switch id := id.(type) {
+	case nil:
+		return "null"
+	case string:
+		return strconv.Quote(id)
+	default:
+		return fmt.Sprint(id)
+	}
+}
+
+// loggedValue formats the params, a result or error data compactly, truncated beyond maxLoggedValueSize
+// or redacted if so set
+func loggedValue(valueRaw json.RawMessage) string {
+	if logRedaction.Load() {
+		return fmt.Sprintf("[redacted %v bytes]", len(valueRaw))
+	}
+	var compacted bytes.Buffer
+	if json.Compact(&compacted, valueRaw) == nil {
+		valueRaw = compacted.Bytes()
+	}
+	if len(valueRaw) <= maxLoggedValueSize {
+		return string(valueRaw)
+	}
+	end := maxLoggedValueSize
+	for end > 0 && !utf8.RuneStart(valueRaw[end]) {
+		end--
+	}
+	return fmt.Sprintf("%s…(%v bytes)", valueRaw[:end], len(valueRaw))
+}
@@ -0,0 +1,110 @@
+/*  Copyright 2022  Kosmas Valianos (kosmas.valianos@gmail.com)
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    https://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License. */
+
+package gojsonrpc
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"log/slog"
+	"strings"
+	"testing"
+)
+
+func TestFormat_String(t *testing.T) {
+	tests := []struct {
+		name   string
+		value  fmt.Stringer
+		redact bool
+		want   string
+	}{
+		{
+			name:  "Request",
+			value: &request{Method: "subtract", Params: json.RawMessage(`[42, 23]`), ID: float64(1)},
+			want:  `request method="subtract" id=1 params=[42,23]`,
+		},
+		{
+			name:  "Request with string id and no params",
+			value: &request{Method: "ping", ID: "abc"},
+			want:  `request method="ping" id="abc"`,
+		},
+		{
+			name:   "Request redacted",
+			value:  &request{Method: "login", Params: json.RawMessage(`{"password":"secret"}`), ID: float64(2)},
+			redact: true,
+			want:   `request method="login" id=2 params=[redacted 21 bytes]`,
+		},
+		{
+			name:  "Notification",
+			value: &notification{Method: "update", Params: json.RawMessage(`[1,2,3]`)},
+			want:  `notification method="update" params=[1,2,3]`,
+		},
+		{
+			name:  "Result response",
+			value: &response{Result: json.RawMessage(`19`), ID: float64(1)},
+			want:  `response id=1 result=19`,
+		},
+		{
+			name:  "Error response with null id",
+			value: &response{Error: &JsonParseError},
+			want:  `response id=null error={code=-32700 message="Parse error"}`,
+		},
+		{
+			name:   "Error with data and cause redacted",
+			value:  &jsonRPCError{Code: -32603, Message: "Internal error", Data: json.RawMessage(`"table users"`), cause: errors.New("disk full")},
+			redact: true,
+			want:   `code=-32603 message="Internal error" data=[redacted 13 bytes] cause="disk full"`,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			SetLogRedaction(tt.redact)
+			defer SetLogRedaction(false)
+			if got := tt.value.String(); got != tt.want {
+				t.Errorf("String() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestFormat_StringTruncated(t *testing.T) {
+	params := json.RawMessage(`["` + strings.Repeat("é", 100) + `"]`)
+	got := (&notification{Method: "update", Params: params}).String()
+	want := fmt.Sprintf(`notification method="update" params=["%v…(%v bytes)`, strings.Repeat("é", 63), len(params))
+	if got != want {
+		t.Errorf("String() = %v, want %v", got, want)
+	}
+}
+
+func TestFormat_LogValue(t *testing.T) {
+	var output bytes.Buffer
+	logger := slog.New(slog.NewTextHandler(&output, &slog.HandlerOptions{
+		ReplaceAttr: func(groups []string, a slog.Attr) slog.Attr {
+			if len(groups) == 0 && (a.Key == slog.TimeKey || a.Key == slog.LevelKey) {
+				return slog.Attr{}
+			}
+			return a
+		},
+	}))
+	logger.Info("received",
+		"request", &request{Method: "subtract", Params: json.RawMessage(`[42,23]`), ID: float64(1)},
+		"response", &response{Error: &jsonRPCError{Code: -32601, Message: "Method not found", Data: json.RawMessage(`"subtract"`)}, ID: "1"},
+	)
+	want := `msg=received request.method=subtract request.id=1 request.params=[42,23] response.id=1 response.error.code=-32601 response.error.message="Method not found" response.error.data="\"subtract\""` + "\n"
+	if got := output.String(); got != want {
+		t.Errorf("LogValue() = %v, want %v", got, want)
+	}
+}
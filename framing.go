@@ -0,0 +1,239 @@
+/*  Copyright 2022  Kosmas Valianos (kosmas.valianos@gmail.com)
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    https://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License. */
+
+package gojsonrpc
+
+import (
+	"bufio"
+	"bytes"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/textproto"
+	"strconv"
+)
+
+// Framing delimits the messages of a stream
+type Framing interface {
+	// ReadMessage reads the next message of up to maxMessageSize bytes. A larger message fails with ErrMessageTooLarge
+	// and should be skipped, so that the next call reads the following one
+	ReadMessage(reader *bufio.Reader, maxMessageSize int) ([]byte, error)
+	// WriteMessage writes a message with its delimitation
+	WriteMessage(writer io.Writer, messageRaw []byte) error
+}
+
+var (
+	// NewlineFraming terminates each message with a newline, as emitted by the constructors. It is the default Framing
+	NewlineFraming Framing = newlineFraming{}
+	// ContentLengthFraming precedes each message with a "Content-Length: N\r\n\r\n" header,
+	// as in the Language Server Protocol and the Debug Adapter Protocol
+	ContentLengthFraming Framing = contentLengthFraming{}
+	// ConcatenatedFraming delimits each message by its own JSON value, the messages being concatenated or separated
+	// by whitespace such as newlines, as some peers write them. Its messages are written as by NewlineFraming
+	ConcatenatedFraming Framing = concatenatedFraming{}
+)
+
+type newlineFraming struct{}
+
+// ReadMessage reads the next line, assembling it from as many reads as needed and skipping the blank lines.
+// A line exceeding the size limit is skipped without being buffered, so the next one can be read.
+// Returns the message or an error once the stream ends or the line exceeds the size limit
+func (newlineFraming) ReadMessage(reader *bufio.Reader, maxMessageSize int) ([]byte, error) {
+	for {
+		var line []byte
+		for {
+			fragment, err := reader.ReadSlice('\n')
+			if len(line)+len(fragment) > maxMessageSize {
+				for errors.Is(err, bufio.ErrBufferFull) {
+					_, err = reader.ReadSlice('\n')
+				}
+				return nil, ErrMessageTooLarge
+			}
+			line = append(line, fragment...)
+			if err == nil {
+				break
+			}
+			if !errors.Is(err, bufio.ErrBufferFull) {
+				if err == io.EOF && len(bytes.TrimSpace(line)) > 0 {
+					return line, nil
+				}
+				return nil, err
+			}
+		}
+		if len(bytes.TrimSpace(line)) > 0 {
+			return line, nil
+		}
+	}
+}
+
+// WriteMessage writes a message followed by a newline, compacting it first if it is indented
+func (newlineFraming) WriteMessage(writer io.Writer, messageRaw []byte) error {
+	messageRaw = bytes.TrimRight(messageRaw, " \t\r\n")
+	if bytes.IndexByte(messageRaw, '\n') >= 0 {
+		var compacted bytes.Buffer
+		err := json.Compact(&compacted, messageRaw)
+		if err != nil {
+			return err
+		}
+		messageRaw = compacted.Bytes()
+	}
+	line := make([]byte, 0, len(messageRaw)+1)
+	line = append(append(line, messageRaw...), '\n')
+	_, err := writer.Write(line)
+	return err
+}
+
+type contentLengthFraming struct{}
+
+// ReadMessage reads the header, ignoring all but the Content-Length, and the content of the next message.
+// A content exceeding the size limit is skipped without being buffered, so the next message can be read.
+// Returns the message or an error once the stream ends, the header is invalid or the content exceeds the size limit
+func (contentLengthFraming) ReadMessage(reader *bufio.Reader, maxMessageSize int) ([]byte, error) {
+	header, err := textproto.NewReader(reader).ReadMIMEHeader()
+	if err != nil {
+		if err == io.EOF || (errors.Is(err, io.ErrUnexpectedEOF) && len(header) == 0) {
+			return nil, io.EOF
+		}
+		return nil, err
+	}
+	values := header.Values("Content-Length")
+	if len(values) != 1 {
+		return nil, errors.New("missing Content-Length header")
+	}
+	length, err := strconv.Atoi(values[0])
+	if err != nil || length < 0 {
+		return nil, fmt.Errorf("invalid Content-Length header %q", values[0])
+	}
+	if length > maxMessageSize {
+		_, _ = io.CopyN(io.Discard, reader, int64(length))
+		return nil, ErrMessageTooLarge
+	}
+	content := make([]byte, length)
+	_, err = io.ReadFull(reader, content)
+	if err != nil {
+		return nil, err
+	}
+	return content, nil
+}
+
+// WriteMessage writes the Content-Length header and the message, without its trailing newline
+func (contentLengthFraming) WriteMessage(writer io.Writer, messageRaw []byte) error {
+	messageRaw = bytes.TrimRight(messageRaw, " \t\r\n")
+	frame := make([]byte, 0, len(messageRaw)+32)
+	frame = append(frame, "Content-Length: "...)
+	frame = strconv.AppendInt(frame, int64(len(messageRaw)), 10)
+	frame = append(frame, "\r\n\r\n"...)
+	frame = append(frame, messageRaw...)
+	_, err := writer.Write(frame)
+	return err
+}
+
+type concatenatedFraming struct{}
+
+// ReadMessage skips the whitespace and reads the next JSON object or array, assembling it from as many reads
+// as needed. A message exceeding the size limit is skipped without being buffered, so the next one can be read.
+// Returns the message or an error once the stream ends, the message does not start as a JSON-RPC message
+// or exceeds the size limit
+func (concatenatedFraming) ReadMessage(reader *bufio.Reader, maxMessageSize int) ([]byte, error) {
+	for {
+		c, err := reader.ReadByte()
+		if err != nil {
+			return nil, err
+		}
+		if c == '{' || c == '[' {
+			break
+		}
+		if c != ' ' && c != '\t' && c != '\r' && c != '\n' {
+			return nil, fmt.Errorf("invalid character %q at the start of a message", c)
+		}
+	}
+	_ = reader.UnreadByte()
+
+	var scanner jsonValueScanner
+	var message []byte
+	tooLarge := false
+	for {
+		if reader.Buffered() == 0 {
+			if _, err := reader.Peek(1); err != nil {
+				if err == io.EOF {
+					return nil, io.ErrUnexpectedEOF
+				}
+				return nil, err
+			}
+		}
+		chunk, _ := reader.Peek(reader.Buffered())
+		end := scanner.scan(chunk)
+		n := len(chunk)
+		if end >= 0 {
+			n = end + 1
+		}
+		if len(message)+n > maxMessageSize {
+			tooLarge, message = true, nil
+		}
+		if !tooLarge {
+			message = append(message, chunk[:n]...)
+		}
+		_, _ = reader.Discard(n)
+		if end >= 0 {
+			if tooLarge {
+				return nil, ErrMessageTooLarge
+			}
+			return message, nil
+		}
+	}
+}
+
+// WriteMessage writes a message followed by a newline, compacting it first if it is indented
+func (concatenatedFraming) WriteMessage(writer io.Writer, messageRaw []byte) error {
+	return newlineFraming{}.WriteMessage(writer, messageRaw)
+}
+
+// jsonValueScanner finds the end of a JSON object or array fed in chunks, tracking the nesting and the strings
+// only. The value itself is validated when parsing the message
+type jsonValueScanner struct {
+	depth    int
+	inString bool
+	escaped  bool
+}
+
+// scan continues the scanning with the next chunk.
+// Returns the index of the byte closing the value or -1 if the value continues past the chunk
+func (s *jsonValueScanner) scan(chunk []byte) int {
+	for i, c := range chunk {
+		if s.inString {
+			switch {
+			case s.escaped:
+				s.escaped = false
+			case c == '\\':
+				s.escaped = true
+			case c == '"':
+				s.inString = false
+			}
+			continue
+		}
+		switch c {
+		case '"':
+			s.inString = true
+		case '{', '[':
+			s.depth++
+		case '}', ']':
+			s.depth--
+			if s.depth == 0 {
+				return i
+			}
+		}
+	}
+	return -1
+}
@@ -0,0 +1,282 @@
+/*  Copyright 2022  Kosmas Valianos (kosmas.valianos@gmail.com)
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    https://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License. */
+
+package gojsonrpc
+
+import (
+	"bufio"
+	"bytes"
+	"context"
+	"errors"
+	"io"
+	"net"
+	"strings"
+	"testing"
+	"testing/iotest"
+)
+
+func Test_contentLengthFraming_ReadMessage(t *testing.T) {
+	tests := []struct {
+		name    string
+		stream  string
+		want    []string
+		wantErr bool
+	}{
+		{
+			name:   "Messages",
+			stream: "Content-Length: 8\r\n\r\n{\"id\":1}Content-Length: 8\r\n\r\n{\"id\":2}",
+			want:   []string{`{"id":1}`, `{"id":2}`},
+		},
+		{
+			name:   "Content-Type header",
+			stream: "Content-Length: 8\r\nContent-Type: application/vscode-jsonrpc; charset=utf-8\r\n\r\n{\"id\":1}",
+			want:   []string{`{"id":1}`},
+		},
+		{
+			name:   "Case insensitive header",
+			stream: "content-length: 8\r\n\r\n{\"id\":1}",
+			want:   []string{`{"id":1}`},
+		},
+		{
+			name:   "Content with newlines",
+			stream: "Content-Length: 12\r\n\r\n{\n  \"id\":1\n}",
+			want:   []string{"{\n  \"id\":1\n}"},
+		},
+		{
+			name:    "Missing Content-Length",
+			stream:  "Content-Type: application/json\r\n\r\n{\"id\":1}",
+			wantErr: true,
+		},
+		{
+			name:    "Invalid Content-Length",
+			stream:  "Content-Length: eight\r\n\r\n{\"id\":1}",
+			wantErr: true,
+		},
+		{
+			name:    "Too large",
+			stream:  "Content-Length: 4096\r\n\r\n{}",
+			wantErr: true,
+		},
+		{
+			name:    "Truncated content",
+			stream:  "Content-Length: 8\r\n\r\n{\"id\"",
+			wantErr: true,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			reader := bufio.NewReader(iotest.OneByteReader(strings.NewReader(tt.stream)))
+			var got []string
+			var err error
+			for {
+				var message []byte
+				message, err = ContentLengthFraming.ReadMessage(reader, 1024)
+				if err != nil {
+					break
+				}
+				got = append(got, string(message))
+			}
+			if (err != io.EOF) != tt.wantErr {
+				t.Errorf("ReadMessage() = %v, wantErr %v", err, tt.wantErr)
+			}
+			if !tt.wantErr && strings.Join(got, "|") != strings.Join(tt.want, "|") {
+				t.Errorf("messages = %q, want %q", got, tt.want)
+			}
+		})
+	}
+}
+
+func Test_concatenatedFraming_ReadMessage(t *testing.T) {
+	tests := []struct {
+		name    string
+		stream  string
+		want    []string
+		wantErr error
+	}{
+		{
+			name:   "Concatenated",
+			stream: `{"id":1}{"id":2}[{"id":3}]`,
+			want:   []string{`{"id":1}`, `{"id":2}`, `[{"id":3}]`},
+		},
+		{
+			name:   "Newline separated",
+			stream: "{\"id\":1}\n\r\n  {\n  \"id\": 2\n}\n",
+			want:   []string{`{"id":1}`, "{\n  \"id\": 2\n}"},
+		},
+		{
+			name:   "Brackets and escapes in strings",
+			stream: `{"method":"}{","params":["\"]", "\\"]}{"id":2}`,
+			want:   []string{`{"method":"}{","params":["\"]", "\\"]}`, `{"id":2}`},
+		},
+		{
+			name:    "Too large message skipped",
+			stream:  `{"params":"` + strings.Repeat("a", 2048) + `"}{"id":2}`,
+			want:    []string{`{"id":2}`},
+			wantErr: ErrMessageTooLarge,
+		},
+		{
+			name:    "Invalid start",
+			stream:  `{"id":1} "id"`,
+			want:    []string{`{"id":1}`},
+			wantErr: errors.New(`invalid character '"' at the start of a message`),
+		},
+		{
+			name:    "Truncated message",
+			stream:  `{"id":1}{"id"`,
+			want:    []string{`{"id":1}`},
+			wantErr: io.ErrUnexpectedEOF,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			reader := bufio.NewReaderSize(iotest.HalfReader(strings.NewReader(tt.stream)), 16)
+			var got []string
+			var firstErr error
+			for {
+				message, err := ConcatenatedFraming.ReadMessage(reader, 1024)
+				if err == io.EOF {
+					break
+				}
+				if err != nil {
+					if firstErr == nil {
+						firstErr = err
+					}
+					if err != ErrMessageTooLarge {
+						break
+					}
+					continue
+				}
+				got = append(got, string(message))
+			}
+			if (firstErr == nil) != (tt.wantErr == nil) || (firstErr != nil && firstErr.Error() != tt.wantErr.Error()) {
+				t.Errorf("ReadMessage() error = %v, want %v", firstErr, tt.wantErr)
+			}
+			if strings.Join(got, "|") != strings.Join(tt.want, "|") {
+				t.Errorf("messages = %q, want %q", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestDecoder_ConcatenatedFraming(t *testing.T) {
+	requestRaw, err := NewRequest("subtract", []int{42, 23}, 1)
+	if err != nil {
+		t.Fatal(err)
+	}
+	stream := bytes.TrimSpace(requestRaw)
+	stream = append(stream, `{"jsonrpc":"2.0","method":"update"}`...)
+	decoder := NewDecoder(iotest.OneByteReader(bytes.NewReader(stream)), ConcatenatedFraming)
+
+	request, err := decoder.DecodeRequest()
+	if err != nil || request.Method != "subtract" {
+		t.Fatalf("DecodeRequest() = %v, %v, want subtract", request, err)
+	}
+	notification, err := decoder.DecodeNotification()
+	if err != nil || notification.Method != "update" {
+		t.Fatalf("DecodeNotification() = %v, %v, want update", notification, err)
+	}
+	if _, err := decoder.Decode(); err != io.EOF {
+		t.Errorf("Decode() error = %v, want %v", err, io.EOF)
+	}
+}
+
+func TestFraming_ReadMessage_tooLarge(t *testing.T) {
+	// The stream stays usable after a message exceeding the size limit
+	large := `{"params":"` + strings.Repeat("a", 2048) + `"}`
+	tests := []struct {
+		name    string
+		framing Framing
+		stream  string
+	}{
+		{
+			name:    "NewlineFraming",
+			framing: NewlineFraming,
+			stream:  large + "\n" + `{"id":2}` + "\n",
+		},
+		{
+			name:    "ContentLengthFraming",
+			framing: ContentLengthFraming,
+			stream:  "Content-Length: 2061\r\n\r\n" + large + "Content-Length: 8\r\n\r\n{\"id\":2}",
+		},
+		{
+			name:    "ConcatenatedFraming",
+			framing: ConcatenatedFraming,
+			stream:  large + `{"id":2}`,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			reader := bufio.NewReaderSize(iotest.HalfReader(strings.NewReader(tt.stream)), 16)
+			_, err := tt.framing.ReadMessage(reader, 1024)
+			if err != ErrMessageTooLarge {
+				t.Fatalf("ReadMessage() = %v, want %v", err, ErrMessageTooLarge)
+			}
+			message, err := tt.framing.ReadMessage(reader, 1024)
+			if err != nil || string(bytes.TrimSpace(message)) != `{"id":2}` {
+				t.Errorf("ReadMessage() = %q, %v, want %q", message, err, `{"id":2}`)
+			}
+			if _, err = tt.framing.ReadMessage(reader, 1024); err != io.EOF {
+				t.Errorf("ReadMessage() = %v, want %v", err, io.EOF)
+			}
+		})
+	}
+}
+
+func Test_contentLengthFraming_WriteMessage(t *testing.T) {
+	var written bytes.Buffer
+	err := ContentLengthFraming.WriteMessage(&written, []byte("{\"jsonrpc\":\"2.0\",\"result\":\"é\",\"id\":1}\n"))
+	// The length counts bytes, not characters
+	want := "Content-Length: 38\r\n\r\n{\"jsonrpc\":\"2.0\",\"result\":\"é\",\"id\":1}"
+	if err != nil || written.String() != want {
+		t.Errorf("WriteMessage() = %q, %v, want %q", written.String(), err, want)
+	}
+}
+
+func TestWithTCPFraming(t *testing.T) {
+	listener, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer listener.Close()
+	go ServeTCP(listener, newTestMux(t), WithTCPFraming(ContentLengthFraming))
+
+	t.Run("Conn", func(t *testing.T) {
+		conn, err := DialTCP(context.Background(), listener.Addr().String(), nil, WithTCPFraming(ContentLengthFraming))
+		if err != nil {
+			t.Fatalf("DialTCP() = %v, want nil", err)
+		}
+		defer conn.Close()
+		result, err := Call[int](context.Background(), conn.Client(), "subtract", []int{42, 23})
+		if err != nil || result != 19 {
+			t.Errorf("Call() = %v, %v, want 19", result, err)
+		}
+	})
+
+	t.Run("Raw client", func(t *testing.T) {
+		client, err := net.Dial("tcp", listener.Addr().String())
+		if err != nil {
+			t.Fatal(err)
+		}
+		defer client.Close()
+		request := `{"jsonrpc":"2.0","method":"subtract","params":[42,23],"id":1}`
+		_, err = io.WriteString(client, "Content-Length: 61\r\n\r\n"+request)
+		if err != nil {
+			t.Fatal(err)
+		}
+		response, err := ContentLengthFraming.ReadMessage(bufio.NewReader(client), defaultMaxBodySize)
+		if err != nil || string(response) != `{"jsonrpc":"2.0","result":19,"id":1}` {
+			t.Errorf("response = %s, %v", response, err)
+		}
+	})
+}
@@ -0,0 +1,280 @@
+/*  Copyright 2022  Kosmas Valianos (kosmas.valianos@gmail.com)
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    https://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License. */
+
+package gojsonrpc
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"strings"
+	"sync"
+)
+
+// Gateway is an http.Handler exposing the methods of a Mux as a REST interface, mapping the configured HTTP routes
+// onto JSON-RPC methods, e.g. POST /users onto "user.create", so that one service serves both interfaces
+// with the same handlers. It is safe for concurrent use
+type Gateway struct {
+	mux         *Mux
+	idGenerator IncrementingIDGenerator
+	maxBodySize int64
+	errorStatus func(code int) int
+	mu          sync.RWMutex
+	routes      []gatewayRoute
+}
+
+// gatewayRoute maps the HTTP requests of a method and a path pattern onto a JSON-RPC method
+type gatewayRoute struct {
+	httpMethod string
+	segments   []string
+	method     string
+}
+
+// GatewayOption configures a Gateway
+type GatewayOption func(*Gateway)
+
+// WithGatewayMaxBodySize sets the size limit in bytes of the body of the HTTP requests, 1MB by default.
+// Larger requests are answered with HTTP status 413 Request Entity Too Large
+func WithGatewayMaxBodySize(size int64) GatewayOption {
+	return func(g *Gateway) {
+		if size > 0 {
+			g.maxBodySize = size
+		}
+	}
+}
+
+// WithGatewayErrorStatus sets the function returning the HTTP status code of the responses with an error object
+// of the code, e.g. to map the codes defined by the application. The default one is DefaultGatewayErrorStatus
+func WithGatewayErrorStatus(status func(code int) int) GatewayOption {
+	return func(g *Gateway) {
+		if status != nil {
+			g.errorStatus = status
+		}
+	}
+}
+
+// NewGateway creates a Gateway serving the routes with the mux, configured by the options.
+// Returns a *Gateway object
+func NewGateway(mux *Mux, options ...GatewayOption) *Gateway {
+	gateway := &Gateway{mux: mux, maxBodySize: defaultMaxBodySize, errorStatus: DefaultGatewayErrorStatus}
+	for _, option := range options {
+		option(gateway)
+	}
+	return gateway
+}
+
+// Route maps the HTTP requests of the HTTP method and the path pattern onto the JSON-RPC method, e.g.
+// Route(http.MethodGet, "/users/{id}", "user.get"). The segments of the pattern in braces capture the segment
+// of the path as a named param. The routes are tried in the order they are added.
+// Returns an error if the HTTP method, the pattern or the method is invalid
+func (g *Gateway) Route(httpMethod, pattern, method string) error {
+	if httpMethod == "" || method == "" {
+		return errors.New("HTTP method and method must not be empty")
+	}
+	if !strings.HasPrefix(pattern, "/") {
+		return fmt.Errorf("pattern %q must start with /", pattern)
+	}
+	segments := strings.Split(strings.Trim(pattern, "/"), "/")
+	for _, segment := range segments {
+		name, ok := patternParam(segment)
+		if ok && (name == "" || strings.ContainsAny(name, "{}")) || !ok && strings.ContainsAny(segment, "{}") {
+			return fmt.Errorf("pattern %q has an invalid segment %q", pattern, segment)
+		}
+	}
+
+	g.mu.Lock()
+	defer g.mu.Unlock()
+	g.routes = append(g.routes, gatewayRoute{httpMethod: strings.ToUpper(httpMethod), segments: segments, method: method})
+	return nil
+}
+
+// patternParam returns the name of the param captured by a segment of a pattern, if it is in braces
+func patternParam(segment string) (string, bool) {
+	if strings.HasPrefix(segment, "{") && strings.HasSuffix(segment, "}") {
+		return segment[1 : len(segment)-1], true
+	}
+	return "", false
+}
+
+// match matches the path against the pattern of the route.
+// Returns the params captured by the path or false if it does not match
+func (r *gatewayRoute) match(path []string) (map[string]string, bool) {
+	if len(path) != len(r.segments) {
+		return nil, false
+	}
+	var params map[string]string
+	for i, segment := range r.segments {
+		if name, ok := patternParam(segment); ok {
+			if params == nil {
+				params = make(map[string]string)
+			}
+			params[name] = path[i]
+			continue
+		}
+		if segment != path[i] {
+			return nil, false
+		}
+	}
+	return params, true
+}
+
+// ServeHTTP serves a REST request with the method of its route. The named params are the members of the JSON object
+// of the body, if any, overridden by the query parameters and then by the path params. The values of the query and
+// the path are taken as JSON if they are a number, a boolean or null, as strings otherwise, and the repeated query
+// parameters as arrays. The result is written as the body with HTTP status 200 OK and the error object with the HTTP
+// status code of the WithGatewayErrorStatus. Unknown paths are answered with HTTP status 404 Not Found and the known
+// ones requested with another HTTP method with 405 Method Not Allowed
+func (g *Gateway) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	route, pathParams, allowed := g.route(r.Method, r.URL.Path)
+	if route == nil {
+		if len(allowed) > 0 {
+			w.Header().Set("Allow", strings.Join(allowed, ", "))
+			http.Error(w, http.StatusText(http.StatusMethodNotAllowed), http.StatusMethodNotAllowed)
+			return
+		}
+		http.NotFound(w, r)
+		return
+	}
+
+	params, jsonRPCError := g.params(w, r, pathParams)
+	if jsonRPCError != nil {
+		statusCode := http.StatusBadRequest
+		var maxBytesErr *http.MaxBytesError
+		if errors.As(jsonRPCError, &maxBytesErr) {
+			statusCode = http.StatusRequestEntityTooLarge
+		}
+		writeGatewayError(w, statusCode, jsonRPCError)
+		return
+	}
+	requestRaw, err := newRequestWithID(route.method, params, g.idGenerator.NextID())
+	if err != nil {
+		writeGatewayError(w, http.StatusBadRequest, &JsonInvalidMethodParameters)
+		return
+	}
+
+	ctx := r.Context()
+	if r.TLS != nil {
+		ctx = withPeerCertificate(ctx, r.TLS)
+	}
+	response, err := ParseResponseZeroCopy(g.mux.Serve(ctx, requestRaw))
+	if err != nil {
+		writeGatewayError(w, http.StatusInternalServerError, &JsonInternalError)
+		return
+	}
+	if response.IsError() {
+		writeGatewayError(w, g.errorStatus(response.Error.Code), response.Error)
+		return
+	}
+	writeHTTPBody(w, http.StatusOK, "application/json", append(response.RawResult(), '\n'))
+}
+
+// route finds the first route of the HTTP method matching the path.
+// Returns the route and its path params or the HTTP methods of the routes matching the path but not the HTTP method
+func (g *Gateway) route(httpMethod, path string) (*gatewayRoute, map[string]string, []string) {
+	segments := strings.Split(strings.Trim(path, "/"), "/")
+	g.mu.RLock()
+	defer g.mu.RUnlock()
+	var allowed []string
+	for i := range g.routes {
+		route := &g.routes[i]
+		params, ok := route.match(segments)
+		if !ok {
+			continue
+		}
+		if route.httpMethod == httpMethod {
+			return route, params, nil
+		}
+		allowed = append(allowed, route.httpMethod)
+	}
+	return nil, nil, allowed
+}
+
+// params builds the named params of a REST request from its body, its query and its path params.
+// Returns the params or a *jsonRPCError object, wrapping a *http.MaxBytesError if the body is too large
+func (g *Gateway) params(w http.ResponseWriter, r *http.Request, pathParams map[string]string) (map[string]json.RawMessage, *jsonRPCError) {
+	params := make(map[string]json.RawMessage)
+	body, err := io.ReadAll(http.MaxBytesReader(w, r.Body, g.maxBodySize))
+	if err != nil {
+		return nil, JsonInvalidRequest.WithCause(err)
+	}
+	if len(strings.TrimSpace(string(body))) > 0 {
+		if !json.Valid(body) {
+			return nil, &JsonParseError
+		}
+		if jsonKind(body) != '{' || json.Unmarshal(body, &params) != nil {
+			return nil, &JsonInvalidMethodParameters
+		}
+	}
+
+	for name, values := range r.URL.Query() {
+		if len(values) == 1 {
+			params[name] = gatewayValue(values[0])
+			continue
+		}
+		elements := make([]json.RawMessage, len(values))
+		for i, value := range values {
+			elements[i] = gatewayValue(value)
+		}
+		params[name], _ = json.Marshal(elements)
+	}
+	for name, value := range pathParams {
+		params[name] = gatewayValue(value)
+	}
+	return params, nil
+}
+
+// gatewayValue takes a value of a query or a path as JSON if it is a number, a boolean or null, as a string otherwise
+func gatewayValue(value string) json.RawMessage {
+	if json.Valid([]byte(value)) {
+		switch jsonKind([]byte(value)) {
+		case '0', 't', 'n':
+			return json.RawMessage(value)
+		}
+	}
+	valueRaw, _ := json.Marshal(value)
+	return valueRaw
+}
+
+// writeGatewayError writes the error object as the body of a REST response
+func writeGatewayError(w http.ResponseWriter, statusCode int, jsonRPCError *jsonRPCError) {
+	body, err := json.Marshal(jsonRPCError)
+	if err != nil {
+		http.Error(w, http.StatusText(http.StatusInternalServerError), http.StatusInternalServerError)
+		return
+	}
+	writeHTTPBody(w, statusCode, "application/json", append(body, '\n'))
+}
+
+// DefaultGatewayErrorStatus returns the HTTP status code of a REST response with an error object of the code:
+// 400 Bad Request for a parse error, an invalid request or invalid params, 404 Not Found for an unknown method,
+// 504 Gateway Timeout for a timeout, 503 Service Unavailable for a busy server, 502 Bad Gateway for an unavailable
+// upstream and 500 Internal Server Error otherwise
+func DefaultGatewayErrorStatus(code int) int {
+	switch code {
+	case ParseError, InvalidRequest, InvalidMethodParameters:
+		return http.StatusBadRequest
+	case MethodNotFound:
+		return http.StatusNotFound
+	case RequestTimeout:
+		return http.StatusGatewayTimeout
+	case ServerBusy:
+		return http.StatusServiceUnavailable
+	case UpstreamUnavailable:
+		return http.StatusBadGateway
+	default:
+		return http.StatusInternalServerError
+	}
+}
@@ -0,0 +1,196 @@
+/*  Copyright 2022  Kosmas Valianos (kosmas.valianos@gmail.com)
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    https://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License. */
+
+package gojsonrpc
+
+import (
+	"context"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+)
+
+type gatewayUser struct {
+	ID    int      `json:"id"`
+	Name  string   `json:"name"`
+	Admin bool     `json:"admin"`
+	Tags  []string `json:"tags,omitempty"`
+}
+
+func TestGateway(t *testing.T) {
+	mux := newTestMux(t)
+	err := HandleFunc(mux, "user.create", func(ctx context.Context, params gatewayUser) (gatewayUser, error) {
+		params.ID = 7
+		return params, nil
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	err = HandleFunc(mux, "user.get", func(ctx context.Context, params gatewayUser) (gatewayUser, error) {
+		if params.ID != 7 {
+			return gatewayUser{}, &JsonInvalidMethodParameters
+		}
+		return gatewayUser{ID: 7, Name: "foo", Tags: params.Tags}, nil
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	gateway := NewGateway(mux, WithGatewayMaxBodySize(128))
+	routes := []struct{ httpMethod, pattern, method string }{
+		{http.MethodPost, "/users", "user.create"},
+		{http.MethodGet, "/users/{id}", "user.get"},
+		{http.MethodGet, "/fail", "fail"},
+		{http.MethodGet, "/missing", "user.delete"},
+	}
+	for _, route := range routes {
+		if err := gateway.Route(route.httpMethod, route.pattern, route.method); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	tests := []struct {
+		name           string
+		method         string
+		target         string
+		body           string
+		wantStatusCode int
+		wantBody       string
+	}{
+		{
+			name:           "Body params",
+			method:         http.MethodPost,
+			target:         "/users",
+			body:           `{"name":"foo","admin":true}`,
+			wantStatusCode: http.StatusOK,
+			wantBody:       `{"id":7,"name":"foo","admin":true}`,
+		},
+		{
+			name:           "Query params override body params",
+			method:         http.MethodPost,
+			target:         "/users?admin=false&name=bar",
+			body:           `{"name":"foo","admin":true}`,
+			wantStatusCode: http.StatusOK,
+			wantBody:       `{"id":7,"name":"bar","admin":false}`,
+		},
+		{
+			name:           "Path params and repeated query params",
+			method:         http.MethodGet,
+			target:         "/users/7?tags=a&tags=b",
+			wantStatusCode: http.StatusOK,
+			wantBody:       `{"id":7,"name":"foo","admin":false,"tags":["a","b"]}`,
+		},
+		{
+			name:           "Error object",
+			method:         http.MethodGet,
+			target:         "/users/8",
+			wantStatusCode: http.StatusBadRequest,
+			wantBody:       `{"code":-32602,"message":"Invalid method parameters"}`,
+		},
+		{
+			name:           "Internal error",
+			method:         http.MethodGet,
+			target:         "/fail",
+			wantStatusCode: http.StatusInternalServerError,
+			wantBody:       `{"code":-32603,"message":"Internal error"}`,
+		},
+		{
+			name:           "Unregistered method",
+			method:         http.MethodGet,
+			target:         "/missing",
+			wantStatusCode: http.StatusNotFound,
+			wantBody:       `{"code":-32601,"message":"Method not found"}`,
+		},
+		{
+			name:           "Body not an object",
+			method:         http.MethodPost,
+			target:         "/users",
+			body:           `["foo"]`,
+			wantStatusCode: http.StatusBadRequest,
+			wantBody:       `{"code":-32602,"message":"Invalid method parameters"}`,
+		},
+		{
+			name:           "Body not JSON",
+			method:         http.MethodPost,
+			target:         "/users",
+			body:           `{"name":`,
+			wantStatusCode: http.StatusBadRequest,
+			wantBody:       `{"code":-32700,"message":"Parse error"}`,
+		},
+		{
+			name:           "Body too large",
+			method:         http.MethodPost,
+			target:         "/users",
+			body:           `{"name":"` + strings.Repeat("a", 128) + `"}`,
+			wantStatusCode: http.StatusRequestEntityTooLarge,
+			wantBody:       `{"code":-32600,"message":"Invalid Request"}`,
+		},
+		{
+			name:           "Unknown path",
+			method:         http.MethodGet,
+			target:         "/groups",
+			wantStatusCode: http.StatusNotFound,
+			wantBody:       "404 page not found",
+		},
+		{
+			name:           "Method not allowed",
+			method:         http.MethodDelete,
+			target:         "/users",
+			wantStatusCode: http.StatusMethodNotAllowed,
+			wantBody:       "Method Not Allowed",
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			request := httptest.NewRequest(tt.method, tt.target, strings.NewReader(tt.body))
+			recorder := httptest.NewRecorder()
+			gateway.ServeHTTP(recorder, request)
+			response := recorder.Result()
+			if response.StatusCode != tt.wantStatusCode {
+				t.Errorf("ServeHTTP() status code = %v, want %v", response.StatusCode, tt.wantStatusCode)
+			}
+			body, _ := io.ReadAll(response.Body)
+			if got := strings.TrimSpace(string(body)); got != tt.wantBody {
+				t.Errorf("ServeHTTP() body = %v, want %v", got, tt.wantBody)
+			}
+		})
+	}
+}
+
+func TestGateway_Route(t *testing.T) {
+	tests := []struct {
+		name       string
+		httpMethod string
+		pattern    string
+		method     string
+		wantErr    bool
+	}{
+		{name: "Route", httpMethod: http.MethodGet, pattern: "/users/{id}/orders", method: "order.list"},
+		{name: "Root", httpMethod: http.MethodGet, pattern: "/", method: "status"},
+		{name: "No HTTP method", pattern: "/users", method: "user.list", wantErr: true},
+		{name: "No method", httpMethod: http.MethodGet, pattern: "/users", wantErr: true},
+		{name: "Relative pattern", httpMethod: http.MethodGet, pattern: "users", method: "user.list", wantErr: true},
+		{name: "Unnamed param", httpMethod: http.MethodGet, pattern: "/users/{}", method: "user.get", wantErr: true},
+		{name: "Unbalanced braces", httpMethod: http.MethodGet, pattern: "/users/{id", method: "user.get", wantErr: true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			gateway := NewGateway(NewMux())
+			if err := gateway.Route(tt.httpMethod, tt.pattern, tt.method); (err != nil) != tt.wantErr {
+				t.Errorf("Route() error = %v, wantErr %v", err, tt.wantErr)
+			}
+		})
+	}
+}
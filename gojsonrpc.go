@@ -53,27 +53,41 @@ func ParseNotification(notificationRaw []byte) (*notification, error) {
 	return &notification, nil
 }
 
-// NewNotification creates a notification using the method and the params.
+// UnmarshalParams unmarshals the params of the notification into v with the JSONEngine of the package,
+// leaving v untouched if they are omitted.
+// Returns an error
+func (n *notification) UnmarshalParams(v any) error {
+	return unmarshalParams(n.Params, v)
+}
+
+// NewNotification creates a notification using the method and the params, followed by a newline unless the options
+// set another delimiter.
 // Returns the raw bytes of the notification or an error
-func NewNotification(method string, params any) ([]byte, error) {
-	notification := notification{
+func NewNotification(method string, params any, options ...MarshalOption) ([]byte, error) {
+	config := newMarshalConfig(options)
+	notification, err := buildNotification(method, params, &config)
+	if err != nil {
+		return nil, err
+	}
+	return encodeMessage(notification, &config)
+}
+
+// buildNotification builds the notification using the method and the params marshaled as the config sets.
+// Returns a *notification object or an error
+func buildNotification(method string, params any, config *marshalConfig) (*notification, error) {
+	notification := &notification{
 		JsonRPC: "2.0",
 		Method:  method,
 	}
 
 	if params != nil {
 		var err error
-		notification.Params, err = json.Marshal(params)
+		notification.Params, err = config.marshalValue(params)
 		if err != nil {
 			return nil, err
 		}
 	}
-
-	notificationRaw, err := json.Marshal(&notification)
-	if err != nil {
-		return nil, err
-	}
-	return append(notificationRaw, '\n'), nil
+	return notification, nil
 }
 
 type request struct {
@@ -85,17 +99,51 @@ type request struct {
 
 // NewResultResponse creates a result response using a result object (nil for omitting).
 // Returns the raw bytes of the response or an error
-func (r *request) NewResultResponse(result any) ([]byte, error) {
+func (r *request) NewResultResponse(result any, options ...MarshalOption) ([]byte, error) {
 	response := response{
 		JsonRPC: jsonRPCProtocol,
 		ID:      r.ID,
 	}
-	return marshalResultResponse(response, result)
+	return marshalResultResponse(response, result, options)
+}
+
+// NewErrorResponse creates an error response from a *jsonRPCError object using the id of the request.
+// Returns the raw bytes of the response or an error
+func (r *request) NewErrorResponse(jsonError *jsonRPCError, options ...MarshalOption) ([]byte, error) {
+	return NewErrorResponse(r.ID, jsonError, options...)
+}
+
+// UnmarshalParams unmarshals the params of the request into v with the JSONEngine of the package,
+// leaving v untouched if they are omitted.
+// Returns an error
+func (r *request) UnmarshalParams(v any) error {
+	return unmarshalParams(r.Params, v)
+}
+
+func unmarshalParams(paramsRaw json.RawMessage, v any) error {
+	if len(paramsRaw) == 0 {
+		return nil
+	}
+	return currentJSONEngine().Unmarshal(paramsRaw, v)
 }
 
 // ParseRequest parses a JSON-RPC request from raw bytes.
 // Returns a *request object or a *jsonRPCError error object
 func ParseRequest(requestRaw []byte) (*request, *jsonRPCError) {
+	request, jsonRPCError := parseRequest(requestRaw)
+	if jsonRPCError != nil {
+		return nil, jsonRPCError
+	}
+
+	if strings.HasPrefix(request.Method, "rpc.") {
+		return nil, &JsonInvalidRequest
+	}
+	return request, nil
+}
+
+// parseRequest parses a JSON-RPC request from raw bytes allowing the reserved methods with prefix "rpc.".
+// Returns a *request object or a *jsonRPCError error object
+func parseRequest(requestRaw []byte) (*request, *jsonRPCError) {
 	jsonRPCError := &JsonParseError
 	var request request
 	err := json.Unmarshal(requestRaw, &request)
@@ -108,10 +156,6 @@ func ParseRequest(requestRaw []byte) (*request, *jsonRPCError) {
 		return nil, jsonRPCError
 	}
 
-	if strings.HasPrefix(request.Method, "rpc.") {
-		return nil, &JsonInvalidRequest
-	}
-
 	switch request.ID.(type) {
 	case float64:
 		// This is the type which json.Unmarshal() uses for JSON number
@@ -123,10 +167,22 @@ func ParseRequest(requestRaw []byte) (*request, *jsonRPCError) {
 	}
 }
 
-// NewRequest creates a request using the method, the params and the id.
+// NewRequest creates a request using the method, the params and the id, followed by a newline unless the options
+// set another delimiter.
 // Returns the raw bytes of the request or an error
-func NewRequest[I idInterface](method string, params any, id I) ([]byte, error) {
-	request := request{
+func NewRequest[I idInterface](method string, params any, id I, options ...MarshalOption) ([]byte, error) {
+	config := newMarshalConfig(options)
+	request, err := buildRequest(method, params, id, &config)
+	if err != nil {
+		return nil, err
+	}
+	return encodeMessage(request, &config)
+}
+
+// buildRequest builds the request using the method, the params marshaled as the config sets and the id.
+// Returns a *request object or an error
+func buildRequest[I idInterface](method string, params any, id I, config *marshalConfig) (*request, error) {
+	request := &request{
 		JsonRPC: "2.0",
 		Method:  method,
 		ID:      id,
@@ -134,23 +190,20 @@ func NewRequest[I idInterface](method string, params any, id I) ([]byte, error)
 
 	if params != nil {
 		var err error
-		request.Params, err = json.Marshal(params)
+		request.Params, err = config.marshalValue(params)
 		if err != nil {
 			return nil, err
 		}
 	}
-
-	requestRaw, err := json.Marshal(&request)
-	if err != nil {
-		return nil, err
-	}
-	return append(requestRaw, '\n'), nil
+	return request, nil
 }
 
 type jsonRPCError struct {
 	Code    int             `json:"code"`
 	Message string          `json:"message"`
 	Data    json.RawMessage `json:"data,omitempty"`
+	// cause is the Go error behind the error, never sent to the peer
+	cause error
 }
 
 // Const error codes
@@ -171,6 +224,20 @@ var (
 	JsonInternalError           = jsonRPCError{Code: InternalError, Message: "Internal error"}
 )
 
+// Const server error codes
+const (
+	RequestTimeout      = -32000
+	ServerBusy          = -32003
+	UpstreamUnavailable = -32004
+)
+
+// Common server error objects
+var (
+	JsonRequestTimeout      = jsonRPCError{Code: RequestTimeout, Message: "Request timeout"}
+	JsonServerBusy          = jsonRPCError{Code: ServerBusy, Message: "Server busy"}
+	JsonUpstreamUnavailable = jsonRPCError{Code: UpstreamUnavailable, Message: "Upstream unavailable"}
+)
+
 // Error implements Error() of error interface
 func (j *jsonRPCError) Error() string {
 	return fmt.Sprintf("Code: %v Message: %v Data: %v", j.Code, j.Message, string(j.Data))
@@ -184,6 +251,7 @@ func (j jsonRPCError) AddData(data any) (*jsonRPCError, error) {
 	jsonRPCError := jsonRPCError{
 		Code:    j.Code,
 		Message: j.Message,
+		cause:   j.cause,
 	}
 
 	var err error
@@ -194,11 +262,16 @@ func (j jsonRPCError) AddData(data any) (*jsonRPCError, error) {
 	return &jsonRPCError, nil
 }
 
-// NewJsonRPCError creates a jsonRPCError.
+// NewJsonRPCError creates a jsonRPCError with a code defined by the application, see IsCustomCode, which is not the code
+// of an error object of the package, e.g. JsonRequestTimeout.
 // Returns a *jsonRPCError object or an error
 func NewJsonRPCError(code int, message string, data any) (*jsonRPCError, error) {
-	if code < -32099 || code > -32000 {
-		return nil, errors.New("code must be between  -32099 and -32000")
+	if !IsCustomCode(code) {
+		return nil, fmt.Errorf("code must not be between %v and %v unless between %v and %v",
+			ReservedErrorStart, ReservedErrorEnd, ServerErrorStart, ServerErrorEnd)
+	}
+	if predefined, ok := predefinedError(code); ok {
+		return nil, fmt.Errorf("code %v is the code of %q", code, predefined.Message)
 	}
 
 	jsonRPCError := jsonRPCError{
@@ -224,41 +297,104 @@ type response struct {
 // ParseResponse parses a JSON-RPC request from raw bytes.
 // Returns a *response object or a error
 func ParseResponse(responseRaw []byte) (*response, error) {
-	var response response
-	err := json.Unmarshal(responseRaw, &response)
+	response, err := unmarshalResponse(responseRaw)
 	if err != nil {
 		return nil, err
 	}
 
-	if response.JsonRPC != jsonRPCProtocol {
-		return nil, fmt.Errorf("jsonrpc must be exactly \"%v\"", jsonRPCProtocol)
+	if err := response.validateEnvelope(); err != nil {
+		return nil, err
+	}
+	return response, nil
+}
+
+// validateEnvelope checks the members of the response which ParseResponse checks.
+// Returns an error
+func (r *response) validateEnvelope() error {
+	if r.JsonRPC != jsonRPCProtocol {
+		return fmt.Errorf("jsonrpc must be exactly \"%v\"", jsonRPCProtocol)
 	}
 
-	if len(response.Result) == 0 && response.Error == nil {
-		return nil, errors.New("response must have a \"result\" or an \"error\"")
-	} else if len(response.Result) > 0 && response.Error != nil {
-		return nil, errors.New("response must not have a \"result\" and an \"error\"")
+	if len(r.Result) == 0 && r.Error == nil {
+		return errors.New("response must have a \"result\" or an \"error\"")
+	} else if len(r.Result) > 0 && r.Error != nil {
+		return errors.New("response must not have a \"result\" and an \"error\"")
 	}
 
-	if response.ID == nil {
-		if response.Error == nil {
-			return nil, errors.New("response's ID must not be null when error does not exist")
-		} else if response.Error.Code != ParseError && response.Error.Code != InvalidRequest {
-			return nil, fmt.Errorf("response's ID must be null only when error's code is %v or %v", ParseError, InvalidRequest)
+	if r.ID == nil {
+		if r.Error == nil {
+			return errors.New("response's ID must not be null when error does not exist")
+		} else if r.Error.Code != ParseError && r.Error.Code != InvalidRequest {
+			return fmt.Errorf("response's ID must be null only when error's code is %v or %v", ParseError, InvalidRequest)
 		}
 	}
+	return nil
+}
+
+// UnmarshalResult unmarshals the result of the response into v with the JSONEngine of the package.
+// Returns an error, the *jsonRPCError object of an error response
+func (r *response) UnmarshalResult(v any) error {
+	if r.Error != nil {
+		return r.Error
+	}
+	return currentJSONEngine().Unmarshal(r.Result, v)
+}
 
+// IsError reports whether the response is an error response
+func (r *response) IsError() bool {
+	return r.Error != nil
+}
+
+// Err returns the *jsonRPCError object of an error response as an error, or nil for a result response
+func (r *response) Err() error {
+	if r.Error == nil {
+		return nil
+	}
+	return r.Error
+}
+
+// GetID returns the id of the response, null when the request could not be identified.
+// It is not named ID since that is the name of the field
+func (r *response) GetID() any {
+	return r.ID
+}
+
+// RawResult returns the result of the response without unmarshaling it, nil for an error response
+func (r *response) RawResult() json.RawMessage {
+	return r.Result
+}
+
+// unmarshalResponse decodes a JSON-RPC response with encoding/json without validating it.
+// Returns a *response object or an error
+func unmarshalResponse(responseRaw []byte) (*response, error) {
+	var response response
+	err := json.Unmarshal(responseRaw, &response)
+	if err != nil {
+		return nil, err
+	}
 	return &response, nil
 }
 
-// NewErrorResponse creates a response from a *jsonRPCError object using the id if it's applicable and not nil.
+// NewErrorResponse creates a response from a *jsonRPCError object using the id if it's applicable and not nil,
+// followed by a newline unless the options set another delimiter.
 // Returns the raw bytes of the response or an error
-func NewErrorResponse(id any, jsonError *jsonRPCError) ([]byte, error) {
+func NewErrorResponse(id any, jsonError *jsonRPCError, options ...MarshalOption) ([]byte, error) {
+	response, err := buildErrorResponse(id, jsonError)
+	if err != nil {
+		return nil, err
+	}
+	config := newMarshalConfig(options)
+	return encodeMessage(response, &config)
+}
+
+// buildErrorResponse builds the response from a *jsonRPCError object using the id if it's applicable and not nil.
+// Returns a *response object or an error
+func buildErrorResponse(id any, jsonError *jsonRPCError) (*response, error) {
 	if jsonError == nil {
 		return nil, errors.New("no JSON-RPC error passed as parameter")
 	}
 
-	response := response{
+	response := &response{
 		JsonRPC: jsonRPCProtocol,
 		Error:   jsonError,
 	}
@@ -278,35 +414,28 @@ func NewErrorResponse(id any, jsonError *jsonRPCError) ([]byte, error) {
 			return nil, errors.New("id must be of type int, float64 or string")
 		}
 	}
-
-	responseRaw, err := json.Marshal(&response)
-	if err != nil {
-		return nil, err
-	}
-	return append(responseRaw, '\n'), nil
+	return response, nil
 }
 
-// NewResultResponse creates a response from a result object using the id.
+// NewResultResponse creates a response from a result object using the id, followed by a newline unless the options
+// set another delimiter.
 // Returns the raw bytes of the response or an error
-func NewResultResponse[I idInterface](id I, result any) ([]byte, error) {
+func NewResultResponse[I idInterface](id I, result any, options ...MarshalOption) ([]byte, error) {
 	response := response{
 		JsonRPC: jsonRPCProtocol,
 		ID:      id,
 	}
 
-	return marshalResultResponse(response, result)
+	return marshalResultResponse(response, result, options)
 }
 
-func marshalResultResponse(response response, result any) ([]byte, error) {
+func marshalResultResponse(response response, result any, options []MarshalOption) ([]byte, error) {
+	config := newMarshalConfig(options)
 	var err error
-	response.Result, err = json.Marshal(result)
+	response.Result, err = config.marshalValue(result)
 	if err != nil {
 		return nil, err
 	}
 
-	responseRaw, err := json.Marshal(&response)
-	if err != nil {
-		return nil, err
-	}
-	return append(responseRaw, '\n'), nil
+	return encodeMessage(&response, &config)
 }
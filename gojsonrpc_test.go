@@ -20,6 +20,8 @@ package gojsonrpc
 
 import (
 	"bytes"
+	"encoding/json"
+	"errors"
 	"reflect"
 	"testing"
 )
@@ -290,7 +292,7 @@ func TestNewJsonRPCError(t *testing.T) {
 		{
 			name: "Valid parameters",
 			args: args{
-				code:    -32000,
+				code:    -32050,
 				message: "Database error",
 				data: struct {
 					ServerName     string `json:"server-name"`
@@ -301,11 +303,37 @@ func TestNewJsonRPCError(t *testing.T) {
 				},
 			},
 			want: &jsonRPCError{
-				Code:    -32000,
+				Code:    -32050,
 				Message: "Database error",
 				Data:    []byte(`{"server-name":"example.com","server-protocol":"http"}`),
 			},
 		},
+		{
+			name: "Application code",
+			args: args{
+				code:    1001,
+				message: "Insufficient funds",
+				data:    42.5,
+			},
+			want: &jsonRPCError{
+				Code:    1001,
+				Message: "Insufficient funds",
+				Data:    []byte(`42.5`),
+			},
+		},
+		{
+			name: "Negative application code",
+			args: args{
+				code:    -40000,
+				message: "Quota exceeded",
+				data:    "daily",
+			},
+			want: &jsonRPCError{
+				Code:    -40000,
+				Message: "Quota exceeded",
+				Data:    []byte(`"daily"`),
+			},
+		},
 		{
 			name: "Invalid parameters",
 			args: args{
@@ -314,6 +342,22 @@ func TestNewJsonRPCError(t *testing.T) {
 			},
 			wantErr: true,
 		},
+		{
+			name: "Predefined code",
+			args: args{
+				code:    MethodNotFound,
+				message: "No such method",
+			},
+			wantErr: true,
+		},
+		{
+			name: "Predefined server error code",
+			args: args{
+				code:    RequestTimeout,
+				message: "Too slow",
+			},
+			wantErr: true,
+		},
 	}
 
 	for _, tt := range tests {
@@ -348,7 +392,7 @@ func TestNewErrorResponse(t *testing.T) {
 			name: "Valid parameters",
 			args: args{
 				id:      1,
-				code:    -32000,
+				code:    -32050,
 				message: "Database error",
 				data: struct {
 					ServerName     string `json:"server-name"`
@@ -358,7 +402,7 @@ func TestNewErrorResponse(t *testing.T) {
 					ServerProtocol: "http",
 				},
 			},
-			want: []byte(`{"jsonrpc":"2.0","error":{"code":-32000,"message":"Database error","data":{"server-name":"example.com","server-protocol":"http"}},"id":1}` + "\n"),
+			want: []byte(`{"jsonrpc":"2.0","error":{"code":-32050,"message":"Database error","data":{"server-name":"example.com","server-protocol":"http"}},"id":1}` + "\n"),
 		},
 		{
 			name: "Invalid parameters",
@@ -441,3 +485,98 @@ func TestNewResultResponse(t *testing.T) {
 		})
 	}
 }
+
+func TestResponse_Inspection(t *testing.T) {
+	tests := []struct {
+		name          string
+		responseRaw   string
+		wantIsError   bool
+		wantErr       error
+		wantID        any
+		wantRawResult json.RawMessage
+	}{
+		{
+			name:          "Result response",
+			responseRaw:   `{"jsonrpc": "2.0", "result": {"sum": 19}, "id": "abc"}`,
+			wantID:        "abc",
+			wantRawResult: json.RawMessage(`{"sum": 19}`),
+		},
+		{
+			name:        "Error response",
+			responseRaw: `{"jsonrpc": "2.0", "error": {"code": -32601, "message": "Method not found"}, "id": 1}`,
+			wantIsError: true,
+			wantErr:     ErrMethodNotFound,
+			wantID:      float64(1),
+		},
+		{
+			name:        "Error response with null id",
+			responseRaw: `{"jsonrpc": "2.0", "error": {"code": -32700, "message": "Parse error"}, "id": null}`,
+			wantIsError: true,
+			wantErr:     ErrParseError,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			response, err := ParseResponse([]byte(tt.responseRaw))
+			if err != nil {
+				t.Fatal(err)
+			}
+			if got := response.IsError(); got != tt.wantIsError {
+				t.Errorf("IsError() = %v, want %v", got, tt.wantIsError)
+			}
+			if err := response.Err(); tt.wantErr == nil && err != nil || !errors.Is(err, tt.wantErr) {
+				t.Errorf("Err() = %v, want %v", err, tt.wantErr)
+			}
+			if got := response.GetID(); got != tt.wantID {
+				t.Errorf("GetID() = %v, want %v", got, tt.wantID)
+			}
+			if got := response.RawResult(); !bytes.Equal(got, tt.wantRawResult) {
+				t.Errorf("RawResult() = %s, want %s", got, tt.wantRawResult)
+			}
+		})
+	}
+}
+
+func TestRequest_NewErrorResponse(t *testing.T) {
+	tests := []struct {
+		name       string
+		requestRaw string
+		jsonError  *jsonRPCError
+		want       []byte
+		wantErr    bool
+	}{
+		{
+			name:       "Number id",
+			requestRaw: `{"jsonrpc": "2.0", "method": "subtract", "params": [42], "id": 1}`,
+			jsonError:  &JsonInvalidMethodParameters,
+			want:       []byte(`{"jsonrpc":"2.0","error":{"code":-32602,"message":"Invalid method parameters"},"id":1}` + "\n"),
+		},
+		{
+			name:       "String id",
+			requestRaw: `{"jsonrpc": "2.0", "method": "foobar", "id": "abc"}`,
+			jsonError:  &JsonMethodNotFound,
+			want:       []byte(`{"jsonrpc":"2.0","error":{"code":-32601,"message":"Method not found"},"id":"abc"}` + "\n"),
+		},
+		{
+			name:       "No JSON-RPC error",
+			requestRaw: `{"jsonrpc": "2.0", "method": "foobar", "id": 1}`,
+			wantErr:    true,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			jsonRPCRequest, jsonRPCError := ParseRequest([]byte(tt.requestRaw))
+			if jsonRPCError != nil {
+				t.Fatal(jsonRPCError)
+			}
+			got, err := jsonRPCRequest.NewErrorResponse(tt.jsonError)
+			if (err != nil) != tt.wantErr {
+				t.Errorf("NewErrorResponse() error = %v, wantErr %v", err, tt.wantErr)
+				return
+			}
+			if !bytes.Equal(got, tt.want) {
+				t.Errorf("NewErrorResponse() = %s, want %s", got, tt.want)
+			}
+		})
+	}
+}
@@ -0,0 +1,273 @@
+/*  Copyright 2022  Kosmas Valianos (kosmas.valianos@gmail.com)
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    https://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License. */
+
+package gojsonrpc
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"strings"
+)
+
+// GRPCCode is a gRPC status code, convertible to and from the codes.Code of grpc-go
+type GRPCCode uint32
+
+// Const gRPC status codes
+const (
+	GRPCOK GRPCCode = iota
+	GRPCCanceled
+	GRPCUnknown
+	GRPCInvalidArgument
+	GRPCDeadlineExceeded
+	GRPCNotFound
+	GRPCAlreadyExists
+	GRPCPermissionDenied
+	GRPCResourceExhausted
+	GRPCFailedPrecondition
+	GRPCAborted
+	GRPCOutOfRange
+	GRPCUnimplemented
+	GRPCInternal
+	GRPCUnavailable
+	GRPCDataLoss
+	GRPCUnauthenticated
+)
+
+var grpcCodeNames = []string{
+	"OK", "Canceled", "Unknown", "InvalidArgument", "DeadlineExceeded", "NotFound", "AlreadyExists",
+	"PermissionDenied", "ResourceExhausted", "FailedPrecondition", "Aborted", "OutOfRange", "Unimplemented",
+	"Internal", "Unavailable", "DataLoss", "Unauthenticated",
+}
+
+// String implements String() of fmt.Stringer interface
+func (c GRPCCode) String() string {
+	if int(c) < len(grpcCodeNames) {
+		return grpcCodeNames[c]
+	}
+	return fmt.Sprintf("Code(%d)", uint32(c))
+}
+
+// GRPCError is the status of a failed gRPC call, convertible to and from the *status.Status of grpc-go:
+//
+//	status.Error(codes.Code(grpcError.Code), grpcError.Message)
+//	&gojsonrpc.GRPCError{Code: gojsonrpc.GRPCCode(status.Code(err)), Message: status.Convert(err).Message()}
+type GRPCError struct {
+	Code    GRPCCode
+	Message string
+}
+
+// Error implements Error() of error interface
+func (e *GRPCError) Error() string {
+	return fmt.Sprintf("rpc error: code = %v desc = %v", e.Code, e.Message)
+}
+
+// grpcErrorData is the data of the JSON-RPC errors translated from gRPC errors, so that they translate back losslessly
+type grpcErrorData struct {
+	GRPCCode    GRPCCode `json:"grpcCode"`
+	GRPCMessage string   `json:"grpcMessage,omitempty"`
+}
+
+// jsonRPCCodes are the JSON-RPC errors of the gRPC codes, the others being translated into JsonInternalError
+var jsonRPCCodes = map[GRPCCode]*jsonRPCError{
+	GRPCCanceled:           &JsonRequestCancelled,
+	GRPCInvalidArgument:    &JsonInvalidMethodParameters,
+	GRPCOutOfRange:         &JsonInvalidMethodParameters,
+	GRPCDeadlineExceeded:   &JsonRequestTimeout,
+	GRPCUnimplemented:      &JsonMethodNotFound,
+	GRPCResourceExhausted:  &JsonServerBusy,
+	GRPCUnavailable:        &JsonUpstreamUnavailable,
+	GRPCFailedPrecondition: &JsonServerNotInitialized,
+}
+
+// grpcCodes are the gRPC codes of the JSON-RPC error codes, the others being translated into GRPCUnknown
+var grpcCodes = map[int]GRPCCode{
+	ParseError:              GRPCInvalidArgument,
+	InvalidRequest:          GRPCInvalidArgument,
+	InvalidMethodParameters: GRPCInvalidArgument,
+	MethodNotFound:          GRPCUnimplemented,
+	InternalError:           GRPCInternal,
+	RequestTimeout:          GRPCDeadlineExceeded,
+	ServerBusy:              GRPCResourceExhausted,
+	UpstreamUnavailable:     GRPCUnavailable,
+	ServerNotInitialized:    GRPCFailedPrecondition,
+	RequestCancelled:        GRPCCanceled,
+}
+
+// JsonRPCError translates the gRPC error into a JSON-RPC error, e.g. InvalidArgument into JsonInvalidMethodParameters,
+// Unimplemented into JsonMethodNotFound or DeadlineExceeded into JsonRequestTimeout, the codes without an equivalent
+// becoming JsonInternalError. The gRPC code and message are kept in the data so that NewGRPCError translates it back.
+// Returns a *jsonRPCError object
+func (e *GRPCError) JsonRPCError() *jsonRPCError {
+	jsonRPCError, ok := jsonRPCCodes[e.Code]
+	if !ok {
+		jsonRPCError = &JsonInternalError
+	}
+	translated, err := jsonRPCError.AddData(grpcErrorData{GRPCCode: e.Code, GRPCMessage: e.Message})
+	if err != nil {
+		return jsonRPCError.WithCause(e)
+	}
+	translated.cause = e
+	return translated
+}
+
+// NewGRPCError translates the error into a gRPC error: the JSON-RPC error in its chain by its code, e.g.
+// JsonMethodNotFound into Unimplemented, or by the gRPC code in its data if it was translated from a gRPC error,
+// a context error into Canceled or DeadlineExceeded and any other error into Unknown.
+// Returns a *GRPCError object or nil if err is nil
+func NewGRPCError(err error) *GRPCError {
+	if err == nil {
+		return nil
+	}
+	var grpcError *GRPCError
+	if errors.As(err, &grpcError) {
+		return grpcError
+	}
+	jsonRPCError, ok := AsJsonRPCError(err)
+	switch {
+	case ok && jsonRPCError != nil:
+		var data grpcErrorData
+		if jsonRPCError.UnmarshalData(&data) == nil && data.GRPCCode != GRPCOK {
+			return &GRPCError{Code: data.GRPCCode, Message: data.GRPCMessage}
+		}
+		code, ok := grpcCodes[jsonRPCError.Code]
+		if !ok {
+			code = GRPCUnknown
+		}
+		return &GRPCError{Code: code, Message: jsonRPCError.Message}
+	case errors.Is(err, context.Canceled):
+		return &GRPCError{Code: GRPCCanceled, Message: err.Error()}
+	case errors.Is(err, context.DeadlineExceeded), errors.Is(err, ErrCallTimeout):
+		return &GRPCError{Code: GRPCDeadlineExceeded, Message: err.Error()}
+	default:
+		return &GRPCError{Code: GRPCUnknown, Message: err.Error()}
+	}
+}
+
+// GRPCMethodName maps the full name of a gRPC method, e.g. "/users.UserService/Create", onto a JSON-RPC method
+type GRPCMethodName func(fullMethod string) string
+
+// DefaultGRPCMethodName maps "/package.Service/Method" onto "package.Service.Method"
+func DefaultGRPCMethodName(fullMethod string) string {
+	return strings.Replace(strings.TrimPrefix(fullMethod, "/"), "/", ".", 1)
+}
+
+// GRPCBridge exposes the methods of a Mux as a generic gRPC service whose messages are JSON, the params and the result
+// of the methods. It has no dependency on grpc-go: its Invoke serves the unary calls received by the handler of the
+// unknown services of a grpc.Server using a codec passing the messages as they are:
+//
+//	grpc.NewServer(grpc.ForceServerCodec(rawCodec{}), grpc.UnknownServiceHandler(func(srv any, stream grpc.ServerStream) error {
+//		fullMethod, _ := grpc.MethodFromServerStream(stream)
+//		var payload []byte
+//		if err := stream.RecvMsg(&payload); err != nil {
+//			return err
+//		}
+//		reply, grpcError := bridge.Invoke(stream.Context(), fullMethod, payload)
+//		if grpcError != nil {
+//			return status.Error(codes.Code(grpcError.Code), grpcError.Message)
+//		}
+//		return stream.SendMsg(reply)
+//	}))
+//
+// It is safe for concurrent use
+type GRPCBridge struct {
+	mux         *Mux
+	methodName  GRPCMethodName
+	idGenerator IncrementingIDGenerator
+}
+
+// GRPCBridgeOption configures a GRPCBridge
+type GRPCBridgeOption func(*GRPCBridge)
+
+// WithGRPCMethodName sets the mapping of the gRPC methods onto the JSON-RPC methods, DefaultGRPCMethodName by default
+func WithGRPCMethodName(methodName GRPCMethodName) GRPCBridgeOption {
+	return func(b *GRPCBridge) {
+		if methodName != nil {
+			b.methodName = methodName
+		}
+	}
+}
+
+// NewGRPCBridge creates a GRPCBridge serving the gRPC calls with the mux, configured by the options.
+// Returns a *GRPCBridge object
+func NewGRPCBridge(mux *Mux, options ...GRPCBridgeOption) *GRPCBridge {
+	bridge := &GRPCBridge{mux: mux, methodName: DefaultGRPCMethodName}
+	for _, option := range options {
+		option(bridge)
+	}
+	return bridge
+}
+
+// Invoke serves a unary gRPC call with the method its full name maps onto, the JSON payload being the params.
+// An empty payload means no params.
+// Returns the JSON of the result or a *GRPCError object translated from the error object of the response
+func (b *GRPCBridge) Invoke(ctx context.Context, fullMethod string, payload []byte) ([]byte, *GRPCError) {
+	var params any
+	if len(payload) > 0 {
+		if !json.Valid(payload) {
+			return nil, &GRPCError{Code: GRPCInvalidArgument, Message: "payload is not valid JSON"}
+		}
+		params = json.RawMessage(payload)
+	}
+	requestRaw, err := newRequestWithID(b.methodName(fullMethod), params, b.idGenerator.NextID())
+	if err != nil {
+		return nil, &GRPCError{Code: GRPCInvalidArgument, Message: err.Error()}
+	}
+	response, err := ParseResponseZeroCopy(b.mux.Serve(ctx, requestRaw))
+	if err != nil {
+		return nil, &GRPCError{Code: GRPCInternal, Message: err.Error()}
+	}
+	if response.IsError() {
+		return nil, NewGRPCError(response.Error)
+	}
+	return response.RawResult(), nil
+}
+
+// GRPCInvoker makes unary calls to a gRPC backend whose messages are JSON. The *grpc.ClientConn of grpc-go provides
+// Invoke and only needs to be wrapped, with a codec passing the messages as they are and the errors translated:
+//
+//	func (c grpcConn) Invoke(ctx context.Context, fullMethod string, payload []byte) ([]byte, error) {
+//		var reply []byte
+//		err := c.ClientConn.Invoke(ctx, fullMethod, payload, &reply, grpc.ForceCodec(rawCodec{}))
+//		if s, ok := status.FromError(err); ok && err != nil {
+//			return nil, &gojsonrpc.GRPCError{Code: gojsonrpc.GRPCCode(s.Code()), Message: s.Message()}
+//		}
+//		return reply, err
+//	}
+type GRPCInvoker interface {
+	// Invoke calls the gRPC method with the JSON payload and returns the JSON of the reply
+	Invoke(ctx context.Context, fullMethod string, payload []byte) ([]byte, error)
+}
+
+// GRPCHandler returns a Handler calling the gRPC method of the backend with the params as the payload, e.g. registered
+// with Mux.Handle to serve a JSON-RPC method from a gRPC backend. The reply is the result and the *GRPCError objects
+// are translated into JSON-RPC errors by their code, see GRPCError.JsonRPCError. Other errors are answered with
+// JsonUpstreamUnavailable, or JsonRequestTimeout if the call timed out, with the error as cause
+func GRPCHandler(invoker GRPCInvoker, fullMethod string) Handler {
+	return HandlerFunc(func(ctx context.Context, params json.RawMessage) (any, error) {
+		reply, err := invoker.Invoke(ctx, fullMethod, params)
+		var grpcError *GRPCError
+		switch {
+		case errors.As(err, &grpcError):
+			return nil, grpcError.JsonRPCError()
+		case err != nil:
+			return nil, upstreamError(ctx, err)
+		case len(reply) == 0:
+			return nil, nil
+		default:
+			return json.RawMessage(reply), nil
+		}
+	})
+}
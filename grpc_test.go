@@ -0,0 +1,186 @@
+/*  Copyright 2022  Kosmas Valianos (kosmas.valianos@gmail.com)
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    https://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License. */
+
+package gojsonrpc
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+func TestGRPCError_JsonRPCError(t *testing.T) {
+	tests := []struct {
+		name      string
+		grpcError *GRPCError
+		wantCode  int
+	}{
+		{name: "InvalidArgument", grpcError: &GRPCError{Code: GRPCInvalidArgument, Message: "bad id"}, wantCode: InvalidMethodParameters},
+		{name: "Unimplemented", grpcError: &GRPCError{Code: GRPCUnimplemented}, wantCode: MethodNotFound},
+		{name: "DeadlineExceeded", grpcError: &GRPCError{Code: GRPCDeadlineExceeded}, wantCode: RequestTimeout},
+		{name: "Canceled", grpcError: &GRPCError{Code: GRPCCanceled}, wantCode: RequestCancelled},
+		{name: "Unavailable", grpcError: &GRPCError{Code: GRPCUnavailable}, wantCode: UpstreamUnavailable},
+		{name: "No equivalent", grpcError: &GRPCError{Code: GRPCPermissionDenied, Message: "denied"}, wantCode: InternalError},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			translated := tt.grpcError.JsonRPCError()
+			if translated.Code != tt.wantCode {
+				t.Errorf("JsonRPCError().Code = %v, want %v", translated.Code, tt.wantCode)
+			}
+			if !errors.Is(translated, tt.grpcError) {
+				t.Errorf("JsonRPCError() does not wrap %v", tt.grpcError)
+			}
+			// The data survives the wire and translates back losslessly
+			wire, err := json.Marshal(translated)
+			if err != nil {
+				t.Fatal(err)
+			}
+			var received jsonRPCError
+			if err := json.Unmarshal(wire, &received); err != nil {
+				t.Fatal(err)
+			}
+			if got := NewGRPCError(&received); !reflect.DeepEqual(got, tt.grpcError) {
+				t.Errorf("NewGRPCError() = %v, want %v", got, tt.grpcError)
+			}
+		})
+	}
+}
+
+func TestNewGRPCError(t *testing.T) {
+	tests := []struct {
+		name string
+		err  error
+		want *GRPCError
+	}{
+		{name: "No error", err: nil, want: nil},
+		{name: "Method not found", err: &JsonMethodNotFound, want: &GRPCError{Code: GRPCUnimplemented, Message: "Method not found"}},
+		{name: "Invalid params", err: &JsonInvalidMethodParameters, want: &GRPCError{Code: GRPCInvalidArgument, Message: "Invalid method parameters"}},
+		{name: "Server busy", err: &JsonServerBusy, want: &GRPCError{Code: GRPCResourceExhausted, Message: "Server busy"}},
+		{name: "Custom code", err: &jsonRPCError{Code: 1001, Message: "Quota exceeded"}, want: &GRPCError{Code: GRPCUnknown, Message: "Quota exceeded"}},
+		{name: "Context canceled", err: context.Canceled, want: &GRPCError{Code: GRPCCanceled, Message: "context canceled"}},
+		{name: "Call timeout", err: ErrCallTimeout, want: &GRPCError{Code: GRPCDeadlineExceeded, Message: "call timeout"}},
+		{name: "Other error", err: errors.New("boom"), want: &GRPCError{Code: GRPCUnknown, Message: "boom"}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := NewGRPCError(tt.err); !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("NewGRPCError() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestGRPCBridge_Invoke(t *testing.T) {
+	mux := NewMux()
+	if err := mux.Mount("calculator", newTestMux(t)); err != nil {
+		t.Fatal(err)
+	}
+	bridge := NewGRPCBridge(mux, WithGRPCMethodName(func(fullMethod string) string {
+		return "calculator." + fullMethod[strings.LastIndex(fullMethod, "/")+1:]
+	}))
+
+	tests := []struct {
+		name       string
+		fullMethod string
+		payload    string
+		wantReply  string
+		wantError  *GRPCError
+	}{
+		{name: "Call", fullMethod: "/calculator.Calculator/subtract", payload: `[42, 23]`, wantReply: `19`},
+		{name: "Invalid params", fullMethod: "/calculator.Calculator/database", wantError: &GRPCError{Code: GRPCInvalidArgument, Message: "Invalid method parameters"}},
+		{name: "Unknown method", fullMethod: "/calculator.Calculator/multiply", wantError: &GRPCError{Code: GRPCUnimplemented, Message: "Method not found"}},
+		{name: "Invalid payload", fullMethod: "/calculator.Calculator/subtract", payload: `[42,`, wantError: &GRPCError{Code: GRPCInvalidArgument, Message: "payload is not valid JSON"}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			reply, grpcError := bridge.Invoke(context.Background(), tt.fullMethod, []byte(tt.payload))
+			if string(reply) != tt.wantReply {
+				t.Errorf("Invoke() reply = %s, want %s", reply, tt.wantReply)
+			}
+			if !reflect.DeepEqual(grpcError, tt.wantError) {
+				t.Errorf("Invoke() error = %v, want %v", grpcError, tt.wantError)
+			}
+		})
+	}
+}
+
+func TestDefaultGRPCMethodName(t *testing.T) {
+	if got, want := DefaultGRPCMethodName("/users.UserService/Create"), "users.UserService.Create"; got != want {
+		t.Errorf("DefaultGRPCMethodName() = %v, want %v", got, want)
+	}
+}
+
+// grpcInvokerFunc is an adapter to allow the use of ordinary functions as a GRPCInvoker
+type grpcInvokerFunc func(ctx context.Context, fullMethod string, payload []byte) ([]byte, error)
+
+func (f grpcInvokerFunc) Invoke(ctx context.Context, fullMethod string, payload []byte) ([]byte, error) {
+	return f(ctx, fullMethod, payload)
+}
+
+func TestGRPCHandler(t *testing.T) {
+	backend := grpcInvokerFunc(func(ctx context.Context, fullMethod string, payload []byte) ([]byte, error) {
+		switch fullMethod {
+		case "/users.UserService/Get":
+			return []byte(`{"id":7,"echo":` + string(payload) + `}`), nil
+		case "/users.UserService/Delete":
+			return nil, &GRPCError{Code: GRPCPermissionDenied, Message: "denied"}
+		default:
+			return nil, errors.New("connection refused")
+		}
+	})
+	mux := NewMux()
+	for method, fullMethod := range map[string]string{
+		"user.get":    "/users.UserService/Get",
+		"user.delete": "/users.UserService/Delete",
+		"user.list":   "/users.UserService/List",
+	} {
+		if err := mux.Handle(method, GRPCHandler(backend, fullMethod)); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	tests := []struct {
+		name     string
+		request  string
+		expected string
+	}{
+		{
+			name:     "Reply",
+			request:  `{"jsonrpc": "2.0", "method": "user.get", "params": {"id": 7}, "id": 1}`,
+			expected: `{"jsonrpc":"2.0","result":{"id":7,"echo":{"id":7}},"id":1}`,
+		},
+		{
+			name:     "gRPC error",
+			request:  `{"jsonrpc": "2.0", "method": "user.delete", "params": {"id": 7}, "id": 2}`,
+			expected: `{"jsonrpc":"2.0","error":{"code":-32603,"message":"Internal error","data":{"grpcCode":7,"grpcMessage":"denied"}},"id":2}`,
+		},
+		{
+			name:     "Backend down",
+			request:  `{"jsonrpc": "2.0", "method": "user.list", "id": 3}`,
+			expected: `{"jsonrpc":"2.0","error":{"code":-32004,"message":"Upstream unavailable"},"id":3}`,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			responseRaw := mux.Serve(context.Background(), []byte(tt.request))
+			if got := strings.TrimSpace(string(responseRaw)); got != tt.expected {
+				t.Errorf("Serve() = %v, want %v", got, tt.expected)
+			}
+		})
+	}
+}
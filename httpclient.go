@@ -0,0 +1,253 @@
+/*  Copyright 2022  Kosmas Valianos (kosmas.valianos@gmail.com)
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    https://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License. */
+
+package gojsonrpc
+
+import (
+	"bytes"
+	"context"
+	"crypto/tls"
+	"encoding/base64"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+)
+
+// HTTPTransport is a Transport posting the messages to a JSON-RPC over HTTP endpoint.
+// Its http.Client keeps the connections alive and reuses them. It is safe for concurrent use
+type HTTPTransport struct {
+	url         string
+	client      *http.Client
+	header      http.Header
+	idempotent  func(method string) bool
+	compression bool
+	tlsConfig   *tls.Config
+	codec       Codec
+	maxBodySize int64
+}
+
+// HTTPTransportOption configures an HTTPTransport
+type HTTPTransportOption func(*HTTPTransport)
+
+// WithHTTPClient sets the http.Client posting the messages, e.g. to configure its timeouts and its connection pool
+func WithHTTPClient(client *http.Client) HTTPTransportOption {
+	return func(t *HTTPTransport) {
+		if client != nil {
+			t.client = client
+		}
+	}
+}
+
+// WithHTTPHeader adds a header to the posted messages, e.g. for authorization
+func WithHTTPHeader(key, value string) HTTPTransportOption {
+	return func(t *HTTPTransport) {
+		t.header.Add(key, value)
+	}
+}
+
+// WithHTTPTLS configures the TLS of the connections to https:// urls, e.g. with a client certificate for mutual TLS
+// or the pool of the CAs of a private server. It applies to the http.Client given by WithHTTPClient too
+func WithHTTPTLS(config *tls.Config) HTTPTransportOption {
+	return func(t *HTTPTransport) {
+		t.tlsConfig = config
+	}
+}
+
+// WithHTTPMaxResponseSize sets the size limit in bytes of the body of the HTTP responses once decompressed, 1MB by default.
+// Larger responses fail with an error wrapping ErrMessageTooLarge. A zero or negative size restores the default
+func WithHTTPMaxResponseSize(size int64) HTTPTransportOption {
+	return func(t *HTTPTransport) {
+		if size <= 0 {
+			size = defaultMaxBodySize
+		}
+		t.maxBodySize = size
+	}
+}
+
+// WithHTTPGet sends the requests of the methods reported idempotent by the function with the HTTP GET binding, so that
+// their responses may be cached. The members of the request are given in the query string, the params encoded in base64.
+// The batches are always posted
+func WithHTTPGet(idempotent func(method string) bool) HTTPTransportOption {
+	return func(t *HTTPTransport) {
+		t.idempotent = idempotent
+	}
+}
+
+// NewHTTPTransport creates an HTTPTransport posting the messages to the url configured by the options.
+// Returns a *HTTPTransport object
+func NewHTTPTransport(url string, options ...HTTPTransportOption) *HTTPTransport {
+	pooled := http.DefaultTransport.(*http.Transport).Clone()
+	pooled.MaxIdleConnsPerHost = 16
+	transport := &HTTPTransport{
+		url:         url,
+		client:      &http.Client{Transport: pooled},
+		header:      make(http.Header),
+		codec:       JSONCodec,
+		maxBodySize: defaultMaxBodySize,
+	}
+	for _, option := range options {
+		option(transport)
+	}
+	if transport.tlsConfig != nil {
+		transport.client = withTLSConfig(transport.client, transport.tlsConfig)
+	}
+	return transport
+}
+
+// withTLSConfig returns a copy of the client whose transport uses the TLS config
+func withTLSConfig(client *http.Client, config *tls.Config) *http.Client {
+	roundTripper, ok := client.Transport.(*http.Transport)
+	if client.Transport == nil {
+		roundTripper, ok = http.DefaultTransport.(*http.Transport)
+	}
+	if !ok {
+		return client
+	}
+	roundTripper = roundTripper.Clone()
+	roundTripper.TLSClientConfig = config
+	configured := *client
+	configured.Transport = roundTripper
+	return &configured
+}
+
+// RoundTrip posts a request or a batch. Error objects are answered with a JSON-RPC response whatever the HTTP status code.
+// Returns the raw bytes of the response or an error if the HTTP status code has no JSON-RPC response
+func (t *HTTPTransport) RoundTrip(ctx context.Context, requestRaw []byte) ([]byte, error) {
+	var (
+		statusCode  int
+		responseRaw []byte
+		err         error
+	)
+	if getURL, ok := t.getURL(requestRaw); ok {
+		statusCode, responseRaw, err = t.do(ctx, http.MethodGet, getURL, nil)
+	} else {
+		statusCode, responseRaw, err = t.post(ctx, requestRaw)
+	}
+	if err != nil {
+		return nil, err
+	}
+	if len(bytes.TrimSpace(responseRaw)) == 0 || !json.Valid(responseRaw) {
+		return nil, fmt.Errorf("HTTP status %v without a JSON-RPC response", statusCode)
+	}
+	return responseRaw, nil
+}
+
+// Send posts a notification, which is answered with HTTP status 204 No Content or 200 OK with no body
+func (t *HTTPTransport) Send(ctx context.Context, notificationRaw []byte) error {
+	statusCode, _, err := t.post(ctx, notificationRaw)
+	if err != nil {
+		return err
+	}
+	if statusCode < 200 || statusCode > 299 {
+		return fmt.Errorf("HTTP status %v", statusCode)
+	}
+	return nil
+}
+
+// getURL returns the URL of a request of an idempotent method with the HTTP GET binding
+func (t *HTTPTransport) getURL(requestRaw []byte) (string, bool) {
+	if t.idempotent == nil || jsonKind(requestRaw) != '{' {
+		return "", false
+	}
+	var request queryEnvelope
+	err := json.Unmarshal(requestRaw, &request)
+	if err != nil || !t.idempotent(request.Method) {
+		return "", false
+	}
+	getURL, err := url.Parse(t.url)
+	if err != nil {
+		return "", false
+	}
+	query := getURL.Query()
+	query.Set("jsonrpc", request.JSONRPC)
+	query.Set("method", request.Method)
+	if len(request.Params) > 0 {
+		query.Set("params", base64.URLEncoding.EncodeToString(request.Params))
+	}
+	if len(request.ID) > 0 {
+		query.Set("id", string(request.ID))
+	}
+	getURL.RawQuery = query.Encode()
+	return getURL.String(), true
+}
+
+// post posts a message and reads the whole body of the answer so that the connection is reused.
+// Returns the HTTP status code and the body or an error
+func (t *HTTPTransport) post(ctx context.Context, messageRaw []byte) (int, []byte, error) {
+	return t.do(ctx, http.MethodPost, t.url, messageRaw)
+}
+
+// do sends an HTTP request with the message as its body, if any, and reads the whole body of the answer up to the size limit.
+// Returns the HTTP status code and the body or an error
+func (t *HTTPTransport) do(ctx context.Context, method string, target string, messageRaw []byte) (int, []byte, error) {
+	if messageRaw != nil {
+		var err error
+		messageRaw, err = t.codec.Marshal(messageRaw)
+		if err != nil {
+			return 0, nil, err
+		}
+	}
+	compressed := t.compression && len(messageRaw) >= compressMinSize
+	if compressed {
+		messageRaw = gzipMessage(messageRaw)
+	}
+	var messageReader io.Reader
+	if messageRaw != nil {
+		messageReader = bytes.NewReader(messageRaw)
+	}
+	httpRequest, err := http.NewRequestWithContext(ctx, method, target, messageReader)
+	if err != nil {
+		return 0, nil, err
+	}
+	for key, values := range t.header {
+		httpRequest.Header[key] = values
+	}
+	if messageRaw != nil {
+		httpRequest.Header.Set("Content-Type", t.codec.ContentType())
+	}
+	if compressed {
+		httpRequest.Header.Set("Content-Encoding", "gzip")
+	}
+	if t.compression {
+		// Decompressed below, as the http.Client only does it when it asks for gzip itself
+		httpRequest.Header.Set("Accept-Encoding", "gzip")
+	}
+	httpRequest.Header.Set("Accept", t.codec.ContentType())
+
+	httpResponse, err := t.client.Do(httpRequest)
+	if err != nil {
+		return 0, nil, err
+	}
+	defer httpResponse.Body.Close()
+	decoded, err := decodedBody(httpResponse.Header, httpResponse.Body)
+	if err != nil {
+		return 0, nil, err
+	}
+	body, err := io.ReadAll(io.LimitReader(decoded, t.maxBodySize+1))
+	if err != nil {
+		return 0, nil, err
+	}
+	if int64(len(body)) > t.maxBodySize {
+		return 0, nil, fmt.Errorf("HTTP response: %w", ErrMessageTooLarge)
+	}
+	if len(body) > 0 && hasContentType(httpResponse.Header.Get("Content-Type"), t.codec) {
+		body, err = t.codec.Unmarshal(body)
+		if err != nil {
+			return 0, nil, err
+		}
+	}
+	return httpResponse.StatusCode, body, nil
+}
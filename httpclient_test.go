@@ -0,0 +1,114 @@
+/*  Copyright 2022  Kosmas Valianos (kosmas.valianos@gmail.com)
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    https://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License. */
+
+package gojsonrpc
+
+import (
+	"context"
+	"errors"
+	"io"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync/atomic"
+	"testing"
+)
+
+func newTestHTTPServer(t *testing.T, mux *Mux) (*httptest.Server, *atomic.Int64) {
+	var connections atomic.Int64
+	server := httptest.NewUnstartedServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.Header.Get("Authorization") != "Bearer token" {
+			w.WriteHeader(http.StatusUnauthorized)
+			return
+		}
+		body, _ := io.ReadAll(r.Body)
+		responseRaw := mux.Serve(r.Context(), body)
+		if responseRaw == nil {
+			w.WriteHeader(http.StatusNoContent)
+			return
+		}
+		w.Header().Set("Content-Type", "application/json")
+		if code, ok := responseErrorCode(responseRaw); ok && code == MethodNotFound {
+			w.WriteHeader(http.StatusNotFound)
+		}
+		w.Write(responseRaw)
+	}))
+	server.Config.ConnState = func(conn net.Conn, state http.ConnState) {
+		if state == http.StateNew {
+			connections.Add(1)
+		}
+	}
+	server.Start()
+	t.Cleanup(server.Close)
+	return server, &connections
+}
+
+func TestHTTPTransport(t *testing.T) {
+	server, connections := newTestHTTPServer(t, newTestMux(t))
+	client := NewClient(NewHTTPTransport(server.URL, WithHTTPHeader("Authorization", "Bearer token")))
+
+	for i := 0; i < 5; i++ {
+		result, err := Call[int](context.Background(), client, "subtract", []int{42, 23})
+		if err != nil || result != 19 {
+			t.Fatalf("Call() = %v, %v, want 19", result, err)
+		}
+	}
+	if got := connections.Load(); got != 1 {
+		t.Errorf("connections = %v, want 1 reused", got)
+	}
+
+	_, err := Call[int](context.Background(), client, "multiply", []int{42, 23})
+	if jsonRPCError, ok := AsJsonRPCError(err); !ok || jsonRPCError.Code != MethodNotFound {
+		t.Errorf("Call() error = %v, want %v", err, JsonMethodNotFound)
+	}
+
+	err = client.Notify(context.Background(), "subtract", []int{42, 23})
+	if err != nil {
+		t.Errorf("Notify() error = %v", err)
+	}
+
+	unauthorized := NewClient(NewHTTPTransport(server.URL))
+	if _, err := Call[int](context.Background(), unauthorized, "subtract", []int{42, 23}); err == nil {
+		t.Error("Call() without authorization succeeded")
+	}
+	if err := unauthorized.Notify(context.Background(), "subtract", []int{42, 23}); err == nil {
+		t.Error("Notify() without authorization succeeded")
+	}
+}
+
+func TestWithHTTPMaxResponseSize(t *testing.T) {
+	server := httptest.NewServer(HTTPHandler(newTestMux(t)))
+	defer server.Close()
+	large := []string{strings.Repeat("a", 2048)}
+
+	tests := []struct {
+		name    string
+		options []HTTPTransportOption
+	}{
+		{name: "Plain response", options: []HTTPTransportOption{WithHTTPMaxResponseSize(1024)}},
+		{name: "Compressed response", options: []HTTPTransportOption{WithHTTPMaxResponseSize(1024), WithHTTPCompression()}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			client := NewClient(NewHTTPTransport(server.URL, tt.options...))
+			if _, err := Call[[]string](context.Background(), client, "raw", large); !errors.Is(err, ErrMessageTooLarge) {
+				t.Errorf("Call() error = %v, want %v", err, ErrMessageTooLarge)
+			}
+			if difference, err := Call[int](context.Background(), client, "subtract", []int{42, 23}); err != nil || difference != 19 {
+				t.Errorf("Call() = %v, %v, want 19", difference, err)
+			}
+		})
+	}
+}
@@ -0,0 +1,276 @@
+/*  Copyright 2022  Kosmas Valianos (kosmas.valianos@gmail.com)
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    https://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License. */
+
+package gojsonrpc
+
+import (
+	"context"
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"io"
+	"mime"
+	"net/http"
+	"net/url"
+	"strings"
+)
+
+// defaultMaxBodySize is the default size limit of the body of the HTTP requests
+const defaultMaxBodySize = 1 << 20
+
+// httpHandler serves JSON-RPC over HTTP with a Mux
+type httpHandler struct {
+	mux         *Mux
+	maxBodySize int64
+	getAllowed  func(method string) bool
+	cors        *CORSConfig
+	events      *EventStream
+	codecs      map[string]Codec
+}
+
+// HTTPHandlerOption configures the http.Handler returned by HTTPHandler
+type HTTPHandlerOption func(*httpHandler)
+
+// WithMaxBodySize sets the size limit in bytes of the body of the HTTP requests, 1MB by default.
+// Larger requests are answered with HTTP status 413 Request Entity Too Large
+func WithMaxBodySize(size int64) HTTPHandlerOption {
+	return func(h *httpHandler) {
+		if size > 0 {
+			h.maxBodySize = size
+		}
+	}
+}
+
+// WithHTTPGetMethods enables the HTTP GET binding for the methods allowed by the function, which should only allow
+// the idempotent ones. The members of the request are then given in the query string: jsonrpc, method, params encoded
+// in base64 and id, a notification having none. Other methods are answered with HTTP status 405 Method Not Allowed
+func WithHTTPGetMethods(allowed func(method string) bool) HTTPHandlerOption {
+	return func(h *httpHandler) {
+		h.getAllowed = allowed
+	}
+}
+
+// HTTPHandler returns an http.Handler serving the JSON-RPC requests, notifications and batches posted
+// with Content-Type application/json with the mux, configured by the options. The bodies compressed with gzip or deflate
+// are decompressed and the responses of 1KB or more are compressed with gzip if the Accept-Encoding header allows it.
+// The HTTP status code of a response follows its error: 400 Bad Request for an invalid request, 404 Not Found for
+// an unknown method, 500 Internal Server Error for the other pre-defined and server errors, 200 OK otherwise.
+// Notifications and batches of notifications are answered with 204 No Content
+func HTTPHandler(mux *Mux, options ...HTTPHandlerOption) http.Handler {
+	handler := &httpHandler{mux: mux, maxBodySize: defaultMaxBodySize}
+	for _, option := range options {
+		option(handler)
+	}
+	return handler
+}
+
+// ServeHTTP serves a JSON-RPC over HTTP request
+func (h *httpHandler) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	if h.cors != nil && h.cors.handle(w, r, h.allowedMethods()) {
+		return
+	}
+	if r.Method == http.MethodGet && h.events != nil && acceptsEventStream(r.Header) {
+		h.events.serve(w, r)
+		return
+	}
+	if r.Method == http.MethodGet && h.getAllowed != nil {
+		h.serveGet(w, r)
+		return
+	}
+	if r.Method != http.MethodPost {
+		h.methodNotAllowed(w)
+		return
+	}
+	mediaType, _, err := mime.ParseMediaType(r.Header.Get("Content-Type"))
+	codec, ok := h.codec(mediaType)
+	if err != nil || !ok {
+		http.Error(w, http.StatusText(http.StatusUnsupportedMediaType), http.StatusUnsupportedMediaType)
+		return
+	}
+
+	// The limit applies to the decompressed body too
+	decoded, err := decodedBody(r.Header, http.MaxBytesReader(w, r.Body, h.maxBodySize))
+	if errors.Is(err, errUnsupportedEncoding) {
+		http.Error(w, http.StatusText(http.StatusUnsupportedMediaType), http.StatusUnsupportedMediaType)
+		return
+	}
+	if err != nil {
+		writeHTTPResponse(w, http.StatusBadRequest, codec, newNullIDErrorResponse(&JsonParseError))
+		return
+	}
+	body, err := io.ReadAll(http.MaxBytesReader(w, io.NopCloser(decoded), h.maxBodySize))
+	if err != nil {
+		var maxBytesErr *http.MaxBytesError
+		if errors.As(err, &maxBytesErr) {
+			writeHTTPResponse(w, http.StatusRequestEntityTooLarge, codec, newNullIDErrorResponse(&JsonInvalidRequest))
+			return
+		}
+		http.Error(w, http.StatusText(http.StatusBadRequest), http.StatusBadRequest)
+		return
+	}
+
+	messageRaw, err := codec.Unmarshal(body)
+	if err != nil {
+		writeHTTPResponse(w, http.StatusInternalServerError, codec, newNullIDErrorResponse(&JsonParseError))
+		return
+	}
+	h.serve(w, r, codec, messageRaw)
+}
+
+// codec returns the Codec of a media type
+func (h *httpHandler) codec(mediaType string) (Codec, bool) {
+	if mediaType == "application/json" {
+		return JSONCodec, true
+	}
+	codec, ok := h.codecs[strings.ToLower(mediaType)]
+	return codec, ok
+}
+
+// serveGet serves a request or a notification given in the query string
+func (h *httpHandler) serveGet(w http.ResponseWriter, r *http.Request) {
+	query := r.URL.Query()
+	if !h.getAllowed(query.Get("method")) {
+		h.methodNotAllowed(w)
+		return
+	}
+	messageRaw, err := queryMessage(query)
+	if err != nil {
+		writeHTTPResponse(w, http.StatusInternalServerError, JSONCodec, newNullIDErrorResponse(&JsonParseError))
+		return
+	}
+	h.serve(w, r, JSONCodec, messageRaw)
+}
+
+// serve serves a message and writes its response, if any, encoded with the codec
+func (h *httpHandler) serve(w http.ResponseWriter, r *http.Request, codec Codec, messageRaw []byte) {
+	ctx := r.Context()
+	if r.TLS != nil {
+		ctx = withPeerCertificate(ctx, r.TLS)
+	}
+	if h.events != nil {
+		if session, ok := h.events.Session(r.Header.Get(EventSessionHeader)); ok {
+			ctx = context.WithValue(ctx, eventSessionContextKey, session)
+		}
+	}
+	responseRaw := h.mux.Serve(ctx, messageRaw)
+	if responseRaw == nil {
+		w.WriteHeader(http.StatusNoContent)
+		return
+	}
+	statusCode := httpStatusCode(responseRaw)
+	body, err := codec.Marshal(responseRaw)
+	if err != nil {
+		http.Error(w, http.StatusText(http.StatusInternalServerError), http.StatusInternalServerError)
+		return
+	}
+	w.Header().Add("Vary", "Accept-Encoding")
+	if len(body) >= compressMinSize && acceptsEncoding(r.Header, "gzip") {
+		w.Header().Set("Content-Encoding", "gzip")
+		body = gzipMessage(body)
+	}
+	writeHTTPBody(w, statusCode, codec.ContentType(), body)
+}
+
+func (h *httpHandler) methodNotAllowed(w http.ResponseWriter) {
+	w.Header().Set("Allow", h.allowedMethods())
+	http.Error(w, http.StatusText(http.StatusMethodNotAllowed), http.StatusMethodNotAllowed)
+}
+
+// allowedMethods returns the HTTP methods served
+func (h *httpHandler) allowedMethods() string {
+	if h.getAllowed != nil || h.events != nil {
+		return http.MethodGet + ", " + http.MethodPost
+	}
+	return http.MethodPost
+}
+
+// queryEnvelope is a request or a notification given in a query string
+type queryEnvelope struct {
+	JSONRPC string          `json:"jsonrpc,omitempty"`
+	Method  string          `json:"method"`
+	Params  json.RawMessage `json:"params,omitempty"`
+	ID      json.RawMessage `json:"id,omitempty"`
+}
+
+// queryMessage builds the message given in a query string. The id is taken as JSON if valid, as a string otherwise.
+// Returns the raw bytes of the message or an error if the params are not valid base64 encoded JSON
+func queryMessage(query url.Values) ([]byte, error) {
+	envelope := queryEnvelope{JSONRPC: query.Get("jsonrpc"), Method: query.Get("method")}
+	if query.Has("params") {
+		paramsRaw, err := decodeQueryParams(query.Get("params"))
+		if err != nil {
+			return nil, err
+		}
+		if !json.Valid(paramsRaw) {
+			return nil, errors.New("params are not valid JSON")
+		}
+		envelope.Params = paramsRaw
+	}
+	if query.Has("id") {
+		id := query.Get("id")
+		if json.Valid([]byte(id)) {
+			envelope.ID = json.RawMessage(id)
+		} else {
+			envelope.ID, _ = json.Marshal(id)
+		}
+	}
+	return json.Marshal(envelope)
+}
+
+// decodeQueryParams decodes params encoded in standard or URL base64, padded or not
+func decodeQueryParams(params string) ([]byte, error) {
+	for _, encoding := range []*base64.Encoding{base64.StdEncoding, base64.URLEncoding, base64.RawStdEncoding, base64.RawURLEncoding} {
+		paramsRaw, err := encoding.DecodeString(params)
+		if err == nil {
+			return paramsRaw, nil
+		}
+	}
+	return nil, errors.New("params are not base64 encoded")
+}
+
+// httpStatusCode returns the HTTP status code of a response or a batch of responses, 200 OK for the latter
+func httpStatusCode(responseRaw []byte) int {
+	code, ok := responseErrorCode(responseRaw)
+	if !ok {
+		return http.StatusOK
+	}
+	switch {
+	case code == InvalidRequest:
+		return http.StatusBadRequest
+	case code == MethodNotFound:
+		return http.StatusNotFound
+	case code == ParseError, code == InvalidMethodParameters, code == InternalError,
+		code >= ServerErrorStart && code <= ServerErrorEnd:
+		return http.StatusInternalServerError
+	default:
+		return http.StatusOK
+	}
+}
+
+// writeHTTPResponse writes a response encoded with the codec
+func writeHTTPResponse(w http.ResponseWriter, statusCode int, codec Codec, responseRaw []byte) {
+	body, err := codec.Marshal(responseRaw)
+	if err != nil {
+		http.Error(w, http.StatusText(http.StatusInternalServerError), http.StatusInternalServerError)
+		return
+	}
+	writeHTTPBody(w, statusCode, codec.ContentType(), body)
+}
+
+// writeHTTPBody writes the body of a response of the content type
+func writeHTTPBody(w http.ResponseWriter, statusCode int, contentType string, body []byte) {
+	w.Header().Set("Content-Type", contentType)
+	w.WriteHeader(statusCode)
+	_, _ = w.Write(body)
+}
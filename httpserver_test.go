@@ -0,0 +1,251 @@
+/*  Copyright 2022  Kosmas Valianos (kosmas.valianos@gmail.com)
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    https://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License. */
+
+package gojsonrpc
+
+import (
+	"context"
+	"encoding/base64"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"strings"
+	"sync"
+	"testing"
+)
+
+func TestHTTPHandler(t *testing.T) {
+	server := httptest.NewServer(HTTPHandler(newTestMux(t), WithMaxBodySize(128)))
+	defer server.Close()
+
+	tests := []struct {
+		name           string
+		method         string
+		contentType    string
+		body           string
+		wantStatusCode int
+		wantBody       string
+	}{
+		{
+			name:           "Request",
+			method:         http.MethodPost,
+			contentType:    "application/json",
+			body:           `{"jsonrpc":"2.0","method":"subtract","params":[42,23],"id":1}`,
+			wantStatusCode: http.StatusOK,
+			wantBody:       `{"jsonrpc":"2.0","result":19,"id":1}`,
+		},
+		{
+			name:           "Content-Type with charset",
+			method:         http.MethodPost,
+			contentType:    "application/json; charset=utf-8",
+			body:           `{"jsonrpc":"2.0","method":"subtract","params":[42,23],"id":1}`,
+			wantStatusCode: http.StatusOK,
+			wantBody:       `{"jsonrpc":"2.0","result":19,"id":1}`,
+		},
+		{
+			name:           "Notification",
+			method:         http.MethodPost,
+			contentType:    "application/json",
+			body:           `{"jsonrpc":"2.0","method":"subtract","params":[42,23]}`,
+			wantStatusCode: http.StatusNoContent,
+		},
+		{
+			name:           "Batch",
+			method:         http.MethodPost,
+			contentType:    "application/json",
+			body:           `[{"jsonrpc":"2.0","method":"multiply","id":1}]`,
+			wantStatusCode: http.StatusOK,
+			wantBody:       `[{"jsonrpc":"2.0","error":{"code":-32601,"message":"Method not found"},"id":1}]`,
+		},
+		{
+			name:           "Parse error",
+			method:         http.MethodPost,
+			contentType:    "application/json",
+			body:           `{"jsonrpc":"2.0","method"`,
+			wantStatusCode: http.StatusInternalServerError,
+			wantBody:       `{"jsonrpc":"2.0","error":{"code":-32700,"message":"Parse error"},"id":null}`,
+		},
+		{
+			name:           "Invalid request",
+			method:         http.MethodPost,
+			contentType:    "application/json",
+			body:           `{"jsonrpc":"1.0","method":"subtract","id":1}`,
+			wantStatusCode: http.StatusBadRequest,
+		},
+		{
+			name:           "Method not found",
+			method:         http.MethodPost,
+			contentType:    "application/json",
+			body:           `{"jsonrpc":"2.0","method":"multiply","id":1}`,
+			wantStatusCode: http.StatusNotFound,
+			wantBody:       `{"jsonrpc":"2.0","error":{"code":-32601,"message":"Method not found"},"id":1}`,
+		},
+		{
+			name:           "Invalid method parameters",
+			method:         http.MethodPost,
+			contentType:    "application/json",
+			body:           `{"jsonrpc":"2.0","method":"database","id":1}`,
+			wantStatusCode: http.StatusInternalServerError,
+		},
+		{
+			name:           "Too large",
+			method:         http.MethodPost,
+			contentType:    "application/json",
+			body:           `{"jsonrpc":"2.0","method":"raw","params":["` + strings.Repeat("a", 128) + `"],"id":1}`,
+			wantStatusCode: http.StatusRequestEntityTooLarge,
+			wantBody:       `{"jsonrpc":"2.0","error":{"code":-32600,"message":"Invalid Request"},"id":null}`,
+		},
+		{
+			name:           "Unsupported Content-Type",
+			method:         http.MethodPost,
+			contentType:    "text/plain",
+			body:           `{"jsonrpc":"2.0","method":"subtract","params":[42,23],"id":1}`,
+			wantStatusCode: http.StatusUnsupportedMediaType,
+		},
+		{
+			name:           "Method not allowed",
+			method:         http.MethodPut,
+			contentType:    "application/json",
+			body:           `{"jsonrpc":"2.0","method":"subtract","params":[42,23],"id":1}`,
+			wantStatusCode: http.StatusMethodNotAllowed,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			request, err := http.NewRequest(tt.method, server.URL, strings.NewReader(tt.body))
+			if err != nil {
+				t.Fatal(err)
+			}
+			request.Header.Set("Content-Type", tt.contentType)
+			response, err := http.DefaultClient.Do(request)
+			if err != nil {
+				t.Fatal(err)
+			}
+			defer response.Body.Close()
+			body, _ := io.ReadAll(response.Body)
+			if response.StatusCode != tt.wantStatusCode {
+				t.Errorf("StatusCode = %v, want %v", response.StatusCode, tt.wantStatusCode)
+			}
+			if tt.wantBody != "" && strings.TrimSpace(string(body)) != tt.wantBody {
+				t.Errorf("body = %s, want %s", body, tt.wantBody)
+			}
+		})
+	}
+
+	t.Run("HTTPTransport", func(t *testing.T) {
+		result, err := Call[int](context.Background(), NewClient(NewHTTPTransport(server.URL)), "subtract", []int{42, 23})
+		if err != nil || result != 19 {
+			t.Errorf("Call() = %v, %v, want 19", result, err)
+		}
+	})
+}
+
+func TestHTTPHandler_Get(t *testing.T) {
+	var methods []string
+	var mu sync.Mutex
+	handler := HTTPHandler(newTestMux(t), WithHTTPGetMethods(func(method string) bool {
+		return method == "subtract"
+	}))
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		mu.Lock()
+		methods = append(methods, r.Method)
+		mu.Unlock()
+		handler.ServeHTTP(w, r)
+	}))
+	defer server.Close()
+
+	params := base64.StdEncoding.EncodeToString([]byte("[42,23]"))
+	tests := []struct {
+		name           string
+		query          string
+		wantStatusCode int
+		wantBody       string
+	}{
+		{
+			name:           "Request",
+			query:          "jsonrpc=2.0&method=subtract&params=" + url.QueryEscape(params) + "&id=1",
+			wantStatusCode: http.StatusOK,
+			wantBody:       `{"jsonrpc":"2.0","result":19,"id":1}`,
+		},
+		{
+			name:           "String id",
+			query:          "jsonrpc=2.0&method=subtract&params=" + url.QueryEscape(params) + "&id=abc",
+			wantStatusCode: http.StatusOK,
+			wantBody:       `{"jsonrpc":"2.0","result":19,"id":"abc"}`,
+		},
+		{
+			name:           "Notification",
+			query:          "jsonrpc=2.0&method=subtract&params=" + url.QueryEscape(params),
+			wantStatusCode: http.StatusNoContent,
+		},
+		{
+			name:           "Params not base64",
+			query:          "jsonrpc=2.0&method=subtract&params=[42,23]&id=1",
+			wantStatusCode: http.StatusInternalServerError,
+			wantBody:       `{"jsonrpc":"2.0","error":{"code":-32700,"message":"Parse error"},"id":null}`,
+		},
+		{
+			name:           "Missing jsonrpc",
+			query:          "method=subtract&params=" + url.QueryEscape(params) + "&id=1",
+			wantStatusCode: http.StatusBadRequest,
+		},
+		{
+			name:           "Method not allowed",
+			query:          "jsonrpc=2.0&method=database&id=1",
+			wantStatusCode: http.StatusMethodNotAllowed,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			response, err := http.Get(server.URL + "?" + tt.query)
+			if err != nil {
+				t.Fatal(err)
+			}
+			defer response.Body.Close()
+			body, _ := io.ReadAll(response.Body)
+			if response.StatusCode != tt.wantStatusCode {
+				t.Errorf("StatusCode = %v, want %v", response.StatusCode, tt.wantStatusCode)
+			}
+			if tt.wantBody != "" && strings.TrimSpace(string(body)) != tt.wantBody {
+				t.Errorf("body = %s, want %s", body, tt.wantBody)
+			}
+		})
+	}
+
+	t.Run("HTTPTransport", func(t *testing.T) {
+		mu.Lock()
+		methods = nil
+		mu.Unlock()
+		client := NewClient(NewHTTPTransport(server.URL, WithHTTPGet(func(method string) bool {
+			return method == "subtract"
+		})))
+		for _, method := range []string{"subtract", "raw"} {
+			_, err := Call[any](context.Background(), client, method, []int{42, 23})
+			if err != nil {
+				t.Errorf("Call(%v) = %v, want nil", method, err)
+			}
+		}
+		_, err := client.CallBatch(context.Background(), []BatchItem{{Method: "subtract", Params: []int{42, 23}}})
+		if err != nil {
+			t.Errorf("CallBatch() = %v, want nil", err)
+		}
+		mu.Lock()
+		defer mu.Unlock()
+		want := []string{http.MethodGet, http.MethodPost, http.MethodPost}
+		if strings.Join(methods, " ") != strings.Join(want, " ") {
+			t.Errorf("HTTP methods = %v, want %v", methods, want)
+		}
+	})
+}
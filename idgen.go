@@ -0,0 +1,96 @@
+/*  Copyright 2022  Kosmas Valianos (kosmas.valianos@gmail.com)
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    https://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License. */
+
+package gojsonrpc
+
+import (
+	"crypto/rand"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"sync/atomic"
+)
+
+// IDGenerator generates the IDs of the requests of a Client. It must be safe for concurrent use
+type IDGenerator interface {
+	// NextID returns a new ID of type int, float64 or string
+	NextID() any
+}
+
+// IncrementingIDGenerator generates the integers 1, 2, 3 and so on. It is the default IDGenerator of a Client
+type IncrementingIDGenerator struct {
+	last atomic.Int64
+}
+
+// NextID returns the next integer
+func (g *IncrementingIDGenerator) NextID() any {
+	return int(g.last.Add(1))
+}
+
+// UUIDGenerator generates random UUIDv4 strings
+type UUIDGenerator struct{}
+
+// NextID returns a new UUIDv4 string
+func (UUIDGenerator) NextID() any {
+	var uuid [16]byte
+	_, err := rand.Read(uuid[:])
+	if err != nil {
+		panic(err)
+	}
+	uuid[6] = uuid[6]&0x0f | 0x40 // Version 4
+	uuid[8] = uuid[8]&0x3f | 0x80 // Variant RFC 4122
+	return fmt.Sprintf("%x-%x-%x-%x-%x", uuid[0:4], uuid[4:6], uuid[6:8], uuid[8:10], uuid[10:16])
+}
+
+// PrefixedIDGenerator generates the IDs of another IDGenerator as strings with a prefix,
+// e.g. to tell apart the requests of several clients
+type PrefixedIDGenerator struct {
+	Prefix    string
+	Generator IDGenerator
+}
+
+// NextID returns the next ID of the generator with the prefix
+func (g PrefixedIDGenerator) NextID() any {
+	return fmt.Sprint(g.Prefix, g.Generator.NextID())
+}
+
+// WithIDGenerator sets the IDGenerator of the requests. The default one is an IncrementingIDGenerator
+func WithIDGenerator(generator IDGenerator) ClientOption {
+	return func(c *Client) {
+		if generator != nil {
+			c.idGenerator = generator
+		}
+	}
+}
+
+// newRequestWithID creates a request using the method, the params and an id of type int, float64 or string.
+// Returns the raw bytes of the request or an error
+func newRequestWithID(method string, params any, id any) ([]byte, error) {
+	switch id := id.(type) {
+	case int:
+		return NewRequest(method, params, id)
+	case float64:
+		return NewRequest(method, params, id)
+	case string:
+		return NewRequest(method, params, id)
+	default:
+		return nil, errors.New("id must be of type int, float64 or string")
+	}
+}
+
+// idKey returns the JSON encoding of an id, under which the ID of a request and of its response are the same
+func idKey(id any) string {
+	idRaw, _ := json.Marshal(id)
+	return string(idRaw)
+}
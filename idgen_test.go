@@ -0,0 +1,76 @@
+/*  Copyright 2022  Kosmas Valianos (kosmas.valianos@gmail.com)
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    https://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License. */
+
+package gojsonrpc
+
+import (
+	"context"
+	"regexp"
+	"testing"
+)
+
+func TestIDGenerator(t *testing.T) {
+	incrementing := &IncrementingIDGenerator{}
+	for want := 1; want <= 3; want++ {
+		if got := incrementing.NextID(); got != want {
+			t.Errorf("IncrementingIDGenerator.NextID() = %v, want %v", got, want)
+		}
+	}
+
+	uuidPattern := regexp.MustCompile(`^[0-9a-f]{8}-[0-9a-f]{4}-4[0-9a-f]{3}-[89ab][0-9a-f]{3}-[0-9a-f]{12}$`)
+	first, second := UUIDGenerator{}.NextID(), UUIDGenerator{}.NextID()
+	for _, id := range []any{first, second} {
+		if uuid, ok := id.(string); !ok || !uuidPattern.MatchString(uuid) {
+			t.Errorf("UUIDGenerator.NextID() = %v, want a UUIDv4", id)
+		}
+	}
+	if first == second {
+		t.Errorf("UUIDGenerator.NextID() = %v twice", first)
+	}
+
+	prefixed := PrefixedIDGenerator{Prefix: "client-1/", Generator: &IncrementingIDGenerator{}}
+	for _, want := range []string{"client-1/1", "client-1/2"} {
+		if got := prefixed.NextID(); got != want {
+			t.Errorf("PrefixedIDGenerator.NextID() = %v, want %v", got, want)
+		}
+	}
+}
+
+func TestWithIDGenerator(t *testing.T) {
+	generators := []IDGenerator{
+		UUIDGenerator{},
+		PrefixedIDGenerator{Prefix: "client-1/", Generator: &IncrementingIDGenerator{}},
+	}
+	for _, generator := range generators {
+		client := NewClient(&muxTransport{mux: newTestMux(t)}, WithIDGenerator(generator))
+		result, err := Call[int](context.Background(), client, "subtract", []int{42, 23})
+		if err != nil || result != 19 {
+			t.Errorf("Call() = %v, %v, want 19", result, err)
+		}
+		results, err := client.CallBatch(context.Background(), []BatchItem{
+			{Method: "subtract", Params: []int{42, 23}},
+			{Method: "subtract", Params: []int{23, 42}},
+		})
+		if err != nil {
+			t.Fatalf("CallBatch() error = %v", err)
+		}
+		for i, want := range []int{19, -19} {
+			var result int
+			err := results[i].Unmarshal(&result)
+			if err != nil || result != want {
+				t.Errorf("Unmarshal() of result %v = %v, %v, want %v", i, result, err, want)
+			}
+		}
+	}
+}
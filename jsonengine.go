@@ -0,0 +1,97 @@
+/*  Copyright 2022  Kosmas Valianos (kosmas.valianos@gmail.com)
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    https://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License. */
+
+package gojsonrpc
+
+import (
+	"context"
+	"encoding/json"
+	"reflect"
+	"sync/atomic"
+)
+
+// JSONEngine marshals the params and the results to JSON and unmarshals them, e.g. with jsoniter or sonic
+// configured to be compatible with encoding/json, as their encoding dominates the CPU of the busy servers.
+// The envelopes of the messages are always handled by the package. It must be safe for concurrent use
+type JSONEngine interface {
+	Marshal(v any) ([]byte, error)
+	Unmarshal(data []byte, v any) error
+}
+
+type stdJSONEngine struct{}
+
+func (stdJSONEngine) Marshal(v any) ([]byte, error) {
+	return json.Marshal(v)
+}
+
+func (stdJSONEngine) Unmarshal(data []byte, v any) error {
+	return json.Unmarshal(data, v)
+}
+
+// jsonEngineHolder holds the JSONEngine of the package, an atomic.Value needing the same concrete type
+type jsonEngineHolder struct {
+	engine JSONEngine
+}
+
+var packageJSONEngine atomic.Value
+
+// SetJSONEngine sets the JSONEngine of the package, used by the constructors of the messages, the Clients and
+// the Muxes without one of their own. A nil engine restores encoding/json, the default. It should be set at startup
+func SetJSONEngine(engine JSONEngine) {
+	if engine == nil {
+		engine = stdJSONEngine{}
+	}
+	packageJSONEngine.Store(jsonEngineHolder{engine: engine})
+}
+
+// WithJSONEngine sets the JSONEngine of the params and of the results of the handlers of the Mux
+func WithJSONEngine(engine JSONEngine) MuxOption {
+	return func(m *Mux) {
+		m.jsonEngine = engine
+	}
+}
+
+// currentJSONEngine returns the JSONEngine of the package
+func currentJSONEngine() JSONEngine {
+	holder, ok := packageJSONEngine.Load().(jsonEngineHolder)
+	if !ok {
+		return stdJSONEngine{}
+	}
+	return holder.engine
+}
+
+// jsonEngineFromContext returns the JSONEngine of the Mux serving the request, else the one of the package
+func jsonEngineFromContext(ctx context.Context) JSONEngine {
+	if engine, ok := ctx.Value(jsonEngineContextKey).(JSONEngine); ok {
+		return engine
+	}
+	return currentJSONEngine()
+}
+
+// marshalJSONValue marshals the params or the result of a message with the engine. A json.RawMessage is taken
+// as-is and a json.Marshaler marshals itself, without going through the engine, as they are JSON already.
+// The encoding of the message validates and compacts them. Returns the raw bytes of the value or an error
+func marshalJSONValue(engine JSONEngine, value any) (json.RawMessage, error) {
+	switch value := value.(type) {
+	case json.RawMessage:
+		if value != nil {
+			return value, nil
+		}
+	case json.Marshaler:
+		if reflectValue := reflect.ValueOf(value); reflectValue.Kind() != reflect.Pointer || !reflectValue.IsNil() {
+			return value.MarshalJSON()
+		}
+	}
+	return engine.Marshal(value)
+}
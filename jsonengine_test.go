@@ -0,0 +1,150 @@
+/*  Copyright 2022  Kosmas Valianos (kosmas.valianos@gmail.com)
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    https://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License. */
+
+package gojsonrpc
+
+import (
+	"context"
+	"encoding/json"
+	"sync/atomic"
+	"testing"
+)
+
+// countingEngine is encoding/json counting its calls
+type countingEngine struct {
+	marshals   atomic.Int64
+	unmarshals atomic.Int64
+}
+
+func (e *countingEngine) Marshal(v any) ([]byte, error) {
+	e.marshals.Add(1)
+	return json.Marshal(v)
+}
+
+func (e *countingEngine) Unmarshal(data []byte, v any) error {
+	e.unmarshals.Add(1)
+	return json.Unmarshal(data, v)
+}
+
+func TestWithJSONEngine(t *testing.T) {
+	engine := &countingEngine{}
+	mux := NewMux(WithJSONEngine(engine))
+	err := HandleFunc(mux, "subtract", func(ctx context.Context, params [2]int) (int, error) {
+		return params[0] - params[1], nil
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	responseRaw := mux.Serve(context.Background(), []byte(`{"jsonrpc":"2.0","method":"subtract","params":[42,23],"id":1}`))
+	want := `{"jsonrpc":"2.0","result":19,"id":1}` + "\n"
+	if string(responseRaw) != want {
+		t.Errorf("Serve() = %q, want %q", responseRaw, want)
+	}
+	if engine.marshals.Load() != 1 || engine.unmarshals.Load() != 1 {
+		t.Errorf("engine calls = %v marshals, %v unmarshals, want 1 and 1", engine.marshals.Load(), engine.unmarshals.Load())
+	}
+}
+
+func TestSetJSONEngine(t *testing.T) {
+	engine := &countingEngine{}
+	SetJSONEngine(engine)
+	defer SetJSONEngine(nil)
+
+	client := NewClient(newLoopbackTransport(newTestMux(t)))
+	var result int
+	err := client.Call(context.Background(), "subtract", []int{42, 23}, &result)
+	if err != nil || result != 19 {
+		t.Errorf("Call() = %v, %v, want %v", result, err, 19)
+	}
+	// The params and the result are each marshaled once and unmarshaled once
+	if engine.marshals.Load() != 2 || engine.unmarshals.Load() != 2 {
+		t.Errorf("engine calls = %v marshals, %v unmarshals, want 2 and 2", engine.marshals.Load(), engine.unmarshals.Load())
+	}
+
+	SetJSONEngine(nil)
+	_, err = NewRequest("subtract", []int{42, 23}, 1)
+	if err != nil || engine.marshals.Load() != 2 {
+		t.Errorf("NewRequest() = %v with %v marshals, want encoding/json restored", err, engine.marshals.Load())
+	}
+}
+
+// selfMarshaler marshals itself to a fixed JSON value
+type selfMarshaler struct{}
+
+func (*selfMarshaler) MarshalJSON() ([]byte, error) {
+	return []byte(`{ "self": true }`), nil
+}
+
+func Test_marshalJSONValue(t *testing.T) {
+	tests := []struct {
+		name         string
+		new          func() ([]byte, error)
+		want         string
+		wantMarshals int64
+	}{
+		{
+			name:         "json.RawMessage params",
+			new:          func() ([]byte, error) { return NewRequest("subtract", json.RawMessage(`[42, 23]`), 1) },
+			want:         `{"jsonrpc":"2.0","method":"subtract","params":[42,23],"id":1}`,
+			wantMarshals: 0,
+		},
+		{
+			name:         "json.Marshaler params",
+			new:          func() ([]byte, error) { return NewNotification("update", &selfMarshaler{}) },
+			want:         `{"jsonrpc":"2.0","method":"update","params":{"self":true}}`,
+			wantMarshals: 0,
+		},
+		{
+			name:         "json.RawMessage result",
+			new:          func() ([]byte, error) { return NewResultResponse("abc", json.RawMessage(`"done"`)) },
+			want:         `{"jsonrpc":"2.0","result":"done","id":"abc"}`,
+			wantMarshals: 0,
+		},
+		{
+			name:         "nil json.Marshaler result",
+			new:          func() ([]byte, error) { return NewResultResponse("abc", (*selfMarshaler)(nil)) },
+			want:         `{"jsonrpc":"2.0","result":null,"id":"abc"}`,
+			wantMarshals: 1,
+		},
+		{
+			name:         "params",
+			new:          func() ([]byte, error) { return NewRequest("subtract", []int{42, 23}, 1) },
+			want:         `{"jsonrpc":"2.0","method":"subtract","params":[42,23],"id":1}`,
+			wantMarshals: 1,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			engine := &countingEngine{}
+			SetJSONEngine(engine)
+			defer SetJSONEngine(nil)
+
+			got, err := tt.new()
+			if err != nil {
+				t.Fatal(err)
+			}
+			if string(got) != tt.want+"\n" {
+				t.Errorf("%v = %q, want %q", tt.name, got, tt.want+"\n")
+			}
+			if engine.marshals.Load() != tt.wantMarshals {
+				t.Errorf("engine marshals = %v, want %v", engine.marshals.Load(), tt.wantMarshals)
+			}
+		})
+	}
+
+	if _, err := NewRequest("subtract", json.RawMessage(`[42,`), 1); err == nil {
+		t.Error("NewRequest() error = nil, want an error for invalid json.RawMessage params")
+	}
+}
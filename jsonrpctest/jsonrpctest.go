@@ -0,0 +1,357 @@
+/*  Copyright 2022  Kosmas Valianos (kosmas.valianos@gmail.com)
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    https://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License. */
+
+// Package jsonrpctest provides utilities for testing the JSON-RPC 2.0 layers of the applications of gojsonrpc without
+// sockets: an in-memory Server, a scriptable fake Peer and the assertions of a Transcript of the messages exchanged.
+package jsonrpctest
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"reflect"
+	"strings"
+	"sync"
+	"testing"
+
+	"github.com/kosmas-valianos/gojsonrpc"
+)
+
+// Direction is the direction of a message of a Transcript from the point of view of the code under test
+type Direction int
+
+const (
+	// Sent is a message sent to the server or the peer, written "-->" as in the JSON-RPC 2.0 specification
+	Sent Direction = iota
+	// Received is a message received from the server or the peer, written "<--"
+	Received
+)
+
+// String returns "-->" or "<--"
+func (d Direction) String() string {
+	if d == Sent {
+		return "-->"
+	}
+	return "<--"
+}
+
+// Message is a message of a Transcript
+type Message struct {
+	Direction Direction
+	Raw       json.RawMessage
+}
+
+// String returns the message as in the JSON-RPC 2.0 specification, e.g. --> {"jsonrpc": "2.0", "method": "update"}
+func (m Message) String() string {
+	return m.Direction.String() + " " + string(bytes.TrimSpace(m.Raw))
+}
+
+// Transcript records the messages exchanged with a Server or a Peer in order. It is safe for concurrent use
+type Transcript struct {
+	mu       sync.Mutex
+	messages []Message
+}
+
+// Messages returns a copy of the messages recorded so far
+func (t *Transcript) Messages() []Message {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	return append([]Message(nil), t.messages...)
+}
+
+// String returns the messages one per line
+func (t *Transcript) String() string {
+	var lines []string
+	for _, message := range t.Messages() {
+		lines = append(lines, message.String())
+	}
+	return strings.Join(lines, "\n")
+}
+
+func (t *Transcript) record(direction Direction, messageRaw []byte) {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	t.messages = append(t.messages, Message{Direction: direction, Raw: append(json.RawMessage(nil), messageRaw...)})
+}
+
+// AssertTranscript checks that the transcript consists of the wanted messages, written as by Message.String, e.g.
+// `--> {"jsonrpc": "2.0", "method": "subtract", "params": [42, 23], "id": 1}`. The JSON of the messages is compared
+// regardless of the whitespace and of the order of the members of the objects
+func AssertTranscript(t testing.TB, transcript *Transcript, want ...string) {
+	t.Helper()
+	got := transcript.Messages()
+	if len(got) != len(want) {
+		t.Errorf("transcript has %v messages, want %v:\n%v", len(got), len(want), transcript)
+		return
+	}
+	for i, message := range got {
+		direction, messageRaw, ok := strings.Cut(strings.TrimSpace(want[i]), " ")
+		if !ok || (direction != Sent.String() && direction != Received.String()) {
+			t.Errorf("wanted message %v %q must start with %v or %v", i, want[i], Sent, Received)
+			continue
+		}
+		if direction != message.Direction.String() || !equalJSON(message.Raw, []byte(messageRaw)) {
+			t.Errorf("message %v = %v, want %v", i, message, strings.TrimSpace(want[i]))
+		}
+	}
+}
+
+// equalJSON reports whether a and b are the same JSON value
+func equalJSON(a, b []byte) bool {
+	var valueA, valueB any
+	if json.Unmarshal(a, &valueA) != nil || json.Unmarshal(b, &valueB) != nil {
+		return bytes.Equal(bytes.TrimSpace(a), bytes.TrimSpace(b))
+	}
+	return reflect.DeepEqual(valueA, valueB)
+}
+
+// Server is an in-memory server serving a Mux, connected to a client Conn over a gojsonrpc.Pipe
+type Server struct {
+	server     *gojsonrpc.Conn
+	client     *gojsonrpc.Conn
+	transcript Transcript
+}
+
+// NewServer starts serving the mux in memory, the Client of the connected Conn being configured by the options.
+// Returns a *Server object
+func NewServer(mux *gojsonrpc.Mux, options ...gojsonrpc.ClientOption) *Server {
+	s := &Server{}
+	serverEnd, clientEnd := gojsonrpc.Pipe()
+	s.server = gojsonrpc.NewConn(serverEnd, mux)
+	s.client = gojsonrpc.NewConn(&recordingConn{MessageConn: clientEnd, transcript: &s.transcript}, nil, options...)
+	return s
+}
+
+// Client returns the Client calling the server
+func (s *Server) Client() *gojsonrpc.Client {
+	return s.client.Client()
+}
+
+// Conn returns the client Conn connected to the server, e.g. to subscribe to its events
+func (s *Server) Conn() *gojsonrpc.Conn {
+	return s.client
+}
+
+// Transcript returns the Transcript of the messages exchanged with the server
+func (s *Server) Transcript() *Transcript {
+	return &s.transcript
+}
+
+// Close closes the connection to the server.
+// Returns an error
+func (s *Server) Close() error {
+	err := s.client.Close()
+	s.server.Close()
+	return err
+}
+
+// recordingConn records the messages written and read in the transcript
+type recordingConn struct {
+	gojsonrpc.MessageConn
+	transcript *Transcript
+}
+
+func (c *recordingConn) WriteMessage(ctx context.Context, messageRaw []byte) error {
+	c.transcript.record(Sent, messageRaw)
+	return c.MessageConn.WriteMessage(ctx, messageRaw)
+}
+
+func (c *recordingConn) ReadMessage() ([]byte, error) {
+	messageRaw, err := c.MessageConn.ReadMessage()
+	if err == nil {
+		c.transcript.record(Received, messageRaw)
+	}
+	return messageRaw, err
+}
+
+// Peer is a scriptable fake server implementing gojsonrpc.Transport. It expects the requests and the notifications
+// in the order of its Expectations and replies as they set. Unexpected messages fail the test, being answered with
+// gojsonrpc.JsonMethodNotFound, and so do the Expectations not met once the test ends
+type Peer struct {
+	t            testing.TB
+	mu           sync.Mutex
+	expectations []*Expectation
+	transcript   Transcript
+}
+
+// Expectation is a request or a notification expected by a Peer and its reply
+type Expectation struct {
+	method       string
+	notification bool
+	params       json.RawMessage
+	result       any
+	err          error
+}
+
+// NewPeer creates a Peer without Expectations, which fails t if some are not met once the test ends.
+// Returns a *Peer object
+func NewPeer(t testing.TB) *Peer {
+	p := &Peer{t: t}
+	t.Cleanup(func() {
+		p.mu.Lock()
+		defer p.mu.Unlock()
+		for _, expectation := range p.expectations {
+			p.t.Errorf("jsonrpctest: expected %v was not received", expectation)
+		}
+	})
+	return p
+}
+
+// Expect expects a request of the method, answered with a null result unless set otherwise.
+// Returns the *Expectation object to configure
+func (p *Peer) Expect(method string) *Expectation {
+	return p.expect(&Expectation{method: method})
+}
+
+// ExpectNotification expects a notification of the method.
+// Returns the *Expectation object to configure
+func (p *Peer) ExpectNotification(method string) *Expectation {
+	return p.expect(&Expectation{method: method, notification: true})
+}
+
+func (p *Peer) expect(expectation *Expectation) *Expectation {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	p.expectations = append(p.expectations, expectation)
+	return expectation
+}
+
+// WithParams expects the params, compared as JSON values with those received
+func (e *Expectation) WithParams(params any) *Expectation {
+	paramsRaw, err := json.Marshal(params)
+	if err != nil {
+		panic(fmt.Sprintf("jsonrpctest: params of %v cannot be marshaled: %v", e.method, err))
+	}
+	e.params = paramsRaw
+	return e
+}
+
+// Reply answers the request with the result
+func (e *Expectation) Reply(result any) *Expectation {
+	e.result = result
+	return e
+}
+
+// ReplyError answers the request with the JSON-RPC error in the chain of err, gojsonrpc.JsonInternalError if none
+func (e *Expectation) ReplyError(err error) *Expectation {
+	e.err = err
+	return e
+}
+
+// String describes the expectation, e.g. request "subtract"
+func (e *Expectation) String() string {
+	kind := "request"
+	if e.notification {
+		kind = "notification"
+	}
+	if e.params != nil {
+		return fmt.Sprintf("%v %q with params %s", kind, e.method, e.params)
+	}
+	return fmt.Sprintf("%v %q", kind, e.method)
+}
+
+// Transcript returns the Transcript of the messages exchanged with the peer
+func (p *Peer) Transcript() *Transcript {
+	return &p.transcript
+}
+
+// RoundTrip answers a request or a batch by the Expectations.
+// Returns the raw bytes of the response
+func (p *Peer) RoundTrip(ctx context.Context, requestRaw []byte) ([]byte, error) {
+	p.transcript.record(Sent, requestRaw)
+	var responseRaw []byte
+	if trimmed := bytes.TrimSpace(requestRaw); len(trimmed) > 0 && trimmed[0] == '[' {
+		var messagesRaw []json.RawMessage
+		if err := json.Unmarshal(trimmed, &messagesRaw); err != nil {
+			return nil, err
+		}
+		var responsesRaw [][]byte
+		for _, messageRaw := range messagesRaw {
+			if reply := p.answer(messageRaw); reply != nil {
+				responsesRaw = append(responsesRaw, bytes.TrimSpace(reply))
+			}
+		}
+		responseRaw = append(append([]byte("["), bytes.Join(responsesRaw, []byte(","))...), ']', '\n')
+	} else {
+		responseRaw = p.answer(requestRaw)
+	}
+	p.transcript.record(Received, responseRaw)
+	return responseRaw, nil
+}
+
+// Send receives a notification by the Expectations.
+// Returns no error
+func (p *Peer) Send(ctx context.Context, notificationRaw []byte) error {
+	p.transcript.record(Sent, notificationRaw)
+	p.answer(notificationRaw)
+	return nil
+}
+
+// answer checks the message against the next Expectation.
+// Returns the raw bytes of the response or nil for a notification
+func (p *Peer) answer(messageRaw []byte) []byte {
+	request, jsonRPCError := gojsonrpc.ParseRequest(messageRaw)
+	method, paramsRaw, notification := "", json.RawMessage(nil), jsonRPCError != nil
+	if notification {
+		parsed, err := gojsonrpc.ParseNotification(messageRaw)
+		if err != nil {
+			p.t.Errorf("jsonrpctest: invalid message %s: %v", messageRaw, err)
+			return nil
+		}
+		method, paramsRaw = parsed.Method, parsed.Params
+	} else {
+		method, paramsRaw = request.Method, request.Params
+	}
+
+	p.mu.Lock()
+	var expectation *Expectation
+	if len(p.expectations) > 0 {
+		expectation = p.expectations[0]
+		p.expectations = p.expectations[1:]
+	}
+	p.mu.Unlock()
+
+	var reply error
+	switch {
+	case expectation == nil || expectation.method != method || expectation.notification != notification:
+		p.t.Errorf("jsonrpctest: unexpected message %s, want %v", bytes.TrimSpace(messageRaw), expectation)
+		reply = &gojsonrpc.JsonMethodNotFound
+	case expectation.params != nil && !equalJSON(paramsRaw, expectation.params):
+		p.t.Errorf("jsonrpctest: params of %q = %s, want %s", method, paramsRaw, expectation.params)
+		reply = &gojsonrpc.JsonInvalidMethodParameters
+	default:
+		reply = expectation.err
+	}
+	if notification {
+		return nil
+	}
+
+	var responseRaw []byte
+	var err error
+	if reply != nil {
+		replyError, ok := gojsonrpc.AsJsonRPCError(reply)
+		if !ok {
+			replyError = &gojsonrpc.JsonInternalError
+		}
+		responseRaw, err = request.NewErrorResponse(replyError)
+	} else {
+		responseRaw, err = request.NewResultResponse(expectation.result)
+	}
+	if err != nil {
+		p.t.Errorf("jsonrpctest: reply to %q cannot be marshaled: %v", method, err)
+		responseRaw, _ = request.NewErrorResponse(&gojsonrpc.JsonInternalError)
+	}
+	return responseRaw
+}
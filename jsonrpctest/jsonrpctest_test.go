@@ -0,0 +1,170 @@
+/*  Copyright 2022  Kosmas Valianos (kosmas.valianos@gmail.com)
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    https://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License. */
+
+package jsonrpctest
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"strings"
+	"testing"
+
+	"github.com/kosmas-valianos/gojsonrpc"
+)
+
+func TestServer(t *testing.T) {
+	mux := gojsonrpc.NewMux()
+	err := gojsonrpc.HandleFunc(mux, "subtract", func(ctx context.Context, params [2]int) (int, error) {
+		return params[0] - params[1], nil
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	server := NewServer(mux)
+	defer server.Close()
+
+	result, err := gojsonrpc.Call[int](context.Background(), server.Client(), "subtract", []int{42, 23})
+	if err != nil || result != 19 {
+		t.Fatalf("Call() = %v, %v, want 19", result, err)
+	}
+	AssertTranscript(t, server.Transcript(),
+		`--> {"jsonrpc": "2.0", "method": "subtract", "params": [42, 23], "id": 1}`,
+		`<-- {"id": 1, "result": 19, "jsonrpc": "2.0"}`,
+	)
+}
+
+func TestPeer(t *testing.T) {
+	peer := NewPeer(t)
+	peer.Expect("subtract").WithParams([]int{42, 23}).Reply(19)
+	peer.ExpectNotification("update").WithParams([]int{1, 2})
+	peer.Expect("divide").ReplyError(fmt.Errorf("division: %w", &gojsonrpc.JsonInvalidMethodParameters))
+	client := gojsonrpc.NewClient(peer)
+
+	result, err := gojsonrpc.Call[int](context.Background(), client, "subtract", []int{42, 23})
+	if err != nil || result != 19 {
+		t.Errorf("Call() = %v, %v, want 19", result, err)
+	}
+	if err := client.Notify(context.Background(), "update", []int{1, 2}); err != nil {
+		t.Error(err)
+	}
+	if err := client.Call(context.Background(), "divide", []int{1, 0}, nil); !errors.Is(err, gojsonrpc.ErrInvalidMethodParameters) {
+		t.Errorf("Call() error = %v, want %v", err, gojsonrpc.ErrInvalidMethodParameters)
+	}
+	AssertTranscript(t, peer.Transcript(),
+		`--> {"jsonrpc": "2.0", "method": "subtract", "params": [42, 23], "id": 1}`,
+		`<-- {"jsonrpc": "2.0", "result": 19, "id": 1}`,
+		`--> {"jsonrpc": "2.0", "method": "update", "params": [1, 2]}`,
+		`--> {"jsonrpc": "2.0", "method": "divide", "params": [1, 0], "id": 2}`,
+		`<-- {"jsonrpc": "2.0", "error": {"code": -32602, "message": "Invalid method parameters"}, "id": 2}`,
+	)
+}
+
+func TestPeer_batch(t *testing.T) {
+	peer := NewPeer(t)
+	peer.Expect("sum").Reply(7)
+	peer.ExpectNotification("log")
+	client := gojsonrpc.NewClient(peer)
+
+	results, err := client.CallBatch(context.Background(), []gojsonrpc.BatchItem{
+		{Method: "sum", Params: []int{1, 2, 4}},
+		{Method: "log", Notification: true},
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	var sum int
+	if err := results[0].Unmarshal(&sum); err != nil || sum != 7 {
+		t.Errorf("CallBatch() = %v, %v, want 7", sum, err)
+	}
+}
+
+// failures records the failures of a test which are expected
+type failures struct {
+	testing.TB
+	errors []string
+}
+
+func (f *failures) Errorf(format string, args ...any) {
+	f.errors = append(f.errors, fmt.Sprintf(format, args...))
+}
+
+func (f *failures) Helper() {}
+
+func TestPeer_unexpected(t *testing.T) {
+	recorded := &failures{TB: t}
+	t.Run("script", func(t *testing.T) {
+		recorded.TB = t
+		peer := NewPeer(recorded)
+		peer.Expect("subtract").WithParams([]int{42, 23})
+		peer.Expect("never")
+		client := gojsonrpc.NewClient(peer)
+
+		if err := client.Call(context.Background(), "subtract", []int{1, 2}, nil); !errors.Is(err, gojsonrpc.ErrInvalidMethodParameters) {
+			t.Errorf("Call() error = %v, want %v", err, gojsonrpc.ErrInvalidMethodParameters)
+		}
+		if err := client.Call(context.Background(), "add", nil, nil); !errors.Is(err, gojsonrpc.ErrMethodNotFound) {
+			t.Errorf("Call() error = %v, want %v", err, gojsonrpc.ErrMethodNotFound)
+		}
+		if err := client.Call(context.Background(), "extra", nil, nil); !errors.Is(err, gojsonrpc.ErrMethodNotFound) {
+			t.Errorf("Call() error = %v, want %v", err, gojsonrpc.ErrMethodNotFound)
+		}
+	})
+
+	want := []string{"params of \"subtract\"", "unexpected message", "unexpected message"}
+	if len(recorded.errors) != len(want) {
+		t.Fatalf("failures = %q, want %v", recorded.errors, len(want))
+	}
+	for i := range want {
+		if !strings.Contains(recorded.errors[i], want[i]) {
+			t.Errorf("failure %v = %q, want it to contain %q", i, recorded.errors[i], want[i])
+		}
+	}
+}
+
+func TestPeer_notMet(t *testing.T) {
+	recorded := &failures{TB: t}
+	t.Run("script", func(t *testing.T) {
+		recorded.TB = t
+		NewPeer(recorded).Expect("subtract")
+	})
+	if len(recorded.errors) != 1 || !strings.Contains(recorded.errors[0], `request "subtract" was not received`) {
+		t.Errorf("failures = %q, want the expectation not met", recorded.errors)
+	}
+}
+
+func TestAssertTranscript(t *testing.T) {
+	var transcript Transcript
+	transcript.record(Sent, []byte(`{"jsonrpc":"2.0","method":"update"}`+"\n"))
+	tests := []struct {
+		name       string
+		want       []string
+		wantErrors int
+	}{
+		{name: "Same message", want: []string{`--> {"method": "update", "jsonrpc": "2.0"}`}},
+		{name: "Other direction", want: []string{`<-- {"jsonrpc": "2.0", "method": "update"}`}, wantErrors: 1},
+		{name: "Other message", want: []string{`--> {"jsonrpc": "2.0", "method": "delete"}`}, wantErrors: 1},
+		{name: "Missing direction", want: []string{`{"jsonrpc": "2.0", "method": "update"}`}, wantErrors: 1},
+		{name: "More messages", want: []string{`--> {}`, `<-- {}`}, wantErrors: 1},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			recorded := &failures{TB: t}
+			AssertTranscript(recorded, &transcript, tt.want...)
+			if len(recorded.errors) != tt.wantErrors {
+				t.Errorf("AssertTranscript() failures = %q, want %v", recorded.errors, tt.wantErrors)
+			}
+		})
+	}
+}
@@ -0,0 +1,138 @@
+/*  Copyright 2022  Kosmas Valianos (kosmas.valianos@gmail.com)
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    https://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License. */
+
+package gojsonrpc
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"io"
+)
+
+// maxValueDepth is the maximum nesting of the arrays and objects transcoded by the binary codecs
+const maxValueDepth = 10000
+
+var errValueTooDeep = errors.New("value nested too deeply")
+
+// jsonObject is a JSON object keeping the order of its members. The JSON values transcoded by the binary codecs
+// are nil, bool, json.Number, string, []any and jsonObject
+type jsonObject []jsonMember
+
+type jsonMember struct {
+	key   string
+	value any
+}
+
+// parseJSONValue parses a JSON text.
+// Returns its value or an error
+func parseJSONValue(messageRaw []byte) (any, error) {
+	decoder := json.NewDecoder(bytes.NewReader(messageRaw))
+	decoder.UseNumber()
+	value, err := parseJSONToken(decoder, 0)
+	if err != nil {
+		return nil, err
+	}
+	if _, err := decoder.Token(); err != io.EOF {
+		return nil, errors.New("invalid character after top-level value")
+	}
+	return value, nil
+}
+
+func parseJSONToken(decoder *json.Decoder, depth int) (any, error) {
+	token, err := decoder.Token()
+	if err != nil {
+		return nil, err
+	}
+	delim, ok := token.(json.Delim)
+	if !ok {
+		return token, nil
+	}
+	if depth >= maxValueDepth {
+		return nil, errValueTooDeep
+	}
+	switch delim {
+	case '[':
+		array := []any{}
+		for decoder.More() {
+			element, err := parseJSONToken(decoder, depth+1)
+			if err != nil {
+				return nil, err
+			}
+			array = append(array, element)
+		}
+		_, err = decoder.Token()
+		return array, err
+	case '{':
+		object := jsonObject{}
+		for decoder.More() {
+			key, err := decoder.Token()
+			if err != nil {
+				return nil, err
+			}
+			value, err := parseJSONToken(decoder, depth+1)
+			if err != nil {
+				return nil, err
+			}
+			object = append(object, jsonMember{key: key.(string), value: value})
+		}
+		_, err = decoder.Token()
+		return object, err
+	default:
+		return nil, errors.New("unexpected " + delim.String())
+	}
+}
+
+// appendJSONValue appends the JSON text of a value
+func appendJSONValue(dst []byte, value any) ([]byte, error) {
+	switch value := value.(type) {
+	case []any:
+		dst = append(dst, '[')
+		for i, element := range value {
+			if i > 0 {
+				dst = append(dst, ',')
+			}
+			var err error
+			dst, err = appendJSONValue(dst, element)
+			if err != nil {
+				return nil, err
+			}
+		}
+		return append(dst, ']'), nil
+	case jsonObject:
+		dst = append(dst, '{')
+		for i, member := range value {
+			if i > 0 {
+				dst = append(dst, ',')
+			}
+			var err error
+			dst, err = appendJSONValue(dst, member.key)
+			if err != nil {
+				return nil, err
+			}
+			dst = append(dst, ':')
+			dst, err = appendJSONValue(dst, member.value)
+			if err != nil {
+				return nil, err
+			}
+		}
+		return append(dst, '}'), nil
+	default:
+		valueRaw, err := json.Marshal(value)
+		if err != nil {
+			return nil, err
+		}
+		return append(dst, valueRaw...), nil
+	}
+}
@@ -0,0 +1,51 @@
+/*  Copyright 2022  Kosmas Valianos (kosmas.valianos@gmail.com)
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    https://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License. */
+
+package gojsonrpc
+
+import (
+	"strings"
+	"testing"
+)
+
+func Test_parseJSONValue(t *testing.T) {
+	tests := []struct {
+		name       string
+		messageRaw string
+		want       string
+		wantErr    bool
+	}{
+		{"keeps the order", `{"b":1,"a":[true,null,"x"],"c":{}}`, `{"b":1,"a":[true,null,"x"],"c":{}}`, false},
+		{"compacts", "{ \"n\" : 1e3 ,\n \"s\": \"\\u00e9\" }", `{"n":1e3,"s":"é"}`, false},
+		{"too deep", strings.Repeat("[", maxValueDepth+1) + strings.Repeat("]", maxValueDepth+1), "", true},
+		{"trailing value", `{"a":1} {}`, "", true},
+		{"truncated", `{"a":`, "", true},
+		{"unexpected delimiter", `]`, "", true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			value, err := parseJSONValue([]byte(tt.messageRaw))
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("parseJSONValue() error = %v, wantErr %v", err, tt.wantErr)
+			}
+			if err != nil {
+				return
+			}
+			got, err := appendJSONValue(nil, value)
+			if err != nil || string(got) != tt.want {
+				t.Errorf("appendJSONValue() = %s, %v, want %s", got, err, tt.want)
+			}
+		})
+	}
+}
@@ -0,0 +1,169 @@
+/*  Copyright 2022  Kosmas Valianos (kosmas.valianos@gmail.com)
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    https://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License. */
+
+package gojsonrpc
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"io"
+	"log/slog"
+	"time"
+)
+
+// logConfig configures the logging of a Mux, a Client or a Conn
+type logConfig struct {
+	logger     *slog.Logger
+	level      slog.Level
+	errorLevel slog.Level
+	params     bool
+}
+
+// LogOption configures the logging set by WithLogger and WithClientLogger
+type LogOption func(*logConfig)
+
+// WithLogLevel sets the level of the calls answered with a result and of the notifications, slog.LevelInfo by default
+func WithLogLevel(level slog.Level) LogOption {
+	return func(c *logConfig) {
+		c.level = level
+	}
+}
+
+// WithErrorLogLevel sets the level of the calls answered with an error object or failed and of the connections lost,
+// slog.LevelWarn by default
+func WithErrorLogLevel(level slog.Level) LogOption {
+	return func(c *logConfig) {
+		c.errorLevel = level
+	}
+}
+
+// WithLoggedParams logs the params of the calls too, compacted, truncated and redacted as their summaries
+// by SetLogRedaction
+func WithLoggedParams() LogOption {
+	return func(c *logConfig) {
+		c.params = true
+	}
+}
+
+func newLogConfig(logger *slog.Logger, options []LogOption) *logConfig {
+	if logger == nil {
+		return nil
+	}
+	config := &logConfig{logger: logger, level: slog.LevelInfo, errorLevel: slog.LevelWarn}
+	for _, option := range options {
+		option(config)
+	}
+	return config
+}
+
+// WithLogger logs every request and notification served with the logger, configured by the options, with the fields
+// method, id, duration and the code of the error object of the response if any
+func WithLogger(logger *slog.Logger, options ...LogOption) MuxOption {
+	return func(m *Mux) {
+		m.logger = newLogConfig(logger, options)
+	}
+}
+
+// WithClientLogger logs every request, notification and batch sent with the logger, configured by the options,
+// with the fields method, id, duration and the code of the error object of the response or the error of the call
+// if any. A Conn logs the loss of its connection and the responses which no call waits for too
+func WithClientLogger(logger *slog.Logger, options ...LogOption) ClientOption {
+	return func(c *Client) {
+		c.logger = newLogConfig(logger, options)
+	}
+}
+
+// logServed logs a request or a notification served with the code of its error object, 0 if none
+func (c *logConfig) logServed(ctx context.Context, call observedCall, code int, duration time.Duration) {
+	message := "served request"
+	if call.ID == nil {
+		message = "served notification"
+	}
+	c.log(ctx, message, call.Method, call.ID, call.Params, code, nil, duration)
+}
+
+// logCall logs a call made, failed with the error if not nil
+func (c *logConfig) logCall(ctx context.Context, method string, id any, params any, err error, duration time.Duration) {
+	message := "sent request"
+	if id == nil {
+		message = "sent notification"
+	}
+	var idRaw, paramsRaw json.RawMessage
+	if id != nil {
+		idRaw, _ = json.Marshal(id)
+	}
+	if c.params && params != nil {
+		paramsRaw, _ = currentJSONEngine().Marshal(params)
+	}
+	code := 0
+	if jsonRPCError, ok := AsJsonRPCError(err); ok && jsonRPCError != nil {
+		code, err = jsonRPCError.Code, nil
+	}
+	c.log(ctx, message, method, idRaw, paramsRaw, code, err, duration)
+}
+
+// logBatch logs a batch sent, failed with the error if not nil
+func (c *logConfig) logBatch(ctx context.Context, size int, err error, duration time.Duration) {
+	level := c.level
+	attrs := []slog.Attr{slog.Int("size", size), slog.Duration("duration", duration)}
+	if err != nil {
+		level = c.errorLevel
+		attrs = append(attrs, slog.String("error", err.Error()))
+	}
+	c.logger.LogAttrs(ctx, level, "sent batch", attrs...)
+}
+
+// logConnLost logs the loss of the connection of a Conn, at the level of the calls if the remote peer closed it
+func (c *logConfig) logConnLost(ctx context.Context, connID string, err error) {
+	level := c.errorLevel
+	if errors.Is(err, io.EOF) {
+		level = c.level
+	}
+	c.logger.LogAttrs(ctx, level, "connection lost", slog.String("conn", connID), slog.String("error", err.Error()))
+}
+
+// logUndelivered logs a response received by a Conn which no call waits for, e.g. because the call was abandoned
+func (c *logConfig) logUndelivered(ctx context.Context, connID string, err error) {
+	c.logger.LogAttrs(ctx, c.level, "response without a call", slog.String("conn", connID), slog.String("error", err.Error()))
+}
+
+func (c *logConfig) log(ctx context.Context, message, method string, idRaw, paramsRaw json.RawMessage, code int, err error, duration time.Duration) {
+	level := c.level
+	if code != 0 || err != nil {
+		level = c.errorLevel
+	}
+	if !c.logger.Enabled(ctx, level) {
+		return
+	}
+
+	attrs := make([]slog.Attr, 0, 6)
+	attrs = append(attrs, slog.String("method", method))
+	if idRaw != nil {
+		var id any
+		_ = json.Unmarshal(idRaw, &id)
+		attrs = append(attrs, slog.Any("id", id))
+	}
+	if c.params && len(paramsRaw) > 0 {
+		attrs = append(attrs, slog.String("params", loggedValue(paramsRaw)))
+	}
+	attrs = append(attrs, slog.Duration("duration", duration))
+	if code != 0 {
+		attrs = append(attrs, slog.Int("code", code))
+	}
+	if err != nil {
+		attrs = append(attrs, slog.String("error", err.Error()))
+	}
+	c.logger.LogAttrs(ctx, level, message, attrs...)
+}
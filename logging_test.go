@@ -0,0 +1,152 @@
+/*  Copyright 2022  Kosmas Valianos (kosmas.valianos@gmail.com)
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    https://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License. */
+
+package gojsonrpc
+
+import (
+	"context"
+	"fmt"
+	"log/slog"
+	"reflect"
+	"sort"
+	"strings"
+	"sync"
+	"testing"
+)
+
+// testLogHandler records the records logged, without the durations, as strings
+type testLogHandler struct {
+	mu      sync.Mutex
+	level   slog.Level
+	records []string
+}
+
+func (h *testLogHandler) Enabled(ctx context.Context, level slog.Level) bool {
+	return level >= h.level
+}
+
+func (h *testLogHandler) Handle(ctx context.Context, record slog.Record) error {
+	fields := []string{record.Level.String(), record.Message}
+	record.Attrs(func(attr slog.Attr) bool {
+		if attr.Key != "duration" {
+			fields = append(fields, fmt.Sprintf("%v=%v", attr.Key, attr.Value))
+		}
+		return true
+	})
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	h.records = append(h.records, strings.Join(fields, " "))
+	return nil
+}
+
+func (h *testLogHandler) WithAttrs(attrs []slog.Attr) slog.Handler {
+	return h
+}
+
+func (h *testLogHandler) WithGroup(name string) slog.Handler {
+	return h
+}
+
+// sorted returns the records logged sorted, since the elements of a batch are served concurrently
+func (h *testLogHandler) sorted() []string {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	records := append([]string(nil), h.records...)
+	h.records = nil
+	sort.Strings(records)
+	return records
+}
+
+func TestWithLogger(t *testing.T) {
+	tests := []struct {
+		name    string
+		options []LogOption
+		request string
+		want    []string
+	}{
+		{
+			name:    "Request",
+			request: `{"jsonrpc":"2.0","method":"subtract","params":[42,23],"id":1}`,
+			want:    []string{"INFO served request method=subtract id=1"},
+		},
+		{
+			name:    "Error response",
+			request: `{"jsonrpc":"2.0","method":"database","id":"a"}`,
+			want:    []string{"WARN served request method=database id=a code=-32602"},
+		},
+		{
+			name:    "Notification",
+			request: `{"jsonrpc":"2.0","method":"subtract","params":[42,23]}`,
+			want:    []string{"INFO served notification method=subtract"},
+		},
+		{
+			name:    "Batch",
+			request: `[{"jsonrpc":"2.0","method":"subtract","params":[42,23],"id":1},{"jsonrpc":"2.0","method":"foo","id":2}]`,
+			want:    []string{"INFO served request method=subtract id=1", "WARN served request method=foo id=2 code=-32601"},
+		},
+		{
+			name:    "Levels",
+			options: []LogOption{WithLogLevel(slog.LevelDebug), WithErrorLogLevel(slog.LevelError)},
+			request: `[{"jsonrpc":"2.0","method":"subtract","params":[42,23],"id":1},{"jsonrpc":"2.0","method":"fail","id":2}]`,
+			want:    []string{"ERROR served request method=fail id=2 code=-32603"},
+		},
+		{
+			name:    "Params",
+			options: []LogOption{WithLoggedParams()},
+			request: `{"jsonrpc":"2.0","method":"subtract","params":[42, 23],"id":1}`,
+			want:    []string{"INFO served request method=subtract id=1 params=[42,23]"},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			handler := &testLogHandler{level: slog.LevelInfo}
+			mux := newTestMux(t)
+			WithLogger(slog.New(handler), tt.options...)(mux)
+			mux.Serve(context.Background(), []byte(tt.request))
+			if got := handler.sorted(); !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("Serve() logged %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestWithClientLogger(t *testing.T) {
+	handler := &testLogHandler{level: slog.LevelInfo}
+	client := NewClient(&muxTransport{mux: newTestMux(t)}, WithClientLogger(slog.New(handler)))
+	ctx := context.Background()
+
+	var difference int
+	if err := client.Call(ctx, "subtract", [2]int{42, 23}, &difference); err != nil {
+		t.Fatal(err)
+	}
+	_ = client.Call(ctx, "database", nil, nil)
+	if err := client.Notify(ctx, "subtract", [2]int{42, 23}); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := client.CallBatch(ctx, []BatchItem{{Method: "subtract", Params: [2]int{42, 23}}}); err != nil {
+		t.Fatal(err)
+	}
+	_ = NewClient(&downTransport{}, WithClientLogger(slog.New(handler))).Call(ctx, "subtract", [2]int{42, 23}, nil)
+
+	want := []string{
+		"INFO sent batch size=1",
+		"INFO sent notification method=subtract",
+		"INFO sent request method=subtract id=1",
+		"WARN sent request method=database id=2 code=-32602",
+		"WARN sent request method=subtract id=1 error=connection refused",
+	}
+	if got := handler.sorted(); !reflect.DeepEqual(got, want) {
+		t.Errorf("Client logged %v, want %v", got, want)
+	}
+}
@@ -0,0 +1,112 @@
+/*  Copyright 2022  Kosmas Valianos (kosmas.valianos@gmail.com)
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    https://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License. */
+
+package gojsonrpc
+
+import (
+	"bytes"
+	"encoding/json"
+)
+
+// MarshalOption configures how the constructors, e.g. NewRequest, and the Append functions, e.g. AppendRequest,
+// encode a message
+type MarshalOption func(*marshalConfig)
+
+type marshalConfig struct {
+	// delimiter terminates the message, a newline by default
+	delimiter []byte
+	// indented, prefix and indent set the indentation as json.Indent does
+	indented bool
+	prefix   string
+	indent   string
+	// escapeHTML escapes <, > and & in the JSON strings as json.Marshal does, true by default
+	escapeHTML bool
+}
+
+var newlineDelimiter = []byte("\n")
+
+func newMarshalConfig(options []MarshalOption) marshalConfig {
+	config := marshalConfig{delimiter: newlineDelimiter, escapeHTML: true}
+	for _, option := range options {
+		option(&config)
+	}
+	return config
+}
+
+// WithDelimiter terminates the message with the delimiter instead of a newline, e.g. "\r\n"
+func WithDelimiter(delimiter string) MarshalOption {
+	return func(config *marshalConfig) {
+		config.delimiter = []byte(delimiter)
+	}
+}
+
+// WithoutTrailingNewline does not terminate the message, e.g. for the body of an HTTP request or a WebSocket frame
+// which delimit it already
+func WithoutTrailingNewline() MarshalOption {
+	return func(config *marshalConfig) {
+		config.delimiter = nil
+	}
+}
+
+// WithIndent indents the message as json.MarshalIndent does, e.g. for a CLI, logs or golden files
+func WithIndent(prefix, indent string) MarshalOption {
+	return func(config *marshalConfig) {
+		config.indented = true
+		config.prefix = prefix
+		config.indent = indent
+	}
+}
+
+// WithoutHTMLEscaping does not escape <, > and & in the JSON strings as json.Marshal does, which keeps the URLs and
+// the HTML in the params and the results readable. With another JSONEngine it applies to the message but the params
+// and the results are marshaled by the engine as it is configured
+func WithoutHTMLEscaping() MarshalOption {
+	return func(config *marshalConfig) {
+		config.escapeHTML = false
+	}
+}
+
+// marshalValue marshals the params or a result with the JSONEngine of the package, without escaping HTML
+// with encoding/json if the config sets so.
+// Returns the raw JSON or an error
+func (c *marshalConfig) marshalValue(value any) (json.RawMessage, error) {
+	engine := currentJSONEngine()
+	if _, ok := engine.(stdJSONEngine); !ok || c.escapeHTML {
+		return marshalJSONValue(engine, value)
+	}
+
+	var buffer bytes.Buffer
+	encoder := json.NewEncoder(&buffer)
+	encoder.SetEscapeHTML(false)
+	if err := encoder.Encode(value); err != nil {
+		return nil, err
+	}
+	// Encode appends a newline which is not part of the value
+	return buffer.Bytes()[:buffer.Len()-1], nil
+}
+
+// appendFormatted appends the compact encoding of a message, formatted and terminated as the config sets, to dst.
+// Returns the extended buffer or an error
+func (c *marshalConfig) appendFormatted(dst, body []byte) ([]byte, error) {
+	if c.indented {
+		buffer := bytes.NewBuffer(dst)
+		if err := json.Indent(buffer, body, c.prefix, c.indent); err != nil {
+			return nil, err
+		}
+		dst = buffer.Bytes()
+	} else {
+		dst = append(dst, body...)
+	}
+	return append(dst, c.delimiter...), nil
+}
@@ -0,0 +1,143 @@
+/*  Copyright 2022  Kosmas Valianos (kosmas.valianos@gmail.com)
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    https://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License. */
+
+package gojsonrpc
+
+import (
+	"bytes"
+	"encoding/json"
+	"testing"
+)
+
+func TestMarshalOptions(t *testing.T) {
+	tests := []struct {
+		name    string
+		marshal func(options ...MarshalOption) ([]byte, error)
+		options []MarshalOption
+		want    []byte
+	}{
+		{
+			name: "NewRequest by default",
+			marshal: func(options ...MarshalOption) ([]byte, error) {
+				return NewRequest("subtract", []int{42, 23}, 1, options...)
+			},
+			want: []byte(`{"jsonrpc":"2.0","method":"subtract","params":[42,23],"id":1}` + "\n"),
+		},
+		{
+			name: "NewRequest without trailing newline",
+			marshal: func(options ...MarshalOption) ([]byte, error) {
+				return NewRequest("subtract", []int{42, 23}, 1, options...)
+			},
+			options: []MarshalOption{WithoutTrailingNewline()},
+			want:    []byte(`{"jsonrpc":"2.0","method":"subtract","params":[42,23],"id":1}`),
+		},
+		{
+			name:    "NewNotification with delimiter",
+			marshal: func(options ...MarshalOption) ([]byte, error) { return NewNotification("update", nil, options...) },
+			options: []MarshalOption{WithDelimiter("\r\n")},
+			want:    []byte(`{"jsonrpc":"2.0","method":"update"}` + "\r\n"),
+		},
+		{
+			name:    "NewResultResponse without trailing newline",
+			marshal: func(options ...MarshalOption) ([]byte, error) { return NewResultResponse("abc", 19, options...) },
+			options: []MarshalOption{WithoutTrailingNewline()},
+			want:    []byte(`{"jsonrpc":"2.0","result":19,"id":"abc"}`),
+		},
+		{
+			name: "NewErrorResponse with the last delimiter",
+			marshal: func(options ...MarshalOption) ([]byte, error) {
+				return NewErrorResponse(nil, &JsonParseError, options...)
+			},
+			options: []MarshalOption{WithoutTrailingNewline(), WithDelimiter("\x1e")},
+			want:    []byte(`{"jsonrpc":"2.0","error":{"code":-32700,"message":"Parse error"},"id":null}` + "\x1e"),
+		},
+		{
+			name:    "NewResultResponse indented",
+			marshal: func(options ...MarshalOption) ([]byte, error) { return NewResultResponse(1, []int{19, 20}, options...) },
+			options: []MarshalOption{WithIndent("", "  ")},
+			want:    []byte("{\n  \"jsonrpc\": \"2.0\",\n  \"result\": [\n    19,\n    20\n  ],\n  \"id\": 1\n}\n"),
+		},
+		{
+			name: "NewRequest indented without trailing newline",
+			marshal: func(options ...MarshalOption) ([]byte, error) {
+				return NewRequest("subtract", json.RawMessage(`{"minuend": 42}`), "abc", options...)
+			},
+			options: []MarshalOption{WithIndent("> ", "\t"), WithoutTrailingNewline()},
+			want:    []byte("{\n> \t\"jsonrpc\": \"2.0\",\n> \t\"method\": \"subtract\",\n> \t\"params\": {\n> \t\t\"minuend\": 42\n> \t},\n> \t\"id\": \"abc\"\n> }"),
+		},
+		{
+			name:    "NewResultResponse escaping HTML by default",
+			marshal: func(options ...MarshalOption) ([]byte, error) { return NewResultResponse(1, "a=1&b=<2>", options...) },
+			want:    []byte(`{"jsonrpc":"2.0","result":"a=1\u0026b=\u003c2\u003e","id":1}` + "\n"),
+		},
+		{
+			name: "NewResultResponse without HTML escaping",
+			marshal: func(options ...MarshalOption) ([]byte, error) {
+				return NewResultResponse(1, map[string]string{"url": "https://example.com/?a=1&b=<2>"}, options...)
+			},
+			options: []MarshalOption{WithoutHTMLEscaping()},
+			want:    []byte(`{"jsonrpc":"2.0","result":{"url":"https://example.com/?a=1&b=<2>"},"id":1}` + "\n"),
+		},
+		{
+			name: "NewNotification with raw params without HTML escaping",
+			marshal: func(options ...MarshalOption) ([]byte, error) {
+				return NewNotification("render", json.RawMessage(`["<b>&</b>"]`), options...)
+			},
+			options: []MarshalOption{WithoutHTMLEscaping()},
+			want:    []byte(`{"jsonrpc":"2.0","method":"render","params":["<b>&</b>"]}` + "\n"),
+		},
+		{
+			name: "NewErrorResponse with data without HTML escaping",
+			marshal: func(options ...MarshalOption) ([]byte, error) {
+				return NewErrorResponse(1, &jsonRPCError{Code: 1001, Message: "<invalid>", Data: json.RawMessage(`"a&b"`)}, options...)
+			},
+			options: []MarshalOption{WithoutHTMLEscaping()},
+			want:    []byte(`{"jsonrpc":"2.0","error":{"code":1001,"message":"<invalid>","data":"a&b"},"id":1}` + "\n"),
+		},
+		{
+			name: "AppendRequest without trailing newline",
+			marshal: func(options ...MarshalOption) ([]byte, error) {
+				return AppendRequest([]byte("prefix "), "subtract", nil, 1, options...)
+			},
+			options: []MarshalOption{WithoutTrailingNewline()},
+			want:    []byte(`prefix {"jsonrpc":"2.0","method":"subtract","id":1}`),
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := tt.marshal(tt.options...)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if !bytes.Equal(got, tt.want) {
+				t.Errorf("%v = %q, want %q", tt.name, got, tt.want)
+			}
+		})
+	}
+}
+
+func TestWithoutHTMLEscaping_pooled(t *testing.T) {
+	if _, err := NewNotification("<first>", nil, WithoutHTMLEscaping()); err != nil {
+		t.Fatal(err)
+	}
+	// The pooled encoder must escape again for the next message
+	got, err := NewNotification("<second>", nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := []byte(`{"jsonrpc":"2.0","method":"\u003csecond\u003e"}` + "\n")
+	if !bytes.Equal(got, want) {
+		t.Errorf("NewNotification() = %s, want %s", got, want)
+	}
+}
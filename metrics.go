@@ -0,0 +1,279 @@
+/*  Copyright 2022  Kosmas Valianos (kosmas.valianos@gmail.com)
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    https://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License. */
+
+package gojsonrpc
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"time"
+)
+
+// Role tells apart the measures of the calls served from those of the calls made
+type Role int
+
+// Roles of the measures taken by a Mux configured by WithMetrics and by a MetricsTransport
+const (
+	RoleServer Role = iota
+	RoleClient
+)
+
+// String implements String() of fmt.Stringer interface
+func (r Role) String() string {
+	if r == RoleClient {
+		return "client"
+	}
+	return "server"
+}
+
+// MessageDirection tells apart the messages received from those sent
+type MessageDirection int
+
+// Directions of the messages measured
+const (
+	MessageReceived MessageDirection = iota
+	MessageSent
+)
+
+// String implements String() of fmt.Stringer interface
+func (d MessageDirection) String() string {
+	if d == MessageSent {
+		return "sent"
+	}
+	return "received"
+}
+
+// Metrics receives the measures of the calls served by a Mux or made through a MetricsTransport, e.g. to export them
+// to Prometheus with PrometheusMetrics. A Mux measures the calls of the methods which are not registered, including
+// those served by the unknown methods' handler, with the method UnknownMethod, so that its peers cannot make
+// the measures grow with every method name they send. It must be safe for concurrent use
+type Metrics interface {
+	// CallStarted is called when a request or a notification starts being served or is sent
+	CallStarted(role Role, method string)
+	// CallFinished is called once the call is answered, code being 0 for a result or a notification,
+	// the code of the error object otherwise
+	CallFinished(role Role, method string, code int, duration time.Duration)
+	// BatchSize is called with the number of the elements of a batch received or sent
+	BatchSize(role Role, size int)
+	// MessageSize is called with the size in bytes of a message, or of a batch, received or sent
+	MessageSize(role Role, direction MessageDirection, size int)
+}
+
+// UnknownMethod is the method of the measures of the calls served by a Mux for methods which are not registered
+const UnknownMethod = "unknown"
+
+// WithMetrics measures the calls served and the messages received and sent with the metrics
+func WithMetrics(metrics Metrics) MuxOption {
+	return func(m *Mux) {
+		m.metrics = metrics
+	}
+}
+
+// serveObserved serves a message measuring its size, the size of its response and the size of a batch, and observing
+// its call, if not a batch, with the Metrics and the logger of the Mux
+func (m *Mux) serveObserved(ctx context.Context, messageRaw []byte) []byte {
+	ctx = context.WithValue(ctx, observedContextKey, true)
+	if jsonKind(messageRaw) != '[' {
+		if m.metrics == nil {
+			return m.serveObservedCall(ctx, messageRaw)
+		}
+		m.metrics.MessageSize(RoleServer, MessageReceived, len(messageRaw))
+		responseRaw := m.serveObservedCall(ctx, messageRaw)
+		if responseRaw != nil {
+			m.metrics.MessageSize(RoleServer, MessageSent, len(responseRaw))
+		}
+		return responseRaw
+	}
+
+	if m.metrics == nil {
+		return m.Serve(ctx, messageRaw)
+	}
+	m.metrics.MessageSize(RoleServer, MessageReceived, len(messageRaw))
+	if size, ok := batchSize(messageRaw); ok {
+		m.metrics.BatchSize(RoleServer, size)
+	}
+	responseRaw := m.Serve(ctx, messageRaw)
+	if responseRaw != nil {
+		m.metrics.MessageSize(RoleServer, MessageSent, len(responseRaw))
+	}
+	return responseRaw
+}
+
+// serveObservedCall serves a request or a notification measuring its call and logging it
+func (m *Mux) serveObservedCall(ctx context.Context, messageRaw []byte) []byte {
+	call := parseObservedCall(messageRaw)
+	measuredMethod := m.measuredMethod(call.Method)
+	if m.metrics != nil {
+		m.metrics.CallStarted(RoleServer, measuredMethod)
+	}
+	start := time.Now()
+	responseRaw := m.Serve(ctx, messageRaw)
+	duration := time.Since(start)
+	code, _ := responseErrorCode(responseRaw)
+	if m.metrics != nil {
+		m.metrics.CallFinished(RoleServer, measuredMethod, code, duration)
+	}
+	if m.logger != nil {
+		m.logger.logServed(ctx, call, code, duration)
+	}
+	return responseRaw
+}
+
+// measuredMethod returns the method if it is registered, UnknownMethod otherwise
+func (m *Mux) measuredMethod(method string) string {
+	if m.registered(method) || (m.cancelMethod != "" && method == m.cancelMethod) ||
+		(m.notifications != nil && m.notifications.registered(method)) {
+		return method
+	}
+	return UnknownMethod
+}
+
+// observedCall is the method, the id and the params of a request or a notification
+type observedCall struct {
+	Method string          `json:"method"`
+	ID     json.RawMessage `json:"id"`
+	Params json.RawMessage `json:"params"`
+}
+
+// parseObservedCall parses the members of a request or a notification which are observed, empty if it has none
+func parseObservedCall(messageRaw []byte) observedCall {
+	var call observedCall
+	_ = json.Unmarshal(messageRaw, &call)
+	return call
+}
+
+// batchSize returns the number of the elements of a batch
+func batchSize(batchRaw []byte) (int, bool) {
+	var elements []json.RawMessage
+	if json.Unmarshal(batchRaw, &elements) != nil {
+		return 0, false
+	}
+	return len(elements), true
+}
+
+// MetricsTransport is a Transport measuring the calls made and the messages sent and received, e.g. wrapping the
+// Transport of a Client. The calls of a batch are measured one by one, sharing the duration of the batch.
+// The calls failed without a response have the code of JsonRequestTimeout if they timed out,
+// of JsonUpstreamUnavailable otherwise
+type MetricsTransport struct {
+	transport Transport
+	metrics   Metrics
+}
+
+// NewMetricsTransport creates a MetricsTransport over the transport measuring with the metrics.
+// Returns a *MetricsTransport object
+func NewMetricsTransport(transport Transport, metrics Metrics) *MetricsTransport {
+	return &MetricsTransport{transport: transport, metrics: metrics}
+}
+
+// measuredCall is a call of a message sent through a MetricsTransport
+type measuredCall struct {
+	Method string          `json:"method"`
+	ID     json.RawMessage `json:"id"`
+}
+
+// RoundTrip sends a request or a batch measuring its calls.
+// Returns the raw bytes of the response or the error of the transport
+func (t *MetricsTransport) RoundTrip(ctx context.Context, requestRaw []byte) ([]byte, error) {
+	calls := t.start(requestRaw)
+	start := time.Now()
+	responseRaw, err := t.transport.RoundTrip(ctx, requestRaw)
+	duration := time.Since(start)
+	if err != nil {
+		code := upstreamError(ctx, err).Code
+		for _, call := range calls {
+			t.metrics.CallFinished(RoleClient, call.Method, code, duration)
+		}
+		return nil, err
+	}
+
+	t.metrics.MessageSize(RoleClient, MessageReceived, len(responseRaw))
+	if jsonKind(requestRaw) != '[' {
+		// A request may be answered with a null id, e.g. when it is invalid
+		code, _ := responseErrorCode(responseRaw)
+		t.metrics.CallFinished(RoleClient, calls[0].Method, code, duration)
+		return responseRaw, nil
+	}
+
+	var responses []struct {
+		ID    json.RawMessage `json:"id"`
+		Error *struct {
+			Code int `json:"code"`
+		} `json:"error"`
+	}
+	_ = json.Unmarshal(responseRaw, &responses)
+	codes := make(map[string]int, len(responses))
+	for _, response := range responses {
+		if response.Error != nil {
+			codes[string(compactID(response.ID))] = response.Error.Code
+		}
+	}
+	for _, call := range calls {
+		code := 0
+		if call.ID != nil {
+			code = codes[string(call.ID)]
+		}
+		t.metrics.CallFinished(RoleClient, call.Method, code, duration)
+	}
+	return responseRaw, nil
+}
+
+// Send sends a notification measuring its call
+func (t *MetricsTransport) Send(ctx context.Context, notificationRaw []byte) error {
+	calls := t.start(notificationRaw)
+	start := time.Now()
+	err := t.transport.Send(ctx, notificationRaw)
+	code := 0
+	if err != nil {
+		code = upstreamError(ctx, err).Code
+	}
+	for _, call := range calls {
+		t.metrics.CallFinished(RoleClient, call.Method, code, time.Since(start))
+	}
+	return err
+}
+
+// start measures a message sent and starts its calls, the requests and the notifications of a batch.
+// Returns the calls
+func (t *MetricsTransport) start(messageRaw []byte) []measuredCall {
+	t.metrics.MessageSize(RoleClient, MessageSent, len(messageRaw))
+	var calls []measuredCall
+	if jsonKind(messageRaw) == '[' {
+		if json.Unmarshal(messageRaw, &calls) == nil {
+			t.metrics.BatchSize(RoleClient, len(calls))
+		}
+	} else {
+		var call measuredCall
+		_ = json.Unmarshal(messageRaw, &call)
+		calls = append(calls, call)
+	}
+	for i := range calls {
+		calls[i].ID = compactID(calls[i].ID)
+		t.metrics.CallStarted(RoleClient, calls[i].Method)
+	}
+	return calls
+}
+
+// compactID compacts the raw id of a message so that it compares equal to the same id of another message
+func compactID(idRaw json.RawMessage) json.RawMessage {
+	if idRaw == nil {
+		return nil
+	}
+	var compacted bytes.Buffer
+	if json.Compact(&compacted, idRaw) != nil {
+		return idRaw
+	}
+	return compacted.Bytes()
+}
@@ -0,0 +1,203 @@
+/*  Copyright 2022  Kosmas Valianos (kosmas.valianos@gmail.com)
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    https://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License. */
+
+package gojsonrpc
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"reflect"
+	"sort"
+	"sync"
+	"testing"
+	"time"
+)
+
+// testMetrics records the measures, without the durations, as strings
+type testMetrics struct {
+	mu       sync.Mutex
+	measures []string
+}
+
+func (m *testMetrics) record(format string, args ...any) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	m.measures = append(m.measures, fmt.Sprintf(format, args...))
+}
+
+func (m *testMetrics) CallStarted(role Role, method string) {
+	m.record("%v started %v", role, method)
+}
+
+func (m *testMetrics) CallFinished(role Role, method string, code int, duration time.Duration) {
+	m.record("%v finished %v %v", role, method, code)
+}
+
+func (m *testMetrics) BatchSize(role Role, size int) {
+	m.record("%v batch %v", role, size)
+}
+
+func (m *testMetrics) MessageSize(role Role, direction MessageDirection, size int) {
+	m.record("%v %v %v bytes", role, direction, size)
+}
+
+// sorted returns the measures recorded sorted, since the elements of a batch are served concurrently
+func (m *testMetrics) sorted() []string {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	measures := append([]string(nil), m.measures...)
+	m.measures = nil
+	sort.Strings(measures)
+	return measures
+}
+
+func TestWithMetrics(t *testing.T) {
+	metrics := &testMetrics{}
+	mux := newTestMux(t)
+	WithMetrics(metrics)(mux)
+
+	tests := []struct {
+		name    string
+		request string
+		want    []string
+	}{
+		{
+			name:    "Request",
+			request: `{"jsonrpc":"2.0","method":"subtract","params":[42,23],"id":1}`,
+			want:    []string{"server finished subtract 0", "server started subtract"},
+		},
+		{
+			name:    "Error response",
+			request: `{"jsonrpc":"2.0","method":"database","id":1}`,
+			want:    []string{"server finished database -32602", "server started database"},
+		},
+		{
+			name:    "Method not found",
+			request: `{"jsonrpc":"2.0","method":"multiply","id":1}`,
+			want:    []string{"server finished unknown -32601", "server started unknown"},
+		},
+		{
+			name:    "Batch",
+			request: `[{"jsonrpc":"2.0","method":"subtract","params":[42,23],"id":1},{"jsonrpc":"2.0","method":"database","id":2}]`,
+			want: []string{
+				"server batch 2",
+				"server finished database -32602",
+				"server finished subtract 0",
+				"server started database",
+				"server started subtract",
+			},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			responseRaw := mux.Serve(context.Background(), []byte(tt.request))
+			want := append(tt.want, fmt.Sprintf("server received %v bytes", len(tt.request)), fmt.Sprintf("server sent %v bytes", len(responseRaw)))
+			sort.Strings(want)
+			if got := metrics.sorted(); !reflect.DeepEqual(got, want) {
+				t.Errorf("measures = %v, want %v", got, want)
+			}
+		})
+	}
+
+	// The methods served by the unknown methods' handler are not told apart either
+	mux.HandleUnknown(HandlerFunc(func(ctx context.Context, params json.RawMessage) (any, error) {
+		return MethodFromContext(ctx), nil
+	}))
+	requestRaw := `{"jsonrpc":"2.0","method":"multiply","id":1}`
+	responseRaw := mux.Serve(context.Background(), []byte(requestRaw))
+	want := []string{
+		"server finished unknown 0",
+		fmt.Sprintf("server received %v bytes", len(requestRaw)),
+		fmt.Sprintf("server sent %v bytes", len(responseRaw)),
+		"server started unknown",
+	}
+	if got := metrics.sorted(); !reflect.DeepEqual(got, want) {
+		t.Errorf("measures = %v, want %v", got, want)
+	}
+}
+
+// sizedTransport records the sizes of the messages carried by a Transport
+type sizedTransport struct {
+	Transport
+	sent, received int
+}
+
+func (t *sizedTransport) RoundTrip(ctx context.Context, requestRaw []byte) ([]byte, error) {
+	t.sent = len(requestRaw)
+	responseRaw, err := t.Transport.RoundTrip(ctx, requestRaw)
+	t.received = len(responseRaw)
+	return responseRaw, err
+}
+
+func TestMetricsTransport(t *testing.T) {
+	metrics := &testMetrics{}
+	transport := &sizedTransport{Transport: &muxTransport{mux: newTestMux(t)}}
+	client := NewClient(NewMetricsTransport(transport, metrics))
+
+	t.Run("Request", func(t *testing.T) {
+		if _, err := Call[int](context.Background(), client, "subtract", []int{42, 23}); err != nil {
+			t.Fatal(err)
+		}
+		want := []string{
+			"client finished subtract 0",
+			fmt.Sprintf("client received %v bytes", transport.received),
+			fmt.Sprintf("client sent %v bytes", transport.sent),
+			"client started subtract",
+		}
+		if got := metrics.sorted(); !reflect.DeepEqual(got, want) {
+			t.Errorf("measures = %v, want %v", got, want)
+		}
+	})
+
+	t.Run("Batch", func(t *testing.T) {
+		_, err := client.CallBatch(context.Background(), []BatchItem{
+			{Method: "subtract", Params: []int{42, 23}},
+			{Method: "database"},
+			{Method: "subtract", Params: []int{1, 2}, Notification: true},
+		})
+		if err != nil {
+			t.Fatal(err)
+		}
+		want := []string{
+			"client batch 3",
+			"client finished database -32602",
+			"client finished subtract 0",
+			"client finished subtract 0",
+			fmt.Sprintf("client received %v bytes", transport.received),
+			fmt.Sprintf("client sent %v bytes", transport.sent),
+			"client started database",
+			"client started subtract",
+			"client started subtract",
+		}
+		if got := metrics.sorted(); !reflect.DeepEqual(got, want) {
+			t.Errorf("measures = %v, want %v", got, want)
+		}
+	})
+
+	t.Run("Transport error", func(t *testing.T) {
+		client := NewClient(NewMetricsTransport(&downTransport{}, metrics))
+		if err := client.Call(context.Background(), "subtract", []int{42, 23}, nil); err == nil {
+			t.Fatal("Call() error = nil, want an error")
+		}
+		want := []string{
+			"client finished subtract -32004",
+			fmt.Sprintf("client sent %v bytes", len(`{"jsonrpc":"2.0","method":"subtract","params":[42,23],"id":1}`+"\n")),
+			"client started subtract",
+		}
+		if got := metrics.sorted(); !reflect.DeepEqual(got, want) {
+			t.Errorf("measures = %v, want %v", got, want)
+		}
+	})
+}
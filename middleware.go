@@ -0,0 +1,41 @@
+/*  Copyright 2022  Kosmas Valianos (kosmas.valianos@gmail.com)
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    https://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License. */
+
+package gojsonrpc
+
+import (
+	"context"
+	"encoding/json"
+	"runtime/debug"
+)
+
+// Recover creates a Middleware which recovers from panics in handlers and reports them as JsonInternalError.
+// The hook, if not nil, is called with the recovered value and the stack trace e.g. for logging
+func Recover(hook func(ctx context.Context, recovered any, stack []byte)) Middleware {
+	return func(next Handler) Handler {
+		return HandlerFunc(func(ctx context.Context, params json.RawMessage) (result any, err error) {
+			defer func() {
+				recovered := recover()
+				if recovered == nil {
+					return
+				}
+				if hook != nil {
+					hook(ctx, recovered, debug.Stack())
+				}
+				result, err = nil, &JsonInternalError
+			}()
+			return next.ServeJSONRPC(ctx, params)
+		})
+	}
+}
@@ -0,0 +1,97 @@
+/*  Copyright 2022  Kosmas Valianos (kosmas.valianos@gmail.com)
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    https://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License. */
+
+package gojsonrpc
+
+import (
+	"bytes"
+	"context"
+	"testing"
+)
+
+func TestRecover(t *testing.T) {
+	tests := []struct {
+		name          string
+		rawBytes      []byte
+		want          []byte
+		wantRecovered any
+	}{
+		{
+			name:     "Valid request",
+			rawBytes: []byte(`{"jsonrpc": "2.0", "method": "subtract", "params": [42, 23], "id": 1}`),
+			want:     []byte(`{"jsonrpc":"2.0","result":19,"id":1}` + "\n"),
+		},
+		{
+			name:          "Panic in handler",
+			rawBytes:      []byte(`{"jsonrpc": "2.0", "method": "panic", "id": 1}`),
+			want:          []byte(`{"jsonrpc":"2.0","error":{"code":-32603,"message":"Internal error"},"id":1}` + "\n"),
+			wantRecovered: "boom",
+		},
+		{
+			name:          "Panic in notification handler",
+			rawBytes:      []byte(`{"jsonrpc": "2.0", "method": "panic"}`),
+			wantRecovered: "boom",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var recovered any
+			var stack []byte
+			mux := newTestMux(t)
+			mux.Use(Recover(func(ctx context.Context, r any, s []byte) {
+				if MethodFromContext(ctx) != "panic" {
+					t.Errorf("Recover() method = %v, want %v", MethodFromContext(ctx), "panic")
+				}
+				recovered, stack = r, s
+			}))
+			err := HandleFunc(mux, "panic", func(ctx context.Context, params any) (any, error) {
+				panic("boom")
+			})
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			jsonRPCResponseRaw := mux.Serve(context.Background(), tt.rawBytes)
+			if !bytes.Equal(jsonRPCResponseRaw, tt.want) {
+				t.Errorf("Serve() = %v, want %v", string(jsonRPCResponseRaw), string(tt.want))
+			}
+
+			if recovered != tt.wantRecovered {
+				t.Errorf("Recover() recovered = %v, want %v", recovered, tt.wantRecovered)
+			}
+
+			if tt.wantRecovered != nil && len(stack) == 0 {
+				t.Error("Recover() passed no stack trace to the hook")
+			}
+		})
+	}
+
+	t.Run("No hook", func(t *testing.T) {
+		mux := NewMux()
+		mux.Use(Recover(nil))
+		err := HandleFunc(mux, "panic", func(ctx context.Context, params any) (any, error) {
+			panic("boom")
+		})
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		jsonRPCResponseRaw := mux.Serve(context.Background(), []byte(`{"jsonrpc": "2.0", "method": "panic", "id": "abc"}`))
+		want := []byte(`{"jsonrpc":"2.0","error":{"code":-32603,"message":"Internal error"},"id":"abc"}` + "\n")
+		if !bytes.Equal(jsonRPCResponseRaw, want) {
+			t.Errorf("Serve() = %v, want %v", string(jsonRPCResponseRaw), string(want))
+		}
+	})
+}
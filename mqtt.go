@@ -0,0 +1,116 @@
+/*  Copyright 2022  Kosmas Valianos (kosmas.valianos@gmail.com)
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    https://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License. */
+
+package gojsonrpc
+
+import (
+	"context"
+	"errors"
+	"strings"
+)
+
+// MQTTMessage is a message received from MQTT
+type MQTTMessage struct {
+	Topic   string
+	Payload []byte
+}
+
+// MQTTClient is the session with the MQTT broker of the MQTT transport, e.g. a small wrapper of a paho.mqtt.golang
+// client publishing with the QoS of the application and unsubscribing with the token returned by Unsubscribe
+type MQTTClient interface {
+	// Publish publishes the payload to the topic
+	Publish(topic string, payload []byte) error
+	// Subscribe passes the messages published to the topic filter to the handler, in their order, until unsubscribed
+	Subscribe(topic string, handler func(msg MQTTMessage)) (unsubscribe func() error, err error)
+}
+
+// ErrInvalidMQTTClientID is returned when dialing with a client ID which is not a single topic level
+var ErrInvalidMQTTClientID = errors.New("invalid MQTT client ID")
+
+// The topics of a JSON-RPC service under its prefix
+const (
+	mqttRequestTopic      = "/request/"
+	mqttResponseTopic     = "/response/"
+	mqttNotificationTopic = "/notification"
+)
+
+// ServeMQTT serves the requests and notifications of the clients with the mux until the ctx is done. The clients publish
+// them to "<prefix>/request/<client ID>" and receive the responses on "<prefix>/response/<client ID>". The ID of the
+// client is given to the handlers by MQTTClientIDFromContext.
+// Returns the error of the subscription or of the unsubscription
+func ServeMQTT(ctx context.Context, client MQTTClient, prefix string, mux *Mux) error {
+	unsubscribe, err := client.Subscribe(prefix+mqttRequestTopic+"+", func(msg MQTTMessage) {
+		clientID := strings.TrimPrefix(msg.Topic, prefix+mqttRequestTopic)
+		requestCtx := context.WithValue(ctx, mqttClientIDContextKey, clientID)
+		serveBrokerMessage(requestCtx, mux, msg.Payload, func(responseRaw []byte) {
+			_ = client.Publish(prefix+mqttResponseTopic+clientID, responseRaw)
+		})
+	})
+	if err != nil {
+		return err
+	}
+	<-ctx.Done()
+	return unsubscribe()
+}
+
+// MQTTClientIDFromContext returns the ID of the MQTT client which sent the request or notification being served, if any
+func MQTTClientIDFromContext(ctx context.Context) (string, bool) {
+	clientID, ok := ctx.Value(mqttClientIDContextKey).(string)
+	return clientID, ok
+}
+
+// NotifyMQTT sends a notification of the method with the params to the client with the ID on its response topic
+func NotifyMQTT(client MQTTClient, prefix string, clientID string, method string, params any) error {
+	notificationRaw, err := NewNotification(method, params)
+	if err != nil {
+		return err
+	}
+	return client.Publish(prefix+mqttResponseTopic+clientID, notificationRaw)
+}
+
+// BroadcastMQTT sends a notification of the method with the params to all the clients on "<prefix>/notification"
+func BroadcastMQTT(client MQTTClient, prefix string, method string, params any) error {
+	notificationRaw, err := NewNotification(method, params)
+	if err != nil {
+		return err
+	}
+	return client.Publish(prefix+mqttNotificationTopic, notificationRaw)
+}
+
+// DialMQTT creates a Conn of the client with the ID, a single topic level, calling the service under the prefix.
+// The notifications sent to the client or broadcast to all clients are served with the mux, which may be nil.
+// Closing the Conn unsubscribes from the topics of the client.
+// Returns a *Conn object or an error
+func DialMQTT(client MQTTClient, prefix string, clientID string, mux *Mux, options ...ClientOption) (*Conn, error) {
+	if clientID == "" || strings.ContainsAny(clientID, "/+#") {
+		return nil, ErrInvalidMQTTClientID
+	}
+	mqttConn := newBrokerConn(func(ctx context.Context, messageRaw []byte) error {
+		return client.Publish(prefix+mqttRequestTopic+clientID, messageRaw)
+	})
+	handler := func(msg MQTTMessage) {
+		mqttConn.deliver(msg.Payload)
+	}
+	unsubscribeResponses, err := client.Subscribe(prefix+mqttResponseTopic+clientID, handler)
+	if err != nil {
+		return nil, err
+	}
+	unsubscribeNotifications, err := client.Subscribe(prefix+mqttNotificationTopic, handler)
+	if err != nil {
+		_ = unsubscribeResponses()
+		return nil, err
+	}
+	mqttConn.unsubscribe = unsubscribeAll(unsubscribeResponses, unsubscribeNotifications)
+	return NewConn(mqttConn, mux, options...), nil
+}
@@ -0,0 +1,175 @@
+/*  Copyright 2022  Kosmas Valianos (kosmas.valianos@gmail.com)
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    https://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License. */
+
+package gojsonrpc
+
+import (
+	"context"
+	"errors"
+	"sort"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+)
+
+// memMQTT is an in-memory MQTT broker matching the topic filters with single-level wildcards
+type memMQTT struct {
+	mu     sync.Mutex
+	subs   map[int]memMQTTSubscription
+	nextID int
+}
+
+type memMQTTSubscription struct {
+	filter  string
+	handler func(MQTTMessage)
+}
+
+func newMemMQTT() *memMQTT {
+	return &memMQTT{subs: make(map[int]memMQTTSubscription)}
+}
+
+func mqttTopicMatches(filter string, topic string) bool {
+	filterLevels, topicLevels := strings.Split(filter, "/"), strings.Split(topic, "/")
+	if len(filterLevels) != len(topicLevels) {
+		return false
+	}
+	for i := range filterLevels {
+		if filterLevels[i] != "+" && filterLevels[i] != topicLevels[i] {
+			return false
+		}
+	}
+	return true
+}
+
+func (m *memMQTT) Publish(topic string, payload []byte) error {
+	m.mu.Lock()
+	var handlers []func(MQTTMessage)
+	for _, sub := range m.subs {
+		if mqttTopicMatches(sub.filter, topic) {
+			handlers = append(handlers, sub.handler)
+		}
+	}
+	m.mu.Unlock()
+	for _, handler := range handlers {
+		handler(MQTTMessage{Topic: topic, Payload: payload})
+	}
+	return nil
+}
+
+func (m *memMQTT) Subscribe(topic string, handler func(msg MQTTMessage)) (func() error, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	m.nextID++
+	id := m.nextID
+	m.subs[id] = memMQTTSubscription{filter: topic, handler: handler}
+	return func() error {
+		m.mu.Lock()
+		defer m.mu.Unlock()
+		delete(m.subs, id)
+		return nil
+	}, nil
+}
+
+func (m *memMQTT) subscriptions() int {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	return len(m.subs)
+}
+
+func TestDialMQTT(t *testing.T) {
+	broker := newMemMQTT()
+	mux := newTestMux(t)
+	err := HandleFunc(mux, "hello", func(ctx context.Context, params any) (string, error) {
+		clientID, _ := MQTTClientIDFromContext(ctx)
+		return clientID, NotifyMQTT(broker, "devices", clientID, "greeting", []string{"hello " + clientID})
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	ctx, cancel := context.WithCancel(context.Background())
+	served := make(chan error, 1)
+	go func() {
+		served <- ServeMQTT(ctx, broker, "devices", mux)
+	}()
+	for broker.subscriptions() == 0 {
+		time.Sleep(time.Millisecond)
+	}
+
+	greetings := make(chan string, 4)
+	clientMux := NewMux()
+	err = HandleFunc(clientMux, "greeting", func(ctx context.Context, params [1]string) (any, error) {
+		greetings <- params[0]
+		return nil, nil
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	conn, err := DialMQTT(broker, "devices", "sensor-1", clientMux)
+	if err != nil {
+		t.Fatal(err)
+	}
+	var result int
+	err = conn.Call(context.Background(), "subtract", []int{42, 23}, &result)
+	if err != nil || result != 19 {
+		t.Errorf("Call() = %v, %v, want %v", result, err, 19)
+	}
+	var clientID string
+	err = conn.Call(context.Background(), "hello", nil, &clientID)
+	if err != nil || clientID != "sensor-1" {
+		t.Errorf("Call() = %v, %v, want %v", clientID, err, "sensor-1")
+	}
+	err = BroadcastMQTT(broker, "devices", "greeting", []string{"hello all"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	// The notifications are served concurrently
+	got := make([]string, 0, 2)
+	for len(got) < 2 {
+		select {
+		case greeting := <-greetings:
+			got = append(got, greeting)
+		case <-time.After(5 * time.Second):
+			t.Fatalf("greetings = %q, want 2", got)
+		}
+	}
+	sort.Strings(got)
+	if got[0] != "hello all" || got[1] != "hello sensor-1" {
+		t.Errorf("greetings = %q, want %q", got, []string{"hello all", "hello sensor-1"})
+	}
+
+	err = conn.Close()
+	if err != nil {
+		t.Errorf("Close() = %v, want nil", err)
+	}
+	if broker.subscriptions() != 1 {
+		t.Errorf("subscriptions() = %v, want %v", broker.subscriptions(), 1)
+	}
+	cancel()
+	if err := <-served; err != nil {
+		t.Errorf("ServeMQTT() = %v, want nil", err)
+	}
+	if broker.subscriptions() != 0 {
+		t.Errorf("subscriptions() = %v, want %v", broker.subscriptions(), 0)
+	}
+}
+
+func TestDialMQTT_clientID(t *testing.T) {
+	for _, clientID := range []string{"", "a/b", "sensor+", "#"} {
+		_, err := DialMQTT(newMemMQTT(), "devices", clientID, nil)
+		if !errors.Is(err, ErrInvalidMQTTClientID) {
+			t.Errorf("DialMQTT(%q) = %v, want %v", clientID, err, ErrInvalidMQTTClientID)
+		}
+	}
+}
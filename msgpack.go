@@ -0,0 +1,329 @@
+/*  Copyright 2022  Kosmas Valianos (kosmas.valianos@gmail.com)
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    https://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License. */
+
+package gojsonrpc
+
+import (
+	"encoding/binary"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"math"
+	"strconv"
+)
+
+// MessagePackCodec encodes the messages in MessagePack, e.g. for the deployments sensitive to the bandwidth.
+// The members of the messages keep their JSON names and order. The integers are encoded as such and the other
+// numbers as 64-bit floats. The binary strings are decoded as base64 strings
+var MessagePackCodec Codec = messagePackCodec{}
+
+type messagePackCodec struct{}
+
+var errMessagePackTruncated = errors.New("msgpack: truncated data")
+
+// ContentType returns "application/msgpack"
+func (messagePackCodec) ContentType() string {
+	return "application/msgpack"
+}
+
+// Marshal encodes a message given in JSON in MessagePack
+func (messagePackCodec) Marshal(messageRaw []byte) ([]byte, error) {
+	value, err := parseJSONValue(messageRaw)
+	if err != nil {
+		return nil, err
+	}
+	return appendMessagePack(nil, value)
+}
+
+// Unmarshal decodes a message in MessagePack to JSON
+func (messagePackCodec) Unmarshal(data []byte) ([]byte, error) {
+	decoder := messagePackDecoder{data: data}
+	value, err := decoder.decode(0)
+	if err != nil {
+		return nil, err
+	}
+	if decoder.offset != len(data) {
+		return nil, errors.New("msgpack: data after the message")
+	}
+	return appendJSONValue(nil, value)
+}
+
+// appendMessagePack appends the MessagePack encoding of a JSON value
+func appendMessagePack(dst []byte, value any) ([]byte, error) {
+	switch value := value.(type) {
+	case nil:
+		return append(dst, 0xc0), nil
+	case bool:
+		if value {
+			return append(dst, 0xc3), nil
+		}
+		return append(dst, 0xc2), nil
+	case json.Number:
+		return appendMessagePackNumber(dst, value)
+	case string:
+		dst = appendMessagePackLength(dst, len(value), 0xa0, 32, 0xd9, 0xda, 0xdb)
+		return append(dst, value...), nil
+	case []any:
+		dst = appendMessagePackLength(dst, len(value), 0x90, 16, 0, 0xdc, 0xdd)
+		for _, element := range value {
+			var err error
+			dst, err = appendMessagePack(dst, element)
+			if err != nil {
+				return nil, err
+			}
+		}
+		return dst, nil
+	case jsonObject:
+		dst = appendMessagePackLength(dst, len(value), 0x80, 16, 0, 0xde, 0xdf)
+		for _, member := range value {
+			var err error
+			dst, err = appendMessagePack(dst, member.key)
+			if err != nil {
+				return nil, err
+			}
+			dst, err = appendMessagePack(dst, member.value)
+			if err != nil {
+				return nil, err
+			}
+		}
+		return dst, nil
+	default:
+		return nil, fmt.Errorf("msgpack: unsupported value %T", value)
+	}
+}
+
+// appendMessagePackNumber appends an integer in its smallest encoding, any other number as a 64-bit float
+func appendMessagePackNumber(dst []byte, number json.Number) ([]byte, error) {
+	if i, err := strconv.ParseInt(string(number), 10, 64); err == nil {
+		switch {
+		case i >= 0 && i <= math.MaxInt8:
+			return append(dst, byte(i)), nil
+		case i >= 0:
+			return appendMessagePackUint(dst, uint64(i)), nil
+		case i >= -32:
+			return append(dst, byte(int8(i))), nil
+		case i >= math.MinInt8:
+			return append(dst, 0xd0, byte(int8(i))), nil
+		case i >= math.MinInt16 && i <= math.MaxInt16:
+			return binary.BigEndian.AppendUint16(append(dst, 0xd1), uint16(int16(i))), nil
+		case i >= math.MinInt32 && i <= math.MaxInt32:
+			return binary.BigEndian.AppendUint32(append(dst, 0xd2), uint32(int32(i))), nil
+		default:
+			return binary.BigEndian.AppendUint64(append(dst, 0xd3), uint64(i)), nil
+		}
+	}
+	if u, err := strconv.ParseUint(string(number), 10, 64); err == nil {
+		return appendMessagePackUint(dst, u), nil
+	}
+	f, err := strconv.ParseFloat(string(number), 64)
+	if err != nil {
+		return nil, err
+	}
+	return binary.BigEndian.AppendUint64(append(dst, 0xcb), math.Float64bits(f)), nil
+}
+
+// appendMessagePackUint appends an unsigned integer above the positive fixints
+func appendMessagePackUint(dst []byte, u uint64) []byte {
+	switch {
+	case u <= math.MaxUint8:
+		return append(dst, 0xcc, byte(u))
+	case u <= math.MaxUint16:
+		return binary.BigEndian.AppendUint16(append(dst, 0xcd), uint16(u))
+	case u <= math.MaxUint32:
+		return binary.BigEndian.AppendUint32(append(dst, 0xce), uint32(u))
+	default:
+		return binary.BigEndian.AppendUint64(append(dst, 0xcf), u)
+	}
+}
+
+// appendMessagePackLength appends the header of a string, an array or a map of the length: the fix type combined with
+// the length below fixLimit, else the 8-bit, if any, 16-bit or 32-bit type followed by the length
+func appendMessagePackLength(dst []byte, length int, fixType byte, fixLimit int, type8, type16, type32 byte) []byte {
+	switch {
+	case length < fixLimit:
+		return append(dst, fixType|byte(length))
+	case type8 != 0 && length <= math.MaxUint8:
+		return append(dst, type8, byte(length))
+	case length <= math.MaxUint16:
+		return binary.BigEndian.AppendUint16(append(dst, type16), uint16(length))
+	default:
+		return binary.BigEndian.AppendUint32(append(dst, type32), uint32(length))
+	}
+}
+
+// messagePackDecoder decodes MessagePack to JSON values
+type messagePackDecoder struct {
+	data   []byte
+	offset int
+}
+
+// read returns the next n bytes
+func (d *messagePackDecoder) read(n int) ([]byte, error) {
+	if n < 0 || n > len(d.data)-d.offset {
+		return nil, errMessagePackTruncated
+	}
+	b := d.data[d.offset : d.offset+n]
+	d.offset += n
+	return b, nil
+}
+
+// readUint reads a big-endian unsigned integer of size bytes
+func (d *messagePackDecoder) readUint(size int) (uint64, error) {
+	b, err := d.read(size)
+	if err != nil {
+		return 0, err
+	}
+	var u uint64
+	for _, c := range b {
+		u = u<<8 | uint64(c)
+	}
+	return u, nil
+}
+
+// readLength reads a length of size bytes
+func (d *messagePackDecoder) readLength(size int) (int, error) {
+	u, err := d.readUint(size)
+	if err != nil {
+		return 0, err
+	}
+	if u > uint64(len(d.data)-d.offset) {
+		// Every element takes a byte at least
+		return 0, errMessagePackTruncated
+	}
+	return int(u), nil
+}
+
+// decode decodes the next value
+func (d *messagePackDecoder) decode(depth int) (any, error) {
+	typeRaw, err := d.read(1)
+	if err != nil {
+		return nil, err
+	}
+	t := typeRaw[0]
+	switch {
+	case t <= 0x7f:
+		return int64(t), nil
+	case t >= 0xe0:
+		return int64(int8(t)), nil
+	case t&0xe0 == 0xa0:
+		return d.decodeString(int(t & 0x1f))
+	case t&0xf0 == 0x90:
+		return d.decodeArray(int(t&0x0f), depth)
+	case t&0xf0 == 0x80:
+		return d.decodeMap(int(t&0x0f), depth)
+	}
+
+	switch t {
+	case 0xc0:
+		return nil, nil
+	case 0xc2:
+		return false, nil
+	case 0xc3:
+		return true, nil
+	case 0xc4, 0xc5, 0xc6:
+		length, err := d.readLength(1 << (t - 0xc4))
+		if err != nil {
+			return nil, err
+		}
+		b, err := d.read(length)
+		return append([]byte(nil), b...), err
+	case 0xca:
+		u, err := d.readUint(4)
+		return float64(math.Float32frombits(uint32(u))), err
+	case 0xcb:
+		u, err := d.readUint(8)
+		return math.Float64frombits(u), err
+	case 0xcc, 0xcd, 0xce, 0xcf:
+		return d.readUint(1 << (t - 0xcc))
+	case 0xd0, 0xd1, 0xd2, 0xd3:
+		size := 1 << (t - 0xd0)
+		u, err := d.readUint(size)
+		// Sign-extends the integer
+		shift := 64 - 8*size
+		return int64(u<<shift) >> shift, err
+	case 0xd9, 0xda, 0xdb:
+		length, err := d.readLength(1 << (t - 0xd9))
+		if err != nil {
+			return nil, err
+		}
+		return d.decodeString(length)
+	case 0xdc, 0xdd:
+		length, err := d.readLength(2 << (t - 0xdc))
+		if err != nil {
+			return nil, err
+		}
+		return d.decodeArray(length, depth)
+	case 0xde, 0xdf:
+		length, err := d.readLength(2 << (t - 0xde))
+		if err != nil {
+			return nil, err
+		}
+		return d.decodeMap(length, depth)
+	default:
+		return nil, fmt.Errorf("msgpack: unsupported type 0x%02x", t)
+	}
+}
+
+func (d *messagePackDecoder) decodeString(length int) (any, error) {
+	b, err := d.read(length)
+	if err != nil {
+		return nil, err
+	}
+	return string(b), nil
+}
+
+func (d *messagePackDecoder) decodeArray(length int, depth int) (any, error) {
+	if depth >= maxValueDepth {
+		return nil, errValueTooDeep
+	}
+	array := make([]any, 0, length)
+	for i := 0; i < length; i++ {
+		element, err := d.decode(depth + 1)
+		if err != nil {
+			return nil, err
+		}
+		array = append(array, element)
+	}
+	return array, nil
+}
+
+func (d *messagePackDecoder) decodeMap(length int, depth int) (any, error) {
+	if depth >= maxValueDepth {
+		return nil, errValueTooDeep
+	}
+	object := make(jsonObject, 0, length)
+	for i := 0; i < length; i++ {
+		key, err := d.decode(depth + 1)
+		if err != nil {
+			return nil, err
+		}
+		value, err := d.decode(depth + 1)
+		if err != nil {
+			return nil, err
+		}
+		member := jsonMember{value: value}
+		switch key := key.(type) {
+		case string:
+			member.key = key
+		case int64:
+			member.key = strconv.FormatInt(key, 10)
+		case uint64:
+			member.key = strconv.FormatUint(key, 10)
+		default:
+			return nil, fmt.Errorf("msgpack: unsupported map key %T", key)
+		}
+		object = append(object, member)
+	}
+	return object, nil
+}
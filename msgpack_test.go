@@ -0,0 +1,119 @@
+/*  Copyright 2022  Kosmas Valianos (kosmas.valianos@gmail.com)
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    https://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License. */
+
+package gojsonrpc
+
+import (
+	"context"
+	"encoding/hex"
+	"strings"
+	"testing"
+)
+
+func TestMessagePackCodec(t *testing.T) {
+	tests := []struct {
+		name        string
+		messageRaw  string
+		wantEncoded string
+	}{
+		{"null", `null`, "c0"},
+		{"true", `true`, "c3"},
+		{"positive fixint", `42`, "2a"},
+		{"negative fixint", `-1`, "ff"},
+		{"uint8", `200`, "ccc8"},
+		{"uint32", `65536`, "ce00010000"},
+		{"uint64", `18446744073709551615`, "cfffffffffffffffff"},
+		{"int8", `-33`, "d0df"},
+		{"int16", `-129`, "d1ff7f"},
+		{"float", `1.5`, "cb3ff8000000000000"},
+		{"string", `"é"`, "a2c3a9"},
+		{"empty array", `[]`, "90"},
+		{"empty object", `{}`, "80"},
+		{
+			"request",
+			`{"jsonrpc":"2.0","method":"subtract","params":[42,23],"id":1}`,
+			"84a76a736f6e727063a3322e30a66d6574686f64a87375627472616374a6706172616d73922a17a2696401",
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			encoded, err := MessagePackCodec.Marshal([]byte(tt.messageRaw))
+			if err != nil {
+				t.Fatalf("Marshal() error = %v", err)
+			}
+			if hex.EncodeToString(encoded) != tt.wantEncoded {
+				t.Errorf("Marshal() = %x, want %v", encoded, tt.wantEncoded)
+			}
+			decoded, err := MessagePackCodec.Unmarshal(encoded)
+			if err != nil || string(decoded) != tt.messageRaw {
+				t.Errorf("Unmarshal() = %s, %v, want %s", decoded, err, tt.messageRaw)
+			}
+		})
+	}
+}
+
+func TestMessagePackCodec_Unmarshal(t *testing.T) {
+	tests := []struct {
+		name    string
+		encoded string
+		want    string
+		wantErr bool
+	}{
+		{"binary", "c4020102", `"AQI="`, false},
+		{"float32", "ca3fc00000", `1.5`, false},
+		{"integer key", "8101c3", `{"1":true}`, false},
+		{"str8", "d903616263", `"abc"`, false},
+		{"array16", "dc0002c0c0", `[null,null]`, false},
+		{"truncated", "a3616263"[:6], "", true},
+		{"length beyond the data", "ddffffffff", "", true},
+		{"extension", "d40100", "", true},
+		{"array key", "8190c0", "", true},
+		{"trailing data", "c0c0", "", true},
+		{"empty", "", "", true},
+		{"too deep", strings.Repeat("91", maxValueDepth+1) + "c0", "", true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			encoded, err := hex.DecodeString(tt.encoded)
+			if err != nil {
+				t.Fatal(err)
+			}
+			decoded, err := MessagePackCodec.Unmarshal(encoded)
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("Unmarshal() error = %v, wantErr %v", err, tt.wantErr)
+			}
+			if string(decoded) != tt.want {
+				t.Errorf("Unmarshal() = %s, want %s", decoded, tt.want)
+			}
+		})
+	}
+}
+
+func TestMessagePackCodec_conn(t *testing.T) {
+	serverEnd, clientEnd := Pipe()
+	server := NewConn(NewCodecConn(serverEnd, MessagePackCodec), newTestMux(t))
+	defer server.Close()
+	client := NewConn(NewCodecConn(clientEnd, MessagePackCodec), nil)
+	defer client.Close()
+
+	var result int
+	err := client.Call(context.Background(), "subtract", []int{42, 23}, &result)
+	if err != nil || result != 19 {
+		t.Errorf("Call() = %v, %v, want %v", result, err, 19)
+	}
+	err = client.Call(context.Background(), "database", nil, &result)
+	if err == nil || err.Error() != JsonInvalidMethodParameters.Error() {
+		t.Errorf("Call() = %v, want %v", err, &JsonInvalidMethodParameters)
+	}
+}
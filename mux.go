@@ -0,0 +1,659 @@
+/*  Copyright 2022  Kosmas Valianos (kosmas.valianos@gmail.com)
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    https://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License. */
+
+package gojsonrpc
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"reflect"
+	"strings"
+	"sync"
+	"time"
+)
+
+// Handler handles the params of a JSON-RPC request or notification dispatched by a Mux.
+// The result is marshaled into the response while the error is mapped to a jsonRPCError
+type Handler interface {
+	ServeJSONRPC(ctx context.Context, params json.RawMessage) (any, error)
+}
+
+// HandlerFunc is an adapter to allow the use of ordinary functions as a Handler
+type HandlerFunc func(ctx context.Context, params json.RawMessage) (any, error)
+
+// ServeJSONRPC implements Handler by calling f(ctx, params)
+func (f HandlerFunc) ServeJSONRPC(ctx context.Context, params json.RawMessage) (any, error) {
+	return f(ctx, params)
+}
+
+// Middleware wraps a Handler to add cross-cutting behaviour such as logging, authentication or metrics
+type Middleware func(next Handler) Handler
+
+type contextKey int
+
+const (
+	methodContextKey contextKey = iota
+	idContextKey
+	methodFilterContextKey
+	fieldNamingContextKey
+	connContextKey
+	eventSessionContextKey
+	mqttClientIDContextKey
+	peerCertificateContextKey
+	jsonEngineContextKey
+	observedContextKey
+)
+
+// MethodFromContext returns the method of the request or notification being served
+func MethodFromContext(ctx context.Context) string {
+	method, _ := ctx.Value(methodContextKey).(string)
+	return method
+}
+
+// IDFromContext returns the id of the request being served or nil in case of a notification
+func IDFromContext(ctx context.Context) any {
+	return ctx.Value(idContextKey)
+}
+
+// MethodFilter reports whether a method is visible, e.g. to a session given its capabilities or roles
+type MethodFilter func(method string) bool
+
+// ContextWithMethodFilter returns a copy of ctx carrying the filter. When passed to Serve, only the visible methods
+// are routed, the rest being answered with JsonMethodNotFound, and only those are listed by "rpc.discover"
+func ContextWithMethodFilter(ctx context.Context, filter MethodFilter) context.Context {
+	return context.WithValue(ctx, methodFilterContextKey, filter)
+}
+
+// methodVisible reports whether the method is visible through the filter carried by ctx, if any
+func methodVisible(ctx context.Context, method string) bool {
+	filter, ok := ctx.Value(methodFilterContextKey).(MethodFilter)
+	return !ok || filter == nil || filter(method)
+}
+
+// Mux is a JSON-RPC request router. It dispatches requests and notifications to the Handler registered for their method.
+// Methods can be registered and unregistered while serving
+type Mux struct {
+	mu             sync.RWMutex
+	handlers       map[string]Handler
+	builtins       map[string]Handler
+	methodInfos    map[string]MethodInfo
+	mounts         map[string]*Mux
+	unknown        Handler
+	middlewares    []Middleware
+	timeouts       map[string]time.Duration
+	requestTimeout time.Duration
+	timeoutError   *jsonRPCError
+	slots          chan struct{}
+	rejectWhenBusy bool
+	openRPCInfo    OpenRPCInfo
+	fieldNaming    FieldNaming
+	lazyParsing    bool
+	jsonEngine     JSONEngine
+	maxMessageSize int
+	maxBatchSize   int
+	maxDepth       int
+	errorDetails   bool
+	notifications  *NotificationMux
+	cancelMethod   string
+	metrics        Metrics
+	logger         *logConfig
+}
+
+// MuxOption configures a Mux
+type MuxOption func(*Mux)
+
+// WithRequestTimeout sets the default deadline of the requests; when it expires the handler's context is cancelled.
+// A zero or negative timeout means no deadline
+func WithRequestTimeout(timeout time.Duration) MuxOption {
+	return func(m *Mux) {
+		m.requestTimeout = timeout
+	}
+}
+
+// WithTimeoutError sets the error replied when the timeout of a request expires.
+// The default one is JsonRequestTimeout
+func WithTimeoutError(jsonRPCError *jsonRPCError) MuxOption {
+	return func(m *Mux) {
+		if jsonRPCError != nil {
+			m.timeoutError = jsonRPCError
+		}
+	}
+}
+
+// WithMaxConcurrentRequests bounds the number of handlers running at the same time.
+// Additional requests wait for a running handler to return unless reject is true,
+// in which case they are answered with JsonServerBusy. A zero or negative limit means no limit
+func WithMaxConcurrentRequests(limit int, reject bool) MuxOption {
+	return func(m *Mux) {
+		m.slots = nil
+		if limit > 0 {
+			m.slots = make(chan struct{}, limit)
+		}
+		m.rejectWhenBusy = reject
+	}
+}
+
+// WithMaxMessageSize sets the size limit in bytes of the messages passed to Serve. A larger message is answered
+// with JsonInvalidRequest and a null id before being validated or unmarshaled. A zero or negative size means no limit.
+// The transports bound the messages they read as well, e.g. with WithMaxBodySize or WithTCPMaxMessageSize
+func WithMaxMessageSize(size int) MuxOption {
+	return func(m *Mux) {
+		m.maxMessageSize = size
+	}
+}
+
+// WithMaxBatchSize sets the maximum number of requests and notifications of a batch. A larger batch is answered
+// with a single JsonInvalidRequest with a null id, its elements being counted without unmarshaling them.
+// A zero or negative size means no limit
+func WithMaxBatchSize(size int) MuxOption {
+	return func(m *Mux) {
+		m.maxBatchSize = size
+	}
+}
+
+// WithMaxDepth sets the maximum nesting depth of the objects and arrays of the messages, the message itself
+// being at depth 1 as are the elements of a batch. A deeper message is answered with JsonInvalidRequest with a null id before being unmarshaled.
+// A zero or negative depth means no limit
+func WithMaxDepth(depth int) MuxOption {
+	return func(m *Mux) {
+		m.maxDepth = depth
+	}
+}
+
+// WithErrorDetails adds the message of the errors returned by the handlers, which are not a *jsonRPCError,
+// as the data of the JsonInternalError replied. It is meant for debugging, as the messages may reveal internals
+func WithErrorDetails() MuxOption {
+	return func(m *Mux) {
+		m.errorDetails = true
+	}
+}
+
+// WithNotificationMux dispatches the notifications whose method the NotificationMux handles to it, e.g. on its
+// workers, rather than to the handlers of the Mux. The Mux does not close it
+func WithNotificationMux(notifications *NotificationMux) MuxOption {
+	return func(m *Mux) {
+		m.notifications = notifications
+	}
+}
+
+// NewMux creates an empty Mux configured by the options.
+// Returns a *Mux object
+func NewMux(options ...MuxOption) *Mux {
+	mux := &Mux{
+		handlers:     make(map[string]Handler),
+		builtins:     make(map[string]Handler),
+		methodInfos:  make(map[string]MethodInfo),
+		mounts:       make(map[string]*Mux),
+		timeouts:     make(map[string]time.Duration),
+		timeoutError: &JsonRequestTimeout,
+	}
+	for _, option := range options {
+		option(mux)
+	}
+	return mux
+}
+
+// Handle registers the handler for the method.
+// Returns an error if the method is empty, reserved or already registered
+func (m *Mux) Handle(method string, handler Handler) error {
+	if method == "" {
+		return errors.New("method must not be empty")
+	}
+	if strings.HasPrefix(method, "rpc.") {
+		return errors.New("methods with prefix \"rpc.\" are reserved")
+	}
+	if handler == nil {
+		return errors.New("no handler passed as parameter")
+	}
+
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	if _, ok := m.handlers[method]; ok {
+		return fmt.Errorf("method \"%v\" is already registered", method)
+	}
+
+	m.handlers[method] = handler
+	return nil
+}
+
+// Unregister removes the handler, the info and the timeout of the method.
+// Requests already being handled are not affected.
+// Returns an error if the method is not registered
+func (m *Mux) Unregister(method string) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	if _, ok := m.handlers[method]; !ok {
+		return fmt.Errorf("method \"%v\" is not registered", method)
+	}
+
+	delete(m.handlers, method)
+	delete(m.methodInfos, method)
+	delete(m.timeouts, method)
+	return nil
+}
+
+// HandleUnknown registers the handler of the methods which are not registered e.g. for proxying them upstream.
+// The handler gets the method with MethodFromContext. A nil handler restores the JsonMethodNotFound responses
+func (m *Mux) HandleUnknown(handler Handler) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	m.unknown = handler
+}
+
+// Mount routes the methods "prefix.method" to the mux which handles them as "method".
+// The middlewares and the method timeouts of the mux are applied as well.
+// Returns an error if the prefix is empty, contains a ".", is reserved or is already mounted
+func (m *Mux) Mount(prefix string, mux *Mux) error {
+	if prefix == "" {
+		return errors.New("prefix must not be empty")
+	}
+	if strings.Contains(prefix, ".") {
+		return errors.New("prefix must not contain \".\"")
+	}
+	if prefix == "rpc" {
+		return errors.New("prefix \"rpc\" is reserved")
+	}
+	if mux == nil {
+		return errors.New("no mux passed as parameter")
+	}
+
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	if _, ok := m.mounts[prefix]; ok {
+		return fmt.Errorf("prefix \"%v\" is already mounted", prefix)
+	}
+
+	m.mounts[prefix] = mux
+	return nil
+}
+
+// SetTimeout sets the deadline of the requests of a registered method overriding the default one.
+// A zero or negative timeout means no deadline. The methods handled by the HandleUnknown handler
+// are not supported, they get the default one.
+// Returns an error if the method is not registered
+func (m *Mux) SetTimeout(method string, timeout time.Duration) error {
+	if !m.registered(method) {
+		return fmt.Errorf("method \"%v\" is not registered", method)
+	}
+
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	m.timeouts[method] = timeout
+	return nil
+}
+
+// Use appends middlewares to the chain wrapping every registered handler.
+// The first middleware is the outermost one
+func (m *Mux) Use(middlewares ...Middleware) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	m.middlewares = append(m.middlewares, middlewares...)
+}
+
+// HandleFunc registers a typed handler function for the method.
+// The params are unmarshaled into P and the returned R is marshaled into the result.
+// Returns an error if the method is empty, reserved or already registered
+func HandleFunc[P, R any](m *Mux, method string, handler func(ctx context.Context, params P) (R, error)) error {
+	return m.Handle(method, typedHandler[P, R](handler))
+}
+
+// typedHandler is the Handler of a typed handler function registered with HandleFunc
+type typedHandler[P, R any] func(ctx context.Context, params P) (R, error)
+
+// ServeJSONRPC implements Handler by unmarshaling the params into P before calling the handler function.
+// The field names of the params and of the result are translated by the FieldNaming of the Mux, if any
+func (h typedHandler[P, R]) ServeJSONRPC(ctx context.Context, paramsRaw json.RawMessage) (any, error) {
+	naming := fieldNamingFromContext(ctx)
+	paramsType, resultType := h.signature()
+
+	var params P
+	if len(paramsRaw) > 0 {
+		var err error
+		if naming != nil {
+			paramsRaw, err = translateFieldNames(paramsRaw, paramsType, naming, false)
+		}
+		if err == nil {
+			err = jsonEngineFromContext(ctx).Unmarshal(paramsRaw, &params)
+		}
+		if err != nil {
+			jsonRPCError, _ := JsonInvalidMethodParameters.AddData(err.Error())
+			return nil, jsonRPCError
+		}
+	}
+
+	result, err := h(ctx, params)
+	if err != nil || naming == nil {
+		return result, err
+	}
+	resultRaw, err := jsonEngineFromContext(ctx).Marshal(result)
+	if err != nil {
+		return nil, err
+	}
+	return translateFieldNames(resultRaw, resultType, naming, true)
+}
+
+// signature returns the types of the params and of the result of the handler function
+func (h typedHandler[P, R]) signature() (reflect.Type, reflect.Type) {
+	return reflect.TypeOf((*P)(nil)).Elem(), reflect.TypeOf((*R)(nil)).Elem()
+}
+
+// Serve processes a JSON-RPC request or notification, or a batch of them, from raw bytes by calling the Handler registered for its method.
+// The handler's context is derived from ctx, so transports shall cancel ctx when the client disconnects.
+// It is cancelled as well when the timeout of the method expires or when Serve returns.
+// Returns the raw bytes of the response or nil in case of a notification
+func (m *Mux) Serve(ctx context.Context, messageRaw []byte) []byte {
+	if (m.metrics != nil || m.logger != nil) && ctx.Value(observedContextKey) == nil {
+		return m.serveObserved(ctx, messageRaw)
+	}
+	ctx, cancel := context.WithCancel(ctx)
+	defer cancel()
+	if m.fieldNaming != nil {
+		ctx = context.WithValue(ctx, fieldNamingContextKey, m.fieldNaming)
+	}
+	if m.jsonEngine != nil {
+		ctx = context.WithValue(ctx, jsonEngineContextKey, m.jsonEngine)
+	}
+
+	if m.maxMessageSize > 0 && len(messageRaw) > m.maxMessageSize {
+		return newNullIDErrorResponse(&JsonInvalidRequest)
+	}
+	if !json.Valid(messageRaw) {
+		return newNullIDErrorResponse(&JsonParseError)
+	}
+	batch := jsonKind(messageRaw) == '['
+	if m.maxDepth > 0 {
+		maxDepth := m.maxDepth
+		if batch {
+			// The elements of a batch are as deep as when sent alone
+			maxDepth++
+		}
+		if exceedsDepth(messageRaw, maxDepth) {
+			return newNullIDErrorResponse(&JsonInvalidRequest)
+		}
+	}
+	if batch {
+		return m.serveBatch(ctx, messageRaw)
+	}
+
+	var members envelopeMembers
+	scanned := false
+	if m.lazyParsing {
+		members, scanned = scanEnvelope(messageRaw)
+	}
+	if !scanned {
+		var envelope struct {
+			ID json.RawMessage `json:"id"`
+		}
+		err := json.Unmarshal(messageRaw, &envelope)
+		if err != nil {
+			return newNullIDErrorResponse(&JsonInvalidRequest)
+		}
+		members.id = envelope.ID
+	}
+
+	if members.id == nil {
+		var notification *notification
+		var err error
+		if scanned {
+			notification, err = parseScannedNotification(members)
+		} else {
+			notification, err = ParseNotification(messageRaw)
+		}
+		if err != nil {
+			// Notifications are never answered, not even with an error
+			return nil
+		}
+		if m.cancelMethod != "" && notification.Method == m.cancelMethod {
+			cancelRequest(ctx, notification.Params)
+			return nil
+		}
+		if m.notifications != nil && m.notifications.handles(notification.Method) {
+			if methodVisible(ctx, notification.Method) {
+				m.notifications.dispatch(ctx, notification.Method, notification.Params)
+			}
+			return nil
+		}
+		if handler, ok := m.handler(notification.Method); ok && methodVisible(ctx, notification.Method) {
+			ctx, cancel := m.withTimeout(ctx, notification.Method)
+			defer cancel()
+			release, jsonRPCError := m.acquire(ctx)
+			if jsonRPCError != nil {
+				return nil
+			}
+			defer release()
+			ctx = context.WithValue(ctx, methodContextKey, notification.Method)
+			handler.ServeJSONRPC(ctx, notification.Params)
+		}
+		return nil
+	}
+
+	var request *request
+	var jsonRPCError *jsonRPCError
+	if scanned {
+		request, jsonRPCError = parseScannedRequest(members)
+	} else {
+		request, jsonRPCError = parseRequest(messageRaw)
+	}
+	if jsonRPCError != nil {
+		return newNullIDErrorResponse(jsonRPCError)
+	}
+
+	if strings.HasPrefix(request.Method, "rpc.") && !m.isBuiltin(request.Method) {
+		return newNullIDErrorResponse(&JsonInvalidRequest)
+	}
+
+	reply, err := newResponseEnvelope(request.ID)
+	if err != nil {
+		return newNullIDErrorResponse(&JsonInvalidRequest)
+	}
+
+	handler, ok := m.handler(request.Method)
+	if !ok || !methodVisible(ctx, request.Method) {
+		return reply.errorResponse(&JsonMethodNotFound)
+	}
+
+	if m.cancelMethod != "" {
+		var untrack func()
+		ctx, untrack = trackRequest(ctx, request.ID)
+		defer untrack()
+	}
+	ctx, cancel = m.withTimeout(ctx, request.Method)
+	defer cancel()
+	release, jsonRPCError := m.acquire(ctx)
+	if jsonRPCError != nil {
+		return reply.errorResponse(jsonRPCError)
+	}
+	ctx = context.WithValue(ctx, methodContextKey, request.Method)
+	ctx = context.WithValue(ctx, idContextKey, request.ID)
+	result, err := m.call(ctx, handler, request.Params, release)
+	if err != nil {
+		if errors.Is(context.Cause(ctx), errRequestCancelled) {
+			return reply.errorResponse(&JsonRequestCancelled)
+		}
+		return reply.errorResponse(m.toJsonRPCError(err))
+	}
+
+	responseRaw, err := reply.resultResponse(jsonEngineFromContext(ctx), result)
+	if err != nil {
+		return reply.errorResponse(&JsonInternalError)
+	}
+	return responseRaw
+}
+
+// withTimeout derives a context which is cancelled when the timeout of the method expires
+func (m *Mux) withTimeout(ctx context.Context, method string) (context.Context, context.CancelFunc) {
+	timeout, ok := m.timeout(method)
+	if !ok {
+		timeout = m.requestTimeout
+	}
+	if timeout <= 0 {
+		return context.WithCancel(ctx)
+	}
+	return context.WithTimeout(ctx, timeout)
+}
+
+// acquire reserves a slot for running a handler when the concurrent requests are bounded.
+// Returns a function releasing the slot or a *jsonRPCError if no slot could be reserved
+func (m *Mux) acquire(ctx context.Context) (func(), *jsonRPCError) {
+	if m.slots == nil {
+		return func() {}, nil
+	}
+
+	if m.rejectWhenBusy {
+		select {
+		case m.slots <- struct{}{}:
+		default:
+			return nil, &JsonServerBusy
+		}
+	} else {
+		select {
+		case m.slots <- struct{}{}:
+		case <-ctx.Done():
+			return nil, m.contextError(ctx)
+		}
+	}
+	return func() { <-m.slots }, nil
+}
+
+// contextError maps the error of a done context to a *jsonRPCError
+func (m *Mux) contextError(ctx context.Context) *jsonRPCError {
+	if errors.Is(ctx.Err(), context.DeadlineExceeded) {
+		return m.timeoutError
+	}
+	if errors.Is(context.Cause(ctx), errRequestCancelled) {
+		return &JsonRequestCancelled
+	}
+	return &JsonInternalError
+}
+
+// call runs the handler and calls release once it returns. If the context has a deadline which expires
+// before the handler returns, the timeout error is returned without waiting for the handler
+func (m *Mux) call(ctx context.Context, handler Handler, params json.RawMessage, release func()) (any, error) {
+	if _, ok := ctx.Deadline(); !ok {
+		defer release()
+		return handler.ServeJSONRPC(ctx, params)
+	}
+
+	type outcome struct {
+		result any
+		err    error
+	}
+	done := make(chan outcome, 1)
+	go func() {
+		defer release()
+		result, err := handler.ServeJSONRPC(ctx, params)
+		done <- outcome{result: result, err: err}
+	}()
+
+	var o outcome
+	select {
+	case o = <-done:
+	case <-ctx.Done():
+		if errors.Is(ctx.Err(), context.DeadlineExceeded) {
+			return nil, m.timeoutError
+		}
+		o = <-done
+	}
+
+	if o.err != nil && errors.Is(ctx.Err(), context.DeadlineExceeded) {
+		return nil, m.timeoutError
+	}
+	return o.result, o.err
+}
+
+// timeout looks up the timeout set for the method, including the ones set in the mounted muxes
+func (m *Mux) timeout(method string) (time.Duration, bool) {
+	prefix, subMethod, found := strings.Cut(method, ".")
+	m.mu.RLock()
+	timeout, ok := m.timeouts[method]
+	mux, mounted := m.mounts[prefix]
+	m.mu.RUnlock()
+
+	if !ok && found && mounted {
+		return mux.timeout(subMethod)
+	}
+	return timeout, ok
+}
+
+// handler looks up the handler of the method, including the mounted muxes and the unknown methods' handler,
+// and wraps it with the middlewares
+func (m *Mux) handler(method string) (Handler, bool) {
+	prefix, subMethod, found := strings.Cut(method, ".")
+	m.mu.RLock()
+	handler, ok := m.handlers[method]
+	if !ok {
+		handler, ok = m.builtins[method]
+	}
+	mux, mounted := m.mounts[prefix]
+	unknown := m.unknown
+	middlewares := m.middlewares
+	m.mu.RUnlock()
+
+	if !ok && found && mounted {
+		handler, ok = mux.handler(subMethod)
+	}
+	if !ok {
+		if unknown == nil {
+			return nil, false
+		}
+		handler = unknown
+	}
+	for i := len(middlewares) - 1; i >= 0; i-- {
+		handler = middlewares[i](handler)
+	}
+	return handler, true
+}
+
+// registered reports whether a handler is registered for the method, including in the mounted muxes but not as
+// the unknown methods' handler
+func (m *Mux) registered(method string) bool {
+	prefix, subMethod, found := strings.Cut(method, ".")
+	m.mu.RLock()
+	_, ok := m.handlers[method]
+	if !ok {
+		_, ok = m.builtins[method]
+	}
+	mux, mounted := m.mounts[prefix]
+	m.mu.RUnlock()
+	return ok || (found && mounted && mux.registered(subMethod))
+}
+
+// isBuiltin reports whether the reserved method is a built-in one which is enabled
+func (m *Mux) isBuiltin(method string) bool {
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+	_, ok := m.builtins[method]
+	return ok
+}
+
+// toJsonRPCError maps an error returned by a Handler to a *jsonRPCError, the first one in its chain if any.
+// Other errors are reported as JsonInternalError, with their message as data if the Mux exposes the error details
+func (m *Mux) toJsonRPCError(err error) *jsonRPCError {
+	if jsonRPCError, ok := AsJsonRPCError(err); ok && jsonRPCError != nil {
+		return jsonRPCError
+	}
+	if m.errorDetails {
+		if jsonRPCError, dataErr := JsonInternalError.AddData(err.Error()); dataErr == nil {
+			return jsonRPCError
+		}
+	}
+	return &JsonInternalError
+}
+
+func newNullIDErrorResponse(jsonRPCError *jsonRPCError) []byte {
+	responseRaw, _ := NewErrorResponse(nil, jsonRPCError)
+	return responseRaw
+}
@@ -0,0 +1,980 @@
+/*  Copyright 2022  Kosmas Valianos (kosmas.valianos@gmail.com)
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    https://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License. */
+
+package gojsonrpc
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"reflect"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+)
+
+func newTestMux(t *testing.T) *Mux {
+	mux := NewMux()
+	err := HandleFunc(mux, "subtract", func(ctx context.Context, params [2]int) (int, error) {
+		return params[0] - params[1], nil
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	err = HandleFunc(mux, "database", func(ctx context.Context, params any) (any, error) {
+		return nil, &JsonInvalidMethodParameters
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	err = HandleFunc(mux, "fail", func(ctx context.Context, params any) (any, error) {
+		return nil, errors.New("something went wrong")
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	err = mux.Handle("raw", HandlerFunc(func(ctx context.Context, params json.RawMessage) (any, error) {
+		return params, nil
+	}))
+	if err != nil {
+		t.Fatal(err)
+	}
+	return mux
+}
+
+func TestMux_Handle(t *testing.T) {
+	tests := []struct {
+		name    string
+		method  string
+		handler Handler
+		wantErr bool
+	}{
+		{
+			name:   "Valid method",
+			method: "add",
+			handler: HandlerFunc(func(ctx context.Context, params json.RawMessage) (any, error) {
+				return nil, nil
+			}),
+		},
+		{
+			name:   "Invalid method - empty",
+			method: "",
+			handler: HandlerFunc(func(ctx context.Context, params json.RawMessage) (any, error) {
+				return nil, nil
+			}),
+			wantErr: true,
+		},
+		{
+			name:   "Invalid method - prefix rpc.",
+			method: "rpc.add",
+			handler: HandlerFunc(func(ctx context.Context, params json.RawMessage) (any, error) {
+				return nil, nil
+			}),
+			wantErr: true,
+		},
+		{
+			name:   "Invalid method - already registered",
+			method: "subtract",
+			handler: HandlerFunc(func(ctx context.Context, params json.RawMessage) (any, error) {
+				return nil, nil
+			}),
+			wantErr: true,
+		},
+		{
+			name:    "Invalid handler - nil",
+			method:  "add",
+			wantErr: true,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			mux := newTestMux(t)
+			err := mux.Handle(tt.method, tt.handler)
+			if (err != nil) != tt.wantErr {
+				t.Errorf("Handle() error = %v, wantErr %v", err, tt.wantErr)
+			}
+		})
+	}
+}
+
+func TestMux_Serve(t *testing.T) {
+	tests := []struct {
+		name     string
+		rawBytes []byte
+		want     []byte
+	}{
+		{
+			name:     "Valid request",
+			rawBytes: []byte(`{"jsonrpc": "2.0", "method": "subtract", "params": [42, 23], "id": 1}`),
+			want:     []byte(`{"jsonrpc":"2.0","result":19,"id":1}` + "\n"),
+		},
+		{
+			name:     "Valid request - raw handler",
+			rawBytes: []byte(`{"jsonrpc": "2.0", "method": "raw", "params": {"foo": "bar"}, "id": "abc"}`),
+			want:     []byte(`{"jsonrpc":"2.0","result":{"foo":"bar"},"id":"abc"}` + "\n"),
+		},
+		{
+			name:     "Valid notification",
+			rawBytes: []byte(`{"jsonrpc": "2.0", "method": "subtract", "params": [42, 23]}`),
+		},
+		{
+			name:     "Valid notification - method not found",
+			rawBytes: []byte(`{"jsonrpc": "2.0", "method": "add", "params": [42, 23]}`),
+		},
+		{
+			name:     "Parse error",
+			rawBytes: []byte(`{"jsonrpc": "2.0", "method": "subtract", "params": [42, 23], "id": 1`),
+			want:     []byte(`{"jsonrpc":"2.0","error":{"code":-32700,"message":"Parse error"},"id":null}` + "\n"),
+		},
+		{
+			name:     "Invalid request - \"id\" null",
+			rawBytes: []byte(`{"jsonrpc": "2.0", "method": "subtract", "params": [42, 23], "id": null}`),
+			want:     []byte(`{"jsonrpc":"2.0","error":{"code":-32600,"message":"Invalid Request"},"id":null}` + "\n"),
+		},
+		{
+			name:     "Invalid request - not an object",
+			rawBytes: []byte(`"foo"`),
+			want:     []byte(`{"jsonrpc":"2.0","error":{"code":-32600,"message":"Invalid Request"},"id":null}` + "\n"),
+		},
+		{
+			name:     "Method not found",
+			rawBytes: []byte(`{"jsonrpc": "2.0", "method": "add", "params": [42, 23], "id": 1}`),
+			want:     []byte(`{"jsonrpc":"2.0","error":{"code":-32601,"message":"Method not found"},"id":1}` + "\n"),
+		},
+		{
+			name:     "Invalid method parameters - unmarshal",
+			rawBytes: []byte(`{"jsonrpc": "2.0", "method": "subtract", "params": {"foo": "bar"}, "id": 1}`),
+			want:     []byte(`{"jsonrpc":"2.0","error":{"code":-32602,"message":"Invalid method parameters","data":"json: cannot unmarshal object into Go value of type [2]int"},"id":1}` + "\n"),
+		},
+		{
+			name:     "Invalid method parameters - handler",
+			rawBytes: []byte(`{"jsonrpc": "2.0", "method": "database", "id": 1}`),
+			want:     []byte(`{"jsonrpc":"2.0","error":{"code":-32602,"message":"Invalid method parameters"},"id":1}` + "\n"),
+		},
+		{
+			name:     "Internal error",
+			rawBytes: []byte(`{"jsonrpc": "2.0", "method": "fail", "id": 1}`),
+			want:     []byte(`{"jsonrpc":"2.0","error":{"code":-32603,"message":"Internal error"},"id":1}` + "\n"),
+		},
+	}
+
+	mux := newTestMux(t)
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			jsonRPCResponseRaw := mux.Serve(context.Background(), tt.rawBytes)
+			if !bytes.Equal(jsonRPCResponseRaw, tt.want) {
+				t.Errorf("Serve() = %v, want %v", string(jsonRPCResponseRaw), string(tt.want))
+			}
+
+			if jsonRPCResponseRaw != nil {
+				_, err := ParseResponse(jsonRPCResponseRaw)
+				if err != nil {
+					t.Error(err)
+				}
+			}
+		})
+	}
+}
+
+func TestMux_ServeContext(t *testing.T) {
+	tests := []struct {
+		name     string
+		options  []MuxOption
+		rawBytes []byte
+		want     []byte
+	}{
+		{
+			name:     "Request timeout expired",
+			options:  []MuxOption{WithRequestTimeout(10 * time.Millisecond)},
+			rawBytes: []byte(`{"jsonrpc": "2.0", "method": "wait", "id": 1}`),
+			want:     []byte(`{"jsonrpc":"2.0","error":{"code":-32000,"message":"Request timeout"},"id":1}` + "\n"),
+		},
+		{
+			name:     "Request timeout not expired",
+			options:  []MuxOption{WithRequestTimeout(time.Minute)},
+			rawBytes: []byte(`{"jsonrpc": "2.0", "method": "deadline", "id": 1}`),
+			want:     []byte(`{"jsonrpc":"2.0","result":true,"id":1}` + "\n"),
+		},
+		{
+			name:     "No request timeout",
+			rawBytes: []byte(`{"jsonrpc": "2.0", "method": "deadline", "id": 1}`),
+			want:     []byte(`{"jsonrpc":"2.0","result":false,"id":1}` + "\n"),
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			handlerCtxs := make(chan context.Context, 1)
+			mux := NewMux(tt.options...)
+			err := HandleFunc(mux, "wait", func(ctx context.Context, params any) (any, error) {
+				handlerCtxs <- ctx
+				<-ctx.Done()
+				return nil, ctx.Err()
+			})
+			if err != nil {
+				t.Fatal(err)
+			}
+			err = HandleFunc(mux, "deadline", func(ctx context.Context, params any) (bool, error) {
+				handlerCtxs <- ctx
+				_, ok := ctx.Deadline()
+				return ok, nil
+			})
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			jsonRPCResponseRaw := mux.Serve(context.Background(), tt.rawBytes)
+			if !bytes.Equal(jsonRPCResponseRaw, tt.want) {
+				t.Errorf("Serve() = %v, want %v", string(jsonRPCResponseRaw), string(tt.want))
+			}
+
+			if handlerCtx := <-handlerCtxs; handlerCtx.Err() == nil {
+				t.Error("Serve() returned without cancelling the handler's context")
+			}
+		})
+	}
+
+	t.Run("Client disconnected", func(t *testing.T) {
+		mux := NewMux()
+		err := HandleFunc(mux, "wait", func(ctx context.Context, params any) (any, error) {
+			<-ctx.Done()
+			return nil, ctx.Err()
+		})
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		ctx, cancel := context.WithCancel(context.Background())
+		time.AfterFunc(10*time.Millisecond, cancel)
+		jsonRPCResponseRaw := mux.Serve(ctx, []byte(`{"jsonrpc": "2.0", "method": "wait", "id": 1}`))
+		want := []byte(`{"jsonrpc":"2.0","error":{"code":-32603,"message":"Internal error"},"id":1}` + "\n")
+		if !bytes.Equal(jsonRPCResponseRaw, want) {
+			t.Errorf("Serve() = %v, want %v", string(jsonRPCResponseRaw), string(want))
+		}
+	})
+}
+
+func TestMux_Use(t *testing.T) {
+	var calls []string
+	logger := func(name string) Middleware {
+		return func(next Handler) Handler {
+			return HandlerFunc(func(ctx context.Context, params json.RawMessage) (any, error) {
+				calls = append(calls, name+" "+MethodFromContext(ctx))
+				return next.ServeJSONRPC(ctx, params)
+			})
+		}
+	}
+	auth := func(next Handler) Handler {
+		return HandlerFunc(func(ctx context.Context, params json.RawMessage) (any, error) {
+			if IDFromContext(ctx) == "forbidden" {
+				return nil, &JsonInvalidRequest
+			}
+			return next.ServeJSONRPC(ctx, params)
+		})
+	}
+	swap := func(next Handler) Handler {
+		return HandlerFunc(func(ctx context.Context, params json.RawMessage) (any, error) {
+			if IDFromContext(ctx) == "swap" {
+				params = json.RawMessage(`[23, 42]`)
+			}
+			return next.ServeJSONRPC(ctx, params)
+		})
+	}
+
+	tests := []struct {
+		name      string
+		rawBytes  []byte
+		want      []byte
+		wantCalls []string
+	}{
+		{
+			name:      "Valid request",
+			rawBytes:  []byte(`{"jsonrpc": "2.0", "method": "subtract", "params": [42, 23], "id": 1}`),
+			want:      []byte(`{"jsonrpc":"2.0","result":19,"id":1}` + "\n"),
+			wantCalls: []string{"first subtract", "second subtract"},
+		},
+		{
+			name:      "Valid request - params mutated",
+			rawBytes:  []byte(`{"jsonrpc": "2.0", "method": "subtract", "params": [42, 23], "id": "swap"}`),
+			want:      []byte(`{"jsonrpc":"2.0","result":-19,"id":"swap"}` + "\n"),
+			wantCalls: []string{"first subtract", "second subtract"},
+		},
+		{
+			name:      "Valid notification",
+			rawBytes:  []byte(`{"jsonrpc": "2.0", "method": "subtract", "params": [42, 23]}`),
+			wantCalls: []string{"first subtract", "second subtract"},
+		},
+		{
+			name:      "Rejected request",
+			rawBytes:  []byte(`{"jsonrpc": "2.0", "method": "subtract", "params": [42, 23], "id": "forbidden"}`),
+			want:      []byte(`{"jsonrpc":"2.0","error":{"code":-32600,"message":"Invalid Request"},"id":null}` + "\n"),
+			wantCalls: []string{"first subtract"},
+		},
+		{
+			name:     "Method not found",
+			rawBytes: []byte(`{"jsonrpc": "2.0", "method": "add", "params": [42, 23], "id": 1}`),
+			want:     []byte(`{"jsonrpc":"2.0","error":{"code":-32601,"message":"Method not found"},"id":1}` + "\n"),
+		},
+	}
+
+	mux := newTestMux(t)
+	mux.Use(logger("first"), auth)
+	mux.Use(logger("second"), swap)
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			calls = nil
+			jsonRPCResponseRaw := mux.Serve(context.Background(), tt.rawBytes)
+			if !bytes.Equal(jsonRPCResponseRaw, tt.want) {
+				t.Errorf("Serve() = %v, want %v", string(jsonRPCResponseRaw), string(tt.want))
+			}
+
+			if !reflect.DeepEqual(calls, tt.wantCalls) {
+				t.Errorf("Serve() calls = %v, want %v", calls, tt.wantCalls)
+			}
+		})
+	}
+}
+
+func TestMux_SetTimeout(t *testing.T) {
+	customTimeoutError, _ := NewJsonRPCError(-32050, "Too slow", nil)
+	tests := []struct {
+		name        string
+		options     []MuxOption
+		method      string
+		timeout     time.Duration
+		rawBytes    []byte
+		want        []byte
+		unknown     bool
+		wantErr     bool
+		wantHandled bool
+	}{
+		{
+			name:     "Method timeout expired",
+			method:   "block",
+			timeout:  10 * time.Millisecond,
+			rawBytes: []byte(`{"jsonrpc": "2.0", "method": "block", "id": 1}`),
+			want:     []byte(`{"jsonrpc":"2.0","error":{"code":-32000,"message":"Request timeout"},"id":1}` + "\n"),
+		},
+		{
+			name:     "Method timeout expired - custom error",
+			options:  []MuxOption{WithTimeoutError(customTimeoutError)},
+			method:   "block",
+			timeout:  10 * time.Millisecond,
+			rawBytes: []byte(`{"jsonrpc": "2.0", "method": "block", "id": 1}`),
+			want:     []byte(`{"jsonrpc":"2.0","error":{"code":-32050,"message":"Too slow","data":null},"id":1}` + "\n"),
+		},
+		{
+			name:     "Method timeout overrides the default one",
+			options:  []MuxOption{WithRequestTimeout(time.Minute)},
+			method:   "block",
+			timeout:  10 * time.Millisecond,
+			rawBytes: []byte(`{"jsonrpc": "2.0", "method": "block", "id": 1}`),
+			want:     []byte(`{"jsonrpc":"2.0","error":{"code":-32000,"message":"Request timeout"},"id":1}` + "\n"),
+		},
+		{
+			name:     "Method timeout disables the default one",
+			options:  []MuxOption{WithRequestTimeout(10 * time.Millisecond)},
+			method:   "subtract",
+			rawBytes: []byte(`{"jsonrpc": "2.0", "method": "subtract", "params": [42, 23], "id": 1}`),
+			want:     []byte(`{"jsonrpc":"2.0","result":19,"id":1}` + "\n"),
+		},
+		{
+			name:        "Method timeout expired - notification",
+			method:      "block",
+			timeout:     10 * time.Millisecond,
+			rawBytes:    []byte(`{"jsonrpc": "2.0", "method": "block"}`),
+			wantHandled: true,
+		},
+		{
+			name:    "Method not registered",
+			method:  "add",
+			timeout: time.Second,
+			wantErr: true,
+		},
+		{
+			name:    "Method handled by HandleUnknown",
+			method:  "add",
+			timeout: time.Second,
+			unknown: true,
+			wantErr: true,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			unblock := make(chan struct{})
+			defer close(unblock)
+			var handled atomic.Bool
+			mux := NewMux(tt.options...)
+			err := HandleFunc(mux, "block", func(ctx context.Context, params any) (any, error) {
+				// Ignores the cancellation of the context
+				select {
+				case <-unblock:
+				case <-time.After(50 * time.Millisecond):
+					handled.Store(true)
+				}
+				return "done", nil
+			})
+			if err != nil {
+				t.Fatal(err)
+			}
+			err = HandleFunc(mux, "subtract", func(ctx context.Context, params [2]int) (int, error) {
+				time.Sleep(20 * time.Millisecond)
+				return params[0] - params[1], nil
+			})
+			if err != nil {
+				t.Fatal(err)
+			}
+			if tt.unknown {
+				mux.HandleUnknown(HandlerFunc(func(ctx context.Context, params json.RawMessage) (any, error) {
+					return MethodFromContext(ctx), nil
+				}))
+			}
+
+			err = mux.SetTimeout(tt.method, tt.timeout)
+			if (err != nil) != tt.wantErr {
+				t.Errorf("SetTimeout() error = %v, wantErr %v", err, tt.wantErr)
+				return
+			}
+			if tt.wantErr {
+				return
+			}
+
+			jsonRPCResponseRaw := mux.Serve(context.Background(), tt.rawBytes)
+			if !bytes.Equal(jsonRPCResponseRaw, tt.want) {
+				t.Errorf("Serve() = %v, want %v", string(jsonRPCResponseRaw), string(tt.want))
+			}
+
+			if handled.Load() != tt.wantHandled {
+				t.Errorf("Serve() handled = %v, want %v", handled.Load(), tt.wantHandled)
+			}
+		})
+	}
+}
+
+func TestMux_MaxConcurrentRequests(t *testing.T) {
+	tests := []struct {
+		name     string
+		options  []MuxOption
+		rawBytes []byte
+		want     []byte
+	}{
+		{
+			name:     "Busy - rejected",
+			options:  []MuxOption{WithMaxConcurrentRequests(1, true)},
+			rawBytes: []byte(`{"jsonrpc": "2.0", "method": "subtract", "params": [42, 23], "id": 2}`),
+			want:     []byte(`{"jsonrpc":"2.0","error":{"code":-32003,"message":"Server busy"},"id":2}` + "\n"),
+		},
+		{
+			name:     "Busy - queued until timeout",
+			options:  []MuxOption{WithMaxConcurrentRequests(1, false), WithRequestTimeout(10 * time.Millisecond)},
+			rawBytes: []byte(`{"jsonrpc": "2.0", "method": "subtract", "params": [42, 23], "id": 2}`),
+			want:     []byte(`{"jsonrpc":"2.0","error":{"code":-32000,"message":"Request timeout"},"id":2}` + "\n"),
+		},
+		{
+			name:     "Busy - queued until the running handler returns",
+			options:  []MuxOption{WithMaxConcurrentRequests(1, false)},
+			rawBytes: []byte(`{"jsonrpc": "2.0", "method": "subtract", "params": [42, 23], "id": 2}`),
+			want:     []byte(`{"jsonrpc":"2.0","result":19,"id":2}` + "\n"),
+		},
+		{
+			name:     "Not busy",
+			options:  []MuxOption{WithMaxConcurrentRequests(2, true)},
+			rawBytes: []byte(`{"jsonrpc": "2.0", "method": "subtract", "params": [42, 23], "id": 2}`),
+			want:     []byte(`{"jsonrpc":"2.0","result":19,"id":2}` + "\n"),
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			started := make(chan struct{})
+			unblock := make(chan struct{})
+			mux := NewMux(tt.options...)
+			err := HandleFunc(mux, "block", func(ctx context.Context, params any) (any, error) {
+				close(started)
+				<-unblock
+				return "done", nil
+			})
+			if err != nil {
+				t.Fatal(err)
+			}
+			err = HandleFunc(mux, "subtract", func(ctx context.Context, params [2]int) (int, error) {
+				return params[0] - params[1], nil
+			})
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			blocked := make(chan []byte)
+			go func() {
+				blocked <- mux.Serve(context.Background(), []byte(`{"jsonrpc": "2.0", "method": "block", "id": 1}`))
+			}()
+			<-started
+			time.AfterFunc(20*time.Millisecond, func() { close(unblock) })
+
+			jsonRPCResponseRaw := mux.Serve(context.Background(), tt.rawBytes)
+			if !bytes.Equal(jsonRPCResponseRaw, tt.want) {
+				t.Errorf("Serve() = %v, want %v", string(jsonRPCResponseRaw), string(tt.want))
+			}
+			<-blocked
+		})
+	}
+}
+
+func TestMux_MaxMessageSize(t *testing.T) {
+	mux := newTestMux(t)
+	WithMaxMessageSize(64)(mux)
+	tests := []struct {
+		name       string
+		requestRaw string
+		want       string
+	}{
+		{
+			name:       "Within the limit",
+			requestRaw: `{"jsonrpc":"2.0","method":"subtract","params":[42,23],"id":1}`,
+			want:       `{"jsonrpc":"2.0","result":19,"id":1}` + "\n",
+		},
+		{
+			name:       "Too large",
+			requestRaw: `{"jsonrpc":"2.0","method":"subtract","params":[42,23],"id":1,"padding":"xxxxxxxx"}`,
+			want:       `{"jsonrpc":"2.0","error":{"code":-32600,"message":"Invalid Request"},"id":null}` + "\n",
+		},
+		{
+			name:       "Too large and not JSON",
+			requestRaw: strings.Repeat("{", 65),
+			want:       `{"jsonrpc":"2.0","error":{"code":-32600,"message":"Invalid Request"},"id":null}` + "\n",
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got := mux.Serve(context.Background(), []byte(tt.requestRaw))
+			if string(got) != tt.want {
+				t.Errorf("Serve() = %s, want %s", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestMux_MaxBatchSize(t *testing.T) {
+	mux := newTestMux(t)
+	WithMaxBatchSize(2)(mux)
+	tests := []struct {
+		name       string
+		requestRaw string
+		want       string
+	}{
+		{
+			name:       "Within the limit",
+			requestRaw: `[{"jsonrpc":"2.0","method":"subtract","params":[42,23],"id":1}, {"jsonrpc":"2.0","method":"subtract","params":[23,42],"id":2}]`,
+			want:       `[{"jsonrpc":"2.0","result":19,"id":1},{"jsonrpc":"2.0","result":-19,"id":2}]` + "\n",
+		},
+		{
+			name:       "Too large",
+			requestRaw: `[{"jsonrpc":"2.0","method":"subtract","params":[42,23],"id":1}, 1, 2]`,
+			want:       `{"jsonrpc":"2.0","error":{"code":-32600,"message":"Invalid Request"},"id":null}` + "\n",
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got := mux.Serve(context.Background(), []byte(tt.requestRaw))
+			if string(got) != tt.want {
+				t.Errorf("Serve() = %s, want %s", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestMux_MaxDepth(t *testing.T) {
+	mux := newTestMux(t)
+	WithMaxDepth(3)(mux)
+	invalidRequest := `{"jsonrpc":"2.0","error":{"code":-32600,"message":"Invalid Request"},"id":null}` + "\n"
+	tests := []struct {
+		name       string
+		requestRaw string
+		want       string
+	}{
+		{
+			name:       "Within the limit",
+			requestRaw: `{"jsonrpc":"2.0","method":"subtract","params":[42,23],"id":1,"meta":{"tags":["[[[{{{"]}}`,
+			want:       `{"jsonrpc":"2.0","result":19,"id":1}` + "\n",
+		},
+		{
+			name:       "Batch within the limit",
+			requestRaw: `[{"jsonrpc":"2.0","method":"subtract","params":[42,23],"id":1,"meta":{"tags":[]}}]`,
+			want:       `[{"jsonrpc":"2.0","result":19,"id":1}]` + "\n",
+		},
+		{
+			name:       "Too deep",
+			requestRaw: `{"jsonrpc":"2.0","method":"subtract","params":[[[42]],23],"id":1}`,
+			want:       invalidRequest,
+		},
+		{
+			name:       "Batch too deep",
+			requestRaw: `[{"jsonrpc":"2.0","method":"subtract","params":[[[42]],23],"id":1}]`,
+			want:       invalidRequest,
+		},
+		{
+			name:       "Too deep and not JSON",
+			requestRaw: `{"jsonrpc":"2.0","params":[[[42`,
+			want:       `{"jsonrpc":"2.0","error":{"code":-32700,"message":"Parse error"},"id":null}` + "\n",
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got := mux.Serve(context.Background(), []byte(tt.requestRaw))
+			if string(got) != tt.want {
+				t.Errorf("Serve() = %s, want %s", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestMux_HandlerErrors(t *testing.T) {
+	tests := []struct {
+		name    string
+		options []MuxOption
+		err     error
+		want    string
+	}{
+		{
+			name: "Error",
+			err:  errors.New("database unreachable"),
+			want: `{"jsonrpc":"2.0","error":{"code":-32603,"message":"Internal error"},"id":1}` + "\n",
+		},
+		{
+			name:    "Error with details",
+			options: []MuxOption{WithErrorDetails()},
+			err:     errors.New("database unreachable"),
+			want:    `{"jsonrpc":"2.0","error":{"code":-32603,"message":"Internal error","data":"database unreachable"},"id":1}` + "\n",
+		},
+		{
+			name:    "Wrapped JSON-RPC error",
+			options: []MuxOption{WithErrorDetails()},
+			err:     fmt.Errorf("lookup failed: %w", &JsonInvalidMethodParameters),
+			want:    `{"jsonrpc":"2.0","error":{"code":-32602,"message":"Invalid method parameters"},"id":1}` + "\n",
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			mux := NewMux(tt.options...)
+			err := HandleFunc(mux, "lookup", func(ctx context.Context, params any) (any, error) {
+				return nil, tt.err
+			})
+			if err != nil {
+				t.Fatal(err)
+			}
+			got := mux.Serve(context.Background(), []byte(`{"jsonrpc":"2.0","method":"lookup","id":1}`))
+			if string(got) != tt.want {
+				t.Errorf("Serve() = %s, want %s", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestMux_Mount(t *testing.T) {
+	var mu sync.Mutex
+	var calls []string
+	tracer := func(name string) Middleware {
+		return func(next Handler) Handler {
+			return HandlerFunc(func(ctx context.Context, params json.RawMessage) (any, error) {
+				mu.Lock()
+				calls = append(calls, name+" "+MethodFromContext(ctx))
+				mu.Unlock()
+				return next.ServeJSONRPC(ctx, params)
+			})
+		}
+	}
+
+	invoiceMux := NewMux()
+	err := HandleFunc(invoiceMux, "create", func(ctx context.Context, params any) (string, error) {
+		return "created", nil
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	err = HandleFunc(invoiceMux, "block", func(ctx context.Context, params any) (any, error) {
+		<-ctx.Done()
+		return nil, ctx.Err()
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	err = invoiceMux.SetTimeout("block", 10*time.Millisecond)
+	if err != nil {
+		t.Fatal(err)
+	}
+	invoiceMux.Use(tracer("invoice"))
+
+	billingMux := NewMux()
+	err = billingMux.Mount("invoice", invoiceMux)
+	if err != nil {
+		t.Fatal(err)
+	}
+	billingMux.Use(tracer("billing"))
+
+	mux := newTestMux(t)
+	err = mux.Mount("billing", billingMux)
+	if err != nil {
+		t.Fatal(err)
+	}
+	mux.Use(tracer("root"))
+
+	tests := []struct {
+		name      string
+		rawBytes  []byte
+		want      []byte
+		wantCalls []string
+	}{
+		{
+			name:      "Valid request - nested method",
+			rawBytes:  []byte(`{"jsonrpc": "2.0", "method": "billing.invoice.create", "id": 1}`),
+			want:      []byte(`{"jsonrpc":"2.0","result":"created","id":1}` + "\n"),
+			wantCalls: []string{"root billing.invoice.create", "billing billing.invoice.create", "invoice billing.invoice.create"},
+		},
+		{
+			name:      "Valid request - nested method timeout",
+			rawBytes:  []byte(`{"jsonrpc": "2.0", "method": "billing.invoice.block", "id": 1}`),
+			want:      []byte(`{"jsonrpc":"2.0","error":{"code":-32000,"message":"Request timeout"},"id":1}` + "\n"),
+			wantCalls: []string{"root billing.invoice.block", "billing billing.invoice.block", "invoice billing.invoice.block"},
+		},
+		{
+			name:      "Valid request - root method",
+			rawBytes:  []byte(`{"jsonrpc": "2.0", "method": "subtract", "params": [42, 23], "id": 1}`),
+			want:      []byte(`{"jsonrpc":"2.0","result":19,"id":1}` + "\n"),
+			wantCalls: []string{"root subtract"},
+		},
+		{
+			name:     "Method not found - nested method",
+			rawBytes: []byte(`{"jsonrpc": "2.0", "method": "billing.invoice.delete", "id": 1}`),
+			want:     []byte(`{"jsonrpc":"2.0","error":{"code":-32601,"message":"Method not found"},"id":1}` + "\n"),
+		},
+		{
+			name:     "Method not found - prefix only",
+			rawBytes: []byte(`{"jsonrpc": "2.0", "method": "billing", "id": 1}`),
+			want:     []byte(`{"jsonrpc":"2.0","error":{"code":-32601,"message":"Method not found"},"id":1}` + "\n"),
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			mu.Lock()
+			calls = nil
+			mu.Unlock()
+			jsonRPCResponseRaw := mux.Serve(context.Background(), tt.rawBytes)
+			if !bytes.Equal(jsonRPCResponseRaw, tt.want) {
+				t.Errorf("Serve() = %v, want %v", string(jsonRPCResponseRaw), string(tt.want))
+			}
+
+			mu.Lock()
+			defer mu.Unlock()
+			if !reflect.DeepEqual(calls, tt.wantCalls) {
+				t.Errorf("Serve() calls = %v, want %v", calls, tt.wantCalls)
+			}
+		})
+	}
+
+	mountTests := []struct {
+		name    string
+		prefix  string
+		mux     *Mux
+		wantErr bool
+	}{
+		{
+			name:   "Valid prefix",
+			prefix: "shipping",
+			mux:    NewMux(),
+		},
+		{
+			name:    "Invalid prefix - empty",
+			prefix:  "",
+			mux:     NewMux(),
+			wantErr: true,
+		},
+		{
+			name:    "Invalid prefix - contains .",
+			prefix:  "shipping.orders",
+			mux:     NewMux(),
+			wantErr: true,
+		},
+		{
+			name:    "Invalid prefix - rpc",
+			prefix:  "rpc",
+			mux:     NewMux(),
+			wantErr: true,
+		},
+		{
+			name:    "Invalid prefix - already mounted",
+			prefix:  "billing",
+			mux:     NewMux(),
+			wantErr: true,
+		},
+		{
+			name:    "Invalid mux - nil",
+			prefix:  "orders",
+			wantErr: true,
+		},
+	}
+
+	for _, tt := range mountTests {
+		t.Run(tt.name, func(t *testing.T) {
+			err := mux.Mount(tt.prefix, tt.mux)
+			if (err != nil) != tt.wantErr {
+				t.Errorf("Mount() error = %v, wantErr %v", err, tt.wantErr)
+			}
+		})
+	}
+}
+
+func TestMux_HandleUnknown(t *testing.T) {
+	proxy := func(name string) Handler {
+		return HandlerFunc(func(ctx context.Context, params json.RawMessage) (any, error) {
+			return name + " " + MethodFromContext(ctx), nil
+		})
+	}
+
+	billingMux := NewMux()
+	billingMux.HandleUnknown(proxy("billing"))
+	mux := newTestMux(t)
+	err := mux.Mount("billing", billingMux)
+	if err != nil {
+		t.Fatal(err)
+	}
+	mux.HandleUnknown(proxy("root"))
+
+	tests := []struct {
+		name     string
+		rawBytes []byte
+		want     []byte
+	}{
+		{
+			name:     "Registered method",
+			rawBytes: []byte(`{"jsonrpc": "2.0", "method": "subtract", "params": [42, 23], "id": 1}`),
+			want:     []byte(`{"jsonrpc":"2.0","result":19,"id":1}` + "\n"),
+		},
+		{
+			name:     "Unknown method",
+			rawBytes: []byte(`{"jsonrpc": "2.0", "method": "add", "params": [42, 23], "id": 1}`),
+			want:     []byte(`{"jsonrpc":"2.0","result":"root add","id":1}` + "\n"),
+		},
+		{
+			name:     "Unknown method - mounted mux",
+			rawBytes: []byte(`{"jsonrpc": "2.0", "method": "billing.invoice.create", "id": 1}`),
+			want:     []byte(`{"jsonrpc":"2.0","result":"billing billing.invoice.create","id":1}` + "\n"),
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			jsonRPCResponseRaw := mux.Serve(context.Background(), tt.rawBytes)
+			if !bytes.Equal(jsonRPCResponseRaw, tt.want) {
+				t.Errorf("Serve() = %v, want %v", string(jsonRPCResponseRaw), string(tt.want))
+			}
+		})
+	}
+
+	t.Run("Unknown methods' handler removed", func(t *testing.T) {
+		mux.HandleUnknown(nil)
+		jsonRPCResponseRaw := mux.Serve(context.Background(), []byte(`{"jsonrpc": "2.0", "method": "add", "id": 1}`))
+		want := []byte(`{"jsonrpc":"2.0","error":{"code":-32601,"message":"Method not found"},"id":1}` + "\n")
+		if !bytes.Equal(jsonRPCResponseRaw, want) {
+			t.Errorf("Serve() = %v, want %v", string(jsonRPCResponseRaw), string(want))
+		}
+	})
+}
+
+func TestMux_Unregister(t *testing.T) {
+	tests := []struct {
+		name     string
+		method   string
+		rawBytes []byte
+		want     []byte
+		wantErr  bool
+	}{
+		{
+			name:     "Registered method",
+			method:   "subtract",
+			rawBytes: []byte(`{"jsonrpc": "2.0", "method": "subtract", "params": [42, 23], "id": 1}`),
+			want:     []byte(`{"jsonrpc":"2.0","error":{"code":-32601,"message":"Method not found"},"id":1}` + "\n"),
+		},
+		{
+			name:    "Method not registered",
+			method:  "add",
+			wantErr: true,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			mux := newTestMux(t)
+			err := mux.Unregister(tt.method)
+			if (err != nil) != tt.wantErr {
+				t.Errorf("Unregister() error = %v, wantErr %v", err, tt.wantErr)
+				return
+			}
+			if tt.wantErr {
+				return
+			}
+
+			jsonRPCResponseRaw := mux.Serve(context.Background(), tt.rawBytes)
+			if !bytes.Equal(jsonRPCResponseRaw, tt.want) {
+				t.Errorf("Serve() = %v, want %v", string(jsonRPCResponseRaw), string(tt.want))
+			}
+		})
+	}
+
+	t.Run("Registration while serving", func(t *testing.T) {
+		mux := newTestMux(t)
+		var wg sync.WaitGroup
+		for i := 0; i < 4; i++ {
+			wg.Add(2)
+			method := fmt.Sprintf("plugin%d", i)
+			go func() {
+				defer wg.Done()
+				rawBytes := []byte(`{"jsonrpc": "2.0", "method": "` + method + `", "params": [42, 23], "id": 1}`)
+				for j := 0; j < 100; j++ {
+					mux.Serve(context.Background(), rawBytes)
+				}
+			}()
+			go func() {
+				defer wg.Done()
+				mux.Use(Recover(nil))
+				for j := 0; j < 100; j++ {
+					err := HandleFunc(mux, method, func(ctx context.Context, params [2]int) (int, error) {
+						return params[0] + params[1], nil
+					})
+					if err != nil {
+						t.Error(err)
+						return
+					}
+					err = mux.SetTimeout(method, time.Second)
+					if err != nil {
+						t.Error(err)
+						return
+					}
+					err = mux.Unregister(method)
+					if err != nil {
+						t.Error(err)
+						return
+					}
+				}
+			}()
+		}
+		wg.Wait()
+	})
+}
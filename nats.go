@@ -0,0 +1,91 @@
+/*  Copyright 2022  Kosmas Valianos (kosmas.valianos@gmail.com)
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    https://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License. */
+
+package gojsonrpc
+
+import (
+	"context"
+)
+
+// NATSMessage is a message received from NATS
+type NATSMessage struct {
+	Subject string
+	Reply   string
+	Data    []byte
+}
+
+// NATSConn is the connection to NATS of the NATS transport. The *nats.Conn of the nats.go client provides PublishRequest
+// and its Subscribe or QueueSubscribe, to balance the requests across the servers, only needs to be wrapped:
+//
+//	func (c natsConn) Subscribe(subject string, handler func(gojsonrpc.NATSMessage)) (func() error, error) {
+//		sub, err := c.Conn.Subscribe(subject, func(msg *nats.Msg) {
+//			handler(gojsonrpc.NATSMessage{Subject: msg.Subject, Reply: msg.Reply, Data: msg.Data})
+//		})
+//		if err != nil {
+//			return nil, err
+//		}
+//		return sub.Unsubscribe, nil
+//	}
+type NATSConn interface {
+	// PublishRequest publishes the data to the subject with the subject of its reply, none if empty
+	PublishRequest(subject, reply string, data []byte) error
+	// Subscribe passes the messages published to the subject to the handler, in their order, until unsubscribed
+	Subscribe(subject string, handler func(msg NATSMessage)) (unsubscribe func() error, err error)
+}
+
+// natsInboxPrefix is the prefix of the subjects where the clients receive their responses
+const natsInboxPrefix = "_INBOX."
+
+// ServeNATS serves the requests and notifications published to the subject with the mux until the ctx is done,
+// publishing the responses to the reply subjects of the requests.
+// Returns the error of the subscription or of the unsubscription
+func ServeNATS(ctx context.Context, conn NATSConn, subject string, mux *Mux) error {
+	unsubscribe, err := conn.Subscribe(subject, func(msg NATSMessage) {
+		serveBrokerMessage(ctx, mux, msg.Data, func(responseRaw []byte) {
+			if msg.Reply != "" {
+				_ = conn.PublishRequest(msg.Reply, "", responseRaw)
+			}
+		})
+	})
+	if err != nil {
+		return err
+	}
+	<-ctx.Done()
+	return unsubscribe()
+}
+
+// DialNATS creates a Conn publishing the requests to the subject with a reply subject of its own, where it receives
+// their responses, and the notifications without any. The server may publish notifications to the reply subject too,
+// which are served with the mux, which may be nil. Closing the Conn unsubscribes from the reply subject.
+// Returns a *Conn object or an error
+func DialNATS(conn NATSConn, subject string, mux *Mux, options ...ClientOption) (*Conn, error) {
+	clientID, err := newBrokerClientID()
+	if err != nil {
+		return nil, err
+	}
+	inbox := natsInboxPrefix + clientID
+	natsConn := newBrokerConn(func(ctx context.Context, messageRaw []byte) error {
+		if isNotificationMessage(messageRaw) {
+			return conn.PublishRequest(subject, "", messageRaw)
+		}
+		return conn.PublishRequest(subject, inbox, messageRaw)
+	})
+	natsConn.unsubscribe, err = conn.Subscribe(inbox, func(msg NATSMessage) {
+		natsConn.deliver(msg.Data)
+	})
+	if err != nil {
+		return nil, err
+	}
+	return NewConn(natsConn, mux, options...), nil
+}
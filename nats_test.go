@@ -0,0 +1,126 @@
+/*  Copyright 2022  Kosmas Valianos (kosmas.valianos@gmail.com)
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    https://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License. */
+
+package gojsonrpc
+
+import (
+	"context"
+	"errors"
+	"sync"
+	"testing"
+	"time"
+)
+
+// memNATS is an in-memory NATS server delivering the messages to the subscribers of their subject
+type memNATS struct {
+	mu     sync.Mutex
+	subs   map[string]map[int]func(NATSMessage)
+	nextID int
+	// published records the reply subject of every message published to a subject
+	published map[string][]string
+}
+
+func newMemNATS() *memNATS {
+	return &memNATS{subs: make(map[string]map[int]func(NATSMessage)), published: make(map[string][]string)}
+}
+
+func (n *memNATS) PublishRequest(subject, reply string, data []byte) error {
+	n.mu.Lock()
+	n.published[subject] = append(n.published[subject], reply)
+	handlers := make([]func(NATSMessage), 0, len(n.subs[subject]))
+	for _, handler := range n.subs[subject] {
+		handlers = append(handlers, handler)
+	}
+	n.mu.Unlock()
+	for _, handler := range handlers {
+		handler(NATSMessage{Subject: subject, Reply: reply, Data: data})
+	}
+	return nil
+}
+
+func (n *memNATS) Subscribe(subject string, handler func(msg NATSMessage)) (func() error, error) {
+	n.mu.Lock()
+	defer n.mu.Unlock()
+	n.nextID++
+	id := n.nextID
+	if n.subs[subject] == nil {
+		n.subs[subject] = make(map[int]func(NATSMessage))
+	}
+	n.subs[subject][id] = handler
+	return func() error {
+		n.mu.Lock()
+		defer n.mu.Unlock()
+		delete(n.subs[subject], id)
+		return nil
+	}, nil
+}
+
+func (n *memNATS) subscribers(subject string) int {
+	n.mu.Lock()
+	defer n.mu.Unlock()
+	return len(n.subs[subject])
+}
+
+func TestDialNATS(t *testing.T) {
+	nats := newMemNATS()
+	ctx, cancel := context.WithCancel(context.Background())
+	served := make(chan error, 1)
+	go func() {
+		served <- ServeNATS(ctx, nats, "math", newTestMux(t))
+	}()
+	for nats.subscribers("math") == 0 {
+		time.Sleep(time.Millisecond)
+	}
+
+	conn, err := DialNATS(nats, "math", nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	var result int
+	err = conn.Call(context.Background(), "subtract", []int{42, 23}, &result)
+	if err != nil || result != 19 {
+		t.Errorf("Call() = %v, %v, want %v", result, err, 19)
+	}
+	err = conn.Notify(context.Background(), "subtract", []int{42, 23})
+	if err != nil {
+		t.Errorf("Notify() = %v, want nil", err)
+	}
+
+	nats.mu.Lock()
+	replies := nats.published["math"]
+	nats.mu.Unlock()
+	if len(replies) != 2 || replies[0] == "" || replies[1] != "" {
+		t.Errorf("reply subjects = %q, want the inbox for the request only", replies)
+	}
+
+	err = conn.Close()
+	if err != nil {
+		t.Errorf("Close() = %v, want nil", err)
+	}
+	if nats.subscribers(replies[0]) != 0 {
+		t.Errorf("subscribers(%q) = %v, want 0", replies[0], nats.subscribers(replies[0]))
+	}
+	err = conn.Call(context.Background(), "subtract", []int{42, 23}, &result)
+	if !errors.Is(err, ErrTransportClosed) {
+		t.Errorf("Call() = %v, want %v", err, ErrTransportClosed)
+	}
+
+	cancel()
+	if err := <-served; err != nil {
+		t.Errorf("ServeNATS() = %v, want nil", err)
+	}
+	if nats.subscribers("math") != 0 {
+		t.Errorf("subscribers(%q) = %v, want 0", "math", nats.subscribers("math"))
+	}
+}
@@ -0,0 +1,234 @@
+/*  Copyright 2022  Kosmas Valianos (kosmas.valianos@gmail.com)
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    https://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License. */
+
+package gojsonrpc
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"strings"
+	"sync"
+)
+
+// ErrNotificationMuxClosed is returned by NotificationMux.Dispatch once the NotificationMux is closed
+var ErrNotificationMuxClosed = errors.New("notification mux closed")
+
+// NotificationHandler handles the params of a JSON-RPC notification dispatched by a NotificationMux.
+// Nothing is replied to a notification, so it returns nothing
+type NotificationHandler interface {
+	HandleNotification(ctx context.Context, params json.RawMessage)
+}
+
+// NotificationHandlerFunc is an adapter to allow the use of ordinary functions as a NotificationHandler
+type NotificationHandlerFunc func(ctx context.Context, params json.RawMessage)
+
+// HandleNotification implements NotificationHandler by calling f(ctx, params)
+func (f NotificationHandlerFunc) HandleNotification(ctx context.Context, params json.RawMessage) {
+	f(ctx, params)
+}
+
+// NotificationMux is a JSON-RPC notification router. It dispatches notifications to the NotificationHandler
+// registered for their method, either on the goroutine of the caller or on worker goroutines.
+// Methods can be registered and unregistered while dispatching
+type NotificationMux struct {
+	mu       sync.RWMutex
+	handlers map[string]NotificationHandler
+	unknown  NotificationHandler
+	// closeMu guards closed and the sends to queue so that Close does not close it under a sender, apart from mu
+	// so that the handlers can register methods while Dispatch waits for room in the queue
+	closeMu sync.RWMutex
+	closed  bool
+	queue   chan dispatchedNotification
+	workers sync.WaitGroup
+}
+
+// dispatchedNotification is a notification queued for the workers of a NotificationMux
+type dispatchedNotification struct {
+	ctx     context.Context
+	handler NotificationHandler
+	params  json.RawMessage
+}
+
+// NotificationMuxOption configures a NotificationMux
+type NotificationMuxOption func(*notificationMuxConfig)
+
+type notificationMuxConfig struct {
+	workers   int
+	queueSize int
+}
+
+// WithNotificationWorkers dispatches the notifications on the given number of worker goroutines through a queue
+// of queueSize notifications, so that Dispatch does not wait for the handlers. Dispatch waits for room in a full queue.
+// A zero or negative number of workers means the notifications are handled on the goroutine of the caller
+func WithNotificationWorkers(workers, queueSize int) NotificationMuxOption {
+	return func(config *notificationMuxConfig) {
+		config.workers = workers
+		config.queueSize = queueSize
+	}
+}
+
+// NewNotificationMux creates an empty NotificationMux configured by the options, starting its workers, if any.
+// Returns a *NotificationMux object
+func NewNotificationMux(options ...NotificationMuxOption) *NotificationMux {
+	var config notificationMuxConfig
+	for _, option := range options {
+		option(&config)
+	}
+
+	mux := &NotificationMux{handlers: make(map[string]NotificationHandler)}
+	if config.workers > 0 {
+		mux.queue = make(chan dispatchedNotification, max(config.queueSize, 0))
+		mux.workers.Add(config.workers)
+		for i := 0; i < config.workers; i++ {
+			go mux.work()
+		}
+	}
+	return mux
+}
+
+// Handle registers the handler for the method.
+// Returns an error if the method is empty, reserved or already registered
+func (m *NotificationMux) Handle(method string, handler NotificationHandler) error {
+	if method == "" {
+		return errors.New("method must not be empty")
+	}
+	if strings.HasPrefix(method, "rpc.") {
+		return errors.New("methods with prefix \"rpc.\" are reserved")
+	}
+	if handler == nil {
+		return errors.New("no handler passed as parameter")
+	}
+
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	if _, ok := m.handlers[method]; ok {
+		return fmt.Errorf("method \"%v\" is already registered", method)
+	}
+
+	m.handlers[method] = handler
+	return nil
+}
+
+// Unregister removes the handler of the method. Notifications already dispatched are still handled.
+// Returns an error if the method is not registered
+func (m *NotificationMux) Unregister(method string) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	if _, ok := m.handlers[method]; !ok {
+		return fmt.Errorf("method \"%v\" is not registered", method)
+	}
+
+	delete(m.handlers, method)
+	return nil
+}
+
+// HandleUnknown registers the handler of the methods which are not registered.
+// The handler gets the method with MethodFromContext. A nil handler restores ignoring them
+func (m *NotificationMux) HandleUnknown(handler NotificationHandler) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	m.unknown = handler
+}
+
+// Dispatch parses the notification from raw bytes and dispatches it to the handler of its method.
+// With workers it returns once the notification is queued; the handler's context then keeps the values of ctx
+// but is not cancelled with it.
+// Returns an error if the notification is invalid, its method is not registered (wrapping ErrMethodNotFound),
+// ctx is done before it is queued or the NotificationMux is closed
+func (m *NotificationMux) Dispatch(ctx context.Context, notificationRaw []byte) error {
+	notification, err := ParseNotification(notificationRaw)
+	if err != nil {
+		return err
+	}
+	return m.dispatch(ctx, notification.Method, notification.Params)
+}
+
+// dispatch dispatches the params to the handler of the method.
+// Returns an error as Dispatch does
+func (m *NotificationMux) dispatch(ctx context.Context, method string, params json.RawMessage) error {
+	handler, ok := m.handler(method)
+	if !ok {
+		return fmt.Errorf("method \"%v\": %w", method, ErrMethodNotFound)
+	}
+	ctx = context.WithValue(ctx, methodContextKey, method)
+
+	m.closeMu.RLock()
+	defer m.closeMu.RUnlock()
+	if m.closed {
+		return ErrNotificationMuxClosed
+	}
+	if m.queue == nil {
+		handler.HandleNotification(ctx, params)
+		return nil
+	}
+
+	select {
+	case m.queue <- dispatchedNotification{ctx: context.WithoutCancel(ctx), handler: handler, params: params}:
+		return nil
+	case <-ctx.Done():
+		return ctx.Err()
+	}
+}
+
+// Close stops accepting notifications and waits for the workers to handle the queued ones.
+// Returns no error, so that it implements io.Closer
+func (m *NotificationMux) Close() error {
+	m.closeMu.Lock()
+	if m.closed {
+		m.closeMu.Unlock()
+		return nil
+	}
+	m.closed = true
+	if m.queue != nil {
+		close(m.queue)
+	}
+	m.closeMu.Unlock()
+
+	m.workers.Wait()
+	return nil
+}
+
+// handles reports whether a handler, including the unknown methods' one, is registered for the method
+func (m *NotificationMux) handles(method string) bool {
+	_, ok := m.handler(method)
+	return ok
+}
+
+// registered reports whether a handler is registered for the method, not as the unknown methods' handler
+func (m *NotificationMux) registered(method string) bool {
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+	_, ok := m.handlers[method]
+	return ok
+}
+
+// handler looks up the handler of the method, falling back to the unknown methods' handler
+func (m *NotificationMux) handler(method string) (NotificationHandler, bool) {
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+	if handler, ok := m.handlers[method]; ok {
+		return handler, true
+	}
+	return m.unknown, m.unknown != nil
+}
+
+// work handles the queued notifications until the queue is closed
+func (m *NotificationMux) work() {
+	defer m.workers.Done()
+	for notification := range m.queue {
+		notification.handler.HandleNotification(notification.ctx, notification.params)
+	}
+}
@@ -0,0 +1,166 @@
+/*  Copyright 2022  Kosmas Valianos (kosmas.valianos@gmail.com)
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    https://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License. */
+
+package gojsonrpc
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"sync"
+	"sync/atomic"
+	"testing"
+)
+
+func TestNotificationMux_Handle(t *testing.T) {
+	handler := NotificationHandlerFunc(func(ctx context.Context, params json.RawMessage) {})
+	tests := []struct {
+		name    string
+		method  string
+		handler NotificationHandler
+		wantErr bool
+	}{
+		{name: "Valid method", method: "add", handler: handler},
+		{name: "Invalid method - empty", method: "", handler: handler, wantErr: true},
+		{name: "Invalid method - prefix rpc.", method: "rpc.add", handler: handler, wantErr: true},
+		{name: "Invalid method - already registered", method: "update", handler: handler, wantErr: true},
+		{name: "Invalid handler - nil", method: "add", wantErr: true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			mux := NewNotificationMux()
+			if err := mux.Handle("update", handler); err != nil {
+				t.Fatal(err)
+			}
+			if err := mux.Handle(tt.method, tt.handler); (err != nil) != tt.wantErr {
+				t.Errorf("Handle() error = %v, wantErr %v", err, tt.wantErr)
+			}
+		})
+	}
+}
+
+func TestNotificationMux_Dispatch(t *testing.T) {
+	var gotMethod string
+	var gotParams json.RawMessage
+	mux := NewNotificationMux()
+	err := mux.Handle("update", NotificationHandlerFunc(func(ctx context.Context, params json.RawMessage) {
+		gotMethod, gotParams = MethodFromContext(ctx), params
+	}))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if err := mux.Dispatch(context.Background(), []byte(`{"jsonrpc": "2.0", "method": "update", "params": [1,2,3]}`)); err != nil {
+		t.Fatalf("Dispatch() error = %v", err)
+	}
+	if gotMethod != "update" || string(gotParams) != "[1,2,3]" {
+		t.Errorf("Dispatch() handled %v %s, want update [1,2,3]", gotMethod, gotParams)
+	}
+
+	if err := mux.Dispatch(context.Background(), []byte(`{"jsonrpc": "2.0", "method": "foobar"}`)); !errors.Is(err, ErrMethodNotFound) {
+		t.Errorf("Dispatch() error = %v, want %v", err, ErrMethodNotFound)
+	}
+	if err := mux.Dispatch(context.Background(), []byte(`{"jsonrpc": "1.0", "method": "update"}`)); err == nil {
+		t.Error("Dispatch() error = nil, want an error for an invalid notification")
+	}
+
+	mux.HandleUnknown(NotificationHandlerFunc(func(ctx context.Context, params json.RawMessage) {
+		gotMethod = MethodFromContext(ctx)
+	}))
+	if err := mux.Dispatch(context.Background(), []byte(`{"jsonrpc": "2.0", "method": "foobar"}`)); err != nil || gotMethod != "foobar" {
+		t.Errorf("Dispatch() = %v handling %v, want the unknown methods' handler", err, gotMethod)
+	}
+
+	if err := mux.Unregister("update"); err != nil {
+		t.Fatal(err)
+	}
+	if err := mux.Unregister("update"); err == nil {
+		t.Error("Unregister() error = nil, want an error for a method not registered")
+	}
+}
+
+func TestNotificationMux_Workers(t *testing.T) {
+	type key struct{}
+	var handled atomic.Int32
+	var mu sync.Mutex
+	values := make(map[any]bool)
+	mux := NewNotificationMux(WithNotificationWorkers(4, 8))
+	err := mux.Handle("update", NotificationHandlerFunc(func(ctx context.Context, params json.RawMessage) {
+		if ctx.Err() != nil {
+			t.Error("handler's context is cancelled with the context of Dispatch")
+		}
+		mu.Lock()
+		values[ctx.Value(key{})] = true
+		mu.Unlock()
+		handled.Add(1)
+	}))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	const notifications = 100
+	for i := 0; i < notifications; i++ {
+		ctx, cancel := context.WithCancel(context.WithValue(context.Background(), key{}, "value"))
+		err := mux.Dispatch(ctx, []byte(`{"jsonrpc": "2.0", "method": "update"}`))
+		cancel()
+		if err != nil {
+			t.Fatalf("Dispatch() error = %v", err)
+		}
+	}
+	if err := mux.Close(); err != nil {
+		t.Fatal(err)
+	}
+	if got := handled.Load(); got != notifications {
+		t.Errorf("handled %v notifications after Close(), want %v", got, notifications)
+	}
+	if len(values) != 1 || !values["value"] {
+		t.Errorf("handler's context values = %v, want the value of the context of Dispatch", values)
+	}
+
+	if err := mux.Dispatch(context.Background(), []byte(`{"jsonrpc": "2.0", "method": "update"}`)); !errors.Is(err, ErrNotificationMuxClosed) {
+		t.Errorf("Dispatch() error = %v after Close(), want %v", err, ErrNotificationMuxClosed)
+	}
+}
+
+func TestMux_NotificationMux(t *testing.T) {
+	var dispatched, served atomic.Int32
+	notifications := NewNotificationMux()
+	err := notifications.Handle("update", NotificationHandlerFunc(func(ctx context.Context, params json.RawMessage) {
+		dispatched.Add(1)
+	}))
+	if err != nil {
+		t.Fatal(err)
+	}
+	mux := NewMux(WithNotificationMux(notifications))
+	handler := HandlerFunc(func(ctx context.Context, params json.RawMessage) (any, error) {
+		served.Add(1)
+		return nil, nil
+	})
+	if err := mux.Handle("update", handler); err != nil {
+		t.Fatal(err)
+	}
+	if err := mux.Handle("log", handler); err != nil {
+		t.Fatal(err)
+	}
+
+	if got := mux.Serve(context.Background(), []byte(`{"jsonrpc": "2.0", "method": "update"}`)); got != nil {
+		t.Errorf("Serve() = %s, want nil", got)
+	}
+	if got := mux.Serve(context.Background(), []byte(`{"jsonrpc": "2.0", "method": "log"}`)); got != nil {
+		t.Errorf("Serve() = %s, want nil", got)
+	}
+	if dispatched.Load() != 1 || served.Load() != 1 {
+		t.Errorf("dispatched %v and served %v notifications, want 1 and 1", dispatched.Load(), served.Load())
+	}
+}
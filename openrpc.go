@@ -0,0 +1,315 @@
+/*  Copyright 2022  Kosmas Valianos (kosmas.valianos@gmail.com)
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    https://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License. */
+
+package gojsonrpc
+
+import (
+	"encoding"
+	"encoding/json"
+	"fmt"
+	"reflect"
+	"strings"
+	"time"
+)
+
+const openRPCVersion = "1.2.6"
+
+// OpenRPCDocument is an OpenRPC service description, see https://spec.open-rpc.org
+type OpenRPCDocument struct {
+	OpenRPC    string             `json:"openrpc"`
+	Info       OpenRPCInfo        `json:"info"`
+	Methods    []OpenRPCMethod    `json:"methods"`
+	Components *OpenRPCComponents `json:"components,omitempty"`
+}
+
+// OpenRPCInfo is the metadata of the API described by an OpenRPCDocument
+type OpenRPCInfo struct {
+	Title       string `json:"title"`
+	Description string `json:"description,omitempty"`
+	Version     string `json:"version"`
+}
+
+// OpenRPCMethod describes a method in an OpenRPCDocument
+type OpenRPCMethod struct {
+	Name           string                     `json:"name"`
+	Summary        string                     `json:"summary,omitempty"`
+	Description    string                     `json:"description,omitempty"`
+	Deprecated     bool                       `json:"deprecated,omitempty"`
+	ParamStructure string                     `json:"paramStructure,omitempty"`
+	Params         []OpenRPCContentDescriptor `json:"params"`
+	Result         *OpenRPCContentDescriptor  `json:"result,omitempty"`
+}
+
+// OpenRPCContentDescriptor describes a param or the result of a method in an OpenRPCDocument
+type OpenRPCContentDescriptor struct {
+	Name     string      `json:"name"`
+	Required bool        `json:"required,omitempty"`
+	Schema   *JSONSchema `json:"schema"`
+}
+
+// OpenRPCComponents holds the schemas referenced in an OpenRPCDocument
+type OpenRPCComponents struct {
+	Schemas map[string]*JSONSchema `json:"schemas,omitempty"`
+}
+
+// JSONSchema is the subset of JSON Schema used to describe the params and the results of the methods
+type JSONSchema struct {
+	Ref                  string                 `json:"$ref,omitempty"`
+	Type                 string                 `json:"type,omitempty"`
+	Format               string                 `json:"format,omitempty"`
+	Enum                 []any                  `json:"enum,omitempty"`
+	Items                *JSONSchema            `json:"items,omitempty"`
+	MinItems             int                    `json:"minItems,omitempty"`
+	MaxItems             int                    `json:"maxItems,omitempty"`
+	Properties           map[string]*JSONSchema `json:"properties,omitempty"`
+	Required             []string               `json:"required,omitempty"`
+	AdditionalProperties *JSONSchema            `json:"additionalProperties,omitempty"`
+}
+
+// WithOpenRPCInfo sets the info of the OpenRPC document returned by OpenRPC and "rpc.discover"
+func WithOpenRPCInfo(info OpenRPCInfo) MuxOption {
+	return func(m *Mux) {
+		m.openRPCInfo = info
+	}
+}
+
+// OpenRPC generates the OpenRPC document of the registered methods, including the ones of the mounted muxes.
+// The params and the result of the handlers registered with HandleFunc are described by reflecting on their types:
+// struct params by name and array params by position.
+// Returns an *OpenRPCDocument object
+func (m *Mux) OpenRPC() *OpenRPCDocument {
+	return newOpenRPCDocument(m.openRPCInfo, m.fieldNaming, m.routes())
+}
+
+func newOpenRPCDocument(info OpenRPCInfo, naming FieldNaming, routes []route) *OpenRPCDocument {
+	generator := schemaGenerator{
+		naming:  naming,
+		schemas: make(map[string]*JSONSchema),
+		names:   make(map[reflect.Type]string),
+		types:   make(map[string]reflect.Type),
+	}
+
+	document := &OpenRPCDocument{
+		OpenRPC: openRPCVersion,
+		Info:    info,
+		Methods: make([]OpenRPCMethod, 0, len(routes)),
+	}
+	for _, route := range routes {
+		document.Methods = append(document.Methods, generator.method(route))
+	}
+	if len(generator.schemas) > 0 {
+		document.Components = &OpenRPCComponents{Schemas: generator.schemas}
+	}
+	return document
+}
+
+var (
+	timeType          = reflect.TypeOf(time.Time{})
+	jsonMarshalerType = reflect.TypeOf((*json.Marshaler)(nil)).Elem()
+	textMarshalerType = reflect.TypeOf((*encoding.TextMarshaler)(nil)).Elem()
+)
+
+// schemaGenerator generates the JSON schemas of Go types. Named struct types are added to the schemas
+// and referenced, which also covers recursive types
+type schemaGenerator struct {
+	naming  FieldNaming
+	schemas map[string]*JSONSchema
+	names   map[reflect.Type]string
+	types   map[string]reflect.Type
+}
+
+// schemaField is a member of a JSON object marshaled from a struct field
+type schemaField struct {
+	name     string
+	required bool
+	schema   *JSONSchema
+}
+
+func (g *schemaGenerator) method(route route) OpenRPCMethod {
+	method := OpenRPCMethod{
+		Name:        route.info.Name,
+		Summary:     route.info.Summary,
+		Description: route.info.Description,
+		Deprecated:  route.info.Deprecated,
+		Params:      []OpenRPCContentDescriptor{},
+	}
+
+	typed, ok := route.handler.(interface {
+		signature() (reflect.Type, reflect.Type)
+	})
+	if !ok {
+		return method
+	}
+
+	paramsType, resultType := typed.signature()
+	paramsType = indirectType(paramsType)
+	switch {
+	case paramsType.Kind() == reflect.Struct && !hasCustomMarshaling(paramsType):
+		method.ParamStructure = "by-name"
+		for _, field := range g.fields(paramsType) {
+			method.Params = append(method.Params, OpenRPCContentDescriptor{
+				Name:     field.name,
+				Required: field.required,
+				Schema:   field.schema,
+			})
+		}
+	case paramsType.Kind() == reflect.Array && !hasCustomMarshaling(paramsType):
+		method.ParamStructure = "by-position"
+		for i := 0; i < paramsType.Len(); i++ {
+			method.Params = append(method.Params, OpenRPCContentDescriptor{
+				Name:     fmt.Sprintf("param%d", i+1),
+				Required: true,
+				Schema:   g.schema(paramsType.Elem()),
+			})
+		}
+	}
+
+	method.Result = &OpenRPCContentDescriptor{
+		Name:   "result",
+		Schema: g.schema(resultType),
+	}
+	return method
+}
+
+func (g *schemaGenerator) schema(t reflect.Type) *JSONSchema {
+	t = indirectType(t)
+	if enum, ok := reflect.Zero(t).Interface().(interface {
+		enumSchema() (reflect.Type, []any)
+	}); ok {
+		valueType, values := enum.enumSchema()
+		schema := *g.schema(valueType)
+		schema.Enum = values
+		return &schema
+	}
+	switch {
+	case t == timeType:
+		return &JSONSchema{Type: "string", Format: "date-time"}
+	case t.Implements(jsonMarshalerType) || reflect.PointerTo(t).Implements(jsonMarshalerType):
+		// Anything can be marshaled
+		return &JSONSchema{}
+	case t.Implements(textMarshalerType) || reflect.PointerTo(t).Implements(textMarshalerType):
+		return &JSONSchema{Type: "string"}
+	}
+
+	switch t.Kind() {
+	case reflect.Bool:
+		return &JSONSchema{Type: "boolean"}
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64,
+		reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64, reflect.Uintptr:
+		return &JSONSchema{Type: "integer"}
+	case reflect.Float32, reflect.Float64:
+		return &JSONSchema{Type: "number"}
+	case reflect.String:
+		return &JSONSchema{Type: "string"}
+	case reflect.Slice:
+		if t.Elem().Kind() == reflect.Uint8 {
+			// []byte is marshaled as a base64 string
+			return &JSONSchema{Type: "string", Format: "byte"}
+		}
+		return &JSONSchema{Type: "array", Items: g.schema(t.Elem())}
+	case reflect.Array:
+		return &JSONSchema{Type: "array", Items: g.schema(t.Elem()), MinItems: t.Len(), MaxItems: t.Len()}
+	case reflect.Map:
+		return &JSONSchema{Type: "object", AdditionalProperties: g.schema(t.Elem())}
+	case reflect.Struct:
+		if t.Name() == "" {
+			return g.objectSchema(t)
+		}
+		name, ok := g.names[t]
+		if !ok {
+			name = g.name(t)
+			// Added before generating the object schema so that recursive types reference it
+			g.schemas[name] = &JSONSchema{}
+			*g.schemas[name] = *g.objectSchema(t)
+		}
+		return &JSONSchema{Ref: "#/components/schemas/" + name}
+	default:
+		return &JSONSchema{}
+	}
+}
+
+func (g *schemaGenerator) objectSchema(t reflect.Type) *JSONSchema {
+	schema := &JSONSchema{
+		Type:       "object",
+		Properties: make(map[string]*JSONSchema),
+	}
+	for _, field := range g.fields(t) {
+		schema.Properties[field.name] = field.schema
+		if field.required {
+			schema.Required = append(schema.Required, field.name)
+		}
+	}
+	return schema
+}
+
+// fields returns the members of the JSON object marshaled from the struct type
+func (g *schemaGenerator) fields(t reflect.Type) []schemaField {
+	var fields []schemaField
+	for _, field := range jsonFields(t, g.naming) {
+		schema := g.schema(field.typ)
+		if hasTagOption(field.options, "string") {
+			schema = &JSONSchema{Type: "string"}
+		}
+		fields = append(fields, schemaField{
+			name:     field.name,
+			required: !hasTagOption(field.options, "omitempty"),
+			schema:   schema,
+		})
+	}
+	return fields
+}
+
+// name returns a unique name of the named type for the schemas
+func (g *schemaGenerator) name(t reflect.Type) string {
+	base := strings.Map(func(r rune) rune {
+		if r >= 'a' && r <= 'z' || r >= 'A' && r <= 'Z' || r >= '0' && r <= '9' || r == '.' || r == '_' || r == '-' {
+			return r
+		}
+		return '_'
+	}, t.Name())
+
+	name := base
+	for i := 2; ; i++ {
+		if _, ok := g.types[name]; !ok {
+			break
+		}
+		name = fmt.Sprintf("%v%d", base, i)
+	}
+	g.names[t] = name
+	g.types[name] = t
+	return name
+}
+
+func indirectType(t reflect.Type) reflect.Type {
+	for t.Kind() == reflect.Pointer {
+		t = t.Elem()
+	}
+	return t
+}
+
+func hasCustomMarshaling(t reflect.Type) bool {
+	return t.Implements(jsonMarshalerType) || reflect.PointerTo(t).Implements(jsonMarshalerType) ||
+		t.Implements(textMarshalerType) || reflect.PointerTo(t).Implements(textMarshalerType)
+}
+
+func hasTagOption(options, option string) bool {
+	for options != "" {
+		var current string
+		current, options, _ = strings.Cut(options, ",")
+		if current == option {
+			return true
+		}
+	}
+	return false
+}
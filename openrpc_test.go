@@ -0,0 +1,84 @@
+/*  Copyright 2022  Kosmas Valianos (kosmas.valianos@gmail.com)
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    https://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License. */
+
+package gojsonrpc
+
+import (
+	"context"
+	"encoding/json"
+	"testing"
+	"time"
+)
+
+type testAddress struct {
+	Street string `json:"street"`
+	Zip    string `json:"zip,omitempty"`
+}
+
+type testAudit struct {
+	Created time.Time `json:"created"`
+}
+
+type testCustomer struct {
+	testAudit
+	Name     string            `json:"name"`
+	Age      int               `json:"age,omitempty"`
+	ID       int64             `json:"id,string"`
+	Address  *testAddress      `json:"address"`
+	Tags     []string          `json:"tags,omitempty"`
+	Labels   map[string]string `json:"labels,omitempty"`
+	Referrer *testCustomer     `json:"referrer,omitempty"`
+	Secret   string            `json:"-"`
+	internal string
+}
+
+func TestMux_OpenRPC(t *testing.T) {
+	mux := NewMux(WithOpenRPCInfo(OpenRPCInfo{Title: "Customers", Version: "1.0.0"}))
+	err := HandleFunc(mux, "customer.create", func(ctx context.Context, params testCustomer) (*testCustomer, error) {
+		return &params, nil
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	err = HandleFunc(mux, "customer.count", func(ctx context.Context, params [1]struct {
+		Active bool `json:"active"`
+	}) (uint, error) {
+		return 0, nil
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	err = mux.Handle("echo", HandlerFunc(func(ctx context.Context, params json.RawMessage) (any, error) {
+		return params, nil
+	}))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	want := `{"openrpc":"1.2.6","info":{"title":"Customers","version":"1.0.0"},"methods":[` +
+		`{"name":"customer.count","paramStructure":"by-position","params":[{"name":"param1","required":true,"schema":{"type":"object","properties":{"active":{"type":"boolean"}},"required":["active"]}}],"result":{"name":"result","schema":{"type":"integer"}}},` +
+		`{"name":"customer.create","paramStructure":"by-name","params":[{"name":"created","required":true,"schema":{"type":"string","format":"date-time"}},{"name":"name","required":true,"schema":{"type":"string"}},{"name":"age","schema":{"type":"integer"}},{"name":"id","required":true,"schema":{"type":"string"}},{"name":"address","required":true,"schema":{"$ref":"#/components/schemas/testAddress"}},{"name":"tags","schema":{"type":"array","items":{"type":"string"}}},{"name":"labels","schema":{"type":"object","additionalProperties":{"type":"string"}}},{"name":"referrer","schema":{"$ref":"#/components/schemas/testCustomer"}}],"result":{"name":"result","schema":{"$ref":"#/components/schemas/testCustomer"}}},` +
+		`{"name":"echo","params":[]}],` +
+		`"components":{"schemas":{` +
+		`"testAddress":{"type":"object","properties":{"street":{"type":"string"},"zip":{"type":"string"}},"required":["street"]},` +
+		`"testCustomer":{"type":"object","properties":{"address":{"$ref":"#/components/schemas/testAddress"},"age":{"type":"integer"},"created":{"type":"string","format":"date-time"},"id":{"type":"string"},"labels":{"type":"object","additionalProperties":{"type":"string"}},"name":{"type":"string"},"referrer":{"$ref":"#/components/schemas/testCustomer"},"tags":{"type":"array","items":{"type":"string"}}},"required":["created","name","id","address"]}}}}`
+
+	got, err := json.Marshal(mux.OpenRPC())
+	if err != nil {
+		t.Fatal(err)
+	}
+	if string(got) != want {
+		t.Errorf("OpenRPC() = %v, want %v", string(got), want)
+	}
+}
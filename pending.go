@@ -0,0 +1,252 @@
+/*  Copyright 2022  Kosmas Valianos (kosmas.valianos@gmail.com)
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    https://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License. */
+
+package gojsonrpc
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"sync"
+)
+
+// ErrTransportClosed is returned by the calls pending or started on a closed transport
+var ErrTransportClosed = errors.New("transport closed")
+
+// pendingCall is a request or a batch waiting for its response
+type pendingCall struct {
+	ids      []string
+	request  []byte
+	response chan []byte
+	// err is the error of the call once response is closed
+	err error
+}
+
+// pendingCalls correlates the responses received on a persistent connection with the outstanding requests by their IDs
+type pendingCalls struct {
+	mu    sync.Mutex
+	calls map[string]*pendingCall
+	err   error
+}
+
+func newPendingCalls() *pendingCalls {
+	return &pendingCalls{calls: make(map[string]*pendingCall)}
+}
+
+// add registers a request or a batch as outstanding under the IDs of its requests.
+// Returns the *pendingCall object or an error if the request has no ID, an ID is already outstanding or the connection is closed
+func (p *pendingCalls) add(requestRaw []byte) (*pendingCall, error) {
+	ids, err := messageIDs(requestRaw)
+	if err != nil {
+		return nil, err
+	}
+	if len(ids) == 0 {
+		return nil, errors.New("request must have an ID")
+	}
+
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	if p.err != nil {
+		return nil, p.err
+	}
+	for _, id := range ids {
+		if _, ok := p.calls[id]; ok {
+			return nil, errors.New("request's ID " + id + " is already outstanding")
+		}
+	}
+	call := &pendingCall{ids: ids, request: requestRaw, response: make(chan []byte, 1)}
+	for _, id := range ids {
+		p.calls[id] = call
+	}
+	return call, nil
+}
+
+// remove forgets an outstanding call, e.g. once it is abandoned
+func (p *pendingCalls) remove(call *pendingCall) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	p.removeLocked(call)
+}
+
+func (p *pendingCalls) removeLocked(call *pendingCall) {
+	for _, id := range call.ids {
+		if p.calls[id] == call {
+			delete(p.calls, id)
+		}
+	}
+}
+
+// deliver delivers a response or a batch of responses to the outstanding call with one of their IDs.
+// Returns an error if no call is waiting for it
+func (p *pendingCalls) deliver(responseRaw []byte) error {
+	ids, err := messageIDs(responseRaw)
+	if err != nil {
+		return err
+	}
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	for _, id := range ids {
+		if call, ok := p.calls[id]; ok {
+			p.removeLocked(call)
+			call.response <- responseRaw
+			return nil
+		}
+	}
+	return errors.New("no call is waiting for the response")
+}
+
+// requests returns the raw bytes of the outstanding requests and batches, e.g. to replay them
+func (p *pendingCalls) requests() [][]byte {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	seen := make(map[*pendingCall]bool)
+	var requestsRaw [][]byte
+	for _, call := range p.calls {
+		if !seen[call] {
+			seen[call] = true
+			requestsRaw = append(requestsRaw, call.request)
+		}
+	}
+	return requestsRaw
+}
+
+// wait waits for the response of the outstanding call, forgetting it when ctx is done.
+// Returns the raw bytes of the response or an error if ctx is done or the connection is closed first
+func (p *pendingCalls) wait(ctx context.Context, call *pendingCall) ([]byte, error) {
+	select {
+	case responseRaw, ok := <-call.response:
+		if !ok {
+			return nil, call.err
+		}
+		return responseRaw, nil
+	case <-ctx.Done():
+		p.remove(call)
+		return nil, ctx.Err()
+	}
+}
+
+// fail fails the outstanding calls with err, e.g. once the connection is lost
+func (p *pendingCalls) fail(err error) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	p.failLocked(err)
+}
+
+func (p *pendingCalls) failLocked(err error) {
+	for _, call := range p.calls {
+		p.removeLocked(call)
+		call.err = err
+		close(call.response)
+	}
+}
+
+// close fails the outstanding and the future calls with err
+func (p *pendingCalls) close(err error) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	if p.err != nil {
+		return
+	}
+	p.err = err
+	p.failLocked(err)
+}
+
+func (p *pendingCalls) closeError() error {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	return p.err
+}
+
+// messageIDs returns the keys of the IDs of the requests or the responses in a message or a batch.
+// Notifications and responses with a null ID have none
+func messageIDs(messageRaw []byte) ([]string, error) {
+	messagesRaw, err := splitBatch(messageRaw)
+	if err != nil {
+		return nil, err
+	}
+
+	var ids []string
+	for _, messageRaw := range messagesRaw {
+		var envelope struct {
+			ID any `json:"id"`
+		}
+		err := json.Unmarshal(messageRaw, &envelope)
+		if err != nil {
+			return nil, err
+		}
+		if envelope.ID != nil {
+			ids = append(ids, idKey(envelope.ID))
+		}
+	}
+	return ids, nil
+}
+
+// splitBatch returns the messages of a batch or the message itself if it is not a batch
+func splitBatch(messageRaw []byte) ([]json.RawMessage, error) {
+	if jsonKind(messageRaw) != '[' {
+		return []json.RawMessage{messageRaw}, nil
+	}
+	var messagesRaw []json.RawMessage
+	err := json.Unmarshal(messageRaw, &messagesRaw)
+	return messagesRaw, err
+}
+
+// MessageTransport is a Transport over a persistent connection which carries whole messages, e.g. a socket or a stream
+// with a framing. It sends the messages with a function and correlates the responses, which the reader of the connection
+// passes to Deliver, with the outstanding requests by their IDs. Calls abandoned because their context is done are forgotten
+type MessageTransport struct {
+	send    func(ctx context.Context, messageRaw []byte) error
+	pending *pendingCalls
+}
+
+// NewMessageTransport creates a MessageTransport sending the messages with the send function, which must be safe for concurrent use.
+// Returns a *MessageTransport object
+func NewMessageTransport(send func(ctx context.Context, messageRaw []byte) error) *MessageTransport {
+	return &MessageTransport{send: send, pending: newPendingCalls()}
+}
+
+// RoundTrip sends a request or a batch and waits for its response until ctx is done.
+// Returns the raw bytes of the response or an error
+func (t *MessageTransport) RoundTrip(ctx context.Context, requestRaw []byte) ([]byte, error) {
+	call, err := t.pending.add(requestRaw)
+	if err != nil {
+		return nil, err
+	}
+	err = t.send(ctx, requestRaw)
+	if err != nil {
+		t.pending.remove(call)
+		return nil, err
+	}
+	return t.pending.wait(ctx, call)
+}
+
+// Send sends a notification
+func (t *MessageTransport) Send(ctx context.Context, notificationRaw []byte) error {
+	if err := t.pending.closeError(); err != nil {
+		return err
+	}
+	return t.send(ctx, notificationRaw)
+}
+
+// Deliver passes a response or a batch of responses received on the connection to the call waiting for it.
+// Returns an error if no call is waiting for it, e.g. because it was abandoned
+func (t *MessageTransport) Deliver(responseRaw []byte) error {
+	return t.pending.deliver(responseRaw)
+}
+
+// Close fails the outstanding and the future calls with ErrTransportClosed, e.g. once the connection is lost
+func (t *MessageTransport) Close() error {
+	t.pending.close(ErrTransportClosed)
+	return nil
+}
@@ -0,0 +1,146 @@
+/*  Copyright 2022  Kosmas Valianos (kosmas.valianos@gmail.com)
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    https://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License. */
+
+package gojsonrpc
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+)
+
+// newLoopbackTransport creates a MessageTransport whose messages are served asynchronously by the mux,
+// like by a server at the other end of a connection
+func newLoopbackTransport(mux *Mux) *MessageTransport {
+	var transport *MessageTransport
+	transport = NewMessageTransport(func(ctx context.Context, messageRaw []byte) error {
+		go func() {
+			responseRaw := mux.Serve(context.Background(), messageRaw)
+			if responseRaw != nil {
+				_ = transport.Deliver(responseRaw)
+			}
+		}()
+		return nil
+	})
+	return transport
+}
+
+func outstandingCalls(transport *MessageTransport) int {
+	transport.pending.mu.Lock()
+	defer transport.pending.mu.Unlock()
+	return len(transport.pending.calls)
+}
+
+func newSlowTestMux(t *testing.T, unblock chan struct{}) *Mux {
+	mux := newTestMux(t)
+	err := HandleFunc(mux, "slow", func(ctx context.Context, params any) (string, error) {
+		<-unblock
+		return "done", nil
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	return mux
+}
+
+func TestMessageTransport(t *testing.T) {
+	unblock := make(chan struct{})
+	defer close(unblock)
+	transport := newLoopbackTransport(newSlowTestMux(t, unblock))
+	client := NewClient(transport)
+
+	t.Run("Concurrent calls", func(t *testing.T) {
+		calls := make([]*AsyncCall, 0, 10)
+		for i := 0; i < 10; i++ {
+			calls = append(calls, client.Go(context.Background(), "subtract", []int{42, i}))
+		}
+		for i, call := range calls {
+			<-call.Done
+			var result int
+			err := call.Unmarshal(&result)
+			if err != nil || result != 42-i {
+				t.Errorf("Unmarshal() = %v, %v, want %v", result, err, 42-i)
+			}
+		}
+	})
+
+	t.Run("Batch", func(t *testing.T) {
+		results, err := client.CallBatch(context.Background(), []BatchItem{
+			{Method: "subtract", Params: []int{42, 23}},
+			{Method: "multiply", Params: []int{42, 23}},
+		})
+		if err != nil {
+			t.Fatalf("CallBatch() error = %v", err)
+		}
+		var result int
+		if err := results[0].Unmarshal(&result); err != nil || result != 19 {
+			t.Errorf("Unmarshal() = %v, %v, want 19", result, err)
+		}
+		if results[1].Error == nil {
+			t.Error("CallBatch() error of an unknown method = nil")
+		}
+	})
+
+	t.Run("Per-call timeout", func(t *testing.T) {
+		ctx, cancel := context.WithTimeout(context.Background(), 20*time.Millisecond)
+		defer cancel()
+		err := client.Call(ctx, "slow", nil, nil)
+		if !errors.Is(err, ErrCallTimeout) {
+			t.Errorf("Call() error = %v, want %v", err, ErrCallTimeout)
+		}
+		if got := outstandingCalls(transport); got != 0 {
+			t.Errorf("%v abandoned calls are still outstanding", got)
+		}
+	})
+
+	t.Run("Default timeout", func(t *testing.T) {
+		client := NewClient(transport, WithCallTimeout(20*time.Millisecond))
+		err := client.Call(context.Background(), "slow", nil, nil)
+		if !errors.Is(err, ErrCallTimeout) {
+			t.Errorf("Call() error = %v, want %v", err, ErrCallTimeout)
+		}
+	})
+
+	t.Run("Uncorrelated response", func(t *testing.T) {
+		err := transport.Deliver([]byte(`{"jsonrpc":"2.0","result":19,"id":"unknown"}`))
+		if err == nil {
+			t.Error("Deliver() of a response no call waits for succeeded")
+		}
+	})
+}
+
+func TestMessageTransport_Close(t *testing.T) {
+	unblock := make(chan struct{})
+	defer close(unblock)
+	transport := newLoopbackTransport(newSlowTestMux(t, unblock))
+	client := NewClient(transport)
+
+	call := client.Go(context.Background(), "slow", nil)
+	for outstandingCalls(transport) == 0 {
+		time.Sleep(time.Millisecond)
+	}
+	transport.Close()
+
+	<-call.Done
+	if !errors.Is(call.Error, ErrTransportClosed) {
+		t.Errorf("Go() error = %v, want %v", call.Error, ErrTransportClosed)
+	}
+	if err := client.Call(context.Background(), "subtract", []int{42, 23}, nil); !errors.Is(err, ErrTransportClosed) {
+		t.Errorf("Call() error = %v, want %v", err, ErrTransportClosed)
+	}
+	if err := client.Notify(context.Background(), "update", nil); !errors.Is(err, ErrTransportClosed) {
+		t.Errorf("Notify() error = %v, want %v", err, ErrTransportClosed)
+	}
+}
@@ -0,0 +1,97 @@
+/*  Copyright 2022  Kosmas Valianos (kosmas.valianos@gmail.com)
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    https://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License. */
+
+package gojsonrpc
+
+import (
+	"context"
+	"sync"
+)
+
+// pipeBuffer is the number of messages a pipe end may have written and not yet read by the other end
+const pipeBuffer = 16
+
+// pipeConn is one end of an in-memory MessageConn pair
+type pipeConn struct {
+	in     chan []byte
+	out    chan []byte
+	closed chan struct{}
+	peer   *pipeConn
+	once   sync.Once
+}
+
+// Pipe creates the two ends of an in-memory connection, e.g. to test a client and a server together without sockets.
+// Closing either end closes the connection.
+// Returns the two MessageConns
+func Pipe() (MessageConn, MessageConn) {
+	ab, ba := make(chan []byte, pipeBuffer), make(chan []byte, pipeBuffer)
+	a := &pipeConn{in: ba, out: ab, closed: make(chan struct{})}
+	b := &pipeConn{in: ab, out: ba, closed: make(chan struct{})}
+	a.peer, b.peer = b, a
+	return a, b
+}
+
+// NewConnPipe creates the two ends of an in-memory connection as Conns, the server serving the requests and notifications
+// of the client with serverMux and the client those of the server with clientMux, either mux may be nil.
+// The Clients of both are configured by the options.
+// Returns the server and the client Conns
+func NewConnPipe(serverMux *Mux, clientMux *Mux, options ...ClientOption) (*Conn, *Conn) {
+	serverEnd, clientEnd := Pipe()
+	return NewConn(serverEnd, serverMux, options...), NewConn(clientEnd, clientMux, options...)
+}
+
+// WriteMessage passes a copy of the message to the other end, waiting while it has too many messages to read
+func (c *pipeConn) WriteMessage(ctx context.Context, messageRaw []byte) error {
+	select {
+	case <-c.closed:
+		return ErrTransportClosed
+	case <-c.peer.closed:
+		return ErrTransportClosed
+	default:
+	}
+	message := append([]byte(nil), messageRaw...)
+	select {
+	case c.out <- message:
+		return nil
+	case <-c.closed:
+		return ErrTransportClosed
+	case <-c.peer.closed:
+		return ErrTransportClosed
+	case <-ctx.Done():
+		return ctx.Err()
+	}
+}
+
+// ReadMessage returns the next message written by the other end or ErrTransportClosed once either end is closed
+func (c *pipeConn) ReadMessage() ([]byte, error) {
+	select {
+	case <-c.closed:
+		return nil, ErrTransportClosed
+	default:
+	}
+	select {
+	case message := <-c.in:
+		return message, nil
+	case <-c.closed:
+		return nil, ErrTransportClosed
+	case <-c.peer.closed:
+		return nil, ErrTransportClosed
+	}
+}
+
+// Close closes the connection
+func (c *pipeConn) Close() error {
+	c.once.Do(func() { close(c.closed) })
+	return nil
+}
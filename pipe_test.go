@@ -0,0 +1,83 @@
+/*  Copyright 2022  Kosmas Valianos (kosmas.valianos@gmail.com)
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    https://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License. */
+
+package gojsonrpc
+
+import (
+	"context"
+	"errors"
+	"testing"
+)
+
+func TestPipe(t *testing.T) {
+	a, b := Pipe()
+	message := []byte(`{"jsonrpc":"2.0","method":"update"}`)
+	err := a.WriteMessage(context.Background(), message)
+	if err != nil {
+		t.Fatalf("WriteMessage() = %v, want nil", err)
+	}
+	// The message is copied
+	message[0] = '['
+	got, err := b.ReadMessage()
+	if err != nil || string(got) != `{"jsonrpc":"2.0","method":"update"}` {
+		t.Errorf("ReadMessage() = %s, %v", got, err)
+	}
+
+	b.Close()
+	if _, err := a.ReadMessage(); !errors.Is(err, ErrTransportClosed) {
+		t.Errorf("ReadMessage() = %v, want %v", err, ErrTransportClosed)
+	}
+	if err := a.WriteMessage(context.Background(), message); !errors.Is(err, ErrTransportClosed) {
+		t.Errorf("WriteMessage() = %v, want %v", err, ErrTransportClosed)
+	}
+
+	t.Run("Write canceled", func(t *testing.T) {
+		a, _ := Pipe()
+		for i := 0; i < pipeBuffer; i++ {
+			_ = a.WriteMessage(context.Background(), message)
+		}
+		ctx, cancel := context.WithCancel(context.Background())
+		cancel()
+		if err := a.WriteMessage(ctx, message); !errors.Is(err, context.Canceled) {
+			t.Errorf("WriteMessage() = %v, want %v", err, context.Canceled)
+		}
+	})
+}
+
+func TestNewConnPipe(t *testing.T) {
+	clientMux := NewMux()
+	err := HandleFunc(clientMux, "whoami", func(ctx context.Context, params any) (string, error) {
+		return "client", nil
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	server, client := NewConnPipe(newTestMux(t), clientMux)
+	defer server.Close()
+
+	result, err := Call[int](context.Background(), client.Client(), "subtract", []int{42, 23})
+	if err != nil || result != 19 {
+		t.Errorf("Call() = %v, %v, want 19", result, err)
+	}
+	name, err := Call[string](context.Background(), server.Client(), "whoami", nil)
+	if err != nil || name != "client" {
+		t.Errorf("Call() = %v, %v, want client", name, err)
+	}
+
+	client.Close()
+	<-server.Done()
+	if _, err := Call[int](context.Background(), client.Client(), "subtract", []int{42, 23}); !errors.Is(err, ErrTransportClosed) {
+		t.Errorf("Call() = %v, want %v", err, ErrTransportClosed)
+	}
+}
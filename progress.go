@@ -0,0 +1,119 @@
+/*  Copyright 2022  Kosmas Valianos (kosmas.valianos@gmail.com)
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    https://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License. */
+
+package gojsonrpc
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+)
+
+// ProgressMethod is the method of the progress notifications, as in the Language Server Protocol
+const ProgressMethod = "$/progress"
+
+// progressBuffer is the capacity of the channel of the progress of a call
+const progressBuffer = 16
+
+// ProgressParams are the params of a progress notification. The token is the id of the request in progress
+type ProgressParams struct {
+	Token any `json:"token"`
+	Value any `json:"value"`
+}
+
+// ProgressNotification is a progress report of a call made with Conn.GoWithProgress
+type ProgressNotification struct {
+	Token any
+	Value json.RawMessage
+	conn  *Conn
+}
+
+// Unmarshal unmarshals the value of the progress report into value
+func (n ProgressNotification) Unmarshal(value any) error {
+	return n.conn.client.unmarshalResult(n.Value, value)
+}
+
+// Progress reports the progress of the request being served to the remote peer with a notification of ProgressMethod
+// whose token is the id of the request, so that the value reaches the channel of the call made with
+// Conn.GoWithProgress. It is meant for long-running handlers.
+// Returns an error if the request is not served within a Conn, is a notification or the report could not be sent
+func Progress(ctx context.Context, value any) error {
+	conn, ok := ConnFromContext(ctx)
+	if !ok {
+		return errors.New("progress can only be reported within a Conn")
+	}
+	id := IDFromContext(ctx)
+	if id == nil {
+		return errors.New("progress can only be reported for a request")
+	}
+	return conn.Notify(ctx, ProgressMethod, ProgressParams{Token: id, Value: value})
+}
+
+// GoWithProgress calls a method of the remote peer asynchronously as Client.Go does, the progress reports of the
+// remote handler being received on the returned channel, which is closed once the call is complete.
+// Reports are dropped while the channel is full, so that the response is not held up.
+// Returns an *AsyncCall object and the channel of the progress reports
+func (c *Conn) GoWithProgress(ctx context.Context, method string, params any) (*AsyncCall, <-chan ProgressNotification) {
+	id := c.client.idGenerator.NextID()
+	key := idKey(id)
+	progress := make(chan ProgressNotification, progressBuffer)
+	c.progressMu.Lock()
+	if c.progress == nil {
+		c.progress = make(map[string]chan ProgressNotification)
+	}
+	c.progress[key] = progress
+	c.progressMu.Unlock()
+
+	call := c.client.goCall(ctx, method, params, id, func() {
+		c.progressMu.Lock()
+		delete(c.progress, key)
+		close(progress)
+		c.progressMu.Unlock()
+	})
+	return call, progress
+}
+
+// deliverProgress passes a progress notification to the channel of its call.
+// Returns whether the message was such a notification
+func (c *Conn) deliverProgress(notificationRaw []byte) bool {
+	c.progressMu.Lock()
+	expecting := len(c.progress) > 0
+	c.progressMu.Unlock()
+	if !expecting {
+		return false
+	}
+
+	var notification struct {
+		Method string `json:"method"`
+		Params struct {
+			Token any             `json:"token"`
+			Value json.RawMessage `json:"value"`
+		} `json:"params"`
+	}
+	if err := json.Unmarshal(notificationRaw, &notification); err != nil || notification.Method != ProgressMethod {
+		return false
+	}
+
+	c.progressMu.Lock()
+	defer c.progressMu.Unlock()
+	progress, ok := c.progress[idKey(notification.Params.Token)]
+	if !ok {
+		return false
+	}
+	select {
+	case progress <- ProgressNotification{Token: notification.Params.Token, Value: notification.Params.Value, conn: c}:
+	default:
+	}
+	return true
+}
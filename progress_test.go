@@ -0,0 +1,78 @@
+/*  Copyright 2022  Kosmas Valianos (kosmas.valianos@gmail.com)
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    https://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License. */
+
+package gojsonrpc
+
+import (
+	"context"
+	"encoding/json"
+	"testing"
+)
+
+func TestConn_GoWithProgress(t *testing.T) {
+	mux := NewMux()
+	err := HandleFunc(mux, "count", func(ctx context.Context, params [1]int) (string, error) {
+		for i := 1; i <= params[0]; i++ {
+			if err := Progress(ctx, map[string]int{"done": i}); err != nil {
+				return "", err
+			}
+		}
+		return "counted", nil
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	server, client := NewConnPipe(mux, nil)
+	defer server.Close()
+	defer client.Close()
+
+	call, progress := client.GoWithProgress(context.Background(), "count", []int{3})
+	var got []int
+	for notification := range progress {
+		var value struct {
+			Done int `json:"done"`
+		}
+		if err := notification.Unmarshal(&value); err != nil {
+			t.Fatal(err)
+		}
+		if idKey(notification.Token) != idKey(call.ID) {
+			t.Errorf("progress token = %v, want %v", notification.Token, call.ID)
+		}
+		got = append(got, value.Done)
+	}
+	<-call.Done
+	var result string
+	if err := call.Unmarshal(&result); err != nil || result != "counted" {
+		t.Errorf("call = %v, %v, want counted", result, err)
+	}
+	if len(got) != 3 || got[0] != 1 || got[2] != 3 {
+		t.Errorf("progress = %v, want [1 2 3]", got)
+	}
+}
+
+func TestProgress_outsideConn(t *testing.T) {
+	var progressErr error
+	mux := NewMux()
+	err := mux.Handle("work", HandlerFunc(func(ctx context.Context, params json.RawMessage) (any, error) {
+		progressErr = Progress(ctx, 50)
+		return nil, nil
+	}))
+	if err != nil {
+		t.Fatal(err)
+	}
+	mux.Serve(context.Background(), []byte(`{"jsonrpc": "2.0", "method": "work", "id": 1}`))
+	if progressErr == nil {
+		t.Error("Progress() error = nil outside a Conn, want an error")
+	}
+}
@@ -0,0 +1,246 @@
+/*  Copyright 2022  Kosmas Valianos (kosmas.valianos@gmail.com)
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    https://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License. */
+
+package gojsonrpc
+
+import (
+	"bufio"
+	"fmt"
+	"math"
+	"net/http"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+)
+
+// Default buckets of the histograms of PrometheusMetrics
+var (
+	DefaultDurationBuckets    = []float64{0.005, 0.01, 0.025, 0.05, 0.1, 0.25, 0.5, 1, 2.5, 5, 10}
+	DefaultBatchSizeBuckets   = []float64{1, 2, 5, 10, 20, 50, 100}
+	DefaultMessageSizeBuckets = []float64{64, 256, 1024, 4096, 16384, 65536, 262144, 1048576}
+)
+
+// PrometheusMetrics is the Metrics exposing the measures in the text format of Prometheus, without depending on its
+// client library, as an http.Handler to be scraped, e.g. on "/metrics":
+//
+//	<namespace>_calls_total{role,method,code}                  counter of the calls answered by method and error code
+//	<namespace>_call_duration_seconds{role,method}             histogram of the durations of the calls
+//	<namespace>_calls_in_flight{role,method}                   gauge of the calls being served or waiting for a response
+//	<namespace>_batch_size{role}                               histogram of the number of the elements of the batches
+//	<namespace>_message_size_bytes{role,direction}             histogram of the sizes of the messages
+//
+// The code label is "0" for the results and the notifications. It is safe for concurrent use
+type PrometheusMetrics struct {
+	namespace          string
+	durationBuckets    []float64
+	batchSizeBuckets   []float64
+	messageSizeBuckets []float64
+
+	mu           sync.Mutex
+	calls        map[string]float64
+	inFlight     map[string]float64
+	durations    map[string]*histogram
+	batchSizes   map[string]*histogram
+	messageSizes map[string]*histogram
+}
+
+// PrometheusOption configures a PrometheusMetrics
+type PrometheusOption func(*PrometheusMetrics)
+
+// WithPrometheusNamespace sets the prefix of the names of the metrics, "jsonrpc" by default
+func WithPrometheusNamespace(namespace string) PrometheusOption {
+	return func(p *PrometheusMetrics) {
+		if namespace != "" {
+			p.namespace = namespace
+		}
+	}
+}
+
+// WithDurationBuckets sets the upper bounds in seconds of the buckets of the durations, DefaultDurationBuckets by default
+func WithDurationBuckets(buckets ...float64) PrometheusOption {
+	return func(p *PrometheusMetrics) {
+		if len(buckets) > 0 {
+			p.durationBuckets = sortedBuckets(buckets)
+		}
+	}
+}
+
+// WithBatchSizeBuckets sets the upper bounds of the buckets of the batch sizes, DefaultBatchSizeBuckets by default
+func WithBatchSizeBuckets(buckets ...float64) PrometheusOption {
+	return func(p *PrometheusMetrics) {
+		if len(buckets) > 0 {
+			p.batchSizeBuckets = sortedBuckets(buckets)
+		}
+	}
+}
+
+// WithMessageSizeBuckets sets the upper bounds in bytes of the buckets of the message sizes,
+// DefaultMessageSizeBuckets by default
+func WithMessageSizeBuckets(buckets ...float64) PrometheusOption {
+	return func(p *PrometheusMetrics) {
+		if len(buckets) > 0 {
+			p.messageSizeBuckets = sortedBuckets(buckets)
+		}
+	}
+}
+
+func sortedBuckets(buckets []float64) []float64 {
+	sorted := append([]float64(nil), buckets...)
+	sort.Float64s(sorted)
+	return sorted
+}
+
+// NewPrometheusMetrics creates a PrometheusMetrics configured by the options.
+// Returns a *PrometheusMetrics object
+func NewPrometheusMetrics(options ...PrometheusOption) *PrometheusMetrics {
+	metrics := &PrometheusMetrics{
+		namespace:          "jsonrpc",
+		durationBuckets:    DefaultDurationBuckets,
+		batchSizeBuckets:   DefaultBatchSizeBuckets,
+		messageSizeBuckets: DefaultMessageSizeBuckets,
+		calls:              make(map[string]float64),
+		inFlight:           make(map[string]float64),
+		durations:          make(map[string]*histogram),
+		batchSizes:         make(map[string]*histogram),
+		messageSizes:       make(map[string]*histogram),
+	}
+	for _, option := range options {
+		option(metrics)
+	}
+	return metrics
+}
+
+// CallStarted implements Metrics by incrementing the calls in flight
+func (p *PrometheusMetrics) CallStarted(role Role, method string) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	p.inFlight[labels("role", role.String(), "method", method)]++
+}
+
+// CallFinished implements Metrics by counting the call, observing its duration and decrementing the calls in flight
+func (p *PrometheusMetrics) CallFinished(role Role, method string, code int, duration time.Duration) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	key := labels("role", role.String(), "method", method)
+	p.inFlight[key]--
+	p.calls[labels("role", role.String(), "method", method, "code", strconv.Itoa(code))]++
+	observe(p.durations, key, p.durationBuckets, duration.Seconds())
+}
+
+// BatchSize implements Metrics by observing the size of the batch
+func (p *PrometheusMetrics) BatchSize(role Role, size int) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	observe(p.batchSizes, labels("role", role.String()), p.batchSizeBuckets, float64(size))
+}
+
+// MessageSize implements Metrics by observing the size of the message
+func (p *PrometheusMetrics) MessageSize(role Role, direction MessageDirection, size int) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	observe(p.messageSizes, labels("role", role.String(), "direction", direction.String()), p.messageSizeBuckets, float64(size))
+}
+
+// ServeHTTP writes the metrics in the text format of Prometheus
+func (p *PrometheusMetrics) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	w.Header().Set("Content-Type", "text/plain; version=0.0.4; charset=utf-8")
+	buffered := bufio.NewWriter(w)
+	defer buffered.Flush()
+
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	writeSamples(buffered, p.namespace+"_calls_total", "counter", "Calls answered by method and error code.", p.calls)
+	writeHistograms(buffered, p.namespace+"_call_duration_seconds", "Durations of the calls in seconds.", p.durations)
+	writeSamples(buffered, p.namespace+"_calls_in_flight", "gauge", "Calls being served or waiting for a response.", p.inFlight)
+	writeHistograms(buffered, p.namespace+"_batch_size", "Number of the elements of the batches.", p.batchSizes)
+	writeHistograms(buffered, p.namespace+"_message_size_bytes", "Sizes of the messages in bytes.", p.messageSizes)
+}
+
+// histogram is a cumulative histogram of Prometheus
+type histogram struct {
+	buckets []float64
+	counts  []uint64
+	count   uint64
+	sum     float64
+}
+
+func observe(histograms map[string]*histogram, key string, buckets []float64, value float64) {
+	h, ok := histograms[key]
+	if !ok {
+		h = &histogram{buckets: buckets, counts: make([]uint64, len(buckets))}
+		histograms[key] = h
+	}
+	for i, bound := range h.buckets {
+		if value <= bound {
+			h.counts[i]++
+		}
+	}
+	h.count++
+	h.sum += value
+}
+
+// labels formats the label pairs of a sample, escaping their values
+func labels(pairs ...string) string {
+	var formatted strings.Builder
+	for i := 0; i+1 < len(pairs); i += 2 {
+		if i > 0 {
+			formatted.WriteByte(',')
+		}
+		formatted.WriteString(pairs[i])
+		formatted.WriteString(`="`)
+		formatted.WriteString(labelValueEscaper.Replace(pairs[i+1]))
+		formatted.WriteByte('"')
+	}
+	return formatted.String()
+}
+
+var labelValueEscaper = strings.NewReplacer(`\`, `\\`, `"`, `\"`, "\n", `\n`)
+
+func writeSamples(w *bufio.Writer, name, metricType, help string, samples map[string]float64) {
+	fmt.Fprintf(w, "# HELP %v %v\n# TYPE %v %v\n", name, help, name, metricType)
+	for _, key := range sortedKeys(samples) {
+		fmt.Fprintf(w, "%v{%v} %v\n", name, key, formatSample(samples[key]))
+	}
+}
+
+func writeHistograms(w *bufio.Writer, name, help string, histograms map[string]*histogram) {
+	fmt.Fprintf(w, "# HELP %v %v\n# TYPE %v histogram\n", name, help, name)
+	for _, key := range sortedKeys(histograms) {
+		h := histograms[key]
+		for i, bound := range h.buckets {
+			fmt.Fprintf(w, "%v_bucket{%v,le=\"%v\"} %v\n", name, key, formatSample(bound), h.counts[i])
+		}
+		fmt.Fprintf(w, "%v_bucket{%v,le=\"+Inf\"} %v\n", name, key, h.count)
+		fmt.Fprintf(w, "%v_sum{%v} %v\n", name, key, formatSample(h.sum))
+		fmt.Fprintf(w, "%v_count{%v} %v\n", name, key, h.count)
+	}
+}
+
+func sortedKeys[V any](samples map[string]V) []string {
+	keys := make([]string, 0, len(samples))
+	for key := range samples {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+	return keys
+}
+
+func formatSample(value float64) string {
+	if math.IsInf(value, 1) {
+		return "+Inf"
+	}
+	return strconv.FormatFloat(value, 'g', -1, 64)
+}
@@ -0,0 +1,94 @@
+/*  Copyright 2022  Kosmas Valianos (kosmas.valianos@gmail.com)
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    https://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License. */
+
+package gojsonrpc
+
+import (
+	"context"
+	"io"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestPrometheusMetrics(t *testing.T) {
+	metrics := NewPrometheusMetrics(WithPrometheusNamespace("rpc"), WithDurationBuckets(1, 0.1), WithBatchSizeBuckets(2), WithMessageSizeBuckets(100))
+	metrics.CallStarted(RoleServer, "subtract")
+	metrics.CallFinished(RoleServer, "subtract", 0, 50*time.Millisecond)
+	metrics.CallStarted(RoleServer, "subtract")
+	metrics.CallFinished(RoleServer, "subtract", InvalidMethodParameters, 500*time.Millisecond)
+	metrics.CallStarted(RoleClient, `say "hi"`)
+	metrics.BatchSize(RoleServer, 3)
+	metrics.MessageSize(RoleServer, MessageReceived, 62)
+
+	recorder := httptest.NewRecorder()
+	metrics.ServeHTTP(recorder, httptest.NewRequest("GET", "/metrics", nil))
+	if got, want := recorder.Header().Get("Content-Type"), "text/plain; version=0.0.4; charset=utf-8"; got != want {
+		t.Errorf("ServeHTTP() Content-Type = %v, want %v", got, want)
+	}
+	body, _ := io.ReadAll(recorder.Body)
+	want := `# HELP rpc_calls_total Calls answered by method and error code.
+# TYPE rpc_calls_total counter
+rpc_calls_total{role="server",method="subtract",code="-32602"} 1
+rpc_calls_total{role="server",method="subtract",code="0"} 1
+# HELP rpc_call_duration_seconds Durations of the calls in seconds.
+# TYPE rpc_call_duration_seconds histogram
+rpc_call_duration_seconds_bucket{role="server",method="subtract",le="0.1"} 1
+rpc_call_duration_seconds_bucket{role="server",method="subtract",le="1"} 2
+rpc_call_duration_seconds_bucket{role="server",method="subtract",le="+Inf"} 2
+rpc_call_duration_seconds_sum{role="server",method="subtract"} 0.55
+rpc_call_duration_seconds_count{role="server",method="subtract"} 2
+# HELP rpc_calls_in_flight Calls being served or waiting for a response.
+# TYPE rpc_calls_in_flight gauge
+rpc_calls_in_flight{role="client",method="say \"hi\""} 1
+rpc_calls_in_flight{role="server",method="subtract"} 0
+# HELP rpc_batch_size Number of the elements of the batches.
+# TYPE rpc_batch_size histogram
+rpc_batch_size_bucket{role="server",le="2"} 0
+rpc_batch_size_bucket{role="server",le="+Inf"} 1
+rpc_batch_size_sum{role="server"} 3
+rpc_batch_size_count{role="server"} 1
+# HELP rpc_message_size_bytes Sizes of the messages in bytes.
+# TYPE rpc_message_size_bytes histogram
+rpc_message_size_bytes_bucket{role="server",direction="received",le="100"} 1
+rpc_message_size_bytes_bucket{role="server",direction="received",le="+Inf"} 1
+rpc_message_size_bytes_sum{role="server",direction="received"} 62
+rpc_message_size_bytes_count{role="server",direction="received"} 1
+`
+	if string(body) != want {
+		t.Errorf("ServeHTTP() body = %v, want %v", string(body), want)
+	}
+}
+
+func TestPrometheusMetrics_Mux(t *testing.T) {
+	metrics := NewPrometheusMetrics()
+	mux := newTestMux(t)
+	WithMetrics(metrics)(mux)
+	mux.Serve(context.Background(), []byte(`{"jsonrpc":"2.0","method":"subtract","params":[42,23],"id":1}`))
+
+	recorder := httptest.NewRecorder()
+	metrics.ServeHTTP(recorder, httptest.NewRequest("GET", "/metrics", nil))
+	body := recorder.Body.String()
+	for _, sample := range []string{
+		`jsonrpc_calls_total{role="server",method="subtract",code="0"} 1`,
+		`jsonrpc_calls_in_flight{role="server",method="subtract"} 0`,
+		`jsonrpc_call_duration_seconds_count{role="server",method="subtract"} 1`,
+		`jsonrpc_message_size_bytes_count{role="server",direction="sent"} 1`,
+	} {
+		if !strings.Contains(body, sample) {
+			t.Errorf("ServeHTTP() body has no %v", sample)
+		}
+	}
+}
@@ -0,0 +1,136 @@
+/*  Copyright 2022  Kosmas Valianos (kosmas.valianos@gmail.com)
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    https://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License. */
+
+package gojsonrpc
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"strings"
+)
+
+// Proxy is a Handler forwarding the requests and the notifications to upstream JSON-RPC servers chosen by routing rules
+// and relaying their responses, e.g. registered with Mux.HandleUnknown for a gateway. It is safe for concurrent use
+type Proxy struct {
+	routes      []proxyRoute
+	idGenerator IDGenerator
+}
+
+// proxyRoute forwards the messages whose method matches to the upstream
+type proxyRoute struct {
+	match    func(ctx context.Context, method string) bool
+	upstream Transport
+}
+
+// ProxyOption configures a Proxy
+type ProxyOption func(*Proxy)
+
+// WithProxyRoute forwards the methods with the prefix, e.g. "billing.", to the upstream. The method is forwarded as is
+func WithProxyRoute(prefix string, upstream Transport) ProxyOption {
+	return WithProxyRouteFunc(func(ctx context.Context, method string) bool {
+		return strings.HasPrefix(method, prefix)
+	}, upstream)
+}
+
+// WithProxyRouteFunc forwards the methods reported by the predicate to the upstream. The predicate gets the context
+// of the request, e.g. to route by ConnFromContext or by the values set by a middleware
+func WithProxyRouteFunc(match func(ctx context.Context, method string) bool, upstream Transport) ProxyOption {
+	return func(p *Proxy) {
+		if match != nil && upstream != nil {
+			p.routes = append(p.routes, proxyRoute{match: match, upstream: upstream})
+		}
+	}
+}
+
+// WithProxyIDGenerator forwards the requests with the IDs of the generator instead of their own ones, e.g. when the
+// requests of several clients, whose IDs may clash, share an upstream connection. The responses keep their own IDs
+func WithProxyIDGenerator(generator IDGenerator) ProxyOption {
+	return func(p *Proxy) {
+		p.idGenerator = generator
+	}
+}
+
+// NewProxy creates a Proxy routing the methods by the options, trying the routes in the order they are given.
+// Returns a *Proxy object
+func NewProxy(options ...ProxyOption) *Proxy {
+	proxy := &Proxy{}
+	for _, option := range options {
+		option(proxy)
+	}
+	return proxy
+}
+
+// ServeJSONRPC implements Handler by forwarding the request or the notification being served to the upstream of the
+// first matching route, with its own ID unless rewritten by WithProxyIDGenerator.
+// Returns the raw result or the *jsonRPCError object of the upstream response, JsonMethodNotFound if no route matches,
+// JsonRequestTimeout if the context expired or JsonUpstreamUnavailable, with the transport error as cause, otherwise
+func (p *Proxy) ServeJSONRPC(ctx context.Context, params json.RawMessage) (any, error) {
+	method := MethodFromContext(ctx)
+	upstream, ok := p.route(ctx, method)
+	if !ok {
+		return nil, &JsonMethodNotFound
+	}
+	var paramsValue any
+	if params != nil {
+		paramsValue = params
+	}
+
+	id := IDFromContext(ctx)
+	if id == nil {
+		notificationRaw, err := NewNotification(method, paramsValue)
+		if err != nil {
+			return nil, err
+		}
+		return nil, upstream.Send(ctx, notificationRaw)
+	}
+
+	if p.idGenerator != nil {
+		id = p.idGenerator.NextID()
+	}
+	requestRaw, err := newRequestWithID(method, paramsValue, id)
+	if err != nil {
+		return nil, err
+	}
+	responseRaw, err := upstream.RoundTrip(ctx, requestRaw)
+	if err != nil {
+		return nil, upstreamError(ctx, err)
+	}
+	response, err := ParseResponseZeroCopy(responseRaw)
+	if err != nil {
+		return nil, JsonUpstreamUnavailable.WithCause(err)
+	}
+	if response.IsError() {
+		return nil, response.Err()
+	}
+	return response.RawResult(), nil
+}
+
+// route finds the upstream of the first route matching the method
+func (p *Proxy) route(ctx context.Context, method string) (Transport, bool) {
+	for _, route := range p.routes {
+		if route.match(ctx, method) {
+			return route.upstream, true
+		}
+	}
+	return nil, false
+}
+
+// upstreamError translates the error of an upstream transport into the error object of the response
+func upstreamError(ctx context.Context, err error) *jsonRPCError {
+	if errors.Is(err, ErrCallTimeout) || errors.Is(err, context.DeadlineExceeded) || errors.Is(ctx.Err(), context.DeadlineExceeded) {
+		return JsonRequestTimeout.WithCause(err)
+	}
+	return JsonUpstreamUnavailable.WithCause(err)
+}
@@ -0,0 +1,143 @@
+/*  Copyright 2022  Kosmas Valianos (kosmas.valianos@gmail.com)
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    https://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License. */
+
+package gojsonrpc
+
+import (
+	"context"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestProxy(t *testing.T) {
+	billing := NewMux()
+	err := HandleFunc(billing, "billing.echoID", func(ctx context.Context, params any) (any, error) {
+		return IDFromContext(ctx), nil
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	notifications := make(chan []byte, 1)
+	proxy := NewProxy(
+		WithProxyRoute("billing.", &muxTransport{mux: billing}),
+		WithProxyRoute("down.", &downTransport{}),
+		WithProxyRoute("invalid.", staticTransport(`{"jsonrpc": "2.0", "id": 1}`)),
+		WithProxyRouteFunc(func(ctx context.Context, method string) bool {
+			return method == "slow"
+		}, timeoutTransport{}),
+		WithProxyRouteFunc(func(ctx context.Context, method string) bool {
+			return !strings.Contains(method, ".")
+		}, &muxTransport{mux: newTestMux(t), notifications: notifications}),
+	)
+	mux := NewMux()
+	mux.HandleUnknown(proxy)
+
+	tests := []struct {
+		name     string
+		request  string
+		expected string
+	}{
+		{
+			name:     "Forwarded request",
+			request:  `{"jsonrpc": "2.0", "method": "subtract", "params": [42, 23], "id": 1}`,
+			expected: `{"jsonrpc":"2.0","result":19,"id":1}`,
+		},
+		{
+			name:     "Preserved ID",
+			request:  `{"jsonrpc": "2.0", "method": "billing.echoID", "id": "abc"}`,
+			expected: `{"jsonrpc":"2.0","result":"abc","id":"abc"}`,
+		},
+		{
+			name:     "Relayed error response",
+			request:  `{"jsonrpc": "2.0", "method": "database", "id": 2}`,
+			expected: `{"jsonrpc":"2.0","error":{"code":-32602,"message":"Invalid method parameters"},"id":2}`,
+		},
+		{
+			name:     "No route",
+			request:  `{"jsonrpc": "2.0", "method": "unknown.method", "id": 3}`,
+			expected: `{"jsonrpc":"2.0","error":{"code":-32601,"message":"Method not found"},"id":3}`,
+		},
+		{
+			name:     "Upstream down",
+			request:  `{"jsonrpc": "2.0", "method": "down.method", "id": 4}`,
+			expected: `{"jsonrpc":"2.0","error":{"code":-32004,"message":"Upstream unavailable"},"id":4}`,
+		},
+		{
+			name:     "Invalid upstream response",
+			request:  `{"jsonrpc": "2.0", "method": "invalid.method", "id": 5}`,
+			expected: `{"jsonrpc":"2.0","error":{"code":-32004,"message":"Upstream unavailable"},"id":5}`,
+		},
+		{
+			name:     "Upstream timeout",
+			request:  `{"jsonrpc": "2.0", "method": "slow", "id": 6}`,
+			expected: `{"jsonrpc":"2.0","error":{"code":-32000,"message":"Request timeout"},"id":6}`,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			responseRaw := mux.Serve(context.Background(), []byte(tt.request))
+			if got := strings.TrimSpace(string(responseRaw)); got != tt.expected {
+				t.Errorf("Serve() = %v, want %v", got, tt.expected)
+			}
+		})
+	}
+
+	t.Run("Forwarded notification", func(t *testing.T) {
+		responseRaw := mux.Serve(context.Background(), []byte(`{"jsonrpc": "2.0", "method": "update", "params": [1]}`))
+		if responseRaw != nil {
+			t.Errorf("Serve() = %s, want nil", responseRaw)
+		}
+		select {
+		case notificationRaw := <-notifications:
+			if got, want := strings.TrimSpace(string(notificationRaw)), `{"jsonrpc":"2.0","method":"update","params":[1]}`; got != want {
+				t.Errorf("forwarded notification = %v, want %v", got, want)
+			}
+		case <-time.After(time.Second):
+			t.Error("notification not forwarded")
+		}
+	})
+}
+
+func TestProxy_WithProxyIDGenerator(t *testing.T) {
+	upstream := NewMux()
+	err := HandleFunc(upstream, "echoID", func(ctx context.Context, params any) (any, error) {
+		return IDFromContext(ctx), nil
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	proxy := NewProxy(
+		WithProxyRoute("", &muxTransport{mux: upstream}),
+		WithProxyIDGenerator(PrefixedIDGenerator{Prefix: "proxy-", Generator: &IncrementingIDGenerator{}}),
+	)
+	mux := NewMux()
+	mux.HandleUnknown(proxy)
+
+	responseRaw := mux.Serve(context.Background(), []byte(`{"jsonrpc": "2.0", "method": "echoID", "id": 7}`))
+	if got, want := strings.TrimSpace(string(responseRaw)), `{"jsonrpc":"2.0","result":"proxy-1","id":7}`; got != want {
+		t.Errorf("Serve() = %v, want %v", got, want)
+	}
+}
+
+// timeoutTransport is a Transport whose round trips time out
+type timeoutTransport struct{}
+
+func (timeoutTransport) RoundTrip(ctx context.Context, requestRaw []byte) ([]byte, error) {
+	return nil, ErrCallTimeout
+}
+
+func (timeoutTransport) Send(ctx context.Context, notificationRaw []byte) error {
+	return ErrCallTimeout
+}
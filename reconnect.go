@@ -0,0 +1,273 @@
+/*  Copyright 2022  Kosmas Valianos (kosmas.valianos@gmail.com)
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    https://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License. */
+
+package gojsonrpc
+
+import (
+	"context"
+	"errors"
+	"sync"
+	"time"
+)
+
+// ErrConnectionLost is returned by the calls pending when the connection of a ReconnectingTransport is lost without replay
+var ErrConnectionLost = errors.New("connection lost")
+
+const defaultMaxReconnectBackoff = 30 * time.Second
+
+// MessageConn is a persistent connection carrying whole messages, e.g. a socket with a framing
+type MessageConn interface {
+	// WriteMessage writes a message. It is not called concurrently
+	WriteMessage(ctx context.Context, messageRaw []byte) error
+	// ReadMessage reads the next message, failing once the connection is lost or closed. The caller keeps the message,
+	// which must thus not be reused for the next one
+	ReadMessage() ([]byte, error)
+	Close() error
+}
+
+// ConnState is the state of the connection of a ReconnectingTransport
+type ConnState int
+
+const (
+	// Connecting is the state while dialing
+	Connecting ConnState = iota
+	// Connected is the state while the connection is up
+	Connected
+	// Disconnected is the state once the connection is lost, until the next dial
+	Disconnected
+	// Closed is the final state once the transport is closed or gave up dialing
+	Closed
+)
+
+var connStateNames = map[ConnState]string{
+	Connecting:   "connecting",
+	Connected:    "connected",
+	Disconnected: "disconnected",
+	Closed:       "closed",
+}
+
+// String returns the name of the state
+func (s ConnState) String() string {
+	if name, ok := connStateNames[s]; ok {
+		return name
+	}
+	return "unknown"
+}
+
+// ReconnectingTransport is a Transport over a persistent connection which redials it with a backoff when it is lost.
+// The calls pending when the connection is lost fail with ErrConnectionLost unless they are replayed once reconnected
+type ReconnectingTransport struct {
+	dial     func(ctx context.Context) (MessageConn, error)
+	backoff  RetryPolicy
+	onState  func(state ConnState)
+	replay   bool
+	pending  *pendingCalls
+	ctx      context.Context
+	cancel   context.CancelFunc
+	finished chan struct{}
+
+	// mu guards the connection and serializes the writes
+	mu        sync.Mutex
+	conn      MessageConn
+	connected chan struct{}
+}
+
+// ReconnectOption configures a ReconnectingTransport
+type ReconnectOption func(*ReconnectingTransport)
+
+// WithReconnectBackoff sets the backoff between the dials. MaxAttempts caps the consecutive failed dials after which
+// the transport gives up and closes, zero meaning never. The default backoff starts at 100ms and doubles up to 30s
+func WithReconnectBackoff(policy RetryPolicy) ReconnectOption {
+	return func(t *ReconnectingTransport) {
+		t.backoff = policy
+	}
+}
+
+// WithConnStateHandler sets a function called on every change of the state of the connection
+func WithConnStateHandler(onState func(state ConnState)) ReconnectOption {
+	return func(t *ReconnectingTransport) {
+		t.onState = onState
+	}
+}
+
+// WithReplay makes the calls pending when the connection is lost wait for the reconnection and be sent again.
+// Only enable it if the methods are safe to call more than once
+func WithReplay() ReconnectOption {
+	return func(t *ReconnectingTransport) {
+		t.replay = true
+	}
+}
+
+// NewReconnectingTransport creates a ReconnectingTransport dialing the connections with the dial function,
+// configured by the options, and starts connecting in the background.
+// Returns a *ReconnectingTransport object
+func NewReconnectingTransport(dial func(ctx context.Context) (MessageConn, error), options ...ReconnectOption) *ReconnectingTransport {
+	ctx, cancel := context.WithCancel(context.Background())
+	transport := &ReconnectingTransport{
+		dial:      dial,
+		backoff:   RetryPolicy{MaxBackoff: defaultMaxReconnectBackoff},
+		pending:   newPendingCalls(),
+		ctx:       ctx,
+		cancel:    cancel,
+		finished:  make(chan struct{}),
+		connected: make(chan struct{}),
+	}
+	for _, option := range options {
+		option(transport)
+	}
+	go transport.run()
+	return transport
+}
+
+// RoundTrip sends a request or a batch once connected and waits for its response until ctx is done.
+// Returns the raw bytes of the response or an error
+func (t *ReconnectingTransport) RoundTrip(ctx context.Context, requestRaw []byte) ([]byte, error) {
+	if t.replay {
+		// Registered and written under mu so that connect either sees the call and replays it or lets it be written
+		// here, never both. Written now if connected, otherwise replayed once connected
+		t.mu.Lock()
+		call, err := t.pending.add(requestRaw)
+		if err == nil && t.conn != nil {
+			_ = t.conn.WriteMessage(ctx, requestRaw)
+		}
+		t.mu.Unlock()
+		if err != nil {
+			return nil, err
+		}
+		return t.pending.wait(ctx, call)
+	}
+
+	call, err := t.pending.add(requestRaw)
+	if err != nil {
+		return nil, err
+	}
+	err = t.write(ctx, requestRaw)
+	if err != nil {
+		t.pending.remove(call)
+		return nil, err
+	}
+	return t.pending.wait(ctx, call)
+}
+
+// Send sends a notification once connected
+func (t *ReconnectingTransport) Send(ctx context.Context, notificationRaw []byte) error {
+	return t.write(ctx, notificationRaw)
+}
+
+// Close closes the connection, stops reconnecting and fails the outstanding and the future calls with ErrTransportClosed
+func (t *ReconnectingTransport) Close() error {
+	t.cancel()
+	<-t.finished
+	return nil
+}
+
+// write writes a message once connected
+func (t *ReconnectingTransport) write(ctx context.Context, messageRaw []byte) error {
+	for {
+		t.mu.Lock()
+		conn, connected := t.conn, t.connected
+		if conn != nil {
+			err := conn.WriteMessage(ctx, messageRaw)
+			t.mu.Unlock()
+			return err
+		}
+		t.mu.Unlock()
+
+		select {
+		case <-connected:
+		case <-ctx.Done():
+			return ctx.Err()
+		case <-t.ctx.Done():
+			return ErrTransportClosed
+		}
+	}
+}
+
+// run dials the connection, delivers the responses read on it and redials it when it is lost until the transport is closed
+func (t *ReconnectingTransport) run() {
+	defer close(t.finished)
+	defer t.pending.close(ErrTransportClosed)
+	defer t.setState(Closed)
+
+	failures := 0
+	for {
+		t.setState(Connecting)
+		conn, err := t.dial(t.ctx)
+		if err != nil {
+			failures++
+			if t.ctx.Err() != nil || (t.backoff.MaxAttempts > 0 && failures >= t.backoff.MaxAttempts) ||
+				!sleep(t.ctx, t.backoff.backoff(failures)) {
+				return
+			}
+			continue
+		}
+		failures = 0
+
+		t.connect(conn)
+		t.setState(Connected)
+		lost := make(chan struct{})
+		go func() {
+			// Unblocks ReadMessage when the transport is closed
+			select {
+			case <-t.ctx.Done():
+				conn.Close()
+			case <-lost:
+			}
+		}()
+		for {
+			messageRaw, err := conn.ReadMessage()
+			if err != nil {
+				break
+			}
+			_ = t.pending.deliver(messageRaw)
+		}
+		close(lost)
+		t.disconnect(conn)
+		if t.ctx.Err() != nil {
+			return
+		}
+		t.setState(Disconnected)
+	}
+}
+
+// connect makes the connection the current one, replaying the pending calls on it if enabled
+func (t *ReconnectingTransport) connect(conn MessageConn) {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	t.conn = conn
+	close(t.connected)
+	if t.replay {
+		for _, requestRaw := range t.pending.requests() {
+			_ = conn.WriteMessage(t.ctx, requestRaw)
+		}
+	}
+}
+
+// disconnect forgets the lost connection, failing the pending calls unless they are replayed
+func (t *ReconnectingTransport) disconnect(conn MessageConn) {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	conn.Close()
+	t.conn = nil
+	t.connected = make(chan struct{})
+	if !t.replay {
+		t.pending.fail(ErrConnectionLost)
+	}
+}
+
+func (t *ReconnectingTransport) setState(state ConnState) {
+	if t.onState != nil {
+		t.onState(state)
+	}
+}
@@ -0,0 +1,288 @@
+/*  Copyright 2022  Kosmas Valianos (kosmas.valianos@gmail.com)
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    https://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License. */
+
+package gojsonrpc
+
+import (
+	"context"
+	"errors"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+)
+
+// memConn is an in-memory MessageConn served by a mux, like a connection to a server
+type memConn struct {
+	mux      *Mux
+	inbox    chan []byte
+	lost     chan struct{}
+	lostOnce sync.Once
+	writes   atomic.Int64
+	delay    time.Duration
+}
+
+func newMemConn(mux *Mux) *memConn {
+	return &memConn{mux: mux, inbox: make(chan []byte, 64), lost: make(chan struct{})}
+}
+
+func (c *memConn) WriteMessage(ctx context.Context, messageRaw []byte) error {
+	time.Sleep(c.delay)
+	select {
+	case <-c.lost:
+		return errors.New("write on lost connection")
+	default:
+	}
+	c.writes.Add(1)
+	go func() {
+		responseRaw := c.mux.Serve(context.Background(), messageRaw)
+		if responseRaw == nil {
+			return
+		}
+		select {
+		case c.inbox <- responseRaw:
+		case <-c.lost:
+		}
+	}()
+	return nil
+}
+
+func (c *memConn) ReadMessage() ([]byte, error) {
+	select {
+	case messageRaw := <-c.inbox:
+		return messageRaw, nil
+	case <-c.lost:
+		return nil, errors.New("connection lost")
+	}
+}
+
+func (c *memConn) Close() error {
+	c.lostOnce.Do(func() { close(c.lost) })
+	return nil
+}
+
+// memDialer dials memConns, failing the first failures dials, with writes taking writeDelay
+type memDialer struct {
+	mux        *Mux
+	failures   int
+	writeDelay time.Duration
+	mu         sync.Mutex
+	dials      int
+	conns      chan *memConn
+}
+
+func newMemDialer(mux *Mux, failures int) *memDialer {
+	return &memDialer{mux: mux, failures: failures, conns: make(chan *memConn, 16)}
+}
+
+func (d *memDialer) dial(ctx context.Context) (MessageConn, error) {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+	d.dials++
+	if d.dials <= d.failures {
+		return nil, errors.New("connection refused")
+	}
+	conn := newMemConn(d.mux)
+	conn.delay = d.writeDelay
+	d.conns <- conn
+	return conn, nil
+}
+
+// connStates records the states reported by a ReconnectingTransport
+type connStates struct {
+	mu     sync.Mutex
+	states []ConnState
+	events chan ConnState
+}
+
+func newConnStates() *connStates {
+	return &connStates{events: make(chan ConnState, 64)}
+}
+
+func (s *connStates) handle(state ConnState) {
+	s.mu.Lock()
+	s.states = append(s.states, state)
+	s.mu.Unlock()
+	s.events <- state
+}
+
+func (s *connStates) waitFor(t *testing.T, state ConnState) {
+	t.Helper()
+	for {
+		select {
+		case got := <-s.events:
+			if got == state {
+				return
+			}
+		case <-time.After(5 * time.Second):
+			t.Fatalf("state %v not reached", state)
+		}
+	}
+}
+
+func (s *connStates) get() []ConnState {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return append([]ConnState(nil), s.states...)
+}
+
+func fastReconnect() ReconnectOption {
+	return WithReconnectBackoff(RetryPolicy{InitialBackoff: time.Millisecond, MaxBackoff: 5 * time.Millisecond})
+}
+
+func TestReconnectingTransport(t *testing.T) {
+	dialer := newMemDialer(newTestMux(t), 2)
+	states := newConnStates()
+	transport := NewReconnectingTransport(dialer.dial, fastReconnect(), WithConnStateHandler(states.handle))
+	client := NewClient(transport)
+
+	var result int
+	err := client.Call(context.Background(), "subtract", []int{42, 23}, &result)
+	if err != nil || result != 19 {
+		t.Fatalf("Call() = %v, %v, want %v", result, err, 19)
+	}
+
+	// Lose the connection and call again over the redialed one
+	(<-dialer.conns).Close()
+	states.waitFor(t, Disconnected)
+	err = client.Call(context.Background(), "subtract", []int{42, 2}, &result)
+	if err != nil || result != 40 {
+		t.Fatalf("Call() = %v, %v, want %v", result, err, 40)
+	}
+
+	err = transport.Close()
+	if err != nil {
+		t.Errorf("Close() = %v, want nil", err)
+	}
+	want := []ConnState{Connecting, Connecting, Connecting, Connected, Disconnected, Connecting, Connected, Closed}
+	got := states.get()
+	if len(got) != len(want) {
+		t.Fatalf("states = %v, want %v", got, want)
+	}
+	for i := range want {
+		if got[i] != want[i] {
+			t.Errorf("states = %v, want %v", got, want)
+			break
+		}
+	}
+
+	err = client.Call(context.Background(), "subtract", []int{42, 23}, &result)
+	if !errors.Is(err, ErrTransportClosed) {
+		t.Errorf("Call() = %v, want %v", err, ErrTransportClosed)
+	}
+}
+
+func TestReconnectingTransportPendingCalls(t *testing.T) {
+	tests := []struct {
+		name    string
+		options []ReconnectOption
+		wantErr error
+	}{
+		{
+			name:    "Failed without replay",
+			wantErr: ErrConnectionLost,
+		},
+		{
+			name:    "Replayed",
+			options: []ReconnectOption{WithReplay()},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			unblock := make(chan struct{})
+			dialer := newMemDialer(newSlowTestMux(t, unblock), 0)
+			transport := NewReconnectingTransport(dialer.dial, append(tt.options, fastReconnect())...)
+			defer transport.Close()
+			client := NewClient(transport)
+
+			call := client.Go(context.Background(), "slow", nil)
+			conn := <-dialer.conns
+			for conn.writes.Load() == 0 {
+				time.Sleep(time.Millisecond)
+			}
+			conn.Close()
+			<-dialer.conns
+			close(unblock)
+			<-call.Done
+
+			var result string
+			err := call.Unmarshal(&result)
+			if tt.wantErr != nil {
+				if !errors.Is(err, tt.wantErr) {
+					t.Errorf("Unmarshal() = %v, want %v", err, tt.wantErr)
+				}
+				return
+			}
+			if err != nil || result != "done" {
+				t.Errorf("Unmarshal() = %v, %v, want %v", result, err, "done")
+			}
+		})
+	}
+}
+
+func TestReconnectingTransportReplayedOnce(t *testing.T) {
+	// The calls queued behind slow writes when the connection is lost are either replayed by the reconnection or
+	// written by the call, never both
+	unblock := make(chan struct{})
+	dialer := newMemDialer(newSlowTestMux(t, unblock), 0)
+	dialer.writeDelay = time.Millisecond
+	transport := NewReconnectingTransport(dialer.dial, WithReplay(), fastReconnect())
+	defer transport.Close()
+	client := NewClient(transport)
+
+	const calls = 20
+	first := <-dialer.conns
+	var wg sync.WaitGroup
+	for i := 0; i < calls; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			_ = client.Call(context.Background(), "slow", nil, nil)
+		}()
+	}
+	for first.writes.Load() == 0 {
+		time.Sleep(time.Millisecond)
+	}
+	first.Close()
+
+	second := <-dialer.conns
+	deadline := time.Now().Add(5 * time.Second)
+	for second.writes.Load() < calls && time.Now().Before(deadline) {
+		time.Sleep(time.Millisecond)
+	}
+	time.Sleep(50 * time.Millisecond)
+	close(unblock)
+	wg.Wait()
+
+	if writes := second.writes.Load(); writes != calls {
+		t.Errorf("writes = %v, want %v", writes, calls)
+	}
+}
+
+func TestReconnectingTransportGiveUp(t *testing.T) {
+	dialer := newMemDialer(newTestMux(t), 10)
+	states := newConnStates()
+	transport := NewReconnectingTransport(dialer.dial, WithConnStateHandler(states.handle),
+		WithReconnectBackoff(RetryPolicy{MaxAttempts: 3, InitialBackoff: time.Millisecond}))
+	states.waitFor(t, Closed)
+	defer transport.Close()
+
+	err := NewClient(transport).Call(context.Background(), "subtract", []int{42, 23}, nil)
+	if !errors.Is(err, ErrTransportClosed) {
+		t.Errorf("Call() = %v, want %v", err, ErrTransportClosed)
+	}
+	if dialer.dials != 3 {
+		t.Errorf("dials = %v, want %v", dialer.dials, 3)
+	}
+}
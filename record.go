@@ -0,0 +1,291 @@
+/*  Copyright 2022  Kosmas Valianos (kosmas.valianos@gmail.com)
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    https://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License. */
+
+package gojsonrpc
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"sync"
+)
+
+// ErrNoRecording is returned by a Replayer for a request which was not recorded
+var ErrNoRecording = errors.New("no recorded response")
+
+// Exchange is a recorded request, notification or batch and its response, empty for a notification
+type Exchange struct {
+	Request  json.RawMessage `json:"request"`
+	Response json.RawMessage `json:"response,omitempty"`
+}
+
+// Recorder records the exchanges of a Transport or of the handlers of a Mux as JSON Lines, one Exchange per line,
+// e.g. into a golden file for a Replayer or to debug production traffic offline. It is safe for concurrent use.
+// Failing to record never fails the calls, Err reports it
+type Recorder struct {
+	mu      sync.Mutex
+	encoder *json.Encoder
+	err     error
+}
+
+// NewRecorder creates a Recorder writing the exchanges to w.
+// Returns a *Recorder object
+func NewRecorder(w io.Writer) *Recorder {
+	return &Recorder{encoder: json.NewEncoder(w)}
+}
+
+// Record writes the exchange.
+// Returns an error if it could not be written
+func (r *Recorder) Record(exchange Exchange) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	err := r.encoder.Encode(exchange)
+	if err != nil && r.err == nil {
+		r.err = err
+	}
+	return err
+}
+
+// Err returns the first error which occurred while recording, if any
+func (r *Recorder) Err() error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	return r.err
+}
+
+// Transport wraps the transport of a Client so that its exchanges are recorded
+func (r *Recorder) Transport(transport Transport) Transport {
+	return &recordingTransport{transport: transport, recorder: r}
+}
+
+type recordingTransport struct {
+	transport Transport
+	recorder  *Recorder
+}
+
+func (t *recordingTransport) RoundTrip(ctx context.Context, requestRaw []byte) ([]byte, error) {
+	responseRaw, err := t.transport.RoundTrip(ctx, requestRaw)
+	if err == nil {
+		t.recorder.Record(Exchange{Request: bytes.TrimSpace(requestRaw), Response: bytes.TrimSpace(responseRaw)})
+	}
+	return responseRaw, err
+}
+
+func (t *recordingTransport) Send(ctx context.Context, notificationRaw []byte) error {
+	err := t.transport.Send(ctx, notificationRaw)
+	if err == nil {
+		t.recorder.Record(Exchange{Request: bytes.TrimSpace(notificationRaw)})
+	}
+	return err
+}
+
+// Middleware records the requests and the notifications served by the handlers of a Mux and their responses,
+// rebuilt from the params and the result or the error of the handler. The elements of a batch are recorded one by one
+func (r *Recorder) Middleware() Middleware {
+	return func(next Handler) Handler {
+		return HandlerFunc(func(ctx context.Context, params json.RawMessage) (any, error) {
+			result, err := next.ServeJSONRPC(ctx, params)
+			r.recordServed(ctx, params, result, err)
+			return result, err
+		})
+	}
+}
+
+// recordServed records the request or the notification served with the outcome of its handler
+func (r *Recorder) recordServed(ctx context.Context, params json.RawMessage, result any, err error) {
+	method, id := MethodFromContext(ctx), IDFromContext(ctx)
+	var paramsValue any
+	if params != nil {
+		paramsValue = params
+	}
+	if id == nil {
+		notificationRaw, marshalErr := NewNotification(method, paramsValue, WithoutTrailingNewline())
+		if marshalErr == nil {
+			r.Record(Exchange{Request: notificationRaw})
+		}
+		return
+	}
+
+	requestRaw, marshalErr := newRequestWithID(method, paramsValue, id)
+	if marshalErr != nil {
+		return
+	}
+	reply, marshalErr := newResponseEnvelope(id)
+	if marshalErr != nil {
+		return
+	}
+	var responseRaw []byte
+	if err != nil {
+		jsonRPCError, ok := AsJsonRPCError(err)
+		if !ok || jsonRPCError == nil {
+			jsonRPCError = &JsonInternalError
+		}
+		responseRaw = reply.errorResponse(jsonRPCError)
+	} else if responseRaw, marshalErr = reply.resultResponse(jsonEngineFromContext(ctx), result); marshalErr != nil {
+		return
+	}
+	r.Record(Exchange{Request: bytes.TrimSpace(requestRaw), Response: bytes.TrimSpace(responseRaw)})
+}
+
+// ReadExchanges reads the exchanges written by a Recorder.
+// Returns a []Exchange or an error
+func ReadExchanges(r io.Reader) ([]Exchange, error) {
+	var exchanges []Exchange
+	decoder := json.NewDecoder(r)
+	for {
+		var exchange Exchange
+		err := decoder.Decode(&exchange)
+		if err == io.EOF {
+			return exchanges, nil
+		}
+		if err != nil {
+			return nil, fmt.Errorf("exchange %v: %w", len(exchanges), err)
+		}
+		exchanges = append(exchanges, exchange)
+	}
+}
+
+// Replayer is a Transport, and a Handler for a Mux, serving the recorded responses. A request is answered with the
+// response recorded for the same method and params, regardless of the id, which is set to the one of the request.
+// The responses recorded for the same request are served in turn, the last one repeatedly.
+// It is safe for concurrent use
+type Replayer struct {
+	mu        sync.Mutex
+	responses map[string][]*response
+}
+
+// NewReplayer creates a Replayer serving the responses of the exchanges, whose requests may be batches.
+// Returns a *Replayer object or an error if an exchange is invalid
+func NewReplayer(exchanges []Exchange) (*Replayer, error) {
+	r := &Replayer{responses: make(map[string][]*response)}
+	for i, exchange := range exchanges {
+		if len(exchange.Response) == 0 {
+			continue
+		}
+		requestsRaw, err := splitBatch(exchange.Request)
+		if err != nil {
+			return nil, fmt.Errorf("exchange %v: %w", i, err)
+		}
+		responsesRaw, err := splitBatch(exchange.Response)
+		if err != nil {
+			return nil, fmt.Errorf("exchange %v: %w", i, err)
+		}
+		responses := make(map[string]*response, len(responsesRaw))
+		for _, responseRaw := range responsesRaw {
+			response, err := ParseResponse(responseRaw)
+			if err != nil {
+				return nil, fmt.Errorf("exchange %v: %w", i, err)
+			}
+			responses[idKey(response.ID)] = response
+		}
+		for _, requestRaw := range requestsRaw {
+			request, jsonRPCError := parseRequest(requestRaw)
+			if jsonRPCError != nil {
+				continue
+			}
+			if response, ok := responses[idKey(request.ID)]; ok {
+				key := replayKey(request.Method, request.Params)
+				r.responses[key] = append(r.responses[key], response)
+			}
+		}
+	}
+	return r, nil
+}
+
+// RoundTrip answers a request or a batch with the recorded responses.
+// Returns the raw bytes of the response or an error wrapping ErrNoRecording
+func (r *Replayer) RoundTrip(ctx context.Context, requestRaw []byte) ([]byte, error) {
+	requestsRaw, err := splitBatch(requestRaw)
+	if err != nil {
+		return nil, err
+	}
+	responsesRaw := make([][]byte, 0, len(requestsRaw))
+	for _, requestRaw := range requestsRaw {
+		request, jsonRPCError := parseRequest(requestRaw)
+		if jsonRPCError != nil {
+			// Notifications of a batch are not answered
+			continue
+		}
+		response, err := r.replay(request.Method, request.Params)
+		if err != nil {
+			return nil, err
+		}
+		reply, err := newResponseEnvelope(request.ID)
+		if err != nil {
+			return nil, err
+		}
+		var responseRaw []byte
+		if response.Error != nil {
+			responseRaw = reply.errorResponse(response.Error)
+		} else {
+			responseRaw = reply.render(resultResponsePrefix, response.Result, reply.suffix)
+		}
+		responsesRaw = append(responsesRaw, bytes.TrimSpace(responseRaw))
+	}
+	if jsonKind(requestRaw) != '[' {
+		return append(responsesRaw[0], '\n'), nil
+	}
+	return append(append([]byte("["), bytes.Join(responsesRaw, []byte(","))...), ']', '\n'), nil
+}
+
+// Send drops the notification, which has no response to replay.
+// Returns no error
+func (r *Replayer) Send(ctx context.Context, notificationRaw []byte) error {
+	return nil
+}
+
+// ServeJSONRPC implements Handler with the recorded responses, e.g. registered with Mux.HandleUnknown.
+// Returns the recorded result or the *jsonRPCError object of the recorded response, JsonMethodNotFound if none
+func (r *Replayer) ServeJSONRPC(ctx context.Context, params json.RawMessage) (any, error) {
+	response, err := r.replay(MethodFromContext(ctx), params)
+	if err != nil {
+		return nil, &JsonMethodNotFound
+	}
+	if response.Error != nil {
+		return nil, response.Error
+	}
+	return response.Result, nil
+}
+
+// replay finds the next response recorded for the method and the params.
+// Returns a *response object or an error wrapping ErrNoRecording
+func (r *Replayer) replay(method string, params json.RawMessage) (*response, error) {
+	key := replayKey(method, params)
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	responses := r.responses[key]
+	if len(responses) == 0 {
+		return nil, fmt.Errorf("%w for method %q with params %s", ErrNoRecording, method, params)
+	}
+	response := responses[0]
+	if len(responses) > 1 {
+		r.responses[key] = responses[1:]
+	}
+	return response, nil
+}
+
+// replayKey identifies the requests of the method with the params, whatever their whitespace and member order
+func replayKey(method string, params json.RawMessage) string {
+	var value any
+	if len(params) > 0 && json.Unmarshal(params, &value) == nil {
+		// The keys of the maps are marshaled in order
+		if canonical, err := json.Marshal(value); err == nil {
+			params = canonical
+		}
+	}
+	return method + "\x00" + string(params)
+}
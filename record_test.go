@@ -0,0 +1,129 @@
+/*  Copyright 2022  Kosmas Valianos (kosmas.valianos@gmail.com)
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    https://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License. */
+
+package gojsonrpc
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+func TestRecorder_Transport(t *testing.T) {
+	var recording bytes.Buffer
+	recorder := NewRecorder(&recording)
+	client := NewClient(recorder.Transport(&muxTransport{mux: newTestMux(t)}))
+	ctx := context.Background()
+
+	if _, err := Call[int](ctx, client, "subtract", []int{42, 23}); err != nil {
+		t.Fatal(err)
+	}
+	if err := client.Notify(ctx, "update", []int{1}); err != nil {
+		t.Fatal(err)
+	}
+	if err := client.Call(ctx, "database", nil, nil); !errors.Is(err, ErrInvalidMethodParameters) {
+		t.Fatalf("Call() error = %v, want %v", err, ErrInvalidMethodParameters)
+	}
+	if err := recorder.Err(); err != nil {
+		t.Fatal(err)
+	}
+
+	want := `{"request":{"jsonrpc":"2.0","method":"subtract","params":[42,23],"id":1},"response":{"jsonrpc":"2.0","result":19,"id":1}}
+{"request":{"jsonrpc":"2.0","method":"update","params":[1]}}
+{"request":{"jsonrpc":"2.0","method":"database","id":2},"response":{"jsonrpc":"2.0","error":{"code":-32602,"message":"Invalid method parameters"},"id":2}}
+`
+	if got := recording.String(); got != want {
+		t.Errorf("recording = %v, want %v", got, want)
+	}
+}
+
+func TestRecorder_Middleware(t *testing.T) {
+	var recording bytes.Buffer
+	recorder := NewRecorder(&recording)
+	mux := newTestMux(t)
+	mux.Use(recorder.Middleware())
+
+	mux.Serve(context.Background(), []byte(`{"jsonrpc": "2.0", "method": "subtract", "params": [42, 23], "id": "a"}`))
+	mux.Serve(context.Background(), []byte(`{"jsonrpc": "2.0", "method": "fail", "id": 2}`))
+	mux.Serve(context.Background(), []byte(`{"jsonrpc": "2.0", "method": "raw", "params": {"x": 1}}`))
+
+	want := `{"request":{"jsonrpc":"2.0","method":"subtract","params":[42,23],"id":"a"},"response":{"jsonrpc":"2.0","result":19,"id":"a"}}
+{"request":{"jsonrpc":"2.0","method":"fail","id":2},"response":{"jsonrpc":"2.0","error":{"code":-32603,"message":"Internal error"},"id":2}}
+{"request":{"jsonrpc":"2.0","method":"raw","params":{"x":1}}}
+`
+	if got := recording.String(); got != want {
+		t.Errorf("recording = %v, want %v", got, want)
+	}
+}
+
+func TestReplayer(t *testing.T) {
+	recording := `{"request":{"jsonrpc":"2.0","method":"subtract","params":{"minuend":42,"subtrahend":23},"id":1},"response":{"jsonrpc":"2.0","result":19,"id":1}}
+{"request":{"jsonrpc":"2.0","method":"update","params":[1]}}
+
+{"request":[{"jsonrpc":"2.0","method":"count","id":7},{"jsonrpc":"2.0","method":"count","id":8}],"response":[{"jsonrpc":"2.0","result":2,"id":8},{"jsonrpc":"2.0","result":1,"id":7}]}
+{"request":{"jsonrpc":"2.0","method":"database","id":2},"response":{"jsonrpc":"2.0","error":{"code":-32602,"message":"Invalid method parameters"},"id":2}}
+`
+	exchanges, err := ReadExchanges(strings.NewReader(recording))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(exchanges) != 4 {
+		t.Fatalf("ReadExchanges() = %v exchanges, want 4", len(exchanges))
+	}
+	replayer, err := NewReplayer(exchanges)
+	if err != nil {
+		t.Fatal(err)
+	}
+	client := NewClient(replayer, WithIDGenerator(UUIDGenerator{}))
+	ctx := context.Background()
+
+	params := map[string]int{"subtrahend": 23, "minuend": 42}
+	if result, err := Call[int](ctx, client, "subtract", params); err != nil || result != 19 {
+		t.Errorf("Call() = %v, %v, want 19", result, err)
+	}
+	var counts []int
+	for i := 0; i < 3; i++ {
+		count, err := Call[int](ctx, client, "count", nil)
+		if err != nil {
+			t.Fatal(err)
+		}
+		counts = append(counts, count)
+	}
+	if !reflect.DeepEqual(counts, []int{1, 2, 2}) {
+		t.Errorf("Call() = %v, want the recorded responses in turn, the last one repeatedly", counts)
+	}
+	if err := client.Call(ctx, "database", nil, nil); !errors.Is(err, ErrInvalidMethodParameters) {
+		t.Errorf("Call() error = %v, want %v", err, ErrInvalidMethodParameters)
+	}
+	if err := client.Call(ctx, "subtract", []int{1, 2}, nil); !errors.Is(err, ErrNoRecording) {
+		t.Errorf("Call() error = %v, want %v", err, ErrNoRecording)
+	}
+
+	// A Mux serves the recorded responses as well
+	mux := NewMux()
+	mux.HandleUnknown(replayer)
+	got := mux.Serve(ctx, []byte(`{"jsonrpc": "2.0", "method": "subtract", "params": {"minuend": 42, "subtrahend": 23}, "id": 5}`))
+	if want := `{"jsonrpc":"2.0","result":19,"id":5}` + "\n"; string(got) != want {
+		t.Errorf("Serve() = %s, want %s", got, want)
+	}
+}
+
+func TestReadExchanges_invalid(t *testing.T) {
+	if _, err := ReadExchanges(strings.NewReader(`{"request": {}}` + "\n" + `{"request"`)); err == nil {
+		t.Error("ReadExchanges() error = nil, want an error")
+	}
+}
@@ -0,0 +1,115 @@
+/*  Copyright 2022  Kosmas Valianos (kosmas.valianos@gmail.com)
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    https://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License. */
+
+package gojsonrpc
+
+import (
+	"context"
+	"encoding/json"
+)
+
+// RedisClient is the connection to Redis of the Redis transport, e.g. a small wrapper of a go-redis client
+// blocking in BLPOP with no timeout and receiving the messages of its PubSub channel
+type RedisClient interface {
+	// RPush appends the value to the list under the key
+	RPush(ctx context.Context, key string, value []byte) error
+	// BLPop removes and returns the first value of the list under the key, waiting for one until the ctx is done
+	BLPop(ctx context.Context, key string) ([]byte, error)
+	// Publish publishes the message to the channel
+	Publish(ctx context.Context, channel string, message []byte) error
+	// Subscribe passes the messages published to the channel to the handler, in their order, until unsubscribed
+	Subscribe(ctx context.Context, channel string, handler func(message []byte)) (unsubscribe func() error, err error)
+}
+
+// The channels of a JSON-RPC service after its key
+const (
+	redisResponseChannel     = ":response:"
+	redisNotificationChannel = ":notification"
+)
+
+// redisEnvelope is a message pushed to the list of a service with the channel of its response
+type redisEnvelope struct {
+	Reply   string          `json:"reply,omitempty"`
+	Message json.RawMessage `json:"message"`
+}
+
+// ServeRedis serves the requests and notifications pushed to the list under the key with the mux until the ctx is done,
+// publishing the responses to the channels of the clients. Many servers may serve the same list, each message being
+// served by one of them.
+// Returns the error of BLPop or nil once the ctx is done
+func ServeRedis(ctx context.Context, client RedisClient, key string, mux *Mux) error {
+	for {
+		envelopeRaw, err := client.BLPop(ctx, key)
+		if ctx.Err() != nil {
+			return nil
+		}
+		if err != nil {
+			return err
+		}
+		var envelope redisEnvelope
+		if json.Unmarshal(envelopeRaw, &envelope) != nil {
+			// Not pushed by a client of the service, with nowhere to answer
+			continue
+		}
+		serveBrokerMessage(ctx, mux, envelope.Message, func(responseRaw []byte) {
+			if envelope.Reply != "" {
+				_ = client.Publish(ctx, envelope.Reply, responseRaw)
+			}
+		})
+	}
+}
+
+// BroadcastRedis sends a notification of the method with the params to all the clients of the service with the key
+// on the "<key>:notification" channel
+func BroadcastRedis(ctx context.Context, client RedisClient, key string, method string, params any) error {
+	notificationRaw, err := NewNotification(method, params)
+	if err != nil {
+		return err
+	}
+	return client.Publish(ctx, key+redisNotificationChannel, notificationRaw)
+}
+
+// DialRedis creates a Conn pushing the requests and notifications to the list under the key and receiving
+// the responses on a "<key>:response:<client ID>" channel of its own. The notifications broadcast by the service
+// are served with the mux, which may be nil. Closing the Conn unsubscribes from the channels.
+// Returns a *Conn object or an error
+func DialRedis(ctx context.Context, client RedisClient, key string, mux *Mux, options ...ClientOption) (*Conn, error) {
+	clientID, err := newBrokerClientID()
+	if err != nil {
+		return nil, err
+	}
+	reply := key + redisResponseChannel + clientID
+	redisConn := newBrokerConn(func(ctx context.Context, messageRaw []byte) error {
+		envelope := redisEnvelope{Reply: reply, Message: messageRaw}
+		if isNotificationMessage(messageRaw) {
+			envelope.Reply = ""
+		}
+		envelopeRaw, err := json.Marshal(&envelope)
+		if err != nil {
+			return err
+		}
+		return client.RPush(ctx, key, envelopeRaw)
+	})
+	unsubscribeResponses, err := client.Subscribe(ctx, reply, redisConn.deliver)
+	if err != nil {
+		return nil, err
+	}
+	unsubscribeNotifications, err := client.Subscribe(ctx, key+redisNotificationChannel, redisConn.deliver)
+	if err != nil {
+		_ = unsubscribeResponses()
+		return nil, err
+	}
+	redisConn.unsubscribe = unsubscribeAll(unsubscribeResponses, unsubscribeNotifications)
+	return NewConn(redisConn, mux, options...), nil
+}
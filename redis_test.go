@@ -0,0 +1,188 @@
+/*  Copyright 2022  Kosmas Valianos (kosmas.valianos@gmail.com)
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    https://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License. */
+
+package gojsonrpc
+
+import (
+	"context"
+	"sync"
+	"testing"
+	"time"
+)
+
+// memRedis is an in-memory Redis with lists and pub/sub channels
+type memRedis struct {
+	mu     sync.Mutex
+	lists  map[string]chan []byte
+	subs   map[string]map[int]func([]byte)
+	nextID int
+}
+
+func newMemRedis() *memRedis {
+	return &memRedis{lists: make(map[string]chan []byte), subs: make(map[string]map[int]func([]byte))}
+}
+
+func (r *memRedis) list(key string) chan []byte {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	if r.lists[key] == nil {
+		r.lists[key] = make(chan []byte, 64)
+	}
+	return r.lists[key]
+}
+
+func (r *memRedis) RPush(ctx context.Context, key string, value []byte) error {
+	r.list(key) <- append([]byte(nil), value...)
+	return nil
+}
+
+func (r *memRedis) BLPop(ctx context.Context, key string) ([]byte, error) {
+	select {
+	case value := <-r.list(key):
+		return value, nil
+	case <-ctx.Done():
+		return nil, ctx.Err()
+	}
+}
+
+func (r *memRedis) Publish(ctx context.Context, channel string, message []byte) error {
+	r.mu.Lock()
+	handlers := make([]func([]byte), 0, len(r.subs[channel]))
+	for _, handler := range r.subs[channel] {
+		handlers = append(handlers, handler)
+	}
+	r.mu.Unlock()
+	for _, handler := range handlers {
+		handler(message)
+	}
+	return nil
+}
+
+func (r *memRedis) Subscribe(ctx context.Context, channel string, handler func(message []byte)) (func() error, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.nextID++
+	id := r.nextID
+	if r.subs[channel] == nil {
+		r.subs[channel] = make(map[int]func([]byte))
+	}
+	r.subs[channel][id] = handler
+	return func() error {
+		r.mu.Lock()
+		defer r.mu.Unlock()
+		delete(r.subs[channel], id)
+		return nil
+	}, nil
+}
+
+func (r *memRedis) subscriptions() int {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	count := 0
+	for _, subs := range r.subs {
+		count += len(subs)
+	}
+	return count
+}
+
+func TestDialRedis(t *testing.T) {
+	redis := newMemRedis()
+	ctx, cancel := context.WithCancel(context.Background())
+	served := make(chan error, 2)
+	// Two servers sharing the list
+	for i := 0; i < 2; i++ {
+		go func() {
+			served <- ServeRedis(ctx, redis, "math", newTestMux(t))
+		}()
+	}
+
+	updates := make(chan []int, 1)
+	clientMux := NewMux()
+	err := HandleFunc(clientMux, "update", func(ctx context.Context, params []int) (any, error) {
+		updates <- params
+		return nil, nil
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	conn, err := DialRedis(context.Background(), redis, "math", clientMux)
+	if err != nil {
+		t.Fatal(err)
+	}
+	for i := 0; i < 4; i++ {
+		var result int
+		err = conn.Call(context.Background(), "subtract", []int{42, i}, &result)
+		if err != nil || result != 42-i {
+			t.Errorf("Call() = %v, %v, want %v", result, err, 42-i)
+		}
+	}
+	err = conn.Notify(context.Background(), "subtract", []int{42, 23})
+	if err != nil {
+		t.Errorf("Notify() = %v, want nil", err)
+	}
+
+	err = BroadcastRedis(context.Background(), redis, "math", "update", []int{1, 2})
+	if err != nil {
+		t.Fatal(err)
+	}
+	select {
+	case params := <-updates:
+		if len(params) != 2 || params[0] != 1 || params[1] != 2 {
+			t.Errorf("update params = %v, want %v", params, []int{1, 2})
+		}
+	case <-time.After(5 * time.Second):
+		t.Fatal("update not received")
+	}
+
+	err = conn.Close()
+	if err != nil {
+		t.Errorf("Close() = %v, want nil", err)
+	}
+	if redis.subscriptions() != 0 {
+		t.Errorf("subscriptions() = %v, want 0", redis.subscriptions())
+	}
+	cancel()
+	for i := 0; i < 2; i++ {
+		if err := <-served; err != nil {
+			t.Errorf("ServeRedis() = %v, want nil", err)
+		}
+	}
+}
+
+func TestServeRedis_envelope(t *testing.T) {
+	redis := newMemRedis()
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	go ServeRedis(ctx, redis, "math", newTestMux(t))
+
+	responses := make(chan []byte, 1)
+	_, err := redis.Subscribe(ctx, "math:response:1", func(message []byte) {
+		responses <- message
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	// A value not pushed by a client is skipped
+	_ = redis.RPush(ctx, "math", []byte("not an envelope"))
+	_ = redis.RPush(ctx, "math", []byte(`{"reply":"math:response:1","message":{"jsonrpc":"2.0","method":"subtract","params":[42,23],"id":1}}`))
+	select {
+	case responseRaw := <-responses:
+		want := `{"jsonrpc":"2.0","result":19,"id":1}` + "\n"
+		if string(responseRaw) != want {
+			t.Errorf("response = %q, want %q", responseRaw, want)
+		}
+	case <-time.After(5 * time.Second):
+		t.Fatal("response not received")
+	}
+}
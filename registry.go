@@ -0,0 +1,147 @@
+/*  Copyright 2022  Kosmas Valianos (kosmas.valianos@gmail.com)
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    https://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License. */
+
+package gojsonrpc
+
+import (
+	"context"
+	"errors"
+	"sort"
+	"strconv"
+	"sync"
+)
+
+// ErrUnknownConn is returned when calling a connection which is not or no longer in the ConnRegistry
+var ErrUnknownConn = errors.New("unknown connection")
+
+// ConnRegistry keeps track of the connections accepted by a server so that the server can call its clients back,
+// e.g. from outside of any handler. The connections leave the registry once they are closed or lost
+type ConnRegistry struct {
+	mu     sync.RWMutex
+	conns  map[string]*Conn
+	nextID uint64
+}
+
+// NewConnRegistry creates an empty ConnRegistry.
+// Returns a *ConnRegistry object
+func NewConnRegistry() *ConnRegistry {
+	return &ConnRegistry{conns: make(map[string]*Conn)}
+}
+
+// Accept creates a Conn over the connection, like NewConn, and registers it under a new ID until it is closed or lost.
+// Returns a *Conn object
+func (r *ConnRegistry) Accept(conn MessageConn, mux *Mux, options ...ClientOption) *Conn {
+	r.mu.Lock()
+	r.nextID++
+	id := strconv.FormatUint(r.nextID, 10)
+	c := newConn(id, conn, mux, options...)
+	r.conns[id] = c
+	r.mu.Unlock()
+
+	go func() {
+		<-c.Done()
+		r.mu.Lock()
+		delete(r.conns, id)
+		r.mu.Unlock()
+	}()
+	return c
+}
+
+// Conn returns the registered Conn with the ID
+func (r *ConnRegistry) Conn(id string) (*Conn, bool) {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+	c, ok := r.conns[id]
+	return c, ok
+}
+
+// IDs returns the sorted IDs of the registered connections
+func (r *ConnRegistry) IDs() []string {
+	r.mu.RLock()
+	ids := make([]string, 0, len(r.conns))
+	for id := range r.conns {
+		ids = append(ids, id)
+	}
+	r.mu.RUnlock()
+	sort.Slice(ids, func(i, j int) bool {
+		if len(ids[i]) != len(ids[j]) {
+			return len(ids[i]) < len(ids[j])
+		}
+		return ids[i] < ids[j]
+	})
+	return ids
+}
+
+// Call calls a method of the client at the other end of the registered connection with the ID and unmarshals its result
+// into result. The call times out with ctx or the call timeout of the options given to Accept, if any.
+// Returns ErrUnknownConn if no connection is registered with the ID
+func (r *ConnRegistry) Call(ctx context.Context, id string, method string, params any, result any) error {
+	c, ok := r.Conn(id)
+	if !ok {
+		return ErrUnknownConn
+	}
+	return c.Call(ctx, method, params, result)
+}
+
+// SendTo sends a notification to the client at the other end of the registered connection with the ID.
+// Returns ErrUnknownConn if no connection is registered with the ID
+func (r *ConnRegistry) SendTo(ctx context.Context, id string, method string, params any) error {
+	c, ok := r.Conn(id)
+	if !ok {
+		return ErrUnknownConn
+	}
+	return c.Notify(ctx, method, params)
+}
+
+// Broadcast sends a notification to the clients of all the registered connections, concurrently so that a slow client
+// does not hold up the others.
+// Returns the first error of a connection, the notification being sent to the others regardless
+func (r *ConnRegistry) Broadcast(ctx context.Context, method string, params any) error {
+	return r.BroadcastFunc(ctx, nil, method, params)
+}
+
+// BroadcastFunc is like Broadcast but only sends the notification to the clients of the connections selected
+// by the function, all of them if it is nil. The function is called without holding the registry, so it may use it
+func (r *ConnRegistry) BroadcastFunc(ctx context.Context, selected func(conn *Conn) bool, method string, params any) error {
+	r.mu.RLock()
+	registered := make([]*Conn, 0, len(r.conns))
+	for _, c := range r.conns {
+		registered = append(registered, c)
+	}
+	r.mu.RUnlock()
+
+	conns := registered[:0]
+	for _, c := range registered {
+		if selected == nil || selected(c) {
+			conns = append(conns, c)
+		}
+	}
+
+	var (
+		wg       sync.WaitGroup
+		errOnce  sync.Once
+		firstErr error
+	)
+	for _, c := range conns {
+		wg.Add(1)
+		go func(c *Conn) {
+			defer wg.Done()
+			if err := c.Notify(ctx, method, params); err != nil {
+				errOnce.Do(func() { firstErr = err })
+			}
+		}(c)
+	}
+	wg.Wait()
+	return firstErr
+}
@@ -0,0 +1,207 @@
+/*  Copyright 2022  Kosmas Valianos (kosmas.valianos@gmail.com)
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    https://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License. */
+
+package gojsonrpc
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+)
+
+// newTestClientMux creates the mux of a client answering "whoami" with its name and never answering "hang"
+func newTestClientMux(t *testing.T, name string, unblock chan struct{}) *Mux {
+	mux := NewMux()
+	err := HandleFunc(mux, "whoami", func(ctx context.Context, params any) (string, error) {
+		return name, nil
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	err = HandleFunc(mux, "hang", func(ctx context.Context, params any) (any, error) {
+		<-unblock
+		return nil, nil
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	return mux
+}
+
+func TestConnRegistry(t *testing.T) {
+	unblock := make(chan struct{})
+	defer close(unblock)
+	registry := NewConnRegistry()
+	names := []string{"alice", "bob"}
+	clients := make([]*Conn, 0, len(names))
+	for _, name := range names {
+		clientEnd, serverEnd := Pipe()
+		clients = append(clients, NewConn(clientEnd, newTestClientMux(t, name, unblock)))
+		registry.Accept(serverEnd, NewMux(), WithCallTimeout(50*time.Millisecond))
+	}
+
+	ids := registry.IDs()
+	if len(ids) != len(names) {
+		t.Fatalf("IDs() = %v, want %v IDs", ids, len(names))
+	}
+
+	t.Run("Call", func(t *testing.T) {
+		for i, id := range ids {
+			var name string
+			err := registry.Call(context.Background(), id, "whoami", nil, &name)
+			if err != nil || name != names[i] {
+				t.Errorf("Call() = %v, %v, want %v", name, err, names[i])
+			}
+		}
+	})
+
+	t.Run("Timeout", func(t *testing.T) {
+		err := registry.Call(context.Background(), ids[0], "hang", nil, nil)
+		if !errors.Is(err, ErrCallTimeout) {
+			t.Errorf("Call() = %v, want %v", err, ErrCallTimeout)
+		}
+	})
+
+	t.Run("Unknown connection", func(t *testing.T) {
+		err := registry.Call(context.Background(), "42", "whoami", nil, nil)
+		if !errors.Is(err, ErrUnknownConn) {
+			t.Errorf("Call() = %v, want %v", err, ErrUnknownConn)
+		}
+	})
+
+	t.Run("Disconnect", func(t *testing.T) {
+		conn, ok := registry.Conn(ids[1])
+		if !ok || conn.ID() != ids[1] {
+			t.Fatalf("Conn() = %v, %v, want the Conn %v", conn, ok, ids[1])
+		}
+		clients[1].Close()
+		<-conn.Done()
+		deadline := time.Now().Add(5 * time.Second)
+		for len(registry.IDs()) != 1 && time.Now().Before(deadline) {
+			time.Sleep(time.Millisecond)
+		}
+		if got := registry.IDs(); len(got) != 1 || got[0] != ids[0] {
+			t.Errorf("IDs() = %v, want [%v]", got, ids[0])
+		}
+		err := registry.Call(context.Background(), ids[1], "whoami", nil, nil)
+		if !errors.Is(err, ErrUnknownConn) {
+			t.Errorf("Call() = %v, want %v", err, ErrUnknownConn)
+		}
+	})
+
+	clients[0].Close()
+}
+
+func TestConnRegistry_Broadcast(t *testing.T) {
+	registry := NewConnRegistry()
+	names := []string{"alice", "bob", "carol"}
+	received := make(map[string]chan string, len(names))
+	clients := make([]*Conn, 0, len(names))
+	for _, name := range names {
+		events := make(chan string, 4)
+		received[name] = events
+		mux := NewMux()
+		err := HandleFunc(mux, "news", func(ctx context.Context, params [1]string) (any, error) {
+			events <- params[0]
+			return nil, nil
+		})
+		if err != nil {
+			t.Fatal(err)
+		}
+		clientEnd, serverEnd := Pipe()
+		clients = append(clients, NewConn(clientEnd, mux))
+		registry.Accept(serverEnd, NewMux())
+	}
+	defer func() {
+		for _, client := range clients {
+			client.Close()
+		}
+	}()
+	ids := registry.IDs()
+
+	receive := func(t *testing.T, want map[string]string) {
+		t.Helper()
+		for _, name := range names {
+			select {
+			case got := <-received[name]:
+				if got != want[name] {
+					t.Errorf("%v received %v, want %v", name, got, want[name])
+				}
+			case <-time.After(100 * time.Millisecond):
+				if want[name] != "" {
+					t.Errorf("%v received nothing, want %v", name, want[name])
+				}
+			}
+		}
+	}
+
+	t.Run("Broadcast", func(t *testing.T) {
+		err := registry.Broadcast(context.Background(), "news", []string{"hello"})
+		if err != nil {
+			t.Fatalf("Broadcast() = %v, want nil", err)
+		}
+		receive(t, map[string]string{"alice": "hello", "bob": "hello", "carol": "hello"})
+	})
+
+	t.Run("BroadcastFunc", func(t *testing.T) {
+		err := registry.BroadcastFunc(context.Background(), func(conn *Conn) bool {
+			return conn.ID() != ids[1]
+		}, "news", []string{"not for bob"})
+		if err != nil {
+			t.Fatalf("BroadcastFunc() = %v, want nil", err)
+		}
+		receive(t, map[string]string{"alice": "not for bob", "carol": "not for bob"})
+	})
+
+	t.Run("SendTo", func(t *testing.T) {
+		err := registry.SendTo(context.Background(), ids[2], "news", []string{"for carol"})
+		if err != nil {
+			t.Fatalf("SendTo() = %v, want nil", err)
+		}
+		receive(t, map[string]string{"carol": "for carol"})
+
+		err = registry.SendTo(context.Background(), "42", "news", []string{"for nobody"})
+		if !errors.Is(err, ErrUnknownConn) {
+			t.Errorf("SendTo() = %v, want %v", err, ErrUnknownConn)
+		}
+	})
+}
+
+func TestConnRegistry_BroadcastFunc_reentrant(t *testing.T) {
+	registry := NewConnRegistry()
+	clientEnd, serverEnd := Pipe()
+	client := NewConn(clientEnd, NewMux())
+	defer client.Close()
+	registry.Accept(serverEnd, NewMux())
+
+	// The function selecting the connections may use the registry, e.g. to register another connection
+	done := make(chan error, 1)
+	go func() {
+		done <- registry.BroadcastFunc(context.Background(), func(conn *Conn) bool {
+			otherClientEnd, otherServerEnd := Pipe()
+			otherClientEnd.Close()
+			registry.Accept(otherServerEnd, NewMux())
+			return false
+		}, "news", nil)
+	}()
+	select {
+	case err := <-done:
+		if err != nil {
+			t.Errorf("BroadcastFunc() = %v, want nil", err)
+		}
+	case <-time.After(5 * time.Second):
+		t.Fatal("BroadcastFunc() deadlocked")
+	}
+}
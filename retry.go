@@ -0,0 +1,125 @@
+/*  Copyright 2022  Kosmas Valianos (kosmas.valianos@gmail.com)
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    https://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License. */
+
+package gojsonrpc
+
+import (
+	"context"
+	"encoding/json"
+	"math/rand"
+	"time"
+)
+
+// RetryPolicy configures the retries of the calls of a Client which failed in the transport or with selected error codes.
+// Only enable it for methods which are safe to call more than once
+type RetryPolicy struct {
+	// MaxAttempts caps the attempts of a call including the first one. 1 or less means no retries
+	MaxAttempts int
+	// InitialBackoff is the delay before the first retry. The default one is 100ms
+	InitialBackoff time.Duration
+	// MaxBackoff caps the delay before a retry. Zero means no cap
+	MaxBackoff time.Duration
+	// Multiplier multiplies the delay after each retry. The default one is 2
+	Multiplier float64
+	// Jitter randomly shortens each delay by up to this fraction of it, between 0 and 1
+	Jitter float64
+	// Codes are the codes of the error objects of the responses which are retried, e.g. ServerBusy
+	Codes []int
+}
+
+// WithRetryPolicy sets the RetryPolicy of the calls. The retries stop when the context of the call is done
+// or when its deadline would expire before the next attempt
+func WithRetryPolicy(policy RetryPolicy) ClientOption {
+	return func(c *Client) {
+		c.retryPolicy = policy
+	}
+}
+
+// retryable reports whether the attempt of a call whose outcome is responseRaw and err is retried
+func (p RetryPolicy) retryable(ctx context.Context, attempt int, responseRaw []byte, err error) bool {
+	if attempt >= p.MaxAttempts || ctx.Err() != nil {
+		return false
+	}
+	if err != nil {
+		return true
+	}
+	code, ok := responseErrorCode(responseRaw)
+	if !ok {
+		return false
+	}
+	for _, retryCode := range p.Codes {
+		if code == retryCode {
+			return true
+		}
+	}
+	return false
+}
+
+// backoff returns the delay before the retry following the attempt
+func (p RetryPolicy) backoff(attempt int) time.Duration {
+	backoff := float64(p.InitialBackoff)
+	if backoff <= 0 {
+		backoff = float64(100 * time.Millisecond)
+	}
+	multiplier := p.Multiplier
+	if multiplier <= 0 {
+		multiplier = 2
+	}
+	for i := 1; i < attempt; i++ {
+		backoff *= multiplier
+		if p.MaxBackoff > 0 && backoff >= float64(p.MaxBackoff) {
+			break
+		}
+	}
+	if p.MaxBackoff > 0 && backoff > float64(p.MaxBackoff) {
+		backoff = float64(p.MaxBackoff)
+	}
+	if p.Jitter > 0 {
+		backoff -= backoff * p.Jitter * rand.Float64()
+	}
+	return time.Duration(backoff)
+}
+
+// sleep waits for the delay unless ctx is done first or its deadline would expire before the delay.
+// Returns false if the delay was not waited for
+func sleep(ctx context.Context, delay time.Duration) bool {
+	if deadline, ok := ctx.Deadline(); ok && time.Until(deadline) < delay {
+		return false
+	}
+	timer := time.NewTimer(delay)
+	defer timer.Stop()
+	select {
+	case <-timer.C:
+		return true
+	case <-ctx.Done():
+		return false
+	}
+}
+
+// responseErrorCode returns the code of the error object of a response and true or false if it is not an error response
+func responseErrorCode(responseRaw []byte) (int, bool) {
+	if jsonKind(responseRaw) != '{' {
+		return 0, false
+	}
+	var response struct {
+		Error *struct {
+			Code int `json:"code"`
+		} `json:"error"`
+	}
+	err := json.Unmarshal(responseRaw, &response)
+	if err != nil || response.Error == nil {
+		return 0, false
+	}
+	return response.Error.Code, true
+}
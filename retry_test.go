@@ -0,0 +1,118 @@
+/*  Copyright 2022  Kosmas Valianos (kosmas.valianos@gmail.com)
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    https://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License. */
+
+package gojsonrpc
+
+import (
+	"context"
+	"errors"
+	"sync/atomic"
+	"testing"
+	"time"
+)
+
+// flakyTransport fails the first round trips in the transport or with a busy server before serving them with the mux
+type flakyTransport struct {
+	muxTransport
+	failures int64
+	busy     bool
+	attempts atomic.Int64
+}
+
+func (t *flakyTransport) RoundTrip(ctx context.Context, requestRaw []byte) ([]byte, error) {
+	if t.attempts.Add(1) <= t.failures {
+		if t.busy {
+			request, _ := parseRequest(requestRaw)
+			return NewErrorResponse(request.ID, &JsonServerBusy)
+		}
+		return nil, errors.New("connection reset")
+	}
+	return t.muxTransport.RoundTrip(ctx, requestRaw)
+}
+
+func TestWithRetryPolicy(t *testing.T) {
+	policy := RetryPolicy{MaxAttempts: 3, InitialBackoff: time.Millisecond, Codes: []int{ServerBusy}}
+	tests := []struct {
+		name         string
+		failures     int64
+		busy         bool
+		ctxTimeout   time.Duration
+		policy       RetryPolicy
+		wantAttempts int64
+		wantErr      bool
+	}{
+		{name: "No failure", policy: policy, wantAttempts: 1},
+		{name: "Transport failures", failures: 2, policy: policy, wantAttempts: 3},
+		{name: "Busy server", failures: 2, busy: true, policy: policy, wantAttempts: 3},
+		{name: "Too many failures", failures: 3, policy: policy, wantAttempts: 3, wantErr: true},
+		{name: "Code not retried", failures: 1, busy: true, policy: RetryPolicy{MaxAttempts: 3, InitialBackoff: time.Millisecond}, wantAttempts: 1, wantErr: true},
+		{name: "No retries", failures: 1, policy: RetryPolicy{}, wantAttempts: 1, wantErr: true},
+		{name: "Deadline before the retry", failures: 1, ctxTimeout: 50 * time.Millisecond, policy: RetryPolicy{MaxAttempts: 3, InitialBackoff: time.Second}, wantAttempts: 1, wantErr: true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			transport := &flakyTransport{muxTransport: muxTransport{mux: newTestMux(t)}, failures: tt.failures, busy: tt.busy}
+			client := NewClient(transport, WithRetryPolicy(tt.policy))
+			ctx := context.Background()
+			if tt.ctxTimeout > 0 {
+				var cancel context.CancelFunc
+				ctx, cancel = context.WithTimeout(ctx, tt.ctxTimeout)
+				defer cancel()
+			}
+
+			result, err := Call[int](ctx, client, "subtract", []int{42, 23})
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("Call() error = %v, wantErr %v", err, tt.wantErr)
+			}
+			if !tt.wantErr && result != 19 {
+				t.Errorf("Call() = %v, want 19", result)
+			}
+			if got := transport.attempts.Load(); got != tt.wantAttempts {
+				t.Errorf("Call() attempts = %v, want %v", got, tt.wantAttempts)
+			}
+		})
+	}
+}
+
+func TestRetryPolicy_backoff(t *testing.T) {
+	policy := RetryPolicy{InitialBackoff: 10 * time.Millisecond, MaxBackoff: 50 * time.Millisecond, Multiplier: 3}
+	tests := []struct {
+		name    string
+		policy  RetryPolicy
+		attempt int
+		want    time.Duration
+	}{
+		{"First attempt", policy, 1, 10 * time.Millisecond},
+		{"Second attempt", policy, 2, 30 * time.Millisecond},
+		{"Capped attempt", policy, 3, 50 * time.Millisecond},
+		{"Attempt after the cap", policy, 4, 50 * time.Millisecond},
+		{"Initial backoff above the cap", RetryPolicy{InitialBackoff: time.Second, MaxBackoff: 100 * time.Millisecond}, 1, 100 * time.Millisecond},
+		{"Default initial backoff above the cap", RetryPolicy{MaxBackoff: 10 * time.Millisecond}, 1, 10 * time.Millisecond},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := tt.policy.backoff(tt.attempt); got != tt.want {
+				t.Errorf("backoff(%v) = %v, want %v", tt.attempt, got, tt.want)
+			}
+		})
+	}
+
+	policy.Jitter = 0.5
+	for i := 0; i < 100; i++ {
+		if got := policy.backoff(1); got < 5*time.Millisecond || got > 10*time.Millisecond {
+			t.Fatalf("backoff(1) with jitter = %v, want between 5ms and 10ms", got)
+		}
+	}
+}
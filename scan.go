@@ -0,0 +1,415 @@
+/*  Copyright 2022  Kosmas Valianos (kosmas.valianos@gmail.com)
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    https://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License. */
+
+package gojsonrpc
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"strconv"
+	"strings"
+)
+
+// WithLazyParsing makes Serve locate the members of the messages with a scanner instead of decoding them with encoding/json,
+// which it falls back to for the messages the scanner cannot resolve, e.g. with escaped or differently cased member names.
+// The params passed to the handlers then reference the message passed to Serve without copying it, which must thus
+// not be modified until the handlers return
+func WithLazyParsing() MuxOption {
+	return func(m *Mux) {
+		m.lazyParsing = true
+	}
+}
+
+// envelopeMembers are the raw values of the members of a JSON-RPC message, nil when absent
+type envelopeMembers struct {
+	jsonRPC json.RawMessage
+	method  json.RawMessage
+	id      json.RawMessage
+	params  json.RawMessage
+	result  json.RawMessage
+	error   json.RawMessage
+}
+
+var envelopeMemberNames = []string{"jsonrpc", "method", "id", "params", "result", "error"}
+
+// scanEnvelope locates the members of a JSON-RPC message without building a token tree. The message must have been
+// validated with json.Valid, as by Serve, so the values are only delimited, not validated again.
+// Like encoding/json, the last of duplicated members wins.
+// Returns the envelopeMembers and true or false if the message is not an object or encoding/json is needed
+// to resolve its member names
+func scanEnvelope(messageRaw []byte) (envelopeMembers, bool) {
+	var members envelopeMembers
+	i := skipWhitespace(messageRaw, 0)
+	if i >= len(messageRaw) || messageRaw[i] != '{' {
+		return members, false
+	}
+	i = skipWhitespace(messageRaw, i+1)
+	if i < len(messageRaw) && messageRaw[i] == '}' {
+		return members, skipWhitespace(messageRaw, i+1) == len(messageRaw)
+	}
+
+	for i < len(messageRaw) {
+		// Member name
+		if messageRaw[i] != '"' {
+			return members, false
+		}
+		nameEnd := i + 1
+		for nameEnd < len(messageRaw) && messageRaw[nameEnd] != '"' {
+			if messageRaw[nameEnd] == '\\' || messageRaw[nameEnd] >= 0x80 {
+				return members, false
+			}
+			nameEnd++
+		}
+		if nameEnd >= len(messageRaw) {
+			return members, false
+		}
+		name := messageRaw[i+1 : nameEnd]
+
+		i = skipWhitespace(messageRaw, nameEnd+1)
+		if i >= len(messageRaw) || messageRaw[i] != ':' {
+			return members, false
+		}
+		valueStart := skipWhitespace(messageRaw, i+1)
+		valueEnd, ok := skipValue(messageRaw, valueStart)
+		if !ok {
+			return members, false
+		}
+		value := json.RawMessage(messageRaw[valueStart:valueEnd])
+
+		switch string(name) {
+		case "jsonrpc":
+			members.jsonRPC = value
+		case "method":
+			members.method = value
+		case "id":
+			members.id = value
+		case "params":
+			members.params = value
+		case "result":
+			members.result = value
+		case "error":
+			members.error = value
+		default:
+			for _, memberName := range envelopeMemberNames {
+				if strings.EqualFold(string(name), memberName) {
+					// encoding/json matches member names case insensitively
+					return members, false
+				}
+			}
+		}
+
+		i = skipWhitespace(messageRaw, valueEnd)
+		if i >= len(messageRaw) {
+			return members, false
+		}
+		if messageRaw[i] == '}' {
+			return members, skipWhitespace(messageRaw, i+1) == len(messageRaw)
+		}
+		if messageRaw[i] != ',' {
+			return members, false
+		}
+		i = skipWhitespace(messageRaw, i+1)
+	}
+	return members, false
+}
+
+func skipWhitespace(raw []byte, i int) int {
+	for i < len(raw) && (raw[i] == ' ' || raw[i] == '\t' || raw[i] == '\r' || raw[i] == '\n') {
+		i++
+	}
+	return i
+}
+
+// skipValue returns the index after the JSON value starting at i and true or false if it is malformed
+func skipValue(raw []byte, i int) (int, bool) {
+	if i >= len(raw) {
+		return i, false
+	}
+	switch raw[i] {
+	case '"':
+		return skipString(raw, i)
+	case '{', '[':
+		depth := 0
+		for i < len(raw) {
+			switch raw[i] {
+			case '"':
+				var ok bool
+				i, ok = skipString(raw, i)
+				if !ok {
+					return i, false
+				}
+				continue
+			case '{', '[':
+				depth++
+			case '}', ']':
+				depth--
+				if depth == 0 {
+					return i + 1, true
+				}
+			}
+			i++
+		}
+		return i, false
+	default:
+		// Numbers and literals
+		start := i
+		for i < len(raw) && raw[i] != ',' && raw[i] != '}' && raw[i] != ']' &&
+			raw[i] != ' ' && raw[i] != '\t' && raw[i] != '\r' && raw[i] != '\n' {
+			i++
+		}
+		return i, i > start
+	}
+}
+
+// exceedsDepth reports whether the objects and arrays of valid JSON are nested deeper than maxDepth,
+// the outermost one being at depth 1. It stops at the first value past the limit
+func exceedsDepth(raw []byte, maxDepth int) bool {
+	depth := 0
+	for i := 0; i < len(raw); i++ {
+		switch raw[i] {
+		case '"':
+			i, _ = skipString(raw, i)
+			i--
+		case '{', '[':
+			depth++
+			if depth > maxDepth {
+				return true
+			}
+		case '}', ']':
+			depth--
+		}
+	}
+	return false
+}
+
+// skipString returns the index after the JSON string starting at i and true or false if it is not terminated
+func skipString(raw []byte, i int) (int, bool) {
+	for i++; i < len(raw); i++ {
+		switch raw[i] {
+		case '\\':
+			i++
+		case '"':
+			return i + 1, true
+		}
+	}
+	return i, false
+}
+
+// decodeScannedString decodes the raw value of a string member like encoding/json does into a string field.
+// Returns the string or an error if the value is not a string
+func decodeScannedString(valueRaw json.RawMessage) (string, error) {
+	if valueRaw == nil || bytes.Equal(valueRaw, []byte("null")) {
+		return "", nil
+	}
+	if len(valueRaw) >= 2 && valueRaw[0] == '"' && isPlainString(valueRaw[1:len(valueRaw)-1]) {
+		content := valueRaw[1 : len(valueRaw)-1]
+		if string(content) == jsonRPCProtocol {
+			// The version of every message needs no allocation
+			return jsonRPCProtocol, nil
+		}
+		return string(content), nil
+	}
+	var value string
+	err := json.Unmarshal(valueRaw, &value)
+	return value, err
+}
+
+// isPlainString reports whether the content of a JSON string needs no unescaping nor UTF-8 validation
+func isPlainString(content []byte) bool {
+	for _, c := range content {
+		if c == '\\' || c == '"' || c < 0x20 || c >= 0x80 {
+			return false
+		}
+	}
+	return true
+}
+
+// parseScannedNotification parses a JSON-RPC notification from its scanned members like ParseNotification.
+// Returns a *notification object or an error
+func parseScannedNotification(members envelopeMembers) (*notification, error) {
+	jsonRPC, err := decodeScannedString(members.jsonRPC)
+	if err != nil {
+		return nil, err
+	}
+	method, err := decodeScannedString(members.method)
+	if err != nil {
+		return nil, err
+	}
+	if jsonRPC != jsonRPCProtocol || strings.HasPrefix(method, "rpc.") {
+		return nil, errors.New("invalid notification")
+	}
+	return &notification{JsonRPC: jsonRPC, Method: method, Params: members.params}, nil
+}
+
+// parseScannedRequest parses a JSON-RPC request from its scanned members like parseRequest.
+// Returns a *request object or a *jsonRPCError error object
+func parseScannedRequest(members envelopeMembers) (*request, *jsonRPCError) {
+	jsonRPC, err := decodeScannedString(members.jsonRPC)
+	if err != nil {
+		return nil, &JsonParseError
+	}
+	method, err := decodeScannedString(members.method)
+	if err != nil {
+		return nil, &JsonParseError
+	}
+	request := &request{JsonRPC: jsonRPC, Method: method, Params: members.params}
+
+	validID := true
+	switch jsonKind(members.id) {
+	case '"':
+		request.ID, err = decodeScannedString(members.id)
+	case '0':
+		// This is the type which json.Unmarshal() uses for JSON number
+		request.ID, err = strconv.ParseFloat(string(members.id), 64)
+	default:
+		validID = false
+	}
+	if err != nil {
+		return nil, &JsonParseError
+	}
+
+	if jsonRPC != jsonRPCProtocol || !validID {
+		return nil, &JsonInvalidRequest
+	}
+	return request, nil
+}
+
+// decodeEnvelope locates the members of a JSON-RPC message validated with json.Valid like scanEnvelope, decoding the
+// member names with encoding/json so that it resolves the messages which scanEnvelope cannot.
+// Returns the envelopeMembers and true or false if the message is not an object
+func decodeEnvelope(messageRaw []byte) (envelopeMembers, bool) {
+	var members envelopeMembers
+	decoder := json.NewDecoder(bytes.NewReader(messageRaw))
+	token, err := decoder.Token()
+	if delim, ok := token.(json.Delim); err != nil || !ok || delim != '{' {
+		return members, false
+	}
+	for decoder.More() {
+		token, err := decoder.Token()
+		if err != nil {
+			return members, false
+		}
+		name, _ := token.(string)
+		// The value follows the colon after the name
+		valueStart := skipWhitespace(messageRaw, int(decoder.InputOffset()))
+		valueStart = skipWhitespace(messageRaw, valueStart+1)
+		var value json.RawMessage
+		if err := decoder.Decode(&value); err != nil {
+			return members, false
+		}
+		valueRaw := json.RawMessage(messageRaw[valueStart:decoder.InputOffset()])
+
+		// encoding/json matches member names case insensitively, the last of duplicated members winning
+		switch {
+		case strings.EqualFold(name, "jsonrpc"):
+			members.jsonRPC = valueRaw
+		case strings.EqualFold(name, "method"):
+			members.method = valueRaw
+		case strings.EqualFold(name, "id"):
+			members.id = valueRaw
+		case strings.EqualFold(name, "params"):
+			members.params = valueRaw
+		case strings.EqualFold(name, "result"):
+			members.result = valueRaw
+		case strings.EqualFold(name, "error"):
+			members.error = valueRaw
+		}
+	}
+	return members, true
+}
+
+// locateEnvelope locates the members of a JSON-RPC message with scanEnvelope, or decodeEnvelope if it cannot.
+// Returns the envelopeMembers and true or false if the message is not valid JSON or not an object
+func locateEnvelope(messageRaw []byte) (envelopeMembers, bool) {
+	if !json.Valid(messageRaw) {
+		return envelopeMembers{}, false
+	}
+	if members, ok := scanEnvelope(messageRaw); ok {
+		return members, true
+	}
+	return decodeEnvelope(messageRaw)
+}
+
+// ParseNotificationZeroCopy is like ParseNotification but its Params reference the raw bytes without copying them,
+// which must thus not be modified, e.g. reused for the next read, while the notification is in use.
+// Returns a *notification object or an error
+func ParseNotificationZeroCopy(notificationRaw []byte) (*notification, error) {
+	members, ok := locateEnvelope(notificationRaw)
+	if !ok {
+		return ParseNotification(notificationRaw)
+	}
+	return parseScannedNotification(members)
+}
+
+// ParseRequestZeroCopy is like ParseRequest but its Params reference the raw bytes without copying them,
+// which must thus not be modified, e.g. reused for the next read, while the request is in use.
+// Returns a *request object or a *jsonRPCError error object
+func ParseRequestZeroCopy(requestRaw []byte) (*request, *jsonRPCError) {
+	members, ok := locateEnvelope(requestRaw)
+	if !ok {
+		return ParseRequest(requestRaw)
+	}
+	request, jsonRPCError := parseScannedRequest(members)
+	if jsonRPCError != nil {
+		return nil, jsonRPCError
+	}
+	if strings.HasPrefix(request.Method, "rpc.") {
+		return nil, &JsonInvalidRequest
+	}
+	return request, nil
+}
+
+// ParseResponseZeroCopy is like ParseResponse but its Result references the raw bytes without copying them,
+// which must thus not be modified, e.g. reused for the next read, while the response is in use.
+// Returns a *response object or an error
+func ParseResponseZeroCopy(responseRaw []byte) (*response, error) {
+	members, ok := locateEnvelope(responseRaw)
+	if !ok {
+		return ParseResponse(responseRaw)
+	}
+	response, err := parseScannedResponse(members)
+	if err != nil {
+		return nil, err
+	}
+	if err := response.validateEnvelope(); err != nil {
+		return nil, err
+	}
+	return response, nil
+}
+
+// parseScannedResponse decodes a JSON-RPC response from its scanned members like encoding/json does, its Result
+// referencing them, without validating it.
+// Returns a *response object or an error
+func parseScannedResponse(members envelopeMembers) (*response, error) {
+	response := &response{Result: members.result}
+	var err error
+	response.JsonRPC, err = decodeScannedString(members.jsonRPC)
+	if err != nil {
+		return nil, err
+	}
+	if members.id != nil {
+		err = json.Unmarshal(members.id, &response.ID)
+		if err != nil {
+			return nil, err
+		}
+	}
+	if members.error != nil {
+		err = json.Unmarshal(members.error, &response.Error)
+		if err != nil {
+			return nil, err
+		}
+	}
+	return response, nil
+}
@@ -0,0 +1,399 @@
+/*  Copyright 2022  Kosmas Valianos (kosmas.valianos@gmail.com)
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    https://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License. */
+
+package gojsonrpc
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"reflect"
+	"testing"
+)
+
+var scanCorpus = []string{
+	`{"jsonrpc": "2.0", "method": "subtract", "params": [42, 23], "id": 1}`,
+	`{"jsonrpc": "2.0", "method": "subtract", "params": {"subtrahend": 23, "minuend": 42}, "id": "abc"}`,
+	`{"jsonrpc": "2.0", "method": "subtract", "params": [42, 23]}`,
+	`{"jsonrpc": "2.0", "method": "subtract", "id": 1, "id": 2}`,
+	`{"jsonrpc": "2.0", "method": "subtract", "id": 1.5e3}`,
+	`{"jsonrpc": "2.0", "Method": "subtract", "id": 1}`,
+	`{"jsonrpc": "2.0", "\u006dethod": "subtract", "id": 1}`,
+	`{"jsonrpc": "2.0", "method": 1, "id": 1}`,
+	`{"jsonrpc": "2.0", "method": "subtract", "id": null}`,
+	`{"jsonrpc": "2.0", "method": "subtract", "id": [1]}`,
+	`{"jsonrpc": "1.0", "method": "subtract", "id": true}`,
+	`{"jsonrpc": "2.0", "method": "rpc.discover", "id": 1}`,
+	`{"jsonrpc": "2.0", "method": "rpc.discover"}`,
+	`{"jsonrpc": "2.0", "method": "subtract", "params": null, "id": "a\"b"}`,
+	`{"jsonrpc": "2.0", "method": "subtract", "params": "x}]", "id": 1e400}`,
+	`{"foo": {"bar": ["}", "]"]}, "jsonrpc": "2.0", "method": "subtract", "id": -0}`,
+	`{}`,
+	`[]`,
+	`"subtract"`,
+	`42`,
+}
+
+func Test_scanEnvelope(t *testing.T) {
+	tests := []struct {
+		name        string
+		messageRaw  string
+		want        envelopeMembers
+		wantScanned bool
+	}{
+		{
+			name:        "Request",
+			messageRaw:  `{"jsonrpc": "2.0", "method": "subtract", "params": [42, 23], "id": 1}`,
+			want:        envelopeMembers{jsonRPC: json.RawMessage(`"2.0"`), method: json.RawMessage(`"subtract"`), params: json.RawMessage(`[42, 23]`), id: json.RawMessage(`1`)},
+			wantScanned: true,
+		},
+		{
+			name:        "Duplicated member",
+			messageRaw:  `{"id": 1, "method": "subtract", "id" : "abc" }`,
+			want:        envelopeMembers{method: json.RawMessage(`"subtract"`), id: json.RawMessage(`"abc"`)},
+			wantScanned: true,
+		},
+		{
+			name:        "Nested values",
+			messageRaw:  `{"foo": {"bar": ["}", "\"]"]}, "method": "subtract"}`,
+			want:        envelopeMembers{method: json.RawMessage(`"subtract"`)},
+			wantScanned: true,
+		},
+		{
+			name:       "Escaped name",
+			messageRaw: `{"\u006dethod": "subtract"}`,
+		},
+		{
+			name:       "Differently cased name",
+			messageRaw: `{"Method": "subtract"}`,
+		},
+		{
+			name:       "Not an object",
+			messageRaw: `[{"method": "subtract"}]`,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, scanned := scanEnvelope([]byte(tt.messageRaw))
+			if scanned != tt.wantScanned {
+				t.Fatalf("scanEnvelope() scanned = %v, want %v", scanned, tt.wantScanned)
+			}
+			if scanned && !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("scanEnvelope() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+func FuzzScanEnvelope(f *testing.F) {
+	for _, messageRaw := range scanCorpus {
+		f.Add([]byte(messageRaw))
+	}
+	f.Fuzz(func(t *testing.T, messageRaw []byte) {
+		if !json.Valid(messageRaw) {
+			return
+		}
+		members, scanned := scanEnvelope(messageRaw)
+		if !scanned {
+			return
+		}
+
+		var parsed struct {
+			JsonRPC json.RawMessage `json:"jsonrpc"`
+			Method  json.RawMessage `json:"method"`
+			ID      json.RawMessage `json:"id"`
+			Params  json.RawMessage `json:"params"`
+			Result  json.RawMessage `json:"result"`
+			Error   json.RawMessage `json:"error"`
+		}
+		err := json.Unmarshal(messageRaw, &parsed)
+		if err != nil {
+			t.Fatalf("scanEnvelope() scanned what encoding/json rejects: %v", err)
+		}
+		want := envelopeMembers{jsonRPC: parsed.JsonRPC, method: parsed.Method, id: parsed.ID, params: parsed.Params,
+			result: parsed.Result, error: parsed.Error}
+		if !reflect.DeepEqual(members, want) {
+			t.Fatalf("scanEnvelope() = %v, want %v", members, want)
+		}
+
+		if members.id == nil {
+			got, gotErr := parseScannedNotification(members)
+			want, wantErr := ParseNotification(messageRaw)
+			if (gotErr != nil) != (wantErr != nil) || !reflect.DeepEqual(got, want) {
+				t.Fatalf("parseScannedNotification() = %v, %v, want %v, %v", got, gotErr, want, wantErr)
+			}
+			return
+		}
+		got, gotErr := parseScannedRequest(members)
+		want2, wantErr := parseRequest(messageRaw)
+		if !reflect.DeepEqual(got, want2) || !reflect.DeepEqual(gotErr, wantErr) {
+			t.Fatalf("parseScannedRequest() = %v, %v, want %v, %v", got, gotErr, want2, wantErr)
+		}
+	})
+}
+
+func FuzzDecodeEnvelope(f *testing.F) {
+	for _, messageRaw := range append(append([]string{}, scanCorpus...), scanResponseCorpus...) {
+		f.Add([]byte(messageRaw))
+	}
+	f.Fuzz(func(t *testing.T, messageRaw []byte) {
+		if !json.Valid(messageRaw) {
+			return
+		}
+		members, decoded := decodeEnvelope(messageRaw)
+		if !decoded {
+			return
+		}
+
+		var parsed struct {
+			JsonRPC json.RawMessage `json:"jsonrpc"`
+			Method  json.RawMessage `json:"method"`
+			ID      json.RawMessage `json:"id"`
+			Params  json.RawMessage `json:"params"`
+			Result  json.RawMessage `json:"result"`
+			Error   json.RawMessage `json:"error"`
+		}
+		err := json.Unmarshal(messageRaw, &parsed)
+		if err != nil {
+			t.Fatalf("decodeEnvelope() decoded what encoding/json rejects: %v", err)
+		}
+		want := envelopeMembers{jsonRPC: parsed.JsonRPC, method: parsed.Method, id: parsed.ID, params: parsed.Params,
+			result: parsed.Result, error: parsed.Error}
+		if !reflect.DeepEqual(members, want) {
+			t.Fatalf("decodeEnvelope() = %v, want %v", members, want)
+		}
+	})
+}
+
+var scanResponseCorpus = []string{
+	`{"jsonrpc": "2.0", "result": 19, "id": 1}`,
+	`{"jsonrpc": "2.0", "result": {"a": [1, "}"]}, "id": "abc"}`,
+	`{"jsonrpc": "2.0", "result": null, "id": 1}`,
+	`{"jsonrpc": "2.0", "error": {"code": -32600, "message": "Invalid Request"}, "id": null}`,
+	`{"jsonrpc": "2.0", "error": {"code": -32601, "message": "Method not found", "data": [1]}, "id": 1}`,
+	`{"jsonrpc": "2.0", "error": null, "result": 1, "id": 1}`,
+	`{"jsonrpc": "2.0", "error": "failed", "id": 1}`,
+	`{"jsonrpc": "2.0", "Result": 19, "id": 1}`,
+	`{"jsonrpc": "2.0", "r\u0065sult" : [19], "id": 1}`,
+	`{"jsonrpc": "2.0", "result": 19, "id": true}`,
+	`{"jsonrpc": 2, "result": 19, "id": 1}`,
+	`{"result": 19}`,
+	`{"jsonrpc": "2.0", "result": 19, "id": 1`,
+	`null`,
+}
+
+func FuzzParseZeroCopy(f *testing.F) {
+	for _, messageRaw := range append(append([]string{}, scanCorpus...), scanResponseCorpus...) {
+		f.Add([]byte(messageRaw))
+	}
+	f.Fuzz(func(t *testing.T, messageRaw []byte) {
+		// The zero-copy parse functions parse the messages as the copying ones, referencing the raw bytes
+		request, jsonRPCError := ParseRequestZeroCopy(messageRaw)
+		wantRequest, wantJsonRPCError := ParseRequest(messageRaw)
+		if !reflect.DeepEqual(request, wantRequest) || !reflect.DeepEqual(jsonRPCError, wantJsonRPCError) {
+			t.Fatalf("ParseRequestZeroCopy() = %v, %v, want %v, %v", request, jsonRPCError, wantRequest, wantJsonRPCError)
+		}
+		if request != nil && !referencesRaw(messageRaw, request.Params) {
+			t.Fatalf("ParseRequestZeroCopy() params %s, want a sub-slice of the raw bytes", request.Params)
+		}
+
+		notification, err := ParseNotificationZeroCopy(messageRaw)
+		wantNotification, wantErr := ParseNotification(messageRaw)
+		if (err != nil) != (wantErr != nil) || !reflect.DeepEqual(notification, wantNotification) {
+			t.Fatalf("ParseNotificationZeroCopy() = %v, %v, want %v, %v", notification, err, wantNotification, wantErr)
+		}
+		if notification != nil && !referencesRaw(messageRaw, notification.Params) {
+			t.Fatalf("ParseNotificationZeroCopy() params %s, want a sub-slice of the raw bytes", notification.Params)
+		}
+
+		response, err := ParseResponseZeroCopy(messageRaw)
+		wantResponse, wantErr := ParseResponse(messageRaw)
+		if (err != nil) != (wantErr != nil) || !reflect.DeepEqual(response, wantResponse) {
+			t.Fatalf("ParseResponseZeroCopy() = %v, %v, want %v, %v", response, err, wantResponse, wantErr)
+		}
+		if response != nil && !referencesRaw(messageRaw, response.Result) {
+			t.Fatalf("ParseResponseZeroCopy() result %s, want a sub-slice of the raw bytes", response.Result)
+		}
+	})
+}
+
+// referencesRaw reports whether the value is empty or a sub-slice of raw
+func referencesRaw(raw []byte, value json.RawMessage) bool {
+	if len(value) == 0 {
+		return true
+	}
+	for i := range raw {
+		if &raw[i] == &value[0] {
+			return i+len(value) <= len(raw)
+		}
+	}
+	return false
+}
+
+func TestWithLazyParsing(t *testing.T) {
+	ctx := context.Background()
+	mux := newTestMux(t)
+	lazyMux := NewMux(WithLazyParsing())
+	for _, method := range []string{"subtract", "database", "fail", "raw"} {
+		handler, _ := mux.handler(method)
+		err := lazyMux.Handle(method, handler)
+		if err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	for _, messageRaw := range scanCorpus {
+		got := lazyMux.Serve(ctx, []byte(messageRaw))
+		want := mux.Serve(ctx, []byte(messageRaw))
+		if !bytes.Equal(got, want) {
+			t.Errorf("Serve(%v) = %v, want %v", messageRaw, string(got), string(want))
+		}
+	}
+}
+
+func TestParse_copy(t *testing.T) {
+	// The parsed params and result stay valid once the raw bytes are reused, e.g. by the next read
+	requestRaw := []byte(`{"jsonrpc": "2.0", "method": "subtract", "params": [42, 23], "id": 1}`)
+	request, jsonRPCError := ParseRequest(requestRaw)
+	if jsonRPCError != nil {
+		t.Fatal(jsonRPCError)
+	}
+	notification, err := ParseNotification(requestRaw)
+	if err != nil {
+		t.Fatal(err)
+	}
+	responseRaw := []byte(`{"jsonrpc": "2.0", "result": [19], "id": 1}`)
+	response, err := ParseResponse(responseRaw)
+	if err != nil {
+		t.Fatal(err)
+	}
+	copy(requestRaw, bytes.Repeat([]byte(" "), len(requestRaw)))
+	copy(responseRaw, bytes.Repeat([]byte(" "), len(responseRaw)))
+
+	tests := []struct {
+		name  string
+		value json.RawMessage
+		want  string
+	}{
+		{name: "ParseRequest", value: request.Params, want: "[42, 23]"},
+		{name: "ParseNotification", value: notification.Params, want: "[42, 23]"},
+		{name: "ParseResponse", value: response.Result, want: "[19]"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if string(tt.value) != tt.want {
+				t.Errorf("%v() = %s, want %v", tt.name, tt.value, tt.want)
+			}
+		})
+	}
+}
+
+func TestUnmarshalParams(t *testing.T) {
+	request, jsonRPCError := ParseRequest([]byte(`{"jsonrpc": "2.0", "method": "subtract", "params": [42, 23], "id": 1}`))
+	if jsonRPCError != nil {
+		t.Fatal(jsonRPCError)
+	}
+	var params [2]int
+	if err := request.UnmarshalParams(&params); err != nil || params != [2]int{42, 23} {
+		t.Errorf("UnmarshalParams() = %v, %v, want %v", params, err, [2]int{42, 23})
+	}
+
+	notification, err := ParseNotification([]byte(`{"jsonrpc": "2.0", "method": "update"}`))
+	if err != nil {
+		t.Fatal(err)
+	}
+	params = [2]int{1, 2}
+	if err := notification.UnmarshalParams(&params); err != nil || params != [2]int{1, 2} {
+		t.Errorf("UnmarshalParams() = %v, %v, want the params untouched", params, err)
+	}
+}
+
+func TestUnmarshalResult(t *testing.T) {
+	response, err := ParseResponse([]byte(`{"jsonrpc": "2.0", "result": 19, "id": 1}`))
+	if err != nil {
+		t.Fatal(err)
+	}
+	var result int
+	if err := response.UnmarshalResult(&result); err != nil || result != 19 {
+		t.Errorf("UnmarshalResult() = %v, %v, want %v", result, err, 19)
+	}
+
+	response, err = ParseResponse([]byte(`{"jsonrpc": "2.0", "error": {"code": -32601, "message": "Method not found"}, "id": 1}`))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := response.UnmarshalResult(&result); !reflect.DeepEqual(err, &JsonMethodNotFound) {
+		t.Errorf("UnmarshalResult() error = %v, want %v", err, &JsonMethodNotFound)
+	}
+}
+
+func BenchmarkWithLazyParsing(b *testing.B) {
+	requestRaw := []byte(`{"jsonrpc": "2.0", "method": "raw", "params": {"subtrahend": 23, "minuend": 42, "history": [1, 2, 3, 4, 5, 6, 7, 8]}, "id": "2b6f1a8e-8d0a-4c4b-9d8e-3f1b2c7a9e10"}`)
+	handler := HandlerFunc(func(ctx context.Context, params json.RawMessage) (any, error) {
+		return nil, nil
+	})
+	for _, tt := range []struct {
+		name string
+		mux  *Mux
+	}{
+		{name: "WithLazyParsing", mux: NewMux(WithLazyParsing())},
+		{name: "encoding/json", mux: NewMux()},
+	} {
+		if err := tt.mux.Handle("raw", handler); err != nil {
+			b.Fatal(err)
+		}
+		b.Run(tt.name, func(b *testing.B) {
+			b.ReportAllocs()
+			for i := 0; i < b.N; i++ {
+				_ = tt.mux.Serve(context.Background(), requestRaw)
+			}
+		})
+	}
+}
+
+func BenchmarkParseResponseZeroCopy(b *testing.B) {
+	responseRaw := []byte(`{"jsonrpc": "2.0", "result": {"subtrahend": 23, "minuend": 42, "history": [1, 2, 3, 4, 5, 6, 7, 8]}, "id": "2b6f1a8e-8d0a-4c4b-9d8e-3f1b2c7a9e10"}`)
+
+	b.Run("ParseResponseZeroCopy", func(b *testing.B) {
+		b.ReportAllocs()
+		for i := 0; i < b.N; i++ {
+			_, _ = ParseResponseZeroCopy(responseRaw)
+		}
+	})
+	b.Run("ParseResponse", func(b *testing.B) {
+		b.ReportAllocs()
+		for i := 0; i < b.N; i++ {
+			_, _ = ParseResponse(responseRaw)
+		}
+	})
+}
+
+func Test_exceedsDepth(t *testing.T) {
+	tests := []struct {
+		raw  string
+		want bool
+	}{
+		{raw: `42`, want: false},
+		{raw: `{"a":[1,{"b":2}]}`, want: false},
+		{raw: `{"a":"[[[[","b":"\"{{{{"}`, want: false},
+		{raw: `{"a":[1,{"b":[]}]}`, want: true},
+		{raw: `[[[[]]]]`, want: true},
+	}
+	for _, tt := range tests {
+		if got := exceedsDepth([]byte(tt.raw), 3); got != tt.want {
+			t.Errorf("exceedsDepth(%v) = %v, want %v", tt.raw, got, tt.want)
+		}
+	}
+}
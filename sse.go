@@ -0,0 +1,371 @@
+/*  Copyright 2022  Kosmas Valianos (kosmas.valianos@gmail.com)
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    https://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License. */
+
+package gojsonrpc
+
+import (
+	"bufio"
+	"bytes"
+	"context"
+	"crypto/rand"
+	"errors"
+	"fmt"
+	"net/http"
+	"strings"
+	"sync"
+	"time"
+)
+
+// EventSessionHeader is the HTTP header giving the ID of the event session of the client in its requests
+const EventSessionHeader = "Jsonrpc-Session"
+
+const (
+	// eventSessionBuffer is the number of notifications an event session queues before it is dropped as too slow
+	eventSessionBuffer = 64
+	// eventStreamKeepalive is the interval of the comments keeping the idle event streams open through the proxies
+	eventStreamKeepalive = 15 * time.Second
+)
+
+// ErrEventSessionClosed is returned when notifying an event session whose client is gone
+var ErrEventSessionClosed = errors.New("event session closed")
+
+// EventStream streams the notifications of the server to the HTTP clients listening with Server-Sent Events,
+// for the clients which cannot use WebSockets. The clients keep posting their requests. It is safe for concurrent use
+type EventStream struct {
+	mu       sync.Mutex
+	sessions map[string]*EventSession
+}
+
+// EventSession is the event stream of a client
+type EventSession struct {
+	id        string
+	events    chan []byte
+	done      chan struct{}
+	closeOnce sync.Once
+}
+
+// NewEventStream creates an EventStream without any sessions.
+// Returns a *EventStream object
+func NewEventStream() *EventStream {
+	return &EventStream{sessions: make(map[string]*EventSession)}
+}
+
+// WithEventStream serves the HTTP GET requests accepting text/event-stream with the stream. Every client listening
+// receives first a "session" event with the ID of its session and then the notifications as "message" events.
+// The requests posted with the ID in the EventSessionHeader are served with the session in their context
+func WithEventStream(stream *EventStream) HTTPHandlerOption {
+	return func(h *httpHandler) {
+		h.events = stream
+	}
+}
+
+// EventSessionFromContext returns the event session of the client which sent the request or notification
+// being served, if any
+func EventSessionFromContext(ctx context.Context) (*EventSession, bool) {
+	session, ok := ctx.Value(eventSessionContextKey).(*EventSession)
+	return session, ok
+}
+
+// Session returns the open event session with the ID
+func (s *EventStream) Session(id string) (*EventSession, bool) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	session, ok := s.sessions[id]
+	return session, ok
+}
+
+// Notify sends a notification of the method with the params to all the open event sessions
+func (s *EventStream) Notify(method string, params any) error {
+	notificationRaw, err := NewNotification(method, params)
+	if err != nil {
+		return err
+	}
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	for _, session := range s.sessions {
+		session.send(notificationRaw)
+	}
+	return nil
+}
+
+// open opens a new event session
+func (s *EventStream) open() (*EventSession, error) {
+	var idRaw [16]byte
+	_, err := rand.Read(idRaw[:])
+	if err != nil {
+		return nil, err
+	}
+	session := &EventSession{
+		id:     fmt.Sprintf("%x", idRaw),
+		events: make(chan []byte, eventSessionBuffer),
+		done:   make(chan struct{}),
+	}
+	s.mu.Lock()
+	s.sessions[session.id] = session
+	s.mu.Unlock()
+	return session, nil
+}
+
+// close closes the event session and forgets it
+func (s *EventStream) close(session *EventSession) {
+	session.close()
+	s.mu.Lock()
+	delete(s.sessions, session.id)
+	s.mu.Unlock()
+}
+
+// serve streams the events of a new session until the client goes away
+func (s *EventStream) serve(w http.ResponseWriter, r *http.Request) {
+	flusher, ok := w.(http.Flusher)
+	if !ok {
+		http.Error(w, "streaming unsupported", http.StatusInternalServerError)
+		return
+	}
+	session, err := s.open()
+	if err != nil {
+		http.Error(w, http.StatusText(http.StatusInternalServerError), http.StatusInternalServerError)
+		return
+	}
+	defer s.close(session)
+
+	w.Header().Set("Content-Type", "text/event-stream")
+	w.Header().Set("Cache-Control", "no-cache")
+	w.WriteHeader(http.StatusOK)
+	_, err = fmt.Fprintf(w, "event: session\ndata: %s\n\n", session.id)
+	if err != nil {
+		return
+	}
+	flusher.Flush()
+
+	keepalive := time.NewTicker(eventStreamKeepalive)
+	defer keepalive.Stop()
+	for {
+		select {
+		case <-r.Context().Done():
+			return
+		case <-session.done:
+			return
+		case notificationRaw := <-session.events:
+			_, err = fmt.Fprintf(w, "data: %s\n\n", bytes.TrimSuffix(notificationRaw, []byte{'\n'}))
+		case <-keepalive.C:
+			_, err = fmt.Fprint(w, ": keepalive\n\n")
+		}
+		if err != nil {
+			return
+		}
+		flusher.Flush()
+	}
+}
+
+// ID returns the ID of the event session
+func (session *EventSession) ID() string {
+	return session.id
+}
+
+// Notify sends a notification of the method with the params to the client. A client too slow to keep up
+// with its notifications is dropped
+func (session *EventSession) Notify(method string, params any) error {
+	notificationRaw, err := NewNotification(method, params)
+	if err != nil {
+		return err
+	}
+	if !session.send(notificationRaw) {
+		return ErrEventSessionClosed
+	}
+	return nil
+}
+
+// Done returns a channel closed once the client is gone
+func (session *EventSession) Done() <-chan struct{} {
+	return session.done
+}
+
+// send queues a notification, closing the session if its queue is full.
+// Returns whether the notification was queued
+func (session *EventSession) send(notificationRaw []byte) bool {
+	select {
+	case <-session.done:
+		return false
+	default:
+	}
+	select {
+	case session.events <- notificationRaw:
+		return true
+	default:
+		session.close()
+		return false
+	}
+}
+
+func (session *EventSession) close() {
+	session.closeOnce.Do(func() {
+		close(session.done)
+	})
+}
+
+// acceptsEventStream returns whether the request accepts a text/event-stream response
+func acceptsEventStream(header http.Header) bool {
+	for _, accepted := range strings.Split(header.Get("Accept"), ",") {
+		mediaType, _, _ := strings.Cut(accepted, ";")
+		if strings.EqualFold(strings.TrimSpace(mediaType), "text/event-stream") {
+			return true
+		}
+	}
+	return false
+}
+
+// EventSource listens to the event stream of a JSON-RPC over HTTP server
+type EventSource struct {
+	sessionID string
+	cancel    context.CancelFunc
+	done      chan struct{}
+	err       error
+}
+
+// DialEventStream listens to the event stream at the url, configured by the options of the HTTP transport, and serves
+// the notifications received with the mux in their order. The requests to the server should carry the session ID in
+// the EventSessionHeader, e.g. with WithHTTPHeader(EventSessionHeader, source.SessionID()).
+// Returns a *EventSource object or an error
+func DialEventStream(ctx context.Context, url string, mux *Mux, options ...HTTPTransportOption) (*EventSource, error) {
+	transport := NewHTTPTransport(url, options...)
+	streamCtx, cancel := context.WithCancel(context.Background())
+	dialed := make(chan struct{})
+	defer close(dialed)
+	go func() {
+		// The ctx bounds the dialing only
+		select {
+		case <-ctx.Done():
+			cancel()
+		case <-dialed:
+		}
+	}()
+
+	httpRequest, err := http.NewRequestWithContext(streamCtx, http.MethodGet, url, nil)
+	if err != nil {
+		cancel()
+		return nil, err
+	}
+	for key, values := range transport.header {
+		httpRequest.Header[key] = values
+	}
+	httpRequest.Header.Set("Accept", "text/event-stream")
+	httpResponse, err := transport.client.Do(httpRequest)
+	if err != nil {
+		cancel()
+		return nil, err
+	}
+	if httpResponse.StatusCode != http.StatusOK {
+		httpResponse.Body.Close()
+		cancel()
+		return nil, fmt.Errorf("unexpected HTTP status %s", httpResponse.Status)
+	}
+
+	scanner := bufio.NewScanner(httpResponse.Body)
+	scanner.Buffer(nil, defaultMaxBodySize)
+	event, data, err := readEvent(scanner)
+	if err == nil && event != "session" {
+		err = errors.New("missing event session")
+	}
+	if err != nil {
+		httpResponse.Body.Close()
+		cancel()
+		if ctx.Err() != nil {
+			return nil, ctx.Err()
+		}
+		return nil, err
+	}
+
+	source := &EventSource{sessionID: string(data), cancel: cancel, done: make(chan struct{})}
+	if mux == nil {
+		mux = NewMux()
+	}
+	go func() {
+		defer close(source.done)
+		defer httpResponse.Body.Close()
+		for {
+			event, data, err := readEvent(scanner)
+			if err != nil {
+				if streamCtx.Err() == nil {
+					source.err = err
+				}
+				return
+			}
+			if event == "message" {
+				mux.Serve(streamCtx, data)
+			}
+		}
+	}()
+	return source, nil
+}
+
+// SessionID returns the ID of the event session given by the server
+func (s *EventSource) SessionID() string {
+	return s.sessionID
+}
+
+// Done returns a channel closed once the event stream has ended
+func (s *EventSource) Done() <-chan struct{} {
+	return s.done
+}
+
+// Err returns why the event stream ended, nil if it was closed.
+// It must only be called once Done is closed
+func (s *EventSource) Err() error {
+	return s.err
+}
+
+// Close stops listening to the event stream
+func (s *EventSource) Close() error {
+	s.cancel()
+	<-s.done
+	return nil
+}
+
+// readEvent reads the next event of a Server-Sent Events stream, skipping the comments.
+// Returns the type of the event, "message" by default, and its data or an error
+func readEvent(scanner *bufio.Scanner) (string, []byte, error) {
+	event := ""
+	var data []byte
+	dispatch := false
+	for scanner.Scan() {
+		line := scanner.Text()
+		if line == "" {
+			if !dispatch {
+				continue
+			}
+			if event == "" {
+				event = "message"
+			}
+			return event, data, nil
+		}
+		field, value, _ := strings.Cut(line, ":")
+		value = strings.TrimPrefix(value, " ")
+		switch field {
+		case "event":
+			event = value
+			dispatch = true
+		case "data":
+			if data != nil {
+				data = append(data, '\n')
+			}
+			data = append(data, value...)
+			dispatch = true
+		}
+	}
+	err := scanner.Err()
+	if err == nil {
+		err = errors.New("event stream ended")
+	}
+	return "", nil, err
+}
@@ -0,0 +1,194 @@
+/*  Copyright 2022  Kosmas Valianos (kosmas.valianos@gmail.com)
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    https://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License. */
+
+package gojsonrpc
+
+import (
+	"bufio"
+	"context"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestDialEventStream(t *testing.T) {
+	stream := NewEventStream()
+	mux := NewMux()
+	err := HandleFunc(mux, "greet", func(ctx context.Context, params [1]string) (bool, error) {
+		session, ok := EventSessionFromContext(ctx)
+		if !ok {
+			return false, nil
+		}
+		return true, session.Notify("greeting", []string{"hello " + params[0]})
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	server := httptest.NewServer(HTTPHandler(mux, WithEventStream(stream)))
+	defer server.Close()
+
+	greetings := make(chan string, 2)
+	clientMux := NewMux()
+	err = HandleFunc(clientMux, "greeting", func(ctx context.Context, params [1]string) (any, error) {
+		greetings <- params[0]
+		return nil, nil
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+	source, err := DialEventStream(ctx, server.URL, clientMux)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer source.Close()
+	if _, ok := stream.Session(source.SessionID()); !ok {
+		t.Fatalf("Session(%q) not found", source.SessionID())
+	}
+
+	client := NewClient(NewHTTPTransport(server.URL, WithHTTPHeader(EventSessionHeader, source.SessionID())))
+	var greeted bool
+	err = client.Call(ctx, "greet", []string{"alice"}, &greeted)
+	if err != nil || !greeted {
+		t.Fatalf("Call() = %v, %v, want %v", greeted, err, true)
+	}
+	err = stream.Notify("greeting", []string{"hello all"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, want := range []string{"hello alice", "hello all"} {
+		select {
+		case got := <-greetings:
+			if got != want {
+				t.Errorf("greeting = %q, want %q", got, want)
+			}
+		case <-time.After(5 * time.Second):
+			t.Fatalf("greeting %q not received", want)
+		}
+	}
+
+	err = source.Close()
+	if err != nil {
+		t.Errorf("Close() = %v, want nil", err)
+	}
+	if source.Err() != nil {
+		t.Errorf("Err() = %v, want nil", source.Err())
+	}
+}
+
+func TestEventStream_serve(t *testing.T) {
+	stream := NewEventStream()
+	server := httptest.NewServer(HTTPHandler(newTestMux(t), WithEventStream(stream)))
+	defer server.Close()
+
+	request, err := http.NewRequest(http.MethodGet, server.URL, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	request.Header.Set("Accept", "text/event-stream")
+	response, err := http.DefaultClient.Do(request)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer response.Body.Close()
+	if contentType := response.Header.Get("Content-Type"); contentType != "text/event-stream" {
+		t.Errorf("Content-Type = %q, want %q", contentType, "text/event-stream")
+	}
+	scanner := bufio.NewScanner(response.Body)
+	event, id, err := readEvent(scanner)
+	if err != nil || event != "session" {
+		t.Fatalf("readEvent() = %q, %q, %v, want the session", event, id, err)
+	}
+	session, ok := stream.Session(string(id))
+	if !ok {
+		t.Fatalf("Session(%q) not found", id)
+	}
+	err = session.Notify("update", []int{1, 2})
+	if err != nil {
+		t.Fatal(err)
+	}
+	event, data, err := readEvent(scanner)
+	want := `{"jsonrpc":"2.0","method":"update","params":[1,2]}`
+	if err != nil || event != "message" || string(data) != want {
+		t.Errorf("readEvent() = %q, %s, %v, want %q, %s", event, data, err, "message", want)
+	}
+
+	// Without text/event-stream the GET requests are not allowed
+	response, err = http.Get(server.URL)
+	if err != nil {
+		t.Fatal(err)
+	}
+	response.Body.Close()
+	if response.StatusCode != http.StatusMethodNotAllowed || response.Header.Get("Allow") != "GET, POST" {
+		t.Errorf("GET = %v, Allow %q, want %v, %q", response.StatusCode, response.Header.Get("Allow"),
+			http.StatusMethodNotAllowed, "GET, POST")
+	}
+}
+
+func TestEventSession_Notify(t *testing.T) {
+	stream := NewEventStream()
+	session, err := stream.open()
+	if err != nil {
+		t.Fatal(err)
+	}
+	for i := 0; i < eventSessionBuffer; i++ {
+		err = session.Notify("update", nil)
+		if err != nil {
+			t.Fatalf("Notify() = %v, want nil", err)
+		}
+	}
+	// A client falling behind is dropped
+	err = session.Notify("update", nil)
+	if !errors.Is(err, ErrEventSessionClosed) {
+		t.Errorf("Notify() = %v, want %v", err, ErrEventSessionClosed)
+	}
+	select {
+	case <-session.Done():
+	default:
+		t.Error("Done() not closed")
+	}
+}
+
+func Test_readEvent(t *testing.T) {
+	tests := []struct {
+		name      string
+		stream    string
+		wantEvent string
+		wantData  string
+		wantErr   bool
+	}{
+		{"message", "data: {}\n\n", "message", "{}", false},
+		{"named", "event: session\ndata: 42\n\n", "session", "42", false},
+		{"comments", ": keepalive\n\n: keepalive\ndata: {}\n\n", "message", "{}", false},
+		{"multi-line", "data: [1,\ndata: 2]\n\n", "message", "[1,\n2]", false},
+		{"no space", "data:{}\n\n", "message", "{}", false},
+		{"ended", "data: {}\n", "", "", true},
+		{"empty", "", "", "", true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			event, data, err := readEvent(bufio.NewScanner(strings.NewReader(tt.stream)))
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("readEvent() error = %v, wantErr %v", err, tt.wantErr)
+			}
+			if event != tt.wantEvent || string(data) != tt.wantData {
+				t.Errorf("readEvent() = %q, %q, want %q, %q", event, data, tt.wantEvent, tt.wantData)
+			}
+		})
+	}
+}
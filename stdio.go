@@ -0,0 +1,139 @@
+/*  Copyright 2022  Kosmas Valianos (kosmas.valianos@gmail.com)
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    https://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License. */
+
+package gojsonrpc
+
+import (
+	"io"
+	"os"
+	"os/exec"
+	"sync"
+)
+
+// stdioStream is the stream of the standard input and output of the current process
+type stdioStream struct {
+	io.Reader
+	io.Writer
+	closers []io.Closer
+}
+
+// Close closes the input and the output
+func (s *stdioStream) Close() error {
+	var firstErr error
+	for _, closer := range s.closers {
+		if err := closer.Close(); err != nil && firstErr == nil {
+			firstErr = err
+		}
+	}
+	return firstErr
+}
+
+// stdioConfig is the configuration of the stdio transports
+type stdioConfig struct {
+	clientOptions  []ClientOption
+	framing        Framing
+	maxMessageSize int
+}
+
+// StdioOption configures a stdio transport
+type StdioOption func(*stdioConfig)
+
+// WithStdioClientOptions configures the Client of the Conn calling the other process
+func WithStdioClientOptions(options ...ClientOption) StdioOption {
+	return func(c *stdioConfig) {
+		c.clientOptions = append(c.clientOptions, options...)
+	}
+}
+
+// WithStdioFraming sets the Framing of the messages, NewlineFraming by default. Language servers use ContentLengthFraming
+func WithStdioFraming(framing Framing) StdioOption {
+	return func(c *stdioConfig) {
+		c.framing = framing
+	}
+}
+
+// WithStdioMaxMessageSize sets the size limit in bytes of the received messages, 1MB by default. The Conn ends
+// with ErrMessageTooLarge once a larger message arrives, without buffering it. A zero or negative size restores the default
+func WithStdioMaxMessageSize(size int) StdioOption {
+	return func(c *stdioConfig) {
+		c.maxMessageSize = size
+	}
+}
+
+func newStdioConfig(options []StdioOption) *stdioConfig {
+	config := &stdioConfig{}
+	for _, option := range options {
+		option(config)
+	}
+	if config.maxMessageSize <= 0 {
+		config.maxMessageSize = defaultMaxBodySize
+	}
+	return config
+}
+
+// NewStdioConn creates a Conn over the standard input and output of the current process, configured by the options,
+// e.g. for a plugin or a language server spawned by its client. It serves the requests and notifications read on stdin
+// with the mux, which may be nil, and writes to stdout, which must thus not be written to by anything else.
+// Returns a *Conn object
+func NewStdioConn(mux *Mux, options ...StdioOption) *Conn {
+	config := newStdioConfig(options)
+	stream := &stdioStream{Reader: os.Stdin, Writer: os.Stdout, closers: []io.Closer{os.Stdin, os.Stdout}}
+	return NewConn(newStreamConn(stream, config.framing, config.maxMessageSize), mux, config.clientOptions...)
+}
+
+// commandStream is the stream of the standard input and output of a subprocess
+type commandStream struct {
+	io.ReadCloser
+	stdin    io.WriteCloser
+	cmd      *exec.Cmd
+	waitOnce sync.Once
+	waitErr  error
+}
+
+func (s *commandStream) Write(p []byte) (int, error) {
+	return s.stdin.Write(p)
+}
+
+// Close closes the standard input of the subprocess and waits for it to exit
+func (s *commandStream) Close() error {
+	s.waitOnce.Do(func() {
+		s.stdin.Close()
+		s.waitErr = s.cmd.Wait()
+	})
+	return s.waitErr
+}
+
+// StartCommand starts the subprocess and creates a Conn over its standard input and output, configured by the options,
+// e.g. to drive a plugin or a language server. It serves the requests and notifications of the subprocess with the mux,
+// which may be nil. The standard error of the subprocess is left as configured by cmd.
+// Closing the Conn closes the standard input of the subprocess and waits for it to exit.
+// Returns a *Conn object or an error
+func StartCommand(cmd *exec.Cmd, mux *Mux, options ...StdioOption) (*Conn, error) {
+	config := newStdioConfig(options)
+	stdin, err := cmd.StdinPipe()
+	if err != nil {
+		return nil, err
+	}
+	stdout, err := cmd.StdoutPipe()
+	if err != nil {
+		stdin.Close()
+		return nil, err
+	}
+	err = cmd.Start()
+	if err != nil {
+		return nil, err
+	}
+	stream := &commandStream{ReadCloser: stdout, stdin: stdin, cmd: cmd}
+	return NewConn(newStreamConn(stream, config.framing, config.maxMessageSize), mux, config.clientOptions...), nil
+}
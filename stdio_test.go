@@ -0,0 +1,79 @@
+/*  Copyright 2022  Kosmas Valianos (kosmas.valianos@gmail.com)
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    https://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License. */
+
+package gojsonrpc
+
+import (
+	"context"
+	"os"
+	"os/exec"
+	"testing"
+	"time"
+)
+
+// TestStdioHelperProcess is the subprocess of TestStartCommand, serving the test mux over its standard input and output
+func TestStdioHelperProcess(t *testing.T) {
+	if os.Getenv("GOJSONRPC_STDIO_HELPER") != "1" {
+		t.Skip("only run as the subprocess of TestStartCommand")
+	}
+	mux := newTestMux(t)
+	err := HandleFunc(mux, "callback", func(ctx context.Context, params any) (string, error) {
+		conn, _ := ConnFromContext(ctx)
+		var name string
+		err := conn.Call(ctx, "whoami", nil, &name)
+		return "hello " + name, err
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	conn := NewStdioConn(mux)
+	<-conn.Done()
+	os.Exit(0)
+}
+
+func TestStartCommand(t *testing.T) {
+	cmd := exec.Command(os.Args[0], "-test.run=^TestStdioHelperProcess$")
+	cmd.Env = append(os.Environ(), "GOJSONRPC_STDIO_HELPER=1")
+	cmd.Stderr = os.Stderr
+	mux := NewMux()
+	err := HandleFunc(mux, "whoami", func(ctx context.Context, params any) (string, error) {
+		return "parent", nil
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	conn, err := StartCommand(cmd, mux)
+	if err != nil {
+		t.Fatalf("StartCommand() = %v, want nil", err)
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	defer cancel()
+	result, err := Call[int](ctx, conn.Client(), "subtract", []int{42, 23})
+	if err != nil || result != 19 {
+		t.Errorf("Call() = %v, %v, want 19", result, err)
+	}
+	greeting, err := Call[string](ctx, conn.Client(), "callback", nil)
+	if err != nil || greeting != "hello parent" {
+		t.Errorf("Call() = %v, %v, want hello parent", greeting, err)
+	}
+
+	err = conn.Close()
+	if err != nil {
+		t.Errorf("Close() = %v, want nil", err)
+	}
+	if !cmd.ProcessState.Exited() || cmd.ProcessState.ExitCode() != 0 {
+		t.Errorf("subprocess state = %v, want exited with 0", cmd.ProcessState)
+	}
+}
@@ -0,0 +1,161 @@
+/*  Copyright 2022  Kosmas Valianos (kosmas.valianos@gmail.com)
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    https://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License. */
+
+package gojsonrpc
+
+import (
+	"bufio"
+	"context"
+	"errors"
+	"io"
+	"net"
+	"sync"
+)
+
+// Decoder reads a sequence of JSON-RPC messages from a stream delimited by a Framing, one per line by default as
+// in NDJSON. It buffers the stream, which must thus not be read by anything else
+type Decoder struct {
+	reader         *bufio.Reader
+	framing        Framing
+	maxMessageSize int
+}
+
+// NewDecoder creates a Decoder reading the messages from r delimited by the framing, NewlineFraming if nil,
+// of up to 1MB each.
+// Returns a *Decoder object
+func NewDecoder(r io.Reader, framing Framing) *Decoder {
+	if framing == nil {
+		framing = NewlineFraming
+	}
+	return &Decoder{reader: bufio.NewReader(r), framing: framing, maxMessageSize: defaultMaxBodySize}
+}
+
+// SetMaxMessageSize sets the size limit in bytes of the messages, 1MB by default. A larger message is not buffered
+// but fails with ErrMessageTooLarge, see Decode. A zero or negative size restores the default
+func (d *Decoder) SetMaxMessageSize(size int) {
+	if size <= 0 {
+		size = defaultMaxBodySize
+	}
+	d.maxMessageSize = size
+}
+
+// Decode reads the next message of any kind without parsing it. ErrMessageTooLarge is recoverable with the framings
+// of the package: the message is skipped and the next Decode reads the following one. Any other error is final.
+// Returns the raw bytes of the message or an error, io.EOF once the stream ends
+func (d *Decoder) Decode() ([]byte, error) {
+	return d.framing.ReadMessage(d.reader, d.maxMessageSize)
+}
+
+// DecodeRequest reads and parses the next message as a request, see ParseRequest.
+// Returns a *request object or an error, a *jsonRPCError if the message is not a valid request
+func (d *Decoder) DecodeRequest() (*request, error) {
+	requestRaw, err := d.Decode()
+	if err != nil {
+		return nil, err
+	}
+	request, jsonRPCError := ParseRequest(requestRaw)
+	if jsonRPCError != nil {
+		return nil, jsonRPCError
+	}
+	return request, nil
+}
+
+// DecodeNotification reads and parses the next message as a notification, see ParseNotification.
+// Returns a *notification object or an error
+func (d *Decoder) DecodeNotification() (*notification, error) {
+	notificationRaw, err := d.Decode()
+	if err != nil {
+		return nil, err
+	}
+	return ParseNotification(notificationRaw)
+}
+
+// DecodeResponse reads and parses the next message as a response, see ParseResponse.
+// Returns a *response object or an error
+func (d *Decoder) DecodeResponse() (*response, error) {
+	responseRaw, err := d.Decode()
+	if err != nil {
+		return nil, err
+	}
+	return ParseResponse(responseRaw)
+}
+
+// Encoder writes a sequence of JSON-RPC messages to a stream delimited by a Framing, one per line by default as in NDJSON.
+// It is safe for concurrent use, each message being written at once
+type Encoder struct {
+	mu      sync.Mutex
+	writer  io.Writer
+	framing Framing
+}
+
+// NewEncoder creates an Encoder writing the messages to w delimited by the framing, NewlineFraming if nil.
+// Returns a *Encoder object
+func NewEncoder(w io.Writer, framing Framing) *Encoder {
+	if framing == nil {
+		framing = NewlineFraming
+	}
+	return &Encoder{writer: w, framing: framing}
+}
+
+// Encode writes a message created with the constructors, e.g. NewRequest or NewResultResponse
+func (e *Encoder) Encode(messageRaw []byte) error {
+	e.mu.Lock()
+	defer e.mu.Unlock()
+	return e.framing.WriteMessage(e.writer, messageRaw)
+}
+
+// ErrMessageTooLarge is returned when reading a message larger than the size limit of a stream or a connection
+var ErrMessageTooLarge = errors.New("message too large")
+
+// streamConn is a MessageConn over a stream delimiting the messages with a Framing
+type streamConn struct {
+	rwc     io.ReadWriteCloser
+	decoder *Decoder
+	encoder *Encoder
+	writeMu sync.Mutex
+}
+
+// NewStreamConn makes a MessageConn of any stream, e.g. a net.Conn, a serial port, a TLS-wrapped stream or an SSH channel,
+// delimiting the messages with the framing, NewlineFraming if nil, of up to 1MB each. It is turned into a Conn by NewConn.
+// Returns a MessageConn
+func NewStreamConn(rwc io.ReadWriteCloser, framing Framing) MessageConn {
+	return newStreamConn(rwc, framing, defaultMaxBodySize)
+}
+
+func newStreamConn(rwc io.ReadWriteCloser, framing Framing, maxMessageSize int) *streamConn {
+	decoder := NewDecoder(rwc, framing)
+	decoder.maxMessageSize = maxMessageSize
+	return &streamConn{rwc: rwc, decoder: decoder, encoder: NewEncoder(rwc, framing)}
+}
+
+// WriteMessage writes a message with its framing. A net.Conn is written until the deadline of ctx if any
+func (c *streamConn) WriteMessage(ctx context.Context, messageRaw []byte) error {
+	c.writeMu.Lock()
+	defer c.writeMu.Unlock()
+	if conn, ok := c.rwc.(net.Conn); ok {
+		deadline, _ := ctx.Deadline()
+		_ = conn.SetWriteDeadline(deadline)
+	}
+	return c.encoder.Encode(messageRaw)
+}
+
+// ReadMessage reads the next message with its framing
+func (c *streamConn) ReadMessage() ([]byte, error) {
+	return c.decoder.Decode()
+}
+
+// Close closes the stream
+func (c *streamConn) Close() error {
+	return c.rwc.Close()
+}
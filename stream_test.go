@@ -0,0 +1,257 @@
+/*  Copyright 2022  Kosmas Valianos (kosmas.valianos@gmail.com)
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    https://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License. */
+
+package gojsonrpc
+
+import (
+	"bufio"
+	"bytes"
+	"context"
+	"errors"
+	"io"
+	"net"
+	"strings"
+	"sync"
+	"testing"
+	"testing/iotest"
+)
+
+func TestDecoder(t *testing.T) {
+	stream := `{"jsonrpc":"2.0","method":"subtract","params":[42,23],"id":1}
+{"jsonrpc":"2.0","method":"update","params":[1,2,3]}
+
+{"jsonrpc":"2.0","result":19,"id":1}
+{"jsonrpc":"1.0","method":"subtract","id":2}
+`
+	decoder := NewDecoder(strings.NewReader(stream), nil)
+
+	request, err := decoder.DecodeRequest()
+	if err != nil || request.Method != "subtract" {
+		t.Errorf("DecodeRequest() = %v, %v, want the subtract request", request, err)
+	}
+	notification, err := decoder.DecodeNotification()
+	if err != nil || notification.Method != "update" {
+		t.Errorf("DecodeNotification() = %v, %v, want the update notification", notification, err)
+	}
+	response, err := decoder.DecodeResponse()
+	if err != nil || string(response.Result) != "19" {
+		t.Errorf("DecodeResponse() = %v, %v, want the result 19", response, err)
+	}
+	_, err = decoder.DecodeRequest()
+	if jsonRPCError, ok := AsJsonRPCError(err); !ok || jsonRPCError.Code != InvalidRequest {
+		t.Errorf("DecodeRequest() = %v, want %v", err, &JsonInvalidRequest)
+	}
+	_, err = decoder.Decode()
+	if err != io.EOF {
+		t.Errorf("Decode() = %v, want %v", err, io.EOF)
+	}
+}
+
+func TestDecoder_SetMaxMessageSize(t *testing.T) {
+	stream := `{"jsonrpc":"2.0","method":"update"}` + "\n" + `{"jsonrpc":"2.0","method":"update","params":[1,2,3]}` + "\n" +
+		`{"jsonrpc":"2.0","method":"delete"}` + "\n"
+	decoder := NewDecoder(strings.NewReader(stream), nil)
+	decoder.SetMaxMessageSize(40)
+
+	notification, err := decoder.DecodeNotification()
+	if err != nil || notification.Method != "update" {
+		t.Errorf("DecodeNotification() = %v, %v, want the update notification", notification, err)
+	}
+	_, err = decoder.Decode()
+	if err != ErrMessageTooLarge {
+		t.Errorf("Decode() = %v, want %v", err, ErrMessageTooLarge)
+	}
+	// The oversized message is skipped
+	notification, err = decoder.DecodeNotification()
+	if err != nil || notification.Method != "delete" {
+		t.Errorf("DecodeNotification() = %v, %v, want the delete notification", notification, err)
+	}
+
+	decoder.SetMaxMessageSize(0)
+	if decoder.maxMessageSize != defaultMaxBodySize {
+		t.Errorf("maxMessageSize = %v, want %v", decoder.maxMessageSize, defaultMaxBodySize)
+	}
+}
+
+func TestEncoder(t *testing.T) {
+	tests := []struct {
+		name    string
+		framing Framing
+		want    string
+	}{
+		{
+			name: "NDJSON",
+			want: "{\"jsonrpc\":\"2.0\",\"method\":\"subtract\",\"params\":[42,23],\"id\":1}\n{\"jsonrpc\":\"2.0\",\"result\":19,\"id\":1}\n",
+		},
+		{
+			name:    "Content-Length",
+			framing: ContentLengthFraming,
+			want: "Content-Length: 61\r\n\r\n{\"jsonrpc\":\"2.0\",\"method\":\"subtract\",\"params\":[42,23],\"id\":1}" +
+				"Content-Length: 36\r\n\r\n{\"jsonrpc\":\"2.0\",\"result\":19,\"id\":1}",
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var written bytes.Buffer
+			encoder := NewEncoder(&written, tt.framing)
+			requestRaw, _ := NewRequest("subtract", []int{42, 23}, 1)
+			responseRaw, _ := NewResultResponse(1, 19)
+			for _, messageRaw := range [][]byte{requestRaw, responseRaw} {
+				if err := encoder.Encode(messageRaw); err != nil {
+					t.Fatalf("Encode() = %v, want nil", err)
+				}
+			}
+			if written.String() != tt.want {
+				t.Errorf("written = %q, want %q", written.String(), tt.want)
+			}
+
+			// What is encoded is decoded back
+			decoder := NewDecoder(&written, tt.framing)
+			for _, want := range [][]byte{requestRaw, responseRaw} {
+				messageRaw, err := decoder.Decode()
+				if err != nil || string(bytes.TrimSpace(messageRaw)) != string(bytes.TrimSpace(want)) {
+					t.Errorf("Decode() = %s, %v, want %s", messageRaw, err, want)
+				}
+			}
+		})
+	}
+}
+
+func TestEncoder_Concurrent(t *testing.T) {
+	var written bytes.Buffer
+	encoder := NewEncoder(&written, nil)
+	var wg sync.WaitGroup
+	for i := 0; i < 50; i++ {
+		wg.Add(1)
+		go func(i int) {
+			defer wg.Done()
+			notificationRaw, _ := NewNotification("update", []int{i})
+			_ = encoder.Encode(notificationRaw)
+		}(i)
+	}
+	wg.Wait()
+
+	decoder := NewDecoder(&written, nil)
+	for i := 0; i < 50; i++ {
+		if _, err := decoder.DecodeNotification(); err != nil {
+			t.Fatalf("DecodeNotification() = %v, want nil", err)
+		}
+	}
+}
+
+// readWriteCloser makes a MessageConn stream of a reader and a writer
+type readWriteCloser struct {
+	io.Reader
+	io.Writer
+}
+
+func (readWriteCloser) Close() error {
+	return nil
+}
+
+func Test_streamConn_ReadMessage(t *testing.T) {
+	tests := []struct {
+		name    string
+		stream  string
+		want    []string
+		wantErr error
+	}{
+		{
+			name:   "Messages",
+			stream: "{\"id\":1}\n{\"id\":2}\n",
+			want:   []string{"{\"id\":1}\n", "{\"id\":2}\n"},
+		},
+		{
+			name:   "Blank lines",
+			stream: "\n{\"id\":1}\r\n  \n{\"id\":2}\n",
+			want:   []string{"{\"id\":1}\r\n", "{\"id\":2}\n"},
+		},
+		{
+			name:   "Last line without newline",
+			stream: "{\"id\":1}\n{\"id\":2}",
+			want:   []string{"{\"id\":1}\n", "{\"id\":2}"},
+		},
+		{
+			name:    "Too large",
+			stream:  "{\"id\":\"" + strings.Repeat("a", 64) + "\"}\n",
+			wantErr: ErrMessageTooLarge,
+		},
+		{
+			name:   "Longer than the buffer",
+			stream: "{\"id\":\"" + strings.Repeat("a", 20) + "\"}\n",
+			want:   []string{"{\"id\":\"" + strings.Repeat("a", 20) + "\"}\n"},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			// Partial reads of one byte into a buffer smaller than some lines
+			conn := newStreamConn(readWriteCloser{}, NewlineFraming, 48)
+			conn.decoder.reader = bufio.NewReaderSize(iotest.OneByteReader(strings.NewReader(tt.stream)), 16)
+			var got []string
+			for {
+				message, err := conn.ReadMessage()
+				if err != nil {
+					if tt.wantErr != nil && !errors.Is(err, tt.wantErr) {
+						t.Errorf("ReadMessage() = %v, want %v", err, tt.wantErr)
+					}
+					if tt.wantErr == nil && err != io.EOF {
+						t.Errorf("ReadMessage() = %v, want %v", err, io.EOF)
+					}
+					break
+				}
+				got = append(got, string(message))
+			}
+			if strings.Join(got, "|") != strings.Join(tt.want, "|") {
+				t.Errorf("messages = %q, want %q", got, tt.want)
+			}
+		})
+	}
+}
+
+func Test_streamConn_WriteMessage(t *testing.T) {
+	tests := []struct {
+		name    string
+		message string
+		want    string
+	}{
+		{"Trailing newline", "{\"id\":1}\n", "{\"id\":1}\n"},
+		{"No trailing newline", "{\"id\":1}", "{\"id\":1}\n"},
+		{"Indented", "{\n  \"id\": 1\n}\n", "{\"id\":1}\n"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var written bytes.Buffer
+			conn := newStreamConn(readWriteCloser{Reader: strings.NewReader(""), Writer: &written}, nil, defaultMaxBodySize)
+			err := conn.WriteMessage(context.Background(), []byte(tt.message))
+			if err != nil || written.String() != tt.want {
+				t.Errorf("WriteMessage() = %q, %v, want %q", written.String(), err, tt.want)
+			}
+		})
+	}
+}
+
+func TestNewStreamConn(t *testing.T) {
+	// A serial link, say, speaking the LSP framing
+	serverEnd, clientEnd := net.Pipe()
+	server := NewConn(NewStreamConn(serverEnd, ContentLengthFraming), newTestMux(t))
+	defer server.Close()
+	client := NewConn(NewStreamConn(clientEnd, ContentLengthFraming), nil)
+	defer client.Close()
+
+	var result int
+	err := client.Call(context.Background(), "subtract", []int{42, 23}, &result)
+	if err != nil || result != 19 {
+		t.Errorf("Call() = %v, %v, want %v", result, err, 19)
+	}
+}
@@ -0,0 +1,391 @@
+/*  Copyright 2022  Kosmas Valianos (kosmas.valianos@gmail.com)
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    https://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License. */
+
+package gojsonrpc
+
+import (
+	"context"
+	"crypto/rand"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"strings"
+	"sync"
+)
+
+// Subscriptions follow the Ethereum protocol: the "<namespace>subscribe" method, e.g. "eth_subscribe", starts a
+// subscription and returns its ID, the events are notified with the "<namespace>subscription" method, tagged with
+// the ID, and the "<namespace>unsubscribe" method, called with the ID, ends it
+const (
+	subscribeSuffix    = "subscribe"
+	notificationSuffix = "subscription"
+	unsubscribeSuffix  = "unsubscribe"
+)
+
+// Capacities of the channel of the notifications of a ClientSubscription and of the notifications kept
+// for the subscriptions not known yet
+const (
+	subscriptionBuffer     = 64
+	earlyNotificationLimit = 64
+)
+
+var (
+	// ErrUnknownSubscription is returned when publishing to a subscription which is not or no longer active
+	ErrUnknownSubscription = errors.New("unknown subscription")
+	// ErrNoConnection is returned when subscribing outside of a request served by a Conn
+	ErrNoConnection = errors.New("subscriptions need a persistent connection")
+)
+
+// namespace returns the namespace of a "<namespace>subscribe" method
+func namespace(subscribeMethod string) (string, error) {
+	if !strings.HasSuffix(subscribeMethod, subscribeSuffix) || strings.HasSuffix(subscribeMethod, unsubscribeSuffix) {
+		return "", fmt.Errorf("method %q does not end with %q", subscribeMethod, subscribeSuffix)
+	}
+	return strings.TrimSuffix(subscribeMethod, subscribeSuffix), nil
+}
+
+// subscriptionParams are the params of the notification of an event of a subscription
+type subscriptionParams struct {
+	Subscription string `json:"subscription"`
+	Result       any    `json:"result"`
+}
+
+// Subscriptions keeps track of the subscriptions of the clients of a server. A handler of a "<namespace>subscribe"
+// method calls Subscribe to start one and returns its ID, and HandleUnsubscribe handles the "<namespace>unsubscribe" method.
+// The subscriptions end once their connection is closed or lost
+type Subscriptions struct {
+	mu   sync.Mutex
+	subs map[string]*Subscription
+}
+
+// NewSubscriptions creates an empty Subscriptions.
+// Returns a *Subscriptions object
+func NewSubscriptions() *Subscriptions {
+	return &Subscriptions{subs: make(map[string]*Subscription)}
+}
+
+// Subscription is a subscription of a client to the events notified by the server
+type Subscription struct {
+	id     string
+	method string
+	conn   *Conn
+	subs   *Subscriptions
+	done   chan struct{}
+	once   sync.Once
+}
+
+// Subscribe starts a subscription of the client calling the "<namespace>subscribe" method being served.
+// Returns a *Subscription object or ErrNoConnection if the request is not served by a Conn
+func (s *Subscriptions) Subscribe(ctx context.Context) (*Subscription, error) {
+	conn, ok := ConnFromContext(ctx)
+	if !ok {
+		return nil, ErrNoConnection
+	}
+	prefix, err := namespace(MethodFromContext(ctx))
+	if err != nil {
+		return nil, err
+	}
+	var idRaw [16]byte
+	_, err = rand.Read(idRaw[:])
+	if err != nil {
+		return nil, err
+	}
+	sub := &Subscription{
+		id:     fmt.Sprintf("0x%x", idRaw),
+		method: prefix + notificationSuffix,
+		conn:   conn,
+		subs:   s,
+		done:   make(chan struct{}),
+	}
+	s.mu.Lock()
+	s.subs[sub.id] = sub
+	s.mu.Unlock()
+
+	go func() {
+		select {
+		case <-conn.Done():
+			sub.Unsubscribe()
+		case <-sub.done:
+		}
+	}()
+	return sub, nil
+}
+
+// Publish notifies an event to the subscription with the ID.
+// Returns ErrUnknownSubscription if it is not active
+func (s *Subscriptions) Publish(ctx context.Context, id string, result any) error {
+	s.mu.Lock()
+	sub, ok := s.subs[id]
+	s.mu.Unlock()
+	if !ok {
+		return ErrUnknownSubscription
+	}
+	return sub.Notify(ctx, result)
+}
+
+// HandleUnsubscribe is the handler of the "<namespace>unsubscribe" method. It ends the subscription with the ID
+// if it belongs to the calling client.
+// Returns whether a subscription was ended
+func (s *Subscriptions) HandleUnsubscribe(ctx context.Context, params [1]string) (bool, error) {
+	conn, _ := ConnFromContext(ctx)
+	s.mu.Lock()
+	sub, ok := s.subs[params[0]]
+	s.mu.Unlock()
+	if !ok || sub.conn != conn {
+		return false, nil
+	}
+	sub.Unsubscribe()
+	return true, nil
+}
+
+// ID returns the ID of the subscription
+func (sub *Subscription) ID() string {
+	return sub.id
+}
+
+// Notify notifies an event with the result to the client.
+// Returns ErrUnknownSubscription once the subscription ended
+func (sub *Subscription) Notify(ctx context.Context, result any) error {
+	select {
+	case <-sub.done:
+		return ErrUnknownSubscription
+	default:
+	}
+	return sub.conn.Notify(ctx, sub.method, subscriptionParams{Subscription: sub.id, Result: result})
+}
+
+// Done returns a channel closed once the subscription ended, e.g. to stop producing its events
+func (sub *Subscription) Done() <-chan struct{} {
+	return sub.done
+}
+
+// Unsubscribe ends the subscription on the server side
+func (sub *Subscription) Unsubscribe() {
+	sub.once.Do(func() {
+		sub.subs.mu.Lock()
+		delete(sub.subs.subs, sub.id)
+		sub.subs.mu.Unlock()
+		close(sub.done)
+	})
+}
+
+// SubscriptionNotification is an event of a ClientSubscription
+type SubscriptionNotification struct {
+	Subscription string
+	Result       json.RawMessage
+	conn         *Conn
+}
+
+// Unmarshal unmarshals the result of the event into result
+func (n SubscriptionNotification) Unmarshal(result any) error {
+	return n.conn.client.unmarshalResult(n.Result, result)
+}
+
+// ClientSubscription is a subscription of a Conn to the events of the remote peer
+type ClientSubscription struct {
+	id                string
+	unsubscribeMethod string
+	conn              *Conn
+	notifications     chan SubscriptionNotification
+	ending            chan struct{}
+	endOnce           sync.Once
+	sendMu            sync.Mutex
+	ended             bool
+}
+
+// Subscribe calls the "<namespace>subscribe" method of the remote peer, e.g. "eth_subscribe", with the params.
+// The events are received on the channel of the subscription, which is closed once it ends. Receive them promptly:
+// while the channel is full, the connection is not read any further.
+// Returns a *ClientSubscription object or an error
+func (c *Conn) Subscribe(ctx context.Context, method string, params any) (*ClientSubscription, error) {
+	prefix, err := namespace(method)
+	if err != nil {
+		return nil, err
+	}
+	// Events may be received before the ID, they are kept until the subscription is known
+	c.subscriptions.expect(prefix + notificationSuffix)
+
+	var id string
+	err = c.Call(ctx, method, params, &id)
+	if err != nil {
+		return nil, err
+	}
+	sub := &ClientSubscription{
+		id:                id,
+		unsubscribeMethod: prefix + unsubscribeSuffix,
+		conn:              c,
+		notifications:     make(chan SubscriptionNotification, subscriptionBuffer),
+		ending:            make(chan struct{}),
+	}
+	if !c.subscriptions.add(sub) {
+		sub.end()
+	}
+	return sub, nil
+}
+
+// ID returns the ID of the subscription
+func (sub *ClientSubscription) ID() string {
+	return sub.id
+}
+
+// Notifications returns the channel of the events of the subscription, closed once it ends
+func (sub *ClientSubscription) Notifications() <-chan SubscriptionNotification {
+	return sub.notifications
+}
+
+// Unsubscribe ends the subscription and calls the "<namespace>unsubscribe" method of the remote peer
+func (sub *ClientSubscription) Unsubscribe(ctx context.Context) error {
+	sub.conn.subscriptions.remove(sub)
+	sub.end()
+	return sub.conn.Call(ctx, sub.unsubscribeMethod, []string{sub.id}, nil)
+}
+
+// send passes an event to the channel until the subscription ends
+func (sub *ClientSubscription) send(notification SubscriptionNotification) {
+	sub.sendMu.Lock()
+	defer sub.sendMu.Unlock()
+	if sub.ended {
+		return
+	}
+	select {
+	case sub.notifications <- notification:
+	case <-sub.ending:
+	}
+}
+
+// end closes the channel, unblocking a pending send
+func (sub *ClientSubscription) end() {
+	sub.endOnce.Do(func() {
+		close(sub.ending)
+		sub.sendMu.Lock()
+		sub.ended = true
+		close(sub.notifications)
+		sub.sendMu.Unlock()
+	})
+}
+
+// clientSubscriptions routes the events received by a Conn to its ClientSubscriptions. The zero value is ready to use
+type clientSubscriptions struct {
+	mu      sync.Mutex
+	methods map[string]bool
+	subs    map[string]*ClientSubscription
+	early   []SubscriptionNotification
+	closed  bool
+}
+
+// expect makes the notifications of the method be routed to the subscriptions
+func (s *clientSubscriptions) expect(method string) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if s.methods == nil {
+		s.methods = make(map[string]bool)
+	}
+	s.methods[method] = true
+}
+
+// add registers a subscription and passes it the events received before.
+// Returns false if the connection is closed
+func (s *clientSubscriptions) add(sub *ClientSubscription) bool {
+	s.mu.Lock()
+	if s.closed {
+		s.mu.Unlock()
+		return false
+	}
+	if s.subs == nil {
+		s.subs = make(map[string]*ClientSubscription)
+	}
+	s.subs[sub.id] = sub
+	var early []SubscriptionNotification
+	kept := s.early[:0]
+	for _, notification := range s.early {
+		if notification.Subscription == sub.id {
+			early = append(early, notification)
+		} else {
+			kept = append(kept, notification)
+		}
+	}
+	s.early = kept
+	s.mu.Unlock()
+
+	for _, notification := range early {
+		sub.send(notification)
+	}
+	return true
+}
+
+func (s *clientSubscriptions) remove(sub *ClientSubscription) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	delete(s.subs, sub.id)
+}
+
+// deliverSubscription passes a notification to its subscription, keeping it for a while if the subscription
+// is not known yet.
+// Returns false if it is not the event of a subscription
+func (c *Conn) deliverSubscription(notificationRaw []byte) bool {
+	s := &c.subscriptions
+	s.mu.Lock()
+	expecting := len(s.methods) > 0
+	s.mu.Unlock()
+	if !expecting {
+		return false
+	}
+
+	var notification struct {
+		Method string `json:"method"`
+		Params struct {
+			Subscription *string         `json:"subscription"`
+			Result       json.RawMessage `json:"result"`
+		} `json:"params"`
+	}
+	if err := json.Unmarshal(notificationRaw, &notification); err != nil || notification.Params.Subscription == nil {
+		return false
+	}
+	event := SubscriptionNotification{
+		Subscription: *notification.Params.Subscription,
+		Result:       notification.Params.Result,
+		conn:         c,
+	}
+
+	s.mu.Lock()
+	if !s.methods[notification.Method] {
+		s.mu.Unlock()
+		return false
+	}
+	sub, ok := s.subs[event.Subscription]
+	if !ok {
+		if len(s.early) == earlyNotificationLimit {
+			s.early = s.early[1:]
+		}
+		s.early = append(s.early, event)
+		s.mu.Unlock()
+		return true
+	}
+	s.mu.Unlock()
+	sub.send(event)
+	return true
+}
+
+// close ends all the subscriptions once the connection is closed or lost
+func (s *clientSubscriptions) close() {
+	s.mu.Lock()
+	s.closed = true
+	subs := s.subs
+	s.subs = nil
+	s.early = nil
+	s.mu.Unlock()
+	for _, sub := range subs {
+		sub.end()
+	}
+}
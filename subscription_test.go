@@ -0,0 +1,167 @@
+/*  Copyright 2022  Kosmas Valianos (kosmas.valianos@gmail.com)
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    https://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License. */
+
+package gojsonrpc
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+)
+
+// newTestSubscriptionMux creates the mux of a server whose "eth_subscribe" subscriptions are notified the events 1, 2 and 3,
+// the first one before the ID is returned. The server side subscriptions are passed to subscribed
+func newTestSubscriptionMux(t *testing.T, subs *Subscriptions, subscribed chan *Subscription) *Mux {
+	mux := NewMux()
+	err := HandleFunc(mux, "eth_subscribe", func(ctx context.Context, params [1]string) (string, error) {
+		sub, err := subs.Subscribe(ctx)
+		if err != nil {
+			return "", err
+		}
+		err = sub.Notify(ctx, 1)
+		if err != nil {
+			return "", err
+		}
+		go func() {
+			for i := 2; i <= 3; i++ {
+				_ = subs.Publish(context.Background(), sub.ID(), i)
+			}
+			subscribed <- sub
+		}()
+		return sub.ID(), nil
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	err = HandleFunc(mux, "eth_unsubscribe", subs.HandleUnsubscribe)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return mux
+}
+
+func receiveEvents(t *testing.T, sub *ClientSubscription, count int) []int {
+	t.Helper()
+	var events []int
+	for len(events) < count {
+		select {
+		case notification, ok := <-sub.Notifications():
+			if !ok {
+				t.Fatalf("channel closed after the events %v", events)
+			}
+			if notification.Subscription != sub.ID() {
+				t.Errorf("Subscription = %v, want %v", notification.Subscription, sub.ID())
+			}
+			var event int
+			err := notification.Unmarshal(&event)
+			if err != nil {
+				t.Fatalf("Unmarshal() = %v, want nil", err)
+			}
+			events = append(events, event)
+		case <-time.After(5 * time.Second):
+			t.Fatalf("received the events %v, want %v events", events, count)
+		}
+	}
+	return events
+}
+
+func waitClosed(t *testing.T, sub *ClientSubscription) {
+	t.Helper()
+	select {
+	case _, ok := <-sub.Notifications():
+		if ok {
+			t.Error("event received, want the channel closed")
+		}
+	case <-time.After(5 * time.Second):
+		t.Error("channel not closed")
+	}
+}
+
+func TestSubscriptions(t *testing.T) {
+	subs := NewSubscriptions()
+	subscribed := make(chan *Subscription, 1)
+	clientEnd, serverEnd := Pipe()
+	server := NewConn(serverEnd, newTestSubscriptionMux(t, subs, subscribed))
+	defer server.Close()
+	client := NewConn(clientEnd, NewMux())
+	defer client.Close()
+
+	t.Run("Unsubscribe", func(t *testing.T) {
+		sub, err := client.Subscribe(context.Background(), "eth_subscribe", []string{"newHeads"})
+		if err != nil {
+			t.Fatalf("Subscribe() = %v, want nil", err)
+		}
+		events := receiveEvents(t, sub, 3)
+		for i, event := range events {
+			if event != i+1 {
+				t.Fatalf("events = %v, want [1 2 3]", events)
+			}
+		}
+		serverSub := <-subscribed
+
+		err = sub.Unsubscribe(context.Background())
+		if err != nil {
+			t.Fatalf("Unsubscribe() = %v, want nil", err)
+		}
+		waitClosed(t, sub)
+		<-serverSub.Done()
+		err = subs.Publish(context.Background(), sub.ID(), 4)
+		if !errors.Is(err, ErrUnknownSubscription) {
+			t.Errorf("Publish() = %v, want %v", err, ErrUnknownSubscription)
+		}
+	})
+
+	t.Run("Disconnect", func(t *testing.T) {
+		sub, err := client.Subscribe(context.Background(), "eth_subscribe", []string{"newHeads"})
+		if err != nil {
+			t.Fatalf("Subscribe() = %v, want nil", err)
+		}
+		receiveEvents(t, sub, 3)
+		serverSub := <-subscribed
+
+		client.Close()
+		waitClosed(t, sub)
+		select {
+		case <-serverSub.Done():
+		case <-time.After(5 * time.Second):
+			t.Error("server side subscription not ended")
+		}
+		err = serverSub.Notify(context.Background(), 4)
+		if !errors.Is(err, ErrUnknownSubscription) {
+			t.Errorf("Notify() = %v, want %v", err, ErrUnknownSubscription)
+		}
+	})
+}
+
+func Test_namespace(t *testing.T) {
+	tests := []struct {
+		method  string
+		want    string
+		wantErr bool
+	}{
+		{"eth_subscribe", "eth_", false},
+		{"subscribe", "", false},
+		{"eth_unsubscribe", "", true},
+		{"eth_subscription", "", true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.method, func(t *testing.T) {
+			got, err := namespace(tt.method)
+			if (err != nil) != tt.wantErr || got != tt.want {
+				t.Errorf("namespace() = %v, %v, want %v, wantErr %v", got, err, tt.want, tt.wantErr)
+			}
+		})
+	}
+}
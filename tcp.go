@@ -0,0 +1,170 @@
+/*  Copyright 2022  Kosmas Valianos (kosmas.valianos@gmail.com)
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    https://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License. */
+
+package gojsonrpc
+
+import (
+	"context"
+	"crypto/tls"
+	"errors"
+	"net"
+	"time"
+)
+
+// tcpConfig is the configuration of the TCP server and dialer
+type tcpConfig struct {
+	registry       *ConnRegistry
+	clientOptions  []ClientOption
+	framing        Framing
+	maxMessageSize int
+	tlsConfig      *tls.Config
+}
+
+// TCPOption configures the TCP server or dialer
+type TCPOption func(*tcpConfig)
+
+// WithTCPRegistry makes the server register the accepted connections in the registry
+func WithTCPRegistry(registry *ConnRegistry) TCPOption {
+	return func(c *tcpConfig) {
+		c.registry = registry
+	}
+}
+
+// WithTCPClientOptions configures the Client of the Conn calling the remote peer
+func WithTCPClientOptions(options ...ClientOption) TCPOption {
+	return func(c *tcpConfig) {
+		c.clientOptions = append(c.clientOptions, options...)
+	}
+}
+
+// WithTCPFraming sets the Framing of the messages, NewlineFraming by default
+func WithTCPFraming(framing Framing) TCPOption {
+	return func(c *tcpConfig) {
+		c.framing = framing
+	}
+}
+
+// WithTCPMaxMessageSize sets the size limit in bytes of the received messages, 1MB by default. The connection is
+// closed with ErrMessageTooLarge once a larger message arrives, without buffering it. A zero or negative size restores the default
+func WithTCPMaxMessageSize(size int) TCPOption {
+	return func(c *tcpConfig) {
+		c.maxMessageSize = size
+	}
+}
+
+// WithTCPTLS secures the connections with TLS configured by config. The server needs a certificate, e.g. from
+// NewMutualTLSConfig to also verify the clients, and the dialer verifies the server of the address by default
+func WithTCPTLS(config *tls.Config) TCPOption {
+	return func(c *tcpConfig) {
+		c.tlsConfig = config
+	}
+}
+
+func newTCPConfig(options []TCPOption) *tcpConfig {
+	config := &tcpConfig{}
+	for _, option := range options {
+		option(config)
+	}
+	if config.maxMessageSize <= 0 {
+		config.maxMessageSize = defaultMaxBodySize
+	}
+	return config
+}
+
+// ServeTCP accepts the connections of the listener, one message per line by default, each served as a Conn
+// by the mux until the listener is closed. The mux calls the clients back through the Conn given by ConnFromContext
+// or by the ConnRegistry if any.
+// Returns the error of the listener, net.ErrClosed once closed
+func ServeTCP(listener net.Listener, mux *Mux, options ...TCPOption) error {
+	config := newTCPConfig(options)
+	var delay time.Duration
+	for {
+		netConn, err := listener.Accept()
+		if err != nil {
+			var netErr net.Error
+			if errors.As(err, &netErr) && netErr.Timeout() {
+				// Temporary exhaustion, e.g. of the file descriptors
+				delay = minDuration(2*delay+5*time.Millisecond, time.Second)
+				time.Sleep(delay)
+				continue
+			}
+			return err
+		}
+		delay = 0
+		if config.tlsConfig == nil {
+			config.accept(netConn, mux)
+			continue
+		}
+		go func(tlsConn *tls.Conn) {
+			// The handshake completes before serving so that the handlers have the certificate of the client
+			ctx, cancel := context.WithTimeout(context.Background(), tlsHandshakeTimeout)
+			defer cancel()
+			err := tlsConn.HandshakeContext(ctx)
+			if err != nil {
+				tlsConn.Close()
+				return
+			}
+			config.accept(tlsConn, mux)
+		}(tls.Server(netConn, config.tlsConfig))
+	}
+}
+
+// accept serves an accepted connection as a Conn
+func (c *tcpConfig) accept(netConn net.Conn, mux *Mux) {
+	streamConn := newStreamConn(netConn, c.framing, c.maxMessageSize)
+	if c.registry != nil {
+		c.registry.Accept(streamConn, mux, c.clientOptions...)
+	} else {
+		NewConn(streamConn, mux, c.clientOptions...)
+	}
+}
+
+// DialTCPConn opens a TCP connection to the address, configured by the options, e.g. for a ReconnectingTransport.
+// Returns a MessageConn or an error
+func DialTCPConn(ctx context.Context, address string, options ...TCPOption) (MessageConn, error) {
+	config := newTCPConfig(options)
+	var dialer net.Dialer
+	netConn, err := dialer.DialContext(ctx, "tcp", address)
+	if err != nil {
+		return nil, err
+	}
+	if config.tlsConfig != nil {
+		tlsConn := tls.Client(netConn, clientTLSConfig(config.tlsConfig, address))
+		err = tlsConn.HandshakeContext(ctx)
+		if err != nil {
+			netConn.Close()
+			return nil, err
+		}
+		netConn = tlsConn
+	}
+	return newStreamConn(netConn, config.framing, config.maxMessageSize), nil
+}
+
+// DialTCP opens a TCP connection to the address, configured by the options, and makes it a Conn serving
+// the requests and notifications of the server with the mux, which may be nil.
+// Returns a *Conn object or an error
+func DialTCP(ctx context.Context, address string, mux *Mux, options ...TCPOption) (*Conn, error) {
+	conn, err := DialTCPConn(ctx, address, options...)
+	if err != nil {
+		return nil, err
+	}
+	return NewConn(conn, mux, newTCPConfig(options).clientOptions...), nil
+}
+
+func minDuration(a, b time.Duration) time.Duration {
+	if a < b {
+		return a
+	}
+	return b
+}
@@ -0,0 +1,119 @@
+/*  Copyright 2022  Kosmas Valianos (kosmas.valianos@gmail.com)
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    https://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License. */
+
+package gojsonrpc
+
+import (
+	"bufio"
+	"context"
+	"errors"
+	"net"
+	"testing"
+	"time"
+)
+
+func TestServeTCP(t *testing.T) {
+	listener, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	registry := NewConnRegistry()
+	served := make(chan error, 1)
+	go func() {
+		served <- ServeTCP(listener, newTestMux(t), WithTCPRegistry(registry))
+	}()
+
+	t.Run("Conn", func(t *testing.T) {
+		clientMux := NewMux()
+		err := HandleFunc(clientMux, "whoami", func(ctx context.Context, params any) (string, error) {
+			return "device", nil
+		})
+		if err != nil {
+			t.Fatal(err)
+		}
+		conn, err := DialTCP(context.Background(), listener.Addr().String(), clientMux)
+		if err != nil {
+			t.Fatalf("DialTCP() = %v, want nil", err)
+		}
+		defer conn.Close()
+		result, err := Call[int](context.Background(), conn.Client(), "subtract", []int{42, 23})
+		if err != nil || result != 19 {
+			t.Errorf("Call() = %v, %v, want 19", result, err)
+		}
+		var name string
+		err = registry.Call(context.Background(), registry.IDs()[0], "whoami", nil, &name)
+		if err != nil || name != "device" {
+			t.Errorf("Call() = %v, %v, want device", name, err)
+		}
+	})
+
+	t.Run("Raw device", func(t *testing.T) {
+		device, err := net.Dial("tcp", listener.Addr().String())
+		if err != nil {
+			t.Fatal(err)
+		}
+		defer device.Close()
+		// A request written in pieces
+		for _, piece := range []string{`{"jsonrpc":"2.0","met`, `hod":"subtract","params":[42,`, "23],\"id\":1}\n"} {
+			_, err = device.Write([]byte(piece))
+			if err != nil {
+				t.Fatal(err)
+			}
+			time.Sleep(10 * time.Millisecond)
+		}
+		response, err := bufio.NewReader(device).ReadString('\n')
+		if err != nil || response != "{\"jsonrpc\":\"2.0\",\"result\":19,\"id\":1}\n" {
+			t.Errorf("response = %q, %v", response, err)
+		}
+	})
+
+	listener.Close()
+	if err := <-served; !errors.Is(err, net.ErrClosed) {
+		t.Errorf("ServeTCP() = %v, want %v", err, net.ErrClosed)
+	}
+}
+
+func TestWithTCPMaxMessageSize(t *testing.T) {
+	listener, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer listener.Close()
+	go ServeTCP(listener, newTestMux(t), WithTCPMaxMessageSize(64))
+
+	device, err := net.Dial("tcp", listener.Addr().String())
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer device.Close()
+	reader := bufio.NewReader(device)
+	_, err = device.Write([]byte(`{"jsonrpc":"2.0","method":"subtract","params":[42,23],"id":1}` + "\n"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	response, err := reader.ReadString('\n')
+	if err != nil || response != "{\"jsonrpc\":\"2.0\",\"result\":19,\"id\":1}\n" {
+		t.Errorf("response = %q, %v", response, err)
+	}
+
+	// The server drops the connection of a larger message
+	_, err = device.Write([]byte(`{"jsonrpc":"2.0","method":"subtract","params":[42,23],"id":2,"padding":"xxxxxxxx"}` + "\n"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	_ = device.SetReadDeadline(time.Now().Add(5 * time.Second))
+	if response, err := reader.ReadString('\n'); err == nil {
+		t.Errorf("response = %q, want the connection closed", response)
+	}
+}
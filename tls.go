@@ -0,0 +1,87 @@
+/*  Copyright 2022  Kosmas Valianos (kosmas.valianos@gmail.com)
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    https://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License. */
+
+package gojsonrpc
+
+import (
+	"context"
+	"crypto/tls"
+	"crypto/x509"
+	"net"
+	"time"
+)
+
+// tlsHandshakeTimeout bounds the TLS handshakes of the accepted connections
+const tlsHandshakeTimeout = 10 * time.Second
+
+// NewMutualTLSConfig creates the tls.Config of a server, e.g. of an http.Server or of ServeTCP, presenting
+// the certificate and requiring the clients to present a certificate signed by one of the clientCAs.
+// The handlers get the certificate of their client with PeerCertificateFromContext.
+// Returns a *tls.Config object
+func NewMutualTLSConfig(certificate tls.Certificate, clientCAs *x509.CertPool) *tls.Config {
+	return &tls.Config{
+		Certificates: []tls.Certificate{certificate},
+		ClientCAs:    clientCAs,
+		ClientAuth:   tls.RequireAndVerifyClientCert,
+		MinVersion:   tls.VersionTLS12,
+	}
+}
+
+// PeerCertificateFromContext returns the verified certificate of the remote peer of the TLS connection which carried
+// the request or notification being served, if any. A server only has the certificate of its clients with mutual TLS
+func PeerCertificateFromContext(ctx context.Context) (*x509.Certificate, bool) {
+	certificate, ok := ctx.Value(peerCertificateContextKey).(*x509.Certificate)
+	return certificate, ok
+}
+
+// withPeerCertificate adds the verified certificate of the peer of a TLS connection, if any, to the ctx
+func withPeerCertificate(ctx context.Context, state *tls.ConnectionState) context.Context {
+	if len(state.VerifiedChains) == 0 || len(state.VerifiedChains[0]) == 0 {
+		return ctx
+	}
+	return context.WithValue(ctx, peerCertificateContextKey, state.VerifiedChains[0][0])
+}
+
+// connectionState returns the state of the TLS connection under a MessageConn, if any
+func connectionState(conn MessageConn) (*tls.ConnectionState, bool) {
+	var underlying any
+	switch conn := conn.(type) {
+	case *codecConn:
+		return connectionState(conn.conn)
+	case *streamConn:
+		underlying = conn.rwc
+	case *WebSocketConn:
+		underlying = conn.conn
+	}
+	tlsConn, ok := underlying.(*tls.Conn)
+	if !ok {
+		return nil, false
+	}
+	state := tlsConn.ConnectionState()
+	return &state, true
+}
+
+// clientTLSConfig returns the config naming the host of the address as the server, if the config names none
+func clientTLSConfig(config *tls.Config, address string) *tls.Config {
+	if config.ServerName != "" {
+		return config
+	}
+	host, _, err := net.SplitHostPort(address)
+	if err != nil {
+		host = address
+	}
+	config = config.Clone()
+	config.ServerName = host
+	return config
+}
@@ -0,0 +1,212 @@
+/*  Copyright 2022  Kosmas Valianos (kosmas.valianos@gmail.com)
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    https://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License. */
+
+package gojsonrpc
+
+import (
+	"context"
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/tls"
+	"crypto/x509"
+	"crypto/x509/pkix"
+	"math/big"
+	"net"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+)
+
+// testTLS are the TLS configurations of a server requiring client certificates and of its client "alice",
+// both signed by the same CA
+type testTLS struct {
+	server *tls.Config
+	client *tls.Config
+}
+
+func newTestCertificate(t *testing.T, template *x509.Certificate, parent *x509.Certificate, parentKey *ecdsa.PrivateKey) (tls.Certificate, *x509.Certificate, *ecdsa.PrivateKey) {
+	key, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if parent == nil {
+		parent, parentKey = template, key
+	}
+	certificateRaw, err := x509.CreateCertificate(rand.Reader, template, parent, &key.PublicKey, parentKey)
+	if err != nil {
+		t.Fatal(err)
+	}
+	certificate, err := x509.ParseCertificate(certificateRaw)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return tls.Certificate{Certificate: [][]byte{certificateRaw}, PrivateKey: key}, certificate, key
+}
+
+func newTestTLS(t *testing.T) testTLS {
+	notBefore := time.Now().Add(-time.Hour)
+	notAfter := time.Now().Add(time.Hour)
+	_, ca, caKey := newTestCertificate(t, &x509.Certificate{
+		SerialNumber:          big.NewInt(1),
+		Subject:               pkix.Name{CommonName: "test CA"},
+		NotBefore:             notBefore,
+		NotAfter:              notAfter,
+		KeyUsage:              x509.KeyUsageCertSign,
+		BasicConstraintsValid: true,
+		IsCA:                  true,
+	}, nil, nil)
+	serverCertificate, _, _ := newTestCertificate(t, &x509.Certificate{
+		SerialNumber: big.NewInt(2),
+		Subject:      pkix.Name{CommonName: "server"},
+		NotBefore:    notBefore,
+		NotAfter:     notAfter,
+		KeyUsage:     x509.KeyUsageDigitalSignature,
+		ExtKeyUsage:  []x509.ExtKeyUsage{x509.ExtKeyUsageServerAuth},
+		IPAddresses:  []net.IP{net.IPv4(127, 0, 0, 1)},
+	}, ca, caKey)
+	clientCertificate, _, _ := newTestCertificate(t, &x509.Certificate{
+		SerialNumber: big.NewInt(3),
+		Subject:      pkix.Name{CommonName: "alice"},
+		NotBefore:    notBefore,
+		NotAfter:     notAfter,
+		KeyUsage:     x509.KeyUsageDigitalSignature,
+		ExtKeyUsage:  []x509.ExtKeyUsage{x509.ExtKeyUsageClientAuth},
+	}, ca, caKey)
+
+	pool := x509.NewCertPool()
+	pool.AddCert(ca)
+	return testTLS{
+		server: NewMutualTLSConfig(serverCertificate, pool),
+		client: &tls.Config{Certificates: []tls.Certificate{clientCertificate}, RootCAs: pool},
+	}
+}
+
+// newTestPeerMux returns a mux whose "whoami" method returns the common name of the certificate of the peer
+func newTestPeerMux(t *testing.T) *Mux {
+	mux := NewMux()
+	err := HandleFunc(mux, "whoami", func(ctx context.Context, params any) (string, error) {
+		certificate, ok := PeerCertificateFromContext(ctx)
+		if !ok {
+			return "", nil
+		}
+		return certificate.Subject.CommonName, nil
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	return mux
+}
+
+func TestWithTCPTLS(t *testing.T) {
+	config := newTestTLS(t)
+	listener, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer listener.Close()
+	registry := NewConnRegistry()
+	go ServeTCP(listener, newTestPeerMux(t), WithTCPTLS(config.server), WithTCPRegistry(registry))
+
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+	conn, err := DialTCP(ctx, listener.Addr().String(), newTestPeerMux(t), WithTCPTLS(config.client))
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer conn.Close()
+	var name string
+	err = conn.Call(ctx, "whoami", nil, &name)
+	if err != nil || name != "alice" {
+		t.Errorf("Call() = %q, %v, want %q", name, err, "alice")
+	}
+	// The client has the certificate of the server
+	err = registry.Call(ctx, registry.IDs()[0], "whoami", nil, &name)
+	if err != nil || name != "server" {
+		t.Errorf("ConnRegistry.Call() = %q, %v, want %q", name, err, "server")
+	}
+
+	// A client without a certificate is rejected
+	anonymous := &tls.Config{RootCAs: config.client.RootCAs}
+	conn, err = DialTCP(ctx, listener.Addr().String(), nil, WithTCPTLS(anonymous))
+	if err == nil {
+		defer conn.Close()
+		err = conn.Call(ctx, "whoami", nil, &name)
+	}
+	if err == nil {
+		t.Errorf("Call() = %q, want an error", name)
+	}
+}
+
+func TestWithHTTPTLS(t *testing.T) {
+	config := newTestTLS(t)
+	server := httptest.NewUnstartedServer(HTTPHandler(newTestPeerMux(t)))
+	server.TLS = config.server
+	server.StartTLS()
+	defer server.Close()
+
+	client := NewClient(NewHTTPTransport(server.URL, WithHTTPTLS(config.client)))
+	var name string
+	err := client.Call(context.Background(), "whoami", nil, &name)
+	if err != nil || name != "alice" {
+		t.Errorf("Call() = %q, %v, want %q", name, err, "alice")
+	}
+}
+
+func TestWithWebSocketTLS(t *testing.T) {
+	config := newTestTLS(t)
+	server := httptest.NewUnstartedServer(WebSocketHandler(newTestPeerMux(t)))
+	server.TLS = config.server
+	server.StartTLS()
+	defer server.Close()
+
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+	wsURL := "wss" + strings.TrimPrefix(server.URL, "https")
+	conn, err := DialWebSocket(ctx, wsURL, nil, WithWebSocketTLS(config.client))
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer conn.Close()
+	var name string
+	err = conn.Call(ctx, "whoami", nil, &name)
+	if err != nil || name != "alice" {
+		t.Errorf("Call() = %q, %v, want %q", name, err, "alice")
+	}
+}
+
+func Test_clientTLSConfig(t *testing.T) {
+	tests := []struct {
+		name       string
+		serverName string
+		address    string
+		want       string
+	}{
+		{"host and port", "", "device.local:4000", "device.local"},
+		{"host", "", "device.local", "device.local"},
+		{"named", "rpc.example.com", "10.0.0.1:4000", "rpc.example.com"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			config := &tls.Config{ServerName: tt.serverName}
+			if got := clientTLSConfig(config, tt.address).ServerName; got != tt.want {
+				t.Errorf("clientTLSConfig().ServerName = %q, want %q", got, tt.want)
+			}
+			if config.ServerName != tt.serverName {
+				t.Errorf("config.ServerName = %q, want it unchanged", config.ServerName)
+			}
+		})
+	}
+}
@@ -0,0 +1,313 @@
+/*  Copyright 2022  Kosmas Valianos (kosmas.valianos@gmail.com)
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    https://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License. */
+
+package gojsonrpc
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+)
+
+// SpanKind tells apart the spans of the calls made from those of the calls served
+type SpanKind int
+
+// Kinds of the spans started by the Tracing middleware and the TracingTransport
+const (
+	SpanKindServer SpanKind = iota
+	SpanKindClient
+)
+
+// Attributes of the spans, following the semantic conventions of OpenTelemetry for JSON-RPC
+const (
+	AttributeRPCSystem           = "rpc.system"
+	AttributeRPCMethod           = "rpc.method"
+	AttributeJsonRPCVersion      = "rpc.jsonrpc.version"
+	AttributeJsonRPCRequestID    = "rpc.jsonrpc.request_id"
+	AttributeJsonRPCErrorCode    = "rpc.jsonrpc.error_code"
+	AttributeJsonRPCErrorMessage = "rpc.jsonrpc.error_message"
+	AttributeJsonRPCBatchSize    = "rpc.jsonrpc.batch_size"
+)
+
+// Span is a span of a trace, measuring the duration of a call from its start to its end
+type Span interface {
+	// SetAttribute sets an attribute of the span, a string or an int
+	SetAttribute(key string, value any)
+	// End ends the span, failed with the error if not nil
+	End(err error)
+}
+
+// Tracer starts the spans of the calls. A tracer of OpenTelemetry only needs to be wrapped:
+//
+//	func (t otelTracer) Start(ctx context.Context, name string, kind gojsonrpc.SpanKind) (context.Context, gojsonrpc.Span) {
+//		spanKind := trace.SpanKindServer
+//		if kind == gojsonrpc.SpanKindClient {
+//			spanKind = trace.SpanKindClient
+//		}
+//		ctx, span := t.Tracer.Start(ctx, name, trace.WithSpanKind(spanKind))
+//		return ctx, otelSpan{span}
+//	}
+type Tracer interface {
+	// Start starts a span named after the method, a child of the span carried by ctx if any.
+	// Returns the context carrying the span and the span
+	Start(ctx context.Context, name string, kind SpanKind) (context.Context, Span)
+}
+
+// TracePropagator carries the context of a trace across the calls in a metadata map, e.g. the "traceparent" of the
+// W3C Trace Context. A propagation.TextMapPropagator of OpenTelemetry only needs to be wrapped with propagation.MapCarrier
+type TracePropagator interface {
+	// Inject writes the context of the trace carried by ctx into the metadata
+	Inject(ctx context.Context, metadata map[string]string)
+	// Extract returns a copy of ctx carrying the context of the trace read from the metadata
+	Extract(ctx context.Context, metadata map[string]string) context.Context
+}
+
+// MetadataConvention is where the metadata of a call, e.g. the context of its trace, is carried in the message
+// since JSON-RPC 2.0 has no headers
+type MetadataConvention interface {
+	// Inject returns the params carrying the metadata, the params as they are if they cannot carry it
+	Inject(params json.RawMessage, metadata map[string]string) json.RawMessage
+	// Extract returns the metadata carried by the params, nil if none
+	Extract(params json.RawMessage) map[string]string
+}
+
+// ParamsMetadata is the MetadataConvention carrying the metadata as an object member of the named params,
+// e.g. "_meta" as the Model Context Protocol does. Positional params and calls without params carry none
+type ParamsMetadata string
+
+// Inject adds the metadata as a member of the named params
+func (p ParamsMetadata) Inject(params json.RawMessage, metadata map[string]string) json.RawMessage {
+	var members map[string]json.RawMessage
+	if len(metadata) == 0 || jsonKind(params) != '{' || json.Unmarshal(params, &members) != nil {
+		return params
+	}
+	metadataRaw, err := json.Marshal(metadata)
+	if err != nil {
+		return params
+	}
+	members[string(p)] = metadataRaw
+	injected, err := json.Marshal(members)
+	if err != nil {
+		return params
+	}
+	return injected
+}
+
+// Extract reads the metadata from the member of the named params
+func (p ParamsMetadata) Extract(params json.RawMessage) map[string]string {
+	var members map[string]json.RawMessage
+	if jsonKind(params) != '{' || json.Unmarshal(params, &members) != nil {
+		return nil
+	}
+	var metadata map[string]string
+	if json.Unmarshal(members[string(p)], &metadata) != nil {
+		return nil
+	}
+	return metadata
+}
+
+// DefaultMetadataConvention is the MetadataConvention used unless WithMetadataConvention sets another one
+const DefaultMetadataConvention = ParamsMetadata("_meta")
+
+// tracingConfig configures the Tracing middleware and the TracingTransport
+type tracingConfig struct {
+	propagator TracePropagator
+	convention MetadataConvention
+}
+
+// TracingOption configures the Tracing middleware and the TracingTransport
+type TracingOption func(*tracingConfig)
+
+// WithTracePropagator propagates the context of the traces across the calls with the propagator.
+// The traces are not propagated by default
+func WithTracePropagator(propagator TracePropagator) TracingOption {
+	return func(c *tracingConfig) {
+		c.propagator = propagator
+	}
+}
+
+// WithMetadataConvention sets where the context of the traces is carried in the messages, DefaultMetadataConvention
+// by default
+func WithMetadataConvention(convention MetadataConvention) TracingOption {
+	return func(c *tracingConfig) {
+		if convention != nil {
+			c.convention = convention
+		}
+	}
+}
+
+func newTracingConfig(options []TracingOption) tracingConfig {
+	config := tracingConfig{convention: DefaultMetadataConvention}
+	for _, option := range options {
+		option(&config)
+	}
+	return config
+}
+
+// Tracing creates a Middleware starting a server span per request and notification served, a child of the context
+// of the trace propagated by the caller if any. The spans have the attributes of the method, the id and the error code
+// of the response, the errors which are not JSON-RPC errors having the code of JsonInternalError
+func Tracing(tracer Tracer, options ...TracingOption) Middleware {
+	config := newTracingConfig(options)
+	return func(next Handler) Handler {
+		return HandlerFunc(func(ctx context.Context, params json.RawMessage) (any, error) {
+			if config.propagator != nil {
+				if metadata := config.convention.Extract(params); metadata != nil {
+					ctx = config.propagator.Extract(ctx, metadata)
+				}
+			}
+			method := MethodFromContext(ctx)
+			ctx, span := tracer.Start(ctx, method, SpanKindServer)
+			setSpanAttributes(span, method, IDFromContext(ctx))
+
+			result, err := next.ServeJSONRPC(ctx, params)
+			if err != nil {
+				jsonRPCError, ok := AsJsonRPCError(err)
+				if !ok || jsonRPCError == nil {
+					jsonRPCError = &JsonInternalError
+				}
+				setSpanError(span, jsonRPCError)
+			}
+			span.End(err)
+			return result, err
+		})
+	}
+}
+
+func setSpanAttributes(span Span, method string, id any) {
+	span.SetAttribute(AttributeRPCSystem, "jsonrpc")
+	span.SetAttribute(AttributeJsonRPCVersion, jsonRPCProtocol)
+	span.SetAttribute(AttributeRPCMethod, method)
+	if id != nil {
+		span.SetAttribute(AttributeJsonRPCRequestID, fmt.Sprint(id))
+	}
+}
+
+func setSpanError(span Span, jsonRPCError *jsonRPCError) {
+	span.SetAttribute(AttributeJsonRPCErrorCode, jsonRPCError.Code)
+	span.SetAttribute(AttributeJsonRPCErrorMessage, jsonRPCError.Message)
+}
+
+// TracingTransport is a Transport starting a client span per request and notification sent, e.g. wrapping the
+// Transport of a Client, and propagating the context of the trace to the server. A batch has a single span
+type TracingTransport struct {
+	transport Transport
+	tracer    Tracer
+	config    tracingConfig
+}
+
+// NewTracingTransport creates a TracingTransport over the transport starting the spans with the tracer,
+// configured by the options.
+// Returns a *TracingTransport object
+func NewTracingTransport(transport Transport, tracer Tracer, options ...TracingOption) *TracingTransport {
+	return &TracingTransport{transport: transport, tracer: tracer, config: newTracingConfig(options)}
+}
+
+// RoundTrip sends a request or a batch within a client span having the attributes of the method, the id and the error
+// code of the response.
+// Returns the raw bytes of the response or the error of the transport
+func (t *TracingTransport) RoundTrip(ctx context.Context, requestRaw []byte) ([]byte, error) {
+	ctx, span, requestRaw := t.start(ctx, requestRaw)
+	responseRaw, err := t.transport.RoundTrip(ctx, requestRaw)
+	if err != nil {
+		span.End(err)
+		return nil, err
+	}
+	var responseErr error
+	if jsonKind(responseRaw) == '{' {
+		if response, parseErr := ParseResponse(responseRaw); parseErr == nil && response.IsError() {
+			setSpanError(span, response.Error)
+			responseErr = response.Err()
+		}
+	}
+	span.End(responseErr)
+	return responseRaw, nil
+}
+
+// Send sends a notification within a client span having the attribute of the method
+func (t *TracingTransport) Send(ctx context.Context, notificationRaw []byte) error {
+	ctx, span, notificationRaw := t.start(ctx, notificationRaw)
+	err := t.transport.Send(ctx, notificationRaw)
+	span.End(err)
+	return err
+}
+
+// start starts the span of a message and injects the context of its trace into the message, or into every element
+// of a batch.
+// Returns the context carrying the span, the span and the message to send
+func (t *TracingTransport) start(ctx context.Context, messageRaw []byte) (context.Context, Span, []byte) {
+	if jsonKind(messageRaw) == '[' {
+		var elements []json.RawMessage
+		if json.Unmarshal(messageRaw, &elements) != nil {
+			return t.startSpan(ctx, "batch", nil, messageRaw)
+		}
+		ctx, span := t.tracer.Start(ctx, "batch", SpanKindClient)
+		span.SetAttribute(AttributeRPCSystem, "jsonrpc")
+		span.SetAttribute(AttributeJsonRPCVersion, jsonRPCProtocol)
+		span.SetAttribute(AttributeJsonRPCBatchSize, len(elements))
+		metadata := t.metadata(ctx)
+		for i, element := range elements {
+			elements[i] = t.inject(element, metadata)
+		}
+		if injected, err := json.Marshal(elements); err == nil {
+			messageRaw = injected
+		}
+		return ctx, span, messageRaw
+	}
+
+	var envelope struct {
+		Method string `json:"method"`
+		ID     any    `json:"id"`
+	}
+	_ = json.Unmarshal(messageRaw, &envelope)
+	return t.startSpan(ctx, envelope.Method, envelope.ID, messageRaw)
+}
+
+func (t *TracingTransport) startSpan(ctx context.Context, method string, id any, messageRaw []byte) (context.Context, Span, []byte) {
+	ctx, span := t.tracer.Start(ctx, method, SpanKindClient)
+	setSpanAttributes(span, method, id)
+	return ctx, span, t.inject(messageRaw, t.metadata(ctx))
+}
+
+// metadata returns the metadata carrying the context of the trace of ctx, nil if it is not propagated
+func (t *TracingTransport) metadata(ctx context.Context) map[string]string {
+	if t.config.propagator == nil {
+		return nil
+	}
+	metadata := make(map[string]string)
+	t.config.propagator.Inject(ctx, metadata)
+	return metadata
+}
+
+// inject injects the metadata into the params of a message by the MetadataConvention.
+// Returns the message to send
+func (t *TracingTransport) inject(messageRaw []byte, metadata map[string]string) []byte {
+	if len(metadata) == 0 {
+		return messageRaw
+	}
+	var members map[string]json.RawMessage
+	if json.Unmarshal(messageRaw, &members) != nil {
+		return messageRaw
+	}
+	params, ok := members["params"]
+	if !ok {
+		return messageRaw
+	}
+	members["params"] = t.config.convention.Inject(params, metadata)
+	injected, err := json.Marshal(members)
+	if err != nil {
+		return messageRaw
+	}
+	return injected
+}
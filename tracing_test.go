@@ -0,0 +1,210 @@
+/*  Copyright 2022  Kosmas Valianos (kosmas.valianos@gmail.com)
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    https://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License. */
+
+package gojsonrpc
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"reflect"
+	"sync"
+	"testing"
+)
+
+type spanContextKey struct{}
+
+// testSpan is a span recorded by a testTracer
+type testSpan struct {
+	name       string
+	kind       SpanKind
+	parent     string
+	attributes map[string]any
+	err        error
+	ended      bool
+}
+
+func (s *testSpan) SetAttribute(key string, value any) {
+	s.attributes[key] = value
+}
+
+func (s *testSpan) End(err error) {
+	s.err, s.ended = err, true
+}
+
+// testTracer records the spans, identified by their names and their order
+type testTracer struct {
+	mu    sync.Mutex
+	spans []*testSpan
+}
+
+func (t *testTracer) Start(ctx context.Context, name string, kind SpanKind) (context.Context, Span) {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	parent, _ := ctx.Value(spanContextKey{}).(string)
+	span := &testSpan{name: name, kind: kind, parent: parent, attributes: make(map[string]any)}
+	t.spans = append(t.spans, span)
+	return context.WithValue(ctx, spanContextKey{}, fmt.Sprintf("%v-%d", name, len(t.spans))), span
+}
+
+// testPropagator propagates the span of the context as the "traceparent"
+type testPropagator struct{}
+
+func (testPropagator) Inject(ctx context.Context, metadata map[string]string) {
+	if span, ok := ctx.Value(spanContextKey{}).(string); ok {
+		metadata["traceparent"] = span
+	}
+}
+
+func (testPropagator) Extract(ctx context.Context, metadata map[string]string) context.Context {
+	if span, ok := metadata["traceparent"]; ok {
+		return context.WithValue(ctx, spanContextKey{}, span)
+	}
+	return ctx
+}
+
+func TestTracing(t *testing.T) {
+	tracer := &testTracer{}
+	mux := NewMux()
+	mux.Use(Tracing(tracer, WithTracePropagator(testPropagator{})))
+	err := HandleFunc(mux, "echo", func(ctx context.Context, params map[string]any) (map[string]any, error) {
+		return params, nil
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	err = HandleFunc(mux, "database", func(ctx context.Context, params any) (any, error) {
+		return nil, &JsonInvalidMethodParameters
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	client := NewClient(NewTracingTransport(&muxTransport{mux: mux}, tracer, WithTracePropagator(testPropagator{})))
+
+	t.Run("Propagated request", func(t *testing.T) {
+		tracer.spans = nil
+		var result map[string]any
+		if err := client.Call(context.Background(), "echo", map[string]any{"name": "foo"}, &result); err != nil {
+			t.Fatal(err)
+		}
+		if len(tracer.spans) != 2 {
+			t.Fatalf("spans = %v, want 2", len(tracer.spans))
+		}
+		clientSpan, serverSpan := tracer.spans[0], tracer.spans[1]
+		wantAttributes := map[string]any{
+			AttributeRPCSystem:        "jsonrpc",
+			AttributeJsonRPCVersion:   "2.0",
+			AttributeRPCMethod:        "echo",
+			AttributeJsonRPCRequestID: "1",
+		}
+		for _, span := range []*testSpan{clientSpan, serverSpan} {
+			if !reflect.DeepEqual(span.attributes, wantAttributes) {
+				t.Errorf("span attributes = %v, want %v", span.attributes, wantAttributes)
+			}
+			if !span.ended || span.err != nil {
+				t.Errorf("span ended = %v with %v, want ended without error", span.ended, span.err)
+			}
+		}
+		if clientSpan.kind != SpanKindClient || serverSpan.kind != SpanKindServer {
+			t.Errorf("span kinds = %v, %v, want %v, %v", clientSpan.kind, serverSpan.kind, SpanKindClient, SpanKindServer)
+		}
+		if serverSpan.parent != "echo-1" {
+			t.Errorf("server span parent = %q, want %q", serverSpan.parent, "echo-1")
+		}
+		wantResult := map[string]any{"name": "foo", "_meta": map[string]any{"traceparent": "echo-1"}}
+		if !reflect.DeepEqual(result, wantResult) {
+			t.Errorf("Call() result = %v, want %v", result, wantResult)
+		}
+	})
+
+	t.Run("Error response", func(t *testing.T) {
+		tracer.spans = nil
+		err := client.Call(context.Background(), "database", nil, nil)
+		if err == nil {
+			t.Fatal("Call() error = nil, want an error")
+		}
+		for _, span := range tracer.spans {
+			if span.attributes[AttributeJsonRPCErrorCode] != InvalidMethodParameters {
+				t.Errorf("span error code = %v, want %v", span.attributes[AttributeJsonRPCErrorCode], InvalidMethodParameters)
+			}
+			if span.attributes[AttributeJsonRPCErrorMessage] != "Invalid method parameters" {
+				t.Errorf("span error message = %v, want %v", span.attributes[AttributeJsonRPCErrorMessage], "Invalid method parameters")
+			}
+			if span.err == nil {
+				t.Errorf("span error = nil, want %v", err)
+			}
+		}
+	})
+
+	t.Run("Batch", func(t *testing.T) {
+		tracer.spans = nil
+		_, err := client.CallBatch(context.Background(), []BatchItem{
+			{Method: "echo", Params: map[string]any{"name": "foo"}},
+			{Method: "echo", Params: map[string]any{"name": "bar"}, Notification: true},
+		})
+		if err != nil {
+			t.Fatal(err)
+		}
+		if len(tracer.spans) != 3 {
+			t.Fatalf("spans = %v, want 3", len(tracer.spans))
+		}
+		if batchSpan := tracer.spans[0]; batchSpan.name != "batch" || batchSpan.attributes[AttributeJsonRPCBatchSize] != 2 {
+			t.Errorf("batch span = %v with %v, want batch of size 2", batchSpan.name, batchSpan.attributes)
+		}
+		for _, span := range tracer.spans[1:] {
+			if span.parent != "batch-1" {
+				t.Errorf("server span parent = %q, want %q", span.parent, "batch-1")
+			}
+		}
+	})
+
+	t.Run("Notification", func(t *testing.T) {
+		tracer.spans = nil
+		notifications := make(chan []byte, 1)
+		transport := NewTracingTransport(&muxTransport{mux: mux, notifications: notifications}, tracer)
+		if err := NewClient(transport).Notify(context.Background(), "echo", map[string]any{"name": "foo"}); err != nil {
+			t.Fatal(err)
+		}
+		if _, ok := tracer.spans[0].attributes[AttributeJsonRPCRequestID]; ok || tracer.spans[0].name != "echo" {
+			t.Errorf("notification span = %v with %v, want echo without request id", tracer.spans[0].name, tracer.spans[0].attributes)
+		}
+		if notificationRaw := <-notifications; !json.Valid(notificationRaw) {
+			t.Errorf("Send() notification = %s, want valid JSON", notificationRaw)
+		}
+	})
+}
+
+func TestParamsMetadata(t *testing.T) {
+	metadata := map[string]string{"traceparent": "00-abc-def-01"}
+	tests := []struct {
+		name   string
+		params string
+		want   string
+	}{
+		{name: "Named params", params: `{"name":"foo"}`, want: `{"_meta":{"traceparent":"00-abc-def-01"},"name":"foo"}`},
+		{name: "Positional params", params: `[42,23]`, want: `[42,23]`},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			injected := DefaultMetadataConvention.Inject(json.RawMessage(tt.params), metadata)
+			if string(injected) != tt.want {
+				t.Errorf("Inject() = %s, want %s", injected, tt.want)
+			}
+			extracted := DefaultMetadataConvention.Extract(injected)
+			if jsonKind(injected) == '{' && !reflect.DeepEqual(extracted, metadata) {
+				t.Errorf("Extract() = %v, want %v", extracted, metadata)
+			}
+		})
+	}
+}
@@ -0,0 +1,120 @@
+/*  Copyright 2022  Kosmas Valianos (kosmas.valianos@gmail.com)
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    https://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License. */
+
+package gojsonrpc
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"reflect"
+)
+
+// Validate checks that the notification complies with the specification, e.g. before sending one which was built
+// programmatically: the jsonrpc must be "2.0", the method must not be empty and the params, if present,
+// must be a JSON array or object.
+// Returns an error
+func (n *notification) Validate() error {
+	return validateCall(n.JsonRPC, n.Method, n.Params)
+}
+
+// Validate checks that the request complies with the specification as the notification does and that its id is
+// a number or a string. The methods with prefix "rpc." are valid since they are reserved for extensions,
+// e.g. "rpc.discover", which the peers may call.
+// Returns an error
+func (r *request) Validate() error {
+	if err := validateCall(r.JsonRPC, r.Method, r.Params); err != nil {
+		return err
+	}
+	if r.ID == nil {
+		return errors.New("request's ID must not be null")
+	}
+	if !isValidID(r.ID) {
+		return fmt.Errorf("request's ID must be a number or a string, not %T", r.ID)
+	}
+	return nil
+}
+
+// Validate checks that the response complies with the specification: besides the checks of ParseResponse, its id
+// must be a number or a string unless null, its result must be valid JSON and its error must be valid.
+// Returns an error
+func (r *response) Validate() error {
+	if err := r.validateEnvelope(); err != nil {
+		return err
+	}
+	if r.ID != nil && !isValidID(r.ID) {
+		return fmt.Errorf("response's ID must be a number, a string or null, not %T", r.ID)
+	}
+	if r.Error != nil {
+		return r.Error.Validate()
+	}
+	if !json.Valid(r.Result) {
+		return errors.New("response's result must be valid JSON")
+	}
+	return nil
+}
+
+// Validate checks that the error complies with the specification: its code must not be reserved unless it is the
+// code of an error object of the specification, a server error or reserved by the Language Server Protocol, its
+// message must not be empty and its data, if present, must be valid JSON.
+// Returns an error
+func (j *jsonRPCError) Validate() error {
+	switch {
+	case j.Code == ParseError, j.Code == InvalidRequest, j.Code == MethodNotFound,
+		j.Code == InvalidMethodParameters, j.Code == InternalError:
+	case IsCustomCode(j.Code), IsLSPReservedCode(j.Code):
+	default:
+		return fmt.Errorf("error's code %v is reserved", j.Code)
+	}
+	if j.Message == "" {
+		return errors.New("error's message must not be empty")
+	}
+	if len(j.Data) > 0 && !json.Valid(j.Data) {
+		return errors.New("error's data must be valid JSON")
+	}
+	return nil
+}
+
+// validateCall checks the members which the requests and the notifications have in common.
+// Returns an error
+func validateCall(jsonRPC, method string, paramsRaw json.RawMessage) error {
+	if jsonRPC != jsonRPCProtocol {
+		return fmt.Errorf("jsonrpc must be exactly \"%v\"", jsonRPCProtocol)
+	}
+	if method == "" {
+		return errors.New("method must not be empty")
+	}
+	if len(paramsRaw) == 0 {
+		return nil
+	}
+	if !json.Valid(paramsRaw) {
+		return errors.New("params must be valid JSON")
+	}
+	if trimmed := bytes.TrimSpace(paramsRaw); trimmed[0] != '[' && trimmed[0] != '{' {
+		return errors.New("params must be an array or an object")
+	}
+	return nil
+}
+
+// isValidID reports whether the id is a number or a string, including the types whose underlying type is one of
+// those of the NewRequest
+func isValidID(id any) bool {
+	switch reflect.ValueOf(id).Kind() {
+	case reflect.Int, reflect.Float64, reflect.String:
+		return true
+	default:
+		return false
+	}
+}
@@ -0,0 +1,125 @@
+/*  Copyright 2022  Kosmas Valianos (kosmas.valianos@gmail.com)
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    https://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License. */
+
+package gojsonrpc
+
+import (
+	"encoding/json"
+	"testing"
+)
+
+func TestValidate(t *testing.T) {
+	type customID string
+	tests := []struct {
+		name    string
+		message interface{ Validate() error }
+		wantErr bool
+	}{
+		{
+			name:    "Valid notification",
+			message: &notification{JsonRPC: jsonRPCProtocol, Method: "update", Params: json.RawMessage(`[1,2,3]`)},
+		},
+		{
+			name:    "Notification without method",
+			message: &notification{JsonRPC: jsonRPCProtocol},
+			wantErr: true,
+		},
+		{
+			name:    "Notification with wrong jsonrpc",
+			message: &notification{JsonRPC: "1.0", Method: "update"},
+			wantErr: true,
+		},
+		{
+			name:    "Notification with params by value",
+			message: &notification{JsonRPC: jsonRPCProtocol, Method: "update", Params: json.RawMessage(` 42`)},
+			wantErr: true,
+		},
+		{
+			name:    "Valid request",
+			message: &request{JsonRPC: jsonRPCProtocol, Method: "subtract", Params: json.RawMessage(`{"minuend": 42}`), ID: 1},
+		},
+		{
+			name:    "Request to a reserved method with a custom string id",
+			message: &request{JsonRPC: jsonRPCProtocol, Method: "rpc.discover", ID: customID("abc")},
+		},
+		{
+			name:    "Request with invalid params",
+			message: &request{JsonRPC: jsonRPCProtocol, Method: "subtract", Params: json.RawMessage(`[42,`), ID: 1},
+			wantErr: true,
+		},
+		{
+			name:    "Request with null id",
+			message: &request{JsonRPC: jsonRPCProtocol, Method: "subtract"},
+			wantErr: true,
+		},
+		{
+			name:    "Request with object id",
+			message: &request{JsonRPC: jsonRPCProtocol, Method: "subtract", ID: map[string]int{"id": 1}},
+			wantErr: true,
+		},
+		{
+			name:    "Valid result response",
+			message: &response{JsonRPC: jsonRPCProtocol, Result: json.RawMessage(`19`), ID: "1"},
+		},
+		{
+			name:    "Valid error response with null id",
+			message: &response{JsonRPC: jsonRPCProtocol, Error: &JsonParseError},
+		},
+		{
+			name:    "Response with result and error",
+			message: &response{JsonRPC: jsonRPCProtocol, Result: json.RawMessage(`19`), Error: &JsonInternalError, ID: 1},
+			wantErr: true,
+		},
+		{
+			name:    "Response with invalid result",
+			message: &response{JsonRPC: jsonRPCProtocol, Result: json.RawMessage(`{`), ID: 1},
+			wantErr: true,
+		},
+		{
+			name:    "Response with invalid error",
+			message: &response{JsonRPC: jsonRPCProtocol, Error: &jsonRPCError{Code: -32500, Message: "Reserved"}, ID: 1},
+			wantErr: true,
+		},
+		{
+			name:    "Response with boolean id",
+			message: &response{JsonRPC: jsonRPCProtocol, Result: json.RawMessage(`19`), ID: true},
+			wantErr: true,
+		},
+		{
+			name:    "Valid custom error",
+			message: &jsonRPCError{Code: 1001, Message: "Database error", Data: json.RawMessage(`{"table":"users"}`)},
+		},
+		{
+			name:    "Valid LSP error",
+			message: &JsonContentModified,
+		},
+		{
+			name:    "Error without message",
+			message: &jsonRPCError{Code: 1001},
+			wantErr: true,
+		},
+		{
+			name:    "Error with invalid data",
+			message: &jsonRPCError{Code: 1001, Message: "Database error", Data: json.RawMessage(`users`)},
+			wantErr: true,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if err := tt.message.Validate(); (err != nil) != tt.wantErr {
+				t.Errorf("Validate() error = %v, wantErr %v", err, tt.wantErr)
+			}
+		})
+	}
+}
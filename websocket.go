@@ -0,0 +1,589 @@
+/*  Copyright 2022  Kosmas Valianos (kosmas.valianos@gmail.com)
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    https://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License. */
+
+package gojsonrpc
+
+import (
+	"bufio"
+	"context"
+	"crypto/rand"
+	"crypto/sha1"
+	"crypto/tls"
+	"encoding/base64"
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"io"
+	"net"
+	"net/http"
+	"net/url"
+	"strings"
+	"sync"
+	"time"
+)
+
+// The WebSocket protocol (RFC 6455) details used by the transport
+const (
+	webSocketGUID    = "258EAFA5-E914-47DA-95CA-C5AB0DC85B11"
+	webSocketVersion = "13"
+
+	opContinuation = 0x0
+	opText         = 0x1
+	opBinary       = 0x2
+	opClose        = 0x8
+	opPing         = 0x9
+	opPong         = 0xa
+
+	closeNormal        = 1000
+	closeProtocolError = 1002
+	closeTooLarge      = 1009
+
+	maxControlPayload = 125
+)
+
+// errWebSocketClosed is returned by the reading of a WebSocketConn once the peer closed it
+var errWebSocketClosed = errors.New("websocket closed")
+
+// webSocketConfig is the configuration of the WebSocket server handler and dialer
+type webSocketConfig struct {
+	registry       *ConnRegistry
+	clientOptions  []ClientOption
+	header         http.Header
+	checkOrigin    func(r *http.Request) bool
+	maxMessageSize int64
+	pingInterval   time.Duration
+	readTimeout    time.Duration
+	tlsConfig      *tls.Config
+}
+
+// WebSocketOption configures the WebSocket server handler or dialer
+type WebSocketOption func(*webSocketConfig)
+
+// WithWebSocketRegistry makes the server handler register the accepted connections in the registry
+func WithWebSocketRegistry(registry *ConnRegistry) WebSocketOption {
+	return func(c *webSocketConfig) {
+		c.registry = registry
+	}
+}
+
+// WithWebSocketClientOptions configures the Client of the Conn calling the remote peer
+func WithWebSocketClientOptions(options ...ClientOption) WebSocketOption {
+	return func(c *webSocketConfig) {
+		c.clientOptions = append(c.clientOptions, options...)
+	}
+}
+
+// WithWebSocketHeader adds a header to the opening handshake of the dialer, e.g. for authorization
+func WithWebSocketHeader(key, value string) WebSocketOption {
+	return func(c *webSocketConfig) {
+		c.header.Add(key, value)
+	}
+}
+
+// WithWebSocketCheckOrigin sets the function accepting the opening handshakes of the server handler by their Origin.
+// By default, the handshakes with an Origin header are only accepted from the same host
+func WithWebSocketCheckOrigin(check func(r *http.Request) bool) WebSocketOption {
+	return func(c *webSocketConfig) {
+		c.checkOrigin = check
+	}
+}
+
+// WithWebSocketKeepalive pings the peer every interval and deems it dead, closing the connection, after timeout without
+// any frame from it. Its Conn then fails its outstanding calls and cancels the contexts of the requests it is serving,
+// and a ReconnectingTransport redials it. A timeout of zero defaults to twice the interval
+func WithWebSocketKeepalive(interval, timeout time.Duration) WebSocketOption {
+	return func(c *webSocketConfig) {
+		c.pingInterval = interval
+		c.readTimeout = timeout
+		if timeout <= 0 {
+			c.readTimeout = 2 * interval
+		}
+	}
+}
+
+// WithWebSocketMaxMessageSize sets the size limit in bytes of the received messages, 1MB by default. The connection is
+// closed with status 1009 once a frame announces a larger message. A zero or negative size restores the default
+func WithWebSocketMaxMessageSize(size int64) WebSocketOption {
+	return func(c *webSocketConfig) {
+		if size <= 0 {
+			size = defaultMaxBodySize
+		}
+		c.maxMessageSize = size
+	}
+}
+
+// WithWebSocketTLS configures the TLS of the dialer for the wss:// urls, e.g. with a client certificate for mutual TLS.
+// The host of the url is verified by default. The server handler is secured by the TLS configuration of its http.Server
+func WithWebSocketTLS(config *tls.Config) WebSocketOption {
+	return func(c *webSocketConfig) {
+		c.tlsConfig = config
+	}
+}
+
+func newWebSocketConfig(options []WebSocketOption) *webSocketConfig {
+	config := &webSocketConfig{
+		header:         make(http.Header),
+		checkOrigin:    sameOrigin,
+		maxMessageSize: defaultMaxBodySize,
+	}
+	for _, option := range options {
+		option(config)
+	}
+	return config
+}
+
+// sameOrigin reports whether the Origin of an opening handshake, if any, is the host of the request
+func sameOrigin(r *http.Request) bool {
+	origin := r.Header.Get("Origin")
+	if origin == "" {
+		return true
+	}
+	originURL, err := url.Parse(origin)
+	return err == nil && strings.EqualFold(originURL.Host, r.Host)
+}
+
+// WebSocketConn is a MessageConn carrying one JSON-RPC message per text frame of a WebSocket connection.
+// It answers the pings and the closing handshake of the peer
+type WebSocketConn struct {
+	conn           net.Conn
+	reader         *bufio.Reader
+	client         bool
+	maxMessageSize int64
+	readTimeout    time.Duration
+	writeMu        sync.Mutex
+	closeOnce      sync.Once
+	closeSent      bool
+	closed         chan struct{}
+}
+
+func newWebSocketConn(conn net.Conn, reader *bufio.Reader, client bool, config *webSocketConfig) *WebSocketConn {
+	c := &WebSocketConn{
+		conn:           conn,
+		reader:         reader,
+		client:         client,
+		maxMessageSize: config.maxMessageSize,
+		readTimeout:    config.readTimeout,
+		closed:         make(chan struct{}),
+	}
+	if config.pingInterval > 0 {
+		go c.ping(config.pingInterval)
+	}
+	return c
+}
+
+// ping pings the peer every interval until the connection is closed
+func (c *WebSocketConn) ping(interval time.Duration) {
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-ticker.C:
+			if c.writeControl(opPing, nil) != nil {
+				return
+			}
+		case <-c.closed:
+			return
+		}
+	}
+}
+
+// WriteMessage writes a message in a text frame, until the deadline of ctx if any
+func (c *WebSocketConn) WriteMessage(ctx context.Context, messageRaw []byte) error {
+	c.writeMu.Lock()
+	defer c.writeMu.Unlock()
+	if c.closeSent {
+		return net.ErrClosed
+	}
+	deadline, _ := ctx.Deadline()
+	_ = c.conn.SetWriteDeadline(deadline)
+	return c.writeFrame(opText, messageRaw)
+}
+
+// ReadMessage reads the next text or binary message, reassembling its fragments.
+// Returns the message or an error once the connection is closed or broken
+func (c *WebSocketConn) ReadMessage() ([]byte, error) {
+	var (
+		message []byte
+		started bool
+	)
+	for {
+		fin, opcode, payload, err := c.readFrame()
+		if err != nil {
+			return nil, err
+		}
+		switch opcode {
+		case opPing:
+			err = c.writeControl(opPong, payload)
+			if err != nil {
+				return nil, err
+			}
+			continue
+		case opPong:
+			continue
+		case opClose:
+			code := closeNormal
+			if len(payload) >= 2 {
+				code = int(binary.BigEndian.Uint16(payload))
+			}
+			_ = c.closeWith(code)
+			return nil, errWebSocketClosed
+		case opText, opBinary:
+			if started {
+				return nil, c.fail(closeProtocolError, "new message within a fragmented message")
+			}
+			started = true
+		case opContinuation:
+			if !started {
+				return nil, c.fail(closeProtocolError, "continuation without a message")
+			}
+		default:
+			return nil, c.fail(closeProtocolError, fmt.Sprintf("unknown opcode %v", opcode))
+		}
+
+		if int64(len(message)+len(payload)) > c.maxMessageSize {
+			return nil, c.failTooLarge()
+		}
+		message = append(message, payload...)
+		if fin {
+			return message, nil
+		}
+	}
+}
+
+// Close sends the closing handshake and closes the connection
+func (c *WebSocketConn) Close() error {
+	return c.closeWith(closeNormal)
+}
+
+// closeWith sends a close frame with the status code, once, and closes the connection
+func (c *WebSocketConn) closeWith(code int) error {
+	var err error
+	c.closeOnce.Do(func() {
+		payload := make([]byte, 2)
+		binary.BigEndian.PutUint16(payload, uint16(code))
+		_ = c.writeControl(opClose, payload)
+		err = c.conn.Close()
+		close(c.closed)
+	})
+	return err
+}
+
+// fail closes the connection after a protocol violation of the peer.
+// Returns the error describing it
+func (c *WebSocketConn) fail(code int, reason string) error {
+	_ = c.closeWith(code)
+	return fmt.Errorf("websocket: %v", reason)
+}
+
+// failTooLarge closes the connection as a message exceeds the size limit.
+// Returns an error wrapping ErrMessageTooLarge
+func (c *WebSocketConn) failTooLarge() error {
+	_ = c.closeWith(closeTooLarge)
+	return fmt.Errorf("websocket: %w", ErrMessageTooLarge)
+}
+
+// writeControl writes a control frame
+func (c *WebSocketConn) writeControl(opcode byte, payload []byte) error {
+	c.writeMu.Lock()
+	defer c.writeMu.Unlock()
+	if c.closeSent {
+		return net.ErrClosed
+	}
+	if opcode == opClose {
+		c.closeSent = true
+	}
+	_ = c.conn.SetWriteDeadline(time.Now().Add(time.Second))
+	return c.writeFrame(opcode, payload)
+}
+
+// writeFrame writes an unfragmented frame, masked if written by a client
+func (c *WebSocketConn) writeFrame(opcode byte, payload []byte) error {
+	header := make([]byte, 2, 14)
+	header[0] = 0x80 | opcode
+	switch length := len(payload); {
+	case length <= 125:
+		header[1] = byte(length)
+	case length <= 0xffff:
+		header[1] = 126
+		header = binary.BigEndian.AppendUint16(header, uint16(length))
+	default:
+		header[1] = 127
+		header = binary.BigEndian.AppendUint64(header, uint64(length))
+	}
+
+	frame := payload
+	if c.client {
+		header[1] |= 0x80
+		var mask [4]byte
+		_, err := rand.Read(mask[:])
+		if err != nil {
+			return err
+		}
+		header = append(header, mask[:]...)
+		frame = make([]byte, len(payload))
+		for i := range payload {
+			frame[i] = payload[i] ^ mask[i%4]
+		}
+	}
+	_, err := c.conn.Write(append(header, frame...))
+	return err
+}
+
+// readFrame reads a frame, unmasking it.
+// Returns whether it is the final fragment, its opcode and its payload or an error
+func (c *WebSocketConn) readFrame() (bool, byte, []byte, error) {
+	if c.readTimeout > 0 {
+		_ = c.conn.SetReadDeadline(time.Now().Add(c.readTimeout))
+	}
+	var header [2]byte
+	_, err := io.ReadFull(c.reader, header[:])
+	if err != nil {
+		return false, 0, nil, err
+	}
+	fin := header[0]&0x80 != 0
+	opcode := header[0] & 0x0f
+	masked := header[1]&0x80 != 0
+	length := uint64(header[1] & 0x7f)
+	if header[0]&0x70 != 0 {
+		return false, 0, nil, c.fail(closeProtocolError, "reserved bits set")
+	}
+	if masked == c.client {
+		return false, 0, nil, c.fail(closeProtocolError, "wrong masking")
+	}
+
+	switch length {
+	case 126:
+		var extended [2]byte
+		_, err = io.ReadFull(c.reader, extended[:])
+		length = uint64(binary.BigEndian.Uint16(extended[:]))
+	case 127:
+		var extended [8]byte
+		_, err = io.ReadFull(c.reader, extended[:])
+		length = binary.BigEndian.Uint64(extended[:])
+	}
+	if err != nil {
+		return false, 0, nil, err
+	}
+	if opcode >= opClose && (!fin || length > maxControlPayload) {
+		return false, 0, nil, c.fail(closeProtocolError, "invalid control frame")
+	}
+	if length > uint64(c.maxMessageSize) {
+		return false, 0, nil, c.failTooLarge()
+	}
+
+	var mask [4]byte
+	if masked {
+		_, err = io.ReadFull(c.reader, mask[:])
+		if err != nil {
+			return false, 0, nil, err
+		}
+	}
+	payload := make([]byte, length)
+	_, err = io.ReadFull(c.reader, payload)
+	if err != nil {
+		return false, 0, nil, err
+	}
+	if masked {
+		for i := range payload {
+			payload[i] ^= mask[i%4]
+		}
+	}
+	return fin, opcode, payload, nil
+}
+
+// webSocketAccept returns the Sec-WebSocket-Accept of a Sec-WebSocket-Key
+func webSocketAccept(key string) string {
+	hash := sha1.Sum([]byte(key + webSocketGUID))
+	return base64.StdEncoding.EncodeToString(hash[:])
+}
+
+// headerContains reports whether a comma separated header contains the token
+func headerContains(header http.Header, key, token string) bool {
+	for _, value := range header.Values(key) {
+		for _, element := range strings.Split(value, ",") {
+			if strings.EqualFold(strings.TrimSpace(element), token) {
+				return true
+			}
+		}
+	}
+	return false
+}
+
+// webSocketHandler upgrades the HTTP requests to WebSocket connections served by a Mux
+type webSocketHandler struct {
+	mux    *Mux
+	config *webSocketConfig
+}
+
+// WebSocketHandler returns an http.Handler upgrading the requests to WebSocket connections, configured by the options.
+// Each connection is a Conn serving the requests and notifications of the client with the mux, which calls the client
+// back through the Conn given by ConnFromContext or by the ConnRegistry if any
+func WebSocketHandler(mux *Mux, options ...WebSocketOption) http.Handler {
+	return &webSocketHandler{mux: mux, config: newWebSocketConfig(options)}
+}
+
+// ServeHTTP performs the opening handshake and starts serving the connection
+func (h *webSocketHandler) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodGet {
+		w.Header().Set("Allow", http.MethodGet)
+		http.Error(w, http.StatusText(http.StatusMethodNotAllowed), http.StatusMethodNotAllowed)
+		return
+	}
+	key := r.Header.Get("Sec-WebSocket-Key")
+	if !headerContains(r.Header, "Connection", "upgrade") || !headerContains(r.Header, "Upgrade", "websocket") || key == "" {
+		http.Error(w, "WebSocket handshake expected", http.StatusBadRequest)
+		return
+	}
+	if r.Header.Get("Sec-WebSocket-Version") != webSocketVersion {
+		w.Header().Set("Sec-WebSocket-Version", webSocketVersion)
+		http.Error(w, http.StatusText(http.StatusUpgradeRequired), http.StatusUpgradeRequired)
+		return
+	}
+	if !h.config.checkOrigin(r) {
+		http.Error(w, http.StatusText(http.StatusForbidden), http.StatusForbidden)
+		return
+	}
+	hijacker, ok := w.(http.Hijacker)
+	if !ok {
+		http.Error(w, "WebSocket not supported", http.StatusInternalServerError)
+		return
+	}
+	netConn, buffered, err := hijacker.Hijack()
+	if err != nil {
+		return
+	}
+	_, err = buffered.WriteString("HTTP/1.1 101 Switching Protocols\r\nUpgrade: websocket\r\nConnection: Upgrade\r\n" +
+		"Sec-WebSocket-Accept: " + webSocketAccept(key) + "\r\n\r\n")
+	if err == nil {
+		err = buffered.Flush()
+	}
+	if err != nil {
+		netConn.Close()
+		return
+	}
+	_ = netConn.SetDeadline(time.Time{})
+
+	wsConn := newWebSocketConn(netConn, buffered.Reader, false, h.config)
+	if h.config.registry != nil {
+		h.config.registry.Accept(wsConn, h.mux, h.config.clientOptions...)
+	} else {
+		NewConn(wsConn, h.mux, h.config.clientOptions...)
+	}
+}
+
+// DialWebSocketConn opens a WebSocket connection to a ws:// or wss:// url, configured by the options.
+// Returns a *WebSocketConn object or an error
+func DialWebSocketConn(ctx context.Context, rawURL string, options ...WebSocketOption) (*WebSocketConn, error) {
+	config := newWebSocketConfig(options)
+	wsURL, err := url.Parse(rawURL)
+	if err != nil {
+		return nil, err
+	}
+	address := wsURL.Host
+	if wsURL.Port() == "" {
+		switch wsURL.Scheme {
+		case "ws":
+			address = net.JoinHostPort(wsURL.Hostname(), "80")
+		case "wss":
+			address = net.JoinHostPort(wsURL.Hostname(), "443")
+		}
+	}
+	if wsURL.Scheme != "ws" && wsURL.Scheme != "wss" {
+		return nil, fmt.Errorf("websocket: unsupported scheme %q", wsURL.Scheme)
+	}
+
+	var dialer net.Dialer
+	netConn, err := dialer.DialContext(ctx, "tcp", address)
+	if err != nil {
+		return nil, err
+	}
+	if deadline, ok := ctx.Deadline(); ok {
+		_ = netConn.SetDeadline(deadline)
+	}
+	if wsURL.Scheme == "wss" {
+		tlsConfig := &tls.Config{ServerName: wsURL.Hostname()}
+		if config.tlsConfig != nil {
+			tlsConfig = clientTLSConfig(config.tlsConfig, wsURL.Hostname())
+		}
+		tlsConn := tls.Client(netConn, tlsConfig)
+		err = tlsConn.HandshakeContext(ctx)
+		if err != nil {
+			netConn.Close()
+			return nil, err
+		}
+		netConn = tlsConn
+	}
+
+	reader, err := webSocketHandshake(ctx, netConn, wsURL, config.header)
+	if err != nil {
+		netConn.Close()
+		return nil, err
+	}
+	_ = netConn.SetDeadline(time.Time{})
+	return newWebSocketConn(netConn, reader, true, config), nil
+}
+
+// webSocketHandshake performs the opening handshake of a client.
+// Returns the reader of the connection or an error
+func webSocketHandshake(ctx context.Context, netConn net.Conn, wsURL *url.URL, header http.Header) (*bufio.Reader, error) {
+	var nonce [16]byte
+	_, err := rand.Read(nonce[:])
+	if err != nil {
+		return nil, err
+	}
+	key := base64.StdEncoding.EncodeToString(nonce[:])
+
+	httpURL := *wsURL
+	httpURL.Scheme = strings.Replace(wsURL.Scheme, "ws", "http", 1)
+	request, err := http.NewRequestWithContext(ctx, http.MethodGet, httpURL.String(), nil)
+	if err != nil {
+		return nil, err
+	}
+	for key, values := range header {
+		request.Header[key] = values
+	}
+	request.Header.Set("Upgrade", "websocket")
+	request.Header.Set("Connection", "Upgrade")
+	request.Header.Set("Sec-WebSocket-Key", key)
+	request.Header.Set("Sec-WebSocket-Version", webSocketVersion)
+	err = request.Write(netConn)
+	if err != nil {
+		return nil, err
+	}
+
+	reader := bufio.NewReader(netConn)
+	response, err := http.ReadResponse(reader, request)
+	if err != nil {
+		return nil, err
+	}
+	response.Body.Close()
+	if response.StatusCode != http.StatusSwitchingProtocols {
+		return nil, fmt.Errorf("websocket: HTTP status %v", response.StatusCode)
+	}
+	if response.Header.Get("Sec-WebSocket-Accept") != webSocketAccept(key) {
+		return nil, errors.New("websocket: invalid Sec-WebSocket-Accept")
+	}
+	return reader, nil
+}
+
+// DialWebSocket opens a WebSocket connection to a ws:// or wss:// url, configured by the options, and makes it a Conn
+// serving the requests and notifications of the server with the mux, which may be nil.
+// Returns a *Conn object or an error
+func DialWebSocket(ctx context.Context, rawURL string, mux *Mux, options ...WebSocketOption) (*Conn, error) {
+	wsConn, err := DialWebSocketConn(ctx, rawURL, options...)
+	if err != nil {
+		return nil, err
+	}
+	return NewConn(wsConn, mux, newWebSocketConfig(options).clientOptions...), nil
+}
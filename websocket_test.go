@@ -0,0 +1,312 @@
+/*  Copyright 2022  Kosmas Valianos (kosmas.valianos@gmail.com)
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    https://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License. */
+
+package gojsonrpc
+
+import (
+	"bufio"
+	"context"
+	"encoding/binary"
+	"errors"
+	"io"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestWebSocket(t *testing.T) {
+	registry := NewConnRegistry()
+	server := httptest.NewServer(WebSocketHandler(newTestMux(t), WithWebSocketRegistry(registry)))
+	defer server.Close()
+	wsURL := "ws" + strings.TrimPrefix(server.URL, "http")
+
+	clientMux := NewMux()
+	err := HandleFunc(clientMux, "whoami", func(ctx context.Context, params any) (string, error) {
+		return "browser", nil
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	conn, err := DialWebSocket(context.Background(), wsURL, clientMux)
+	if err != nil {
+		t.Fatalf("DialWebSocket() = %v, want nil", err)
+	}
+
+	t.Run("Call the server", func(t *testing.T) {
+		result, err := Call[int](context.Background(), conn.Client(), "subtract", []int{42, 23})
+		if err != nil || result != 19 {
+			t.Errorf("Call() = %v, %v, want 19", result, err)
+		}
+		large := []string{strings.Repeat("a", 70000)}
+		echoed, err := Call[[]string](context.Background(), conn.Client(), "raw", large)
+		if err != nil || len(echoed) != 1 || echoed[0] != large[0] {
+			t.Errorf("Call() = %v, want the large params echoed", err)
+		}
+	})
+
+	t.Run("Call the client", func(t *testing.T) {
+		ids := registry.IDs()
+		if len(ids) != 1 {
+			t.Fatalf("IDs() = %v, want 1 connection", ids)
+		}
+		var name string
+		err := registry.Call(context.Background(), ids[0], "whoami", nil, &name)
+		if err != nil || name != "browser" {
+			t.Errorf("Call() = %v, %v, want browser", name, err)
+		}
+	})
+
+	t.Run("Close", func(t *testing.T) {
+		err := conn.Close()
+		if err != nil {
+			t.Errorf("Close() = %v, want nil", err)
+		}
+		deadline := time.Now().Add(5 * time.Second)
+		for len(registry.IDs()) != 0 && time.Now().Before(deadline) {
+			time.Sleep(time.Millisecond)
+		}
+		if ids := registry.IDs(); len(ids) != 0 {
+			t.Errorf("IDs() = %v, want none once closed", ids)
+		}
+	})
+}
+
+func TestWebSocketHandler_Handshake(t *testing.T) {
+	server := httptest.NewServer(WebSocketHandler(newTestMux(t)))
+	defer server.Close()
+
+	tests := []struct {
+		name           string
+		header         map[string]string
+		wantStatusCode int
+	}{
+		{
+			name:           "Not an upgrade",
+			header:         map[string]string{},
+			wantStatusCode: http.StatusBadRequest,
+		},
+		{
+			name: "Unsupported version",
+			header: map[string]string{"Connection": "keep-alive, Upgrade", "Upgrade": "websocket",
+				"Sec-WebSocket-Key": "dGhlIHNhbXBsZSBub25jZQ==", "Sec-WebSocket-Version": "8"},
+			wantStatusCode: http.StatusUpgradeRequired,
+		},
+		{
+			name: "Cross origin",
+			header: map[string]string{"Connection": "Upgrade", "Upgrade": "websocket", "Origin": "https://evil.com",
+				"Sec-WebSocket-Key": "dGhlIHNhbXBsZSBub25jZQ==", "Sec-WebSocket-Version": "13"},
+			wantStatusCode: http.StatusForbidden,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			request, err := http.NewRequest(http.MethodGet, server.URL, nil)
+			if err != nil {
+				t.Fatal(err)
+			}
+			for key, value := range tt.header {
+				request.Header.Set(key, value)
+			}
+			response, err := http.DefaultClient.Do(request)
+			if err != nil {
+				t.Fatal(err)
+			}
+			response.Body.Close()
+			if response.StatusCode != tt.wantStatusCode {
+				t.Errorf("StatusCode = %v, want %v", response.StatusCode, tt.wantStatusCode)
+			}
+		})
+	}
+}
+
+func Test_webSocketAccept(t *testing.T) {
+	// The example of RFC 6455
+	if got := webSocketAccept("dGhlIHNhbXBsZSBub25jZQ=="); got != "s3pPLMBiTxaQ9kYGzzhZRbK+xOo=" {
+		t.Errorf("webSocketAccept() = %v, want s3pPLMBiTxaQ9kYGzzhZRbK+xOo=", got)
+	}
+}
+
+func TestWebSocketConn_Frames(t *testing.T) {
+	serverEnd, clientEnd := net.Pipe()
+	config := newWebSocketConfig(nil)
+	server := newWebSocketConn(serverEnd, bufio.NewReader(serverEnd), false, config)
+	client := newWebSocketConn(clientEnd, bufio.NewReader(clientEnd), true, config)
+
+	// The client writes a fragmented message with a ping in between
+	go func() {
+		client.writeMu.Lock()
+		client.writeFrameFragment(opText, false, []byte(`{"jsonrpc":"2.0",`))
+		client.writeFrameFragment(opPing, true, []byte("ping"))
+		client.writeFrameFragment(opContinuation, true, []byte(`"method":"update"}`))
+		client.writeMu.Unlock()
+	}()
+	pong := make(chan []byte, 1)
+	go func() {
+		_, opcode, payload, err := client.readFrame()
+		if err == nil && opcode == opPong {
+			pong <- payload
+		}
+		close(pong)
+	}()
+
+	message, err := server.ReadMessage()
+	if err != nil || string(message) != `{"jsonrpc":"2.0","method":"update"}` {
+		t.Errorf("ReadMessage() = %s, %v, want the reassembled message", message, err)
+	}
+	if payload := <-pong; string(payload) != "ping" {
+		t.Errorf("pong = %q, want %q", payload, "ping")
+	}
+
+	// The closing handshake of the client
+	go client.Close()
+	_, err = server.ReadMessage()
+	if !errors.Is(err, errWebSocketClosed) {
+		t.Errorf("ReadMessage() = %v, want %v", err, errWebSocketClosed)
+	}
+}
+
+func TestWithWebSocketMaxMessageSize(t *testing.T) {
+	serverEnd, clientEnd := net.Pipe()
+	server := newWebSocketConn(serverEnd, bufio.NewReader(serverEnd), false, newWebSocketConfig([]WebSocketOption{WithWebSocketMaxMessageSize(16)}))
+	client := newWebSocketConn(clientEnd, bufio.NewReader(clientEnd), true, newWebSocketConfig(nil))
+
+	go func() {
+		client.writeMu.Lock()
+		client.writeFrameFragment(opText, true, []byte(`{"jsonrpc":"2.0","method":"update"}`))
+		client.writeMu.Unlock()
+	}()
+	closeCode := make(chan int, 1)
+	go func() {
+		_, opcode, payload, err := client.readFrame()
+		if err == nil && opcode == opClose && len(payload) >= 2 {
+			closeCode <- int(binary.BigEndian.Uint16(payload))
+		}
+		close(closeCode)
+	}()
+
+	_, err := server.ReadMessage()
+	if !errors.Is(err, ErrMessageTooLarge) {
+		t.Errorf("ReadMessage() = %v, want %v", err, ErrMessageTooLarge)
+	}
+	if code := <-closeCode; code != closeTooLarge {
+		t.Errorf("close code = %v, want %v", code, closeTooLarge)
+	}
+}
+
+// writeFrameFragment writes a frame like writeFrame, possibly not final
+func (c *WebSocketConn) writeFrameFragment(opcode byte, fin bool, payload []byte) {
+	var frame []byte
+	first := opcode
+	if fin {
+		first |= 0x80
+	}
+	frame = append(frame, first, 0x80|byte(len(payload)), 0, 0, 0, 0)
+	frame = append(frame, payload...)
+	c.conn.Write(frame)
+}
+
+func TestWithWebSocketKeepalive(t *testing.T) {
+	keepalive := newWebSocketConfig([]WebSocketOption{WithWebSocketKeepalive(10*time.Millisecond, 50*time.Millisecond)})
+
+	t.Run("Dead peer", func(t *testing.T) {
+		serverEnd, peerEnd := net.Pipe()
+		defer peerEnd.Close()
+		mux := NewMux()
+		canceled := make(chan struct{})
+		err := HandleFunc(mux, "block", func(ctx context.Context, params any) (any, error) {
+			<-ctx.Done()
+			close(canceled)
+			return nil, ctx.Err()
+		})
+		if err != nil {
+			t.Fatal(err)
+		}
+		conn := NewConn(newWebSocketConn(serverEnd, bufio.NewReader(serverEnd), false, keepalive), mux)
+
+		// The peer sends a request, then neither answers the pings nor sends anything
+		peer := newWebSocketConn(peerEnd, bufio.NewReader(peerEnd), true, newWebSocketConfig(nil))
+		go func() {
+			peer.writeMu.Lock()
+			peer.writeFrame(opText, []byte(`{"jsonrpc":"2.0","method":"block","id":1}`))
+			peer.writeMu.Unlock()
+			io.Copy(io.Discard, peerEnd)
+		}()
+		call := conn.Client().Go(context.Background(), "subtract", []int{42, 23})
+
+		select {
+		case <-conn.Done():
+		case <-time.After(5 * time.Second):
+			t.Fatal("dead peer not detected")
+		}
+		if conn.Err() == nil {
+			t.Error("Err() = nil, want the read timeout")
+		}
+		select {
+		case <-canceled:
+		case <-time.After(5 * time.Second):
+			t.Error("handler context not canceled")
+		}
+		<-call.Done
+		if !errors.Is(call.Error, ErrTransportClosed) {
+			t.Errorf("Error = %v, want %v", call.Error, ErrTransportClosed)
+		}
+	})
+
+	t.Run("Live peer", func(t *testing.T) {
+		// Buffered like in real life, as both ends ping
+		serverEnd, clientEnd := newTCPConns(t)
+		server := NewConn(newWebSocketConn(serverEnd, bufio.NewReader(serverEnd), false, keepalive), newTestMux(t))
+		defer server.Close()
+		client := NewConn(newWebSocketConn(clientEnd, bufio.NewReader(clientEnd), true, keepalive), nil)
+		defer client.Close()
+
+		// Idle for longer than the timeout, kept alive by the pongs
+		time.Sleep(200 * time.Millisecond)
+		select {
+		case <-client.Done():
+			t.Fatalf("connection closed: %v", client.Err())
+		default:
+		}
+		result, err := Call[int](context.Background(), client.Client(), "subtract", []int{42, 23})
+		if err != nil || result != 19 {
+			t.Errorf("Call() = %v, %v, want 19", result, err)
+		}
+	})
+}
+
+// newTCPConns creates the two ends of a loopback TCP connection
+func newTCPConns(t *testing.T) (net.Conn, net.Conn) {
+	listener, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer listener.Close()
+	accepted := make(chan net.Conn, 1)
+	go func() {
+		conn, _ := listener.Accept()
+		accepted <- conn
+	}()
+	clientEnd, err := net.Dial("tcp", listener.Addr().String())
+	if err != nil {
+		t.Fatal(err)
+	}
+	serverEnd := <-accepted
+	if serverEnd == nil {
+		t.Fatal("connection not accepted")
+	}
+	return serverEnd, clientEnd
+}
@@ -0,0 +1,163 @@
+/*  Copyright 2022  Kosmas Valianos (kosmas.valianos@gmail.com)
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    https://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License. */
+
+package gojsonrpc
+
+import (
+	"bufio"
+	"encoding/json"
+	"io"
+	"sync"
+)
+
+var (
+	notificationPrefix = []byte(`{"jsonrpc":"` + jsonRPCProtocol + `","method":`)
+	paramsMemberPrefix = []byte(`,"params":`)
+	idMemberPrefix     = []byte(`,"id":`)
+	messageEnd         = []byte("}\n")
+)
+
+var writerPool = sync.Pool{
+	New: func() any {
+		return bufio.NewWriter(nil)
+	},
+}
+
+// WriteRequest writes the request using the method, the params and the id to w, as NewRequest creates it.
+// With encoding/json as the JSONEngine the params are encoded straight into the buffered w.
+// Returns an error, after which part of the request may have been written
+func WriteRequest[I idInterface](w io.Writer, method string, params any, id I) error {
+	idRaw, err := json.Marshal(id)
+	if err != nil {
+		return err
+	}
+	return writeRequest(w, method, params, idRaw)
+}
+
+// WriteNotification writes the notification using the method and the params to w, as NewNotification creates it.
+// Returns an error, after which part of the notification may have been written
+func WriteNotification(w io.Writer, method string, params any) error {
+	return writeRequest(w, method, params, nil)
+}
+
+// WriteResultResponse writes the response from a result object using the id to w, as NewResultResponse creates it.
+// With encoding/json as the JSONEngine the result is encoded straight into the buffered w.
+// Returns an error, after which part of the response may have been written
+func WriteResultResponse[I idInterface](w io.Writer, id I, result any) error {
+	envelope, err := newResponseEnvelope(id)
+	if err != nil {
+		return err
+	}
+
+	bufferedWriter := getBufferedWriter(w)
+	defer putBufferedWriter(bufferedWriter)
+	bufferedWriter.Write(resultResponsePrefix)
+	if err := writeJSONValue(bufferedWriter, result); err != nil {
+		return err
+	}
+	bufferedWriter.Write(envelope.suffix)
+	return bufferedWriter.Flush()
+}
+
+// WriteErrorResponse writes the response from a *jsonRPCError object using the id to w, as NewErrorResponse creates it.
+// Returns an error
+func WriteErrorResponse(w io.Writer, id any, jsonError *jsonRPCError) error {
+	response, err := buildErrorResponse(id, jsonError)
+	if err != nil {
+		return err
+	}
+	messageBuffer, err := encodePooled(response, true)
+	if err != nil {
+		return err
+	}
+	defer messageBuffer.release()
+	_, err = w.Write(messageBuffer.buffer.Bytes())
+	return err
+}
+
+// writeRequest writes the request, or the notification if idRaw is nil, to w.
+// Returns an error
+func writeRequest(w io.Writer, method string, params any, idRaw []byte) error {
+	methodRaw, err := json.Marshal(method)
+	if err != nil {
+		return err
+	}
+
+	bufferedWriter := getBufferedWriter(w)
+	defer putBufferedWriter(bufferedWriter)
+	bufferedWriter.Write(notificationPrefix)
+	bufferedWriter.Write(methodRaw)
+	if params != nil {
+		bufferedWriter.Write(paramsMemberPrefix)
+		if err := writeJSONValue(bufferedWriter, params); err != nil {
+			return err
+		}
+	}
+	if idRaw != nil {
+		bufferedWriter.Write(idMemberPrefix)
+		bufferedWriter.Write(idRaw)
+	}
+	bufferedWriter.Write(messageEnd)
+	return bufferedWriter.Flush()
+}
+
+// writeJSONValue writes the compact encoding of the value with the JSONEngine of the package to w.
+// Returns an error
+func writeJSONValue(w io.Writer, value any) error {
+	engine := currentJSONEngine()
+	if _, ok := engine.(stdJSONEngine); ok {
+		return json.NewEncoder(trailingNewlineWriter{w: w}).Encode(value)
+	}
+
+	valueRaw, err := marshalJSONValue(engine, value)
+	if err != nil {
+		return err
+	}
+	// The constructors compact the encoding of another engine when marshaling the message
+	messageBuffer := messageBufferPool.Get().(*messageBuffer)
+	defer messageBuffer.release()
+	if err := json.Compact(&messageBuffer.buffer, valueRaw); err != nil {
+		return err
+	}
+	_, err = w.Write(messageBuffer.buffer.Bytes())
+	return err
+}
+
+// trailingNewlineWriter drops the newline json.Encoder writes after the value, which would split the message
+type trailingNewlineWriter struct {
+	w io.Writer
+}
+
+func (t trailingNewlineWriter) Write(p []byte) (int, error) {
+	if len(p) > 0 && p[len(p)-1] == '\n' {
+		if _, err := t.w.Write(p[:len(p)-1]); err != nil {
+			return 0, err
+		}
+		return len(p), nil
+	}
+	return t.w.Write(p)
+}
+
+// getBufferedWriter returns a pooled *bufio.Writer writing to w
+func getBufferedWriter(w io.Writer) *bufio.Writer {
+	bufferedWriter := writerPool.Get().(*bufio.Writer)
+	bufferedWriter.Reset(w)
+	return bufferedWriter
+}
+
+// putBufferedWriter returns the *bufio.Writer to the pool without keeping its io.Writer
+func putBufferedWriter(bufferedWriter *bufio.Writer) {
+	bufferedWriter.Reset(nil)
+	writerPool.Put(bufferedWriter)
+}
@@ -0,0 +1,171 @@
+/*  Copyright 2022  Kosmas Valianos (kosmas.valianos@gmail.com)
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    https://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License. */
+
+package gojsonrpc
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"strings"
+	"testing"
+)
+
+func TestWrite(t *testing.T) {
+	largeResult := strings.Repeat("a", 3*4096)
+	tests := []struct {
+		name  string
+		write func(w *bytes.Buffer) error
+		new   func() ([]byte, error)
+	}{
+		{
+			name:  "WriteRequest",
+			write: func(w *bytes.Buffer) error { return WriteRequest(w, "subtract", []int{42, 23}, 1) },
+			new:   func() ([]byte, error) { return NewRequest("subtract", []int{42, 23}, 1) },
+		},
+		{
+			name:  "WriteRequest without params",
+			write: func(w *bytes.Buffer) error { return WriteRequest(w, "<ping>", nil, "abc") },
+			new:   func() ([]byte, error) { return NewRequest("<ping>", nil, "abc") },
+		},
+		{
+			name:  "WriteNotification",
+			write: func(w *bytes.Buffer) error { return WriteNotification(w, "update", map[string]string{"html": "<b>"}) },
+			new:   func() ([]byte, error) { return NewNotification("update", map[string]string{"html": "<b>"}) },
+		},
+		{
+			name:  "WriteResultResponse",
+			write: func(w *bytes.Buffer) error { return WriteResultResponse(w, 1.5, []int{1, 2, 3}) },
+			new:   func() ([]byte, error) { return NewResultResponse(1.5, []int{1, 2, 3}) },
+		},
+		{
+			name:  "WriteResultResponse nil result",
+			write: func(w *bytes.Buffer) error { return WriteResultResponse(w, 1, nil) },
+			new:   func() ([]byte, error) { return NewResultResponse(1, nil) },
+		},
+		{
+			name:  "WriteResultResponse large result",
+			write: func(w *bytes.Buffer) error { return WriteResultResponse(w, 1, largeResult) },
+			new:   func() ([]byte, error) { return NewResultResponse(1, largeResult) },
+		},
+		{
+			name:  "WriteErrorResponse",
+			write: func(w *bytes.Buffer) error { return WriteErrorResponse(w, "abc", &JsonMethodNotFound) },
+			new:   func() ([]byte, error) { return NewErrorResponse("abc", &JsonMethodNotFound) },
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			want, err := tt.new()
+			if err != nil {
+				t.Fatal(err)
+			}
+			var got bytes.Buffer
+			if err := tt.write(&got); err != nil {
+				t.Fatal(err)
+			}
+			if !bytes.Equal(got.Bytes(), want) {
+				t.Errorf("%v() = %v, want %v", tt.name, got.String(), string(want))
+			}
+		})
+	}
+}
+
+// indentedEngine is encoding/json indenting its output
+type indentedEngine struct{}
+
+func (indentedEngine) Marshal(v any) ([]byte, error) {
+	return json.MarshalIndent(v, "", "  ")
+}
+
+func (indentedEngine) Unmarshal(data []byte, v any) error {
+	return json.Unmarshal(data, v)
+}
+
+func TestWrite_JSONEngine(t *testing.T) {
+	SetJSONEngine(indentedEngine{})
+	defer SetJSONEngine(nil)
+
+	var got bytes.Buffer
+	if err := WriteResultResponse(&got, 1, map[string]int{"a": 1}); err != nil {
+		t.Fatal(err)
+	}
+	want, err := NewResultResponse(1, map[string]int{"a": 1})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got.String() != string(want) {
+		t.Errorf("WriteResultResponse() = %q, want %q", got.String(), want)
+	}
+}
+
+var errWrite = errors.New("write failed")
+
+// failingWriter fails every write
+type failingWriter struct{}
+
+func (failingWriter) Write(p []byte) (int, error) {
+	return 0, errWrite
+}
+
+func TestWrite_error(t *testing.T) {
+	tests := []struct {
+		name    string
+		write   func() error
+		wantErr error
+	}{
+		{name: "WriteRequest", write: func() error { return WriteRequest(failingWriter{}, "subtract", nil, 1) }, wantErr: errWrite},
+		{name: "WriteNotification", write: func() error { return WriteNotification(failingWriter{}, "update", nil) }, wantErr: errWrite},
+		{name: "WriteResultResponse", write: func() error { return WriteResultResponse(failingWriter{}, 1, 19) }, wantErr: errWrite},
+		{name: "WriteErrorResponse", write: func() error { return WriteErrorResponse(failingWriter{}, 1, &JsonInternalError) }, wantErr: errWrite},
+		{name: "WriteRequest params", write: func() error { return WriteRequest(&bytes.Buffer{}, "subtract", make(chan int), 1) }},
+		{name: "WriteResultResponse result", write: func() error { return WriteResultResponse(&bytes.Buffer{}, 1, make(chan int)) }},
+		{name: "WriteErrorResponse id", write: func() error { return WriteErrorResponse(&bytes.Buffer{}, nil, &JsonInternalError) }},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			err := tt.write()
+			if err == nil {
+				t.Fatalf("%v() error = nil, want an error", tt.name)
+			}
+			if tt.wantErr != nil && !errors.Is(err, tt.wantErr) {
+				t.Errorf("%v() error = %v, want %v", tt.name, err, tt.wantErr)
+			}
+		})
+	}
+}
+
+func BenchmarkWriteResultResponse(b *testing.B) {
+	result := make([]string, 10000)
+	for i := range result {
+		result[i] = "2b6f1a8e-8d0a-4c4b-9d8e-3f1b2c7a9e10"
+	}
+	var buffer bytes.Buffer
+
+	b.Run("NewResultResponse", func(b *testing.B) {
+		b.ReportAllocs()
+		for i := 0; i < b.N; i++ {
+			buffer.Reset()
+			responseRaw, _ := NewResultResponse(1, result)
+			buffer.Write(responseRaw)
+		}
+	})
+	b.Run("WriteResultResponse", func(b *testing.B) {
+		b.ReportAllocs()
+		for i := 0; i < b.N; i++ {
+			buffer.Reset()
+			_ = WriteResultResponse(&buffer, 1, result)
+		}
+	})
+}